@@ -0,0 +1,92 @@
+package webrtc
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// AbsCaptureTime is the absolute capture time of a frame carried by the
+// abs-capture-time header extension
+// http://www.webrtc.org/experiments/rtp-hdrext/abs-capture-time
+type AbsCaptureTime struct {
+	// Timestamp is the NTP time, in the UQ32.32 format, at which the first
+	// sample of the frame was captured, as seen by the capture system clock
+	Timestamp uint64
+
+	// EstimatedCaptureClockOffset is the optional estimated offset, in the
+	// Q32.32 format, between the capture system clock and the sender clock
+	EstimatedCaptureClockOffset *int64
+}
+
+// NewAbsCaptureTime creates an AbsCaptureTime for the given capture time
+func NewAbsCaptureTime(captureTime time.Time) AbsCaptureTime {
+	return AbsCaptureTime{Timestamp: toNTPTime(captureTime)}
+}
+
+// CaptureTime returns the capture time as a time.Time
+func (a AbsCaptureTime) CaptureTime() time.Time {
+	return fromNTPTime(a.Timestamp)
+}
+
+// ClockOffset returns the estimated capture clock offset, if present
+func (a AbsCaptureTime) ClockOffset() (time.Duration, bool) {
+	if a.EstimatedCaptureClockOffset == nil {
+		return 0, false
+	}
+	offset := *a.EstimatedCaptureClockOffset
+	return time.Duration(offset>>32)*time.Second + time.Duration((offset&0xFFFFFFFF)*int64(time.Second)>>32), true
+}
+
+func (a AbsCaptureTime) marshal() []byte {
+	if a.EstimatedCaptureClockOffset == nil {
+		payload := make([]byte, 8)
+		binary.BigEndian.PutUint64(payload, a.Timestamp)
+		return payload
+	}
+
+	payload := make([]byte, 16)
+	binary.BigEndian.PutUint64(payload, a.Timestamp)
+	binary.BigEndian.PutUint64(payload[8:], uint64(*a.EstimatedCaptureClockOffset))
+	return payload
+}
+
+func unmarshalAbsCaptureTime(payload []byte) (AbsCaptureTime, error) {
+	switch len(payload) {
+	case 8:
+		return AbsCaptureTime{Timestamp: binary.BigEndian.Uint64(payload)}, nil
+	case 16:
+		offset := int64(binary.BigEndian.Uint64(payload[8:]))
+		return AbsCaptureTime{
+			Timestamp:                   binary.BigEndian.Uint64(payload),
+			EstimatedCaptureClockOffset: &offset,
+		}, nil
+	default:
+		return AbsCaptureTime{}, errors.Errorf("invalid abs capture time extension size %d", len(payload))
+	}
+}
+
+// toClockOffset converts a duration to the Q32.32 format
+func toClockOffset(d time.Duration) int64 {
+	seconds := int64(d / time.Second)
+	fraction := int64(d%time.Second) << 32 / int64(time.Second)
+	return seconds<<32 + fraction
+}
+
+// ntpEpochOffset is the number of seconds between the NTP and the Unix epochs
+const ntpEpochOffset = 2208988800
+
+// toNTPTime converts a time.Time to the 64-bit NTP format
+func toNTPTime(t time.Time) uint64 {
+	seconds := uint64(t.Unix()) + ntpEpochOffset
+	fraction := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return seconds<<32 | fraction
+}
+
+// fromNTPTime converts a 64-bit NTP time to a time.Time
+func fromNTPTime(ntp uint64) time.Time {
+	seconds := int64(ntp>>32) - ntpEpochOffset
+	nanoseconds := int64((ntp & 0xFFFFFFFF) * uint64(time.Second) >> 32)
+	return time.Unix(seconds, nanoseconds)
+}
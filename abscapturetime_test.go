@@ -0,0 +1,60 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pions/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNTPTime(t *testing.T) {
+	now := time.Unix(1550000000, 500000000)
+	ntp := toNTPTime(now)
+	assert.Equal(t, uint64(1550000000+ntpEpochOffset), ntp>>32)
+	assert.Equal(t, uint64(1<<31), ntp&0xFFFFFFFF)
+	assert.Equal(t, now, fromNTPTime(ntp))
+}
+
+func TestAbsCaptureTime_Marshal(t *testing.T) {
+	captureTime := time.Unix(1550000000, 250000000)
+
+	a := NewAbsCaptureTime(captureTime)
+	payload := a.marshal()
+	assert.Len(t, payload, 8)
+
+	parsed, err := unmarshalAbsCaptureTime(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, captureTime, parsed.CaptureTime())
+	_, ok := parsed.ClockOffset()
+	assert.False(t, ok)
+
+	offset := toClockOffset(-1500 * time.Millisecond)
+	a.EstimatedCaptureClockOffset = &offset
+	payload = a.marshal()
+	assert.Len(t, payload, 16)
+
+	parsed, err = unmarshalAbsCaptureTime(payload)
+	assert.NoError(t, err)
+	duration, ok := parsed.ClockOffset()
+	assert.True(t, ok)
+	assert.Equal(t, -1500*time.Millisecond, duration)
+
+	_, err = unmarshalAbsCaptureTime([]byte{0x00})
+	assert.Error(t, err)
+}
+
+func TestTrack_AbsCaptureTime(t *testing.T) {
+	track := &Track{}
+	header := &rtp.Header{}
+	a := NewAbsCaptureTime(time.Unix(1550000000, 0))
+
+	assert.Equal(t, ErrHeaderExtensionNotNegotiated, track.SetAbsCaptureTime(header, a))
+
+	track.headerExtensions = map[string]uint8{AbsCaptureTimeURI: 3}
+	assert.NoError(t, track.SetAbsCaptureTime(header, a))
+
+	parsed, ok := track.AbsCaptureTime(header)
+	assert.True(t, ok)
+	assert.Equal(t, a, parsed)
+}
@@ -0,0 +1,63 @@
+package webrtc
+
+import (
+	"time"
+
+	"github.com/pions/rtp"
+	"github.com/pkg/errors"
+)
+
+// The abs-send-time is the NTP time a packet was sent at in seconds, a 6.18
+// fixed point number wrapping around every 64 seconds
+const (
+	absSendTimeFractionBits = 18
+	absSendTimeMask         = 1<<24 - 1
+)
+
+// toAbsSendTime converts a time.Time to the 24 bits of the abs-send-time
+func toAbsSendTime(t time.Time) uint32 {
+	return uint32(toNTPTime(t)>>(32-absSendTimeFractionBits)) & absSendTimeMask
+}
+
+func marshalAbsSendTime(sendTime uint32) []byte {
+	return []byte{byte(sendTime >> 16), byte(sendTime >> 8), byte(sendTime)}
+}
+
+func unmarshalAbsSendTime(payload []byte) (uint32, error) {
+	if len(payload) != 3 {
+		return 0, errors.Errorf("invalid abs send time extension size %d", len(payload))
+	}
+	return uint32(payload[0])<<16 | uint32(payload[1])<<8 | uint32(payload[2]), nil
+}
+
+// AbsSendTime returns the send time carried by the given header, if the
+// abs-send-time extension was negotiated for the Track and is present in the
+// packet. It is the NTP time of the sender modulo 64 seconds, with a 3.8µs
+// resolution: only the difference between the send times of two packets is
+// meaningful. The RTPSenders stamp it on every packet when it is negotiated.
+func (t *Track) AbsSendTime(header *rtp.Header) (time.Duration, bool) {
+	sendTime, ok := t.absSendTime(header)
+	if !ok {
+		return 0, false
+	}
+	return time.Duration(sendTime) * time.Second >> absSendTimeFractionBits, true
+}
+
+// absSendTime returns the 24 bits of the abs-send-time of a packet
+func (t *Track) absSendTime(header *rtp.Header) (uint32, bool) {
+	id, ok := t.headerExtensionID(AbsSendTimeURI)
+	if !ok {
+		return 0, false
+	}
+
+	payload, ok := getHeaderExtension(header, id)
+	if !ok {
+		return 0, false
+	}
+
+	sendTime, err := unmarshalAbsSendTime(payload)
+	if err != nil {
+		return 0, false
+	}
+	return sendTime, true
+}
@@ -0,0 +1,38 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pions/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAbsSendTime_Marshal(t *testing.T) {
+	// 6.18 fixed point seconds of the NTP time, modulo 64 seconds
+	now := time.Unix(1500000000, int64(500*time.Millisecond))
+	sendTime := toAbsSendTime(now)
+	assert.Equal(t, uint32(1<<17), sendTime&(1<<18-1))
+	assert.Equal(t, toAbsSendTime(now.Add(time.Second)), (sendTime+1<<18)&absSendTimeMask)
+
+	parsed, err := unmarshalAbsSendTime(marshalAbsSendTime(sendTime))
+	assert.NoError(t, err)
+	assert.Equal(t, sendTime, parsed)
+
+	_, err = unmarshalAbsSendTime([]byte{0x00})
+	assert.Error(t, err)
+}
+
+func TestTrack_AbsSendTime(t *testing.T) {
+	track := &Track{}
+	header := &rtp.Header{}
+	assert.NoError(t, setHeaderExtension(header, 3, marshalAbsSendTime(3<<18|1<<16)))
+
+	_, ok := track.AbsSendTime(header)
+	assert.False(t, ok)
+
+	track.headerExtensions = map[string]uint8{AbsSendTimeURI: 3}
+	sendTime, ok := track.AbsSendTime(header)
+	assert.True(t, ok)
+	assert.Equal(t, 3250*time.Millisecond, sendTime)
+}
@@ -0,0 +1,103 @@
+package webrtc
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// ActiveStream is a RTP stream sent or received over a DTLSTransport
+type ActiveStream struct {
+	SSRC uint32
+
+	// Direction is sendonly for a sent stream and recvonly for a received
+	// one
+	Direction RTPTransceiverDirection
+
+	// Receiver is the RTPReceiver reading a received stream, declared or
+	// latched on. It is nil for a stream of the remote nobody receives,
+	// which is drained.
+	Receiver *RTPReceiver
+
+	// Sender is the RTPSender of a sent stream
+	Sender *RTPSender
+
+	// RTX is set for the retransmission stream of the Receiver or Sender
+	RTX bool
+
+	// LastActivity is the time the last packet of the stream was sent or
+	// received, zero until the first one
+	LastActivity time.Time
+}
+
+// ActiveStreams returns the RTP streams of the transport, sorted by SSRC: the
+// ones of the running RTPSenders, the ones their RTPReceivers read and the
+// other streams the remote sent. A stream nobody receives is listed until the
+// transport is closed, so a stale LastActivity hints at a stream the remote
+// stopped or the application leaked.
+func (t *DTLSTransport) ActiveStreams() []ActiveStream {
+	latest := t.receiveTimes.latest()
+
+	t.claimedLock.RLock()
+	streams := make([]ActiveStream, 0, len(latest)+len(t.claimedSSRCs)+len(t.senders))
+	for ssrc, receiver := range t.claimedSSRCs {
+		receiver.mu.Lock()
+		rtxSSRC := receiver.rtxSSRC
+		receiver.mu.Unlock()
+
+		streams = append(streams, ActiveStream{
+			SSRC:         ssrc,
+			Direction:    RTPTransceiverDirectionRecvonly,
+			Receiver:     receiver,
+			RTX:          rtxSSRC != 0 && rtxSSRC == ssrc,
+			LastActivity: latest[ssrc],
+		})
+		delete(latest, ssrc)
+	}
+	for sender := range t.senders {
+		sender.mu.RLock()
+		rtxSSRC := sender.rtxSSRC
+		sender.mu.RUnlock()
+
+		streams = append(streams, ActiveStream{
+			SSRC:         sender.Track.SSRC,
+			Direction:    RTPTransceiverDirectionSendonly,
+			Sender:       sender,
+			LastActivity: unixNanoTime(atomic.LoadInt64(&sender.lastSent)),
+		})
+		if rtxSSRC != 0 {
+			streams = append(streams, ActiveStream{
+				SSRC:         rtxSSRC,
+				Direction:    RTPTransceiverDirectionSendonly,
+				Sender:       sender,
+				RTX:          true,
+				LastActivity: unixNanoTime(atomic.LoadInt64(&sender.lastRTXSent)),
+			})
+		}
+	}
+	t.claimedLock.RUnlock()
+
+	for ssrc, last := range latest {
+		streams = append(streams, ActiveStream{
+			SSRC:         ssrc,
+			Direction:    RTPTransceiverDirectionRecvonly,
+			LastActivity: last,
+		})
+	}
+
+	sort.Slice(streams, func(i, j int) bool {
+		if streams[i].SSRC != streams[j].SSRC {
+			return streams[i].SSRC < streams[j].SSRC
+		}
+		return streams[i].Direction < streams[j].Direction
+	})
+	return streams
+}
+
+// unixNanoTime returns the time of nanoseconds since the epoch, zero for 0
+func unixNanoTime(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
@@ -0,0 +1,117 @@
+package webrtc
+
+import (
+	"github.com/pions/sdp/v2"
+	"github.com/pions/webrtc/pkg/rtcerr"
+)
+
+// announcedReceiver is a RTPReceiver created by ReceiveSSRC, with the mid of
+// the media section its stream belongs to
+type announcedReceiver struct {
+	receiver *RTPReceiver
+	mid      string
+}
+
+// ReceiveSSRC creates the RTPReceiver of a stream whose SSRC the application
+// learned out of band, before any packet of it arrives. The stream belongs to
+// the media section mid and its RTX SSRC is taken from the RemoteDescription
+// if it declares one. The RTPReceiver is started with the media, or right
+// away once it flows, and its Track is handed to OnTrack at the first packet
+// like the ones of the streams the RemoteDescription declares.
+//
+// An incoming stream is bound, in order of precedence:
+//  1. to the RTPReceiver of its SSRC announced with ReceiveSSRC
+//  2. to the RTPReceiver of its SSRC declared by an a=ssrc line
+//  3. to the RTPReceiver of its media section and simulcast layer, named by
+//     the mid and RID header extensions
+//  4. to the RTPReceiver of the media section without a=ssrc, which latches
+//     on the first undeclared stream
+//
+// The streams of known SSRCs are claimed as soon as their RTPReceiver is
+// started, they are never latched on even if a packet arrives first.
+func (pc *PeerConnection) ReceiveSSRC(kind RTPCodecType, mid string, ssrc uint32) (*RTPReceiver, error) {
+	switch {
+	case kind != RTPCodecTypeAudio && kind != RTPCodecTypeVideo:
+		return nil, ErrUnknownType
+	case ssrc == 0:
+		return nil, ErrInvalidSSRC
+	}
+
+	pc.mu.Lock()
+	if pc.closed() {
+		pc.mu.Unlock()
+		return nil, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
+	}
+	if _, ok := pc.announcedSSRCs[ssrc]; ok {
+		pc.mu.Unlock()
+		return nil, ErrSSRCAnnounced
+	}
+	if pc.announcedSSRCs == nil {
+		pc.announcedSSRCs = map[uint32]*announcedReceiver{}
+	}
+	receiver := pc.api.NewRTPReceiver(kind, pc.dtlsTransport)
+	pc.announcedSSRCs[ssrc] = &announcedReceiver{receiver: receiver, mid: mid}
+	started := pc.announcedStarted
+	pc.mu.Unlock()
+
+	if started {
+		pc.startSSRCReceiver(receiver, ssrc, pc.remoteRTXSSRC(ssrc), mid, pc.remoteLayers(receiver.kind, mid))
+	}
+	return receiver, nil
+}
+
+// startAnnouncedReceivers starts the RTPReceivers created by ReceiveSSRC
+// once the media flows, the following ones are started right away
+func (pc *PeerConnection) startAnnouncedReceivers() {
+	pc.mu.Lock()
+	pc.announcedStarted = true
+	announced := make(map[uint32]*announcedReceiver, len(pc.announcedSSRCs))
+	for ssrc, a := range pc.announcedSSRCs {
+		announced[ssrc] = a
+	}
+	pc.mu.Unlock()
+
+	for ssrc, a := range announced {
+		pc.startSSRCReceiver(a.receiver, ssrc, pc.remoteRTXSSRC(ssrc), a.mid, pc.remoteLayers(a.receiver.kind, a.mid))
+	}
+}
+
+// isSSRCAnnounced returns whether a RTPReceiver was created for the SSRC by
+// ReceiveSSRC
+func (pc *PeerConnection) isSSRCAnnounced(ssrc uint32) bool {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	_, ok := pc.announcedSSRCs[ssrc]
+	return ok
+}
+
+// remoteRTXSSRC returns the RTX SSRC the RemoteDescription declares for a
+// media SSRC, 0 if none
+func (pc *PeerConnection) remoteRTXSSRC(ssrc uint32) uint32 {
+	desc := pc.RemoteDescription()
+	if desc == nil || desc.parsed == nil {
+		return 0
+	}
+	for _, media := range desc.parsed.MediaDescriptions {
+		if rtx, ok := rtxSSRCsFromMedia(media)[ssrc]; ok {
+			return rtx
+		}
+	}
+	return 0
+}
+
+// remoteLayers returns the received simulcast layers of the media section of
+// the RemoteDescription with the mid, nil if it has none
+func (pc *PeerConnection) remoteLayers(kind RTPCodecType, mid string) map[string]RIDRestrictions {
+	desc := pc.RemoteDescription()
+	if desc == nil || desc.parsed == nil || mid == "" {
+		return nil
+	}
+	_, ridNegotiated := pc.negotiatedHeaderExtensions(kind)[RIDURI]
+	for _, media := range desc.parsed.MediaDescriptions {
+		if m, _ := media.Attribute(sdp.AttrKeyMID); m == mid {
+			return pc.receivedLayers(media, ridNegotiated)
+		}
+	}
+	return nil
+}
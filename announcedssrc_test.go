@@ -0,0 +1,32 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeerConnection_ReceiveSSRC(t *testing.T) {
+	pc, err := NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = pc.ReceiveSSRC(RTPCodecTypeVideo, "video", 0)
+	assert.Equal(t, ErrInvalidSSRC, err)
+	_, err = pc.ReceiveSSRC(RTPCodecType(0), "video", 1234)
+	assert.Equal(t, ErrUnknownType, err)
+
+	receiver, err := pc.ReceiveSSRC(RTPCodecTypeAudio, "audio", 1234)
+	if assert.NoError(t, err) {
+		assert.Equal(t, RTPCodecTypeAudio, receiver.kind)
+		assert.True(t, pc.isSSRCAnnounced(1234))
+	}
+	_, err = pc.ReceiveSSRC(RTPCodecTypeVideo, "video", 1234)
+	assert.Equal(t, ErrSSRCAnnounced, err)
+	assert.False(t, pc.isSSRCAnnounced(5678))
+
+	assert.NoError(t, pc.Close())
+	_, err = pc.ReceiveSSRC(RTPCodecTypeVideo, "video", 5678)
+	assert.Error(t, err)
+}
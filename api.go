@@ -1,5 +1,9 @@
 package webrtc
 
+import (
+	"github.com/pions/webrtc/pkg/logging"
+)
+
 // API bundles the global funcions of the WebRTC and ORTC API.
 // Some of these functions are also exported globally using the
 // defaultAPI object. Note that the global version of the API
@@ -7,11 +11,25 @@ package webrtc
 type API struct {
 	settingEngine *SettingEngine
 	mediaEngine   *MediaEngine
+
+	// log and iceLog are the loggers of the pc and ice scopes the objects
+	// created by the API log through
+	log    logging.LeveledLogger
+	iceLog logging.LeveledLogger
+
+	// iceConnClaimed is accessed atomically, it is set once an ICEGatherer
+	// gathers on the ICE conn of the SettingEngine. It is shared by the
+	// copies of the API tagging the logs.
+	iceConnClaimed *int32
+
+	// interceptors are bound to the streams in order, set by
+	// WithInterceptors
+	interceptors []Interceptor
 }
 
 // NewAPI Creates a new API object for keeping semi-global settings to WebRTC objects
 func NewAPI(options ...func(*API)) *API {
-	a := &API{}
+	a := &API{iceConnClaimed: new(int32)}
 
 	for _, o := range options {
 		o(a)
@@ -25,6 +43,10 @@ func NewAPI(options ...func(*API)) *API {
 		a.mediaEngine = &MediaEngine{}
 	}
 
+	factory := a.settingEngine.loggerFactory()
+	a.log = factory.NewLogger("pc")
+	a.iceLog = factory.NewLogger("ice")
+
 	return a
 }
 
@@ -0,0 +1,77 @@
+package webrtc
+
+import (
+	"github.com/pions/rtp"
+	"github.com/pkg/errors"
+)
+
+const (
+	audioLevelVoiceBit = 0x80
+
+	// audioLevelMax is the level of silence, -127dBov
+	audioLevelMax = 127
+)
+
+// AudioLevel is the level of the audio of a packet, as carried by the
+// client-to-mixer audio level extension https://tools.ietf.org/html/rfc6464
+type AudioLevel struct {
+	// Level is the magnitude of the audio in -dBov, from 0 for the loudest
+	// to 127 for silence
+	Level uint8
+
+	// Voice is set when the packet was detected to contain speech
+	Voice bool
+}
+
+func (a AudioLevel) marshal() []byte {
+	b := a.Level
+	if a.Voice {
+		b |= audioLevelVoiceBit
+	}
+	return []byte{b}
+}
+
+func unmarshalAudioLevel(payload []byte) (AudioLevel, error) {
+	// The two-byte form pads the level to 4 bytes
+	if len(payload) == 0 {
+		return AudioLevel{}, errors.New("empty audio level extension")
+	}
+	return AudioLevel{Level: payload[0] &^ audioLevelVoiceBit, Voice: payload[0]&audioLevelVoiceBit != 0}, nil
+}
+
+// AudioLevel returns the audio level carried by the given header, if the
+// ssrc-audio-level extension was negotiated for the Track and is present in
+// the packet
+func (t *Track) AudioLevel(header *rtp.Header) (AudioLevel, bool) {
+	id, ok := t.headerExtensionID(AudioLevelURI)
+	if !ok {
+		return AudioLevel{}, false
+	}
+
+	payload, ok := getHeaderExtension(header, id)
+	if !ok {
+		return AudioLevel{}, false
+	}
+
+	a, err := unmarshalAudioLevel(payload)
+	if err != nil {
+		return AudioLevel{}, false
+	}
+	return a, true
+}
+
+// SetAudioLevel writes the audio level into the header of a packet sent on a
+// raw RTP Track, the level of the audio is left to the application to
+// measure. ErrHeaderExtensionNotNegotiated is returned if the
+// ssrc-audio-level extension wasn't negotiated, ErrInvalidAudioLevel if the
+// level exceeds 127.
+func (t *Track) SetAudioLevel(header *rtp.Header, a AudioLevel) error {
+	if a.Level > audioLevelMax {
+		return ErrInvalidAudioLevel
+	}
+	id, ok := t.headerExtensionID(AudioLevelURI)
+	if !ok {
+		return ErrHeaderExtensionNotNegotiated
+	}
+	return setHeaderExtension(header, id, a.marshal())
+}
@@ -0,0 +1,42 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAudioLevel_Marshal(t *testing.T) {
+	for _, a := range []AudioLevel{{}, {Level: 127}, {Level: 30, Voice: true}} {
+		parsed, err := unmarshalAudioLevel(a.marshal())
+		assert.NoError(t, err)
+		assert.Equal(t, a, parsed)
+	}
+	assert.Equal(t, []byte{0x9E}, AudioLevel{Level: 30, Voice: true}.marshal())
+
+	// The padding of the two-byte form is ignored
+	parsed, err := unmarshalAudioLevel([]byte{0x1E, 0x00, 0x00, 0x00})
+	assert.NoError(t, err)
+	assert.Equal(t, AudioLevel{Level: 30}, parsed)
+
+	_, err = unmarshalAudioLevel(nil)
+	assert.Error(t, err)
+}
+
+func TestTrack_AudioLevel(t *testing.T) {
+	track := &Track{}
+	header := &rtp.Header{}
+
+	assert.Equal(t, ErrHeaderExtensionNotNegotiated, track.SetAudioLevel(header, AudioLevel{Level: 10}))
+	_, ok := track.AudioLevel(header)
+	assert.False(t, ok)
+
+	track.headerExtensions = map[string]uint8{AudioLevelURI: 1}
+	assert.Equal(t, ErrInvalidAudioLevel, track.SetAudioLevel(header, AudioLevel{Level: 128}))
+	assert.NoError(t, track.SetAudioLevel(header, AudioLevel{Level: 10, Voice: true}))
+
+	a, ok := track.AudioLevel(header)
+	assert.True(t, ok)
+	assert.Equal(t, AudioLevel{Level: 10, Voice: true}, a)
+}
@@ -0,0 +1,32 @@
+package webrtc
+
+import (
+	"github.com/pions/sdp/v2"
+)
+
+// bandwidthTypeAS is the application specific maximum bandwidth modifier of
+// a b= line, in kilobits per second
+// https://tools.ietf.org/html/rfc4566#section-5.8
+const bandwidthTypeAS = "AS"
+
+// bandwidthLines returns the b=AS line announcing a bitrate in bits per
+// second, rounded up to the kilobit.
+//
+// TODO emit b=TIAS as well once the SDP parser accepts it, pions/sdp only
+// knows the CT and AS modifiers and rejects descriptions with other ones
+func bandwidthLines(bitrate uint64) []sdp.Bandwidth {
+	return []sdp.Bandwidth{
+		{Type: bandwidthTypeAS, Bandwidth: (bitrate + 999) / 1000},
+	}
+}
+
+// maxBitrateFromBandwidth returns the bitrate in bits per second of the b=AS
+// line among b= lines, 0 is returned without one
+func maxBitrateFromBandwidth(lines []sdp.Bandwidth) uint64 {
+	for _, b := range lines {
+		if !b.Experimental && b.Type == bandwidthTypeAS {
+			return b.Bandwidth * 1000
+		}
+	}
+	return 0
+}
@@ -0,0 +1,33 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/sdp/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBandwidthLines(t *testing.T) {
+	assert.Equal(t, []sdp.Bandwidth{{Type: "AS", Bandwidth: 501}}, bandwidthLines(500500))
+}
+
+func TestMaxBitrateFromBandwidth(t *testing.T) {
+	testCases := []struct {
+		lines    []sdp.Bandwidth
+		expected uint64
+	}{
+		{nil, 0},
+		{[]sdp.Bandwidth{{Type: "AS", Bandwidth: 256}}, 256000},
+		{[]sdp.Bandwidth{{Type: "CT", Bandwidth: 1000}, {Type: "AS", Bandwidth: 64}}, 64000},
+		{[]sdp.Bandwidth{{Type: "CT", Bandwidth: 1000}}, 0},
+		{[]sdp.Bandwidth{{Experimental: true, Type: "AS", Bandwidth: 1000}}, 0},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expected,
+			maxBitrateFromBandwidth(testCase.lines),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
@@ -0,0 +1,104 @@
+package webrtc
+
+import (
+	"sync"
+
+	"github.com/pions/rtcp"
+)
+
+// Bounds of the bandwidth estimate when none are set in the SettingEngine, in bits per second
+const (
+	defaultMinBitrate   = 30000
+	defaultStartBitrate = 300000
+	defaultMaxBitrate   = 2500000
+)
+
+// The thresholds and factors of the loss-based controller of
+// https://tools.ietf.org/html/draft-ietf-rmcat-gcc-02#section-6
+const (
+	lossIncreaseThreshold = 0.02
+	lossDecreaseThreshold = 0.1
+	lossIncreaseFactor    = 1.05
+)
+
+// lossBasedBandwidthEstimator estimates the available send bandwidth from the
+// fraction of lost packets in the reception reports of the remote
+type lossBasedBandwidthEstimator struct {
+	mu       sync.Mutex
+	min, max int
+	estimate int
+}
+
+func newLossBasedBandwidthEstimator(min, max int) *lossBasedBandwidthEstimator {
+	e := &lossBasedBandwidthEstimator{min: min, max: max}
+	e.estimate = e.clamp(defaultStartBitrate)
+	return e
+}
+
+func (e *lossBasedBandwidthEstimator) clamp(bitrate int) int {
+	switch {
+	case bitrate < e.min:
+		return e.min
+	case bitrate > e.max:
+		return e.max
+	}
+	return bitrate
+}
+
+// limit lowers the maximum of the estimate to the bitrate the remote asked
+// not to exceed, the minimum is kept if the bitrate is lower
+func (e *lossBasedBandwidthEstimator) limit(bitrate uint64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if bitrate < uint64(e.max) {
+		e.max = int(bitrate)
+		if e.max < e.min {
+			e.max = e.min
+		}
+	}
+	e.estimate = e.clamp(e.estimate)
+}
+
+// bitrate returns the current estimate
+func (e *lossBasedBandwidthEstimator) bitrate() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.estimate
+}
+
+// onReceptionReport updates the estimate with the loss of a report and
+// returns the new estimate
+func (e *lossBasedBandwidthEstimator) onReceptionReport(report rtcp.ReceptionReport) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// FractionLost is a fixed point number with the binary point at the left
+	loss := float64(report.FractionLost) / 256
+	switch {
+	case loss < lossIncreaseThreshold:
+		e.estimate = e.clamp(int(float64(e.estimate) * lossIncreaseFactor))
+	case loss > lossDecreaseThreshold:
+		e.estimate = e.clamp(int(float64(e.estimate) * (1 - 0.5*loss)))
+	}
+	return e.estimate
+}
+
+// receptionReportsFor returns the reception blocks of a RTCP packet about the given SSRC
+func receptionReportsFor(packet rtcp.Packet, ssrc uint32) []rtcp.ReceptionReport {
+	var reports []rtcp.ReceptionReport
+	switch p := packet.(type) {
+	case *rtcp.ReceiverReport:
+		reports = p.Reports
+	case *rtcp.SenderReport:
+		reports = p.Reports
+	}
+
+	var out []rtcp.ReceptionReport
+	for _, r := range reports {
+		if r.SSRC == ssrc {
+			out = append(out, r)
+		}
+	}
+	return out
+}
@@ -0,0 +1,63 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLossBasedBandwidthEstimator(t *testing.T) {
+	e := newLossBasedBandwidthEstimator(100000, 400000)
+	assert.Equal(t, defaultStartBitrate, e.estimate)
+
+	// Low loss increases the estimate
+	assert.Equal(t, 315000, e.onReceptionReport(rtcp.ReceptionReport{FractionLost: 0}))
+
+	// Moderate loss holds it
+	assert.Equal(t, 315000, e.onReceptionReport(rtcp.ReceptionReport{FractionLost: 13}))
+
+	// High loss decreases it, 25% loss takes off 12.5%
+	assert.Equal(t, 275625, e.onReceptionReport(rtcp.ReceptionReport{FractionLost: 64}))
+
+	// The estimate is clamped to the bounds
+	for i := 0; i < 20; i++ {
+		e.onReceptionReport(rtcp.ReceptionReport{FractionLost: 255})
+	}
+	assert.Equal(t, 100000, e.estimate)
+
+	for i := 0; i < 100; i++ {
+		e.onReceptionReport(rtcp.ReceptionReport{FractionLost: 0})
+	}
+	assert.Equal(t, 400000, e.estimate)
+
+	// The start bitrate is clamped as well
+	assert.Equal(t, 500000, newLossBasedBandwidthEstimator(500000, 600000).estimate)
+}
+
+func TestLossBasedBandwidthEstimator_Limit(t *testing.T) {
+	e := newLossBasedBandwidthEstimator(100000, 400000)
+
+	// The limit of the remote lowers the maximum and the estimate
+	e.limit(250000)
+	assert.Equal(t, 250000, e.estimate)
+	for i := 0; i < 100; i++ {
+		e.onReceptionReport(rtcp.ReceptionReport{FractionLost: 0})
+	}
+	assert.Equal(t, 250000, e.estimate)
+
+	// A higher limit doesn't raise it and the minimum is kept
+	e.limit(1000000)
+	assert.Equal(t, 250000, e.max)
+	e.limit(1000)
+	assert.Equal(t, 100000, e.estimate)
+}
+
+func TestReceptionReportsFor(t *testing.T) {
+	reports := []rtcp.ReceptionReport{{SSRC: 1, FractionLost: 1}, {SSRC: 2, FractionLost: 2}}
+
+	assert.Equal(t, reports[1:], receptionReportsFor(&rtcp.ReceiverReport{Reports: reports}, 2))
+	assert.Equal(t, reports[:1], receptionReportsFor(&rtcp.SenderReport{Reports: reports}, 1))
+	assert.Empty(t, receptionReportsFor(&rtcp.ReceiverReport{Reports: reports}, 3))
+	assert.Empty(t, receptionReportsFor(&rtcp.PictureLossIndication{MediaSSRC: 1}, 1))
+}
@@ -5,10 +5,12 @@ import (
 	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"math/big"
 	"time"
 
@@ -27,20 +29,24 @@ type Certificate struct {
 // GenerateCertificate by allowing to specify a template x509.Certificate to
 // be used in order to define certificate parameters.
 func NewCertificate(key crypto.PrivateKey, tpl x509.Certificate) (*Certificate, error) {
+	return newCertificate(key, tpl, rand.Reader)
+}
+
+func newCertificate(key crypto.PrivateKey, tpl x509.Certificate, random io.Reader) (*Certificate, error) {
 	var err error
 	var certDER []byte
 	switch sk := key.(type) {
 	case *rsa.PrivateKey:
 		pk := sk.Public()
 		tpl.SignatureAlgorithm = x509.SHA256WithRSA
-		certDER, err = x509.CreateCertificate(rand.Reader, &tpl, &tpl, pk, sk)
+		certDER, err = x509.CreateCertificate(random, &tpl, &tpl, pk, sk)
 		if err != nil {
 			return nil, &rtcerr.UnknownError{Err: err}
 		}
 	case *ecdsa.PrivateKey:
 		pk := sk.Public()
 		tpl.SignatureAlgorithm = x509.ECDSAWithSHA256
-		certDER, err = x509.CreateCertificate(rand.Reader, &tpl, &tpl, pk, sk)
+		certDER, err = x509.CreateCertificate(random, &tpl, &tpl, pk, sk)
 		if err != nil {
 			return nil, &rtcerr.UnknownError{Err: err}
 		}
@@ -56,6 +62,45 @@ func NewCertificate(key crypto.PrivateKey, tpl x509.Certificate) (*Certificate,
 	return &Certificate{privateKey: key, x509Cert: cert}, nil
 }
 
+// CertificateFromTLS builds the Certificate of a tls.Certificate, such as a
+// long-lived one loaded with tls.LoadX509KeyPair for a persistent identity
+// whose fingerprint the remotes already know. Only the leaf certificate is
+// used, its private key has to be an RSA or an ECDSA one.
+func CertificateFromTLS(certificate tls.Certificate) (*Certificate, error) {
+	switch certificate.PrivateKey.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey:
+	default:
+		return nil, &rtcerr.NotSupportedError{Err: ErrPrivateKeyType}
+	}
+
+	cert := certificate.Leaf
+	if cert == nil {
+		if len(certificate.Certificate) == 0 {
+			return nil, &rtcerr.InvalidAccessError{Err: ErrNoCertificate}
+		}
+		var err error
+		if cert, err = x509.ParseCertificate(certificate.Certificate[0]); err != nil {
+			return nil, &rtcerr.InvalidAccessError{Err: err}
+		}
+	}
+
+	return &Certificate{privateKey: certificate.PrivateKey, x509Cert: cert}, nil
+}
+
+// TLSCertificate returns the certificate and its private key as a
+// tls.Certificate, for a generated certificate to be kept and used again
+// with CertificateFromTLS.
+func (c Certificate) TLSCertificate() tls.Certificate {
+	if c.x509Cert == nil {
+		return tls.Certificate{PrivateKey: c.privateKey}
+	}
+	return tls.Certificate{
+		Certificate: [][]byte{c.x509Cert.Raw},
+		PrivateKey:  c.privateKey,
+		Leaf:        c.x509Cert,
+	}
+}
+
 // Equals determines if two certificates are identical by comparing both the
 // secretKeys and x509Certificates.
 func (c Certificate) Equals(o Certificate) bool {
@@ -93,31 +138,36 @@ var fingerprintAlgorithms = []dtls.HashAlgorithm{dtls.HashAlgorithmSHA256}
 
 // GetFingerprints returns the list of certificate fingerprints, one of which
 // is computed with the digest algorithm used in the certificate signature.
+// They are the fingerprints of the descriptions of a PeerConnection using
+// the certificate, known before its offer is created.
 func (c Certificate) GetFingerprints() []DTLSFingerprint {
-	res := make([]DTLSFingerprint, len(fingerprintAlgorithms))
+	res := make([]DTLSFingerprint, 0, len(fingerprintAlgorithms))
 
-	i := 0
 	for _, algo := range fingerprintAlgorithms {
 		value, err := dtls.Fingerprint(c.x509Cert, algo)
 		if err != nil {
 			fmt.Printf("Failed to create fingerprint: %v\n", err)
 			continue
 		}
-		res[i] = DTLSFingerprint{
+		res = append(res, DTLSFingerprint{
 			Algorithm: algo.String(),
 			Value:     value,
-		}
+		})
 	}
 
-	return res[:i+1]
+	return res
 }
 
 // GenerateCertificate causes the creation of an X.509 certificate and
 // corresponding private key.
 func GenerateCertificate(secretKey crypto.PrivateKey) (*Certificate, error) {
+	return generateCertificate(secretKey, rand.Reader)
+}
+
+func generateCertificate(secretKey crypto.PrivateKey, random io.Reader) (*Certificate, error) {
 	origin := make([]byte, 16)
 	/* #nosec */
-	if _, err := rand.Read(origin); err != nil {
+	if _, err := io.ReadFull(random, origin); err != nil {
 		return nil, &rtcerr.UnknownError{Err: err}
 	}
 
@@ -126,12 +176,12 @@ func GenerateCertificate(secretKey crypto.PrivateKey) (*Certificate, error) {
 	/* #nosec */
 	maxBigInt.Exp(big.NewInt(2), big.NewInt(130), nil).Sub(maxBigInt, big.NewInt(1))
 	/* #nosec */
-	serialNumber, err := rand.Int(rand.Reader, maxBigInt)
+	serialNumber, err := rand.Int(random, maxBigInt)
 	if err != nil {
 		return nil, &rtcerr.UnknownError{Err: err}
 	}
 
-	return NewCertificate(secretKey, x509.Certificate{
+	return newCertificate(secretKey, x509.Certificate{
 		ExtKeyUsage: []x509.ExtKeyUsage{
 			x509.ExtKeyUsageClientAuth,
 			x509.ExtKeyUsageServerAuth,
@@ -144,5 +194,5 @@ func GenerateCertificate(secretKey crypto.PrivateKey) (*Certificate, error) {
 		Version:               2,
 		Subject:               pkix.Name{CommonName: hex.EncodeToString(origin)},
 		IsCA:                  true,
-	})
+	}, random)
 }
@@ -86,3 +86,34 @@ func TestGenerateCertificateExpires(t *testing.T) {
 	now := time.Now()
 	assert.False(t, cert.Expires().IsZero() || now.After(cert.Expires()))
 }
+
+func TestCertificateFromTLS(t *testing.T) {
+	sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	cert, err := GenerateCertificate(sk)
+	assert.Nil(t, err)
+
+	// A certificate kept as PEM is loaded again
+	skDER, err := x509.MarshalECPrivateKey(sk)
+	assert.Nil(t, err)
+	tlsCert, err := tls.X509KeyPair(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.TLSCertificate().Certificate[0]}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: skDER}),
+	)
+	assert.Nil(t, err)
+
+	loaded, err := CertificateFromTLS(tlsCert)
+	assert.Nil(t, err)
+	assert.True(t, cert.Equals(*loaded))
+	assert.Equal(t, cert.GetFingerprints(), loaded.GetFingerprints())
+
+	loaded, err = CertificateFromTLS(cert.TLSCertificate())
+	assert.Nil(t, err)
+	assert.True(t, cert.Equals(*loaded))
+
+	_, err = CertificateFromTLS(tls.Certificate{PrivateKey: sk})
+	assert.Error(t, err)
+	_, err = CertificateFromTLS(tls.Certificate{Certificate: tlsCert.Certificate})
+	assert.Error(t, err)
+}
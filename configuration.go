@@ -13,7 +13,10 @@ type Configuration struct {
 	ICEServers []ICEServer
 
 	// ICETransportPolicy indicates which candidates the ICEAgent is allowed
-	// to use.
+	// to use. Changing it with SetConfiguration gathers the candidates again
+	// before the first description is set, such as to widen a gathering of
+	// host candidates to the server reflexive and relay ones. Afterwards it
+	// applies to the gathering of the next ICE restart.
 	ICETransportPolicy ICETransportPolicy
 
 	// BundlePolicy indicates which media-bundling policy to use when gathering
@@ -24,6 +27,10 @@ type Configuration struct {
 	// candidates.
 	RTCPMuxPolicy RTCPMuxPolicy
 
+	// SDPSemantics indicates how the RTPTransceivers are mapped to the media
+	// sections of the offers and answers, unified-plan by default.
+	SDPSemantics SDPSemantics
+
 	// PeerIdentity sets the target peer identity for the PeerConnection.
 	// The PeerConnection will not establish a connection to a remote peer
 	// unless it can be successfully authenticated with the provided name.
@@ -31,11 +38,12 @@ type Configuration struct {
 
 	// Certificates describes a set of certificates that the PeerConnection
 	// uses to authenticate. Valid values for this parameter are created
-	// through calls to the GenerateCertificate function. Although any given
-	// DTLS connection will use only one certificate, this attribute allows the
-	// caller to provide multiple certificates that support different
-	// algorithms. The final certificate will be selected based on the DTLS
-	// handshake, which establishes which certificates are allowed. The
+	// through calls to the GenerateCertificate function, with an ECDSA or an
+	// RSA key, or with CertificateFromTLS. Although any given DTLS connection
+	// will use only one certificate, this attribute allows the caller to
+	// provide multiple certificates that support different algorithms. The
+	// final certificate will be selected based on the DTLS handshake, which
+	// establishes which certificates are allowed. The
 	// PeerConnection implementation selects which of the certificates is
 	// used for a given connection; how certificates are selected is outside
 	// the scope of this specification. If this value is absent, then a default
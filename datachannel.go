@@ -1,21 +1,39 @@
 package webrtc
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"sync"
+	"sync/atomic"
 
 	"github.com/pions/datachannel"
+	"github.com/pions/sctp"
 	"github.com/pions/webrtc/pkg/rtcerr"
 	"github.com/pkg/errors"
 )
 
 const dataChannelBufferSize = 16384 // Lowest common denominator among browsers
 
+// dataChannelConn is what a DataChannel reads and writes its messages on: a
+// pions/datachannel announced in-band, or a negotiatedDataChannel
+type dataChannelConn interface {
+	ReadDataChannel(p []byte) (int, bool, error)
+	WriteDataChannel(p []byte, isString bool) (int, error)
+	Close() error
+}
+
 // DataChannel represents a WebRTC DataChannel
 // The DataChannel interface represents a network channel
 // which can be used for bidirectional peer-to-peer transfers of arbitrary data
 type DataChannel struct {
+	// Accessed atomically, kept first for the 64-bit alignment required on
+	// 32-bit platforms. They count the messages of the data-channel stats.
+	messagesSent     uint64
+	bytesSent        uint64
+	messagesReceived uint64
+	bytesReceived    uint64
+
 	mu sync.RWMutex
 
 	// Label represents a label that can be used to distinguish this
@@ -40,7 +58,11 @@ type DataChannel struct {
 	Protocol string
 
 	// Negotiated represents whether this DataChannel was negotiated by the
-	// application (true), or not (false).
+	// application (true), or not (false). A negotiated DataChannel isn't
+	// announced to the remote peer, which has to create a DataChannel with
+	// the same ID. The messages sent before it did are kept for it if it
+	// was created before the connection was established, otherwise they
+	// are lost.
 	Negotiated bool
 
 	// ID represents the ID for this DataChannel. The value is initially
@@ -58,17 +80,9 @@ type DataChannel struct {
 	// ReadyState represents the state of the DataChannel object.
 	ReadyState DataChannelState
 
-	// BufferedAmount represents the number of bytes of application data
-	// (UTF-8 text and binary data) that have been queued using send(). Even
-	// though the data transmission can occur in parallel, the returned value
-	// MUST NOT be decreased before the current task yielded back to the event
-	// loop to prevent race conditions. The value does not include framing
-	// overhead incurred by the protocol, or buffering done by the operating
-	// system or network hardware. The value of BufferedAmount slot will only
-	// increase with each call to the send() method as long as the ReadyState is
-	// open; however, BufferedAmount does not reset to zero once the channel
-	// closes.
-	BufferedAmount uint64
+	// bufferedAmount counts the bytes of the messages Send is handing to
+	// the SCTP association, see BufferedAmount
+	bufferedAmount uint64
 
 	// BufferedAmountLowThreshold represents the threshold at which the
 	// bufferedAmount is considered to be low. When the bufferedAmount decreases
@@ -85,15 +99,19 @@ type DataChannel struct {
 	// "blob". This attribute controls how binary data is exposed to scripts.
 	// binaryType                 string
 
-	// OnBufferedAmountLow func()
 	// OnError             func()
 
-	onMessageHandler func(DataChannelMessage)
-	onOpenHandler    func()
-	onCloseHandler   func()
+	onMessageHandler           func(DataChannelMessage)
+	onOpenHandler              func()
+	onCloseHandler             func()
+	onBufferedAmountLowHandler func()
+
+	// onClosedHandler is invoked once the DataChannel is closed, the
+	// PeerConnection forgets the channels the remote opened with it
+	onClosedHandler func(*DataChannel)
 
 	sctpTransport *SCTPTransport
-	dataChannel   *datachannel.DataChannel
+	dataChannel   dataChannelConn
 
 	// A reference to the associated api object used by this datachannel
 	api *API
@@ -130,6 +148,7 @@ func (api *API) newDataChannel(params *DataChannelParameters) (*DataChannel, err
 		Ordered:           params.Ordered,
 		MaxPacketLifeTime: params.MaxPacketLifeTime,
 		MaxRetransmits:    params.MaxRetransmits,
+		Negotiated:        params.Negotiated,
 		ReadyState:        DataChannelStateConnecting,
 		api:               api,
 	}, nil
@@ -145,41 +164,31 @@ func (d *DataChannel) open(sctpTransport *SCTPTransport) error {
 		return err
 	}
 
-	var channelType datachannel.ChannelType
-	var reliabilityParameteer uint32
-
-	switch {
-	case d.MaxPacketLifeTime == nil && d.MaxRetransmits == nil:
-		if d.Ordered {
-			channelType = datachannel.ChannelTypeReliable
-		} else {
-			channelType = datachannel.ChannelTypeReliableUnordered
+	// The remote may have opened the stream of a negotiated DataChannel
+	// already, the DataChannel is unlocked as it can be opened by
+	// acceptDataChannels meanwhile
+	if d.Negotiated {
+		d.mu.RUnlock()
+		if stream := sctpTransport.negotiatedStream(d); stream != nil {
+			d.openNegotiated(stream)
 		}
+		return nil
+	}
 
-	case d.MaxRetransmits != nil:
-		reliabilityParameteer = uint32(*d.MaxRetransmits)
-		if d.Ordered {
-			channelType = datachannel.ChannelTypePartialReliableRexmit
-		} else {
-			channelType = datachannel.ChannelTypePartialReliableRexmitUnordered
-		}
-	default:
-		reliabilityParameteer = uint32(*d.MaxPacketLifeTime)
-		if d.Ordered {
-			channelType = datachannel.ChannelTypePartialReliableTimed
-		} else {
-			channelType = datachannel.ChannelTypePartialReliableTimedUnordered
-		}
+	stream, err := d.sctpTransport.association.OpenStream(*d.ID, sctp.PayloadTypeWebRTCBinary)
+	if err != nil {
+		d.mu.RUnlock()
+		return err
 	}
+	stream.SetReliabilityParams(d.reliabilityParams())
 
-	cfg := &datachannel.Config{
+	channelType, reliabilityParameter := d.channelType()
+	dc, err := datachannel.Client(stream, &datachannel.Config{
 		ChannelType:          channelType,
 		Priority:             datachannel.ChannelPriorityNormal, // TODO: Wiring
-		ReliabilityParameter: reliabilityParameteer,
+		ReliabilityParameter: reliabilityParameter,
 		Label:                d.Label,
-	}
-
-	dc, err := datachannel.Dial(d.sctpTransport.association, *d.ID, cfg)
+	})
 	if err != nil {
 		d.mu.RUnlock()
 		return err
@@ -192,6 +201,53 @@ func (d *DataChannel) open(sctpTransport *SCTPTransport) error {
 	return nil
 }
 
+// openNegotiated opens the negotiated DataChannel on its SCTP stream
+func (d *DataChannel) openNegotiated(stream *sctp.Stream) {
+	d.mu.Lock()
+	stream.SetReliabilityParams(d.reliabilityParams())
+	d.ReadyState = DataChannelStateOpen
+	d.mu.Unlock()
+
+	d.handleOpen(&negotiatedDataChannel{stream: stream})
+}
+
+// channelType returns the DCEP channel type and reliability parameter
+// announcing the reliability of the DataChannel
+func (d *DataChannel) channelType() (datachannel.ChannelType, uint32) {
+	switch {
+	case d.MaxRetransmits != nil:
+		if d.Ordered {
+			return datachannel.ChannelTypePartialReliableRexmit, uint32(*d.MaxRetransmits)
+		}
+		return datachannel.ChannelTypePartialReliableRexmitUnordered, uint32(*d.MaxRetransmits)
+
+	case d.MaxPacketLifeTime != nil:
+		if d.Ordered {
+			return datachannel.ChannelTypePartialReliableTimed, uint32(*d.MaxPacketLifeTime)
+		}
+		return datachannel.ChannelTypePartialReliableTimedUnordered, uint32(*d.MaxPacketLifeTime)
+
+	default:
+		if d.Ordered {
+			return datachannel.ChannelTypeReliable, 0
+		}
+		return datachannel.ChannelTypeReliableUnordered, 0
+	}
+}
+
+// reliabilityParams returns the parameters of sctp.Stream.SetReliabilityParams
+// the messages of the DataChannel are sent with
+func (d *DataChannel) reliabilityParams() (unordered bool, relType byte, relVal uint32) {
+	switch {
+	case d.MaxRetransmits != nil:
+		return !d.Ordered, sctp.ReliabilityTypeRexmit, uint32(*d.MaxRetransmits)
+	case d.MaxPacketLifeTime != nil:
+		return !d.Ordered, sctp.ReliabilityTypeTimed, uint32(*d.MaxPacketLifeTime)
+	default:
+		return !d.Ordered, sctp.ReliabilityTypeReliable, 0
+	}
+}
+
 func (d *DataChannel) ensureSCTP() error {
 	if d.sctpTransport == nil ||
 		d.sctpTransport.association == nil {
@@ -262,6 +318,54 @@ func (d *DataChannel) onClose() (done chan struct{}) {
 	return
 }
 
+// BufferedAmount represents the number of bytes of application data
+// (UTF-8 text and binary data) that have been queued using send(). Even
+// though the data transmission can occur in parallel, the returned value
+// MUST NOT be decreased before the current task yielded back to the event
+// loop to prevent race conditions. The value does not include framing
+// overhead incurred by the protocol, or buffering done by the operating
+// system or network hardware. The value of BufferedAmount slot will only
+// increase with each call to the send() method as long as the ReadyState is
+// open; however, BufferedAmount does not reset to zero once the channel
+// closes.
+// The SCTP association takes a message before Send returns and doesn't
+// report the bytes it has yet to send, so BufferedAmount only counts the
+// messages of concurrent calls to Send.
+func (d *DataChannel) BufferedAmount() uint64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.bufferedAmount
+}
+
+// OnBufferedAmountLow sets an event handler which is invoked when the
+// BufferedAmount decreases from above the BufferedAmountLowThreshold to
+// equal or below it.
+func (d *DataChannel) OnBufferedAmountLow(f func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onBufferedAmountLowHandler = f
+}
+
+func (d *DataChannel) onBufferedAmountLow() (done chan struct{}) {
+	d.mu.RLock()
+	hdlr := d.onBufferedAmountLowHandler
+	d.mu.RUnlock()
+
+	done = make(chan struct{})
+	if hdlr == nil {
+		close(done)
+		return
+	}
+
+	go func() {
+		hdlr()
+		close(done)
+	}()
+
+	return
+}
+
 // DataChannelMessage represents a message received from the
 // data channel. IsString will be set to true if the incoming
 // message is of the string type. Otherwise the message is of
@@ -294,7 +398,7 @@ func (d *DataChannel) onMessage(msg DataChannelMessage) {
 	hdlr(msg)
 }
 
-func (d *DataChannel) handleOpen(dc *datachannel.DataChannel) {
+func (d *DataChannel) handleOpen(dc dataChannelConn) {
 	d.mu.Lock()
 	d.dataChannel = dc
 	d.mu.Unlock()
@@ -304,7 +408,8 @@ func (d *DataChannel) handleOpen(dc *datachannel.DataChannel) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	if !d.api.settingEngine.detach.DataChannels {
+	// A negotiated DataChannel can't be detached
+	if !d.api.settingEngine.detach.DataChannels || d.Negotiated {
 		go d.readLoop()
 	}
 }
@@ -314,13 +419,17 @@ func (d *DataChannel) readLoop() {
 		buffer := make([]byte, dataChannelBufferSize)
 		n, isString, err := d.dataChannel.ReadDataChannel(buffer)
 		if err == io.ErrShortBuffer {
-			pcLog.Warnf("Failed to read from data channel: The message is larger than %d bytes.\n", dataChannelBufferSize)
+			d.api.log.Warnf("Failed to read from data channel: The message is larger than %d bytes.\n", dataChannelBufferSize)
 			continue
 		}
 		if err != nil {
 			d.mu.Lock()
 			d.ReadyState = DataChannelStateClosed
+			closedHdlr := d.onClosedHandler
 			d.mu.Unlock()
+			if closedHdlr != nil {
+				closedHdlr(d)
+			}
 			if err != io.EOF {
 				// TODO: Throw OnError
 				fmt.Println("Failed to read from data channel", err)
@@ -329,6 +438,8 @@ func (d *DataChannel) readLoop() {
 			return
 		}
 
+		atomic.AddUint64(&d.messagesReceived, 1)
+		atomic.AddUint64(&d.bytesReceived, uint64(n))
 		d.onMessage(DataChannelMessage{Data: buffer[:n], IsString: isString})
 	}
 }
@@ -344,8 +455,18 @@ func (d *DataChannel) Send(data []byte) error {
 		data = []byte{0}
 	}
 
-	_, err = d.dataChannel.WriteDataChannel(data, false)
-	return err
+	return d.send(data, false)
+}
+
+// SendContext is Send bound to a context, it fails with the error of the
+// context once it is done. The SCTP association takes the message without
+// waiting for the remote peer, a send only fails if the context is done
+// before the message is handed over.
+func (d *DataChannel) SendContext(ctx context.Context, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return d.Send(data)
 }
 
 // SendText sends the text message to the DataChannel peer
@@ -360,10 +481,42 @@ func (d *DataChannel) SendText(s string) error {
 		data = []byte{0}
 	}
 
-	_, err = d.dataChannel.WriteDataChannel(data, true)
+	return d.send(data, true)
+}
+
+// send writes the message, counting it in the BufferedAmount until it is
+// handed to the SCTP association
+func (d *DataChannel) send(data []byte, isString bool) error {
+	d.mu.Lock()
+	d.bufferedAmount += uint64(len(data))
+	dc := d.dataChannel
+	d.mu.Unlock()
+
+	_, err := dc.WriteDataChannel(data, isString)
+	d.messageSent(len(data), err)
+
+	d.mu.Lock()
+	wasHigh := d.bufferedAmount > d.BufferedAmountLowThreshold
+	d.bufferedAmount -= uint64(len(data))
+	isLow := d.bufferedAmount <= d.BufferedAmountLowThreshold
+	d.mu.Unlock()
+
+	if wasHigh && isLow {
+		d.onBufferedAmountLow()
+	}
 	return err
 }
 
+// messageSent counts a message for the data-channel stats, unless its write
+// failed
+func (d *DataChannel) messageSent(size int, err error) {
+	if err != nil {
+		return
+	}
+	atomic.AddUint64(&d.messagesSent, 1)
+	atomic.AddUint64(&d.bytesSent, uint64(size))
+}
+
 func (d *DataChannel) ensureOpen() error {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
@@ -375,39 +528,71 @@ func (d *DataChannel) ensureOpen() error {
 
 // Detach allows you to detach the underlying datachannel. This provides
 // an idiomatic API to work with, however it disables the OnMessage callback.
+// The resulting DataChannel is an io.ReadWriteCloser, suiting the protocols
+// that stream bytes rather than messages. A Read returns a single message,
+// the buffer has to hold the largest one as the larger messages are lost.
 // Before calling Detach you have to enable this behavior by calling
 // webrtc.DetachDataChannels(). Combining detached and normal data channels
 // is not supported.
-// Please reffer to the data-channels-detach example and the
+// The writes are handed to the SCTP association right away, it doesn't
+// block them while the remote peer is slow to read, so the writers have to
+// pace themselves.
+// Please refer to the data-channels-detach example and the
 // pions/datachannel documentation for the correct way to handle the
-// resulting DataChannel object.
+// resulting DataChannel object. A negotiated DataChannel can't be detached,
+// its messages are still delivered to OnMessage.
 func (d *DataChannel) Detach() (*datachannel.DataChannel, error) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
 	if !d.api.settingEngine.detach.DataChannels {
-		return nil, errors.New("enable detaching by calling webrtc.DetachDataChannels()")
+		return nil, ErrDetachNotEnabled
 	}
 
 	if d.dataChannel == nil {
-		return nil, errors.New("datachannel not opened yet, try calling Detach from OnOpen")
+		return nil, ErrDetachBeforeOpened
+	}
+
+	dc, ok := d.dataChannel.(*datachannel.DataChannel)
+	if !ok {
+		return nil, ErrDetachNegotiated
 	}
 
-	return d.dataChannel, nil
+	return dc, nil
 }
 
 // Close Closes the DataChannel. It may be called regardless of whether
 // the DataChannel object was created by this peer or the remote peer.
 func (d *DataChannel) Close() error {
 	d.mu.Lock()
-	defer d.mu.Unlock()
 
 	if d.ReadyState == DataChannelStateClosing ||
 		d.ReadyState == DataChannelStateClosed {
+		d.mu.Unlock()
 		return nil
 	}
 
 	d.ReadyState = DataChannelStateClosing
+	err := d.dataChannel.Close()
 
-	return d.dataChannel.Close()
+	// No read loop sees a detached DataChannel close
+	closedHdlr := d.onClosedHandler
+	detached := d.api.settingEngine.detach.DataChannels && !d.Negotiated
+	d.mu.Unlock()
+	if detached && closedHdlr != nil {
+		closedHdlr(d)
+	}
+	return err
+}
+
+// onClosed sets the handler invoked once the DataChannel is closed, at once
+// when it already is
+func (d *DataChannel) onClosed(f func(*DataChannel)) {
+	d.mu.Lock()
+	d.onClosedHandler = f
+	closed := d.ReadyState == DataChannelStateClosed
+	d.mu.Unlock()
+	if closed {
+		f(d)
+	}
 }
@@ -73,6 +73,86 @@ func TestDataChannel_ORTCE2E(t *testing.T) {
 	}
 }
 
+func TestDataChannel_ORTCNegotiatedSendOnOpen(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	stackA, stackB, err := newORTCPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stackB.sctp.OnDataChannel(func(d *DataChannel) {
+		t.Errorf("A negotiated data channel shouldn't be announced")
+	})
+
+	dcParams := &DataChannelParameters{
+		Label:      "Foo",
+		ID:         1,
+		Negotiated: true,
+	}
+	stackB.sctp.registerNegotiated(dcParams.ID)
+
+	err = signalORTCPair(stackA, stackB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A sends as soon as its channel opens, before B opened its own
+	channelA, err := stackA.api.newDataChannel(dcParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	channelA.OnOpen(func() {
+		if e := channelA.SendText("ABC"); e != nil {
+			t.Errorf("Failed to send on data channel: %v", e)
+		}
+	})
+	if err = channelA.open(stackA.sctp); err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		stackB.sctp.lock.RLock()
+		_, held := stackB.sctp.negotiatedStreams[dcParams.ID]
+		stackB.sctp.lock.RUnlock()
+		if held {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	awaitString := make(chan string)
+	channelB, err := stackB.api.newDataChannel(dcParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	channelB.OnMessage(func(msg DataChannelMessage) {
+		awaitString <- string(msg.Data)
+	})
+	if err = channelB.open(stackB.sctp); err != nil {
+		t.Fatal(err)
+	}
+
+	if msg := <-awaitString; msg != "ABC" {
+		t.Fatalf("Unexpected message %q", msg)
+	}
+
+	err = stackA.close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = stackB.close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 type testORTCStack struct {
 	api      *API
 	gatherer *ICEGatherer
@@ -1,6 +1,7 @@
 package webrtc
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/binary"
 	"io"
@@ -8,7 +9,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pions/datachannel"
+	"github.com/pions/sctp"
 	"github.com/pions/transport/test"
+	"github.com/pions/webrtc/pkg/rtcerr"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -83,6 +87,10 @@ func TestDataChannel_Send(t *testing.T) {
 	assert.True(t, dc.Ordered, "Ordered should be set to true")
 
 	dc.OnOpen(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.Equal(t, context.Canceled, dc.SendContext(ctx, []byte("Ping")))
+
 		e := dc.SendText("Ping")
 		if e != nil {
 			t.Fatalf("Failed to send string on data channel")
@@ -112,6 +120,97 @@ func TestDataChannel_Send(t *testing.T) {
 	closePair(t, offerPC, answerPC, done)
 }
 
+func TestDataChannel_DataChannelsOnly(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	offerPC, answerPC, err := api.newPair()
+	if err != nil {
+		t.Fatalf("Failed to create a PC pair for testing")
+	}
+
+	done := make(chan bool)
+	dc, err := offerPC.CreateDataChannel("data", nil)
+	if err != nil {
+		t.Fatalf("Failed to create a data channel")
+	}
+	dc.OnOpen(func() {
+		if e := dc.SendText("Ping"); e != nil {
+			t.Errorf("Failed to send string on data channel")
+		}
+	})
+	dc.OnMessage(func(msg DataChannelMessage) {
+		done <- true
+	})
+	answerPC.OnDataChannel(func(d *DataChannel) {
+		d.OnMessage(func(msg DataChannelMessage) {
+			if e := d.SendText("Pong"); e != nil {
+				t.Errorf("Failed to send string on data channel")
+			}
+		})
+	})
+
+	offer, err := offerPC.CreateOffer(&OfferOptions{DataChannelsOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = offerPC.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	if err = answerPC.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := answerPC.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = answerPC.SetLocalDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+	if err = offerPC.SetRemoteDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both descriptions only have the application section
+	for _, desc := range []SessionDescription{offer, answer} {
+		if assert.Len(t, desc.parsed.MediaDescriptions, 1, desc.Type.String()) {
+			media := desc.parsed.MediaDescriptions[0]
+			assert.Equal(t, "application", media.MediaName.Media)
+			_, ok := media.Attribute("ice-ufrag")
+			assert.True(t, ok, "the application section should have ICE parameters")
+		}
+		group, _ := desc.parsed.Attribute("group")
+		assert.Equal(t, "BUNDLE data", group)
+		_, ok := desc.parsed.Attribute("fingerprint")
+		assert.True(t, ok, "the description should have the DTLS fingerprint")
+	}
+
+	closePair(t, offerPC, answerPC, done)
+}
+
+func TestDataChannel_DataChannelsOnly_WithTrack(t *testing.T) {
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pc, err := api.NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	track, err := pc.NewSampleTrack(DefaultPayloadTypeOpus, "audio", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pc.AddTrack(track); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = pc.CreateOffer(&OfferOptions{DataChannelsOnly: true})
+	assert.Equal(t, &rtcerr.InvalidStateError{Err: ErrDataChannelsOnlyWithTracks}, err)
+	assert.NoError(t, pc.Close())
+}
+
 func TestDataChannel_EventHandlers(t *testing.T) {
 	to := test.TimeOut(time.Second * 20)
 	defer to.Stop()
@@ -145,6 +244,176 @@ func TestDataChannel_EventHandlers(t *testing.T) {
 	<-onMessageCalled
 }
 
+// blockingDataChannelConn is a dataChannelConn whose writes wait for release
+type blockingDataChannelConn struct {
+	written chan []byte
+	release chan struct{}
+}
+
+func (c *blockingDataChannelConn) ReadDataChannel(p []byte) (int, bool, error) {
+	return 0, false, io.EOF
+}
+
+func (c *blockingDataChannelConn) WriteDataChannel(p []byte, isString bool) (int, error) {
+	c.written <- p
+	<-c.release
+	return len(p), nil
+}
+
+func (c *blockingDataChannelConn) Close() error {
+	return nil
+}
+
+func TestDataChannel_BufferedAmountLow(t *testing.T) {
+	to := test.TimeOut(time.Second * 20)
+	defer to.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	conn := &blockingDataChannelConn{written: make(chan []byte), release: make(chan struct{})}
+	dc := &DataChannel{
+		api:                        NewAPI(),
+		ReadyState:                 DataChannelStateOpen,
+		BufferedAmountLowThreshold: 4,
+		dataChannel:                conn,
+	}
+
+	onBufferedAmountLowCalled := make(chan struct{}, 1)
+	dc.OnBufferedAmountLow(func() {
+		onBufferedAmountLowCalled <- struct{}{}
+	})
+
+	sent := make(chan error)
+	go func() { sent <- dc.Send(make([]byte, 8)) }()
+	<-conn.written
+	go func() { sent <- dc.SendText("12345678") }()
+	<-conn.written
+
+	assert.Equal(t, uint64(16), dc.BufferedAmount())
+
+	// The BufferedAmount stays above the threshold until both messages
+	// were written
+	conn.release <- struct{}{}
+	assert.NoError(t, <-sent)
+	select {
+	case <-onBufferedAmountLowCalled:
+		t.Fatal("OnBufferedAmountLow called above the threshold")
+	default:
+	}
+
+	conn.release <- struct{}{}
+	assert.NoError(t, <-sent)
+	<-onBufferedAmountLowCalled
+
+	assert.Equal(t, uint64(0), dc.BufferedAmount())
+
+	// A message below the threshold doesn't call it
+	go func() { sent <- dc.SendText("hi") }()
+	<-conn.written
+	conn.release <- struct{}{}
+	assert.NoError(t, <-sent)
+	select {
+	case <-onBufferedAmountLowCalled:
+		t.Fatal("OnBufferedAmountLow called below the threshold")
+	default:
+	}
+}
+
+func TestDataChannel_Negotiated(t *testing.T) {
+	to := test.TimeOut(time.Second * 20)
+	defer to.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api := NewAPI()
+	offerPC, answerPC, err := api.newPair()
+	if err != nil {
+		t.Fatalf("Failed to create a PC pair for testing")
+	}
+
+	_, err = offerPC.CreateDataChannel("data", &DataChannelInit{Negotiated: &[]bool{true}[0]})
+	assert.Equal(t, &rtcerr.TypeError{Err: ErrNegotiatedWithoutID}, err)
+
+	negotiated := true
+	id := uint16(5)
+	options := &DataChannelInit{Negotiated: &negotiated, ID: &id}
+	offerDC, err := offerPC.CreateDataChannel("data", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	answerDC, err := answerPC.CreateDataChannel("data", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, offerDC.Negotiated)
+
+	answerPC.OnDataChannel(func(d *DataChannel) {
+		t.Errorf("A negotiated data channel shouldn't be announced")
+	})
+
+	// The messages are only sent once both peers opened the channel
+	opened := make(chan struct{}, 2)
+	offerDC.OnOpen(func() { opened <- struct{}{} })
+	answerDC.OnOpen(func() { opened <- struct{}{} })
+
+	done := make(chan bool)
+	answerDC.OnMessage(func(msg DataChannelMessage) {
+		assert.True(t, msg.IsString)
+		assert.Equal(t, "Ping", string(msg.Data))
+		if e := answerDC.Send([]byte("Pong")); e != nil {
+			t.Errorf("Failed to send on data channel: %v", e)
+		}
+	})
+	offerDC.OnMessage(func(msg DataChannelMessage) {
+		assert.False(t, msg.IsString)
+		assert.Equal(t, "Pong", string(msg.Data))
+		done <- true
+	})
+
+	if err = signalPair(offerPC, answerPC); err != nil {
+		t.Fatalf("Failed to signal our PC pair for testing")
+	}
+
+	<-opened
+	<-opened
+	if err = offerDC.SendText("Ping"); err != nil {
+		t.Fatal(err)
+	}
+
+	closePair(t, offerPC, answerPC, done)
+}
+
+func TestDataChannel_ReliabilityParams(t *testing.T) {
+	three := uint16(3)
+	testCases := []struct {
+		dc          *DataChannel
+		channelType datachannel.ChannelType
+		unordered   bool
+		relType     byte
+		relVal      uint32
+	}{
+		{&DataChannel{Ordered: true}, datachannel.ChannelTypeReliable, false, sctp.ReliabilityTypeReliable, 0},
+		{&DataChannel{}, datachannel.ChannelTypeReliableUnordered, true, sctp.ReliabilityTypeReliable, 0},
+		{&DataChannel{Ordered: true, MaxRetransmits: &three}, datachannel.ChannelTypePartialReliableRexmit, false, sctp.ReliabilityTypeRexmit, 3},
+		{&DataChannel{MaxRetransmits: &three}, datachannel.ChannelTypePartialReliableRexmitUnordered, true, sctp.ReliabilityTypeRexmit, 3},
+		{&DataChannel{Ordered: true, MaxPacketLifeTime: &three}, datachannel.ChannelTypePartialReliableTimed, false, sctp.ReliabilityTypeTimed, 3},
+		{&DataChannel{MaxPacketLifeTime: &three}, datachannel.ChannelTypePartialReliableTimedUnordered, true, sctp.ReliabilityTypeTimed, 3},
+	}
+
+	for i, testCase := range testCases {
+		channelType, reliabilityParameter := testCase.dc.channelType()
+		assert.Equal(t, testCase.channelType, channelType, "testCase: %d", i)
+		assert.Equal(t, testCase.relVal, reliabilityParameter, "testCase: %d", i)
+
+		unordered, relType, relVal := testCase.dc.reliabilityParams()
+		assert.Equal(t, testCase.unordered, unordered, "testCase: %d", i)
+		assert.Equal(t, testCase.relType, relType, "testCase: %d", i)
+		assert.Equal(t, testCase.relVal, relVal, "testCase: %d", i)
+	}
+}
+
 func TestDataChannel_MessagesAreOrdered(t *testing.T) {
 	report := test.CheckRoutines(t)
 	defer report()
@@ -285,3 +554,96 @@ func TestDataChannelParamters(t *testing.T) {
 		closeReliabilityParamTest(t, offerPC, answerPC, done)
 	})
 }
+
+func TestDataChannel_Detach(t *testing.T) {
+	to := test.TimeOut(time.Second * 20)
+	defer to.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.DetachDataChannels()
+	api := NewAPI(WithSettingEngine(s))
+
+	offerPC, answerPC, err := api.newPair()
+	if err != nil {
+		t.Fatalf("Failed to create a PC pair for testing")
+	}
+
+	dc, err := offerPC.CreateDataChannel("data", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = dc.Detach()
+	assert.Equal(t, ErrDetachBeforeOpened, err)
+
+	// The answerer echoes the bytes it reads
+	answerPC.OnDataChannel(func(d *DataChannel) {
+		d.OnOpen(func() {
+			raw, detachErr := d.Detach()
+			if detachErr != nil {
+				t.Errorf("Failed to detach data channel: %v", detachErr)
+				return
+			}
+			go func() {
+				var rwc io.ReadWriteCloser = raw
+				buffer := make([]byte, dataChannelBufferSize)
+				for {
+					n, readErr := rwc.Read(buffer)
+					if readErr != nil {
+						return
+					}
+					if _, writeErr := rwc.Write(buffer[:n]); writeErr != nil {
+						return
+					}
+				}
+			}()
+		})
+	})
+
+	done := make(chan bool)
+	dc.OnMessage(func(msg DataChannelMessage) {
+		t.Errorf("A detached data channel shouldn't call OnMessage")
+	})
+	dc.OnOpen(func() {
+		raw, detachErr := dc.Detach()
+		if detachErr != nil {
+			t.Errorf("Failed to detach data channel: %v", detachErr)
+			return
+		}
+		go func() {
+			var rwc io.ReadWriteCloser = raw
+			if _, writeErr := rwc.Write([]byte("Ping")); writeErr != nil {
+				t.Errorf("Failed to write to data channel: %v", writeErr)
+				return
+			}
+			buffer := make([]byte, dataChannelBufferSize)
+			n, readErr := rwc.Read(buffer)
+			if readErr != nil {
+				t.Errorf("Failed to read from data channel: %v", readErr)
+				return
+			}
+			assert.Equal(t, "Ping", string(buffer[:n]))
+			done <- true
+		}()
+	})
+
+	if err = signalPair(offerPC, answerPC); err != nil {
+		t.Fatalf("Failed to signal our PC pair for testing")
+	}
+
+	closePair(t, offerPC, answerPC, done)
+
+	other, err := NewAPI().NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherDC, err := other.CreateDataChannel("data", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = otherDC.Detach()
+	assert.Equal(t, ErrDetachNotEnabled, err)
+	assert.NoError(t, other.Close())
+}
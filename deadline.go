@@ -0,0 +1,58 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+)
+
+// deadline is a read or write deadline with the semantics of the ones of a
+// net.Conn: the operations blocked on it are rearmed when it is set again,
+// and it can be set again to extend it. The zero value has no deadline.
+type deadline struct {
+	mu      sync.Mutex
+	t       time.Time
+	changed chan struct{}
+}
+
+// set sets the deadline, the zero time removes it
+func (d *deadline) set(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.t = t
+	if d.changed != nil {
+		close(d.changed)
+	}
+	d.changed = make(chan struct{})
+}
+
+// isSet returns whether a deadline is set
+func (d *deadline) isSet() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return !d.t.IsZero()
+}
+
+// exceeded returns whether the deadline is set and elapsed
+func (d *deadline) exceeded() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return !d.t.IsZero() && !time.Now().Before(d.t)
+}
+
+// wait returns a channel receiving once the deadline elapses, nil without
+// a deadline, and one closed when the deadline is set again. stop releases
+// the timer of the deadline.
+func (d *deadline) wait() (elapsed <-chan time.Time, changed <-chan struct{}, stop func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.changed == nil {
+		d.changed = make(chan struct{})
+	}
+	if d.t.IsZero() {
+		return nil, d.changed, func() {}
+	}
+	timer := time.NewTimer(time.Until(d.t))
+	return timer.C, d.changed, func() { timer.Stop() }
+}
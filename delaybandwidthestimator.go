@@ -0,0 +1,302 @@
+package webrtc
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// The parameters of the delay-based controller of
+// https://tools.ietf.org/html/draft-ietf-rmcat-gcc-02#section-5, with the
+// trendline filter of the WebRTC implementation standing for its Kalman filter
+const (
+	// delayBurstInterval groups the packets sent within it, they are
+	// estimated as a single one
+	delayBurstInterval = 5 * time.Millisecond
+
+	// delayTrendlineWindow is the number of groups the trend of the delay
+	// is computed over, delayTrendlineSmoothing smooths their delay
+	delayTrendlineWindow    = 20
+	delayTrendlineSmoothing = 0.9
+	delayTrendlineGain      = 4
+	delayTrendlineMaxDeltas = 60
+
+	// The bounds and gains of the adaptive threshold the trend is compared
+	// to, in milliseconds
+	delayInitialThreshold = 12.5
+	delayMinThreshold     = 6
+	delayMaxThreshold     = 600
+	delayThresholdUp      = 0.0087
+	delayThresholdDown    = 0.039
+
+	// delayDecreaseFactor is the fraction of the acknowledged bitrate the
+	// estimate falls to on overuse
+	delayDecreaseFactor = 0.85
+
+	// delayIncreaseFactor is the increase of the estimate per second while
+	// the delay is stable
+	delayIncreaseFactor = 1.08
+)
+
+// bandwidthUsage is the state of the bottleneck as detected from the trend
+// of the delay
+type bandwidthUsage int
+
+const (
+	bandwidthUsageNormal bandwidthUsage = iota
+	bandwidthUsageOveruse
+	bandwidthUsageUnderuse
+)
+
+// packetGroup is a burst of packets, sent within delayBurstInterval
+type packetGroup struct {
+	firstSent   time.Time
+	lastSent    time.Time
+	lastArrival time.Duration
+}
+
+// delayBasedBandwidthEstimator estimates the available send bandwidth from
+// the variation of the one way delay of the packets, measured with the
+// TransportLayerCC feedback of the remote. A growing delay means a queue
+// builds up at the bottleneck, before any packet is lost.
+type delayBasedBandwidthEstimator struct {
+	mu       sync.Mutex
+	min, max int
+	estimate int
+
+	group, previousGroup *packetGroup
+
+	// The trendline filter
+	accumulatedDelay float64
+	smoothedDelay    float64
+	firstArrival     time.Duration
+	samples          [][2]float64
+	deltas           int
+
+	threshold       float64
+	thresholdUpdate time.Duration
+	overusing       int
+	usage           bandwidthUsage
+
+	ackedBitrate int
+	lastUpdate   time.Time
+}
+
+func newDelayBasedBandwidthEstimator(min, max int) *delayBasedBandwidthEstimator {
+	e := &delayBasedBandwidthEstimator{min: min, max: max, threshold: delayInitialThreshold}
+	e.estimate = e.clamp(defaultStartBitrate)
+	return e
+}
+
+func (e *delayBasedBandwidthEstimator) clamp(bitrate int) int {
+	switch {
+	case bitrate < e.min:
+		return e.min
+	case bitrate > e.max:
+		return e.max
+	}
+	return bitrate
+}
+
+// limit lowers the maximum of the estimate to the bitrate the remote asked
+// not to exceed, the minimum is kept if the bitrate is lower
+func (e *delayBasedBandwidthEstimator) limit(bitrate uint64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if bitrate < uint64(e.max) {
+		e.max = int(bitrate)
+		if e.max < e.min {
+			e.max = e.min
+		}
+	}
+	e.estimate = e.clamp(e.estimate)
+}
+
+// bitrate returns the current estimate
+func (e *delayBasedBandwidthEstimator) bitrate() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.estimate
+}
+
+// onFeedback updates the estimate with the packets a feedback message
+// reported received and returns the new estimate
+func (e *delayBasedBandwidthEstimator) onFeedback(arrivals []transportCCArrival, now time.Time) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.updateAckedBitrate(arrivals)
+	for _, a := range arrivals {
+		e.addArrival(a)
+	}
+	e.updateEstimate(now)
+	return e.estimate
+}
+
+// updateAckedBitrate measures the bitrate the remote received
+func (e *delayBasedBandwidthEstimator) updateAckedBitrate(arrivals []transportCCArrival) {
+	if len(arrivals) < 2 {
+		return
+	}
+	span := arrivals[len(arrivals)-1].arrival - arrivals[0].arrival
+	if span < delayBurstInterval {
+		return
+	}
+
+	// The first packet arrived at the start of the span
+	bits := 0
+	for _, a := range arrivals[1:] {
+		bits += a.size * 8
+	}
+	e.ackedBitrate = int(float64(bits) / span.Seconds())
+}
+
+// addArrival adds a packet to its group, the delay variation is measured
+// between the groups
+func (e *delayBasedBandwidthEstimator) addArrival(a transportCCArrival) {
+	if e.group == nil {
+		e.group = &packetGroup{firstSent: a.sent, lastSent: a.sent, lastArrival: a.arrival}
+		return
+	}
+
+	// Reordered packets are ignored
+	if a.sent.Before(e.group.firstSent) {
+		return
+	}
+
+	if a.sent.Sub(e.group.firstSent) <= delayBurstInterval {
+		e.group.lastSent = a.sent
+		if a.arrival > e.group.lastArrival {
+			e.group.lastArrival = a.arrival
+		}
+		return
+	}
+
+	if e.previousGroup != nil {
+		sendDelta := e.group.lastSent.Sub(e.previousGroup.lastSent)
+		arrivalDelta := e.group.lastArrival - e.previousGroup.lastArrival
+		e.updateTrendline(float64(arrivalDelta-sendDelta)/float64(time.Millisecond), e.group.lastArrival)
+	}
+	e.previousGroup = e.group
+	e.group = &packetGroup{firstSent: a.sent, lastSent: a.sent, lastArrival: a.arrival}
+}
+
+// updateTrendline computes the trend of the smoothed accumulated delay over
+// the last groups with a linear regression, and detects an overuse when it
+// exceeds the adaptive threshold
+func (e *delayBasedBandwidthEstimator) updateTrendline(delayVariation float64, arrival time.Duration) {
+	if e.deltas == 0 {
+		e.firstArrival = arrival
+	}
+	if e.deltas < delayTrendlineMaxDeltas {
+		e.deltas++
+	}
+
+	e.accumulatedDelay += delayVariation
+	e.smoothedDelay = delayTrendlineSmoothing*e.smoothedDelay + (1-delayTrendlineSmoothing)*e.accumulatedDelay
+
+	x := float64(arrival-e.firstArrival) / float64(time.Millisecond)
+	e.samples = append(e.samples, [2]float64{x, e.smoothedDelay})
+	if len(e.samples) > delayTrendlineWindow {
+		e.samples = e.samples[1:]
+	}
+	if len(e.samples) < delayTrendlineWindow {
+		return
+	}
+
+	trend := linearRegressionSlope(e.samples) * float64(e.deltas) * delayTrendlineGain
+	switch {
+	case trend > e.threshold:
+		// The overuse has to last for two groups to be detected
+		e.overusing++
+		if e.overusing > 1 {
+			e.usage = bandwidthUsageOveruse
+		}
+	case trend < -e.threshold:
+		e.overusing = 0
+		e.usage = bandwidthUsageUnderuse
+	default:
+		e.overusing = 0
+		e.usage = bandwidthUsageNormal
+	}
+	e.updateThreshold(trend, arrival)
+}
+
+// updateThreshold adapts the threshold to the trend, it rises slowly and
+// falls fast so the estimator isn't starved by concurrent TCP flows
+// https://tools.ietf.org/html/draft-ietf-rmcat-gcc-02#section-5.4
+func (e *delayBasedBandwidthEstimator) updateThreshold(trend float64, arrival time.Duration) {
+	elapsed := float64(arrival-e.thresholdUpdate) / float64(time.Millisecond)
+	e.thresholdUpdate = arrival
+
+	// A spike of the trend doesn't move the threshold
+	if math.Abs(trend) > e.threshold+15 {
+		return
+	}
+	if elapsed > 100 {
+		elapsed = 100
+	}
+
+	k := delayThresholdUp
+	if math.Abs(trend) < e.threshold {
+		k = delayThresholdDown
+	}
+	e.threshold += k * (math.Abs(trend) - e.threshold) * elapsed
+	e.threshold = math.Max(delayMinThreshold, math.Min(delayMaxThreshold, e.threshold))
+}
+
+// updateEstimate moves the estimate according to the usage of the bottleneck
+// https://tools.ietf.org/html/draft-ietf-rmcat-gcc-02#section-5.5
+func (e *delayBasedBandwidthEstimator) updateEstimate(now time.Time) {
+	elapsed := time.Duration(0)
+	if !e.lastUpdate.IsZero() {
+		elapsed = now.Sub(e.lastUpdate)
+	}
+	e.lastUpdate = now
+
+	switch e.usage {
+	case bandwidthUsageOveruse:
+		decreased := int(float64(e.estimate) * delayDecreaseFactor)
+		if e.ackedBitrate != 0 {
+			decreased = int(float64(e.ackedBitrate) * delayDecreaseFactor)
+		}
+		if decreased < e.estimate {
+			e.estimate = decreased
+		}
+	case bandwidthUsageNormal:
+		increased := int(float64(e.estimate) * math.Pow(delayIncreaseFactor, elapsed.Seconds()))
+
+		// The estimate doesn't run away from what the remote receives
+		if e.ackedBitrate != 0 {
+			if ceiling := int(1.5*float64(e.ackedBitrate)) + 10000; increased > ceiling {
+				increased = ceiling
+			}
+		}
+		if increased > e.estimate {
+			e.estimate = increased
+		}
+	}
+	e.estimate = e.clamp(e.estimate)
+}
+
+// linearRegressionSlope returns the slope of the least squares line through
+// the points
+func linearRegressionSlope(points [][2]float64) float64 {
+	var sumX, sumY float64
+	for _, p := range points {
+		sumX += p[0]
+		sumY += p[1]
+	}
+	meanX, meanY := sumX/float64(len(points)), sumY/float64(len(points))
+
+	var numerator, denominator float64
+	for _, p := range points {
+		numerator += (p[0] - meanX) * (p[1] - meanY)
+		denominator += (p[0] - meanX) * (p[0] - meanX)
+	}
+	if denominator == 0 {
+		return 0
+	}
+	return numerator / denominator
+}
@@ -0,0 +1,113 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// transportCCTrace replays a stream of 1200 bytes packets sent every 10ms
+// through the history and the feedback messages of the remote, sent every
+// 100ms
+type transportCCTrace struct {
+	t         *testing.T
+	history   *transportCCHistory
+	estimator *delayBasedBandwidthEstimator
+	start     time.Time
+	sent      int
+}
+
+func newTransportCCTrace(t *testing.T) *transportCCTrace {
+	return &transportCCTrace{
+		t:         t,
+		history:   &transportCCHistory{},
+		estimator: newDelayBasedBandwidthEstimator(defaultMinBitrate, defaultMaxBitrate),
+		start:     time.Now(),
+	}
+}
+
+// run sends the packets of the given duration and returns the last estimate,
+// delay returns the one way delay of each packet
+func (tr *transportCCTrace) run(duration time.Duration, delay func(i int) time.Duration) int {
+	estimate := 0
+	for end := tr.sent + int(duration/(10*time.Millisecond)); tr.sent < end; {
+		feedback := &TransportLayerCC{BaseSequenceNumber: uint16(tr.sent)}
+		previous := time.Duration(0)
+		for j := 0; j < 10; j++ {
+			sent := tr.start.Add(time.Duration(tr.sent) * 10 * time.Millisecond)
+			tr.history.add(1200, sent)
+
+			// The arrivals are written relative to the start of the trace
+			arrival := sent.Sub(tr.start) + delay(tr.sent)
+			if j == 0 {
+				feedback.ReferenceTime = uint32(arrival / (64 * time.Millisecond))
+				previous = time.Duration(feedback.ReferenceTime) * 64 * time.Millisecond
+			}
+			feedback.Packets = append(feedback.Packets, TransportCCPacketStatus{Received: true, Delta: arrival - previous})
+			previous = arrival
+			tr.sent++
+		}
+
+		// The feedback goes through the wire format
+		raw, err := feedback.Marshal()
+		if err != nil {
+			tr.t.Fatal(err)
+		}
+		decoded := &TransportLayerCC{}
+		if err = decoded.Unmarshal(raw); err != nil {
+			tr.t.Fatal(err)
+		}
+		now := tr.start.Add(time.Duration(tr.sent) * 10 * time.Millisecond)
+		estimate = tr.estimator.onFeedback(tr.history.arrivals(decoded), now)
+	}
+	return estimate
+}
+
+func TestDelayBasedBandwidthEstimator(t *testing.T) {
+	tr := newTransportCCTrace(t)
+
+	// With a stable delay the estimate grows, up to what the remote
+	// receives: 960kbps
+	stable := tr.run(10*time.Second, func(int) time.Duration { return 30 * time.Millisecond })
+	assert.True(t, stable > defaultStartBitrate, "estimate %d should grow", stable)
+	assert.True(t, stable <= int(1.5*960000)+10000, "estimate %d should follow the acknowledged bitrate", stable)
+
+	// The bottleneck drops to 480kbps, a queue builds up: each packet takes
+	// 20ms to go through and arrives 10ms later than the previous. The
+	// estimate falls below the bitrate of the bottleneck.
+	queued := tr.sent
+	congested := tr.run(time.Second, func(i int) time.Duration {
+		return 30*time.Millisecond + time.Duration(i-queued)*10*time.Millisecond
+	})
+	assert.True(t, congested < stable, "estimate %d should decrease from %d", congested, stable)
+	assert.True(t, congested < 480000, "estimate %d should fall below the bottleneck", congested)
+
+	// The estimate stays in the bounds
+	assert.True(t, congested >= defaultMinBitrate)
+}
+
+func TestDelayBasedBandwidthEstimator_Limit(t *testing.T) {
+	e := newDelayBasedBandwidthEstimator(defaultMinBitrate, defaultMaxBitrate)
+	e.limit(100000)
+	assert.Equal(t, 100000, e.bitrate())
+
+	e.limit(1000)
+	assert.Equal(t, defaultMinBitrate, e.bitrate())
+}
+
+func TestLinearRegressionSlope(t *testing.T) {
+	assert.Equal(t, 2.0, linearRegressionSlope([][2]float64{{0, 1}, {1, 3}, {2, 5}}))
+	assert.Equal(t, 0.0, linearRegressionSlope([][2]float64{{1, 1}, {1, 3}}))
+}
+
+func TestRTPSender_BandwidthEstimate(t *testing.T) {
+	r := &RTPSender{}
+	assert.Equal(t, 200000, r.bandwidthEstimate(200000))
+
+	// The lowest of the loss-based and delay-based estimates is reported
+	r.delayEstimator = newDelayBasedBandwidthEstimator(defaultMinBitrate, defaultMaxBitrate)
+	r.delayEstimator.limit(100000)
+	assert.Equal(t, 100000, r.bandwidthEstimate(200000))
+	assert.Equal(t, 50000, r.bandwidthEstimate(50000))
+}
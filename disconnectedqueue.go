@@ -0,0 +1,68 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pions/rtp"
+)
+
+// disconnectedQueueSize is the number of packets a disconnectedQueue holds
+// at most, whatever its buffer duration
+const disconnectedQueueSize = 1024
+
+// disconnectedQueue holds the packets a RTPSender writes while the ICE
+// connection is disconnected, up to the buffer duration and
+// disconnectedQueueSize packets. The packets are dropped with a zero
+// duration, the path they would be sent on is dead.
+type disconnectedQueue struct {
+	buffer time.Duration
+
+	mu      sync.Mutex
+	packets []*rtp.Packet
+	written []time.Time
+}
+
+// push queues a packet written at now, it returns the number of packets
+// dropped
+func (q *disconnectedQueue) push(packet *rtp.Packet, now time.Time) int {
+	if q.buffer <= 0 {
+		return 1
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.packets = append(q.packets, packet)
+	q.written = append(q.written, now)
+	return q.expire(now)
+}
+
+// flush returns the queued packets written within the buffer duration of
+// now, and the number of the older ones dropped
+func (q *disconnectedQueue) flush(now time.Time) ([]*rtp.Packet, int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.packets) == 0 {
+		return nil, 0
+	}
+
+	dropped := q.expire(now)
+	packets := q.packets
+	q.packets, q.written = nil, nil
+	return packets, dropped
+}
+
+// expire drops the packets written longer than the buffer duration ago, and
+// the oldest ones past disconnectedQueueSize
+func (q *disconnectedQueue) expire(now time.Time) int {
+	expired := 0
+	if len(q.written) > disconnectedQueueSize {
+		expired = len(q.written) - disconnectedQueueSize
+	}
+	for expired < len(q.written) && now.Sub(q.written[expired]) > q.buffer {
+		expired++
+	}
+	q.packets = q.packets[expired:]
+	q.written = q.written[expired:]
+	return expired
+}
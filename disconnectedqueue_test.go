@@ -0,0 +1,51 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pions/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisconnectedQueue(t *testing.T) {
+	start := time.Now()
+	packet := func(sequenceNumber uint16) *rtp.Packet {
+		return &rtp.Packet{Header: rtp.Header{SequenceNumber: sequenceNumber}}
+	}
+
+	// The packets are dropped without a buffer
+	q := disconnectedQueue{}
+	assert.Equal(t, 1, q.push(packet(1), start))
+	packets, dropped := q.flush(start)
+	assert.Empty(t, packets)
+	assert.Equal(t, 0, dropped)
+
+	// The packets older than the buffer are dropped as new ones are queued
+	q = disconnectedQueue{buffer: time.Second}
+	assert.Equal(t, 0, q.push(packet(1), start))
+	assert.Equal(t, 0, q.push(packet(2), start.Add(time.Second/2)))
+	assert.Equal(t, 1, q.push(packet(3), start.Add(time.Second*3/2)))
+
+	// and once flushed
+	packets, dropped = q.flush(start.Add(time.Second * 2))
+	assert.Equal(t, 1, dropped)
+	assert.Equal(t, []*rtp.Packet{packet(3)}, packets)
+
+	assert.Equal(t, 0, q.push(packet(4), start))
+	packets, dropped = q.flush(start.Add(time.Hour))
+	assert.Empty(t, packets)
+	assert.Equal(t, 1, dropped)
+
+	// The oldest packets past the size are dropped
+	q = disconnectedQueue{buffer: time.Hour}
+	for i := 0; i < disconnectedQueueSize; i++ {
+		assert.Equal(t, 0, q.push(packet(uint16(i)), start))
+	}
+	assert.Equal(t, 1, q.push(packet(disconnectedQueueSize), start))
+	packets, dropped = q.flush(start)
+	assert.Equal(t, 0, dropped)
+	if assert.Len(t, packets, disconnectedQueueSize) {
+		assert.Equal(t, uint16(1), packets[0].SequenceNumber)
+	}
+}
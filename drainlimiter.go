@@ -0,0 +1,49 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+)
+
+// drainLimiter spaces out the packets of a stream to a bitrate, as if they
+// were drained through a link of that rate. A packet that would wait longer
+// than maxDelay is dropped instead.
+type drainLimiter struct {
+	mu       sync.Mutex
+	bitrate  int
+	maxDelay time.Duration
+
+	// next is the time the link is free, once the packets admitted so far
+	// are drained
+	next time.Time
+}
+
+// set changes the bitrate and the longest delay, a zero bitrate admits
+// every packet right away
+func (l *drainLimiter) set(bitrate int, maxDelay time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.bitrate = bitrate
+	l.maxDelay = maxDelay
+	l.next = time.Time{}
+}
+
+// admit returns how long a packet of size bytes arriving at now waits to
+// be delivered, false if it is dropped
+func (l *drainLimiter) admit(size int, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.bitrate <= 0 {
+		return 0, true
+	}
+
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	if wait > l.maxDelay {
+		return 0, false
+	}
+	l.next = l.next.Add(time.Duration(int64(size) * 8 * int64(time.Second) / int64(l.bitrate)))
+	return wait, true
+}
@@ -0,0 +1,42 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrainLimiter(t *testing.T) {
+	start := time.Now()
+	l := &drainLimiter{}
+
+	// Every packet is admitted without a bitrate
+	for i := 0; i < 10; i++ {
+		wait, ok := l.admit(1000, start)
+		assert.True(t, ok)
+		assert.Zero(t, wait)
+	}
+
+	// At 80kbps a packet of 1000 bytes takes 100ms to drain
+	l.set(80000, 150*time.Millisecond)
+	wait, ok := l.admit(1000, start)
+	assert.True(t, ok)
+	assert.Zero(t, wait)
+	wait, ok = l.admit(1000, start)
+	assert.True(t, ok)
+	assert.Equal(t, 100*time.Millisecond, wait)
+
+	// Past the longest delay the packets are dropped, without delaying the
+	// following ones
+	_, ok = l.admit(1000, start)
+	assert.False(t, ok)
+	wait, ok = l.admit(1000, start.Add(100*time.Millisecond))
+	assert.True(t, ok)
+	assert.Equal(t, 100*time.Millisecond, wait)
+
+	// The link is free once idle
+	wait, ok = l.admit(1000, start.Add(time.Second))
+	assert.True(t, ok)
+	assert.Zero(t, wait)
+}
@@ -1,5 +1,16 @@
 package webrtc
 
+import (
+	"crypto/x509"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pions/dtls"
+	"github.com/pions/sdp/v2"
+	"github.com/pkg/errors"
+)
+
 // DTLSFingerprint specifies the hash function algorithm and certificate
 // fingerprint as described in https://tools.ietf.org/html/rfc4572.
 type DTLSFingerprint struct {
@@ -12,3 +23,86 @@ type DTLSFingerprint struct {
 	// https://tools.ietf.org/html/rfc4572#section-5.
 	Value string `json:"value"`
 }
+
+// parseDTLSFingerprint parses the value of a fingerprint attribute, the
+// hash function name followed by the fingerprint
+func parseDTLSFingerprint(value string) (DTLSFingerprint, error) {
+	parts := strings.Split(value, " ")
+	if len(parts) != 2 {
+		return DTLSFingerprint{}, errors.New("invalid fingerprint")
+	}
+	return DTLSFingerprint{Algorithm: parts[0], Value: parts[1]}, nil
+}
+
+// fingerprintAlgorithmStrength ranks the hash functions of the fingerprints,
+// the certificate of the remote is checked against the strongest first
+var fingerprintAlgorithmStrength = map[string]int{
+	"md5":     1,
+	"sha-1":   2,
+	"sha-224": 3,
+	"sha-256": 4,
+	"sha-384": 5,
+	"sha-512": 6,
+}
+
+// sortFingerprints orders fingerprints by the strength of their hash
+// function, strongest first. Unknown hash functions come last.
+func sortFingerprints(fingerprints []DTLSFingerprint) {
+	sort.SliceStable(fingerprints, func(i, j int) bool {
+		return fingerprintAlgorithmStrength[strings.ToLower(fingerprints[i].Algorithm)] >
+			fingerprintAlgorithmStrength[strings.ToLower(fingerprints[j].Algorithm)]
+	})
+}
+
+// fingerprintsFromAttributes parses every fingerprint attribute of a
+// description or media section, strongest hash function first
+func fingerprintsFromAttributes(attributes []sdp.Attribute) ([]DTLSFingerprint, error) {
+	var fingerprints []DTLSFingerprint
+	for _, a := range attributes {
+		if a.Key != "fingerprint" {
+			continue
+		}
+		fingerprint, err := parseDTLSFingerprint(a.Value)
+		if err != nil {
+			return nil, err
+		}
+		fingerprints = append(fingerprints, fingerprint)
+	}
+	sortFingerprints(fingerprints)
+	return fingerprints, nil
+}
+
+// CertificateFingerprint computes the fingerprint of a DER encoded
+// certificate, such as the one of DTLSTransport.RemoteCertificate, with the
+// hash function of the given name, e.g. sha-256
+func CertificateFingerprint(certificate []byte, algorithm string) (DTLSFingerprint, error) {
+	hashAlgo, err := dtls.HashAlgorithmString(algorithm)
+	if err != nil {
+		return DTLSFingerprint{}, err
+	}
+	cert, err := x509.ParseCertificate(certificate)
+	if err != nil {
+		return DTLSFingerprint{}, err
+	}
+	value, err := dtls.Fingerprint(cert, hashAlgo)
+	if err != nil {
+		return DTLSFingerprint{}, err
+	}
+	return DTLSFingerprint{Algorithm: algorithm, Value: value}, nil
+}
+
+// DTLSFingerprintMismatchError is the reason the DTLS handshake fails when
+// the certificate the remote presented matches none of the fingerprints of
+// its description, PeerConnection.ConnectionError returns it
+type DTLSFingerprintMismatchError struct {
+	// Expected are the fingerprints of the description of the remote
+	Expected []DTLSFingerprint
+
+	// Presented are the fingerprints of the certificate the remote
+	// presented, computed with the hash functions of Expected
+	Presented []DTLSFingerprint
+}
+
+func (e *DTLSFingerprintMismatchError) Error() string {
+	return fmt.Sprintf("no matching fingerprint: expected %v, presented %v", e.Expected, e.Presented)
+}
@@ -0,0 +1,92 @@
+package webrtc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/pions/sdp/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCertificateFingerprint(t *testing.T) {
+	sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := GenerateCertificate(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fingerprint, err := CertificateFingerprint(certificate.x509Cert.Raw, "sha-256")
+	assert.NoError(t, err)
+	assert.Equal(t, certificate.GetFingerprints()[0], fingerprint)
+
+	_, err = CertificateFingerprint(certificate.x509Cert.Raw, "md4")
+	assert.Error(t, err)
+	_, err = CertificateFingerprint([]byte{0x00}, "sha-256")
+	assert.Error(t, err)
+}
+
+func TestFingerprintsFromAttributes(t *testing.T) {
+	attributes := []sdp.Attribute{
+		{Key: "fingerprint", Value: "sha-1 AA"},
+		{Key: "setup", Value: "actpass"},
+		{Key: "fingerprint", Value: "sha-256 BB"},
+		{Key: "fingerprint", Value: "md4 CC"},
+		{Key: "fingerprint", Value: "SHA-512 DD"},
+	}
+	fingerprints, err := fingerprintsFromAttributes(attributes)
+	assert.NoError(t, err)
+	assert.Equal(t, []DTLSFingerprint{
+		{Algorithm: "SHA-512", Value: "DD"},
+		{Algorithm: "sha-256", Value: "BB"},
+		{Algorithm: "sha-1", Value: "AA"},
+		{Algorithm: "md4", Value: "CC"},
+	}, fingerprints)
+
+	_, err = fingerprintsFromAttributes([]sdp.Attribute{{Key: "fingerprint", Value: "sha-256"}})
+	assert.Error(t, err)
+}
+
+func TestDTLSTransport_ValidateFingerPrint(t *testing.T) {
+	sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := GenerateCertificate(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sha256, err := CertificateFingerprint(certificate.x509Cert.Raw, "sha-256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sha512, err := CertificateFingerprint(certificate.x509Cert.Raw, "sha-512")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transport := &DTLSTransport{}
+	validate := func(fingerprints ...DTLSFingerprint) error {
+		return transport.validateFingerPrint(DTLSParameters{Fingerprints: fingerprints}, certificate.x509Cert)
+	}
+
+	// Any matching fingerprint is enough, unsupported hash functions are
+	// skipped
+	unknown := DTLSFingerprint{Algorithm: "md4", Value: "00"}
+	wrong := DTLSFingerprint{Algorithm: "sha-256", Value: "00"}
+	assert.NoError(t, validate(unknown, sha256, sha512))
+	assert.NoError(t, validate(wrong, sha512))
+	assert.NoError(t, validate(DTLSFingerprint{Algorithm: "SHA-512", Value: strings.ToUpper(sha512.Value)}))
+
+	err = validate(unknown, wrong)
+	mismatch, ok := err.(*DTLSFingerprintMismatchError)
+	if assert.True(t, ok, err) {
+		assert.Equal(t, []DTLSFingerprint{unknown, wrong}, mismatch.Expected)
+		assert.Equal(t, []DTLSFingerprint{sha256}, mismatch.Presented)
+	}
+}
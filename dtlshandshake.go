@@ -0,0 +1,302 @@
+package webrtc
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/pions/webrtc/pkg/logging"
+)
+
+// dtlsCipherSuites are the cipher suites implemented by pions/dtls, in the
+// order it prefers them
+var dtlsCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+	tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+}
+
+// dtlsEllipticCurves are the curves implemented by pions/dtls, in the order
+// it prefers them
+var dtlsEllipticCurves = []tls.CurveID{tls.X25519, tls.CurveP256}
+
+const (
+	dtlsRecordHeaderLength    = 13
+	dtlsHandshakeHeaderLength = 12
+
+	dtlsContentTypeAlert     = 21
+	dtlsContentTypeHandshake = 22
+
+	dtlsAlertLevelFatal       = 2
+	dtlsAlertHandshakeFailure = 40
+
+	dtlsHandshakeTypeClientHello       = 1
+	dtlsHandshakeTypeServerHello       = 2
+	dtlsHandshakeTypeServerKeyExchange = 12
+
+	dtlsExtensionSupportedGroups = 10
+	dtlsCurveTypeNamedCurve      = 3
+)
+
+// dtlsHandshakeConn sits between pions/dtls and the mux endpoint it reads the
+// DTLS records from. It follows the unencrypted hello messages to learn the
+// negotiated cipher suite and curve, and fails the handshake when the curve
+// isn't permitted by the SettingEngine: a read error aborts the handshake of
+// pions/dtls with that error.
+type dtlsHandshakeConn struct {
+	net.Conn
+
+	// curves are the permitted ones, nil permits all
+	curves []tls.CurveID
+
+	log logging.LeveledLogger
+
+	// version and cipherSuite are the ones of the hello of the server,
+	// peerRandom the random of the hello of the remote
+	mu          sync.Mutex
+	version     uint16
+	cipherSuite uint16
+	peerRandom  []byte
+}
+
+func newDTLSHandshakeConn(conn net.Conn, curves []tls.CurveID, log logging.LeveledLogger) *dtlsHandshakeConn {
+	return &dtlsHandshakeConn{Conn: conn, curves: curves, log: log}
+}
+
+func (c *dtlsHandshakeConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if err != nil {
+		return n, err
+	}
+
+	if err := c.inspect(p[:n], true); err != nil {
+		// The remote is told the handshake failed instead of retransmitting
+		// its flight until it times out
+		alert := []byte{dtlsContentTypeAlert, 0xfe, 0xfd, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2, dtlsAlertLevelFatal, dtlsAlertHandshakeFailure}
+		if _, writeErr := c.Conn.Write(alert); writeErr != nil {
+			c.log.Warnf("Failed to send the DTLS handshake failure alert: %v", writeErr)
+		}
+		return 0, err
+	}
+	return n, nil
+}
+
+func (c *dtlsHandshakeConn) Write(p []byte) (int, error) {
+	// The local messages are only followed to learn the cipher suite
+	// selected when acting as the server
+	_ = c.inspect(p, false)
+	return c.Conn.Write(p)
+}
+
+// negotiatedCipherSuite returns the cipher suite selected by the server, if
+// its hello was exchanged
+func (c *dtlsHandshakeConn) negotiatedCipherSuite() (uint16, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cipherSuite, c.cipherSuite != 0
+}
+
+// negotiatedState returns the version and cipher suite selected by the
+// server and the random of the remote, once the hellos were exchanged
+func (c *dtlsHandshakeConn) negotiatedState() (version, cipherSuite uint16, peerRandom []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.version, c.cipherSuite, append([]byte(nil), c.peerRandom...)
+}
+
+// inspect walks the records of a datagram, the remote messages are checked
+// against the permitted curves
+func (c *dtlsHandshakeConn) inspect(buf []byte, remote bool) error {
+	for len(buf) >= dtlsRecordHeaderLength {
+		contentType := buf[0]
+		epoch := binary.BigEndian.Uint16(buf[3:])
+		end := dtlsRecordHeaderLength + int(binary.BigEndian.Uint16(buf[11:]))
+		if end > len(buf) {
+			return nil
+		}
+
+		// Only the messages of epoch 0 are unencrypted
+		if contentType == dtlsContentTypeHandshake && epoch == 0 {
+			if err := c.inspectHandshake(buf[dtlsRecordHeaderLength:end], remote); err != nil {
+				return err
+			}
+		}
+		buf = buf[end:]
+	}
+	return nil
+}
+
+func (c *dtlsHandshakeConn) inspectHandshake(buf []byte, remote bool) error {
+	for len(buf) >= dtlsHandshakeHeaderLength {
+		handshakeType := buf[0]
+		fragmentOffset := uint32(buf[6])<<16 | uint32(buf[7])<<8 | uint32(buf[8])
+		end := dtlsHandshakeHeaderLength + (int(buf[9])<<16 | int(buf[10])<<8 | int(buf[11]))
+		if end > len(buf) {
+			return nil
+		}
+
+		// The fields checked are at the start of the messages
+		if fragmentOffset == 0 {
+			body := buf[dtlsHandshakeHeaderLength:end]
+			var err error
+			switch handshakeType {
+			case dtlsHandshakeTypeClientHello:
+				if remote {
+					err = c.handleClientHello(body)
+				}
+			case dtlsHandshakeTypeServerHello:
+				c.handleServerHello(body, remote)
+			case dtlsHandshakeTypeServerKeyExchange:
+				if remote {
+					err = c.handleServerKeyExchange(body)
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+		buf = buf[end:]
+	}
+	return nil
+}
+
+// handleClientHello records the random of a remote client and checks the
+// curves it offers. pions/dtls selects the first curve of the client it
+// implements.
+func (c *dtlsHandshakeConn) handleClientHello(body []byte) error {
+	// Version, random and session ID
+	offset := 34
+	if offset >= len(body) {
+		return nil
+	}
+	c.mu.Lock()
+	c.peerRandom = append(c.peerRandom[:0], body[2:offset]...)
+	c.mu.Unlock()
+	offset += 1 + int(body[offset])
+
+	// Cookie
+	if offset >= len(body) {
+		return nil
+	}
+	offset += 1 + int(body[offset])
+
+	if offset+2 > len(body) {
+		return nil
+	}
+	suitesEnd := offset + 2 + int(binary.BigEndian.Uint16(body[offset:]))
+	if suitesEnd > len(body) {
+		return nil
+	}
+
+	// Compression methods
+	offset = suitesEnd
+	if offset >= len(body) {
+		return nil
+	}
+	offset += 1 + int(body[offset])
+
+	// The curves are only found in the extensions, pions/dtls defaults
+	// to X25519 without them
+	curves := []tls.CurveID{tls.X25519}
+	if offset+2 <= len(body) {
+		extensions := body[offset+2:]
+		for len(extensions) >= 4 {
+			extensionType := binary.BigEndian.Uint16(extensions)
+			extensionEnd := 4 + int(binary.BigEndian.Uint16(extensions[2:]))
+			if extensionEnd > len(extensions) {
+				break
+			}
+			if extensionType == dtlsExtensionSupportedGroups && extensionEnd >= 6 {
+				curves = nil
+				for i := 6; i+2 <= extensionEnd; i += 2 {
+					if id := tls.CurveID(binary.BigEndian.Uint16(extensions[i:])); containsCurve(dtlsEllipticCurves, id) {
+						curves = append(curves, id)
+					}
+				}
+			}
+			extensions = extensions[extensionEnd:]
+		}
+	}
+	return c.checkCurves(curves)
+}
+
+// handleServerHello records the version and cipher suite selected by the
+// server
+func (c *dtlsHandshakeConn) handleServerHello(body []byte, remote bool) {
+	// Version, random and session ID
+	offset := 34
+	if offset >= len(body) {
+		return
+	}
+	offset += 1 + int(body[offset])
+	if offset+2 > len(body) {
+		return
+	}
+	id := binary.BigEndian.Uint16(body[offset:])
+
+	c.mu.Lock()
+	c.version = binary.BigEndian.Uint16(body)
+	c.cipherSuite = id
+	if remote {
+		c.peerRandom = append(c.peerRandom[:0], body[2:34]...)
+	}
+	c.mu.Unlock()
+}
+
+// handleServerKeyExchange checks the curve selected by a remote server
+func (c *dtlsHandshakeConn) handleServerKeyExchange(body []byte) error {
+	if len(body) < 3 || body[0] != dtlsCurveTypeNamedCurve {
+		return nil
+	}
+	return c.checkCurves([]tls.CurveID{tls.CurveID(binary.BigEndian.Uint16(body[1:]))})
+}
+
+// checkCurves fails unless the first of the curves the handshake can use is
+// permitted, it is the one pions/dtls selects
+func (c *dtlsHandshakeConn) checkCurves(curves []tls.CurveID) error {
+	if c.curves == nil {
+		return nil
+	}
+
+	for _, id := range curves {
+		if containsCurve(c.curves, id) {
+			if id != curves[0] {
+				return fmt.Errorf("DTLS handshake failed: the remote prefers the elliptic curve %s over the permitted %s, the curve can't be selected",
+					curves[0], id)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("DTLS handshake failed: the remote offered none of the permitted elliptic curves, offered: [%s] permitted: [%s]",
+		curveNames(curves), curveNames(c.curves))
+}
+
+func containsUint16(values []uint16, value uint16) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsCurve(curves []tls.CurveID, curve tls.CurveID) bool {
+	for _, c := range curves {
+		if c == curve {
+			return true
+		}
+	}
+	return false
+}
+
+func curveNames(curves []tls.CurveID) string {
+	names := make([]string, 0, len(curves))
+	for _, id := range curves {
+		names = append(names, id.String())
+	}
+	return strings.Join(names, " ")
+}
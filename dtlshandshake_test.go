@@ -0,0 +1,141 @@
+package webrtc
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// dtlsHandshakeRecord builds an epoch 0 record holding a single handshake message
+func dtlsHandshakeRecord(handshakeType uint8, body []byte) []byte {
+	record := make([]byte, dtlsRecordHeaderLength+dtlsHandshakeHeaderLength, dtlsRecordHeaderLength+dtlsHandshakeHeaderLength+len(body))
+	record[0] = dtlsContentTypeHandshake
+	record[1], record[2] = 0xfe, 0xfd
+	binary.BigEndian.PutUint16(record[11:], uint16(dtlsHandshakeHeaderLength+len(body)))
+
+	handshake := record[dtlsRecordHeaderLength:]
+	handshake[0] = handshakeType
+	handshake[3] = uint8(len(body))
+	handshake[11] = uint8(len(body))
+	return append(record, body...)
+}
+
+func dtlsClientHello(suites []uint16, curves []tls.CurveID) []byte {
+	body := make([]byte, 34)
+	// Session ID and cookie
+	body = append(body, 0, 0)
+
+	body = append(body, 0, uint8(len(suites)*2))
+	for _, id := range suites {
+		body = append(body, uint8(id>>8), uint8(id))
+	}
+	// Compression methods
+	body = append(body, 1, 0)
+
+	groups := []byte{0, dtlsExtensionSupportedGroups, 0, uint8(2 + len(curves)*2), 0, uint8(len(curves) * 2)}
+	for _, id := range curves {
+		groups = append(groups, uint8(id>>8), uint8(id))
+	}
+	body = append(body, 0, uint8(len(groups)))
+	body = append(body, groups...)
+
+	return dtlsHandshakeRecord(dtlsHandshakeTypeClientHello, body)
+}
+
+func dtlsServerHello(suite uint16) []byte {
+	body := make([]byte, 34)
+	body = append(body, 0, uint8(suite>>8), uint8(suite), 0)
+	return dtlsHandshakeRecord(dtlsHandshakeTypeServerHello, body)
+}
+
+func TestDTLSHandshakeConn_ClientHello(t *testing.T) {
+	offered := dtlsClientHello(
+		[]uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA},
+		[]tls.CurveID{tls.X25519, tls.CurveP256},
+	)
+
+	for _, test := range []struct {
+		curves []tls.CurveID
+		fail   bool
+	}{
+		{nil, false},
+		{[]tls.CurveID{tls.X25519}, false},
+		// Offered, but not preferred by the remote
+		{[]tls.CurveID{tls.CurveP256}, true},
+	} {
+		c := newDTLSHandshakeConn(nil, test.curves, pcLog)
+		err := c.inspect(offered, true)
+		assert.Equal(t, test.fail, err != nil, "%v: %v", test.curves, err)
+	}
+
+	// The unknown curves are ignored
+	c := newDTLSHandshakeConn(nil, []tls.CurveID{tls.CurveP256}, pcLog)
+	assert.NoError(t, c.inspect(dtlsClientHello(
+		[]uint16{tls.TLS_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA},
+		[]tls.CurveID{tls.CurveP384, tls.CurveP256},
+	), true))
+}
+
+func TestDTLSHandshakeConn_ServerHello(t *testing.T) {
+	c := newDTLSHandshakeConn(nil, nil, pcLog)
+
+	_, ok := c.negotiatedCipherSuite()
+	assert.False(t, ok)
+
+	// A local hello is recorded as well
+	assert.NoError(t, c.inspect(dtlsServerHello(tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256), false))
+	id, ok := c.negotiatedCipherSuite()
+	assert.True(t, ok)
+	assert.Equal(t, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, id)
+
+	// The version and random of a remote hello are recorded
+	hello := dtlsServerHello(tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256)
+	body := hello[dtlsRecordHeaderLength+dtlsHandshakeHeaderLength:]
+	body[0], body[1] = 0xfe, 0xfd
+	body[2], body[33] = 0x01, 0x02
+	assert.NoError(t, c.inspect(hello, true))
+	version, id, peerRandom := c.negotiatedState()
+	assert.Equal(t, uint16(0xfefd), version)
+	assert.Equal(t, tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, id)
+	if assert.Len(t, peerRandom, 32) {
+		assert.Equal(t, uint8(0x01), peerRandom[0])
+		assert.Equal(t, uint8(0x02), peerRandom[31])
+	}
+
+	// Encrypted records are skipped
+	record := dtlsServerHello(tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256)
+	record[4] = 1
+	assert.NoError(t, c.inspect(record, true))
+}
+
+func TestPeerConnection_DTLSCipherSuites(t *testing.T) {
+	s := SettingEngine{}
+	assert.NoError(t, s.SetDTLSEllipticCurves([]tls.CurveID{tls.X25519}))
+
+	offerPC, answerPC, err := NewAPI(WithSettingEngine(s)).newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan bool)
+	dc, err := offerPC.CreateDataChannel("data", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dc.OnOpen(func() {
+		done <- true
+	})
+
+	if err = signalPair(offerPC, answerPC); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	assert.Equal(t, "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", offerPC.dtlsTransport.GetStats().DTLSCipher)
+	assert.Equal(t, "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", answerPC.dtlsTransport.GetStats().DTLSCipher)
+
+	assert.NoError(t, offerPC.Close())
+	assert.NoError(t, answerPC.Close())
+}
@@ -3,7 +3,7 @@ package webrtc
 import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
-	"crypto/rand"
+	"crypto/tls"
 	"crypto/x509"
 	"errors"
 	"fmt"
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/pions/dtls"
+	"github.com/pions/rtcp"
 	"github.com/pions/srtp"
 	"github.com/pions/webrtc/internal/mux"
 	"github.com/pions/webrtc/pkg/rtcerr"
@@ -27,24 +28,97 @@ type DTLSTransport struct {
 	iceTransport     *ICETransport
 	certificates     []Certificate
 	remoteParameters DTLSParameters
-	// State     DTLSTransportState
 
-	// OnStateChange func()
+	// stateLock guards the state apart from lock, which is held through the
+	// handshake
+	stateLock         sync.RWMutex
+	state             DTLSTransportState
+	onStateChangeHdlr func(DTLSTransportState)
+
 	// OnError       func()
 
 	conn *dtls.Conn
 
-	srtpSession   *srtp.SessionSRTP
-	srtcpSession  *srtp.SessionSRTCP
-	srtpEndpoint  *mux.Endpoint
-	srtcpEndpoint *mux.Endpoint
+	// handshakeConn follows the handshake of conn
+	handshakeConn *dtlsHandshakeConn
+
+	// handshakeErr is the reason the DTLS handshake failed
+	handshakeErr error
+
+	// remoteCertificate is the DER encoding of the certificate the remote
+	// presented in the handshake, it is guarded by stateLock
+	remoteCertificate []byte
+
+	// verifyRemoteCertificate checks the certificate of the remote along
+	// its fingerprints, it is guarded by stateLock
+	verifyRemoteCertificate func(certificate []byte) error
+
+	// sdesKeys are the SRTP keys exchanged in the a=crypto lines of the
+	// descriptions, nil unless SDES was negotiated instead of DTLS
+	sdesKeys *srtp.SessionKeys
+
+	srtpProtectionProfile SRTPProtectionProfile
+	srtpSession           *srtp.SessionSRTP
+	srtcpSession          *srtp.SessionSRTCP
+	srtpEndpoint          *mux.Endpoint
+	srtcpEndpoint         *mux.Endpoint
+
+	// srtpAuth and srtcpAuth skip the packets of the remote failing
+	// authentication before the sessions decrypt them
+	srtpAuth  *srtpAuthConn
+	srtcpAuth *srtpAuthConn
+
+	// srtpLimit caps the SRTP streams of the remote, see
+	// SettingEngine.SetMaxIncomingStreams
+	srtpLimit *srtpStreamLimitConn
+
+	onUnhandledRTPHandler  func(ssrc uint32, payloadType uint8, raw []byte)
+	onLimitExceededHandler func(ssrc uint32)
+
+	// claimedSSRCs are the SSRCs a RTPReceiver reads, their streams are
+	// no longer drained. They have their own lock: they are checked while
+	// the SRTP session waits for a stream to be read, and the session is
+	// closed under lock. The senders of the transport share it.
+	claimedSSRCs map[uint32]*RTPReceiver
+	senders      map[*RTPSender]bool
+	claimedLock  sync.RWMutex
+
+	// receiveTimes are the receive times of the last SRTP packets of the
+	// remote, see Track.ReceiveTime
+	receiveTimes *receiveTimes
+
+	// transportCC numbers the packets sent with the transport-wide
+	// sequence number extension, created with the first one
+	transportCC *transportCCHistory
+
+	// congestionControlLog logs once which of goog-remb and transport-cc
+	// the senders follow when both are negotiated
+	congestionControlLog sync.Once
+
+	// transportCCReceived keeps the arrival of the packets received with
+	// the transport-wide sequence number extension, created with the first
+	// RTPReceiver sending the feedback
+	transportCCReceived *transportCCRecorder
+
+	// A reference to the associated api object
+	api *API
 }
 
 // NewDTLSTransport creates a new DTLSTransport.
 // This constructor is part of the ORTC API. It is not
 // meant to be used together with the basic WebRTC API.
 func (api *API) NewDTLSTransport(transport *ICETransport, certificates []Certificate) (*DTLSTransport, error) {
-	t := &DTLSTransport{iceTransport: transport}
+	t := &DTLSTransport{
+		iceTransport: transport,
+		state:        DTLSTransportStateNew,
+		claimedSSRCs: map[uint32]*RTPReceiver{},
+		senders:      map[*RTPSender]bool{},
+		receiveTimes: newReceiveTimes(),
+		api:          api,
+	}
+	if transport != nil {
+		transport.onConnected(t.reconnected)
+	}
 
 	if len(certificates) > 0 {
 		now := time.Now()
@@ -55,11 +129,11 @@ func (api *API) NewDTLSTransport(transport *ICETransport, certificates []Certifi
 			t.certificates = append(t.certificates, x509Cert)
 		}
 	} else {
-		sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		sk, err := ecdsa.GenerateKey(elliptic.P256(), api.settingEngine.randomSource())
 		if err != nil {
 			return nil, &rtcerr.UnknownError{Err: err}
 		}
-		certificate, err := GenerateCertificate(sk)
+		certificate, err := generateCertificate(sk, api.settingEngine.randomSource())
 		if err != nil {
 			return nil, err
 		}
@@ -69,6 +143,145 @@ func (api *API) NewDTLSTransport(transport *ICETransport, certificates []Certifi
 	return t, nil
 }
 
+// OnUnhandledRTP sets an event handler which is invoked with the packets of
+// the streams no RTPReceiver reads, such as a simulcast layer or a RTX stream
+// the SDP didn't describe. The handler is invoked at most once per second for
+// a stream. A RTPReceiver started for the SSRC from the handler takes the
+// stream over, the packets in between are lost.
+func (t *DTLSTransport) OnUnhandledRTP(f func(ssrc uint32, payloadType uint8, raw []byte)) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.onUnhandledRTPHandler = f
+}
+
+func (t *DTLSTransport) onUnhandledRTP(ssrc uint32, payloadType uint8, raw []byte) (done chan struct{}) {
+	t.lock.RLock()
+	hdlr := t.onUnhandledRTPHandler
+	t.lock.RUnlock()
+
+	done = make(chan struct{})
+	if hdlr == nil {
+		close(done)
+		return
+	}
+
+	go func() {
+		hdlr(ssrc, payloadType, raw)
+		close(done)
+	}()
+
+	return
+}
+
+// OnLimitExceeded sets an event handler which is invoked when the packets
+// of a new SSRC are dropped, as the remote already sends as many streams as
+// SettingEngine.SetMaxIncomingStreams permits. It is invoked at most once a
+// second, with the SSRC of one of the dropped packets.
+func (t *DTLSTransport) OnLimitExceeded(f func(ssrc uint32)) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.onLimitExceededHandler = f
+}
+
+func (t *DTLSTransport) onLimitExceeded(ssrc uint32) (done chan struct{}) {
+	t.lock.RLock()
+	hdlr := t.onLimitExceededHandler
+	t.lock.RUnlock()
+
+	t.api.log.Warnf("Dropping the packets of SSRC %d, too many incoming streams", ssrc)
+	done = make(chan struct{})
+	if hdlr == nil {
+		close(done)
+		return
+	}
+
+	go func() {
+		hdlr(ssrc)
+		close(done)
+	}()
+
+	return
+}
+
+// claimSSRC records that a RTPReceiver reads the stream of the SSRC
+func (t *DTLSTransport) claimSSRC(ssrc uint32, receiver *RTPReceiver) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.claimedLock.Lock()
+	t.claimedSSRCs[ssrc] = receiver
+	t.claimedLock.Unlock()
+	if t.srtpLimit != nil {
+		t.srtpLimit.reserve(ssrc)
+	}
+}
+
+// releaseSSRC records that the stream of the SSRC was closed
+func (t *DTLSTransport) releaseSSRC(ssrc uint32) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.claimedLock.Lock()
+	delete(t.claimedSSRCs, ssrc)
+	t.claimedLock.Unlock()
+	t.receiveTimes.forget(ssrc)
+	if t.srtpLimit != nil {
+		t.srtpLimit.release(ssrc)
+	}
+}
+
+func (t *DTLSTransport) isSSRCClaimed(ssrc uint32) bool {
+	t.claimedLock.RLock()
+	defer t.claimedLock.RUnlock()
+	return t.claimedSSRCs[ssrc] != nil
+}
+
+// addSender records a RTPSender sending over the transport, until
+// removeSender
+func (t *DTLSTransport) addSender(sender *RTPSender) {
+	t.claimedLock.Lock()
+	defer t.claimedLock.Unlock()
+	t.senders[sender] = true
+}
+
+func (t *DTLSTransport) removeSender(sender *RTPSender) {
+	t.claimedLock.Lock()
+	defer t.claimedLock.Unlock()
+	delete(t.senders, sender)
+}
+
+// reconnected has the senders send the packets they held back while the ICE
+// connection was disconnected
+func (t *DTLSTransport) reconnected() {
+	t.claimedLock.RLock()
+	defer t.claimedLock.RUnlock()
+	for sender := range t.senders {
+		sender.signalReconnected()
+	}
+}
+
+// transportCCHistory returns the history of the packets sent with a
+// transport-wide sequence number, shared by the RTPSenders of the transport
+func (t *DTLSTransport) transportCCHistory() *transportCCHistory {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.transportCC == nil {
+		t.transportCC = &transportCCHistory{}
+	}
+	return t.transportCC
+}
+
+// transportCCRecorder returns the recorder of the packets received with a
+// transport-wide sequence number, shared by the RTPReceivers of the transport
+func (t *DTLSTransport) transportCCRecorder() *transportCCRecorder {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.transportCCReceived == nil {
+		t.transportCCReceived = &transportCCRecorder{}
+	}
+	return t.transportCCReceived
+}
+
 // GetLocalParameters returns the DTLS parameters of the local DTLSTransport upon construction.
 func (t *DTLSTransport) GetLocalParameters() DTLSParameters {
 	fingerprints := []DTLSFingerprint{}
@@ -90,34 +303,161 @@ func (t *DTLSTransport) startSRTP() error {
 
 	if t.srtpSession != nil && t.srtcpSession != nil {
 		return nil
-	} else if t.conn == nil {
-		return fmt.Errorf("the DTLS transport has not started yet")
 	}
 
-	srtpConfig := &srtp.Config{
-		Profile: srtp.ProtectionProfileAes128CmHmacSha1_80,
+	profile, srtpConfig, err := t.srtpConfig()
+	if err != nil {
+		return err
 	}
 
-	err := srtpConfig.ExtractSessionKeysFromDTLS(t.conn, t.isClient())
+	keys := srtpConfig.Keys
+	srtpAuth, err := newSRTPAuthConn(t.srtpEndpoint, keys.RemoteMasterKey, keys.RemoteMasterSalt, false)
 	if err != nil {
-		return fmt.Errorf("failed to extract sctp session keys: %v", err)
+		return fmt.Errorf("failed to start srtp: %v", err)
+	}
+	srtpAuth.receiveTimes = t.receiveTimes
+	srtcpAuth, err := newSRTPAuthConn(t.srtcpEndpoint, keys.RemoteMasterKey, keys.RemoteMasterSalt, true)
+	if err != nil {
+		return fmt.Errorf("failed to start srtp: %v", err)
+	}
+
+	// The limit applies to the authenticated packets
+	srtpLimit := newSRTPStreamLimitConn(srtpAuth, t.api.settingEngine.maxIncomingStreams, func(ssrc uint32) {
+		t.onLimitExceeded(ssrc)
+	})
+	t.claimedLock.RLock()
+	for ssrc := range t.claimedSSRCs {
+		srtpLimit.reserve(ssrc)
 	}
+	t.claimedLock.RUnlock()
 
-	srtpSession, err := srtp.NewSessionSRTP(t.srtpEndpoint, srtpConfig)
+	srtpSession, err := srtp.NewSessionSRTP(srtpLimit, srtpConfig)
 	if err != nil {
 		return fmt.Errorf("failed to start srtp: %v", err)
 	}
 
-	srtcpSession, err := srtp.NewSessionSRTCP(t.srtcpEndpoint, srtpConfig)
+	srtcpSession, err := srtp.NewSessionSRTCP(srtcpAuth, srtpConfig)
 	if err != nil {
 		return fmt.Errorf("failed to start srtp: %v", err)
 	}
 
+	t.srtpProtectionProfile = profile
 	t.srtpSession = srtpSession
 	t.srtcpSession = srtcpSession
+	t.srtpAuth = srtpAuth
+	t.srtpLimit = srtpLimit
+	t.srtcpAuth = srtcpAuth
+
+	go t.drainSRTCP(srtcpSession)
 	return nil
 }
 
+// drainSRTCP pulls and discards RTCP packets that don't match any SRTCP stream
+// These could be sent to the user, but right now we don't provide an API
+// to distribute orphaned RTCP messages. This is needed to make sure we don't block
+// and provides useful debugging messages. It runs until the session is
+// closed, for the PeerConnections and the ORTC users alike. Undeclared RTP
+// streams are drained by the rtpRouter of a PeerConnection.
+func (t *DTLSTransport) drainSRTCP(srtcpSession *srtp.SessionSRTCP) {
+	for {
+		r, ssrc, err := srtcpSession.AcceptStream()
+		if err != nil {
+			t.api.log.Warnf("Failed to accept RTCP %v \n", err)
+			return
+		}
+
+		go func() {
+			rtcpBuf := make([]byte, receiveMTU)
+			for {
+				i, err := r.Read(rtcpBuf)
+				if err != nil {
+					t.api.log.Warnf("Failed to read, drainSRTCP done for: %v %d \n", err, ssrc)
+					return
+				}
+
+				rtcpPacket, _, err := rtcp.Unmarshal(rtcpBuf[:i])
+				if err != nil {
+					t.api.log.Warnf("Failed to unmarshal RTCP packet, discarding: %v \n", err)
+					continue
+				}
+				t.api.log.Debugf("got RTCP: %+v", rtcpPacket)
+			}
+		}()
+	}
+}
+
+// srtpConfig returns the configuration of the SRTP sessions, with the keys
+// exchanged by SDES or exported from the DTLS connection
+func (t *DTLSTransport) srtpConfig() (SRTPProtectionProfile, *srtp.Config, error) {
+	if t.sdesKeys != nil {
+		return SRTPProtectionProfileAes128CmHmacSha1_80, &srtp.Config{
+			Keys:    *t.sdesKeys,
+			Profile: srtp.ProtectionProfileAes128CmHmacSha1_80,
+		}, nil
+	} else if t.handshakeErr != nil {
+		return 0, nil, fmt.Errorf("the DTLS handshake failed: %v", t.handshakeErr)
+	} else if t.conn == nil {
+		return 0, nil, fmt.Errorf("the DTLS transport has not started yet")
+	}
+
+	dtlsProfile, ok := t.conn.SelectedSRTPProtectionProfile()
+	if !ok {
+		return 0, nil, fmt.Errorf("no SRTP protection profile was negotiated")
+	}
+	profile, srtpProfile, ok := newSRTPProtectionProfile(dtlsProfile)
+	if !ok {
+		return 0, nil, fmt.Errorf("unsupported SRTP protection profile %#x", dtlsProfile)
+	}
+
+	srtpConfig := &srtp.Config{
+		Profile: srtpProfile,
+	}
+
+	err := srtpConfig.ExtractSessionKeysFromDTLS(t.conn, t.isClient())
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to extract sctp session keys: %v", err)
+	}
+	return profile, srtpConfig, nil
+}
+
+// SelectedSRTPProtectionProfile returns the SRTP protection profile
+// negotiated by the DTLS handshake, once the SRTP session has been started
+func (t *DTLSTransport) SelectedSRTPProtectionProfile() (SRTPProtectionProfile, bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.srtpSession == nil {
+		return SRTPProtectionProfile(Unknown), false
+	}
+	return t.srtpProtectionProfile, true
+}
+
+// GetStats returns the traffic sent and received by the DTLSTransport, as
+// counted on the sockets of the underlying ICETransport, and the negotiated
+// DTLS cipher suite and SRTP protection profile. The SRTP and SRTCP packets
+// skipped since they failed authentication are counted.
+func (t *DTLSTransport) GetStats() TransportStats {
+	stats := t.iceTransport.GetStats()
+
+	t.lock.RLock()
+	handshakeConn := t.handshakeConn
+	srtpAuth, srtcpAuth := t.srtpAuth, t.srtcpAuth
+	t.lock.RUnlock()
+	if srtpAuth != nil && srtcpAuth != nil {
+		stats.SRTPAuthFailures = srtpAuth.authFailures()
+		stats.SRTCPAuthFailures = srtcpAuth.authFailures()
+	}
+	if handshakeConn != nil {
+		if id, ok := handshakeConn.negotiatedCipherSuite(); ok {
+			stats.DTLSCipher = tls.CipherSuiteName(id)
+		}
+	}
+	if profile, ok := t.SelectedSRTPProtectionProfile(); ok {
+		stats.SRTPCipher = profile.String()
+	}
+	return stats
+}
+
 func (t *DTLSTransport) getSRTPSession() (*srtp.SessionSRTP, error) {
 	t.lock.RLock()
 	if t.srtpSession != nil {
@@ -164,20 +504,108 @@ func (t *DTLSTransport) isClient() bool {
 	return isClient
 }
 
+// State returns the current DTLS transport state.
+func (t *DTLSTransport) State() DTLSTransportState {
+	t.stateLock.RLock()
+	defer t.stateLock.RUnlock()
+	return t.state
+}
+
+// OnStateChange sets a handler that is fired when the DTLS transport state
+// changes. The state is connecting during the handshake, connected once the
+// remote is authenticated and the SRTP keys can be exported, failed when
+// the handshake failed and closed once stopped.
+func (t *DTLSTransport) OnStateChange(f func(DTLSTransportState)) {
+	t.stateLock.Lock()
+	defer t.stateLock.Unlock()
+	t.onStateChangeHdlr = f
+}
+
+// onStateChange must not be called with lock held, the handler may call
+// the methods of the DTLSTransport
+func (t *DTLSTransport) onStateChange(state DTLSTransportState) {
+	t.stateLock.Lock()
+	if t.state == state {
+		t.stateLock.Unlock()
+		return
+	}
+	t.state = state
+	hdlr := t.onStateChangeHdlr
+	t.stateLock.Unlock()
+
+	t.api.log.Infof("DTLS transport state changed: %s", state)
+	if hdlr != nil {
+		hdlr(state)
+	}
+}
+
 // Start DTLS transport negotiation with the parameters of the remote DTLS transport
 func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
+	dtlsEndpoint, err := t.prepare()
+	if err != nil {
+		return err
+	}
+
+	t.onStateChange(DTLSTransportStateConnecting)
+
+	t.lock.Lock()
+	// The SRTP sessions must not start with an unauthenticated peer
+	err = t.handshake(remoteParameters, dtlsEndpoint)
+	if err != nil {
+		t.handshakeErr = err
+	}
+	t.lock.Unlock()
+
+	if err != nil {
+		t.onStateChange(DTLSTransportStateFailed)
+		return err
+	}
+	t.onStateChange(DTLSTransportStateConnected)
+	return nil
+}
+
+// startSDES connects the transport without a DTLS handshake, the SRTP
+// sessions use the master keys exchanged in the a=crypto lines of the
+// descriptions
+func (t *DTLSTransport) startSDES(local, remote sdesCrypto) error {
+	dtlsEndpoint, err := t.prepare()
+	if err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+	// No DTLS record is expected, the endpoint would only buffer them
+	t.iceTransport.mux.RemoveEndpoint(dtlsEndpoint)
+	t.sdesKeys = &srtp.SessionKeys{
+		LocalMasterKey:   local.masterKey(),
+		LocalMasterSalt:  local.masterSalt(),
+		RemoteMasterKey:  remote.masterKey(),
+		RemoteMasterSalt: remote.masterSalt(),
+	}
+	t.lock.Unlock()
+
+	t.onStateChange(DTLSTransportStateConnected)
+	return nil
+}
+
+// prepare creates the endpoints of the ICE connection the DTLS records and
+// the SRTP and SRTCP packets are read from
+func (t *DTLSTransport) prepare() (*mux.Endpoint, error) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
 	if err := t.ensureICEConn(); err != nil {
-		return err
+		return nil, err
 	}
 
 	mx := t.iceTransport.mux
 	dtlsEndpoint := mx.NewEndpoint(mux.MatchDTLS)
 	t.srtpEndpoint = mx.NewEndpoint(mux.MatchSRTP)
 	t.srtcpEndpoint = mx.NewEndpoint(mux.MatchSRTCP)
+	return dtlsEndpoint, nil
+}
 
+func (t *DTLSTransport) handshake(remoteParameters DTLSParameters, dtlsEndpoint *mux.Endpoint) error {
 	// TODO: handle multiple certs
 	cert := t.certificates[0]
 
@@ -187,16 +615,17 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 		SRTPProtectionProfiles: []dtls.SRTPProtectionProfile{dtls.SRTP_AES128_CM_HMAC_SHA1_80},
 		ClientAuth:             dtls.RequireAnyClientCert,
 	}
+	t.handshakeConn = newDTLSHandshakeConn(dtlsEndpoint, t.api.settingEngine.dtls.EllipticCurves, t.api.log)
 	if t.isClient() {
 		// Assumes the peer offered to be passive and we accepted.
-		dtlsConn, err := dtls.Client(dtlsEndpoint, dtlsCofig)
+		dtlsConn, err := dtls.Client(t.handshakeConn, dtlsCofig)
 		if err != nil {
 			return err
 		}
 		t.conn = dtlsConn
 	} else {
 		// Assumes we offer to be passive and this is accepted.
-		dtlsConn, err := dtls.Server(dtlsEndpoint, dtlsCofig)
+		dtlsConn, err := dtls.Server(t.handshakeConn, dtlsCofig)
 		if err != nil {
 			return err
 		}
@@ -208,12 +637,101 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 	if remoteCert == nil {
 		return fmt.Errorf("peer didn't provide certificate via DTLS")
 	}
+	t.stateLock.Lock()
+	t.remoteCertificate = remoteCert.Raw
+	verify := t.verifyRemoteCertificate
+	t.stateLock.Unlock()
+
+	// The fingerprints of the parameters may be left to the verifier
+	if verify == nil || len(remoteParameters.Fingerprints) != 0 {
+		if err := t.validateFingerPrint(remoteParameters, remoteCert); err != nil {
+			return err
+		}
+	}
+	if verify != nil {
+		return verify(remoteCert.Raw)
+	}
+	return nil
+}
+
+// SetRemoteCertificateVerifier sets a function checking the DER encoding of
+// the certificate the remote presents in the DTLS handshake, for the ORTC
+// applications that got the fingerprint of the remote out of band;
+// CertificateFingerprint computes the fingerprint of the certificate. The
+// handshake fails with the error it returns. The fingerprints given to Start
+// are still checked, none may be given with a verifier. It has to be set
+// before Start.
+func (t *DTLSTransport) SetRemoteCertificateVerifier(verify func(certificate []byte) error) {
+	t.stateLock.Lock()
+	defer t.stateLock.Unlock()
+	t.verifyRemoteCertificate = verify
+}
+
+// RemoteCertificate returns the DER encoding of the certificate the remote
+// presented in the DTLS handshake, nil until the handshake completes and
+// when SDES is used. The certificate is kept when its fingerprint matches
+// none of the description of the remote, for the application to log it.
+// CertificateFingerprint computes its fingerprint.
+func (t *DTLSTransport) RemoteCertificate() []byte {
+	t.stateLock.RLock()
+	defer t.stateLock.RUnlock()
+	return t.remoteCertificate
+}
+
+// DTLSConnectionState describes the DTLS connection of a DTLSTransport, as
+// negotiated by its handshake
+type DTLSConnectionState struct {
+	// Version is the DTLS version selected by the server, 0xfefd for
+	// DTLS 1.2
+	Version uint16
+
+	// CipherSuite is the ID of the cipher suite, tls.CipherSuiteName names it
+	CipherSuite uint16
+
+	SRTPProtectionProfile SRTPProtectionProfile
+
+	// PeerRandom is the random of the hello of the remote
+	PeerRandom []byte
+
+	// PeerCertificates is the certificate chain of the remote. pions/dtls
+	// only keeps the leaf certificate of the chain.
+	PeerCertificates []*x509.Certificate
+}
+
+// ConnectionState returns the state of the DTLS connection, for debugging
+// the interoperability with a remote. It is false until the handshake
+// completes, and when SDES is used instead of DTLS.
+func (t *DTLSTransport) ConnectionState() (DTLSConnectionState, bool) {
+	// lock is held through the handshake
+	if t.State() != DTLSTransportStateConnected {
+		return DTLSConnectionState{}, false
+	}
+
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	if t.conn == nil || t.handshakeConn == nil {
+		return DTLSConnectionState{}, false
+	}
 
-	return t.validateFingerPrint(remoteParameters, remoteCert)
+	state := DTLSConnectionState{}
+	state.Version, state.CipherSuite, state.PeerRandom = t.handshakeConn.negotiatedState()
+	if dtlsProfile, ok := t.conn.SelectedSRTPProtectionProfile(); ok {
+		state.SRTPProtectionProfile, _, _ = newSRTPProtectionProfile(dtlsProfile)
+	}
+	if remoteCert := t.conn.RemoteCertificate(); remoteCert != nil {
+		state.PeerCertificates = []*x509.Certificate{remoteCert}
+	}
+	return state, true
 }
 
 // Stop stops and closes the DTLSTransport object.
 func (t *DTLSTransport) Stop() error {
+	err := t.stop()
+	t.onStateChange(DTLSTransportStateClosed)
+	return err
+}
+
+func (t *DTLSTransport) stop() error {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
@@ -241,10 +759,16 @@ func (t *DTLSTransport) Stop() error {
 }
 
 func (t *DTLSTransport) validateFingerPrint(remoteParameters DTLSParameters, remoteCert *x509.Certificate) error {
-	for _, fp := range remoteParameters.Fingerprints {
-		hashAlgo, err := dtls.HashAlgorithmString(fp.Algorithm)
+	fingerprints := append([]DTLSFingerprint{}, remoteParameters.Fingerprints...)
+	sortFingerprints(fingerprints)
+
+	// The certificate has to match one of the fingerprints, the ones of an
+	// unsupported hash function are skipped
+	mismatch := &DTLSFingerprintMismatchError{Expected: remoteParameters.Fingerprints}
+	for _, fp := range fingerprints {
+		hashAlgo, err := dtls.HashAlgorithmString(strings.ToLower(fp.Algorithm))
 		if err != nil {
-			return err
+			continue
 		}
 
 		remoteValue, err := dtls.Fingerprint(remoteCert, hashAlgo)
@@ -255,9 +779,10 @@ func (t *DTLSTransport) validateFingerPrint(remoteParameters DTLSParameters, rem
 		if strings.EqualFold(remoteValue, fp.Value) {
 			return nil
 		}
+		mismatch.Presented = append(mismatch.Presented, DTLSFingerprint{Algorithm: fp.Algorithm, Value: remoteValue})
 	}
 
-	return errors.New("no matching fingerprint")
+	return mismatch
 }
 
 func (t *DTLSTransport) ensureICEConn() error {
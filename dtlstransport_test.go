@@ -0,0 +1,150 @@
+package webrtc
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pions/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDTLSTransport_OnStateChange(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	stackA, stackB, err := newORTCPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, DTLSTransportStateNew, stackA.dtls.State())
+
+	var mu sync.Mutex
+	var states []DTLSTransportState
+	stackA.dtls.OnStateChange(func(state DTLSTransportState) {
+		// The handler may call the DTLSTransport
+		assert.Equal(t, state, stackA.dtls.State())
+
+		mu.Lock()
+		defer mu.Unlock()
+		states = append(states, state)
+	})
+
+	if err = signalORTCPair(stackA, stackB); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, DTLSTransportStateConnected, stackA.dtls.State())
+	assert.Equal(t, DTLSTransportStateConnected, stackB.dtls.State())
+
+	for _, s := range []*testORTCStack{stackA, stackB} {
+		assert.NoError(t, s.dtls.Stop())
+		assert.NoError(t, s.ice.Stop())
+	}
+	assert.Equal(t, DTLSTransportStateClosed, stackA.dtls.State())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []DTLSTransportState{
+		DTLSTransportStateConnecting,
+		DTLSTransportStateConnected,
+		DTLSTransportStateClosed,
+	}, states)
+}
+
+func TestDTLSTransport_ConnectionState(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	stackA, stackB, err := newORTCPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ok := stackA.dtls.ConnectionState()
+	assert.False(t, ok)
+
+	if err = signalORTCPair(stackA, stackB); err != nil {
+		t.Fatal(err)
+	}
+
+	stateA, ok := stackA.dtls.ConnectionState()
+	assert.True(t, ok)
+	stateB, ok := stackB.dtls.ConnectionState()
+	assert.True(t, ok)
+
+	assert.Equal(t, uint16(0xfefd), stateA.Version)
+	assert.Equal(t, stateA.Version, stateB.Version)
+	assert.NotZero(t, stateA.CipherSuite)
+	assert.Equal(t, stateA.CipherSuite, stateB.CipherSuite)
+	assert.Equal(t, SRTPProtectionProfileAes128CmHmacSha1_80, stateA.SRTPProtectionProfile)
+	assert.Len(t, stateA.PeerRandom, 32)
+	assert.Len(t, stateB.PeerRandom, 32)
+	if assert.Len(t, stateA.PeerCertificates, 1) {
+		assert.Equal(t, stackA.dtls.RemoteCertificate(), stateA.PeerCertificates[0].Raw)
+	}
+
+	for _, s := range []*testORTCStack{stackA, stackB} {
+		assert.NoError(t, s.dtls.Stop())
+		assert.NoError(t, s.ice.Stop())
+	}
+	_, ok = stackA.dtls.ConnectionState()
+	assert.False(t, ok)
+}
+
+func TestDTLSTransport_SetRemoteCertificateVerifier(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	// The fingerprints of the remote are checked against the ones exchanged
+	// out of band
+	stackA, stackB, err := newORTCPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range [][2]*testORTCStack{{stackA, stackB}, {stackB, stackA}} {
+		expected := s[1].dtls.GetLocalParameters().Fingerprints[0]
+		s[0].dtls.SetRemoteCertificateVerifier(func(certificate []byte) error {
+			fingerprint, err := CertificateFingerprint(certificate, expected.Algorithm)
+			if err != nil {
+				return err
+			}
+			if fingerprint != expected {
+				return errors.New("unexpected fingerprint")
+			}
+			return nil
+		})
+	}
+	if err = signalORTCPair(stackA, stackB); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, stackA.close())
+	assert.NoError(t, stackB.close())
+
+	// The handshake fails with the error of the verifier
+	stackA, stackB, err = newORTCPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	errRejected := errors.New("rejected")
+	for _, s := range []*testORTCStack{stackA, stackB} {
+		s.dtls.SetRemoteCertificateVerifier(func([]byte) error {
+			return errRejected
+		})
+	}
+	assert.Error(t, signalORTCPair(stackA, stackB))
+	assert.Equal(t, DTLSTransportStateFailed, stackA.dtls.State())
+
+	for _, s := range []*testORTCStack{stackA, stackB} {
+		assert.NoError(t, s.dtls.Stop())
+		assert.NoError(t, s.ice.Stop())
+	}
+}
@@ -30,6 +30,10 @@ var (
 	// ErrExistingTrack indicates that a track already exists.
 	ErrExistingTrack = errors.New("track already exists")
 
+	// ErrNoCertificate indicates that a tls.Certificate holds no
+	// certificate.
+	ErrNoCertificate = errors.New("tls.Certificate has no certificate")
+
 	// ErrPrivateKeyType indicates that a particular private key encryption
 	// chosen to generate a certificate is not supported.
 	ErrPrivateKeyType = errors.New("private key type not supported")
@@ -50,6 +54,10 @@ var (
 	// RTCPMuxPolicy was made after PeerConnection has been initialized.
 	ErrModifyingRTCPMuxPolicy = errors.New("rtcp mux policy cannot be modified")
 
+	// ErrModifyingSDPSemantics indicates that an attempt to modify
+	// SDPSemantics was made after PeerConnection has been initialized.
+	ErrModifyingSDPSemantics = errors.New("sdp semantics cannot be modified")
+
 	// ErrModifyingICECandidatePoolSize indicates that an attempt to modify
 	// ICECandidatePoolSize was made after PeerConnection has been initialized.
 	ErrModifyingICECandidatePoolSize = errors.New("ice candidate pool size cannot be modified")
@@ -79,4 +87,231 @@ var (
 	// ErrNoRemoteDescription indicates that an operation was rejected because
 	// the remote description is not set
 	ErrNoRemoteDescription = errors.New("remote description is not set")
+
+	// ErrHeaderExtensionIDExhausted indicates that no more RTP header
+	// extensions can be registered in the Media Engine
+	ErrHeaderExtensionIDExhausted = errors.New("no RTP header extension ID available")
+
+	// ErrRTCPReportInterval indicates that the requested RTCP report interval
+	// is shorter than the minimum allowed.
+	ErrRTCPReportInterval = errors.New("rtcp report interval is too short")
+
+	// ErrSSRCLatchingDisabled indicates that a RTPReceiver was started without
+	// a SSRC while latching on undeclared SSRCs is disabled.
+	ErrSSRCLatchingDisabled = errors.New("no SSRC to receive and SSRC latching is disabled")
+
+	// ErrHeaderExtensionNotNegotiated indicates that a RTP header extension
+	// was used without being negotiated with the remote.
+	ErrHeaderExtensionNotNegotiated = errors.New("header extension not negotiated")
+
+	// ErrRTCPDisabled indicates that RTCP was used while it is disabled in
+	// the SettingEngine.
+	ErrRTCPDisabled = errors.New("RTCP is disabled")
+
+	// ErrReadTimeout indicates that no packet was received before the read
+	// deadline, or within the read timeout set in the SettingEngine. It is a
+	// net.Error whose Timeout method returns true.
+	ErrReadTimeout error = &timeoutError{"read timeout"}
+
+	// ErrWriteTimeout indicates that a write couldn't be handed over before
+	// the write deadline. It is a net.Error whose Timeout method returns
+	// true.
+	ErrWriteTimeout error = &timeoutError{"write timeout"}
+
+	// ErrBandwidthEstimationBounds indicates that the bounds of the bandwidth
+	// estimate are not positive or the minimum is above the maximum.
+	ErrBandwidthEstimationBounds = errors.New("invalid bandwidth estimation bounds")
+
+	// ErrInvalidDSCP indicates that a DSCP code point doesn't fit in six bits.
+	ErrInvalidDSCP = errors.New("invalid DSCP code point")
+
+	// ErrDTLSCipherSuiteUnsupported indicates that the DTLS transport can't
+	// restrict its handshake to the cipher suites given to the SettingEngine.
+	ErrDTLSCipherSuiteUnsupported = errors.New("unsupported DTLS cipher suite")
+
+	// ErrDTLSEllipticCurveUnsupported indicates that an elliptic curve
+	// permitted in the SettingEngine is not implemented by the DTLS transport.
+	ErrDTLSEllipticCurveUnsupported = errors.New("unsupported DTLS elliptic curve")
+
+	// ErrInvalidJitterBufferLatency indicates that the latency of the jitter
+	// buffer isn't positive or is longer than a live stream can wait.
+	ErrInvalidJitterBufferLatency = errors.New("invalid jitter buffer latency")
+
+	// ErrInvalidMaxHostCandidates indicates that the limit of host candidates
+	// is negative or its policy unknown.
+	ErrInvalidMaxHostCandidates = errors.New("invalid host candidate limit")
+
+	// ErrInvalidMaxIncomingStreams indicates that the maximum of incoming
+	// streams is negative.
+	ErrInvalidMaxIncomingStreams = errors.New("invalid maximum of incoming streams")
+
+	// ErrInvalidReorderWindow indicates that the NACK reorder window is
+	// negative or longer than packets can be waited for.
+	ErrInvalidReorderWindow = errors.New("invalid NACK reorder window")
+
+	// ErrInvalidRID indicates that a simulcast RID is empty, too long or
+	// holds characters other than alphanumerics, '-' and '_'.
+	ErrInvalidRID = errors.New("invalid RID")
+
+	// ErrStatsInterval indicates that the interval of the stats sampling
+	// loop is not positive.
+	ErrStatsInterval = errors.New("invalid stats interval")
+
+	// ErrNoDepacketizer indicates that frames were read from a Track whose
+	// codec can't be depacketized.
+	ErrNoDepacketizer = errors.New("no depacketizer for the codec of the track")
+
+	// ErrReceiveBufferSize indicates the receive buffer size of a stream
+	// is not a positive number of packets.
+	ErrReceiveBufferSize = errors.New("invalid receive buffer size")
+
+	// ErrPassthroughDisabled indicates that passthrough packets were read
+	// while passthrough is not enabled in the SettingEngine.
+	ErrPassthroughDisabled = errors.New("passthrough is disabled")
+
+	// ErrRTCPFeedbackNotNegotiated indicates that a RTCP feedback message
+	// was sent without being negotiated with the remote.
+	ErrRTCPFeedbackNotNegotiated = errors.New("rtcp feedback not negotiated")
+
+	// ErrNotRawRTPTrack indicates that RTP packets were written to a Track
+	// that wasn't created with NewRawRTPTrack.
+	ErrNotRawRTPTrack = errors.New("track does not accept raw RTP")
+
+	// ErrInvalidSlowConsumerThreshold indicates that the drop rate above
+	// which a forwarded RTPSender is slow is negative or not below 1.
+	ErrInvalidSlowConsumerThreshold = errors.New("invalid slow consumer threshold")
+
+	// ErrNotSampleTrack indicates that a sample was written to a Track
+	// created with NewRawRTPTrack.
+	ErrNotSampleTrack = errors.New("track does not accept samples")
+
+	// ErrTrackNotSending indicates that media was written to a Track
+	// before it was added to a PeerConnection.
+	ErrTrackNotSending = errors.New("track is not sent")
+
+	// ErrTrackNotReceived indicates that RTP packets were read from a Track
+	// that is sent rather than received.
+	ErrTrackNotReceived = errors.New("track is not received")
+
+	// ErrInvalidAudioRedundancy indicates the number of redundant encodings
+	// sent with each audio packet is negative.
+	ErrInvalidAudioRedundancy = errors.New("invalid audio redundancy")
+
+	// ErrDataChannelsOnlyWithTracks indicates that an offer without media
+	// sections was requested while tracks are sent.
+	ErrDataChannelsOnlyWithTracks = errors.New("data channels only offer with tracks")
+
+	// ErrRTPSenderStarted indicates that the initial RTP state of a
+	// RTPSender was set after it started sending.
+	ErrRTPSenderStarted = errors.New("rtp sender already started")
+
+	// ErrRTPSenderStopped indicates that media was written to a Track after
+	// its RTPSender was stopped.
+	ErrRTPSenderStopped = errors.New("rtp sender stopped")
+
+	// ErrSCTPTransportStopped indicates that the SCTPTransport was stopped
+	// before its association was established.
+	ErrSCTPTransportStopped = errors.New("SCTPTransport stopped while connecting")
+
+	// ErrInvalidKeyFrameRequestInterval indicates the minimum interval
+	// between the keyframe requests is negative.
+	ErrInvalidKeyFrameRequestInterval = errors.New("invalid keyframe request interval")
+
+	// ErrInvalidNAT1To1IPs indicates that a NAT 1:1 IP of the SettingEngine
+	// is malformed, or is advertised in candidates other than host or srflx.
+	ErrInvalidNAT1To1IPs = errors.New("invalid NAT 1:1 IPs")
+
+	// ErrICEConnInUse indicates that an ICEGatherer gathered while the ICE
+	// conn of the SettingEngine is used by another one.
+	ErrICEConnInUse = errors.New("ice conn already in use")
+
+	// ErrRemoteRollback indicates that a remote description was rolled
+	// back, the transports are already started with the remote offer or
+	// pranswer and only the local offer can be rolled back.
+	ErrRemoteRollback = errors.New("remote description can not be rolled back")
+
+	// ErrRollbackPranswer indicates that a local description was rolled
+	// back while a pranswer is pending, which can't be.
+	ErrRollbackPranswer = errors.New("can not roll back while a pranswer is pending")
+
+	// ErrRTPReceiverStarted indicates that a RTPReceiver was configured
+	// after Receive was called.
+	ErrRTPReceiverStarted = errors.New("rtp receiver already started")
+
+	// ErrSRTCPNotEstablished indicates that RTCP was read from a Track
+	// received without RTCP.
+	ErrSRTCPNotEstablished = errors.New("SRTCP not established")
+
+	// ErrInvalidReadStreamRetry indicates the retries of the opening of a
+	// ReadStream are negative.
+	ErrInvalidReadStreamRetry = errors.New("invalid read stream retry")
+
+	// ErrInvalidDisconnectedBuffer indicates the duration the packets are
+	// held back while the ICE connection is disconnected is negative.
+	ErrInvalidDisconnectedBuffer = errors.New("invalid disconnected buffer")
+
+	// ErrInvalidICERestartRetry indicates the automatic restarts of a failed
+	// ICE connection are negative.
+	ErrInvalidICERestartRetry = errors.New("invalid ICE restart retry")
+
+	// ErrConnectionFailed indicates that a PeerConnection failed to
+	// connect.
+	ErrConnectionFailed = errors.New("connection failed")
+
+	// ErrInvalidTransmissionOffset indicates the transmission time offset
+	// doesn't fit in the 24 bits of the toffset extension.
+	ErrInvalidTransmissionOffset = errors.New("invalid transmission offset")
+
+	// ErrInvalidAudioLevel indicates the audio level exceeds the 127 -dBov
+	// of the ssrc-audio-level extension.
+	ErrInvalidAudioLevel = errors.New("invalid audio level")
+
+	// ErrInvalidDrainRate indicates the bitrate or the longest delay the
+	// packets of a RTPReceiver are delivered with is negative.
+	ErrInvalidDrainRate = errors.New("invalid drain rate")
+
+	// ErrRTPReceiverNotStarted indicates that a RTPReceiver was used before
+	// Receive was called.
+	ErrRTPReceiverNotStarted = errors.New("rtp receiver not started")
+
+	// ErrSSRCAnnounced indicates that a RTPReceiver was already created for
+	// the SSRC with ReceiveSSRC.
+	ErrSSRCAnnounced = errors.New("SSRC already announced")
+
+	// ErrInvalidSSRC indicates that a stream was announced with a zero SSRC.
+	ErrInvalidSSRC = errors.New("invalid SSRC")
+
+	// ErrModifyingICETransportPolicy indicates that an attempt to modify
+	// ICETransportPolicy was made while the ICEConn of the SettingEngine
+	// can't be gathered again.
+	ErrModifyingICETransportPolicy = errors.New("ice transport policy cannot be modified with an ice conn")
+
+	// ErrDTLSTransportNotConnected indicates RTCP was written before the
+	// DTLS transport of the PeerConnection connected
+	ErrDTLSTransportNotConnected = errors.New("the DTLS transport is not connected")
+
+	// ErrSenderNotCreatedByConnection indicates that RemoveTrack was called
+	// with a RTPSender of another PeerConnection.
+	ErrSenderNotCreatedByConnection = errors.New("RTPSender not created by the PeerConnection")
+
+	// ErrDetachNotEnabled indicates that Detach was called on a DataChannel
+	// of an API whose SettingEngine doesn't detach data channels.
+	ErrDetachNotEnabled = errors.New("enable detaching by calling webrtc.DetachDataChannels()")
+
+	// ErrDetachBeforeOpened indicates that Detach was called on a
+	// DataChannel that isn't open yet.
+	ErrDetachBeforeOpened = errors.New("datachannel not opened yet, try calling Detach from OnOpen")
+
+	// ErrDetachNegotiated indicates that Detach was called on a negotiated
+	// DataChannel, the messages of which are delivered to OnMessage.
+	ErrDetachNegotiated = errors.New("negotiated datachannels can't be detached")
 )
+
+// timeoutError is the net.Error of the deadlines and timeouts
+type timeoutError struct {
+	msg string
+}
+
+func (e *timeoutError) Error() string   { return e.msg }
+func (e *timeoutError) Timeout() bool   { return true }
+func (e *timeoutError) Temporary() bool { return true }
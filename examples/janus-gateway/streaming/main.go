@@ -63,7 +63,7 @@ func main() {
 			panic(err)
 		}
 		for {
-			err = i.AddPacket(<-track.Packets)
+			err = i.WriteRTP(<-track.Packets)
 			if err != nil {
 				panic(err)
 			}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pions/webrtc"
+	"github.com/pions/webrtc/pkg/media"
+	"github.com/pions/webrtc/pkg/media/ivfreader"
+	"github.com/pions/webrtc/pkg/media/oggreader"
+
+	"github.com/pions/webrtc/examples/internal/signal"
+)
+
+type mediaReader interface {
+	media.SampleReader
+	Close() error
+}
+
+func playFromDisk(r mediaReader, track *webrtc.Track) {
+	defer func() {
+		if err := r.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	if err := track.SendSamples(r); err != nil {
+		panic(err)
+	}
+	fmt.Printf("Done playing %s \n", track.Kind)
+}
+
+func main() {
+	// Open the files before anything else, so a missing one is reported first
+	ivf, _, err := ivfreader.New("output.ivf")
+	if err != nil {
+		panic(err)
+	}
+	ogg, _, err := oggreader.New("output.ogg")
+	if err != nil {
+		panic(err)
+	}
+
+	// Everything below is the pion-WebRTC API! Thanks for using it ❤️.
+
+	// Prepare the configuration
+	config := webrtc.Configuration{
+		ICEServers: []webrtc.ICEServer{
+			{
+				URLs: []string{"stun:stun.l.google.com:19302"},
+			},
+		},
+	}
+
+	// Create a new RTCPeerConnection
+	peerConnection, err := webrtc.NewPeerConnection(config)
+	if err != nil {
+		panic(err)
+	}
+
+	// Create a audio track
+	opusTrack, err := peerConnection.NewSampleTrack(webrtc.DefaultPayloadTypeOpus, "audio", "pion1")
+	if err != nil {
+		panic(err)
+	}
+	_, err = peerConnection.AddTrack(opusTrack)
+	if err != nil {
+		panic(err)
+	}
+
+	// Create a video track
+	vp8Track, err := peerConnection.NewSampleTrack(webrtc.DefaultPayloadTypeVP8, "video", "pion2")
+	if err != nil {
+		panic(err)
+	}
+	_, err = peerConnection.AddTrack(vp8Track)
+	if err != nil {
+		panic(err)
+	}
+
+	// Set the handler for ICE connection state
+	// This will notify you when the peer has connected/disconnected
+	var play sync.Once
+	peerConnection.OnICEConnectionStateChange(func(connectionState webrtc.ICEConnectionState) {
+		fmt.Printf("Connection State has changed %s \n", connectionState.String())
+
+		// Start playing the files once connected, a PLI of the browser
+		// skips the video to its next keyframe
+		if connectionState == webrtc.ICEConnectionStateConnected {
+			play.Do(func() {
+				go playFromDisk(ivf, vp8Track)
+				go playFromDisk(ogg, opusTrack)
+			})
+		}
+	})
+
+	// Wait for the offer to be pasted
+	offer := webrtc.SessionDescription{}
+	signal.Decode(signal.MustReadStdin(), &offer)
+
+	// Set the remote SessionDescription
+	err = peerConnection.SetRemoteDescription(offer)
+	if err != nil {
+		panic(err)
+	}
+
+	// Create an answer
+	answer, err := peerConnection.CreateAnswer(nil)
+	if err != nil {
+		panic(err)
+	}
+
+	// Sets the LocalDescription, and starts our UDP listeners
+	err = peerConnection.SetLocalDescription(answer)
+	if err != nil {
+		panic(err)
+	}
+
+	// Output the answer in base64 so we can paste it in browser
+	fmt.Println(signal.Encode(answer))
+
+	// Block forever
+	select {}
+}
@@ -2,11 +2,14 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/pions/rtcp"
+	"github.com/pions/rtp"
 	"github.com/pions/webrtc"
 	"github.com/pions/webrtc/pkg/media/ivfwriter"
+	"github.com/pions/webrtc/pkg/media/oggwriter"
 
 	"github.com/pions/webrtc/examples/internal/signal"
 )
@@ -57,18 +60,21 @@ func main() {
 			}
 		}()
 
-		if track.Codec.Name == webrtc.VP8 {
+		switch track.Codec.Name {
+		case webrtc.VP8:
 			fmt.Println("Got VP8 track, saving to disk as output.ivf")
 			i, err := ivfwriter.New("output.ivf")
 			if err != nil {
 				panic(err)
 			}
-			for {
-				err = i.AddPacket(<-track.Packets)
-				if err != nil {
-					panic(err)
-				}
+			saveToDisk(i, track)
+		case webrtc.Opus:
+			fmt.Println("Got Opus track, saving to disk as output.ogg")
+			o, err := oggwriter.New("output.ogg", 48000, 2)
+			if err != nil {
+				panic(err)
 			}
+			saveToDisk(o, track)
 		}
 	})
 
@@ -106,3 +112,31 @@ func main() {
 	// Block forever
 	select {}
 }
+
+type mediaWriter interface {
+	WriteRTP(packet *rtp.Packet) error
+	io.Closer
+}
+
+// saveToDisk writes the packets of the track until it is done, the file is
+// closed afterwards
+func saveToDisk(w mediaWriter, track *webrtc.Track) {
+	defer func() {
+		if err := w.Close(); err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	for {
+		packet, err := track.ReadRTP()
+		if err != nil {
+			if err != io.EOF {
+				fmt.Println(err)
+			}
+			return
+		}
+		if err := w.WriteRTP(packet); err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package webrtc
+
+import (
+	"strings"
+)
+
+// Defaults of the format parameters the codecs are matched with, when the
+// a=fmtp attribute leaves them out
+const (
+	h264DefaultPacketizationMode = "0"
+	h264DefaultProfileLevelID    = "420010"
+	vp9DefaultProfileID          = "0"
+)
+
+// fmtpParameters returns the parameters of the format specific parameters of
+// an a=fmtp attribute, separated by semicolons. The names are lower cased,
+// the parameters without a value are kept with an empty one.
+func fmtpParameters(line string) map[string]string {
+	parameters := map[string]string{}
+	for _, p := range strings.Split(line, ";") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		kv := strings.SplitN(p, "=", 2)
+		value := ""
+		if len(kv) == 2 {
+			value = strings.TrimSpace(kv[1])
+		}
+		parameters[strings.ToLower(strings.TrimSpace(kv[0]))] = value
+	}
+	return parameters
+}
+
+// fmtpParameter returns a parameter of the format specific parameters, or
+// def when they don't have it
+func fmtpParameter(parameters map[string]string, name, def string) string {
+	if value, ok := parameters[name]; ok && value != "" {
+		return value
+	}
+	return def
+}
+
+// fmtpMatches returns whether a remote codec with the format specific
+// parameters remote can be used with a local codec of the same name with the
+// parameters local.
+//
+// H264 needs the same packetization mode and profile, the level of the
+// profile-level-id isn't compared (RFC 6184 section 8.2.2). VP9 needs the
+// same profile-id. The parameters of the other codecs are preferences that
+// don't prevent the codecs from being used.
+func fmtpMatches(name, local, remote string) bool {
+	localParameters, remoteParameters := fmtpParameters(local), fmtpParameters(remote)
+	switch {
+	case strings.EqualFold(name, H264):
+		if fmtpParameter(localParameters, "packetization-mode", h264DefaultPacketizationMode) !=
+			fmtpParameter(remoteParameters, "packetization-mode", h264DefaultPacketizationMode) {
+			return false
+		}
+		return h264Profile(fmtpParameter(localParameters, "profile-level-id", h264DefaultProfileLevelID)) ==
+			h264Profile(fmtpParameter(remoteParameters, "profile-level-id", h264DefaultProfileLevelID))
+	case strings.EqualFold(name, VP9):
+		return fmtpParameter(localParameters, "profile-id", vp9DefaultProfileID) ==
+			fmtpParameter(remoteParameters, "profile-id", vp9DefaultProfileID)
+	default:
+		return true
+	}
+}
+
+// h264Profile returns the profile_idc and profile-iop bytes of a
+// profile-level-id, the level_idc byte left out
+// https://tools.ietf.org/html/rfc6184#section-8.1
+func h264Profile(profileLevelID string) string {
+	if len(profileLevelID) != 6 {
+		return ""
+	}
+	return strings.ToLower(profileLevelID[:4])
+}
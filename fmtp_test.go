@@ -0,0 +1,43 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFmtpParameters(t *testing.T) {
+	assert.Equal(t, map[string]string{
+		"level-asymmetry-allowed": "1",
+		"packetization-mode":      "1",
+		"profile-level-id":        "42e01f",
+		"usedtx":                  "",
+	}, fmtpParameters("level-asymmetry-allowed=1; Packetization-Mode=1;profile-level-id=42e01f;usedtx"))
+	assert.Empty(t, fmtpParameters(""))
+}
+
+func TestFmtpMatches(t *testing.T) {
+	testCases := []struct {
+		name          string
+		local, remote string
+		matches       bool
+	}{
+		{H264, "packetization-mode=1;profile-level-id=42001f", "profile-level-id=42001f;packetization-mode=1", true},
+		// The level isn't compared
+		{H264, "packetization-mode=1;profile-level-id=42001f", "packetization-mode=1;profile-level-id=42001E", true},
+		{H264, "packetization-mode=1;profile-level-id=42001f", "packetization-mode=0;profile-level-id=42001f", false},
+		{H264, "packetization-mode=1;profile-level-id=42001f", "profile-level-id=42001f", false},
+		{H264, "packetization-mode=1;profile-level-id=42001f", "packetization-mode=1;profile-level-id=42e01f", false},
+		{H264, "packetization-mode=1;profile-level-id=42001f", "packetization-mode=1;profile-level-id=640032", false},
+		// The defaults of RFC 6184
+		{H264, "", "packetization-mode=0;profile-level-id=42000b", true},
+		{VP9, "", "profile-id=0", true},
+		{VP9, "profile-id=0", "profile-id=2", false},
+		{Opus, "minptime=10;useinbandfec=1", "useinbandfec=0", true},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.matches, fmtpMatches(testCase.name, testCase.local, testCase.remote),
+			"%s %q %q", testCase.name, testCase.local, testCase.remote)
+	}
+}
@@ -0,0 +1,198 @@
+package webrtc
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// slowConsumerWindow is the number of packets the drop rate of a RTPSender
+// is measured over
+const slowConsumerWindow = 100
+
+// ForwardedStats counts the packets a Forwarder wrote to the Track of a
+// RTPSender
+type ForwardedStats struct {
+	// PacketsForwarded is the number of packets the Track took
+	PacketsForwarded uint64
+
+	// PacketsDropped is the number of packets whose write failed
+	PacketsDropped uint64
+
+	// PacketsLate is the number of the dropped packets the Track didn't
+	// take before its write deadline, the RTPSender lagging behind
+	PacketsLate uint64
+}
+
+// forwardedSender is the state of the forwarding to a RTPSender
+type forwardedSender struct {
+	sender *RTPSender
+	stats  ForwardedStats
+
+	// The packets written and dropped in the current window
+	windowWritten int
+	windowDropped int
+}
+
+// Forwarder forwards the RTP packets of a received Track to the raw RTP
+// Tracks of local RTPSenders, the building block of a SFU. A single reader
+// writes the packets in the order they are read, one sender after the
+// other: every Track gets the stream in the order it was received, without
+// interleaving whatever the concurrency of the application.
+//
+// A write blocks the following ones until the Track takes the packet, a
+// write deadline set on the Track of a slow RTPSender drops its packets
+// instead. The Forwarder counts them, see Stats and OnSlowConsumer.
+type Forwarder struct {
+	receiver *RTPReceiver
+	senders  []*forwardedSender
+
+	mu                    sync.Mutex
+	stats                 map[*RTPSender]*ForwardedStats
+	slowConsumerThreshold float64
+	onSlowConsumerHandler func(*RTPSender)
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Forward starts forwarding the packets of the Track of receiver to the
+// Tracks of senders, which must be raw RTP Tracks. The Forwarder reads the
+// packets with ReadRTP, the Track must not be read otherwise. The header
+// extensions are remapped to the ones negotiated for each Track.
+//
+// The forwarding goes on until Stop is called, the RTPReceiver stops or
+// every RTPSender was stopped. A stopped RTPSender is left out of the
+// following packets.
+func Forward(receiver *RTPReceiver, senders ...*RTPSender) (*Forwarder, error) {
+	if receiver.Track == nil {
+		return nil, ErrRTPReceiverNotStarted
+	}
+	for _, sender := range senders {
+		if !sender.Track.isRawRTP {
+			return nil, ErrNotRawRTPTrack
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &Forwarder{
+		receiver: receiver,
+		stats:    make(map[*RTPSender]*ForwardedStats),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	for _, sender := range senders {
+		forwarded := &forwardedSender{sender: sender}
+		f.senders = append(f.senders, forwarded)
+		f.stats[sender] = &forwarded.stats
+	}
+	go f.run(ctx)
+	return f, nil
+}
+
+// OnSlowConsumer sets an event handler which is invoked when more than
+// threshold of the packets written to the Track of a RTPSender were
+// dropped, a fraction from 0 up to 1 excluded. The drop rate is measured
+// over windows of 100 packets, the handler is invoked once per window
+// above the threshold. The application may stop the RTPSender, it is then
+// left out.
+func (f *Forwarder) OnSlowConsumer(threshold float64, handler func(*RTPSender)) error {
+	if threshold < 0 || threshold >= 1 {
+		return ErrInvalidSlowConsumerThreshold
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.slowConsumerThreshold = threshold
+	f.onSlowConsumerHandler = handler
+	return nil
+}
+
+// Stats returns the counts of the packets written to the Track of sender,
+// false if sender isn't one the Forwarder was started with
+func (f *Forwarder) Stats(sender *RTPSender) (ForwardedStats, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stats, ok := f.stats[sender]
+	if !ok {
+		return ForwardedStats{}, false
+	}
+	return *stats, true
+}
+
+func (f *Forwarder) run(ctx context.Context) {
+	defer close(f.done)
+
+	headerExtensions := f.receiver.Track.HeaderExtensions()
+	for len(f.senders) != 0 {
+		packet, err := f.receiver.ReadRTPContext(ctx)
+		switch err {
+		case nil:
+		case ErrReadTimeout:
+			continue
+		case io.EOF, context.Canceled:
+			return
+		default:
+			f.receiver.api.log.Warnf("Failed to read the forwarded RTP: %v", err)
+			return
+		}
+
+		senders := f.senders[:0]
+		for _, forwarded := range f.senders {
+			err := forwarded.sender.Track.WriteRTP(packet, headerExtensions)
+			if err == ErrRTPSenderStopped {
+				continue
+			}
+			f.written(forwarded, err)
+			senders = append(senders, forwarded)
+		}
+		f.senders = senders
+	}
+}
+
+// written counts a packet written to the Track of a RTPSender, invoking
+// the OnSlowConsumer handler once a window dropped too many of them
+func (f *Forwarder) written(forwarded *forwardedSender, err error) {
+	f.mu.Lock()
+	switch err {
+	case nil:
+		forwarded.stats.PacketsForwarded++
+	case ErrWriteTimeout:
+		forwarded.stats.PacketsDropped++
+		forwarded.stats.PacketsLate++
+		forwarded.windowDropped++
+	default:
+		f.receiver.api.log.Warnf("Failed to forward RTP to %s: %v", forwarded.sender.Track.ID, err)
+		forwarded.stats.PacketsDropped++
+		forwarded.windowDropped++
+	}
+
+	forwarded.windowWritten++
+	if forwarded.windowWritten < slowConsumerWindow {
+		f.mu.Unlock()
+		return
+	}
+	dropRate := float64(forwarded.windowDropped) / float64(forwarded.windowWritten)
+	forwarded.windowWritten, forwarded.windowDropped = 0, 0
+	hdlr := f.onSlowConsumerHandler
+	slow := dropRate > f.slowConsumerThreshold
+	f.mu.Unlock()
+
+	// The handler may stop the Forwarder, which waits for this loop
+	if hdlr != nil && slow {
+		go hdlr(forwarded.sender)
+	}
+}
+
+// Stop stops the forwarding, it returns once the last packet read was
+// written to every Track
+func (f *Forwarder) Stop() {
+	f.cancel()
+	<-f.done
+}
+
+// Done returns a channel that is closed once the forwarding ended
+func (f *Forwarder) Done() <-chan struct{} {
+	return f.done
+}
@@ -0,0 +1,146 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pions/rtp"
+	"github.com/pions/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func newForwardedTrack(t *testing.T) (*Track, *RTPSender) {
+	track, err := NewRawRTPTrack(DefaultPayloadTypeVP8, 5000, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return track, NewAPI().NewRTPSender(track, nil)
+}
+
+func TestForward(t *testing.T) {
+	receiver := NewAPI().NewRTPReceiver(RTPCodecTypeVideo, nil)
+	_, err := Forward(receiver)
+	assert.Equal(t, ErrRTPReceiverNotStarted, err)
+	receiver.Track = &Track{}
+
+	sampleTrack, err := NewSampleTrack(DefaultPayloadTypeOpus, "audio", "pion", NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Forward(receiver, NewAPI().NewRTPSender(sampleTrack, nil))
+	assert.Equal(t, ErrNotRawRTPTrack, err)
+
+	first, firstSender := newForwardedTrack(t)
+	second, secondSender := newForwardedTrack(t)
+	forwarder, err := Forward(receiver, firstSender, secondSender)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each Track gets every packet, in order
+	const packets = 100
+	go func() {
+		for i := 0; i < packets; i++ {
+			receiver.rtpOut <- &rtp.Packet{Header: rtp.Header{SequenceNumber: uint16(i)}}
+		}
+	}()
+	for i := 0; i < packets; i++ {
+		assert.Equal(t, uint16(i), (<-first.rawInput).SequenceNumber)
+		assert.Equal(t, uint16(i), (<-second.rawInput).SequenceNumber)
+	}
+
+	// A stopped sender is left out
+	secondSender.Stop()
+	receiver.rtpOut <- &rtp.Packet{Header: rtp.Header{SequenceNumber: packets}}
+	assert.Equal(t, uint16(packets), (<-first.rawInput).SequenceNumber)
+
+	// The forwarding ends with the receiver
+	receiver.closeRTPOut()
+	select {
+	case <-forwarder.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the forwarding should end once the receiver stopped")
+	}
+	forwarder.Stop()
+}
+
+func TestForwarder_Stop(t *testing.T) {
+	receiver := NewAPI().NewRTPReceiver(RTPCodecTypeVideo, nil)
+	receiver.Track = &Track{}
+	_, sender := newForwardedTrack(t)
+
+	forwarder, err := Forward(receiver, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forwarder.Stop()
+	<-forwarder.Done()
+
+	// The forwarding also ends once every sender stopped
+	forwarder, err = Forward(receiver, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender.Stop()
+	receiver.rtpOut <- &rtp.Packet{}
+	select {
+	case <-forwarder.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the forwarding should end once every sender stopped")
+	}
+}
+
+func TestForwarder_OnSlowConsumer(t *testing.T) {
+	to := test.TimeOut(time.Second * 20)
+	defer to.Stop()
+
+	receiver := NewAPI().NewRTPReceiver(RTPCodecTypeVideo, nil)
+	receiver.Track = &Track{}
+	fast, fastSender := newForwardedTrack(t)
+	slow, slowSender := newForwardedTrack(t)
+
+	// The slow Track takes no packet before its write deadline
+	if err := slow.SetWriteDeadline(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	forwarder, err := Forward(receiver, fastSender, slowSender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ErrInvalidSlowConsumerThreshold, forwarder.OnSlowConsumer(1, nil))
+	assert.Equal(t, ErrInvalidSlowConsumerThreshold, forwarder.OnSlowConsumer(-0.1, nil))
+
+	slowConsumers := make(chan *RTPSender, 1)
+	assert.NoError(t, forwarder.OnSlowConsumer(0.25, func(sender *RTPSender) {
+		slowConsumers <- sender
+	}))
+
+	go func() {
+		for i := 0; i < slowConsumerWindow; i++ {
+			receiver.rtpOut <- &rtp.Packet{Header: rtp.Header{SequenceNumber: uint16(i)}}
+		}
+	}()
+	for i := 0; i < slowConsumerWindow; i++ {
+		assert.Equal(t, uint16(i), (<-fast.rawInput).SequenceNumber)
+	}
+
+	assert.Equal(t, slowSender, <-slowConsumers)
+	select {
+	case sender := <-slowConsumers:
+		t.Fatalf("OnSlowConsumer invoked for another sender %v", sender)
+	default:
+	}
+
+	stats, ok := forwarder.Stats(fastSender)
+	assert.True(t, ok)
+	assert.Equal(t, ForwardedStats{PacketsForwarded: slowConsumerWindow}, stats)
+	stats, ok = forwarder.Stats(slowSender)
+	assert.True(t, ok)
+	assert.Equal(t, ForwardedStats{PacketsDropped: slowConsumerWindow, PacketsLate: slowConsumerWindow}, stats)
+
+	_, ok = forwarder.Stats(NewAPI().NewRTPSender(fast, nil))
+	assert.False(t, ok)
+
+	forwarder.Stop()
+}
@@ -0,0 +1,203 @@
+package webrtc
+
+import (
+	"github.com/pions/rtp"
+	"github.com/pkg/errors"
+)
+
+// frameDepacketizer extracts the part of a frame carried by a RTP packet
+type frameDepacketizer interface {
+	// depacketize returns the frame data carried by a packet and whether the
+	// packet starts a frame. afterFrameEnd tells if the previous packet was
+	// received and ended a frame, for the codecs whose payload can't tell.
+	depacketize(packet *rtp.Packet, afterFrameEnd bool) (data []byte, frameStart bool, err error)
+}
+
+func newFrameDepacketizer(codec *RTPCodec) (frameDepacketizer, error) {
+	if codec == nil {
+		return nil, ErrNoDepacketizer
+	}
+
+	switch codec.Name {
+	case VP8:
+		return &vp8Depacketizer{}, nil
+	case H264:
+		return &h264Depacketizer{}, nil
+	}
+	return nil, ErrNoDepacketizer
+}
+
+// vp8Depacketizer strips the VP8 payload descriptor
+// https://tools.ietf.org/html/rfc7741#section-4.2
+type vp8Depacketizer struct{}
+
+const (
+	vp8ExtendedBit    = 0x80
+	vp8StartBit       = 0x10
+	vp8PartitionMask  = 0x07
+	vp8PictureIDBit   = 0x80
+	vp8TL0PICIDXBit   = 0x40
+	vp8TIDKEYIDXMask  = 0x30
+	vp8PictureIDLong  = 0x80
+	vp8DescriptorSize = 1
+)
+
+func (d *vp8Depacketizer) depacketize(packet *rtp.Packet, afterFrameEnd bool) ([]byte, bool, error) {
+	payload := packet.Payload
+	if len(payload) < vp8DescriptorSize {
+		return nil, false, errors.New("VP8 payload is too short")
+	}
+
+	// A frame starts with the beginning of its first partition
+	frameStart := payload[0]&vp8StartBit != 0 && payload[0]&vp8PartitionMask == 0
+
+	i := vp8DescriptorSize
+	if payload[0]&vp8ExtendedBit != 0 {
+		if len(payload) <= i {
+			return nil, false, errors.New("VP8 payload descriptor is truncated")
+		}
+		extension := payload[i]
+		i++
+
+		if extension&vp8PictureIDBit != 0 {
+			if len(payload) <= i {
+				return nil, false, errors.New("VP8 payload descriptor is truncated")
+			}
+			if payload[i]&vp8PictureIDLong != 0 {
+				i += 2
+			} else {
+				i++
+			}
+		}
+		if extension&vp8TL0PICIDXBit != 0 {
+			i++
+		}
+		if extension&vp8TIDKEYIDXMask != 0 {
+			i++
+		}
+	}
+
+	if i > len(payload) {
+		return nil, false, errors.New("VP8 payload descriptor is truncated")
+	}
+	return payload[i:], frameStart, nil
+}
+
+// h264Depacketizer turns the NAL units of H264 packets into an Annex B
+// byte stream, prefixing each with a start code
+// https://tools.ietf.org/html/rfc6184#section-5
+type h264Depacketizer struct{}
+
+const (
+	h264NALTypeMask  = 0x1F
+	h264NALRefMask   = 0xE0
+	h264STAPA        = 24
+	h264FUA          = 28
+	h264FUStartBit   = 0x80
+	h264FUHeaderSize = 2
+	h264STAPASize    = 2
+)
+
+var h264StartCode = []byte{0x00, 0x00, 0x00, 0x01}
+
+func (d *h264Depacketizer) depacketize(packet *rtp.Packet, afterFrameEnd bool) ([]byte, bool, error) {
+	payload := packet.Payload
+	if len(payload) < 1 {
+		return nil, false, errors.New("H264 payload is too short")
+	}
+
+	// An access unit carries no marker of its beginning, it starts right after
+	// the packet with the marker bit of the previous one
+	switch nalType := payload[0] & h264NALTypeMask; {
+	case nalType > 0 && nalType < h264STAPA:
+		return append(append([]byte{}, h264StartCode...), payload...), afterFrameEnd, nil
+
+	case nalType == h264STAPA:
+		var data []byte
+		for i := 1; i < len(payload); {
+			if i+h264STAPASize > len(payload) {
+				return nil, false, errors.New("H264 STAP-A is truncated")
+			}
+			size := int(payload[i])<<8 | int(payload[i+1])
+			i += h264STAPASize
+			if i+size > len(payload) {
+				return nil, false, errors.New("H264 STAP-A NAL unit exceeds the payload")
+			}
+			data = append(data, h264StartCode...)
+			data = append(data, payload[i:i+size]...)
+			i += size
+		}
+		return data, afterFrameEnd, nil
+
+	case nalType == h264FUA:
+		if len(payload) < h264FUHeaderSize {
+			return nil, false, errors.New("H264 FU-A is too short")
+		}
+		if payload[1]&h264FUStartBit == 0 {
+			return payload[h264FUHeaderSize:], false, nil
+		}
+
+		// The header of the fragmented NAL unit is rebuilt from the FU indicator and header
+		data := append([]byte{}, h264StartCode...)
+		data = append(data, payload[0]&h264NALRefMask|payload[1]&h264NALTypeMask)
+		return append(data, payload[h264FUHeaderSize:]...), afterFrameEnd, nil
+
+	default:
+		return nil, false, errors.Errorf("H264 packetization of NAL unit type %d is not supported", nalType)
+	}
+}
+
+// frameAssembler assembles the packets of a stream into complete frames. A
+// frame ends with the packet carrying the marker bit, frames missing a
+// packet are dropped.
+type frameAssembler struct {
+	depacketizer frameDepacketizer
+
+	frame      []byte
+	timestamp  uint32
+	assembling bool
+
+	hasLast            bool
+	lastSequenceNumber uint16
+	lastMarker         bool
+}
+
+func (a *frameAssembler) drop() {
+	a.frame = nil
+	a.assembling = false
+}
+
+// push adds a packet and returns the frame it completes, if any
+func (a *frameAssembler) push(packet *rtp.Packet) ([]byte, bool) {
+	contiguous := a.hasLast && packet.SequenceNumber == a.lastSequenceNumber+1
+	afterFrameEnd := contiguous && a.lastMarker
+	a.hasLast, a.lastSequenceNumber, a.lastMarker = true, packet.SequenceNumber, packet.Marker
+
+	// A packet was lost, or the one with the marker bit of the frame
+	if !contiguous || (a.assembling && packet.Timestamp != a.timestamp) {
+		a.drop()
+	}
+
+	data, frameStart, err := a.depacketizer.depacketize(packet, afterFrameEnd)
+	if err != nil {
+		pcLog.Warnf("Failed to depacketize RTP packet, dropping frame: %v", err)
+		a.drop()
+		return nil, false
+	}
+
+	if !a.assembling {
+		if !frameStart {
+			return nil, false
+		}
+		a.assembling = true
+		a.timestamp = packet.Timestamp
+	}
+	a.frame = append(a.frame, data...)
+
+	if !packet.Marker {
+		return nil, false
+	}
+	frame := a.frame
+	a.drop()
+	return frame, true
+}
@@ -0,0 +1,150 @@
+package webrtc
+
+import (
+	"io"
+	"testing"
+
+	"github.com/pions/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func framePacket(sequenceNumber uint16, timestamp uint32, marker bool, payload ...byte) *rtp.Packet {
+	return &rtp.Packet{
+		Header: rtp.Header{
+			SequenceNumber: sequenceNumber,
+			Timestamp:      timestamp,
+			Marker:         marker,
+		},
+		Payload: payload,
+	}
+}
+
+func TestVP8Depacketizer(t *testing.T) {
+	d := &vp8Depacketizer{}
+
+	// Start of partition 0 with a 15 bit picture ID, TL0PICIDX and KEYIDX
+	data, frameStart, err := d.depacketize(framePacket(0, 0, false, 0x90, 0xD0, 0x81, 0x02, 0x03, 0x04, 0xAA), false)
+	assert.NoError(t, err)
+	assert.True(t, frameStart)
+	assert.Equal(t, []byte{0xAA}, data)
+
+	// Continuation of partition 0
+	data, frameStart, err = d.depacketize(framePacket(0, 0, false, 0x00, 0xBB), false)
+	assert.NoError(t, err)
+	assert.False(t, frameStart)
+	assert.Equal(t, []byte{0xBB}, data)
+
+	// Start of partition 1
+	_, frameStart, err = d.depacketize(framePacket(0, 0, false, 0x11, 0xCC), false)
+	assert.NoError(t, err)
+	assert.False(t, frameStart)
+
+	_, _, err = d.depacketize(framePacket(0, 0, false), false)
+	assert.Error(t, err)
+	_, _, err = d.depacketize(framePacket(0, 0, false, 0x90, 0x80), false)
+	assert.Error(t, err)
+	_, _, err = d.depacketize(framePacket(0, 0, false, 0x90, 0xC0, 0x81), false)
+	assert.Error(t, err)
+}
+
+func TestH264Depacketizer(t *testing.T) {
+	d := &h264Depacketizer{}
+
+	// Single NAL unit
+	data, frameStart, err := d.depacketize(framePacket(0, 0, false, 0x65, 0x01), true)
+	assert.NoError(t, err)
+	assert.True(t, frameStart)
+	assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x01, 0x65, 0x01}, data)
+
+	_, frameStart, err = d.depacketize(framePacket(0, 0, false, 0x65, 0x01), false)
+	assert.NoError(t, err)
+	assert.False(t, frameStart)
+
+	// STAP-A with a SPS and a PPS
+	data, _, err = d.depacketize(framePacket(0, 0, false, 0x78, 0x00, 0x02, 0x67, 0x01, 0x00, 0x01, 0x68), true)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x01, 0x67, 0x01, 0x00, 0x00, 0x00, 0x01, 0x68}, data)
+
+	_, _, err = d.depacketize(framePacket(0, 0, false, 0x78, 0x00, 0x05, 0x67), true)
+	assert.Error(t, err)
+
+	// FU-A fragments of an IDR slice rebuild its NAL header
+	data, frameStart, err = d.depacketize(framePacket(0, 0, false, 0x7C, 0x85, 0x01), true)
+	assert.NoError(t, err)
+	assert.True(t, frameStart)
+	assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x01, 0x65, 0x01}, data)
+
+	data, frameStart, err = d.depacketize(framePacket(0, 0, false, 0x7C, 0x45, 0x02), true)
+	assert.NoError(t, err)
+	assert.False(t, frameStart)
+	assert.Equal(t, []byte{0x02}, data)
+
+	_, _, err = d.depacketize(framePacket(0, 0, false, 0x7C), true)
+	assert.Error(t, err)
+	_, _, err = d.depacketize(framePacket(0, 0, false, 0x7D, 0x00), true)
+	assert.Error(t, err)
+}
+
+func TestFrameAssembler(t *testing.T) {
+	a := &frameAssembler{depacketizer: &vp8Depacketizer{}}
+
+	// Packets before the beginning of a frame are skipped
+	_, ok := a.push(framePacket(9, 0, true, 0x00, 0x00))
+	assert.False(t, ok)
+
+	_, ok = a.push(framePacket(10, 1, false, 0x10, 0x01))
+	assert.False(t, ok)
+	frame, ok := a.push(framePacket(11, 1, true, 0x00, 0x02))
+	assert.True(t, ok)
+	assert.Equal(t, []byte{0x01, 0x02}, frame)
+
+	// A frame missing a packet is dropped
+	_, ok = a.push(framePacket(12, 2, false, 0x10, 0x03))
+	assert.False(t, ok)
+	_, ok = a.push(framePacket(14, 2, true, 0x00, 0x04))
+	assert.False(t, ok)
+
+	// As is a frame missing its marker bit
+	_, ok = a.push(framePacket(15, 3, false, 0x10, 0x05))
+	assert.False(t, ok)
+	frame, ok = a.push(framePacket(16, 4, true, 0x10, 0x06))
+	assert.True(t, ok)
+	assert.Equal(t, []byte{0x06}, frame)
+
+	// H264 only starts after a marker bit
+	a = &frameAssembler{depacketizer: &h264Depacketizer{}}
+	_, ok = a.push(framePacket(0, 0, false, 0x41, 0x01))
+	assert.False(t, ok)
+	_, ok = a.push(framePacket(1, 0, true, 0x41, 0x02))
+	assert.False(t, ok)
+	_, ok = a.push(framePacket(2, 1, false, 0x7C, 0x85, 0x03))
+	assert.False(t, ok)
+	frame, ok = a.push(framePacket(3, 1, true, 0x7C, 0x45, 0x04))
+	assert.True(t, ok)
+	assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x01, 0x65, 0x03, 0x04}, frame)
+
+	// Sequence numbers wrap around
+	a.lastSequenceNumber = 0xFFFF
+	frame, ok = a.push(framePacket(0, 2, true, 0x41, 0x05))
+	assert.True(t, ok)
+	assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x01, 0x41, 0x05}, frame)
+}
+
+func TestTrackReadFrame(t *testing.T) {
+	packets := make(chan *rtp.Packet, 2)
+	track := &Track{Codec: NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000), Packets: packets}
+
+	packets <- framePacket(0, 0, false, 0x10, 0x01)
+	packets <- framePacket(1, 0, true, 0x00, 0x02)
+	frame, err := track.ReadFrame()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x01, 0x02}, frame)
+
+	close(packets)
+	_, err = track.ReadFrame()
+	assert.Equal(t, io.EOF, err)
+
+	track = &Track{Codec: NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000, 2)}
+	_, err = track.ReadFrame()
+	assert.Equal(t, ErrNoDepacketizer, err)
+}
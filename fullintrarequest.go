@@ -0,0 +1,126 @@
+package webrtc
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/pions/rtcp"
+	"github.com/pkg/errors"
+)
+
+// FullIntraRequest is the Full Intra Request (FIR) RTCP feedback message
+// defined in https://tools.ietf.org/html/rfc5104#section-4.3.1, it asks the
+// sender of the media for a decoder refresh point. Some legacy endpoints
+// only send keyframes when receiving it instead of a PictureLossIndication.
+type FullIntraRequest struct {
+	// SSRC of sender
+	SenderSSRC uint32
+
+	// SSRC of the media source, unused and always 0 as required by RFC5104
+	MediaSSRC uint32
+
+	FIR []FIREntry
+}
+
+// FIREntry is a single request of a FullIntraRequest
+type FIREntry struct {
+	// SSRC of the media the keyframe is requested for
+	SSRC uint32
+
+	// SequenceNumber is incremented for every new request for the SSRC,
+	// a retransmitted request keeps the same value
+	SequenceNumber uint8
+}
+
+const (
+	formatFIR = 4
+
+	rtcpHeaderLength = 4
+	firEntryLength   = 8
+)
+
+var _ rtcp.Packet = (*FullIntraRequest)(nil)
+
+func (p FullIntraRequest) len() int {
+	return rtcpHeaderLength + 8 + len(p.FIR)*firEntryLength
+}
+
+// Header returns the Header associated with this packet.
+func (p *FullIntraRequest) Header() rtcp.Header {
+	return rtcp.Header{
+		Count:  formatFIR,
+		Type:   rtcp.TypePayloadSpecificFeedback,
+		Length: uint16(p.len()/4 - 1),
+	}
+}
+
+// Marshal encodes the FullIntraRequest in binary
+func (p FullIntraRequest) Marshal() ([]byte, error) {
+	rawPacket := make([]byte, p.len())
+
+	hData, err := p.Header().Marshal()
+	if err != nil {
+		return nil, err
+	}
+	copy(rawPacket, hData)
+
+	packetBody := rawPacket[rtcpHeaderLength:]
+	binary.BigEndian.PutUint32(packetBody, p.SenderSSRC)
+	binary.BigEndian.PutUint32(packetBody[4:], p.MediaSSRC)
+	for i, fir := range p.FIR {
+		entry := packetBody[8+i*firEntryLength:]
+		binary.BigEndian.PutUint32(entry, fir.SSRC)
+		entry[4] = fir.SequenceNumber
+	}
+
+	return rawPacket, nil
+}
+
+// Unmarshal decodes the FullIntraRequest from binary
+func (p *FullIntraRequest) Unmarshal(rawPacket []byte) error {
+	if len(rawPacket) < rtcpHeaderLength+8 {
+		return errors.New("rtcp: packet too short")
+	}
+
+	var h rtcp.Header
+	if err := h.Unmarshal(rawPacket); err != nil {
+		return err
+	}
+
+	if h.Type != rtcp.TypePayloadSpecificFeedback || h.Count != formatFIR {
+		return errors.New("rtcp: wrong packet type")
+	}
+
+	end := (int(h.Length) + 1) * 4
+	if end > len(rawPacket) || end < rtcpHeaderLength+8 {
+		return errors.New("rtcp: invalid packet length")
+	}
+
+	p.SenderSSRC = binary.BigEndian.Uint32(rawPacket[rtcpHeaderLength:])
+	p.MediaSSRC = binary.BigEndian.Uint32(rawPacket[rtcpHeaderLength+4:])
+	p.FIR = nil
+	for i := rtcpHeaderLength + 8; i+firEntryLength <= end; i += firEntryLength {
+		p.FIR = append(p.FIR, FIREntry{
+			SSRC:           binary.BigEndian.Uint32(rawPacket[i:]),
+			SequenceNumber: rawPacket[i+4],
+		})
+	}
+	return nil
+}
+
+// DestinationSSRC returns an array of SSRC values that this packet refers to.
+func (p *FullIntraRequest) DestinationSSRC() []uint32 {
+	ssrcs := make([]uint32, 0, len(p.FIR))
+	for _, fir := range p.FIR {
+		ssrcs = append(ssrcs, fir.SSRC)
+	}
+	return ssrcs
+}
+
+func (p *FullIntraRequest) String() string {
+	out := fmt.Sprintf("FullIntraRequest %x %x", p.SenderSSRC, p.MediaSSRC)
+	for _, fir := range p.FIR {
+		out += fmt.Sprintf(" (%x %d)", fir.SSRC, fir.SequenceNumber)
+	}
+	return out
+}
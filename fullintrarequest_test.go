@@ -0,0 +1,47 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFullIntraRequest(t *testing.T) {
+	fir := FullIntraRequest{
+		SenderSSRC: 0x902f9e2e,
+		FIR: []FIREntry{
+			{SSRC: 0x12345678, SequenceNumber: 42},
+			{SSRC: 0x9abcdef0, SequenceNumber: 255},
+		},
+	}
+
+	raw, err := fir.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x84, 0xce, 0x00, 0x06,
+		0x90, 0x2f, 0x9e, 0x2e,
+		0x00, 0x00, 0x00, 0x00,
+		0x12, 0x34, 0x56, 0x78,
+		0x2a, 0x00, 0x00, 0x00,
+		0x9a, 0xbc, 0xde, 0xf0,
+		0xff, 0x00, 0x00, 0x00,
+	}, raw)
+
+	packet, err := unmarshalRTCP(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, &fir, packet)
+	assert.Equal(t, []uint32{0x12345678, 0x9abcdef0}, packet.DestinationSSRC())
+
+	// Truncated FCI
+	var decoded FullIntraRequest
+	assert.Error(t, decoded.Unmarshal(raw[:len(raw)-4]))
+
+	// Other packets are left to the rtcp package
+	pli, err := (&rtcp.PictureLossIndication{MediaSSRC: 1}).Marshal()
+	assert.NoError(t, err)
+	packet, err = unmarshalRTCP(pli)
+	assert.NoError(t, err)
+	assert.IsType(t, &rtcp.PictureLossIndication{}, packet)
+	assert.Error(t, decoded.Unmarshal(pli))
+}
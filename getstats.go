@@ -0,0 +1,336 @@
+package webrtc
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// StatsType is the type of a stats object, as named by
+// https://www.w3.org/TR/webrtc-stats/#rtcstatstype-str*
+type StatsType string
+
+const (
+	// StatsTypeInboundRTP is the type of the InboundRTPStreamStats
+	StatsTypeInboundRTP StatsType = "inbound-rtp"
+
+	// StatsTypeOutboundRTP is the type of the OutboundRTPStreamStats
+	StatsTypeOutboundRTP StatsType = "outbound-rtp"
+
+	// StatsTypeCandidatePair is the type of the ICECandidatePairStats
+	StatsTypeCandidatePair StatsType = "candidate-pair"
+
+	// StatsTypeTransport is the type of the DTLSTransportStats
+	StatsTypeTransport StatsType = "transport"
+
+	// StatsTypeDataChannel is the type of the DataChannelStats
+	StatsTypeDataChannel StatsType = "data-channel"
+)
+
+// transportStatsID is the ID of the stats of the DTLSTransport, all the
+// media and data of a PeerConnection are bundled on it
+const transportStatsID = "RTCTransport_0"
+
+// Stats is a stats object of a StatsCollection: an *InboundRTPStreamStats,
+// *OutboundRTPStreamStats, *ICECandidatePairStats, *DTLSTransportStats or
+// *DataChannelStats
+type Stats interface {
+	statsID() string
+}
+
+// StatsCollection is the result of GetStats, the stats objects by their ID.
+// The IDs are stable: the objects about the same stream, pair or channel
+// have the same ID from a call to the next, so that the rates, such as the
+// bitrate, are the difference of their counters divided by the difference of
+// their Timestamp.
+type StatsCollection map[string]Stats
+
+func (c StatsCollection) add(s Stats) {
+	c[s.statsID()] = s
+}
+
+// InboundRTPStreamStats contains the counters of a received RTP stream
+// https://www.w3.org/TR/webrtc-stats/#inboundrtpstats-dict*
+type InboundRTPStreamStats struct {
+	ID        string    `json:"id"`
+	Type      StatsType `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+
+	SSRC        uint32 `json:"ssrc"`
+	Kind        string `json:"kind"`
+	TrackID     string `json:"trackId"`
+	TransportID string `json:"transportId"`
+
+	// BytesReceived counts the payloads, HeaderBytesReceived the RTP headers
+	// and the padding
+	PacketsReceived     uint64 `json:"packetsReceived"`
+	BytesReceived       uint64 `json:"bytesReceived"`
+	HeaderBytesReceived uint64 `json:"headerBytesReceived"`
+
+	// PacketsLost is the number of packets expected from the sequence
+	// numbers that were not received, it is negative when duplicates were
+	// https://tools.ietf.org/html/rfc3550#section-6.4.1
+	PacketsLost int64 `json:"packetsLost"`
+
+	// Jitter is the interarrival jitter in seconds, 0 while the clock rate of
+	// the payload type is unknown
+	Jitter float64 `json:"jitter"`
+
+	// LastPacketReceivedTimestamp is zero until a packet is received
+	LastPacketReceivedTimestamp time.Time `json:"lastPacketReceivedTimestamp"`
+
+	// NACKCount is the number of NACK packets sent about the stream
+	NACKCount uint64 `json:"nackCount"`
+}
+
+func (s *InboundRTPStreamStats) statsID() string { return s.ID }
+
+// OutboundRTPStreamStats contains the counters of a sent RTP stream
+// https://www.w3.org/TR/webrtc-stats/#outboundrtpstats-dict*
+type OutboundRTPStreamStats struct {
+	ID        string    `json:"id"`
+	Type      StatsType `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+
+	SSRC        uint32 `json:"ssrc"`
+	Kind        string `json:"kind"`
+	TrackID     string `json:"trackId"`
+	TransportID string `json:"transportId"`
+
+	// The counters include the retransmissions sent on the SSRC of the
+	// stream, not the ones sent on its RTX stream. BytesSent counts the
+	// payloads, HeaderBytesSent the RTP headers and the padding.
+	PacketsSent     uint64 `json:"packetsSent"`
+	BytesSent       uint64 `json:"bytesSent"`
+	HeaderBytesSent uint64 `json:"headerBytesSent"`
+
+	// RetransmittedPacketsSent is the number of packets sent again after a
+	// NACK, on the RTX stream or on the SSRC of the stream
+	RetransmittedPacketsSent uint64 `json:"retransmittedPacketsSent"`
+
+	// NACKCount is the number of NACK packets received about the stream
+	NACKCount uint64 `json:"nackCount"`
+}
+
+func (s *OutboundRTPStreamStats) statsID() string { return s.ID }
+
+// ICECandidatePairStats contains the state of the connectivity checks of a
+// pair of the checklist, with the round-trip time measured on it
+// https://www.w3.org/TR/webrtc-stats/#candidatepair-dict*
+type ICECandidatePairStats struct {
+	ID          string    `json:"id"`
+	Type        StatsType `json:"type"`
+	Timestamp   time.Time `json:"timestamp"`
+	TransportID string    `json:"transportId"`
+
+	ICECandidatePairCheck
+}
+
+func (s *ICECandidatePairStats) statsID() string { return s.ID }
+
+// DTLSTransportStats contains the traffic and the state of the transport the
+// media and data are sent on
+// https://www.w3.org/TR/webrtc-stats/#transportstats-dict*
+type DTLSTransportStats struct {
+	ID        string    `json:"id"`
+	Type      StatsType `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+
+	DTLSState DTLSTransportState `json:"dtlsState"`
+
+	// SelectedCandidatePairID is the ID of the ICECandidatePairStats of the
+	// pair the media is sent on, empty until one is selected
+	SelectedCandidatePairID string `json:"selectedCandidatePairId,omitempty"`
+
+	TransportStats
+}
+
+func (s *DTLSTransportStats) statsID() string { return s.ID }
+
+// DataChannelStats contains the messages sent and received on a DataChannel.
+// The messages of a detached DataChannel are not counted.
+// https://www.w3.org/TR/webrtc-stats/#dcstats-dict*
+type DataChannelStats struct {
+	ID        string    `json:"id"`
+	Type      StatsType `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+
+	Label                 string           `json:"label"`
+	Protocol              string           `json:"protocol"`
+	DataChannelIdentifier uint16           `json:"dataChannelIdentifier"`
+	State                 DataChannelState `json:"state"`
+
+	MessagesSent     uint64 `json:"messagesSent"`
+	BytesSent        uint64 `json:"bytesSent"`
+	MessagesReceived uint64 `json:"messagesReceived"`
+	BytesReceived    uint64 `json:"bytesReceived"`
+}
+
+func (s *DataChannelStats) statsID() string { return s.ID }
+
+// candidatePairStatsID returns the ID of the stats of a candidate pair, after
+// the addresses of its candidates
+func candidatePairStatsID(pair ICECandidatePair) string {
+	return fmt.Sprintf("RTCIceCandidatePair_%s:%d_%s:%d", pair.Local.IP, pair.Local.Port, pair.Remote.IP, pair.Remote.Port)
+}
+
+// GetStats returns the stats of every RTP stream sent and received, of the
+// candidate pairs of the checklist, of the transport and of the DataChannels.
+// The counters are kept as the packets and messages flow, GetStats only
+// samples them and can be called from any goroutine. The objects that don't
+// exist yet, such as the streams before their first packet or the pairs
+// before the ICE agent starts, are left out.
+func (pc *PeerConnection) GetStats() StatsCollection {
+	now := time.Now()
+	stats := StatsCollection{}
+
+	pc.mu.RLock()
+	transceivers := append([]*RTPTransceiver{}, pc.rtpTransceivers...)
+	dataChannels := append([]*DataChannel{}, pc.remoteDataChannels...)
+	for _, d := range pc.dataChannels {
+		dataChannels = append(dataChannels, d)
+	}
+	pc.mu.RUnlock()
+
+	for _, t := range transceivers {
+		if sender := t.Sender(); sender != nil {
+			if s, ok := sender.outboundStats(now); ok {
+				stats.add(s)
+			}
+		}
+		if receiver := t.Receiver(); receiver != nil {
+			if s, ok := receiver.inboundStats(now); ok {
+				stats.add(s)
+			}
+		}
+	}
+
+	var selected string
+	if checks, err := pc.iceTransport.GetChecklist(); err == nil {
+		for _, check := range checks {
+			s := &ICECandidatePairStats{
+				ID:                    candidatePairStatsID(check.ICECandidatePair),
+				Type:                  StatsTypeCandidatePair,
+				Timestamp:             now,
+				TransportID:           transportStatsID,
+				ICECandidatePairCheck: check,
+			}
+			if check.Selected {
+				selected = s.ID
+			}
+			stats.add(s)
+		}
+	}
+
+	if pc.dtlsTransport != nil {
+		stats.add(&DTLSTransportStats{
+			ID:                      transportStatsID,
+			Type:                    StatsTypeTransport,
+			Timestamp:               now,
+			DTLSState:               pc.dtlsTransport.State(),
+			SelectedCandidatePairID: selected,
+			TransportStats:          pc.dtlsTransport.GetStats(),
+		})
+	}
+
+	for _, d := range dataChannels {
+		if s, ok := d.stats(now); ok {
+			stats.add(s)
+		}
+	}
+	return stats
+}
+
+// GetStats returns the outbound-rtp stats of the Track sent by the
+// RTPSender, the collection is empty until Send
+func (r *RTPSender) GetStats() StatsCollection {
+	stats := StatsCollection{}
+	if s, ok := r.outboundStats(time.Now()); ok {
+		stats.add(s)
+	}
+	return stats
+}
+
+// outboundStats returns the outbound-rtp stats of the RTPSender, once sending
+func (r *RTPSender) outboundStats(now time.Time) (*OutboundRTPStreamStats, bool) {
+	r.mu.RLock()
+	sending := r.sendDone != nil
+	r.mu.RUnlock()
+	if r.Track == nil || !sending {
+		return nil, false
+	}
+
+	nack := r.NACKStats()
+	return &OutboundRTPStreamStats{
+		ID:          fmt.Sprintf("RTCOutboundRTPStream_%d", r.Track.SSRC),
+		Type:        StatsTypeOutboundRTP,
+		Timestamp:   now,
+		SSRC:        r.Track.SSRC,
+		Kind:        r.Track.Kind.String(),
+		TrackID:     r.Track.ID,
+		TransportID: transportStatsID,
+
+		PacketsSent:     atomic.LoadUint64(&r.packetsSent),
+		BytesSent:       atomic.LoadUint64(&r.bytesSent),
+		HeaderBytesSent: atomic.LoadUint64(&r.headerBytesSent),
+
+		RetransmittedPacketsSent: nack.RetransmitsSent,
+		NACKCount:                nack.NACKsReceived,
+	}, true
+}
+
+// GetStats returns the inbound-rtp stats of the Track received by the
+// RTPReceiver, the collection is empty until its first packet
+func (r *RTPReceiver) GetStats() StatsCollection {
+	stats := StatsCollection{}
+	if s, ok := r.inboundStats(time.Now()); ok {
+		stats.add(s)
+	}
+	return stats
+}
+
+// inboundStats returns the inbound-rtp stats of the RTPReceiver, once
+// receiving
+func (r *RTPReceiver) inboundStats(now time.Time) (*InboundRTPStreamStats, bool) {
+	ssrc, err := r.receivingSSRC()
+	if err != nil {
+		return nil, false
+	}
+
+	s := &InboundRTPStreamStats{
+		ID:          fmt.Sprintf("RTCInboundRTPStream_%d", ssrc),
+		Type:        StatsTypeInboundRTP,
+		Timestamp:   now,
+		SSRC:        ssrc,
+		Kind:        r.kind.String(),
+		TrackID:     r.Track.ID,
+		TransportID: transportStatsID,
+		NACKCount:   r.NACKStats().NACKsSent,
+	}
+	r.reception.fill(s)
+	return s, true
+}
+
+// stats returns the data-channel stats of the DataChannel, once it has an ID
+func (d *DataChannel) stats(now time.Time) (*DataChannelStats, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.ID == nil {
+		return nil, false
+	}
+
+	return &DataChannelStats{
+		ID:                    fmt.Sprintf("RTCDataChannel_%d", *d.ID),
+		Type:                  StatsTypeDataChannel,
+		Timestamp:             now,
+		Label:                 d.Label,
+		Protocol:              d.Protocol,
+		DataChannelIdentifier: *d.ID,
+		State:                 d.ReadyState,
+
+		MessagesSent:     atomic.LoadUint64(&d.messagesSent),
+		BytesSent:        atomic.LoadUint64(&d.bytesSent),
+		MessagesReceived: atomic.LoadUint64(&d.messagesReceived),
+		BytesReceived:    atomic.LoadUint64(&d.bytesReceived),
+	}, true
+}
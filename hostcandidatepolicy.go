@@ -0,0 +1,49 @@
+package webrtc
+
+import (
+	"fmt"
+
+	"github.com/pions/webrtc/pkg/ice"
+)
+
+// HostCandidatePolicy decides which host candidates are kept first when their
+// number is limited with SettingEngine.SetMaxHostCandidates
+type HostCandidatePolicy int
+
+const (
+	// HostCandidatePolicyInterfaceOrder keeps the addresses in the order the
+	// operating system lists the interfaces in.
+	HostCandidatePolicyInterfaceOrder HostCandidatePolicy = iota + 1
+
+	// HostCandidatePolicyDefaultRouteFirst keeps the addresses of the default
+	// routes first, then the others in the order of the interfaces.
+	HostCandidatePolicyDefaultRouteFirst
+)
+
+// This is done this way because of a linter.
+const (
+	hostCandidatePolicyInterfaceOrderStr    = "interface-order"
+	hostCandidatePolicyDefaultRouteFirstStr = "default-route-first"
+)
+
+func (p HostCandidatePolicy) String() string {
+	switch p {
+	case HostCandidatePolicyInterfaceOrder:
+		return hostCandidatePolicyInterfaceOrderStr
+	case HostCandidatePolicyDefaultRouteFirst:
+		return hostCandidatePolicyDefaultRouteFirstStr
+	default:
+		return ErrUnknownType.Error()
+	}
+}
+
+func (p HostCandidatePolicy) toICE() (ice.HostCandidatePolicy, error) {
+	switch p {
+	case HostCandidatePolicyInterfaceOrder:
+		return ice.HostCandidatePolicyInterfaceOrder, nil
+	case HostCandidatePolicyDefaultRouteFirst:
+		return ice.HostCandidatePolicyDefaultRouteFirst, nil
+	default:
+		return ice.HostCandidatePolicy(Unknown), fmt.Errorf("unknown host candidate policy: %s", p)
+	}
+}
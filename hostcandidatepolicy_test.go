@@ -0,0 +1,36 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/webrtc/pkg/ice"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostCandidatePolicy_String(t *testing.T) {
+	testCases := []struct {
+		policy         HostCandidatePolicy
+		expectedString string
+	}{
+		{HostCandidatePolicy(Unknown), unknownStr},
+		{HostCandidatePolicyInterfaceOrder, "interface-order"},
+		{HostCandidatePolicyDefaultRouteFirst, "default-route-first"},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedString,
+			testCase.policy.String(),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
+
+func TestHostCandidatePolicy_ToICE(t *testing.T) {
+	policy, err := HostCandidatePolicyDefaultRouteFirst.toICE()
+	assert.NoError(t, err)
+	assert.Equal(t, ice.HostCandidatePolicyDefaultRouteFirst, policy)
+
+	_, err = HostCandidatePolicy(Unknown).toICE()
+	assert.Error(t, err)
+}
@@ -4,12 +4,16 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"strconv"
+	"strings"
 
 	"github.com/pions/sdp/v2"
 	"github.com/pions/webrtc/pkg/ice"
 )
 
-// ICECandidate represents a ice candidate
+// ICECandidate represents a ice candidate. The IP of a host candidate may be
+// the .local multicast DNS name it hides its address behind, see
+// SettingEngine.SetICEMulticastDNSMode.
 type ICECandidate struct {
 	Foundation     string           `json:"foundation"`
 	Priority       uint32           `json:"priority"`
@@ -20,6 +24,118 @@ type ICECandidate struct {
 	Component      uint16           `json:"component"`
 	RelatedAddress string           `json:"relatedAddress"`
 	RelatedPort    uint16           `json:"relatedPort"`
+
+	// Extensions are the extension attributes of the candidate line, such as
+	// tcptype or generation, in their order
+	Extensions []ICECandidateExtension `json:"extensions,omitempty"`
+}
+
+// ICECandidateExtension is an extension attribute of a candidate line
+type ICECandidateExtension struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// UnmarshalICECandidate parses a candidate line, as found in
+// ICECandidateInit.Candidate and defined in
+// https://tools.ietf.org/html/rfc5245#section-15.1
+// The "candidate:" and "a=candidate:" prefixes are optional. Lines with a
+// missing or malformed field are refused.
+func UnmarshalICECandidate(line string) (ICECandidate, error) {
+	raw := strings.TrimPrefix(strings.TrimSpace(line), "a=")
+	raw = strings.TrimPrefix(raw, "candidate:")
+
+	fields := strings.Fields(raw)
+	if len(fields) < 8 || fields[6] != "typ" {
+		return ICECandidate{}, errors.New("candidate is missing the typ field")
+	}
+
+	component, err := strconv.ParseUint(fields[1], 10, 16)
+	if err != nil {
+		return ICECandidate{}, fmt.Errorf("invalid component %s", fields[1])
+	}
+	protocol, err := newICEProtocol(fields[2])
+	if err != nil {
+		return ICECandidate{}, err
+	}
+	priority, err := strconv.ParseUint(fields[3], 10, 32)
+	if err != nil {
+		return ICECandidate{}, fmt.Errorf("invalid priority %s", fields[3])
+	}
+	port, err := strconv.ParseUint(fields[5], 10, 16)
+	if err != nil {
+		return ICECandidate{}, fmt.Errorf("invalid port %s", fields[5])
+	}
+	typ, err := newICECandidateType(fields[7])
+	if err != nil {
+		return ICECandidate{}, err
+	}
+
+	c := ICECandidate{
+		Foundation: fields[0],
+		Priority:   uint32(priority),
+		IP:         fields[4],
+		Protocol:   protocol,
+		Port:       uint16(port),
+		Typ:        typ,
+		Component:  uint16(component),
+	}
+
+	extensions := fields[8:]
+	if len(extensions)%2 != 0 {
+		return ICECandidate{}, fmt.Errorf("extension %s has no value", extensions[len(extensions)-1])
+	}
+	hasRelatedPort := false
+	for i := 0; i < len(extensions); i += 2 {
+		key, value := extensions[i], extensions[i+1]
+		switch key {
+		case "raddr":
+			c.RelatedAddress = value
+		case "rport":
+			relatedPort, err := strconv.ParseUint(value, 10, 16)
+			if err != nil {
+				return ICECandidate{}, fmt.Errorf("invalid related port %s", value)
+			}
+			c.RelatedPort = uint16(relatedPort)
+			hasRelatedPort = true
+		default:
+			c.Extensions = append(c.Extensions, ICECandidateExtension{Key: key, Value: value})
+		}
+	}
+	if (c.RelatedAddress != "") != hasRelatedPort {
+		return ICECandidate{}, errors.New("related address and port must be given together")
+	}
+
+	if err := c.validate(); err != nil {
+		return ICECandidate{}, err
+	}
+	return c, nil
+}
+
+// Marshal returns the candidate line of the candidate, with the "candidate:"
+// prefix of ICECandidateInit.Candidate
+func (c ICECandidate) Marshal() string {
+	val := fmt.Sprintf("candidate:%s %d %s %d %s %d typ %s",
+		c.Foundation, c.Component, c.Protocol, c.Priority, c.IP, c.Port, c.Typ)
+
+	if c.RelatedAddress != "" {
+		val = fmt.Sprintf("%s raddr %s rport %d", val, c.RelatedAddress, c.RelatedPort)
+	}
+
+	for _, e := range c.Extensions {
+		val = fmt.Sprintf("%s %s %s", val, e.Key, e.Value)
+	}
+	return val
+}
+
+// Extension returns the value of the extension attribute with the given key
+func (c ICECandidate) Extension(key string) (string, bool) {
+	for _, e := range c.Extensions {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return "", false
 }
 
 // Conversion for package sdp
@@ -33,7 +149,7 @@ func newICECandidateFromSDP(c sdp.ICECandidate) (ICECandidate, error) {
 	if err != nil {
 		return ICECandidate{}, err
 	}
-	return ICECandidate{
+	candidate := ICECandidate{
 		Foundation:     c.Foundation,
 		Priority:       c.Priority,
 		IP:             c.IP,
@@ -43,11 +159,31 @@ func newICECandidateFromSDP(c sdp.ICECandidate) (ICECandidate, error) {
 		Typ:            typ,
 		RelatedAddress: c.RelatedAddress,
 		RelatedPort:    c.RelatedPort,
-	}, nil
+	}
+	for _, a := range c.ExtensionAttributes {
+		candidate.Extensions = append(candidate.Extensions, ICECandidateExtension{Key: a.Key, Value: a.Value})
+	}
+	return candidate, nil
+}
+
+// validate performs the checks on the fields of a candidate line that its
+// syntax leaves out, as defined in https://tools.ietf.org/html/rfc5245#section-15.1
+func (c ICECandidate) validate() error {
+	switch {
+	case c.Component != 1 && c.Component != 2:
+		return fmt.Errorf("invalid component %d", c.Component)
+	case net.ParseIP(c.IP) == nil && !(c.Typ == ICECandidateTypeHost && ice.IsMulticastDNSName(c.IP)):
+		return fmt.Errorf("invalid connection address %s", c.IP)
+	case c.Port == 0 && c.Protocol != ICEProtocolTCP:
+		return errors.New("invalid port 0")
+	case c.RelatedAddress != "" && net.ParseIP(c.RelatedAddress) == nil:
+		return fmt.Errorf("invalid related address %s", c.RelatedAddress)
+	}
+	return nil
 }
 
 func (c ICECandidate) toSDP() sdp.ICECandidate {
-	return sdp.ICECandidate{
+	candidate := sdp.ICECandidate{
 		Foundation:     c.Foundation,
 		Priority:       c.Priority,
 		IP:             c.IP,
@@ -58,6 +194,52 @@ func (c ICECandidate) toSDP() sdp.ICECandidate {
 		RelatedAddress: c.RelatedAddress,
 		RelatedPort:    c.RelatedPort,
 	}
+	for _, e := range c.Extensions {
+		candidate.ExtensionAttributes = append(candidate.ExtensionAttributes, sdp.ICECandidateAttribute{Key: e.Key, Value: e.Value})
+	}
+	return candidate
+}
+
+// localCandidates are the candidates a media section announces, complete
+// once the gatherer gathered them all
+type localCandidates struct {
+	candidates []ICECandidate
+	complete   bool
+}
+
+// gatheredCandidates returns the candidates the gatherer gathered so far
+func gatheredCandidates(g *ICEGatherer) (localCandidates, error) {
+	// The state is read first, the candidates are all there once complete
+	complete := g.State() == ICEGathererStateComplete
+	candidates, err := g.GetLocalCandidates()
+	if err != nil {
+		return localCandidates{}, err
+	}
+	return localCandidates{candidates: candidates, complete: complete}, nil
+}
+
+// addTo adds the candidates to a media section, followed by
+// end-of-candidates once they are complete
+func (l localCandidates) addTo(media *sdp.MediaDescription) {
+	// RTCP is multiplexed, only the RTP component has candidates
+	for _, c := range l.candidates {
+		sdpCandidate := c.toSDP()
+		sdpCandidate.ExtensionAttributes = append(sdpCandidate.ExtensionAttributes, sdp.ICECandidateAttribute{Key: "generation", Value: "0"})
+		sdpCandidate.Component = uint16(ICEComponentRTP)
+		media.WithICECandidate(sdpCandidate)
+	}
+	if l.complete {
+		media.WithPropertyAttribute("end-of-candidates")
+	}
+}
+
+// ToJSON returns the ICECandidateInit to signal the candidate with, such as
+// one passed to PeerConnection.OnICECandidate. The candidates of a
+// PeerConnection belong to its first media section, the others are bundled
+// with it.
+func (c ICECandidate) ToJSON() ICECandidateInit {
+	var index uint16
+	return ICECandidateInit{Candidate: c.Marshal(), SDPMLineIndex: &index}
 }
 
 // Conversion for package ice
@@ -96,6 +278,9 @@ func newICECandidateFromICE(i *ice.Candidate) (ICECandidate, error) {
 		Typ:        typ,
 	}
 
+	if i.Hostname != "" {
+		c.IP = i.Hostname
+	}
 	if i.RelatedAddress != nil {
 		c.RelatedAddress = i.RelatedAddress.Address
 		c.RelatedPort = uint16(i.RelatedAddress.Port)
@@ -107,6 +292,9 @@ func newICECandidateFromICE(i *ice.Candidate) (ICECandidate, error) {
 func (c ICECandidate) toICE() (*ice.Candidate, error) {
 	ip := net.ParseIP(c.IP)
 	if ip == nil {
+		if c.Typ == ICECandidateTypeHost && ice.IsMulticastDNSName(c.IP) {
+			return ice.NewCandidateHostName(c.Protocol.String(), c.IP, int(c.Port), c.Component)
+		}
 		return nil, errors.New("failed to parse IP address")
 	}
 
@@ -112,6 +112,33 @@ func TestICECandidate_Convert(t *testing.T) {
 				RelatedPort:    4321,
 			},
 		},
+		{
+			ICECandidate{
+				Foundation: "foundation",
+				Priority:   128,
+				IP:         "1f4712db-ea17-4bcf-a596-105139dfd8bf.local",
+				Protocol:   ICEProtocolUDP,
+				Port:       1234,
+				Typ:        ICECandidateTypeHost,
+				Component:  1,
+			}, &ice.Candidate{
+				Hostname:        "1f4712db-ea17-4bcf-a596-105139dfd8bf.local",
+				NetworkType:     ice.NetworkTypeUDP4,
+				Port:            1234,
+				Type:            ice.CandidateTypeHost,
+				Component:       1,
+				LocalPreference: 65535,
+			},
+			sdp.ICECandidate{
+				Foundation: "foundation",
+				Priority:   128,
+				IP:         "1f4712db-ea17-4bcf-a596-105139dfd8bf.local",
+				Protocol:   "udp",
+				Port:       1234,
+				Typ:        "host",
+				Component:  1,
+			},
+		},
 	}
 
 	for i, testCase := range testCases {
@@ -129,6 +156,14 @@ func TestICECandidate_Convert(t *testing.T) {
 			"testCase: %d ice not equal %v", i, actualSDP,
 		)
 	}
+
+	// The local candidates hiding their address are signaled by their name
+	local, err := ice.NewCandidateHost("udp", net.ParseIP("192.168.1.2"), 1234, 1)
+	assert.NoError(t, err)
+	local.Hostname = "1f4712db-ea17-4bcf-a596-105139dfd8bf.local"
+	candidate, err := newICECandidateFromICE(local)
+	assert.NoError(t, err)
+	assert.Equal(t, local.Hostname, candidate.IP)
 }
 
 func TestConvertTypeFromICE(t *testing.T) {
@@ -160,3 +195,128 @@ func TestConvertTypeFromICE(t *testing.T) {
 		}
 	})
 }
+
+func TestUnmarshalICECandidate(t *testing.T) {
+	testCases := []struct {
+		line      string
+		candidate ICECandidate
+	}{
+		{
+			"candidate:1 1 udp 2130706431 192.168.1.5 50000 typ host",
+			ICECandidate{
+				Foundation: "1",
+				Priority:   2130706431,
+				IP:         "192.168.1.5",
+				Protocol:   ICEProtocolUDP,
+				Port:       50000,
+				Typ:        ICECandidateTypeHost,
+				Component:  1,
+			},
+		},
+		{
+			"candidate:842163049 1 udp 1677729535 1.2.3.4 61665 typ srflx raddr 10.0.0.2 rport 61665 generation 0 ufrag EsAw network-id 1",
+			ICECandidate{
+				Foundation:     "842163049",
+				Priority:       1677729535,
+				IP:             "1.2.3.4",
+				Protocol:       ICEProtocolUDP,
+				Port:           61665,
+				Typ:            ICECandidateTypeSrflx,
+				Component:      1,
+				RelatedAddress: "10.0.0.2",
+				RelatedPort:    61665,
+				Extensions: []ICECandidateExtension{
+					{"generation", "0"},
+					{"ufrag", "EsAw"},
+					{"network-id", "1"},
+				},
+			},
+		},
+		{
+			"candidate:1052210658 2 tcp 1518280447 ::1 9 typ host tcptype active generation 0",
+			ICECandidate{
+				Foundation: "1052210658",
+				Priority:   1518280447,
+				IP:         "::1",
+				Protocol:   ICEProtocolTCP,
+				Port:       9,
+				Typ:        ICECandidateTypeHost,
+				Component:  2,
+				Extensions: []ICECandidateExtension{
+					{"tcptype", "active"},
+					{"generation", "0"},
+				},
+			},
+		},
+		{
+			"candidate:3367420582 1 udp 2122260223 1f4712db-ea17-4bcf-a596-105139dfd8bf.local 56243 typ host generation 0",
+			ICECandidate{
+				Foundation: "3367420582",
+				Priority:   2122260223,
+				IP:         "1f4712db-ea17-4bcf-a596-105139dfd8bf.local",
+				Protocol:   ICEProtocolUDP,
+				Port:       56243,
+				Typ:        ICECandidateTypeHost,
+				Component:  1,
+				Extensions: []ICECandidateExtension{
+					{"generation", "0"},
+				},
+			},
+		},
+	}
+
+	for i, testCase := range testCases {
+		for _, line := range []string{testCase.line, "a=" + testCase.line, testCase.line[len("candidate:"):]} {
+			candidate, err := UnmarshalICECandidate(line)
+			assert.NoError(t, err, "testCase: %d %s", i, line)
+			assert.Equal(t, testCase.candidate, candidate, "testCase: %d %s", i, line)
+		}
+		assert.Equal(t, testCase.line, testCase.candidate.Marshal(), "testCase: %d", i)
+	}
+
+	tcpType, ok := testCases[2].candidate.Extension("tcptype")
+	assert.True(t, ok)
+	assert.Equal(t, "active", tcpType)
+	_, ok = testCases[0].candidate.Extension("tcptype")
+	assert.False(t, ok)
+
+	for _, line := range []string{
+		"",
+		"candidate:1 1 udp",
+		"candidate:1 1 udp 2130706431 192.168.1.5 50000 host",
+		"candidate:1 3 udp 2130706431 192.168.1.5 50000 typ host",
+		"candidate:1 1 sctp 2130706431 192.168.1.5 50000 typ host",
+		"candidate:1 1 udp -1 192.168.1.5 50000 typ host",
+		"candidate:1 1 udp 2130706431 not-an-ip 50000 typ host",
+		"candidate:1 1 udp 2130706431 1f4712db-ea17-4bcf-a596-105139dfd8bf.local 50000 typ srflx raddr 10.0.0.2 rport 1",
+		"candidate:1 1 udp 2130706431 192.168.1.5 0 typ host",
+		"candidate:1 1 udp 2130706431 192.168.1.5 50000 typ bogus",
+		"candidate:1 1 udp 2130706431 192.168.1.5 50000 typ host generation",
+		"candidate:1 1 udp 2130706431 1.2.3.4 50000 typ srflx raddr 10.0.0.2",
+		"candidate:1 1 udp 2130706431 1.2.3.4 50000 typ srflx raddr bogus rport 1",
+		"candidate:1 1 udp 2130706431 1.2.3.4 50000 typ srflx raddr 10.0.0.2 rport 70000",
+	} {
+		_, err := UnmarshalICECandidate(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func FuzzUnmarshalICECandidate(f *testing.F) {
+	f.Add("candidate:1 1 udp 2130706431 192.168.1.5 50000 typ host")
+	f.Add("candidate:842163049 1 udp 1677729535 1.2.3.4 61665 typ srflx raddr 10.0.0.2 rport 61665 generation 0")
+	f.Add("a=candidate:1 2 TCP 1518280447 ::1 9 typ host tcptype passive")
+
+	f.Fuzz(func(t *testing.T, line string) {
+		candidate, err := UnmarshalICECandidate(line)
+		if err != nil {
+			return
+		}
+
+		// A parsed candidate round-trips
+		reparsed, err := UnmarshalICECandidate(candidate.Marshal())
+		if err != nil {
+			t.Fatalf("Failed to parse the marshaled %q of %q: %v", candidate.Marshal(), line, err)
+		}
+		assert.Equal(t, candidate, reparsed)
+	})
+}
@@ -0,0 +1,78 @@
+package webrtc
+
+import (
+	"time"
+
+	"github.com/pions/webrtc/pkg/ice"
+)
+
+// ICECandidatePair is a pair of a local and a remote ICECandidate that passed
+// the connectivity checks
+type ICECandidatePair struct {
+	Local  ICECandidate `json:"local"`
+	Remote ICECandidate `json:"remote"`
+
+	Priority uint32 `json:"priority"`
+
+	// RTT is the round-trip time of the last connectivity check answered on
+	// the pair, 0 until one is
+	RTT time.Duration `json:"rtt"`
+
+	// Selected is true for the pair the media is sent on
+	Selected bool `json:"selected"`
+}
+
+func newICECandidatePairFromICE(p ice.CandidatePairStats) (ICECandidatePair, error) {
+	local, err := newICECandidateFromICE(p.Local)
+	if err != nil {
+		return ICECandidatePair{}, err
+	}
+	remote, err := newICECandidateFromICE(p.Remote)
+	if err != nil {
+		return ICECandidatePair{}, err
+	}
+
+	return ICECandidatePair{
+		Local:    local,
+		Remote:   remote,
+		Priority: p.Priority,
+		RTT:      p.RTT,
+		Selected: p.Selected,
+	}, nil
+}
+
+// ICECandidatePairCheck is a pair of the checklist of an ICETransport, with
+// the state of its connectivity checks
+type ICECandidatePairCheck struct {
+	ICECandidatePair
+
+	State ICECandidatePairState `json:"state"`
+
+	// Nominated is set once the controlling side nominated the pair
+	Nominated bool `json:"nominated"`
+
+	RequestsSent      uint64 `json:"requestsSent"`
+	ResponsesReceived uint64 `json:"responsesReceived"`
+
+	// LastRequestSent and LastResponseReceived are zero until a check was
+	// sent or answered on the pair
+	LastRequestSent      time.Time `json:"lastRequestSent"`
+	LastResponseReceived time.Time `json:"lastResponseReceived"`
+}
+
+func newICECandidatePairCheckFromICE(c ice.CandidatePairCheck) (ICECandidatePairCheck, error) {
+	pair, err := newICECandidatePairFromICE(c.CandidatePairStats)
+	if err != nil {
+		return ICECandidatePairCheck{}, err
+	}
+
+	return ICECandidatePairCheck{
+		ICECandidatePair:     pair,
+		State:                newICECandidatePairStateFromICE(c.State),
+		Nominated:            c.Nominated,
+		RequestsSent:         c.RequestsSent,
+		ResponsesReceived:    c.ResponsesReceived,
+		LastRequestSent:      c.LastRequestSent,
+		LastResponseReceived: c.LastResponseReceived,
+	}, nil
+}
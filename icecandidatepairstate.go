@@ -0,0 +1,63 @@
+package webrtc
+
+import "github.com/pions/webrtc/pkg/ice"
+
+// ICECandidatePairState is the state of the connectivity checks of a pair of
+// the checklist
+type ICECandidatePairState int
+
+const (
+	// ICECandidatePairStateWaiting indicates no check was sent on the pair
+	// yet.
+	ICECandidatePairStateWaiting ICECandidatePairState = iota + 1
+
+	// ICECandidatePairStateInProgress indicates checks were sent on the pair
+	// and none was answered yet.
+	ICECandidatePairStateInProgress
+
+	// ICECandidatePairStateSucceeded indicates a check of the pair was
+	// answered with a success response.
+	ICECandidatePairStateSucceeded
+
+	// ICECandidatePairStateFailed indicates the last check of the pair was
+	// answered with an error, or no check was answered in time.
+	ICECandidatePairStateFailed
+)
+
+// This is done this way because of a linter.
+const (
+	iceCandidatePairStateWaitingStr    = "waiting"
+	iceCandidatePairStateInProgressStr = "in-progress"
+	iceCandidatePairStateSucceededStr  = "succeeded"
+	iceCandidatePairStateFailedStr     = "failed"
+)
+
+func newICECandidatePairStateFromICE(s ice.CandidatePairState) ICECandidatePairState {
+	switch s {
+	case ice.CandidatePairStateWaiting:
+		return ICECandidatePairStateWaiting
+	case ice.CandidatePairStateInProgress:
+		return ICECandidatePairStateInProgress
+	case ice.CandidatePairStateSucceeded:
+		return ICECandidatePairStateSucceeded
+	case ice.CandidatePairStateFailed:
+		return ICECandidatePairStateFailed
+	default:
+		return ICECandidatePairState(Unknown)
+	}
+}
+
+func (s ICECandidatePairState) String() string {
+	switch s {
+	case ICECandidatePairStateWaiting:
+		return iceCandidatePairStateWaitingStr
+	case ICECandidatePairStateInProgress:
+		return iceCandidatePairStateInProgressStr
+	case ICECandidatePairStateSucceeded:
+		return iceCandidatePairStateSucceededStr
+	case ICECandidatePairStateFailed:
+		return iceCandidatePairStateFailedStr
+	default:
+		return unknownStr
+	}
+}
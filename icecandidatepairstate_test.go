@@ -0,0 +1,50 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/webrtc/pkg/ice"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestICECandidatePairState_String(t *testing.T) {
+	testCases := []struct {
+		state          ICECandidatePairState
+		expectedString string
+	}{
+		{ICECandidatePairState(Unknown), unknownStr},
+		{ICECandidatePairStateWaiting, "waiting"},
+		{ICECandidatePairStateInProgress, "in-progress"},
+		{ICECandidatePairStateSucceeded, "succeeded"},
+		{ICECandidatePairStateFailed, "failed"},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedString,
+			testCase.state.String(),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
+
+func TestNewICECandidatePairStateFromICE(t *testing.T) {
+	testCases := []struct {
+		state    ice.CandidatePairState
+		expected ICECandidatePairState
+	}{
+		{ice.CandidatePairState(0), ICECandidatePairState(Unknown)},
+		{ice.CandidatePairStateWaiting, ICECandidatePairStateWaiting},
+		{ice.CandidatePairStateInProgress, ICECandidatePairStateInProgress},
+		{ice.CandidatePairStateSucceeded, ICECandidatePairStateSucceeded},
+		{ice.CandidatePairStateFailed, ICECandidatePairStateFailed},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expected,
+			newICECandidatePairStateFromICE(testCase.state),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
@@ -3,8 +3,10 @@ package webrtc
 import (
 	"errors"
 	"sync"
+	"sync/atomic"
 
 	"github.com/pions/webrtc/pkg/ice"
+	"github.com/pions/webrtc/pkg/rtcerr"
 )
 
 // The ICEGatherer gathers local host, server reflexive and relay
@@ -16,9 +18,21 @@ type ICEGatherer struct {
 	state ICEGathererState
 
 	validatedServers []*ice.URL
+	gatherPolicy     ICETransportPolicy
 
 	agent *ice.Agent
 
+	// errors are the gathering errors of the servers, replayed to the
+	// handler set after the gathering
+	errors      []ICEGatheringError
+	onErrorHdlr func(ICEGatheringError)
+
+	// trickle gathers the candidates in the background once Gather is
+	// called, they are passed to onLocalCandidateHdlr as they are gathered
+	trickle              bool
+	onLocalCandidateHdlr func(*ICECandidate)
+	onStateChangeHdlr    func(ICEGathererState)
+
 	api *API
 }
 
@@ -40,6 +54,8 @@ func (api *API) NewICEGatherer(opts ICEGatherOptions) (*ICEGatherer, error) {
 	return &ICEGatherer{
 		state:            ICEGathererStateNew,
 		validatedServers: validatedServers,
+		gatherPolicy:     opts.ICEGatherPolicy,
+		trickle:          api.settingEngine.trickle,
 		api:              api,
 	}, nil
 }
@@ -51,30 +67,268 @@ func (g *ICEGatherer) State() ICEGathererState {
 	return g.state
 }
 
-// Gather ICE candidates.
+// Gather ICE candidates. With SettingEngine.EnableTrickle, Gather returns
+// once the local parameters are known and the candidates are gathered in
+// the background, they are passed to OnLocalCandidate as they are gathered.
 func (g *ICEGatherer) Gather() error {
+	if err := g.createAgent(); err != nil {
+		return err
+	}
+
+	g.lock.Lock()
+	if !g.trickle || g.state != ICEGathererStateNew {
+		g.lock.Unlock()
+		return nil
+	}
+	agent := g.agent
+	g.state = ICEGathererStateGathering
+	hdlr := g.onStateChangeHdlr
+	g.lock.Unlock()
+
+	if hdlr != nil {
+		hdlr(ICEGathererStateGathering)
+	}
+	if err := agent.OnCandidate(func(c *ice.Candidate) {
+		g.onCandidate(agent, c)
+	}); err != nil {
+		return err
+	}
+	return agent.GatherCandidates()
+}
+
+// createAgent creates the ICE agent, which knows the local parameters of
+// the gatherer. The candidates are gathered with it unless trickle is set,
+// they are left to Gather otherwise.
+func (g *ICEGatherer) createAgent() error {
 	g.lock.Lock()
 	defer g.lock.Unlock()
 
+	if g.agent != nil {
+		return nil
+	}
+
+	var networkTypes []ice.NetworkType
+	for _, t := range g.api.settingEngine.networkTypes {
+		networkType, err := t.toICE()
+		if err != nil {
+			return err
+		}
+		networkTypes = append(networkTypes, networkType)
+	}
+
+	var hostCandidatePolicy ice.HostCandidatePolicy
+	if g.api.settingEngine.hostCandidates.Max > 0 {
+		var err error
+		if hostCandidatePolicy, err = g.api.settingEngine.hostCandidates.Policy.toICE(); err != nil {
+			return err
+		}
+	}
+
+	var multicastDNSMode ice.MulticastDNSMode
+	if mode := g.api.settingEngine.multicastDNSMode; mode != MulticastDNSMode(Unknown) {
+		var err error
+		if multicastDNSMode, err = mode.toICE(); err != nil {
+			return err
+		}
+	}
+
 	config := &ice.AgentConfig{
 		Urls:              g.validatedServers,
 		PortMin:           g.api.settingEngine.ephemeralUDP.PortMin,
 		PortMax:           g.api.settingEngine.ephemeralUDP.PortMax,
 		ConnectionTimeout: g.api.settingEngine.timeout.ICEConnection,
 		KeepaliveInterval: g.api.settingEngine.timeout.ICEKeepalive,
+		FailedTimeout:     g.api.settingEngine.timeout.ICEFailed,
+		RandomSource:      g.api.settingEngine.insecureRandomSource,
+		DSCP:              g.api.settingEngine.dscp,
+		TLSConfig:         g.api.settingEngine.turnTLSConfig,
+		NetworkTypes:      networkTypes,
+		CandidateTypes:    g.gatherPolicy.candidateTypes(),
+
+		InterfaceFilter:        g.api.settingEngine.interfaceFilter,
+		IPFilter:               g.api.settingEngine.ipFilter,
+		NAT1To1IPs:             g.api.settingEngine.nat1To1IPs.IPs,
+		NAT1To1IPCandidateType: g.api.settingEngine.nat1To1IPs.CandidateType,
+
+		MaxHostCandidates:   g.api.settingEngine.hostCandidates.Max,
+		HostCandidatePolicy: hostCandidatePolicy,
+		MulticastDNSMode:    multicastDNSMode,
+
+		Logger: g.api.iceLog,
+
+		Trickle: g.trickle,
+	}
+	if conn := g.api.settingEngine.iceConn; conn != nil {
+		if !atomic.CompareAndSwapInt32(g.api.iceConnClaimed, 0, 1) {
+			return ErrICEConnInUse
+		}
+		config.PacketConn = conn
+	}
+	if mux := g.api.settingEngine.iceUDPMux; mux != nil {
+		config.UDPMux = mux.mux
 	}
 
 	agent, err := ice.NewAgent(config)
 	if err != nil {
 		return err
 	}
-
 	g.agent = agent
+	if g.trickle {
+		return nil
+	}
+
+	gatheringErrors, err := agent.GetGatheringErrors()
+	if err != nil {
+		return err
+	}
+	for _, e := range gatheringErrors {
+		g.errors = append(g.errors, newICEGatheringErrorFromICE(e))
+	}
+
 	g.state = ICEGathererStateComplete
+	onGatheringErrors(g.onErrorHdlr, g.errors)
 
 	return nil
 }
 
+// onCandidate passes the candidates trickled by the agent to the
+// OnLocalCandidate handler, and completes the gathering at the end. The
+// candidates of an agent that was replaced are dropped.
+func (g *ICEGatherer) onCandidate(agent *ice.Agent, c *ice.Candidate) {
+	g.lock.RLock()
+	current := g.agent == agent
+	g.lock.RUnlock()
+	if !current {
+		return
+	}
+
+	if c != nil {
+		candidate, err := newICECandidateFromICE(c)
+		if err != nil {
+			g.api.log.Warnf("Failed to convert the local candidate %s: %v", c, err)
+			return
+		}
+		g.lock.RLock()
+		hdlr := g.onLocalCandidateHdlr
+		g.lock.RUnlock()
+		if hdlr != nil {
+			hdlr(&candidate)
+		}
+		return
+	}
+
+	g.lock.Lock()
+	if g.agent != agent {
+		g.lock.Unlock()
+		return
+	}
+	gatheringErrors, err := agent.GetGatheringErrors()
+	if err != nil {
+		g.lock.Unlock()
+		return
+	}
+	for _, e := range gatheringErrors {
+		g.errors = append(g.errors, newICEGatheringErrorFromICE(e))
+	}
+	g.state = ICEGathererStateComplete
+	onLocalCandidateHdlr, onStateChangeHdlr := g.onLocalCandidateHdlr, g.onStateChangeHdlr
+	done := onGatheringErrors(g.onErrorHdlr, g.errors)
+	g.lock.Unlock()
+
+	// The errors are reported before the end of the candidates
+	<-done
+	if onStateChangeHdlr != nil {
+		onStateChangeHdlr(ICEGathererStateComplete)
+	}
+	if onLocalCandidateHdlr != nil {
+		onLocalCandidateHdlr(nil)
+	}
+}
+
+// OnLocalCandidate sets an event handler which is invoked with each local
+// candidate gathered with SettingEngine.EnableTrickle, and with nil once
+// the gathering is complete. The handler is invoked from the gathering,
+// in the order the candidates are gathered.
+func (g *ICEGatherer) OnLocalCandidate(f func(*ICECandidate)) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.onLocalCandidateHdlr = f
+}
+
+// OnStateChange sets an event handler which is invoked when the gathering
+// of the candidates starts and completes with SettingEngine.EnableTrickle
+func (g *ICEGatherer) OnStateChange(f func(ICEGathererState)) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.onStateChangeHdlr = f
+}
+
+// setGatherPolicy gathers the candidates again under another policy, the
+// previous ones are closed. The gathering errors are those of the new
+// gathering.
+func (g *ICEGatherer) setGatherPolicy(policy ICETransportPolicy) error {
+	g.lock.Lock()
+	if policy == g.gatherPolicy {
+		g.lock.Unlock()
+		return nil
+	}
+	// The conn is closed with the agent, it can't be gathered again
+	if g.api.settingEngine.iceConn != nil {
+		g.lock.Unlock()
+		return &rtcerr.InvalidModificationError{Err: ErrModifyingICETransportPolicy}
+	}
+
+	if g.agent != nil {
+		if err := g.agent.Close(); err != nil {
+			g.lock.Unlock()
+			return err
+		}
+		g.agent = nil
+	}
+	// A trickled gathering that started is started again
+	restart := g.trickle && g.state != ICEGathererStateNew
+	g.gatherPolicy = policy
+	g.errors = nil
+	g.state = ICEGathererStateNew
+	g.lock.Unlock()
+
+	if restart {
+		return g.Gather()
+	}
+	return g.createAgent()
+}
+
+// OnError sets an event handler which is invoked for each STUN or TURN
+// server candidates could not be gathered from. The gathering happens when
+// the PeerConnection is created, the handler is invoked right away with the
+// errors of a gathering that already happened.
+func (g *ICEGatherer) OnError(f func(ICEGatheringError)) {
+	g.lock.Lock()
+	g.onErrorHdlr = f
+	errs := append([]ICEGatheringError{}, g.errors...)
+	g.lock.Unlock()
+
+	onGatheringErrors(f, errs)
+}
+
+// onGatheringErrors invokes the handler with each error, in order
+func onGatheringErrors(hdlr func(ICEGatheringError), errs []ICEGatheringError) (done chan struct{}) {
+	done = make(chan struct{})
+	if hdlr == nil || len(errs) == 0 {
+		close(done)
+		return
+	}
+
+	go func() {
+		for _, e := range errs {
+			hdlr(e)
+		}
+		close(done)
+	}()
+
+	return
+}
+
 // Close prunes all local candidates, and closes the ports.
 func (g *ICEGatherer) Close() error {
 	g.lock.Lock()
@@ -93,6 +347,52 @@ func (g *ICEGatherer) Close() error {
 	return nil
 }
 
+// restart starts a new ICE session with the local parameters params, new
+// random ones when empty. The candidates are gathered again: before restart
+// returns, or by the next Gather with trickle.
+func (g *ICEGatherer) restart(params ICEParameters) error {
+	g.lock.Lock()
+	agent := g.agent
+	g.lock.Unlock()
+	if agent == nil {
+		return errors.New("gatherer not started")
+	}
+
+	if err := agent.Restart(params.UsernameFragment, params.Password); err != nil {
+		return err
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.errors = nil
+	if g.trickle {
+		g.state = ICEGathererStateNew
+		return nil
+	}
+
+	gatheringErrors, err := agent.GetGatheringErrors()
+	if err != nil {
+		return err
+	}
+	for _, e := range gatheringErrors {
+		g.errors = append(g.errors, newICEGatheringErrorFromICE(e))
+	}
+	onGatheringErrors(g.onErrorHdlr, g.errors)
+	return nil
+}
+
+// rollbackRestart brings back the ICE session restart ended, the candidates
+// gathered meanwhile are kept
+func (g *ICEGatherer) rollbackRestart() error {
+	g.lock.RLock()
+	agent := g.agent
+	g.lock.RUnlock()
+	if agent == nil {
+		return errors.New("gatherer not started")
+	}
+	return agent.RollbackRestart()
+}
+
 // GetLocalParameters returns the ICE parameters of the ICEGatherer.
 func (g *ICEGatherer) GetLocalParameters() (ICEParameters, error) {
 	g.lock.RLock()
@@ -1,6 +1,7 @@
 package webrtc
 
 import (
+	"net"
 	"testing"
 	"time"
 
@@ -59,3 +60,90 @@ func TestNewICEGatherer_Success(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestICEGatherer_NetworkTypes(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.SetNetworkTypes([]NetworkType{NetworkTypeUDP6})
+	api := NewAPI(WithSettingEngine(s))
+
+	gatherer, err := api.NewICEGatherer(ICEGatherOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = gatherer.Gather(); err != nil {
+		t.Fatal(err)
+	}
+
+	candidates, err := gatherer.GetLocalCandidates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range candidates {
+		if ip := net.ParseIP(c.IP); ip == nil || ip.To4() != nil {
+			t.Fatalf("Gathered a candidate on %s with only IPv6 enabled", c.IP)
+		}
+	}
+
+	if err = gatherer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestICEGatherer_OnError(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.SetNetworkTypes([]NetworkType{NetworkTypeUDP4})
+	api := NewAPI(WithSettingEngine(s))
+
+	gatherer, err := api.NewICEGatherer(ICEGatherOptions{
+		ICEServers: []ICEServer{{
+			URLs:           []string{"turns:127.0.0.1:5349?transport=udp"},
+			Username:       "user",
+			Credential:     "pass",
+			CredentialType: ICECredentialTypePassword,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = gatherer.Gather(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The errors of a gathering that already happened are replayed
+	errs := make(chan ICEGatheringError, 1)
+	gatherer.OnError(func(e ICEGatheringError) {
+		errs <- e
+	})
+
+	e := <-errs
+	if e.URL != "turns:127.0.0.1:5349?transport=udp" {
+		t.Errorf("Unexpected URL %s", e.URL)
+	}
+	if e.NetworkType != NetworkTypeUDP4 {
+		t.Errorf("Unexpected network type %s", e.NetworkType)
+	}
+	if e.ErrorCode != 0 || e.ErrorText == "" {
+		t.Errorf("Unexpected error %d %q for an unsupported scheme", e.ErrorCode, e.ErrorText)
+	}
+
+	if err = gatherer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
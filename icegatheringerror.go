@@ -0,0 +1,40 @@
+package webrtc
+
+import (
+	"github.com/pions/webrtc/pkg/ice"
+)
+
+// ICEGatheringErrorCodeServerUnreachable is the ErrorCode of an
+// ICEGatheringError when the server could not be reached or sent no response
+const ICEGatheringErrorCodeServerUnreachable = ice.ErrorCodeServerUnreachable
+
+// ICEGatheringError describes the failure to gather candidates from one of
+// the STUN or TURN servers of the Configuration, as the icecandidateerror
+// event of the WebRTC API does.
+// https://www.w3.org/TR/webrtc/#rtcpeerconnectioniceerrorevent
+type ICEGatheringError struct {
+	// URL is the URL of the server that failed
+	URL string `json:"url"`
+
+	// NetworkType is the network the candidates were gathered on
+	NetworkType NetworkType `json:"networkType"`
+
+	// ErrorCode is the STUN error code the server responded with, such as
+	// 401 when the credentials are rejected or 437 on an allocation
+	// mismatch, or ICEGatheringErrorCodeServerUnreachable. It is 0 when no
+	// request was sent, as for the schemes that aren't supported.
+	ErrorCode int `json:"errorCode"`
+
+	// ErrorText is the reason of the failure
+	ErrorText string `json:"errorText"`
+}
+
+func newICEGatheringErrorFromICE(e *ice.GatheringError) ICEGatheringError {
+	networkType, _ := newNetworkType(e.NetworkType.String())
+	return ICEGatheringError{
+		URL:         e.URL.String(),
+		NetworkType: networkType,
+		ErrorCode:   e.ErrorCode,
+		ErrorText:   e.Err.Error(),
+	}
+}
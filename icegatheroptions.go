@@ -3,4 +3,8 @@ package webrtc
 // ICEGatherOptions provides options relating to the gathering of ICE candidates.
 type ICEGatherOptions struct {
 	ICEServers []ICEServer
+
+	// ICEGatherPolicy restricts the local candidates gathered, all of them
+	// are gathered when it is left unset
+	ICEGatherPolicy ICETransportPolicy
 }
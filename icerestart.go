@@ -0,0 +1,267 @@
+package webrtc
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pions/sdp/v2"
+	"github.com/pions/webrtc/internal/util"
+)
+
+// iceSession is an ICE session of the PeerConnection: the media one, and
+// the one of the data channels when they aren't bundled with the media
+type iceSession struct {
+	gatherer  *ICEGatherer
+	transport *ICETransport
+
+	// inSession returns whether the media section of mid belongs to the
+	// session
+	inSession func(mid string) bool
+}
+
+func (pc *PeerConnection) iceSessions() []iceSession {
+	dataTransport := pc.unbundledData()
+	if dataTransport == nil {
+		return []iceSession{{
+			gatherer:  pc.iceGatherer,
+			transport: pc.iceTransport,
+			inSession: func(string) bool { return true },
+		}}
+	}
+
+	return []iceSession{
+		{
+			gatherer:  pc.iceGatherer,
+			transport: pc.iceTransport,
+			inSession: func(mid string) bool { return mid != dataTransport.mid },
+		},
+		{
+			gatherer:  dataTransport.iceGatherer,
+			transport: dataTransport.iceTransport,
+			inSession: func(mid string) bool { return mid == dataTransport.mid },
+		},
+	}
+}
+
+// newICERestart returns new local parameters for the started ICE sessions,
+// for an offer with OfferOptions.ICERestart. The sessions keep theirs until
+// the offer is set, see applyICERestart.
+func (pc *PeerConnection) newICERestart() (map[*ICEGatherer]ICEParameters, error) {
+	restart := map[*ICEGatherer]ICEParameters{}
+	for _, s := range pc.iceSessions() {
+		if s.transport.remoteParameters().UsernameFragment == "" {
+			continue
+		}
+
+		ufrag, err := util.RandSeqFrom(pc.api.settingEngine.randomSource(), 16)
+		if err != nil {
+			return nil, err
+		}
+		pwd, err := util.RandSeqFrom(pc.api.settingEngine.randomSource(), 32)
+		if err != nil {
+			return nil, err
+		}
+		restart[s.gatherer] = ICEParameters{UsernameFragment: ufrag, Password: pwd}
+	}
+	return restart, nil
+}
+
+// offeredICE returns the local ICE parameters and candidates an offer
+// carries for the session of g: the new parameters of an ICE restart, whose
+// candidates are only gathered once the offer is set, or the current ones
+func (pc *PeerConnection) offeredICE(g *ICEGatherer) (ICEParameters, localCandidates, error) {
+	if params, ok := pc.pendingICERestart[g]; ok {
+		return params, localCandidates{}, nil
+	}
+
+	params, err := g.GetLocalParameters()
+	if err != nil {
+		return ICEParameters{}, localCandidates{}, err
+	}
+	candidates, err := gatheredCandidates(g)
+	if err != nil {
+		return ICEParameters{}, localCandidates{}, err
+	}
+	return params, candidates, nil
+}
+
+// applyICERestart restarts the ICE sessions with the parameters of the ICE
+// restart offer being set, the remote ones come with the answer. The
+// selected pairs keep carrying the traffic, with the previous credentials,
+// meanwhile. Without trickle the candidates gathered again are added to the
+// description.
+func (pc *PeerConnection) applyICERestart(desc *SessionDescription) error {
+	restart := pc.pendingICERestart
+	pc.pendingICERestart = nil
+	if len(restart) > 0 {
+		pc.mu.Lock()
+		pc.failedICERestart = false
+		pc.mu.Unlock()
+	}
+
+	changed := false
+	for _, s := range pc.iceSessions() {
+		params, ok := restart[s.gatherer]
+		if !ok {
+			continue
+		}
+		if err := s.gatherer.restart(params); err != nil {
+			return err
+		}
+		pc.appliedICERestart = append(pc.appliedICERestart, s.gatherer)
+
+		candidates, err := gatheredCandidates(s.gatherer)
+		if err != nil {
+			return err
+		}
+		if len(candidates.candidates) == 0 && !candidates.complete {
+			continue
+		}
+		for _, m := range desc.parsed.MediaDescriptions {
+			if mid, _ := m.Attribute(sdp.AttrKeyMID); s.inSession(mid) {
+				candidates.addTo(m)
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	raw, err := desc.parsed.Marshal()
+	if err != nil {
+		return err
+	}
+	desc.SDP = string(raw)
+	return nil
+}
+
+// rollbackICERestart brings back the ICE sessions the rolled back offer
+// restarted
+func (pc *PeerConnection) rollbackICERestart() error {
+	gatherers := pc.appliedICERestart
+	pc.appliedICERestart = nil
+	for _, g := range gatherers {
+		if err := g.rollbackRestart(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restartRemoteICE restarts the ICE sessions whose credentials changed in a
+// description renegotiating the connection. The sessions restart locally
+// as well for an offer, the answer carries the new local credentials.
+func (pc *PeerConnection) restartRemoteICE(desc *SessionDescription) error {
+	for _, s := range pc.iceSessions() {
+		current := s.transport.remoteParameters()
+		if current.UsernameFragment == "" {
+			continue
+		}
+
+		params, candidates, err := remoteICEParameters(desc.parsed, s.inSession)
+		if err != nil {
+			return err
+		}
+		if params.UsernameFragment == "" ||
+			(params.UsernameFragment == current.UsernameFragment && params.Password == current.Password) {
+			continue
+		}
+
+		if desc.Type == SDPTypeOffer {
+			if err := s.gatherer.restart(ICEParameters{}); err != nil {
+				return err
+			}
+
+			// The remote restarted the failed connection first
+			pc.mu.Lock()
+			pc.failedICERestart = false
+			pc.mu.Unlock()
+		}
+		if err := s.transport.restart(params); err != nil {
+			return err
+		}
+		for _, c := range candidates {
+			if err := s.transport.AddRemoteCandidate(c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// restartFailedICE schedules the restart of the ICE connection once it
+// failed, with the retry of SettingEngine.SetICERestartOnFailure. The
+// restart is left to the next offer, OnNegotiationNeeded is fired for it
+// unless the connection recovered meanwhile.
+func (pc *PeerConnection) restartFailedICE(state ICEConnectionState) {
+	retry := pc.api.settingEngine.iceRestartRetry
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	switch {
+	case state == ICEConnectionStateConnected || state == ICEConnectionStateCompleted:
+		pc.iceRestartAttempts = 0
+		return
+	case state != ICEConnectionStateFailed || pc.iceRestartAttempts >= retry.Attempts:
+		return
+	}
+
+	backoff := retry.Backoff
+	for i := 0; i < pc.iceRestartAttempts; i++ {
+		backoff *= 2
+	}
+	pc.iceRestartAttempts++
+
+	time.AfterFunc(backoff, func() {
+		pc.mu.Lock()
+		failed := pc.iceConnectionState == ICEConnectionStateFailed && !pc.closed()
+		if failed {
+			pc.failedICERestart = true
+		}
+		pc.mu.Unlock()
+
+		if failed {
+			pc.api.log.Infof("Restarting the failed ICE connection")
+			pc.updateNegotiationNeeded()
+		}
+	})
+}
+
+// remoteICEParameters returns the ICE credentials and the candidates of the
+// media sections of a session
+func remoteICEParameters(desc *sdp.SessionDescription, inSession func(mid string) bool) (ICEParameters, []ICECandidate, error) {
+	var params ICEParameters
+	var candidates []ICECandidate
+	for _, m := range desc.MediaDescriptions {
+		if mid, _ := m.Attribute(sdp.AttrKeyMID); !inSession(mid) {
+			continue
+		}
+
+		for _, a := range m.Attributes {
+			switch {
+			case a.IsICECandidate():
+				sdpCandidate, err := a.ToICECandidate()
+				if err != nil {
+					return ICEParameters{}, nil, err
+				}
+
+				candidate, err := newICECandidateFromSDP(sdpCandidate)
+				if err != nil {
+					return ICEParameters{}, nil, err
+				}
+				// The RTCP candidates are never used, see
+				// SetRemoteDescription
+				if candidate.Component == uint16(ICEComponentRTCP) {
+					continue
+				}
+				candidates = append(candidates, candidate)
+			case strings.HasPrefix(*a.String(), "ice-ufrag"):
+				params.UsernameFragment = (*a.String())[len("ice-ufrag:"):]
+			case strings.HasPrefix(*a.String(), "ice-pwd"):
+				params.Password = (*a.String())[len("ice-pwd:"):]
+			}
+		}
+	}
+	return params, candidates, nil
+}
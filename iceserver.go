@@ -36,12 +36,17 @@ func (s ICEServer) validate() ([]*ice.URL, error) {
 			switch s.CredentialType {
 			case ICECredentialTypePassword:
 				// https://www.w3.org/TR/webrtc/#set-the-configuration (step #11.3.3)
-				if _, ok := s.Credential.(string); !ok {
+				password, ok := s.Credential.(string)
+				if !ok {
 					return nil, &rtcerr.InvalidAccessError{Err: ErrTurnCredencials}
 				}
+				url.Username = s.Username
+				url.Password = password
 
 			case ICECredentialTypeOauth:
 				// https://www.w3.org/TR/webrtc/#set-the-configuration (step #11.3.4)
+				// The servers are only authenticated with long-term credentials,
+				// gathering from them reports an ICEGatheringError
 				if _, ok := s.Credential.(OAuthCredential); !ok {
 					return nil, &rtcerr.InvalidAccessError{Err: ErrTurnCredencials}
 				}
@@ -35,6 +35,12 @@ func TestICEServer_validate(t *testing.T) {
 			_, err := testCase.iceServer.validate()
 			assert.Nil(t, err, "testCase: %d %v", i, testCase)
 		}
+
+		// The password is the long-term credential of the TURN URLs
+		urls, err := testCases[0].iceServer.validate()
+		assert.NoError(t, err)
+		assert.Equal(t, "unittest", urls[0].Username)
+		assert.Equal(t, "placeholder", urls[0].Password)
 	})
 	t.Run("Failure", func(t *testing.T) {
 		testCases := []struct {
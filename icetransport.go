@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"sync/atomic"
 
 	"github.com/pions/webrtc/internal/mux"
 	"github.com/pions/webrtc/pkg/ice"
@@ -12,6 +13,10 @@ import (
 // ICETransport allows an application access to information about the ICE
 // transport over which packets are sent and received.
 type ICETransport struct {
+	// state is accessed atomically, it is kept first for the 64-bit
+	// alignment required on 32-bit platforms
+	state int32
+
 	lock sync.RWMutex
 
 	role ICERole
@@ -21,6 +26,13 @@ type ICETransport struct {
 
 	onConnectionStateChangeHdlr func(ICETransportState)
 
+	// onConnectedHdlr is fired with the connected state before
+	// onConnectionStateChangeHdlr, set by the DTLSTransport
+	onConnectedHdlr func()
+
+	// remoteParams are the ICE parameters of the remote session
+	remoteParams ICEParameters
+
 	gatherer *ICEGatherer
 	conn     *ice.Conn
 	mux      *mux.Mux
@@ -79,6 +91,7 @@ func (t *ICETransport) Start(gatherer *ICEGatherer, params ICEParameters, role *
 		role = &controlled
 	}
 	t.role = *role
+	t.remoteParams = params
 
 	// Drop the lock here to allow trickle-ICE candidates to be
 	// added so that the agent can complete a connection
@@ -112,6 +125,31 @@ func (t *ICETransport) Start(gatherer *ICEGatherer, params ICEParameters, role *
 	return nil
 }
 
+// restart checks the session the ICEGatherer restarted against the remote
+// session of params, the remote candidates are to be added afterwards. The
+// connection is kept, the traffic moves on to the new session once a pair
+// of it is selected.
+func (t *ICETransport) restart(params ICEParameters) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if err := t.ensureGatherer(); err != nil {
+		return err
+	}
+	if err := t.gatherer.agent.SetRemoteCredentials(params.UsernameFragment, params.Password); err != nil {
+		return err
+	}
+	t.remoteParams = params
+	return nil
+}
+
+// remoteParameters returns the ICE parameters of the remote session
+func (t *ICETransport) remoteParameters() ICEParameters {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return t.remoteParams
+}
+
 // Stop irreversibly stops the ICETransport.
 func (t *ICETransport) Stop() error {
 	// Close the Mux. This closes the Mux and the underlying ICE conn.
@@ -121,6 +159,12 @@ func (t *ICETransport) Stop() error {
 	if t.mux != nil {
 		return t.mux.Close()
 	}
+
+	// The transport didn't connect, closing the agent of the ICEGatherer
+	// aborts a pending Start
+	if t.gatherer != nil {
+		return t.gatherer.Close()
+	}
 	return nil
 }
 
@@ -132,15 +176,35 @@ func (t *ICETransport) OnConnectionStateChange(f func(ICETransportState)) {
 	t.onConnectionStateChangeHdlr = f
 }
 
+// onConnected sets a handler that is fired when the ICE connection is
+// connected, the first time or once it reconnects
+func (t *ICETransport) onConnected(f func()) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.onConnectedHdlr = f
+}
+
 func (t *ICETransport) onConnectionStateChange(state ICETransportState) {
+	atomic.StoreInt32(&t.state, int32(state))
 	t.lock.RLock()
-	hdlr := t.onConnectionStateChangeHdlr
+	hdlr, connectedHdlr := t.onConnectionStateChangeHdlr, t.onConnectedHdlr
 	t.lock.RUnlock()
+	if connectedHdlr != nil && (state == ICETransportStateConnected || state == ICETransportStateCompleted) {
+		connectedHdlr()
+	}
 	if hdlr != nil {
 		hdlr(state)
 	}
 }
 
+// State returns the current ICE transport state.
+func (t *ICETransport) State() ICETransportState {
+	if state := atomic.LoadInt32(&t.state); state != 0 {
+		return ICETransportState(state)
+	}
+	return ICETransportStateNew
+}
+
 // Role indicates the current role of the ICE transport.
 func (t *ICETransport) Role() ICERole {
 	t.lock.RLock()
@@ -193,6 +257,114 @@ func (t *ICETransport) AddRemoteCandidate(remoteCandidate ICECandidate) error {
 	return nil
 }
 
+// GetCandidatePairs returns the candidate pairs that passed the connectivity
+// checks, by decreasing priority, with the round-trip time measured on them.
+func (t *ICETransport) GetCandidatePairs() ([]ICECandidatePair, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if err := t.ensureGatherer(); err != nil {
+		return nil, err
+	}
+
+	pairs, err := t.gatherer.agent.GetCandidatePairs()
+	if err != nil {
+		return nil, err
+	}
+
+	out := []ICECandidatePair{}
+	for _, p := range pairs {
+		pair, err := newICECandidatePairFromICE(p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, pair)
+	}
+	return out, nil
+}
+
+// GetChecklist returns a snapshot of the checklist: every pair of a local
+// and a remote candidate, by decreasing priority, with the state of its
+// connectivity checks. The checks stop once a pair is selected, until it
+// fails.
+func (t *ICETransport) GetChecklist() ([]ICECandidatePairCheck, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if err := t.ensureGatherer(); err != nil {
+		return nil, err
+	}
+
+	checks, err := t.gatherer.agent.GetChecklist()
+	if err != nil {
+		return nil, err
+	}
+
+	out := []ICECandidatePairCheck{}
+	for _, c := range checks {
+		check, err := newICECandidatePairCheckFromICE(c)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, check)
+	}
+	return out, nil
+}
+
+// SetSelectedCandidatePair forces the media onto the pair of the given
+// candidates, which must be one of GetCandidatePairs, instead of the pair
+// nominated by the connectivity checks. The controlling side nominates the
+// pair to the remote as well, the controlled side only sends on it. The pair
+// is monitored like a nominated one, the nominations are followed again once
+// it times out.
+//
+// This is an advanced feature: forcing a pair that the network favors less,
+// such as a pair through a relay, costs latency and bandwidth, and forcing a
+// pair that happens to work now can hurt connectivity later.
+func (t *ICETransport) SetSelectedCandidatePair(local, remote ICECandidate) error {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if err := t.ensureGatherer(); err != nil {
+		return err
+	}
+
+	iceLocal, err := local.toICE()
+	if err != nil {
+		return err
+	}
+	iceRemote, err := remote.toICE()
+	if err != nil {
+		return err
+	}
+	return t.gatherer.agent.SetSelectedCandidatePair(iceLocal, iceRemote)
+}
+
+// GetStats returns the traffic sent and received on the sockets of the
+// ICETransport so far, and its current ICE role. The counters are monotonic
+// and safe to read from any goroutine.
+func (t *ICETransport) GetStats() TransportStats {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if err := t.ensureGatherer(); err != nil {
+		return TransportStats{}
+	}
+
+	stats := t.gatherer.agent.GetTransportStats()
+	role := ICERoleControlled
+	if stats.Controlling {
+		role = ICERoleControlling
+	}
+	return TransportStats{
+		BytesSent:       stats.BytesSent,
+		BytesReceived:   stats.BytesReceived,
+		PacketsSent:     stats.PacketsSent,
+		PacketsReceived: stats.PacketsReceived,
+		ICERole:         role,
+	}
+}
+
 func (t *ICETransport) ensureGatherer() error {
 	if t.gatherer == nil ||
 		t.gatherer.agent == nil {
@@ -0,0 +1,66 @@
+package webrtc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pions/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestICETransport_State(t *testing.T) {
+	transport := NewAPI().NewICETransport(nil)
+	assert.Equal(t, ICETransportState(ICETransportStateNew), transport.State())
+
+	var fired ICETransportState
+	transport.OnConnectionStateChange(func(state ICETransportState) {
+		fired = state
+	})
+	transport.onConnectionStateChange(ICETransportStateDisconnected)
+	assert.Equal(t, ICETransportState(ICETransportStateDisconnected), transport.State())
+	assert.Equal(t, transport.State(), fired)
+}
+
+func TestICETransport_GetChecklist(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	pcOffer, pcAnswer, err := NewAPI().newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	if err = pcOffer.WaitUntilConnected(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	checks, err := pcOffer.iceTransport.GetChecklist()
+	if err != nil {
+		t.Fatal(err)
+	}
+	selected := 0
+	for _, check := range checks {
+		if check.Selected {
+			selected++
+			assert.Equal(t, ICECandidatePairStateSucceeded, check.State)
+			assert.True(t, check.Nominated)
+			assert.NotZero(t, check.ResponsesReceived)
+		}
+	}
+	assert.Equal(t, 1, selected)
+
+	// The pairs validated by the checks are in the checklist
+	pairs, err := pcOffer.iceTransport.GetCandidatePairs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, len(checks) >= len(pairs))
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
@@ -1,5 +1,9 @@
 package webrtc
 
+import (
+	"github.com/pions/webrtc/pkg/ice"
+)
+
 // ICETransportPolicy defines the ICE candidate policy surface the
 // permitted candidates. Only these candidates are used for connectivity checks.
 type ICETransportPolicy int
@@ -11,12 +15,18 @@ const (
 
 	// ICETransportPolicyAll indicates any type of candidate is used.
 	ICETransportPolicyAll
+
+	// ICETransportPolicyNoHost indicates the server reflexive and relay
+	// candidates are used, the host addresses are kept private. It isn't
+	// part of the WebRTC specification.
+	ICETransportPolicyNoHost
 )
 
 // This is done this way because of a linter.
 const (
-	iceTransportPolicyRelayStr = "relay"
-	iceTransportPolicyAllStr   = "all"
+	iceTransportPolicyRelayStr  = "relay"
+	iceTransportPolicyAllStr    = "all"
+	iceTransportPolicyNoHostStr = "nohost"
 )
 
 func newICETransportPolicy(raw string) ICETransportPolicy {
@@ -25,6 +35,8 @@ func newICETransportPolicy(raw string) ICETransportPolicy {
 		return ICETransportPolicyRelay
 	case iceTransportPolicyAllStr:
 		return ICETransportPolicyAll
+	case iceTransportPolicyNoHostStr:
+		return ICETransportPolicyNoHost
 	default:
 		return ICETransportPolicy(Unknown)
 	}
@@ -36,7 +48,22 @@ func (t ICETransportPolicy) String() string {
 		return iceTransportPolicyRelayStr
 	case ICETransportPolicyAll:
 		return iceTransportPolicyAllStr
+	case ICETransportPolicyNoHost:
+		return iceTransportPolicyNoHostStr
 	default:
 		return ErrUnknownType.Error()
 	}
 }
+
+// candidateTypes returns the types of the local candidates gathered under
+// the policy, nil for all of them
+func (t ICETransportPolicy) candidateTypes() []ice.CandidateType {
+	switch t {
+	case ICETransportPolicyRelay:
+		return []ice.CandidateType{ice.CandidateTypeRelay}
+	case ICETransportPolicyNoHost:
+		return []ice.CandidateType{ice.CandidateTypeServerReflexive, ice.CandidateTypeRelay}
+	default:
+		return nil
+	}
+}
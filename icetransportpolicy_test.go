@@ -14,6 +14,7 @@ func TestNewICETransportPolicy(t *testing.T) {
 		{unknownStr, ICETransportPolicy(Unknown)},
 		{"relay", ICETransportPolicyRelay},
 		{"all", ICETransportPolicyAll},
+		{"nohost", ICETransportPolicyNoHost},
 	}
 
 	for i, testCase := range testCases {
@@ -33,6 +34,7 @@ func TestICETransportPolicy_String(t *testing.T) {
 		{ICETransportPolicy(Unknown), unknownStr},
 		{ICETransportPolicyRelay, "relay"},
 		{ICETransportPolicyAll, "all"},
+		{ICETransportPolicyNoHost, "nohost"},
 	}
 
 	for i, testCase := range testCases {
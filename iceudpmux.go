@@ -0,0 +1,34 @@
+package webrtc
+
+import (
+	"net"
+
+	"github.com/pions/webrtc/pkg/ice"
+)
+
+// ICEUDPMux shares a single UDP socket between the ICE agents of the
+// PeerConnections of the APIs it is set on, such as one bound to the only
+// port a container exposes. The packets of the remotes are told apart by
+// the ufrag of their connectivity checks.
+type ICEUDPMux struct {
+	mux *ice.UDPMux
+}
+
+// NewICEUDPMux creates an ICEUDPMux reading from conn, which must be bound
+// to the address the remotes reach, not to all the interfaces:
+// it is the single host candidate of the agents. The conn is closed with
+// the ICEUDPMux.
+func NewICEUDPMux(conn net.PacketConn) *ICEUDPMux {
+	return &ICEUDPMux{mux: ice.NewUDPMux(conn, nil)}
+}
+
+// LocalAddr returns the address of the socket
+func (m *ICEUDPMux) LocalAddr() net.Addr {
+	return m.mux.LocalAddr()
+}
+
+// Close closes the socket, the PeerConnections still using it lose their
+// ICE connection
+func (m *ICEUDPMux) Close() error {
+	return m.mux.Close()
+}
@@ -0,0 +1,179 @@
+package webrtc
+
+import (
+	"github.com/pions/rtcp"
+	"github.com/pions/rtp"
+)
+
+// Interceptor sees the RTP and RTCP packets of the Tracks between the
+// SRTP sessions and the RTPSenders and RTPReceivers, to log, drop, delay or
+// rewrite them, or to send feedback of its own. The Interceptors are
+// registered with WithInterceptors and bound to every stream of the API in
+// registration order: each one wraps the reader or writer the previous one
+// returned, so the first one registered is the closest to the network, it
+// reads the packets first and writes them last.
+//
+// The readers and writers are bound once the SSRC of the stream is known.
+// The retransmissions on the RTX stream of a Track go through the writer of
+// the Track, they aren't read through the reader of the received Track. The
+// RTCP sent for any SSRC with PeerConnection.WriteRTCP or SendRTCP isn't
+// intercepted, nor are the streams read with ReadPassthroughRTP and
+// ReadPassthroughRTCP.
+type Interceptor interface {
+	// BindRemoteStream wraps the reader the RTPReceiver reads the RTP
+	// packets of a received stream with. The packets read last are
+	// delivered to the Track: a reader drops a packet by reading the next
+	// one, and ends the stream by returning an error.
+	BindRemoteStream(info StreamInfo, reader RTPReader) RTPReader
+
+	// BindLocalStream wraps the writer the RTPSender writes the RTP packets
+	// of a sent stream with. A writer drops a packet by returning nil
+	// without writing it. The header of the packets is a copy, their payload
+	// is shared with the packets kept for the retransmissions: a writer
+	// rewriting the payload copies it first.
+	BindLocalStream(info StreamInfo, writer RTPWriter) RTPWriter
+
+	// BindRTCPReader wraps the reader of the RTCP packets received about a
+	// stream, sent or received.
+	BindRTCPReader(info StreamInfo, reader RTCPReader) RTCPReader
+
+	// BindRTCPWriter wraps the writer of the RTCP packets the RTPSender or
+	// the RTPReceiver of a stream sends, the feedback and reports included.
+	BindRTCPWriter(info StreamInfo, writer RTCPWriter) RTCPWriter
+}
+
+// StreamInfo describes the stream an Interceptor is bound to
+type StreamInfo struct {
+	SSRC uint32
+	Kind RTPCodecType
+
+	// HeaderExtensions maps the URI of the header extensions negotiated for
+	// the stream to their ID
+	HeaderExtensions map[string]uint8
+}
+
+// RTPReader reads the RTP packets of a stream
+type RTPReader interface {
+	ReadRTP() (*rtp.Packet, error)
+}
+
+// RTPReaderFunc is a function implementing RTPReader
+type RTPReaderFunc func() (*rtp.Packet, error)
+
+// ReadRTP calls f
+func (f RTPReaderFunc) ReadRTP() (*rtp.Packet, error) {
+	return f()
+}
+
+// RTPWriter writes the RTP packets of a stream
+type RTPWriter interface {
+	WriteRTP(packet *rtp.Packet) error
+}
+
+// RTPWriterFunc is a function implementing RTPWriter
+type RTPWriterFunc func(packet *rtp.Packet) error
+
+// WriteRTP calls f
+func (f RTPWriterFunc) WriteRTP(packet *rtp.Packet) error {
+	return f(packet)
+}
+
+// RTCPReader reads the RTCP packets of a stream, one compound packet at a
+// time
+type RTCPReader interface {
+	ReadRTCP() ([]rtcp.Packet, error)
+}
+
+// RTCPReaderFunc is a function implementing RTCPReader
+type RTCPReaderFunc func() ([]rtcp.Packet, error)
+
+// ReadRTCP calls f
+func (f RTCPReaderFunc) ReadRTCP() ([]rtcp.Packet, error) {
+	return f()
+}
+
+// RTCPWriter writes RTCP packets in a single compound packet
+type RTCPWriter interface {
+	WriteRTCP(packets []rtcp.Packet) error
+}
+
+// RTCPWriterFunc is a function implementing RTCPWriter
+type RTCPWriterFunc func(packets []rtcp.Packet) error
+
+// WriteRTCP calls f
+func (f RTCPWriterFunc) WriteRTCP(packets []rtcp.Packet) error {
+	return f(packets)
+}
+
+// NoOpInterceptor is an Interceptor returning the readers and writers it is
+// bound to unchanged, it is embedded by the Interceptors that only wrap some
+// of them.
+type NoOpInterceptor struct{}
+
+// BindRemoteStream returns reader
+func (NoOpInterceptor) BindRemoteStream(info StreamInfo, reader RTPReader) RTPReader {
+	return reader
+}
+
+// BindLocalStream returns writer
+func (NoOpInterceptor) BindLocalStream(info StreamInfo, writer RTPWriter) RTPWriter {
+	return writer
+}
+
+// BindRTCPReader returns reader
+func (NoOpInterceptor) BindRTCPReader(info StreamInfo, reader RTCPReader) RTCPReader {
+	return reader
+}
+
+// BindRTCPWriter returns writer
+func (NoOpInterceptor) BindRTCPWriter(info StreamInfo, writer RTCPWriter) RTCPWriter {
+	return writer
+}
+
+// WithInterceptors allows providing Interceptors to the API, they are bound
+// in the order they are passed in, after the ones provided before.
+func WithInterceptors(interceptors ...Interceptor) func(a *API) {
+	return func(a *API) {
+		a.interceptors = append(a.interceptors, interceptors...)
+	}
+}
+
+func (api *API) bindRemoteStream(info StreamInfo, reader RTPReader) RTPReader {
+	for _, i := range api.interceptors {
+		reader = i.BindRemoteStream(info, reader)
+	}
+	return reader
+}
+
+func (api *API) bindLocalStream(info StreamInfo, writer RTPWriter) RTPWriter {
+	for _, i := range api.interceptors {
+		writer = i.BindLocalStream(info, writer)
+	}
+	return writer
+}
+
+func (api *API) bindRTCPReader(info StreamInfo, reader RTCPReader) RTCPReader {
+	for _, i := range api.interceptors {
+		reader = i.BindRTCPReader(info, reader)
+	}
+	return reader
+}
+
+func (api *API) bindRTCPWriter(info StreamInfo, writer RTCPWriter) RTCPWriter {
+	for _, i := range api.interceptors {
+		writer = i.BindRTCPWriter(info, writer)
+	}
+	return writer
+}
+
+// streamInfo returns the info of a stream of the Track
+func (t *Track) streamInfo(ssrc uint32) StreamInfo {
+	return StreamInfo{SSRC: ssrc, Kind: t.Kind, HeaderExtensions: t.HeaderExtensions()}
+}
+
+// streamInfo returns the info of a stream of the RTPReceiver. The kind of
+// its Track is only set with the codec of the first packet, once the
+// streams are bound.
+func (r *RTPReceiver) streamInfo(ssrc uint32) StreamInfo {
+	return StreamInfo{SSRC: ssrc, Kind: r.kind, HeaderExtensions: r.Track.HeaderExtensions()}
+}
@@ -0,0 +1,73 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/rtcp"
+	"github.com/pions/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+// markingInterceptor appends its mark to the payload of the RTP packets it
+// reads and writes
+type markingInterceptor struct {
+	NoOpInterceptor
+	mark byte
+}
+
+func (i *markingInterceptor) BindRemoteStream(info StreamInfo, reader RTPReader) RTPReader {
+	return RTPReaderFunc(func() (*rtp.Packet, error) {
+		packet, err := reader.ReadRTP()
+		if err != nil {
+			return nil, err
+		}
+		packet.Payload = append(packet.Payload, i.mark)
+		return packet, nil
+	})
+}
+
+func (i *markingInterceptor) BindLocalStream(info StreamInfo, writer RTPWriter) RTPWriter {
+	return RTPWriterFunc(func(packet *rtp.Packet) error {
+		packet.Payload = append(append([]byte{}, packet.Payload...), i.mark)
+		return writer.WriteRTP(packet)
+	})
+}
+
+func TestAPI_Interceptors(t *testing.T) {
+	api := NewAPI(WithInterceptors(&markingInterceptor{mark: 1}), WithInterceptors(&markingInterceptor{mark: 2}))
+	info := StreamInfo{SSRC: 5000, Kind: RTPCodecTypeVideo}
+
+	// The first Interceptor registered reads the packets first
+	reader := api.bindRemoteStream(info, RTPReaderFunc(func() (*rtp.Packet, error) {
+		return &rtp.Packet{}, nil
+	}))
+	packet, err := reader.ReadRTP()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1, 2}, packet.Payload)
+
+	// and writes them last
+	var written *rtp.Packet
+	writer := api.bindLocalStream(info, RTPWriterFunc(func(packet *rtp.Packet) error {
+		written = packet
+		return nil
+	}))
+	assert.NoError(t, writer.WriteRTP(&rtp.Packet{}))
+	assert.Equal(t, []byte{2, 1}, written.Payload)
+
+	// The NoOpInterceptor returns the RTCP readers and writers as is
+	var rtcpWritten []rtcp.Packet
+	rtcpWriter := api.bindRTCPWriter(info, RTCPWriterFunc(func(packets []rtcp.Packet) error {
+		rtcpWritten = packets
+		return nil
+	}))
+	pli := &rtcp.PictureLossIndication{MediaSSRC: info.SSRC}
+	assert.NoError(t, rtcpWriter.WriteRTCP([]rtcp.Packet{pli}))
+	assert.Equal(t, []rtcp.Packet{pli}, rtcpWritten)
+
+	rtcpReader := api.bindRTCPReader(info, RTCPReaderFunc(func() ([]rtcp.Packet, error) {
+		return []rtcp.Packet{pli}, nil
+	}))
+	packets, err := rtcpReader.ReadRTCP()
+	assert.NoError(t, err)
+	assert.Equal(t, []rtcp.Packet{pli}, packets)
+}
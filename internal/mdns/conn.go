@@ -0,0 +1,217 @@
+// Package mdns resolves the multicast DNS names of the hosts of the link,
+// such as the .local names browsers hide the addresses of their ICE
+// candidates behind, and answers the queries for the local names
+// (RFC 6762). Only the IPv4 addresses of the names are resolved.
+package mdns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pions/webrtc/pkg/logging"
+)
+
+const (
+	// DefaultAddress is the IPv4 group and port of mDNS
+	DefaultAddress = "224.0.0.251:5353"
+
+	inboundBufferSize = 9000
+
+	// queryInterval is how long a query waits for an answer before it is
+	// sent again
+	queryInterval = time.Second
+)
+
+// ErrClosed is returned by the queries of a closed Conn
+var ErrClosed = errors.New("mDNS conn is closed")
+
+// Conn answers the queries for its names on the link and resolves the
+// names of the other hosts
+type Conn struct {
+	conn net.PacketConn
+	dst  net.Addr
+	log  logging.LeveledLogger
+
+	lock    sync.Mutex
+	names   map[string]net.IP
+	queries map[string][]chan net.IP
+
+	closeOnce sync.Once
+	closed    chan struct{}
+	readDone  chan struct{}
+}
+
+// Listen joins the mDNS group on the default multicast interface
+func Listen(log logging.LeveledLogger) (*Conn, error) {
+	addr, err := net.ResolveUDPAddr("udp4", DefaultAddress)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenMulticastUDP("udp4", nil, addr)
+	if err != nil {
+		return nil, err
+	}
+	return Server(conn, addr, log), nil
+}
+
+// Server creates a Conn reading the messages of the link from conn and
+// sending its own to dst, the group conn joined
+func Server(conn net.PacketConn, dst net.Addr, log logging.LeveledLogger) *Conn {
+	c := &Conn{
+		conn:     conn,
+		dst:      dst,
+		log:      log,
+		names:    map[string]net.IP{},
+		queries:  map[string][]chan net.IP{},
+		closed:   make(chan struct{}),
+		readDone: make(chan struct{}),
+	}
+	go c.readLoop()
+	return c
+}
+
+// normalize returns the name the names are compared by, names are case
+// insensitive
+func normalize(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+// AddName makes the Conn answer the queries for name with ip
+func (c *Conn) AddName(name string, ip net.IP) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.names[normalize(name)] = ip
+}
+
+// Query resolves name to the IPv4 address the host owning it answers with,
+// it is queried again every second until ctx is done
+func (c *Conn) Query(ctx context.Context, name string) (net.IP, error) {
+	name = normalize(name)
+	answer := make(chan net.IP, 1)
+
+	c.lock.Lock()
+	c.queries[name] = append(c.queries[name], answer)
+	c.lock.Unlock()
+	defer c.removeQuery(name, answer)
+
+	query := (&message{questions: []question{{name: name, typ: typeA}}}).marshal()
+	ticker := time.NewTicker(queryInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := c.conn.WriteTo(query, c.dst); err != nil {
+			c.log.Warnf("Failed to send mDNS query for %s: %v", name, err)
+		}
+
+		select {
+		case ip := <-answer:
+			return ip, nil
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-c.closed:
+			return nil, ErrClosed
+		}
+	}
+}
+
+func (c *Conn) removeQuery(name string, answer chan net.IP) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	queries := c.queries[name]
+	for i, q := range queries {
+		if q == answer {
+			queries = append(queries[:i], queries[i+1:]...)
+			break
+		}
+	}
+	if len(queries) == 0 {
+		delete(c.queries, name)
+	} else {
+		c.queries[name] = queries
+	}
+}
+
+// Close stops answering the queries, the pending ones return ErrClosed
+func (c *Conn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		err = c.conn.Close()
+		<-c.readDone
+	})
+	return err
+}
+
+func (c *Conn) readLoop() {
+	defer close(c.readDone)
+
+	b := make([]byte, inboundBufferSize)
+	for {
+		n, _, err := c.conn.ReadFrom(b)
+		if err != nil {
+			select {
+			case <-c.closed:
+			default:
+				c.log.Warnf("Failed to read mDNS message: %v", err)
+			}
+			return
+		}
+
+		m, err := parseMessage(b[:n])
+		if err != nil {
+			c.log.Debugf("Discarding mDNS message: %v", err)
+			continue
+		}
+		if m.response {
+			c.handleResponse(m)
+		} else {
+			c.handleQuery(m)
+		}
+	}
+}
+
+// handleResponse passes the addresses of the names queried to their queries
+func (c *Conn) handleResponse(m *message) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, a := range m.answers {
+		if a.typ != typeA || len(a.data) != net.IPv4len {
+			continue
+		}
+		name := normalize(a.name)
+		for _, answer := range c.queries[name] {
+			select {
+			case answer <- net.IP(append([]byte{}, a.data...)):
+			default:
+			}
+		}
+	}
+}
+
+// handleQuery answers the questions for the local names
+func (c *Conn) handleQuery(m *message) {
+	c.lock.Lock()
+	response := &message{response: true}
+	for _, q := range m.questions {
+		if q.typ != typeA && q.typ != typeANY {
+			continue
+		}
+		if ip, ok := c.names[normalize(q.name)]; ok && ip.To4() != nil {
+			response.answers = append(response.answers, record{name: q.name, typ: typeA, data: ip.To4()})
+		}
+	}
+	c.lock.Unlock()
+
+	if len(response.answers) == 0 {
+		return
+	}
+	if _, err := c.conn.WriteTo(response.marshal(), c.dst); err != nil {
+		c.log.Warnf("Failed to send mDNS response: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package mdns
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pions/transport/test"
+	"github.com/pions/webrtc/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// linkedPair returns two Conns, each sending its messages to the other
+func linkedPair(t *testing.T) (*Conn, *Conn) {
+	a, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := logging.NewScopedLogger("mdns")
+	return Server(a, b.LocalAddr(), log), Server(b, a.LocalAddr(), log)
+}
+
+func TestConn(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	a, b := linkedPair(t)
+	a.AddName("7b1f3c54-3a6b-4c84-9c4c-6f3f0b2e1b5a.local", net.IPv4(192, 168, 1, 2))
+
+	// The names are case insensitive
+	ip, err := b.Query(context.Background(), "7B1F3C54-3A6B-4C84-9C4C-6F3F0B2E1B5A.local.")
+	if assert.NoError(t, err) {
+		assert.True(t, ip.Equal(net.IPv4(192, 168, 1, 2)))
+	}
+
+	// An unknown name isn't answered
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = b.Query(ctx, "unknown.local")
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// Closing ends the pending queries
+	done := make(chan error)
+	go func() {
+		_, queryErr := a.Query(context.Background(), "unknown.local")
+		done <- queryErr
+	}()
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, a.Close())
+	assert.Equal(t, ErrClosed, <-done)
+	assert.NoError(t, a.Close())
+	assert.NoError(t, b.Close())
+}
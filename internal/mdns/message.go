@@ -0,0 +1,186 @@
+package mdns
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+const (
+	headerSize = 12
+
+	// The flags of a response, QR and AA
+	// https://tools.ietf.org/html/rfc6762#section-18
+	flagResponse = 0x8400
+
+	typeA   = 1
+	typeANY = 255
+
+	classIN = 1
+	// The top bit of the class of a question asks for a unicast response,
+	// the one of a record flushes the caches
+	classMask       = 0x7FFF
+	classCacheFlush = 0x8000
+
+	// responseTTL is the time to live of the answers, the one RFC 6762
+	// recommends for the records of host names
+	responseTTL = 120
+
+	// maxPointers bounds the compression pointers followed in a name, a
+	// loop of them is otherwise endless
+	maxPointers = 16
+)
+
+var errTruncated = errors.New("mDNS message is truncated")
+
+type question struct {
+	name string
+	typ  uint16
+}
+
+type record struct {
+	name string
+	typ  uint16
+	data []byte
+}
+
+// message is a DNS message, only the parts mDNS resolution uses
+// https://tools.ietf.org/html/rfc1035#section-4.1
+type message struct {
+	response  bool
+	questions []question
+	answers   []record
+}
+
+func (m *message) marshal() []byte {
+	b := make([]byte, headerSize)
+	if m.response {
+		binary.BigEndian.PutUint16(b[2:], flagResponse)
+	}
+	binary.BigEndian.PutUint16(b[4:], uint16(len(m.questions)))
+	binary.BigEndian.PutUint16(b[6:], uint16(len(m.answers)))
+
+	for _, q := range m.questions {
+		b = appendName(b, q.name)
+		b = appendUint16(b, q.typ)
+		b = appendUint16(b, classIN)
+	}
+	for _, a := range m.answers {
+		b = appendName(b, a.name)
+		b = appendUint16(b, a.typ)
+		b = appendUint16(b, classIN|classCacheFlush)
+		b = append(b, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(b[len(b)-4:], responseTTL)
+		b = appendUint16(b, uint16(len(a.data)))
+		b = append(b, a.data...)
+	}
+	return b
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendName(b []byte, name string) []byte {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
+
+// parseMessage parses the questions and the records of a message, the
+// records of the authority and additional sections are read as answers
+func parseMessage(b []byte) (*message, error) {
+	if len(b) < headerSize {
+		return nil, errTruncated
+	}
+	m := &message{response: binary.BigEndian.Uint16(b[2:])&0x8000 != 0}
+	questions := int(binary.BigEndian.Uint16(b[4:]))
+	records := int(binary.BigEndian.Uint16(b[6:])) +
+		int(binary.BigEndian.Uint16(b[8:])) +
+		int(binary.BigEndian.Uint16(b[10:]))
+
+	offset := headerSize
+	for i := 0; i < questions; i++ {
+		name, next, err := parseName(b, offset)
+		if err != nil {
+			return nil, err
+		}
+		if next+4 > len(b) {
+			return nil, errTruncated
+		}
+		m.questions = append(m.questions, question{
+			name: name,
+			typ:  binary.BigEndian.Uint16(b[next:]),
+		})
+		offset = next + 4
+	}
+
+	for i := 0; i < records; i++ {
+		name, next, err := parseName(b, offset)
+		if err != nil {
+			return nil, err
+		}
+		// Type, class, TTL and data length
+		if next+10 > len(b) {
+			return nil, errTruncated
+		}
+		length := int(binary.BigEndian.Uint16(b[next+8:]))
+		if next+10+length > len(b) {
+			return nil, errTruncated
+		}
+		if binary.BigEndian.Uint16(b[next+2:])&classMask == classIN {
+			m.answers = append(m.answers, record{
+				name: name,
+				typ:  binary.BigEndian.Uint16(b[next:]),
+				data: b[next+10 : next+10+length],
+			})
+		}
+		offset = next + 10 + length
+	}
+	return m, nil
+}
+
+// parseName parses the name at offset, and returns the offset following
+// it. The labels may end with a pointer to the rest of the name.
+// https://tools.ietf.org/html/rfc1035#section-4.1.4
+func parseName(b []byte, offset int) (string, int, error) {
+	var labels []string
+	next := -1
+	for pointers := 0; ; {
+		if offset >= len(b) {
+			return "", 0, errTruncated
+		}
+		length := int(b[offset])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = offset + 1
+			}
+			return strings.Join(labels, "."), next, nil
+
+		case length&0xC0 == 0xC0:
+			if offset+2 > len(b) {
+				return "", 0, errTruncated
+			}
+			if pointers++; pointers > maxPointers {
+				return "", 0, errors.New("mDNS name has too many pointers")
+			}
+			if next < 0 {
+				next = offset + 2
+			}
+			offset = int(binary.BigEndian.Uint16(b[offset:]) & 0x3FFF)
+
+		case length&0xC0 != 0:
+			return "", 0, errors.New("mDNS name has an unknown label type")
+
+		default:
+			if offset+1+length > len(b) {
+				return "", 0, errTruncated
+			}
+			labels = append(labels, string(b[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+	}
+}
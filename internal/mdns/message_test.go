@@ -0,0 +1,45 @@
+package mdns
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessage(t *testing.T) {
+	m := &message{
+		response:  true,
+		questions: []question{{name: "a.local", typ: typeA}},
+		answers:   []record{{name: "a.local", typ: typeA, data: []byte{192, 168, 1, 2}}},
+	}
+
+	parsed, err := parseMessage(m.marshal())
+	if assert.NoError(t, err) {
+		assert.Equal(t, m, parsed)
+	}
+
+	_, err = parseMessage(m.marshal()[:20])
+	assert.Equal(t, errTruncated, err)
+}
+
+func TestParseMessage_Pointers(t *testing.T) {
+	// A response whose answer points to the name of its question
+	raw := []byte{
+		0x00, 0x00, 0x84, 0x00, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00,
+		0x01, 'a', 0x05, 'l', 'o', 'c', 'a', 'l', 0x00, 0x00, 0x01, 0x00, 0x01,
+		0xC0, 0x0C, 0x00, 0x01, 0x80, 0x01, 0x00, 0x00, 0x00, 0x78, 0x00, 0x04, 10, 0, 0, 1,
+	}
+	m, err := parseMessage(raw)
+	if assert.NoError(t, err) {
+		assert.True(t, m.response)
+		assert.Equal(t, []record{{name: "a.local", typ: typeA, data: []byte{10, 0, 0, 1}}}, m.answers)
+	}
+
+	// A pointer to itself never ends
+	raw = []byte{
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0xC0, 0x0C, 0x00, 0x01, 0x00, 0x01,
+	}
+	_, err = parseMessage(raw)
+	assert.Error(t, err)
+}
@@ -2,6 +2,7 @@ package mux
 
 import (
 	"errors"
+	"io"
 	"net"
 	"time"
 )
@@ -10,10 +11,17 @@ import (
 type Endpoint struct {
 	mux     *Mux
 	readCh  chan []byte
-	wroteCh chan int
+	wroteCh chan wrote
 	doneCh  chan struct{}
 }
 
+// wrote is the size of the packet copied into the buffer of a Read, with its
+// receive time
+type wrote struct {
+	n         int
+	timestamp time.Time
+}
+
 // Close unregisters the endpoint from the Mux
 func (e *Endpoint) Close() error {
 	e.close()
@@ -30,12 +38,24 @@ func (e *Endpoint) close() {
 }
 
 // Read reads a packet of len(p) bytes from the underlying conn
-// that are matched by the associated MuxFunc
+// that are matched by the associated MuxFunc. A packet larger than p is
+// never truncated, io.ErrShortBuffer is returned and the packet is kept
+// for the next Read.
 func (e *Endpoint) Read(p []byte) (int, error) {
+	n, _, err := e.ReadWithTimestamp(p)
+	return n, err
+}
+
+// ReadWithTimestamp is Read returning the receive time of the packet as
+// well, as read from the underlying conn
+func (e *Endpoint) ReadWithTimestamp(p []byte) (int, time.Time, error) {
 	select {
 	case e.readCh <- p:
-		n := <-e.wroteCh
-		return n, nil
+		w := <-e.wroteCh
+		if w.n > len(p) {
+			return 0, time.Time{}, io.ErrShortBuffer
+		}
+		return w.n, w.timestamp, nil
 	case <-e.doneCh:
 		// Unblock Mux.dispatch
 		select {
@@ -43,7 +63,7 @@ func (e *Endpoint) Read(p []byte) (int, error) {
 		default:
 			close(e.readCh)
 		}
-		return 0, errors.New("endpoint closed")
+		return 0, time.Time{}, errors.New("endpoint closed")
 	}
 }
 
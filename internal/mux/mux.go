@@ -4,8 +4,15 @@ import (
 	"fmt"
 	"net"
 	"sync"
+	"time"
 )
 
+// timestampReader is implemented by the conns reading the packets with their
+// receive time, such as the ICE conn
+type timestampReader interface {
+	ReadWithTimestamp(p []byte) (int, time.Time, error)
+}
+
 // Mux allows multiplexing
 type Mux struct {
 	lock       sync.RWMutex
@@ -34,7 +41,7 @@ func (m *Mux) NewEndpoint(f MatchFunc) *Endpoint {
 	e := &Endpoint{
 		mux:     m,
 		readCh:  make(chan []byte),
-		wroteCh: make(chan int),
+		wroteCh: make(chan wrote),
 		doneCh:  make(chan struct{}),
 	}
 
@@ -78,16 +85,26 @@ func (m *Mux) readLoop() {
 	}()
 	buf := make([]byte, m.bufferSize)
 	for {
-		n, err := m.nextConn.Read(buf)
+		n, timestamp, err := m.read(buf)
 		if err != nil {
 			return
 		}
 
-		m.dispatch(buf[:n])
+		m.dispatch(buf[:n], timestamp)
+	}
+}
+
+// read reads the next packet of the conn with its receive time, the time it
+// is read unless the conn knows better
+func (m *Mux) read(buf []byte) (int, time.Time, error) {
+	if r, ok := m.nextConn.(timestampReader); ok {
+		return r.ReadWithTimestamp(buf)
 	}
+	n, err := m.nextConn.Read(buf)
+	return n, time.Now(), err
 }
 
-func (m *Mux) dispatch(buf []byte) {
+func (m *Mux) dispatch(buf []byte, timestamp time.Time) {
 	var endpoint *Endpoint
 
 	m.lock.Lock()
@@ -104,14 +121,22 @@ func (m *Mux) dispatch(buf []byte) {
 		return
 	}
 
-	select {
-	case readBuf, ok := <-endpoint.readCh:
-		if !ok {
+	for {
+		select {
+		case readBuf, ok := <-endpoint.readCh:
+			if !ok {
+				return
+			}
+			// A buffer too small is handed the size of the packet, which
+			// waits for the next Read
+			if len(readBuf) < len(buf) {
+				endpoint.wroteCh <- wrote{n: len(buf)}
+				continue
+			}
+			endpoint.wroteCh <- wrote{n: copy(readBuf, buf), timestamp: timestamp}
+			return
+		case <-endpoint.doneCh:
 			return
 		}
-		n := copy(readBuf, buf)
-		endpoint.wroteCh <- n
-	case <-endpoint.doneCh:
-		return
 	}
 }
@@ -1,6 +1,8 @@
 package mux
 
 import (
+	"bytes"
+	"io"
 	"net"
 	"testing"
 	"time"
@@ -74,7 +76,7 @@ func TestNoEndpoints(t *testing.T) {
 		panic("Failed to close network pipe")
 	}
 	m := NewMux(ca, 8192)
-	m.dispatch(make([]byte, 1))
+	m.dispatch(make([]byte, 1), time.Now())
 	err = m.Close()
 	if err != nil {
 		t.Fatalf("Failed to close empty mux")
@@ -85,3 +87,75 @@ func TestNoEndpoints(t *testing.T) {
 	}
 
 }
+
+func TestEndpoint_ShortBuffer(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	e, cb, stop := pipeMemory()
+	defer stop(t)
+
+	packet := bytes.Repeat([]byte{0x01}, 100)
+	go func() {
+		if _, err := cb.Write(packet); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// The packet isn't truncated, it is kept for a large enough buffer
+	if _, err := e.Read(make([]byte, 10)); err != io.ErrShortBuffer {
+		t.Fatalf("Read with a short buffer should fail with io.ErrShortBuffer, got %v", err)
+	}
+	buf := make([]byte, 200)
+	n, err := e.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packet, buf[:n]) {
+		t.Fatalf("The packet read after a short buffer should be whole, got %d bytes", n)
+	}
+}
+
+// timestampConn reads the packets of a conn with a fixed receive time
+type timestampConn struct {
+	net.Conn
+	timestamp time.Time
+}
+
+func (c *timestampConn) ReadWithTimestamp(p []byte) (int, time.Time, error) {
+	n, err := c.Conn.Read(p)
+	return n, c.timestamp, err
+}
+
+func TestEndpoint_ReadWithTimestamp(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	ca, cb := net.Pipe()
+	timestamp := time.Unix(1234, 5678)
+	m := NewMux(&timestampConn{Conn: ca, timestamp: timestamp}, 8192)
+	e := m.NewEndpoint(func([]byte) bool { return true })
+	defer func() {
+		if err := cb.Close(); err != nil {
+			t.Error(err)
+		}
+		if err := m.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	go func() {
+		if _, err := cb.Write([]byte{0x01}); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// The receive time of the conn is passed through
+	n, readTimestamp, err := e.ReadWithTimestamp(make([]byte, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || !readTimestamp.Equal(timestamp) {
+		t.Fatalf("read %d bytes received at %v instead of 1 byte at %v", n, readTimestamp, timestamp)
+	}
+}
@@ -1,17 +1,42 @@
 package util
 
 import (
+	"io"
 	"math/rand"
 	"time"
 )
 
+const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
 // RandSeq generates a random alpha numeric sequence of the requested length
 func RandSeq(n int) string {
 	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	letters := []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
-	b := make([]rune, n)
+	b := make([]byte, n)
 	for i := range b {
 		b[i] = letters[r.Intn(len(letters))]
 	}
 	return string(b)
 }
+
+// RandSeqFrom generates a random alpha numeric sequence of the requested
+// length, reading its randomness from the provided reader
+func RandSeqFrom(r io.Reader, n int) (string, error) {
+	// Bytes past the largest multiple of len(letters) are rejected so every
+	// letter is equally likely
+	maxByte := byte(256 / len(letters) * len(letters))
+
+	b := make([]byte, 0, n)
+	buf := make([]byte, n)
+	for len(b) < n {
+		chunk := buf[:n-len(b)]
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return "", err
+		}
+		for _, c := range chunk {
+			if c < maxByte {
+				b = append(b, letters[int(c)%len(letters)])
+			}
+		}
+	}
+	return string(b), nil
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"bytes"
 	"regexp"
 	"testing"
 )
@@ -15,3 +16,17 @@ func TestRandSeq(t *testing.T) {
 		t.Errorf("RandSeq should be AlphaNumeric only")
 	}
 }
+
+func TestRandSeqFrom(t *testing.T) {
+	seq, err := RandSeqFrom(bytes.NewReader([]byte{0, 1, 26, 51, 255, 52}), 5)
+	if err != nil {
+		t.Fatalf("RandSeqFrom failed: %v", err)
+	}
+	if seq != "abAZa" {
+		t.Errorf("RandSeqFrom returned %q, expected %q", seq, "abAZa")
+	}
+
+	if _, err := RandSeqFrom(bytes.NewReader([]byte{0, 1}), 5); err == nil {
+		t.Errorf("RandSeqFrom should fail when the reader runs dry")
+	}
+}
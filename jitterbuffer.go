@@ -0,0 +1,162 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pions/rtp"
+)
+
+const (
+	// jitterBufferMaxLatency is the longest latency of a jitter buffer, any
+	// longer and the stream is no longer live
+	jitterBufferMaxLatency = 2 * time.Second
+
+	// jitterBufferMaxPackets is the most packets a jitter buffer holds, the
+	// gap before them is skipped past it. A packet further behind than it
+	// is not late, the sequence numbers of the stream reset or jumped.
+	jitterBufferMaxPackets = 1024
+)
+
+// jitterBuffer puts the packets of a stream back in sequence number order.
+// The packets following a gap are held until the missing ones arrive, for
+// up to latency; the gap is then skipped. The packets arriving once their
+// turn passed are dropped, unless far enough behind that the stream
+// restarted at them.
+type jitterBuffer struct {
+	lock    sync.Mutex
+	latency time.Duration
+	out     func(*rtp.Packet)
+
+	// next is the sequence number of the packet put out next
+	started bool
+	next    uint16
+	held    map[uint16]heldPacket
+
+	// timer releases the held packets once they waited for latency
+	timer  *time.Timer
+	closed bool
+}
+
+type heldPacket struct {
+	packet  *rtp.Packet
+	arrival time.Time
+}
+
+func newJitterBuffer(latency time.Duration, out func(*rtp.Packet)) *jitterBuffer {
+	return &jitterBuffer{
+		latency: latency,
+		out:     out,
+		held:    map[uint16]heldPacket{},
+	}
+}
+
+// push adds a packet arriving at now, false if it arrived too late and is
+// dropped. The packets that became contiguous are put out.
+func (j *jitterBuffer) push(packet *rtp.Packet, now time.Time) bool {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if j.closed {
+		j.out(packet)
+		return true
+	}
+
+	if !j.started {
+		j.started = true
+		j.next = packet.SequenceNumber
+	}
+	if int16(packet.SequenceNumber-j.next) < 0 {
+		if j.next-packet.SequenceNumber <= jitterBufferMaxPackets {
+			return false
+		}
+		// The sequence numbers reset or jumped, the held packets are put out
+		// and the stream restarts at the packet
+		j.flush()
+		j.next = packet.SequenceNumber
+	}
+	// A duplicate is dropped too, the first copy is in its place
+	if _, ok := j.held[packet.SequenceNumber]; ok {
+		return false
+	}
+
+	j.held[packet.SequenceNumber] = heldPacket{packet: packet, arrival: now}
+	j.release(now)
+	return true
+}
+
+// release puts out the contiguous packets, skipping the gap before the
+// held ones that waited for latency
+func (j *jitterBuffer) release(now time.Time) {
+	for len(j.held) != 0 {
+		if held, ok := j.held[j.next]; ok {
+			delete(j.held, j.next)
+			j.next++
+			j.out(held.packet)
+			continue
+		}
+
+		first := j.first()
+		wait := j.held[first].arrival.Add(j.latency).Sub(now)
+		if wait > 0 && len(j.held) < jitterBufferMaxPackets {
+			j.schedule(wait)
+			return
+		}
+		j.next = first
+	}
+	j.schedule(0)
+}
+
+// first returns the sequence number of the earliest packet held
+func (j *jitterBuffer) first() uint16 {
+	var first uint16
+	distance := -1
+	for sequenceNumber := range j.held {
+		if d := int(sequenceNumber - j.next); distance < 0 || d < distance {
+			first, distance = sequenceNumber, d
+		}
+	}
+	return first
+}
+
+// schedule arms the timer to release the held packets after wait, or
+// stops it
+func (j *jitterBuffer) schedule(wait time.Duration) {
+	if j.timer != nil {
+		j.timer.Stop()
+		j.timer = nil
+	}
+	if wait <= 0 {
+		return
+	}
+
+	j.timer = time.AfterFunc(wait, func() {
+		j.lock.Lock()
+		defer j.lock.Unlock()
+		if !j.closed {
+			j.release(time.Now())
+		}
+	})
+}
+
+// close puts out the packets held in order, the ones pushed later are put
+// out right away
+func (j *jitterBuffer) close() {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if j.closed {
+		return
+	}
+	j.closed = true
+	j.flush()
+}
+
+// flush puts out the packets held in order
+func (j *jitterBuffer) flush() {
+	for len(j.held) != 0 {
+		first := j.first()
+		j.out(j.held[first].packet)
+		delete(j.held, first)
+		j.next = first + 1
+	}
+	j.schedule(0)
+}
@@ -0,0 +1,133 @@
+package webrtc
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pions/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+type jitterBufferOutput struct {
+	lock            sync.Mutex
+	sequenceNumbers []uint16
+}
+
+func (o *jitterBufferOutput) put(packet *rtp.Packet) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.sequenceNumbers = append(o.sequenceNumbers, packet.SequenceNumber)
+}
+
+func (o *jitterBufferOutput) take() []uint16 {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	sequenceNumbers := o.sequenceNumbers
+	o.sequenceNumbers = nil
+	return sequenceNumbers
+}
+
+func jitterBufferPacket(sequenceNumber uint16) *rtp.Packet {
+	return &rtp.Packet{Header: rtp.Header{SequenceNumber: sequenceNumber}}
+}
+
+func TestJitterBuffer(t *testing.T) {
+	start := time.Now()
+	o := &jitterBufferOutput{}
+	j := newJitterBuffer(time.Hour, o.put)
+	defer j.close()
+
+	// The contiguous packets are put out right away, across the wrap
+	for _, sequenceNumber := range []uint16{65534, 65535, 0} {
+		assert.True(t, j.push(jitterBufferPacket(sequenceNumber), start))
+	}
+	assert.Equal(t, []uint16{65534, 65535, 0}, o.take())
+
+	// The packets following a gap wait for the missing one
+	assert.True(t, j.push(jitterBufferPacket(3), start))
+	assert.True(t, j.push(jitterBufferPacket(2), start))
+	assert.Empty(t, o.take())
+	assert.True(t, j.push(jitterBufferPacket(1), start))
+	assert.Equal(t, []uint16{1, 2, 3}, o.take())
+
+	// The late and duplicated packets are dropped
+	assert.False(t, j.push(jitterBufferPacket(2), start))
+	assert.True(t, j.push(jitterBufferPacket(6), start))
+	assert.False(t, j.push(jitterBufferPacket(6), start))
+	assert.Empty(t, o.take())
+
+	// The gap is skipped once the packet after it waited for the latency
+	assert.True(t, j.push(jitterBufferPacket(8), start.Add(time.Minute)))
+	assert.True(t, j.push(jitterBufferPacket(7), start.Add(time.Hour)))
+	assert.Equal(t, []uint16{6, 7, 8}, o.take())
+	assert.False(t, j.push(jitterBufferPacket(4), start.Add(time.Hour)))
+
+	// Closing puts out the packets held
+	assert.True(t, j.push(jitterBufferPacket(12), start.Add(time.Hour)))
+	assert.True(t, j.push(jitterBufferPacket(10), start.Add(time.Hour)))
+	j.close()
+	assert.Equal(t, []uint16{10, 12}, o.take())
+	assert.True(t, j.push(jitterBufferPacket(11), start.Add(time.Hour)))
+	assert.Equal(t, []uint16{11}, o.take())
+}
+
+func TestJitterBuffer_Timer(t *testing.T) {
+	o := &jitterBufferOutput{}
+	j := newJitterBuffer(20*time.Millisecond, o.put)
+	defer j.close()
+
+	assert.True(t, j.push(jitterBufferPacket(1), time.Now()))
+	assert.True(t, j.push(jitterBufferPacket(3), time.Now()))
+	assert.True(t, j.push(jitterBufferPacket(4), time.Now()))
+	assert.Equal(t, []uint16{1}, o.take())
+
+	// The held packets are released without another packet arriving
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, []uint16{3, 4}, o.take())
+}
+
+func TestJitterBuffer_MaxPackets(t *testing.T) {
+	start := time.Now()
+	o := &jitterBufferOutput{}
+	j := newJitterBuffer(time.Hour, o.put)
+	defer j.close()
+
+	assert.True(t, j.push(jitterBufferPacket(0), start))
+	assert.Equal(t, []uint16{0}, o.take())
+
+	// Past the most packets held the gap is skipped
+	for i := 0; i < jitterBufferMaxPackets; i++ {
+		assert.True(t, j.push(jitterBufferPacket(uint16(2+i)), start))
+	}
+	assert.Len(t, o.take(), jitterBufferMaxPackets)
+}
+
+func TestJitterBuffer_Resync(t *testing.T) {
+	start := time.Now()
+	o := &jitterBufferOutput{}
+	j := newJitterBuffer(time.Hour, o.put)
+	defer j.close()
+
+	assert.True(t, j.push(jitterBufferPacket(5000), start))
+	assert.True(t, j.push(jitterBufferPacket(5002), start))
+	assert.Equal(t, []uint16{5000}, o.take())
+
+	// A packet up to the most packets held behind is late
+	assert.False(t, j.push(jitterBufferPacket(5001-jitterBufferMaxPackets), start))
+	assert.Empty(t, o.take())
+
+	// Further behind the sequence numbers reset, the held packets are put
+	// out and the stream restarts
+	assert.True(t, j.push(jitterBufferPacket(10), start))
+	assert.Equal(t, []uint16{5002, 10}, o.take())
+	assert.True(t, j.push(jitterBufferPacket(12), start))
+	assert.True(t, j.push(jitterBufferPacket(11), start))
+	assert.Equal(t, []uint16{11, 12}, o.take())
+
+	// So does a jump across the half of the sequence number space
+	assert.True(t, j.push(jitterBufferPacket(40000), start))
+	assert.Equal(t, []uint16{40000}, o.take())
+	assert.True(t, j.push(jitterBufferPacket(40001), start))
+	assert.Equal(t, []uint16{40001}, o.take())
+}
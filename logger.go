@@ -0,0 +1,66 @@
+package webrtc
+
+import (
+	"encoding/hex"
+	"io"
+
+	"github.com/pions/webrtc/pkg/logging"
+)
+
+// logTagSize is the number of random bytes of the log tag of a
+// PeerConnection, printed in hexadecimal
+const logTagSize = 4
+
+// taggedLogger prefixes the messages of a logger with the tag of the
+// PeerConnection they are about
+type taggedLogger struct {
+	logging.LeveledLogger
+	prefix string
+}
+
+func newTaggedLogger(logger logging.LeveledLogger, tag string) *taggedLogger {
+	return &taggedLogger{LeveledLogger: logger, prefix: "[" + tag + "] "}
+}
+
+func (l *taggedLogger) Trace(msg string) { l.LeveledLogger.Trace(l.prefix + msg) }
+func (l *taggedLogger) Debug(msg string) { l.LeveledLogger.Debug(l.prefix + msg) }
+func (l *taggedLogger) Info(msg string)  { l.LeveledLogger.Info(l.prefix + msg) }
+func (l *taggedLogger) Warn(msg string)  { l.LeveledLogger.Warn(l.prefix + msg) }
+func (l *taggedLogger) Error(msg string) { l.LeveledLogger.Error(l.prefix + msg) }
+
+func (l *taggedLogger) Tracef(format string, args ...interface{}) {
+	l.LeveledLogger.Tracef(l.prefix+format, args...)
+}
+
+func (l *taggedLogger) Debugf(format string, args ...interface{}) {
+	l.LeveledLogger.Debugf(l.prefix+format, args...)
+}
+
+func (l *taggedLogger) Infof(format string, args ...interface{}) {
+	l.LeveledLogger.Infof(l.prefix+format, args...)
+}
+
+func (l *taggedLogger) Warnf(format string, args ...interface{}) {
+	l.LeveledLogger.Warnf(l.prefix+format, args...)
+}
+
+func (l *taggedLogger) Errorf(format string, args ...interface{}) {
+	l.LeveledLogger.Errorf(l.prefix+format, args...)
+}
+
+// newLogTag returns a random tag telling apart the logs of a PeerConnection
+func newLogTag(random io.Reader) (string, error) {
+	buf := make([]byte, logTagSize)
+	if _, err := io.ReadFull(random, buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// withLogTag returns a copy of the API whose objects log with the tag
+func (api *API) withLogTag(tag string) *API {
+	tagged := *api
+	tagged.log = newTaggedLogger(api.log, tag)
+	tagged.iceLog = newTaggedLogger(api.iceLog, tag)
+	return &tagged
+}
@@ -0,0 +1,73 @@
+package webrtc
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pions/webrtc/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingLoggerFactory records the Info messages of its loggers, by scope
+type recordingLoggerFactory struct {
+	mu       sync.Mutex
+	messages map[string][]string
+}
+
+type recordingLogger struct {
+	logging.NopLogger
+	scope   string
+	factory *recordingLoggerFactory
+}
+
+func (f *recordingLoggerFactory) NewLogger(scope string) logging.LeveledLogger {
+	return &recordingLogger{scope: scope, factory: f}
+}
+
+func (l *recordingLogger) Infof(format string, args ...interface{}) {
+	l.factory.mu.Lock()
+	defer l.factory.mu.Unlock()
+	l.factory.messages[l.scope] = append(l.factory.messages[l.scope], fmt.Sprintf(format, args...))
+}
+
+func TestSettingEngine_SetLoggerFactory(t *testing.T) {
+	factory := &recordingLoggerFactory{messages: map[string][]string{}}
+	s := SettingEngine{}
+	s.SetLoggerFactory(factory)
+	api := NewAPI(WithSettingEngine(s))
+
+	pcA, err := api.NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pcB, err := api.NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, pcA.LogTag(), 2*logTagSize)
+	assert.NotEqual(t, pcA.LogTag(), pcB.LogTag())
+
+	offer, err := pcA.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pcA.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+
+	// The messages of the PeerConnection carry its tag
+	factory.mu.Lock()
+	messages := factory.messages["pc"]
+	factory.mu.Unlock()
+	found := false
+	for _, m := range messages {
+		assert.True(t, strings.HasPrefix(m, "["+pcA.LogTag()+"] "), m)
+		found = found || strings.Contains(m, "signaling state changed")
+	}
+	assert.True(t, found, "the signaling state change should be logged")
+
+	assert.NoError(t, pcA.Close())
+	assert.NoError(t, pcB.Close())
+}
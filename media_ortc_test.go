@@ -0,0 +1,89 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pions/rtp"
+	"github.com/pions/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMedia_ORTCE2E(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	stackA, stackB, err := newORTCPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = signalORTCPair(stackA, stackB); err != nil {
+		t.Fatal(err)
+	}
+
+	// The parameters a PeerConnection negotiates are given directly
+	track, err := NewRawRTPTrack(DefaultPayloadTypeVP8, 1234, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := stackA.api.NewRTPSender(track, stackA.dtls)
+	pli := make(chan struct{})
+	sender.OnPictureLossIndication(func() {
+		select {
+		case <-pli:
+		default:
+			close(pli)
+		}
+	})
+	sender.Send(RTPSendParameters{
+		Encodings:    RTPEncodingParameters{RTPCodingParameters{SSRC: track.SSRC, PayloadType: track.PayloadType}},
+		RTCPFeedback: []RTCPFeedback{{Type: TypeRTCPFBNACK, Parameter: RTCPFBParameterPLI}},
+	})
+
+	receiver := stackB.api.NewRTPReceiver(RTPCodecTypeVideo, stackB.dtls)
+	hasRecv, err := receiver.Receive(RTPReceiveParameters{
+		Encodings:    RTPDecodingParameters{RTPCodingParameters{SSRC: track.SSRC}},
+		RTCPFeedback: []RTCPFeedback{{Type: TypeRTCPFBNACK, Parameter: RTCPFBParameterPLI}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for sequenceNumber := uint16(0); ; sequenceNumber++ {
+			select {
+			case <-hasRecv:
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+			packet := &rtp.Packet{Header: rtp.Header{Version: 2, SSRC: track.SSRC, PayloadType: track.PayloadType, SequenceNumber: sequenceNumber}, Payload: []byte{0x00}}
+			if err := track.WriteRTP(packet, nil); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	<-hasRecv
+
+	packet, err := receiver.ReadRTP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, track.SSRC, packet.SSRC)
+	assert.Equal(t, track.SSRC, receiver.Track.SSRC)
+
+	// The RTCP of the receiver reaches the sender
+	assert.NoError(t, receiver.RequestKeyFramePLI())
+	<-pli
+
+	sender.Stop()
+	assert.NoError(t, stackB.dtls.Stop())
+	assert.NoError(t, receiver.Stop())
+	assert.NoError(t, stackA.dtls.Stop())
+	assert.NoError(t, stackA.close())
+	assert.NoError(t, stackB.close())
+}
@@ -0,0 +1,111 @@
+package webrtc
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pions/sdp/v2"
+)
+
+// MediaDescription is a read-only view of a media section (m-line) of a
+// SessionDescription
+type MediaDescription struct {
+	// Media is the media of the section: audio, video or application for
+	// the data channels
+	Media string
+
+	// Kind is the kind of the RTP media of the section, zero for the
+	// application section
+	Kind RTPCodecType
+
+	Mid string
+
+	// Direction is the direction of the section as signaled by the
+	// description, sendrecv when it signals none
+	Direction RTPTransceiverDirection
+
+	// Rejected is set when the port of the section is zero
+	Rejected bool
+
+	// Codecs are the codecs of the section in order of preference
+	Codecs []MediaDescriptionCodec
+
+	// SSRCs are the SSRCs of the a=ssrc lines, in order of appearance
+	SSRCs []uint32
+}
+
+// MediaDescriptionCodec is a codec of a MediaDescription, with the payload
+// type the description maps it to
+type MediaDescriptionCodec struct {
+	PayloadType uint8
+	Name        string
+	ClockRate   uint32
+	Channels    uint16
+	SDPFmtpLine string
+}
+
+// newMediaDescription returns the view of a parsed media section
+func newMediaDescription(media *sdp.MediaDescription) MediaDescription {
+	m := MediaDescription{
+		Media:     media.MediaName.Media,
+		Direction: RTPTransceiverDirectionSendrecv,
+		Rejected:  media.MediaName.Port.Value == 0,
+	}
+	switch m.Media {
+	case RTPCodecTypeAudio.String():
+		m.Kind = RTPCodecTypeAudio
+	case RTPCodecTypeVideo.String():
+		m.Kind = RTPCodecTypeVideo
+	}
+	m.Mid, _ = media.Attribute(sdp.AttrKeyMID)
+
+	seen := map[uint32]bool{}
+	for _, a := range media.Attributes {
+		if direction := NewRTPTransceiverDirection(a.Key); direction != RTPTransceiverDirection(Unknown) {
+			m.Direction = direction
+			continue
+		}
+		if a.Key != sdp.AttrKeySSRC {
+			continue
+		}
+		ssrc, err := strconv.ParseUint(strings.Split(a.Value, " ")[0], 10, 32)
+		if err != nil || seen[uint32(ssrc)] {
+			continue
+		}
+		seen[uint32(ssrc)] = true
+		m.SSRCs = append(m.SSRCs, uint32(ssrc))
+	}
+
+	if m.Kind == 0 {
+		return m
+	}
+	for _, codec := range codecsFromMedia(media) {
+		c := MediaDescriptionCodec{
+			PayloadType: codec.PayloadType,
+			Name:        codec.Name,
+			ClockRate:   codec.ClockRate,
+			SDPFmtpLine: codec.Fmtp,
+		}
+		if channels, err := strconv.ParseUint(codec.EncodingParameters, 10, 16); err == nil {
+			c.Channels = uint16(channels)
+		}
+		m.Codecs = append(m.Codecs, c)
+	}
+	return m
+}
+
+// RemoteMediaDescriptions returns the media sections of the remote
+// description, in the order of its m-lines. It reflects the description
+// applied last, pending or current, and is nil before SetRemoteDescription.
+func (pc *PeerConnection) RemoteMediaDescriptions() []MediaDescription {
+	desc := pc.RemoteDescription()
+	if desc == nil || desc.parsed == nil {
+		return nil
+	}
+
+	medias := make([]MediaDescription, 0, len(desc.parsed.MediaDescriptions))
+	for _, media := range desc.parsed.MediaDescriptions {
+		medias = append(medias, newMediaDescription(media))
+	}
+	return medias
+}
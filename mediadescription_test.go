@@ -0,0 +1,96 @@
+package webrtc
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pions/transport/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeerConnection_RemoteMediaDescriptions(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterCodec(NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000, 2))
+	api.mediaEngine.RegisterCodec(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Nil(t, pcAnswer.RemoteMediaDescriptions())
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(track); err != nil {
+		t.Fatal(err)
+	}
+
+	// The connections are closed once established, not while connecting
+	var connected sync.WaitGroup
+	connected.Add(2)
+	for _, pc := range []*PeerConnection{pcOffer, pcAnswer} {
+		pc.OnConnectionStateChange(func(state PeerConnectionState) {
+			if state == PeerConnectionStateConnected {
+				connected.Done()
+			}
+		})
+	}
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	medias := pcAnswer.RemoteMediaDescriptions()
+	if len(medias) != 3 {
+		t.Fatalf("expected 3 media descriptions, got %d", len(medias))
+	}
+
+	audio := medias[0]
+	assert.Equal(t, "audio", audio.Media)
+	assert.Equal(t, RTPCodecTypeAudio, audio.Kind)
+	assert.Equal(t, "audio", audio.Mid)
+	// Nothing is sent on the audio section
+	assert.Equal(t, RTPTransceiverDirectionRecvonly, audio.Direction)
+	assert.False(t, audio.Rejected)
+	assert.Equal(t, []MediaDescriptionCodec{{
+		PayloadType: DefaultPayloadTypeOpus,
+		Name:        Opus,
+		ClockRate:   48000,
+		Channels:    2,
+		SDPFmtpLine: "minptime=10;useinbandfec=1",
+	}}, audio.Codecs)
+	assert.Empty(t, audio.SSRCs)
+
+	video := medias[1]
+	assert.Equal(t, RTPCodecTypeVideo, video.Kind)
+	assert.Equal(t, "video", video.Mid)
+	assert.Equal(t, RTPTransceiverDirectionSendrecv, video.Direction)
+	assert.Equal(t, []uint32{track.SSRC}, video.SSRCs)
+	if assert.Len(t, video.Codecs, 1) {
+		assert.Equal(t, VP8, video.Codecs[0].Name)
+	}
+
+	data := medias[2]
+	assert.Equal(t, "application", data.Media)
+	assert.Equal(t, RTPCodecType(0), data.Kind)
+	assert.Equal(t, "data", data.Mid)
+	assert.Empty(t, data.Codecs)
+
+	// The remote of the offerer is the answer
+	medias = pcOffer.RemoteMediaDescriptions()
+	if assert.Len(t, medias, 3) {
+		assert.Equal(t, RTPTransceiverDirectionRecvonly, medias[1].Direction)
+		assert.Empty(t, medias[1].SSRCs)
+	}
+
+	connected.Wait()
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
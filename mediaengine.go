@@ -2,6 +2,7 @@ package webrtc
 
 import (
 	"strconv"
+	"strings"
 
 	"github.com/pions/rtp"
 	"github.com/pions/rtp/codecs"
@@ -19,7 +20,14 @@ const (
 
 // MediaEngine defines the codecs supported by a PeerConnection
 type MediaEngine struct {
-	codecs []*RTPCodec
+	codecs           []*RTPCodec
+	headerExtensions []*mediaEngineHeaderExtension
+}
+
+type mediaEngineHeaderExtension struct {
+	id   uint8
+	uri  string
+	kind RTPCodecType
 }
 
 // RegisterCodec registers a codec to a media engine
@@ -38,6 +46,50 @@ func (m *MediaEngine) RegisterDefaultCodecs() {
 	m.RegisterCodec(NewRTPVP9Codec(DefaultPayloadTypeVP9, 90000))
 }
 
+// RegisterHeaderExtension registers a RFC5285 RTP header extension for the
+// given kind of media and returns the ID it is offered with. An extension
+// registered for both audio and video shares the same ID.
+func (m *MediaEngine) RegisterHeaderExtension(extension RTPHeaderExtensionCapability, kind RTPCodecType) (uint8, error) {
+	var id uint8
+	used := map[uint8]bool{}
+	for _, e := range m.headerExtensions {
+		used[e.id] = true
+		if e.uri == extension.URI {
+			if e.kind == kind {
+				return e.id, nil
+			}
+			id = e.id
+		}
+	}
+
+	// Only the IDs usable with the one-byte header are handed out
+	for i := uint8(1); id == 0 && i <= headerExtensionOneByteMaxID; i++ {
+		if !used[i] {
+			id = i
+		}
+	}
+	if id == 0 {
+		return 0, ErrHeaderExtensionIDExhausted
+	}
+
+	m.headerExtensions = append(m.headerExtensions, &mediaEngineHeaderExtension{
+		id:   id,
+		uri:  extension.URI,
+		kind: kind,
+	})
+	return id, nil
+}
+
+func (m *MediaEngine) getHeaderExtensionsByKind(kind RTPCodecType) []*mediaEngineHeaderExtension {
+	var extensions []*mediaEngineHeaderExtension
+	for _, e := range m.headerExtensions {
+		if e.kind == kind {
+			extensions = append(extensions, e)
+		}
+	}
+	return extensions
+}
+
 func (m *MediaEngine) getCodec(payloadType uint8) (*RTPCodec, error) {
 	for _, codec := range m.codecs {
 		if codec.PayloadType == payloadType {
@@ -47,17 +99,29 @@ func (m *MediaEngine) getCodec(payloadType uint8) (*RTPCodec, error) {
 	return nil, ErrCodecNotFound
 }
 
+// getCodecSDP returns the registered codec of a codec of a description,
+// the one with the same format parameters or else the first one with
+// compatible ones
 func (m *MediaEngine) getCodecSDP(sdpCodec sdp.Codec) (*RTPCodec, error) {
+	var match *RTPCodec
 	for _, codec := range m.codecs {
 		if codec.Name == sdpCodec.Name &&
 			codec.ClockRate == sdpCodec.ClockRate &&
 			(sdpCodec.EncodingParameters == "" ||
 				strconv.Itoa(int(codec.Channels)) == sdpCodec.EncodingParameters) &&
-			codec.SDPFmtpLine == sdpCodec.Fmtp { // TODO: Protocol specific matching?
-			return codec, nil
+			fmtpMatches(codec.Name, codec.SDPFmtpLine, sdpCodec.Fmtp) {
+			if codec.SDPFmtpLine == sdpCodec.Fmtp {
+				return codec, nil
+			}
+			if match == nil {
+				match = codec
+			}
 		}
 	}
-	return nil, ErrCodecNotFound
+	if match == nil {
+		return nil, ErrCodecNotFound
+	}
+	return match, nil
 }
 
 func (m *MediaEngine) getCodecsByKind(kind RTPCodecType) []*RTPCodec {
@@ -70,6 +134,131 @@ func (m *MediaEngine) getCodecsByKind(kind RTPCodecType) []*RTPCodec {
 	return codecs
 }
 
+// filterCodecsByName keeps the codecs with one of the given names, all of
+// them when no name is given
+func filterCodecsByName(codecs []*RTPCodec, names []string) []*RTPCodec {
+	if len(names) == 0 {
+		return codecs
+	}
+
+	var filtered []*RTPCodec
+	for _, codec := range codecs {
+		for _, name := range names {
+			if strings.EqualFold(codec.Name, name) {
+				filtered = append(filtered, codec)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// codecsFromMedia returns the codecs of the payload types of a media section,
+// in the order they are listed in the m-line. The static payload types the
+// media section has no a=rtpmap for only carry their payload type.
+func codecsFromMedia(media *sdp.MediaDescription) []sdp.Codec {
+	rtpmaps := map[uint8]sdp.Codec{}
+	for _, a := range media.Attributes {
+		if a.Key != "rtpmap" && a.Key != "fmtp" {
+			continue
+		}
+
+		// a=rtpmap:<payload type> <encoding name>/<clock rate>[/<encoding parameters>]
+		// a=fmtp:<payload type> <format specific parameters>
+		fields := strings.SplitN(a.Value, " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		pt, err := strconv.ParseUint(fields[0], 10, 8)
+		if err != nil {
+			continue
+		}
+
+		codec := rtpmaps[uint8(pt)]
+		codec.PayloadType = uint8(pt)
+		if a.Key == "fmtp" {
+			codec.Fmtp = fields[1]
+			rtpmaps[uint8(pt)] = codec
+			continue
+		}
+
+		encoding := strings.Split(fields[1], "/")
+		codec.Name = encoding[0]
+		if len(encoding) > 1 {
+			rate, err := strconv.ParseUint(encoding[1], 10, 32)
+			if err != nil {
+				continue
+			}
+			codec.ClockRate = uint32(rate)
+		}
+		if len(encoding) > 2 {
+			codec.EncodingParameters = encoding[2]
+		}
+		rtpmaps[uint8(pt)] = codec
+	}
+
+	var codecs []sdp.Codec
+	for _, format := range media.MediaName.Formats {
+		pt, err := strconv.ParseUint(format, 10, 8)
+		if err != nil {
+			continue
+		}
+		codec, ok := rtpmaps[uint8(pt)]
+		if !ok {
+			codec = sdp.Codec{PayloadType: uint8(pt)}
+		}
+		codecs = append(codecs, codec)
+	}
+	return codecs
+}
+
+// matchRemoteCodecs returns the codecs that are also in the remote codecs, in
+// the order of the local codecs, with the payload types of the remote ones.
+//
+// The local order is the preference, the codecs registered first in the
+// MediaEngine are preferred whatever the order of the remote. A remote codec
+// with incompatible format parameters, such as another H264 packetization
+// mode or profile, isn't matched, see fmtpMatches. One with the same format
+// parameters is matched over the other ones of the same name, and a static
+// payload type the remote has no a=rtpmap for matches the local codec with
+// that payload type.
+func matchRemoteCodecs(codecs []*RTPCodec, remoteCodecs []sdp.Codec) []*RTPCodec {
+	var matched []*RTPCodec
+	for _, codec := range codecs {
+		var match *sdp.Codec
+		for i := range remoteCodecs {
+			remote := &remoteCodecs[i]
+			switch {
+			case remote.Name == "":
+				if remote.PayloadType == codec.PayloadType && remote.PayloadType < dynamicPayloadTypeMin {
+					match = remote
+				}
+			case !strings.EqualFold(remote.Name, codec.Name) ||
+				remote.ClockRate != codec.ClockRate ||
+				(remote.EncodingParameters != "" && remote.EncodingParameters != strconv.Itoa(int(codec.Channels))) ||
+				!fmtpMatches(codec.Name, codec.SDPFmtpLine, remote.Fmtp):
+			case match == nil || remote.Fmtp == codec.SDPFmtpLine:
+				match = remote
+			}
+			if match != nil && match.Fmtp == codec.SDPFmtpLine {
+				break
+			}
+		}
+		if match == nil {
+			continue
+		}
+
+		c := *codec
+		c.PayloadType = match.PayloadType
+		// RED refers to the primary encoding with the payload type of the remote
+		if strings.EqualFold(codec.Name, RED) {
+			c.SDPFmtpLine = match.Fmtp
+		}
+		matched = append(matched, &c)
+	}
+	return matched
+}
+
 // Names for the default codecs supported by pions-webrtc
 const (
 	G722 = "G722"
@@ -77,6 +266,7 @@ const (
 	VP8  = "VP8"
 	VP9  = "VP9"
 	H264 = "H264"
+	RED  = "red"
 )
 
 // NewRTPG722Codec is a helper to create a G722 codec
@@ -103,6 +293,27 @@ func NewRTPOpusCodec(payloadType uint8, clockrate uint32, channels uint16) *RTPC
 	return c
 }
 
+// NewRTPRedCodec is a helper to create a RED codec as defined in RFC 2198,
+// carrying redundant encodings of the audio codec with the given payload
+// type. It is registered along that codec, typically Opus:
+//
+//	m.RegisterCodec(NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000, 2))
+//	m.RegisterCodec(NewRTPRedCodec(63, 48000, 2, DefaultPayloadTypeOpus))
+//
+// The RTPReceivers recover the packets lost on the way from the redundant
+// encodings once RED is negotiated, the RTPSenders only send redundancy
+// when enabled with SettingEngine.SetAudioRedundancy.
+func NewRTPRedCodec(payloadType uint8, clockrate uint32, channels uint16, primaryPayloadType uint8) *RTPCodec {
+	c := NewRTPCodec(RTPCodecTypeAudio,
+		RED,
+		clockrate,
+		channels,
+		redFmtp(primaryPayloadType),
+		payloadType,
+		nil)
+	return c
+}
+
 // NewRTPVP8Codec is a helper to create an VP8 codec
 func NewRTPVP8Codec(payloadType uint8, clockrate uint32) *RTPCodec {
 	c := NewRTPCodec(RTPCodecTypeVideo,
@@ -112,6 +323,7 @@ func NewRTPVP8Codec(payloadType uint8, clockrate uint32) *RTPCodec {
 		"",
 		payloadType,
 		&codecs.VP8Payloader{})
+	c.RTCPFeedback = defaultVideoRTCPFeedback()
 	return c
 }
 
@@ -124,6 +336,7 @@ func NewRTPVP9Codec(payloadType uint8, clockrate uint32) *RTPCodec {
 		"",
 		payloadType,
 		nil) // TODO
+	c.RTCPFeedback = defaultVideoRTCPFeedback()
 	return c
 }
 
@@ -136,6 +349,7 @@ func NewRTPH264Codec(payloadType uint8, clockrate uint32) *RTPCodec {
 		"level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42001f",
 		payloadType,
 		&codecs.H264Payloader{})
+	c.RTCPFeedback = defaultVideoRTCPFeedback()
 	return c
 }
 
@@ -201,6 +415,9 @@ type RTPCodecCapability struct {
 	ClockRate   uint32
 	Channels    uint16
 	SDPFmtpLine string
+
+	// RTCPFeedback is offered in a=rtcp-fb attributes for the codec
+	RTCPFeedback []RTCPFeedback
 }
 
 // RTPHeaderExtensionCapability is used to define a RFC5285 RTP header extension supported by the codec.
@@ -1,6 +1,7 @@
 package webrtc
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/pions/sdp/v2"
@@ -33,3 +34,112 @@ func TestCodecRegistration(t *testing.T) {
 	_, err := api.mediaEngine.getCodecSDP(sdp.Codec{PayloadType: invalidPT})
 	assert.Equal(t, err, ErrCodecNotFound)
 }
+
+func TestHeaderExtensionRegistration(t *testing.T) {
+	m := MediaEngine{}
+
+	videoID, err := m.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: VideoOrientationURI}, RTPCodecTypeVideo)
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(1), videoID)
+
+	// The same URI shares its ID across kinds
+	audioID, err := m.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: VideoOrientationURI}, RTPCodecTypeAudio)
+	assert.NoError(t, err)
+	assert.Equal(t, videoID, audioID)
+
+	for i := 2; i <= headerExtensionOneByteMaxID; i++ {
+		_, err = m.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: fmt.Sprintf("urn:test:%d", i)}, RTPCodecTypeVideo)
+		assert.NoError(t, err)
+	}
+
+	_, err = m.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: "urn:test:exhausted"}, RTPCodecTypeVideo)
+	assert.Equal(t, ErrHeaderExtensionIDExhausted, err)
+
+	assert.Len(t, m.getHeaderExtensionsByKind(RTPCodecTypeAudio), 1)
+}
+
+func TestFilterCodecsByName(t *testing.T) {
+	m := MediaEngine{}
+	m.RegisterDefaultCodecs()
+
+	assert.Len(t, filterCodecsByName(m.getCodecsByKind(RTPCodecTypeVideo), nil), 3)
+
+	filtered := filterCodecsByName(m.getCodecsByKind(RTPCodecTypeVideo), []string{"vp8", Opus})
+	assert.Len(t, filtered, 1)
+	assert.Equal(t, VP8, filtered[0].Name)
+
+	assert.Len(t, filterCodecsByName(m.getCodecsByKind(RTPCodecTypeAudio), []string{VP8}), 0)
+}
+
+func TestMatchRemoteCodecs(t *testing.T) {
+	m := MediaEngine{}
+	m.RegisterCodec(NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000, 2))
+	m.RegisterCodec(NewRTPG722Codec(DefaultPayloadTypeG722, 8000))
+
+	media := &sdp.MediaDescription{
+		MediaName: sdp.MediaName{Media: "audio", Formats: []string{"9", "109", "0"}},
+		Attributes: []sdp.Attribute{
+			sdp.NewAttribute("rtpmap", "109 OPUS/48000/2"),
+			sdp.NewAttribute("fmtp", "109 minptime=10;useinbandfec=1"),
+			sdp.NewAttribute("rtpmap", "0 PCMU/8000"),
+		},
+	}
+
+	remote := codecsFromMedia(media)
+	assert.Equal(t, []sdp.Codec{
+		{PayloadType: 9},
+		{PayloadType: 109, Name: "OPUS", ClockRate: 48000, EncodingParameters: "2", Fmtp: "minptime=10;useinbandfec=1"},
+		{PayloadType: 0, Name: "PCMU", ClockRate: 8000},
+	}, remote)
+
+	// The local order is kept, the static G722 matches without a=rtpmap
+	matched := matchRemoteCodecs(m.getCodecsByKind(RTPCodecTypeAudio), remote)
+	assert.Len(t, matched, 2)
+	assert.Equal(t, Opus, matched[0].Name)
+	assert.Equal(t, uint8(109), matched[0].PayloadType)
+	assert.Equal(t, G722, matched[1].Name)
+	assert.Equal(t, uint8(DefaultPayloadTypeG722), matched[1].PayloadType)
+
+	// The codecs of the MediaEngine are left untouched
+	codec, err := m.getCodec(DefaultPayloadTypeOpus)
+	assert.NoError(t, err)
+	assert.Equal(t, Opus, codec.Name)
+
+	// RED refers to the primary encoding offered by the remote
+	m.RegisterCodec(NewRTPRedCodec(63, 48000, 2, DefaultPayloadTypeOpus))
+	media.WithCodec(110, RED, 48000, 2, redFmtp(109))
+	matched = matchRemoteCodecs(filterCodecsByName(m.getCodecsByKind(RTPCodecTypeAudio), []string{RED}), codecsFromMedia(media))
+	assert.Len(t, matched, 1)
+	assert.Equal(t, uint8(110), matched[0].PayloadType)
+	assert.Equal(t, redFmtp(109), matched[0].SDPFmtpLine)
+}
+
+func TestMatchRemoteCodecs_Fmtp(t *testing.T) {
+	m := MediaEngine{}
+	m.RegisterCodec(NewRTPH264Codec(DefaultPayloadTypeH264, 90000))
+
+	// The H264 codecs a browser offers
+	media := &sdp.MediaDescription{MediaName: sdp.MediaName{Media: "video"}}
+	media.WithCodec(127, H264, 90000, 0, "level-asymmetry-allowed=1;packetization-mode=0;profile-level-id=42001f")
+	media.WithCodec(125, H264, 90000, 0, "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e01f")
+	media.WithCodec(123, H264, 90000, 0, "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=4d001f")
+	assert.Empty(t, matchRemoteCodecs(m.getCodecsByKind(RTPCodecTypeVideo), codecsFromMedia(media)))
+
+	// Another level of the profile matches, the same parameters are preferred
+	media.WithCodec(102, H264, 90000, 0, "packetization-mode=1;profile-level-id=42001e")
+	media.WithCodec(104, H264, 90000, 0, "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42001f")
+	matched := matchRemoteCodecs(m.getCodecsByKind(RTPCodecTypeVideo), codecsFromMedia(media))
+	assert.Len(t, matched, 1)
+	assert.Equal(t, uint8(104), matched[0].PayloadType)
+
+	media.MediaName.Formats = media.MediaName.Formats[:4]
+	matched = matchRemoteCodecs(m.getCodecsByKind(RTPCodecTypeVideo), codecsFromMedia(media))
+	assert.Len(t, matched, 1)
+	assert.Equal(t, uint8(102), matched[0].PayloadType)
+
+	codec, err := m.getCodecSDP(sdp.Codec{Name: H264, ClockRate: 90000, Fmtp: "packetization-mode=1;profile-level-id=42001e"})
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(DefaultPayloadTypeH264), codec.PayloadType)
+	_, err = m.getCodecSDP(sdp.Codec{Name: H264, ClockRate: 90000, Fmtp: "packetization-mode=0;profile-level-id=42001f"})
+	assert.Equal(t, ErrCodecNotFound, err)
+}
@@ -0,0 +1,60 @@
+package webrtc
+
+import (
+	"fmt"
+
+	"github.com/pions/webrtc/pkg/ice"
+)
+
+// MulticastDNSMode decides how the ICE agents use multicast DNS, the .local
+// names browsers such as Chrome hide the addresses of their host candidates
+// behind
+type MulticastDNSMode int
+
+const (
+	// MulticastDNSModeDisabled ignores the remote host candidates with a
+	// .local name.
+	MulticastDNSModeDisabled MulticastDNSMode = iota + 1
+
+	// MulticastDNSModeQueryOnly resolves the .local names of the remote host
+	// candidates, the local ones keep their addresses. It is the default.
+	MulticastDNSModeQueryOnly
+
+	// MulticastDNSModeQueryAndGather also hides the IPv4 addresses of the
+	// local host candidates behind generated .local names, and answers the
+	// queries for them. The IPv6 host candidates aren't gathered.
+	MulticastDNSModeQueryAndGather
+)
+
+// This is done this way because of a linter.
+const (
+	multicastDNSModeDisabledStr       = "disabled"
+	multicastDNSModeQueryOnlyStr      = "query-only"
+	multicastDNSModeQueryAndGatherStr = "query-and-gather"
+)
+
+func (m MulticastDNSMode) String() string {
+	switch m {
+	case MulticastDNSModeDisabled:
+		return multicastDNSModeDisabledStr
+	case MulticastDNSModeQueryOnly:
+		return multicastDNSModeQueryOnlyStr
+	case MulticastDNSModeQueryAndGather:
+		return multicastDNSModeQueryAndGatherStr
+	default:
+		return ErrUnknownType.Error()
+	}
+}
+
+func (m MulticastDNSMode) toICE() (ice.MulticastDNSMode, error) {
+	switch m {
+	case MulticastDNSModeDisabled:
+		return ice.MulticastDNSModeDisabled, nil
+	case MulticastDNSModeQueryOnly:
+		return ice.MulticastDNSModeQueryOnly, nil
+	case MulticastDNSModeQueryAndGather:
+		return ice.MulticastDNSModeQueryAndGather, nil
+	default:
+		return ice.MulticastDNSMode(Unknown), fmt.Errorf("unknown multicast DNS mode: %s", m)
+	}
+}
@@ -0,0 +1,37 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/webrtc/pkg/ice"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMulticastDNSMode_String(t *testing.T) {
+	testCases := []struct {
+		mode           MulticastDNSMode
+		expectedString string
+	}{
+		{MulticastDNSMode(Unknown), unknownStr},
+		{MulticastDNSModeDisabled, "disabled"},
+		{MulticastDNSModeQueryOnly, "query-only"},
+		{MulticastDNSModeQueryAndGather, "query-and-gather"},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedString,
+			testCase.mode.String(),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
+
+func TestMulticastDNSMode_ToICE(t *testing.T) {
+	mode, err := MulticastDNSModeQueryAndGather.toICE()
+	assert.NoError(t, err)
+	assert.Equal(t, ice.MulticastDNSModeQueryAndGather, mode)
+
+	_, err = MulticastDNSMode(Unknown).toICE()
+	assert.Error(t, err)
+}
@@ -0,0 +1,280 @@
+package webrtc
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pions/rtcp"
+	"github.com/pions/rtp"
+)
+
+// NACKStats counts the generic NACKs and retransmissions of a RTPSender or
+// RTPReceiver, enabled with SettingEngine.EnableNACK
+type NACKStats struct {
+	// NACKsSent is the number of NACK packets sent by a RTPReceiver
+	NACKsSent uint64
+
+	// NACKsReceived is the number of NACK packets received by a RTPSender
+	NACKsReceived uint64
+
+	// RetransmitsSent is the number of packets retransmitted by a RTPSender
+	RetransmitsSent uint64
+
+	// RetransmitsReceived is the number of retransmissions received by a
+	// RTPReceiver, every RTX packet and without RTX the late packets that
+	// were NACKed
+	RetransmitsReceived uint64
+
+	// PacketsRecovered is the number of lost packets a RTPReceiver got back
+	PacketsRecovered uint64
+
+	// RetransmitsAbandoned is the number of NACKed packets a RTPSender didn't
+	// retransmit because they were sent longer than the retransmit deadline
+	// ago, set with SettingEngine.SetRetransmitDeadline
+	RetransmitsAbandoned uint64
+
+	// SpuriousNACKs is the number of packets a RTPReceiver NACKed that were
+	// only reordered: the original arrived late on the media stream. They
+	// are only told apart from the retransmissions with RTX, the NACKs that
+	// were useful are counted in PacketsRecovered.
+	SpuriousNACKs uint64
+}
+
+// nackStats holds the counters of NACKStats, they are accessed atomically
+type nackStats struct {
+	nacksSent            uint64
+	nacksReceived        uint64
+	retransmitsSent      uint64
+	retransmitsReceived  uint64
+	packetsRecovered     uint64
+	retransmitsAbandoned uint64
+	spuriousNACKs        uint64
+}
+
+func (s *nackStats) snapshot() NACKStats {
+	return NACKStats{
+		NACKsSent:            atomic.LoadUint64(&s.nacksSent),
+		NACKsReceived:        atomic.LoadUint64(&s.nacksReceived),
+		RetransmitsSent:      atomic.LoadUint64(&s.retransmitsSent),
+		RetransmitsReceived:  atomic.LoadUint64(&s.retransmitsReceived),
+		PacketsRecovered:     atomic.LoadUint64(&s.packetsRecovered),
+		RetransmitsAbandoned: atomic.LoadUint64(&s.retransmitsAbandoned),
+		SpuriousNACKs:        atomic.LoadUint64(&s.spuriousNACKs),
+	}
+}
+
+const (
+	// rtpHistorySize is the number of sent packets kept for retransmission,
+	// it divides 65536 so the sequence numbers wrap around with the history
+	rtpHistorySize = 512
+
+	// nackMaxMissing is the largest gap NACKs are sent for, the packets of
+	// larger gaps are given up on
+	nackMaxMissing = 256
+
+	// nackRetryInterval is how long a missing packet is waited for before
+	// it is NACKed again
+	nackRetryInterval = 100 * time.Millisecond
+
+	// nackMaxRetries is how many times a missing packet is NACKed
+	nackMaxRetries = 3
+
+	// rtcpMaxNACKPairs is the number of NACK pairs that fit in a packet
+	rtcpMaxNACKPairs = 253
+
+	// nackMaxReorderWindow is the longest a missing packet is waited for
+	// before it is NACKed the first time, any longer and the retransmission
+	// comes too late
+	nackMaxReorderWindow = 100 * time.Millisecond
+
+	// nackReorderDecay is how often the reordering measured by an adaptive
+	// reorder window is halved, the window narrows back once the network
+	// stops reordering
+	nackReorderDecay = 5 * time.Second
+)
+
+// rtpHistory keeps the last packets sent by a RTPSender
+type rtpHistory struct {
+	mu      sync.Mutex
+	packets [rtpHistorySize]*rtp.Packet
+	sent    [rtpHistorySize]time.Time
+}
+
+// add keeps a copy of a sent packet, with the time it was sent at
+func (h *rtpHistory) add(packet *rtp.Packet) {
+	p := &rtp.Packet{Header: packet.Header, Payload: append([]byte{}, packet.Payload...)}
+	p.CSRC = append([]uint32{}, packet.CSRC...)
+	p.ExtensionPayload = append([]byte{}, packet.ExtensionPayload...)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.packets[packet.SequenceNumber%rtpHistorySize] = p
+	h.sent[packet.SequenceNumber%rtpHistorySize] = time.Now()
+}
+
+// get returns the sent packet with the given sequence number and the time it
+// was sent at, if still kept
+func (h *rtpHistory) get(sequenceNumber uint16) (*rtp.Packet, time.Time, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	p := h.packets[sequenceNumber%rtpHistorySize]
+	if p == nil || p.SequenceNumber != sequenceNumber {
+		return nil, time.Time{}, false
+	}
+	return p, h.sent[sequenceNumber%rtpHistorySize], true
+}
+
+type missingPacket struct {
+	detected time.Time
+	lastNACK time.Time
+	retries  int
+}
+
+// nackGenerator detects the packets missing from a stream and decides when
+// they are NACKed. A missing packet is waited for during the reorder window
+// before being NACKed, an adaptive window widens to the longest reordering
+// lately observed.
+type nackGenerator struct {
+	reorderWindow time.Duration
+	adaptive      bool
+
+	// rtx is set when the retransmissions come over RTX, the packets of
+	// the media stream are then all originals
+	rtx bool
+
+	mu      sync.Mutex
+	started bool
+	highest uint16
+	missing map[uint16]*missingPacket
+
+	// reordering is the longest a packet arrived after it was detected
+	// missing, since it was last decayed
+	reordering time.Duration
+	decayed    time.Time
+}
+
+func newNACKGenerator(reorderWindow time.Duration, adaptive, rtx bool) *nackGenerator {
+	return &nackGenerator{
+		reorderWindow: reorderWindow,
+		adaptive:      adaptive,
+		rtx:           rtx,
+		missing:       map[uint16]*missingPacket{},
+	}
+}
+
+// window returns how long a missing packet is waited for before it is NACKed
+func (g *nackGenerator) window() time.Duration {
+	if !g.adaptive || g.reordering <= g.reorderWindow {
+		return g.reorderWindow
+	}
+
+	// Half again the reordering observed, to cover its jitter
+	if window := g.reordering + g.reordering/2; window < nackMaxReorderWindow {
+		return window
+	}
+	return nackMaxReorderWindow
+}
+
+// push records a packet received on the media stream and returns whether
+// it was missing and already NACKed
+func (g *nackGenerator) push(sequenceNumber uint16, now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if now.Sub(g.decayed) >= nackReorderDecay {
+		g.reordering /= 2
+		g.decayed = now
+	}
+
+	if !g.started {
+		g.started = true
+		g.highest = sequenceNumber
+		return false
+	}
+
+	diff := sequenceNumber - g.highest
+	switch {
+	case diff == 0:
+		return false
+	case diff < 0x8000:
+		// Newer packet, the ones in between are missing
+		if diff-1 > nackMaxMissing {
+			g.missing = map[uint16]*missingPacket{}
+		} else {
+			for s := g.highest + 1; s != sequenceNumber; s++ {
+				g.missing[s] = &missingPacket{detected: now}
+			}
+		}
+		g.highest = sequenceNumber
+		return false
+	}
+
+	m, ok := g.missing[sequenceNumber]
+	if !ok {
+		return false
+	}
+	delete(g.missing, sequenceNumber)
+
+	// Without RTX a NACKed packet may be the retransmission, its delay isn't
+	// reordering
+	if delay := now.Sub(m.detected); delay > g.reordering && (m.retries == 0 || g.rtx) {
+		g.reordering = delay
+	}
+	return m.retries != 0
+}
+
+// recover records a retransmitted packet and returns whether it was missing
+func (g *nackGenerator) recover(sequenceNumber uint16) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	_, ok := g.missing[sequenceNumber]
+	delete(g.missing, sequenceNumber)
+	return ok
+}
+
+// pending returns the missing packets to NACK now, in order
+func (g *nackGenerator) pending(now time.Time) []uint16 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	window := g.window()
+	var sequenceNumbers []uint16
+	for s, m := range g.missing {
+		if now.Sub(m.detected) < window || now.Sub(m.lastNACK) < nackRetryInterval {
+			continue
+		}
+		if m.retries == nackMaxRetries {
+			delete(g.missing, s)
+			continue
+		}
+		m.retries++
+		m.lastNACK = now
+		sequenceNumbers = append(sequenceNumbers, s)
+	}
+
+	// Ordered by their distance to the highest packet to cope with wrap around
+	sort.Slice(sequenceNumbers, func(i, j int) bool {
+		return g.highest-sequenceNumbers[i] > g.highest-sequenceNumbers[j]
+	})
+	return sequenceNumbers
+}
+
+// nackPairs packs ordered sequence numbers into NACK pairs
+func nackPairs(sequenceNumbers []uint16) []rtcp.NackPair {
+	var pairs []rtcp.NackPair
+	for _, s := range sequenceNumbers {
+		if len(pairs) != 0 {
+			last := &pairs[len(pairs)-1]
+			if diff := s - last.PacketID; diff > 0 && diff <= 16 {
+				last.LostPackets |= 1 << (diff - 1)
+				continue
+			}
+		}
+		pairs = append(pairs, rtcp.NackPair{PacketID: s})
+	}
+	return pairs
+}
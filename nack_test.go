@@ -0,0 +1,156 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pions/rtcp"
+	"github.com/pions/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNACKGenerator(t *testing.T) {
+	g := newNACKGenerator(0, false, false)
+	now := time.Now()
+
+	assert.False(t, g.push(65534, now))
+	assert.False(t, g.push(65535, now))
+	assert.Empty(t, g.pending(now))
+
+	// The gap is detected across the wrap around
+	assert.False(t, g.push(2, now))
+	assert.Equal(t, []uint16{0, 1}, g.pending(now))
+
+	// NACKs are retried after the interval
+	assert.Empty(t, g.pending(now.Add(nackRetryInterval/2)))
+	assert.Equal(t, []uint16{0, 1}, g.pending(now.Add(nackRetryInterval)))
+
+	// A late packet was missing
+	assert.True(t, g.push(0, now))
+	assert.False(t, g.push(0, now))
+	assert.True(t, g.recover(1))
+	assert.False(t, g.recover(1))
+	assert.Empty(t, g.pending(now.Add(2*nackRetryInterval)))
+
+	// Missing packets are given up on after the retries
+	assert.False(t, g.push(4, now))
+	for i := 0; i < nackMaxRetries; i++ {
+		assert.Equal(t, []uint16{3}, g.pending(now.Add(time.Duration(i)*nackRetryInterval)))
+	}
+	assert.Empty(t, g.pending(now.Add(nackMaxRetries*nackRetryInterval)))
+	assert.False(t, g.push(3, now))
+
+	// Large gaps are not NACKed
+	assert.False(t, g.push(4+nackMaxMissing+2, now))
+	assert.Empty(t, g.pending(now))
+}
+
+func TestNACKGenerator_ReorderWindow(t *testing.T) {
+	window := 20 * time.Millisecond
+	g := newNACKGenerator(window, false, true)
+	now := time.Now()
+
+	// A missing packet is only NACKed after the window
+	assert.False(t, g.push(1, now))
+	assert.False(t, g.push(3, now))
+	assert.Empty(t, g.pending(now.Add(window/2)))
+	assert.False(t, g.push(2, now.Add(window/2)))
+	assert.False(t, g.push(5, now.Add(window/2)))
+	assert.Equal(t, []uint16{4}, g.pending(now.Add(window/2+window)))
+
+	// The original arrives after it was NACKed, the NACK was spurious
+	assert.True(t, g.push(4, now.Add(3*window)))
+}
+
+func TestNACKGenerator_AdaptiveReorderWindow(t *testing.T) {
+	window := 10 * time.Millisecond
+	g := newNACKGenerator(window, true, true)
+	now := time.Now()
+
+	assert.False(t, g.push(1, now))
+	assert.False(t, g.push(3, now))
+	assert.Equal(t, []uint16{2}, g.pending(now.Add(window)))
+
+	// The spurious NACK widens the window to half again the reordering
+	assert.True(t, g.push(2, now.Add(4*window)))
+	assert.Equal(t, 6*window, g.window())
+
+	assert.False(t, g.push(5, now.Add(4*window)))
+	assert.Empty(t, g.pending(now.Add(8*window)))
+	assert.Equal(t, []uint16{4}, g.pending(now.Add(10*window)))
+
+	// It is capped, and narrows back once the reordering stops
+	assert.True(t, g.push(4, now.Add(time.Second)))
+	assert.Equal(t, nackMaxReorderWindow, g.window())
+	for i := 0; i < 8; i++ {
+		assert.False(t, g.push(uint16(6+i), now.Add(time.Duration(i+1)*nackReorderDecay)))
+	}
+	assert.Equal(t, window, g.window())
+
+	// Without RTX a NACKed packet of the media stream may be the
+	// retransmission, its delay isn't taken for reordering
+	g = newNACKGenerator(window, true, false)
+	assert.False(t, g.push(1, now))
+	assert.False(t, g.push(3, now))
+	assert.Equal(t, []uint16{2}, g.pending(now.Add(window)))
+	assert.True(t, g.push(2, now.Add(4*window)))
+	assert.Equal(t, window, g.window())
+}
+
+func TestNACKPairs(t *testing.T) {
+	assert.Equal(t, []rtcp.NackPair{
+		{PacketID: 1, LostPackets: 0x8001},
+		{PacketID: 20},
+		{PacketID: 65535, LostPackets: 0x0001},
+	}, nackPairs([]uint16{1, 2, 17, 20, 65535, 0}))
+	assert.Empty(t, nackPairs(nil))
+}
+
+func TestRTPHistory(t *testing.T) {
+	h := &rtpHistory{}
+
+	payload := []byte{0x01}
+	h.add(&rtp.Packet{Header: rtp.Header{SequenceNumber: 10}, Payload: payload})
+	payload[0] = 0x02
+
+	p, sent, ok := h.get(10)
+	assert.True(t, ok)
+	assert.Equal(t, []byte{0x01}, p.Payload)
+	assert.False(t, sent.IsZero())
+
+	_, _, ok = h.get(11)
+	assert.False(t, ok)
+
+	// Older packets are overwritten
+	h.add(&rtp.Packet{Header: rtp.Header{SequenceNumber: 10 + rtpHistorySize}})
+	_, _, ok = h.get(10)
+	assert.False(t, ok)
+	_, _, ok = h.get(10 + rtpHistorySize)
+	assert.True(t, ok)
+}
+
+func TestRTPSender_RetransmitDeadline(t *testing.T) {
+	s := SettingEngine{}
+	s.SetRetransmitDeadline(time.Millisecond)
+
+	h := &rtpHistory{}
+	h.add(&rtp.Packet{Header: rtp.Header{SequenceNumber: 10, SSRC: 5}})
+	time.Sleep(10 * time.Millisecond)
+
+	r := &RTPSender{
+		Track:   &Track{SSRC: 5},
+		history: h,
+		api:     NewAPI(WithSettingEngine(s)),
+	}
+
+	// The packet is past the deadline, it is dropped without being written
+	r.retransmit(&rtcp.TransportLayerNack{
+		MediaSSRC: 5,
+		Nacks:     []rtcp.NackPair{{PacketID: 10}, {PacketID: 11}},
+	})
+
+	stats := r.NACKStats()
+	assert.Equal(t, uint64(1), stats.NACKsReceived)
+	assert.Equal(t, uint64(0), stats.RetransmitsSent)
+	assert.Equal(t, uint64(1), stats.RetransmitsAbandoned)
+}
@@ -0,0 +1,74 @@
+package webrtc
+
+import (
+	"io"
+
+	"github.com/pions/sctp"
+)
+
+// negotiatedDataChannel carries the messages of a DataChannel negotiated by
+// the application over its SCTP stream: no DCEP message is exchanged, both
+// peers open the stream with the ID they agreed on.
+type negotiatedDataChannel struct {
+	stream *sctp.Stream
+}
+
+// ReadDataChannel reads a message of len(p) bytes, returning whether it is a
+// string
+func (c *negotiatedDataChannel) ReadDataChannel(p []byte) (int, bool, error) {
+	for {
+		n, ppi, err := c.stream.ReadSCTP(p)
+		if err == io.EOF {
+			// The outgoing stream is reset as well once the remote
+			// reset its own
+			if closeErr := c.stream.Close(); closeErr != nil {
+				return 0, false, closeErr
+			}
+		}
+		if err != nil {
+			return 0, false, err
+		}
+
+		switch ppi {
+		case sctp.PayloadTypeWebRTCString:
+			return n, true, nil
+		case sctp.PayloadTypeWebRTCStringEmpty:
+			return 0, true, nil
+		case sctp.PayloadTypeWebRTCBinary:
+			return n, false, nil
+		case sctp.PayloadTypeWebRTCBinaryEmpty:
+			return 0, false, nil
+		default:
+			// DCEP messages have no meaning on a negotiated stream
+			continue
+		}
+	}
+}
+
+// WriteDataChannel writes the message p, as a string if isString is set
+func (c *negotiatedDataChannel) WriteDataChannel(p []byte, isString bool) (int, error) {
+	// An empty message is sent as a single byte with the PPI telling it's
+	// empty, SCTP doesn't support empty user messages
+	var ppi sctp.PayloadProtocolIdentifier
+	switch {
+	case isString && len(p) > 0:
+		ppi = sctp.PayloadTypeWebRTCString
+	case isString:
+		ppi = sctp.PayloadTypeWebRTCStringEmpty
+	case len(p) > 0:
+		ppi = sctp.PayloadTypeWebRTCBinary
+	default:
+		ppi = sctp.PayloadTypeWebRTCBinaryEmpty
+	}
+	if len(p) == 0 {
+		_, err := c.stream.WriteSCTP([]byte{0}, ppi)
+		return 0, err
+	}
+
+	return c.stream.WriteSCTP(p, ppi)
+}
+
+// Close resets the outgoing stream
+func (c *negotiatedDataChannel) Close() error {
+	return c.stream.Close()
+}
@@ -0,0 +1,57 @@
+package webrtc
+
+import (
+	"fmt"
+
+	"github.com/pions/webrtc/pkg/ice"
+)
+
+// NetworkType represents the type of network candidates are gathered on.
+type NetworkType int
+
+const (
+	// NetworkTypeUDP4 indicates UDP over IPv4.
+	NetworkTypeUDP4 NetworkType = iota + 1
+
+	// NetworkTypeUDP6 indicates UDP over IPv6.
+	NetworkTypeUDP6
+)
+
+// This is done this way because of a linter.
+const (
+	networkTypeUDP4Str = "udp4"
+	networkTypeUDP6Str = "udp6"
+)
+
+func newNetworkType(raw string) (NetworkType, error) {
+	switch raw {
+	case networkTypeUDP4Str:
+		return NetworkTypeUDP4, nil
+	case networkTypeUDP6Str:
+		return NetworkTypeUDP6, nil
+	default:
+		return NetworkType(Unknown), fmt.Errorf("unknown network type: %s", raw)
+	}
+}
+
+func (t NetworkType) String() string {
+	switch t {
+	case NetworkTypeUDP4:
+		return networkTypeUDP4Str
+	case NetworkTypeUDP6:
+		return networkTypeUDP6Str
+	default:
+		return ErrUnknownType.Error()
+	}
+}
+
+func (t NetworkType) toICE() (ice.NetworkType, error) {
+	switch t {
+	case NetworkTypeUDP4:
+		return ice.NetworkTypeUDP4, nil
+	case NetworkTypeUDP6:
+		return ice.NetworkTypeUDP6, nil
+	default:
+		return ice.NetworkType(Unknown), fmt.Errorf("unknown network type: %s", t)
+	}
+}
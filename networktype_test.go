@@ -0,0 +1,75 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/webrtc/pkg/ice"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetworkType(t *testing.T) {
+	testCases := []struct {
+		typeString   string
+		shouldFail   bool
+		expectedType NetworkType
+	}{
+		{unknownStr, true, NetworkType(Unknown)},
+		{"udp4", false, NetworkTypeUDP4},
+		{"udp6", false, NetworkTypeUDP6},
+	}
+
+	for i, testCase := range testCases {
+		actual, err := newNetworkType(testCase.typeString)
+		if (err != nil) != testCase.shouldFail {
+			t.Error(err)
+		}
+		assert.Equal(t,
+			testCase.expectedType,
+			actual,
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
+
+func TestNetworkType_String(t *testing.T) {
+	testCases := []struct {
+		nType          NetworkType
+		expectedString string
+	}{
+		{NetworkType(Unknown), unknownStr},
+		{NetworkTypeUDP4, "udp4"},
+		{NetworkTypeUDP6, "udp6"},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedString,
+			testCase.nType.String(),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
+
+func TestNetworkType_ToICE(t *testing.T) {
+	testCases := []struct {
+		nType      NetworkType
+		shouldFail bool
+		expected   ice.NetworkType
+	}{
+		{NetworkType(Unknown), true, ice.NetworkType(Unknown)},
+		{NetworkTypeUDP4, false, ice.NetworkTypeUDP4},
+		{NetworkTypeUDP6, false, ice.NetworkTypeUDP6},
+	}
+
+	for i, testCase := range testCases {
+		actual, err := testCase.nType.toICE()
+		if (err != nil) != testCase.shouldFail {
+			t.Error(err)
+		}
+		assert.Equal(t,
+			testCase.expected,
+			actual,
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
@@ -6,6 +6,16 @@ type OfferAnswerOptions struct {
 	// VoiceActivityDetection allows the application to provide information
 	// about whether it wishes voice detection feature to be enabled or disabled.
 	VoiceActivityDetection bool
+
+	// Codecs restricts the codecs put in the description to the ones with
+	// these names, such as Opus or VP8. All the codecs registered in the
+	// MediaEngine are used when empty.
+	Codecs []string
+
+	// MaxBitrate is the bitrate in bits per second the remote is asked not
+	// to exceed when sending each of the audio and video media, announced
+	// with b=AS lines. No limit is announced when it is 0.
+	MaxBitrate uint64
 }
 
 // AnswerOptions structure describes the options used to control the answer
@@ -21,6 +31,29 @@ type OfferOptions struct {
 
 	// ICERestart forces the underlying ice gathering process to be restarted.
 	// When this value is true, the generated description will have ICE
-	// credentials that are different from the current credentials
+	// credentials that are different from the current credentials. The
+	// sessions restart once the offer is set with SetLocalDescription, the
+	// candidates gathered again are then trickled or, without trickle, added
+	// to the LocalDescription to signal. Rolling the offer back brings the
+	// previous session back.
 	ICERestart bool
+
+	// UnbundleDataChannel leaves the data channels out of the BUNDLE group
+	// of the media when the offer has media sections. The application
+	// section then gathers its own candidates and runs its own ICE session
+	// and DTLS handshake. Some legacy endpoints fail to demultiplex SCTP
+	// bundled with RTP and need it, while browsers bundle everything under
+	// the max-bundle policy and may reject it. A remote offer that leaves
+	// the data channels out of its BUNDLE group is answered the same way,
+	// regardless of this option. The ICE connection state only follows the
+	// media transport.
+	UnbundleDataChannel bool
+
+	// DataChannelsOnly leaves the media sections out of the offer, which
+	// then only has the application section of the data channels with its
+	// ICE parameters and the DTLS fingerprint. The media sections are
+	// otherwise offered for every kind the MediaEngine has codecs for, to
+	// receive media even without tracks. The PeerConnection must not send
+	// any track.
+	DataChannelsOnly bool
 }
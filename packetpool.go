@@ -0,0 +1,69 @@
+package webrtc
+
+import (
+	"sync"
+
+	"github.com/pions/rtp"
+)
+
+// packetBufferSize is the size of the buffers the received RTP packets are
+// held in, the MTU of most networks. A larger packet gets a buffer of its
+// own, which isn't pooled.
+const packetBufferSize = 1500
+
+// packetPool holds the received RTP packets released with
+// ReleaseRTPPacket, each keeping its buffer in Raw. The RTPReceivers of all
+// the PeerConnections share it.
+var packetPool = sync.Pool{
+	New: func() interface{} {
+		return &rtp.Packet{}
+	},
+}
+
+// newReceivedPacket unmarshals a received RTP packet, copied into the
+// buffer of a packet of the pool, as the read buffer of the stream is
+// reused for the next one
+func newReceivedPacket(raw []byte) (*rtp.Packet, error) {
+	packet := packetPool.Get().(*rtp.Packet)
+
+	var buf []byte
+	switch {
+	case len(raw) > packetBufferSize:
+		buf = make([]byte, len(raw))
+	case cap(packet.Raw) == packetBufferSize:
+		buf = packet.Raw[:len(raw)]
+	default:
+		buf = make([]byte, len(raw), packetBufferSize)
+	}
+	copy(buf, raw)
+
+	if err := packet.Unmarshal(buf); err != nil {
+		packet.Raw = buf
+		ReleaseRTPPacket(packet)
+		return nil, err
+	}
+	return packet, nil
+}
+
+// ReleaseRTPPacket hands a RTP packet read from a received Track back to
+// the pool the RTPReceivers take the packets they receive from, along with
+// the buffer holding it, so the receive path of a SFU forwarding many
+// streams doesn't allocate for each packet. The packet, its payload and
+// its header extensions must not be used once released, nor may the packet
+// be released twice. Releasing is optional, the packets not released are
+// garbage collected.
+//
+// Track.WriteRTP copies the packet it is given, a forwarded packet can be
+// released once WriteRTP returns. The packets sent on the RawRTP channel of
+// a Track are read by the RTPSender later, they must never be released.
+// The library keeps no reference to the packets delivered to the Track, an
+// interceptor keeping some must copy them. The packets a RED packet carried
+// are held in the buffer of the RED packet, which is never released.
+func ReleaseRTPPacket(packet *rtp.Packet) {
+	raw := packet.Raw
+	*packet = rtp.Packet{}
+	if cap(raw) == packetBufferSize {
+		packet.Raw = raw[:0]
+	}
+	packetPool.Put(packet)
+}
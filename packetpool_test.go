@@ -0,0 +1,95 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceivedPacketPool(t *testing.T) {
+	raw, err := (&rtp.Packet{
+		Header:  rtp.Header{Version: 2, SequenceNumber: 5, SSRC: 5000},
+		Payload: []byte{0x01, 0x02},
+	}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packet, err := newReceivedPacket(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, uint16(5), packet.SequenceNumber)
+	assert.Equal(t, []byte{0x01, 0x02}, packet.Payload)
+	assert.Equal(t, packetBufferSize, cap(packet.Raw))
+
+	// The packet is copied, the read buffer can be reused
+	raw[len(raw)-1] = 0x03
+	assert.Equal(t, []byte{0x01, 0x02}, packet.Payload)
+
+	ReleaseRTPPacket(packet)
+
+	// Reading and releasing doesn't allocate once the pool holds a packet
+	allocs := testing.AllocsPerRun(100, func() {
+		p, readErr := newReceivedPacket(raw)
+		if readErr != nil {
+			t.Fatal(readErr)
+		}
+		ReleaseRTPPacket(p)
+	})
+	assert.True(t, allocs < 1, "%v allocations per packet", allocs)
+
+	// The larger packets get a buffer of their own, which isn't pooled
+	large := append(append([]byte{}, raw...), make([]byte, packetBufferSize)...)
+	packet, err = newReceivedPacket(large)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(large), len(packet.Raw))
+	assert.NotEqual(t, packetBufferSize, cap(packet.Raw))
+	ReleaseRTPPacket(packet)
+
+	_, err = newReceivedPacket([]byte{0x80})
+	assert.Error(t, err)
+}
+
+func TestReceivedPacketPool_ForwardThenRelease(t *testing.T) {
+	track, err := NewRawRTPTrack(DefaultPayloadTypeVP8, 5000, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := NewAPI().NewRTPSender(track, nil)
+
+	source := &rtp.Packet{
+		Header:  rtp.Header{Version: 2, SequenceNumber: 5, SSRC: 5000, CSRC: []uint32{1}},
+		Payload: []byte{0x01, 0x02},
+	}
+	assert.NoError(t, setHeaderExtension(&source.Header, 1, []byte{0x03}))
+	raw, err := source.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A SFU forwards the received packet then releases it, the buffer is
+	// reused by a newer packet before the RTPSender reads the queued one
+	packet, err := newReceivedPacket(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, track.WriteRTP(packet, nil))
+	buf := packet.Raw
+	ReleaseRTPPacket(packet)
+	for i := range buf {
+		buf[i] = 0xFF
+	}
+
+	sent := <-track.rawInput
+	assert.Equal(t, []byte{0x01, 0x02}, sent.Payload)
+	assert.Equal(t, []uint32{1}, sent.CSRC)
+	payload, ok := getHeaderExtension(&sent.Header, 1)
+	assert.True(t, ok)
+	assert.Equal(t, []byte{0x03}, payload)
+
+	sender.Stop()
+}
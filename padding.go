@@ -0,0 +1,27 @@
+package webrtc
+
+import (
+	"github.com/pions/rtp"
+)
+
+// isPaddingOnly returns whether a RTP packet carries nothing but padding.
+// The last byte of the padding is its length, which covers the whole payload
+// of such a packet https://tools.ietf.org/html/rfc3550#section-5.1
+func isPaddingOnly(packet *rtp.Packet) bool {
+	if !packet.Padding || len(packet.Payload) == 0 {
+		return false
+	}
+	return int(packet.Payload[len(packet.Payload)-1]) >= len(packet.Payload)
+}
+
+// paddingSize returns the number of padding bytes at the end of the payload
+// of a RTP packet, the whole payload at most
+func paddingSize(header *rtp.Header, payload []byte) int {
+	if !header.Padding || len(payload) == 0 {
+		return 0
+	}
+	if size := int(payload[len(payload)-1]); size < len(payload) {
+		return size
+	}
+	return len(payload)
+}
@@ -0,0 +1,27 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPaddingOnly(t *testing.T) {
+	for _, test := range []struct {
+		padding bool
+		payload []byte
+		want    bool
+	}{
+		{false, []byte{0x10, 0x00}, false},
+		{false, nil, false},
+		{true, nil, false},
+		{true, []byte{0x00, 0x00, 0x03}, true},
+		{true, []byte{0x01}, true},
+		// Padding after a payload
+		{true, []byte{0x10, 0x00, 0x02}, false},
+	} {
+		packet := &rtp.Packet{Header: rtp.Header{Padding: test.padding}, Payload: test.payload}
+		assert.Equal(t, test.want, isPaddingOnly(packet), "%v %v", test.padding, test.payload)
+	}
+}
@@ -0,0 +1,189 @@
+package webrtc
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/pions/rtcp"
+	"github.com/pkg/errors"
+)
+
+// PauseResumeType is the type of a message of a PauseResume packet
+type PauseResumeType uint8
+
+const (
+	// PauseResumeTypePause asks the sender of a stream to pause it
+	PauseResumeTypePause PauseResumeType = iota
+
+	// PauseResumeTypeResume asks the sender of a paused stream to resume it
+	PauseResumeTypeResume
+
+	// PauseResumeTypePaused confirms that a stream is paused
+	PauseResumeTypePaused
+
+	// PauseResumeTypeRefused denies a PAUSE request
+	PauseResumeTypeRefused
+)
+
+func (t PauseResumeType) String() string {
+	switch t {
+	case PauseResumeTypePause:
+		return "PAUSE"
+	case PauseResumeTypeResume:
+		return "RESUME"
+	case PauseResumeTypePaused:
+		return "PAUSED"
+	case PauseResumeTypeRefused:
+		return "REFUSED"
+	default:
+		return ErrUnknownType.Error()
+	}
+}
+
+// PauseResume is the RTP stream pause and resume RTCP feedback message
+// defined in https://tools.ietf.org/html/rfc7728#section-8, it lets the
+// receiver of a stream ask its sender to stop sending it, saving the bandwidth
+// of a stream that isn't used for a while.
+type PauseResume struct {
+	// SSRC of sender
+	SenderSSRC uint32
+
+	// SSRC of the media source, unused and always 0 as required by RFC7728
+	MediaSSRC uint32
+
+	Messages []PauseResumeMessage
+}
+
+// PauseResumeMessage is a single message of a PauseResume
+type PauseResumeMessage struct {
+	Type PauseResumeType
+
+	// SSRC of the stream the message is about
+	SSRC uint32
+
+	// PauseID ties the messages of a pause together, it is incremented once
+	// the stream is resumed
+	PauseID uint16
+
+	// Parameter is the type specific parameter, its length is a multiple
+	// of 4 bytes
+	Parameter []byte
+}
+
+const (
+	formatPauseResume = 9
+
+	pauseResumeMessageLength = 8
+)
+
+var _ rtcp.Packet = (*PauseResume)(nil)
+
+func (p PauseResume) len() int {
+	l := rtcpHeaderLength + 8
+	for _, m := range p.Messages {
+		l += pauseResumeMessageLength + len(m.Parameter)
+	}
+	return l
+}
+
+// Header returns the Header associated with this packet.
+func (p *PauseResume) Header() rtcp.Header {
+	return rtcp.Header{
+		Count:  formatPauseResume,
+		Type:   rtcp.TypeTransportSpecificFeedback,
+		Length: uint16(p.len()/4 - 1),
+	}
+}
+
+// Marshal encodes the PauseResume in binary
+func (p PauseResume) Marshal() ([]byte, error) {
+	for _, m := range p.Messages {
+		if len(m.Parameter)%4 != 0 || len(m.Parameter)/4 > 0xFF {
+			return nil, errors.Errorf("rtcp: invalid %s parameter length %d", m.Type, len(m.Parameter))
+		}
+	}
+
+	rawPacket := make([]byte, p.len())
+
+	hData, err := p.Header().Marshal()
+	if err != nil {
+		return nil, err
+	}
+	copy(rawPacket, hData)
+
+	packetBody := rawPacket[rtcpHeaderLength:]
+	binary.BigEndian.PutUint32(packetBody, p.SenderSSRC)
+	binary.BigEndian.PutUint32(packetBody[4:], p.MediaSSRC)
+	i := 8
+	for _, m := range p.Messages {
+		binary.BigEndian.PutUint32(packetBody[i:], m.SSRC)
+		packetBody[i+4] = uint8(m.Type) << 4
+		packetBody[i+5] = uint8(len(m.Parameter) / 4)
+		binary.BigEndian.PutUint16(packetBody[i+6:], m.PauseID)
+		copy(packetBody[i+pauseResumeMessageLength:], m.Parameter)
+		i += pauseResumeMessageLength + len(m.Parameter)
+	}
+
+	return rawPacket, nil
+}
+
+// Unmarshal decodes the PauseResume from binary
+func (p *PauseResume) Unmarshal(rawPacket []byte) error {
+	if len(rawPacket) < rtcpHeaderLength+8 {
+		return errors.New("rtcp: packet too short")
+	}
+
+	var h rtcp.Header
+	if err := h.Unmarshal(rawPacket); err != nil {
+		return err
+	}
+
+	if h.Type != rtcp.TypeTransportSpecificFeedback || h.Count != formatPauseResume {
+		return errors.New("rtcp: wrong packet type")
+	}
+
+	end := (int(h.Length) + 1) * 4
+	if end > len(rawPacket) || end < rtcpHeaderLength+8 {
+		return errors.New("rtcp: invalid packet length")
+	}
+
+	p.SenderSSRC = binary.BigEndian.Uint32(rawPacket[rtcpHeaderLength:])
+	p.MediaSSRC = binary.BigEndian.Uint32(rawPacket[rtcpHeaderLength+4:])
+	p.Messages = nil
+	for i := rtcpHeaderLength + 8; i+pauseResumeMessageLength <= end; {
+		parameterEnd := i + pauseResumeMessageLength + int(rawPacket[i+5])*4
+		if parameterEnd > end {
+			return errors.New("rtcp: invalid pause resume parameter length")
+		}
+
+		var parameter []byte
+		if parameterEnd > i+pauseResumeMessageLength {
+			parameter = append(parameter, rawPacket[i+pauseResumeMessageLength:parameterEnd]...)
+		}
+		p.Messages = append(p.Messages, PauseResumeMessage{
+			Type:      PauseResumeType(rawPacket[i+4] >> 4),
+			SSRC:      binary.BigEndian.Uint32(rawPacket[i:]),
+			PauseID:   binary.BigEndian.Uint16(rawPacket[i+6:]),
+			Parameter: parameter,
+		})
+		i = parameterEnd
+	}
+	return nil
+}
+
+// DestinationSSRC returns an array of SSRC values that this packet refers to.
+func (p *PauseResume) DestinationSSRC() []uint32 {
+	ssrcs := make([]uint32, 0, len(p.Messages))
+	for _, m := range p.Messages {
+		ssrcs = append(ssrcs, m.SSRC)
+	}
+	return ssrcs
+}
+
+func (p *PauseResume) String() string {
+	out := fmt.Sprintf("PauseResume %x %x", p.SenderSSRC, p.MediaSSRC)
+	for _, m := range p.Messages {
+		out += fmt.Sprintf(" (%s %x %d)", m.Type, m.SSRC, m.PauseID)
+	}
+	return out
+}
@@ -0,0 +1,48 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPauseResume(t *testing.T) {
+	pauseResume := PauseResume{
+		SenderSSRC: 0x902f9e2e,
+		Messages: []PauseResumeMessage{
+			{Type: PauseResumeTypePause, SSRC: 0x12345678, PauseID: 0x0102},
+			{Type: PauseResumeTypeRefused, SSRC: 0x9abcdef0, PauseID: 7, Parameter: []byte{1, 2, 3, 4}},
+		},
+	}
+
+	raw, err := pauseResume.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x89, 0xcd, 0x00, 0x07,
+		0x90, 0x2f, 0x9e, 0x2e,
+		0x00, 0x00, 0x00, 0x00,
+		0x12, 0x34, 0x56, 0x78,
+		0x00, 0x00, 0x01, 0x02,
+		0x9a, 0xbc, 0xde, 0xf0,
+		0x30, 0x01, 0x00, 0x07,
+		0x01, 0x02, 0x03, 0x04,
+	}, raw)
+
+	packet, err := unmarshalRTCP(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, &pauseResume, packet)
+	assert.Equal(t, []uint32{0x12345678, 0x9abcdef0}, packet.DestinationSSRC())
+
+	// The parameter exceeds the packet
+	var decoded PauseResume
+	truncated := append([]byte{}, raw[:len(raw)-4]...)
+	truncated[3] = 0x06
+	assert.Error(t, decoded.Unmarshal(truncated))
+
+	// The parameter is counted in 32-bit words
+	pauseResume.Messages[1].Parameter = []byte{1, 2}
+	_, err = pauseResume.Marshal()
+	assert.Error(t, err)
+
+	assert.Equal(t, "PAUSED", PauseResumeTypePaused.String())
+}
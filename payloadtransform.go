@@ -0,0 +1,109 @@
+package webrtc
+
+import (
+	"sync"
+
+	"github.com/pions/rtp"
+)
+
+// frameBoundaryWindow is the number of packets of a stream whose timestamps
+// are kept to find the first packets of the frames
+const frameBoundaryWindow = 64
+
+// PayloadTransformInfo describes the packet given to a PayloadTransform
+type PayloadTransformInfo struct {
+	// Sender is the RTPSender of an outgoing packet and Receiver the
+	// RTPReceiver of an incoming one, the other is nil. They tell the
+	// streams apart when a Track is sent to several PeerConnections.
+	Sender   *RTPSender
+	Receiver *RTPReceiver
+
+	// Track is the Track of the packet
+	Track *Track
+
+	// Header is the header of the packet, which tells the SSRC, the payload
+	// type and the timestamp of the frame the packet belongs to. It must not
+	// be modified.
+	Header *rtp.Header
+
+	// FrameStart and FrameEnd tell whether the packet is the first and the
+	// last one of its frame, both are set for every audio packet. FrameEnd
+	// is the marker bit of a video packet. FrameStart is found from the
+	// timestamp of the packet that precedes it: a received packet is only
+	// known to start a frame when the one before it arrived within the
+	// last 64 packets, or when it is the first of its stream.
+	FrameStart bool
+	FrameEnd   bool
+}
+
+// PayloadTransform transforms the payload of a RTP packet, such as to
+// encrypt the media end-to-end so the units forwarding it can't read it,
+// with keys picked from the PayloadTransformInfo. It returns the new
+// payload. A packet is dropped when the transform returns an error.
+//
+// The transform is applied to each packet, SRTP protects the transformed
+// payload. It isn't given the redundancy nor the retransmission wrapping,
+// which carry the transformed payloads.
+type PayloadTransform func(info PayloadTransformInfo, payload []byte) ([]byte, error)
+
+// transformPayload replaces the payload of a packet of the kind with the
+// transformed one
+func transformPayload(transform PayloadTransform, info PayloadTransformInfo, kind RTPCodecType, frames *frameBoundaries, packet *rtp.Packet) error {
+	info.Header = &packet.Header
+	if kind == RTPCodecTypeAudio {
+		info.FrameStart, info.FrameEnd = true, true
+	} else {
+		info.FrameStart, info.FrameEnd = frames.start(&packet.Header), packet.Marker
+	}
+
+	payload, err := transform(info, packet.Payload)
+	if err != nil {
+		return err
+	}
+	packet.Payload = payload
+	return nil
+}
+
+// frameBoundaries keeps the timestamps of the last packets of each stream by
+// sequence number, to tell whether a packet starts a frame
+type frameBoundaries struct {
+	mu      sync.Mutex
+	streams map[uint32]*frameBoundaryStream
+}
+
+type frameBoundaryStream struct {
+	// timestamps are indexed by sequence number, sequenceNumbers tells
+	// which packet each slot holds
+	timestamps      [frameBoundaryWindow]uint32
+	sequenceNumbers [frameBoundaryWindow]uint16
+	received        [frameBoundaryWindow]bool
+}
+
+// start records a packet and returns whether it is the first of its frame:
+// the packet before it has another timestamp. It is false when that packet
+// wasn't recorded, unless the stream has no other packet.
+func (f *frameBoundaries) start(header *rtp.Header) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.streams == nil {
+		f.streams = map[uint32]*frameBoundaryStream{}
+	}
+	stream, ok := f.streams[header.SSRC]
+	if !ok {
+		stream = &frameBoundaryStream{}
+		f.streams[header.SSRC] = stream
+	}
+
+	i := header.SequenceNumber % frameBoundaryWindow
+	stream.timestamps[i] = header.Timestamp
+	stream.sequenceNumbers[i] = header.SequenceNumber
+	stream.received[i] = true
+
+	if !ok {
+		return true
+	}
+	previous := header.SequenceNumber - 1
+	j := previous % frameBoundaryWindow
+	return stream.received[j] && stream.sequenceNumbers[j] == previous && stream.timestamps[j] != header.Timestamp
+}
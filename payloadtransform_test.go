@@ -0,0 +1,39 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrameBoundaries(t *testing.T) {
+	frames := frameBoundaries{}
+
+	for _, test := range []struct {
+		ssrc           uint32
+		sequenceNumber uint16
+		timestamp      uint32
+		start          bool
+	}{
+		// The first packet of a stream
+		{1, 65534, 0, true},
+		{1, 65535, 0, false},
+		// Across the wrap of the sequence numbers
+		{1, 0, 3000, true},
+		{1, 1, 3000, false},
+		// The packet before it was lost
+		{1, 3, 6000, false},
+		// Recovered late
+		{1, 2, 6000, true},
+		{1, 4, 6000, false},
+		// The streams are apart
+		{2, 4, 9000, true},
+		{1, 5, 9000, true},
+		// The packet before it is out of the window
+		{1, 5 + frameBoundaryWindow, 12000, false},
+	} {
+		header := &rtp.Header{SSRC: test.ssrc, SequenceNumber: test.sequenceNumber, Timestamp: test.timestamp}
+		assert.Equal(t, test.start, frames.start(header), "SSRC %d sequence number %d", test.ssrc, test.sequenceNumber)
+	}
+}
@@ -2,19 +2,23 @@
 package webrtc
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
-	"crypto/rand"
+	"encoding/binary"
 	"fmt"
+	"io"
 	"net"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pions/rtcp"
-	"github.com/pions/rtp"
 	"github.com/pions/sdp/v2"
+	"github.com/pions/srtp"
 	"github.com/pions/webrtc/pkg/ice"
 	"github.com/pions/webrtc/pkg/logging"
 	"github.com/pions/webrtc/pkg/rtcerr"
@@ -71,7 +75,7 @@ type PeerConnection struct {
 
 	// ICEGatheringState attribute returns the ICE gathering state of the
 	// PeerConnection instance.
-	ICEGatheringState ICEGatheringState // FIXME NOT-USED
+	ICEGatheringState ICEGatheringState
 
 	// ICEConnectionState attribute returns the ICE connection state of the
 	// PeerConnection instance.
@@ -81,37 +85,107 @@ type PeerConnection struct {
 	// PeerConnection instance.
 	ConnectionState PeerConnectionState
 
+	// connectionError is the reason the ConnectionState became failed
+	connectionError error
+
+	// connectionStateChanged is closed and replaced as the ConnectionState
+	// changes, for WaitUntilConnected
+	connectionStateChanged chan struct{}
+
 	idpLoginURL *string
 
-	isClosed          bool
-	negotiationNeeded bool
+	// isClosed is accessed atomically, it is set under mu by Close
+	isClosed int32
+
+	// reducedSizeRTCP is accessed atomically, it is set when every media
+	// section of the RemoteDescription accepts reduced-size RTCP, for the
+	// packets sent with SendRTCP and WriteRTCP
+	reducedSizeRTCP int32
+
+	// negotiationNeeded is set by the changes an offer must negotiate, until
+	// one is created. negotiationNeededFired is set once OnNegotiationNeeded
+	// was invoked for them.
+	negotiationNeeded      bool
+	negotiationNeededFired bool
+
+	// offerNegotiationNeeded is set while the offers created since the last
+	// answer carry changes, negotiationNeeded is set again if they are
+	// rolled back
+	offerNegotiationNeeded bool
 
 	lastOffer  string
 	lastAnswer string
 
+	// pendingICERestart holds the new local ICE parameters of the last offer
+	// created with OfferOptions.ICERestart, the sessions are restarted with
+	// them once it is set. appliedICERestart are the gatherers it restarted,
+	// until the answer is set or the offer rolled back.
+	pendingICERestart map[*ICEGatherer]ICEParameters
+	appliedICERestart []*ICEGatherer
+
+	// failedICERestart is set once a failed ICE connection is to be
+	// restarted by the next offer, see SettingEngine.SetICERestartOnFailure.
+	// iceRestartAttempts counts these restarts since the connection last
+	// connected. Both are guarded by mu.
+	failedICERestart   bool
+	iceRestartAttempts int
+
 	rtpTransceivers []*RTPTransceiver
 
+	// rtpLock guards the start of the RTPSenders and RTPReceivers after
+	// each negotiation. router is nil until the DTLS transport connected.
+	// The RTPReceivers started for the RemoteDescription are kept by SSRC,
+	// by mid for the routed ones, to stop them once the remote no longer
+	// sends their stream.
+	rtpLock           sync.Mutex
+	router            *rtpRouter
+	ssrcReceivers     map[uint32]*RTPReceiver
+	routedReceivers   map[string][]*RTPReceiver
+	latchingReceivers []*RTPReceiver
+
+	// announcedSSRCs are the RTPReceivers created by ReceiveSSRC, they are
+	// started with the media once announcedStarted is set
+	announcedSSRCs   map[uint32]*announcedReceiver
+	announcedStarted bool
+
 	// DataChannels
 	dataChannels map[uint16]*DataChannel
 
-	// OnNegotiationNeeded        func() // FIXME NOT-USED
-	// OnICECandidate             func() // FIXME NOT-USED
-	// OnICECandidateError        func() // FIXME NOT-USED
-
-	// OnICEGatheringStateChange  func() // FIXME NOT-USED
-	// OnConnectionStateChange    func() // FIXME NOT-USED
+	// remoteDataChannels are the DataChannels opened by the remote, in the
+	// order they were announced, until they are closed
+	remoteDataChannels []*DataChannel
 
 	onSignalingStateChangeHandler     func(SignalingState)
+	onNegotiationNeededHandler        func()
 	onICEConnectionStateChangeHandler func(ICEConnectionState)
+	onConnectionStateChangeHandler    func(PeerConnectionState)
 	onTrackHandler                    func(*Track)
 	onDataChannelHandler              func(*DataChannel)
+	onStatsHandler                    func(StatsReport)
+	onICEGatheringErrorHandler        func(ICEGatheringError)
+	onICECandidateHandler             func(*ICECandidate)
+	onICEGatheringStateChangeHandler  func(ICEGatheringState)
+
+	// statsLoopClose stops the sampling loop of OnStats, nil until started
+	statsLoopClose chan struct{}
 
 	iceGatherer   *ICEGatherer
 	iceTransport  *ICETransport
 	dtlsTransport *DTLSTransport
 	sctpTransport *SCTPTransport
 
+	// dataTransport carries the data channels when they aren't bundled with
+	// the media, nil unless an offer or the remote asked for it
+	dataTransport *unbundledTransport
+
+	// sdesKeySalt is the SRTP master key and salt announced in the a=crypto
+	// lines, nil unless SDES is enabled and offered or negotiated
+	sdesKeySalt []byte
+
 	// A reference to the associated API state used by this connection
+	// logTag prefixes the messages the PeerConnection logs
+	logTag string
+
 	api *API
 }
 
@@ -135,10 +209,10 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 			ICETransportPolicy:   ICETransportPolicyAll,
 			BundlePolicy:         BundlePolicyBalanced,
 			RTCPMuxPolicy:        RTCPMuxPolicyRequire,
+			SDPSemantics:         SDPSemanticsUnifiedPlan,
 			Certificates:         []Certificate{},
 			ICECandidatePoolSize: 0,
 		},
-		isClosed:           false,
 		negotiationNeeded:  false,
 		lastOffer:          "",
 		lastAnswer:         "",
@@ -148,10 +222,15 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 		ConnectionState:    PeerConnectionStateNew,
 		dataChannels:       make(map[uint16]*DataChannel),
 
-		api: api,
+		connectionStateChanged: make(chan struct{}),
 	}
 
 	var err error
+	if pc.logTag, err = newLogTag(api.settingEngine.randomSource()); err != nil {
+		return nil, err
+	}
+	pc.api = api.withLogTag(pc.logTag)
+
 	if err = pc.initConfiguration(configuration); err != nil {
 		return nil, err
 	}
@@ -162,6 +241,8 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 		return nil, err
 	}
 	pc.iceGatherer = gatherer
+	gatherer.OnLocalCandidate(pc.onICECandidate)
+	gatherer.OnStateChange(pc.onICEGathererStateChange)
 
 	err = pc.gather()
 
@@ -203,11 +284,11 @@ func (pc *PeerConnection) initConfiguration(configuration Configuration) error {
 			pc.configuration.Certificates = append(pc.configuration.Certificates, x509Cert)
 		}
 	} else {
-		sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		sk, err := ecdsa.GenerateKey(elliptic.P256(), pc.api.settingEngine.randomSource())
 		if err != nil {
 			return &rtcerr.UnknownError{Err: err}
 		}
-		certificate, err := GenerateCertificate(sk)
+		certificate, err := generateCertificate(sk, pc.api.settingEngine.randomSource())
 		if err != nil {
 			return err
 		}
@@ -222,6 +303,10 @@ func (pc *PeerConnection) initConfiguration(configuration Configuration) error {
 		pc.configuration.RTCPMuxPolicy = configuration.RTCPMuxPolicy
 	}
 
+	if configuration.SDPSemantics != SDPSemantics(Unknown) {
+		pc.configuration.SDPSemantics = configuration.SDPSemantics
+	}
+
 	if configuration.ICECandidatePoolSize != 0 {
 		pc.configuration.ICECandidatePoolSize = configuration.ICECandidatePoolSize
 	}
@@ -254,7 +339,7 @@ func (pc *PeerConnection) onSignalingStateChange(newState SignalingState) (done
 	hdlr := pc.onSignalingStateChangeHandler
 	pc.mu.RUnlock()
 
-	pcLog.Infof("signaling state changed to %s", newState)
+	pc.api.log.Infof("signaling state changed to %s", newState)
 	done = make(chan struct{})
 	if hdlr == nil {
 		close(done)
@@ -269,6 +354,51 @@ func (pc *PeerConnection) onSignalingStateChange(newState SignalingState) (done
 	return
 }
 
+// OnNegotiationNeeded sets an event handler which is invoked when a change
+// of the PeerConnection, such as AddTrack or RemoveTrack, must be negotiated
+// with a new offer. It is invoked once the signaling state is stable, and
+// not again for the following changes until an offer is created.
+func (pc *PeerConnection) OnNegotiationNeeded(f func()) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.onNegotiationNeededHandler = f
+}
+
+// updateNegotiationNeeded records a change an offer must negotiate
+func (pc *PeerConnection) updateNegotiationNeeded() {
+	pc.mu.Lock()
+	pc.negotiationNeeded = true
+	pc.mu.Unlock()
+	pc.checkNegotiationNeeded()
+}
+
+// checkNegotiationNeeded invokes the OnNegotiationNeeded handler if a change
+// must be negotiated and the signaling state is stable
+func (pc *PeerConnection) checkNegotiationNeeded() (done chan struct{}) {
+	done = make(chan struct{})
+	pc.mu.Lock()
+	if !pc.negotiationNeeded || pc.negotiationNeededFired || pc.SignalingState != SignalingStateStable || pc.closed() {
+		pc.mu.Unlock()
+		close(done)
+		return
+	}
+	pc.negotiationNeededFired = true
+	hdlr := pc.onNegotiationNeededHandler
+	pc.mu.Unlock()
+
+	if hdlr == nil {
+		close(done)
+		return
+	}
+
+	go func() {
+		hdlr()
+		close(done)
+	}()
+
+	return
+}
+
 // OnDataChannel sets an event handler which is invoked when a data
 // channel message arrives from a remote peer.
 func (pc *PeerConnection) OnDataChannel(f func(*DataChannel)) {
@@ -278,7 +408,10 @@ func (pc *PeerConnection) OnDataChannel(f func(*DataChannel)) {
 }
 
 // OnTrack sets an event handler which is called when remote track
-// arrives from a remote peer.
+// arrives from a remote peer. Each Track is handed to the handler on its own
+// goroutine, with no lock of the PeerConnection held: the handlers of the
+// Tracks a remote adds at once run concurrently, and may call back into the
+// PeerConnection.
 func (pc *PeerConnection) OnTrack(f func(*Track)) {
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
@@ -290,7 +423,7 @@ func (pc *PeerConnection) onTrack(t *Track) (done chan struct{}) {
 	hdlr := pc.onTrackHandler
 	pc.mu.RUnlock()
 
-	pcLog.Debugf("got new track: %+v", t)
+	pc.api.log.Debugf("got new track: %+v", t)
 	done = make(chan struct{})
 	if hdlr == nil || t == nil {
 		close(done)
@@ -305,6 +438,90 @@ func (pc *PeerConnection) onTrack(t *Track) (done chan struct{}) {
 	return
 }
 
+// OnUnhandledRTP sets an event handler which is invoked with the packets of
+// the streams no RTPReceiver reads, see DTLSTransport.OnUnhandledRTP
+func (pc *PeerConnection) OnUnhandledRTP(f func(ssrc uint32, payloadType uint8, raw []byte)) {
+	pc.dtlsTransport.OnUnhandledRTP(f)
+}
+
+// OnLimitExceeded sets an event handler which is invoked when the packets
+// of a new SSRC are dropped as the remote sends too many streams, see
+// DTLSTransport.OnLimitExceeded
+func (pc *PeerConnection) OnLimitExceeded(f func(ssrc uint32)) {
+	pc.dtlsTransport.OnLimitExceeded(f)
+}
+
+// LogTag returns the tag the messages the PeerConnection logs are prefixed
+// with, to find the logs of a connection among the others
+func (pc *PeerConnection) LogTag() string {
+	return pc.logTag
+}
+
+// OnICEGatheringError sets an event handler which is invoked for each STUN or
+// TURN server of the Configuration candidates could not be gathered from,
+// such as an unreachable server or one rejecting the credentials. The
+// candidates are gathered when the PeerConnection is created, the handler is
+// invoked right away with the errors that already happened. The errors of
+// trickled candidates are reported once their gathering is complete.
+func (pc *PeerConnection) OnICEGatheringError(f func(ICEGatheringError)) {
+	pc.mu.Lock()
+	pc.onICEGatheringErrorHandler = f
+	dataTransport := pc.dataTransport
+	pc.mu.Unlock()
+
+	pc.iceGatherer.OnError(f)
+	if dataTransport != nil {
+		dataTransport.iceGatherer.OnError(f)
+	}
+}
+
+// OnICECandidate sets an event handler which is invoked with each local
+// candidate gathered with SettingEngine.EnableTrickle, to be signaled to
+// the remote, and with nil once the gathering is complete. The gathering
+// starts with the first SetLocalDescription, the handler is invoked from
+// it in the order the candidates are gathered.
+func (pc *PeerConnection) OnICECandidate(f func(*ICECandidate)) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.onICECandidateHandler = f
+}
+
+func (pc *PeerConnection) onICECandidate(c *ICECandidate) {
+	pc.mu.RLock()
+	hdlr := pc.onICECandidateHandler
+	pc.mu.RUnlock()
+
+	if hdlr != nil {
+		hdlr(c)
+	}
+}
+
+// OnICEGatheringStateChange sets an event handler which is invoked when the
+// ICEGatheringState changes, as the trickled candidates are gathered. The
+// state is complete before OnICECandidate is invoked with nil.
+func (pc *PeerConnection) OnICEGatheringStateChange(f func(ICEGatheringState)) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.onICEGatheringStateChangeHandler = f
+}
+
+func (pc *PeerConnection) onICEGathererStateChange(state ICEGathererState) {
+	gatheringState := ICEGatheringStateGathering
+	if state == ICEGathererStateComplete {
+		gatheringState = ICEGatheringStateComplete
+	}
+
+	pc.mu.Lock()
+	pc.ICEGatheringState = gatheringState
+	hdlr := pc.onICEGatheringStateChangeHandler
+	pc.mu.Unlock()
+
+	pc.api.log.Infof("ICE gathering state changed: %s", gatheringState)
+	if hdlr != nil {
+		hdlr(gatheringState)
+	}
+}
+
 // OnICEConnectionStateChange sets an event handler which is called
 // when an ICE connection state is changed.
 func (pc *PeerConnection) OnICEConnectionStateChange(f func(ICEConnectionState)) {
@@ -318,7 +535,35 @@ func (pc *PeerConnection) onICEConnectionStateChange(cs ICEConnectionState) (don
 	hdlr := pc.onICEConnectionStateChangeHandler
 	pc.mu.RUnlock()
 
-	pcLog.Infof("ICE connection state changed: %s", cs)
+	pc.api.log.Infof("ICE connection state changed: %s", cs)
+	done = make(chan struct{})
+	if hdlr == nil {
+		close(done)
+		return
+	}
+
+	go func() {
+		hdlr(cs)
+		close(done)
+	}()
+
+	return
+}
+
+// OnConnectionStateChange sets an event handler which is called
+// when the PeerConnectionState has changed
+func (pc *PeerConnection) OnConnectionStateChange(f func(PeerConnectionState)) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.onConnectionStateChangeHandler = f
+}
+
+func (pc *PeerConnection) onConnectionStateChange(cs PeerConnectionState) (done chan struct{}) {
+	pc.mu.RLock()
+	hdlr := pc.onConnectionStateChangeHandler
+	pc.mu.RUnlock()
+
+	pc.api.log.Infof("peer connection state changed: %s", cs)
 	done = make(chan struct{})
 	if hdlr == nil {
 		close(done)
@@ -333,10 +578,68 @@ func (pc *PeerConnection) onICEConnectionStateChange(cs ICEConnectionState) (don
 	return
 }
 
+// updateConnectionState moves the PeerConnection to the given state and fires
+// OnConnectionStateChange, the error is the reason of a failure
+func (pc *PeerConnection) updateConnectionState(cs PeerConnectionState, err error) {
+	pc.mu.Lock()
+	if pc.closed() || pc.ConnectionState == cs {
+		pc.mu.Unlock()
+		return
+	}
+	pc.ConnectionState = cs
+	if cs == PeerConnectionStateFailed {
+		pc.connectionError = err
+	}
+	close(pc.connectionStateChanged)
+	pc.connectionStateChanged = make(chan struct{})
+	pc.mu.Unlock()
+
+	pc.onConnectionStateChange(cs)
+}
+
+// WaitUntilConnected blocks until the ConnectionState is connected, when
+// the ICE connection is established and the DTLS handshake completed. It
+// returns immediately once connected. ErrConnectionClosed is returned if the
+// PeerConnection is or gets closed, the ConnectionError if it failed, and
+// ctx.Err() if ctx is done first.
+func (pc *PeerConnection) WaitUntilConnected(ctx context.Context) error {
+	for {
+		pc.mu.RLock()
+		state, changed, closed, connectionError := pc.ConnectionState, pc.connectionStateChanged, pc.closed(), pc.connectionError
+		pc.mu.RUnlock()
+
+		switch {
+		case closed || state == PeerConnectionStateClosed:
+			return &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
+		case state == PeerConnectionStateConnected:
+			return nil
+		case state == PeerConnectionStateFailed:
+			if connectionError == nil {
+				return ErrConnectionFailed
+			}
+			return connectionError
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// ConnectionError returns the reason the PeerConnection failed, e.g. the
+// error of the DTLS handshake. It is nil unless the ConnectionState is failed.
+func (pc *PeerConnection) ConnectionError() error {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	return pc.connectionError
+}
+
 // SetConfiguration updates the configuration of this PeerConnection object.
 func (pc *PeerConnection) SetConfiguration(configuration Configuration) error {
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-setconfiguration (step #2)
-	if pc.isClosed {
+	if pc.closed() {
 		return &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
 
@@ -378,6 +681,13 @@ func (pc *PeerConnection) SetConfiguration(configuration Configuration) error {
 		pc.configuration.RTCPMuxPolicy = configuration.RTCPMuxPolicy
 	}
 
+	// The media sections already negotiated depend on the SDPSemantics
+	if configuration.SDPSemantics != SDPSemantics(Unknown) {
+		if configuration.SDPSemantics != pc.configuration.SDPSemantics {
+			return &rtcerr.InvalidModificationError{Err: ErrModifyingSDPSemantics}
+		}
+	}
+
 	// https://www.w3.org/TR/webrtc/#set-the-configuration (step #7)
 	if configuration.ICECandidatePoolSize != 0 {
 		if pc.configuration.ICECandidatePoolSize != configuration.ICECandidatePoolSize &&
@@ -388,7 +698,14 @@ func (pc *PeerConnection) SetConfiguration(configuration Configuration) error {
 	}
 
 	// https://www.w3.org/TR/webrtc/#set-the-configuration (step #8)
+	// The candidates are gathered again under the new policy as long as none
+	// was signaled, the next ICE restart gathers under it otherwise
 	if configuration.ICETransportPolicy != ICETransportPolicy(Unknown) {
+		if pc.LocalDescription() == nil && pc.RemoteDescription() == nil {
+			if err := pc.iceGatherer.setGatherPolicy(configuration.ICETransportPolicy); err != nil {
+				return err
+			}
+		}
 		pc.configuration.ICETransportPolicy = configuration.ICETransportPolicy
 	}
 
@@ -422,38 +739,85 @@ func (pc *PeerConnection) GetConfiguration() Configuration {
 func (pc *PeerConnection) CreateOffer(options *OfferOptions) (SessionDescription, error) {
 	useIdentity := pc.idpLoginURL != nil
 	switch {
-	case options != nil:
-		return SessionDescription{}, errors.Errorf("TODO handle options")
 	case useIdentity:
 		return SessionDescription{}, errors.Errorf("TODO handle identity provider")
-	case pc.isClosed:
+	case pc.closed():
 		return SessionDescription{}, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
+	case options != nil && options.DataChannelsOnly && pc.hasActiveTransceivers():
+		return SessionDescription{}, &rtcerr.InvalidStateError{Err: ErrDataChannelsOnlyWithTracks}
+	}
+
+	// With SDES the offer carries the keys, the answerer chooses
+	if pc.api.settingEngine.sdes {
+		if err := pc.ensureSDESKeySalt(); err != nil {
+			return SessionDescription{}, err
+		}
+	}
+
+	// The ICE sessions restart once the offer is set
+	pc.pendingICERestart = nil
+	pc.mu.RLock()
+	failedICERestart := pc.failedICERestart
+	pc.mu.RUnlock()
+	if (options != nil && options.ICERestart) || failedICERestart {
+		restart, err := pc.newICERestart()
+		if err != nil {
+			return SessionDescription{}, err
+		}
+		pc.pendingICERestart = restart
 	}
 
 	d := sdp.NewJSEPSessionDescription(useIdentity)
 	pc.addFingerprint(d)
+	pc.addICEOptions(d)
 
-	iceParams, err := pc.iceGatherer.GetLocalParameters()
+	iceParams, candidates, err := pc.offeredICE(pc.iceGatherer)
 	if err != nil {
 		return SessionDescription{}, err
 	}
 
-	candidates, err := pc.iceGatherer.GetLocalCandidates()
-	if err != nil {
-		return SessionDescription{}, err
+	var offerAnswerOptions OfferAnswerOptions
+	if options != nil {
+		offerAnswerOptions = options.OfferAnswerOptions
 	}
 
 	bundleValue := "BUNDLE"
 
-	if pc.addRTPMediaSection(d, RTPCodecTypeAudio, "audio", iceParams, RTPTransceiverDirectionSendrecv, candidates, sdp.ConnectionRoleActpass) {
-		bundleValue += " audio"
+	dataMid, dataIndex := "", 0
+	for _, section := range pc.offeredSections(options != nil && options.DataChannelsOnly) {
+		if section.kind == 0 {
+			dataMid, dataIndex = section.mid, len(d.MediaDescriptions)
+			continue
+		}
+		if pc.addRTPMediaSection(d, section.kind, section.mid, nil, offerAnswerOptions, iceParams, RTPTransceiverDirectionSendrecv, candidates, sdp.ConnectionRoleActpass) {
+			bundleValue += " " + section.mid
+		}
 	}
-	if pc.addRTPMediaSection(d, RTPCodecTypeVideo, "video", iceParams, RTPTransceiverDirectionSendrecv, candidates, sdp.ConnectionRoleActpass) {
-		bundleValue += " video"
+
+	// Bundling the data channels only matters with media sections
+	if options != nil && options.UnbundleDataChannel && bundleValue != "BUNDLE" {
+		if pc.dataTransport == nil {
+			if pc.dataTransport, err = pc.createUnbundledTransport(); err != nil {
+				return SessionDescription{}, err
+			}
+		}
+		dataICEParams, dataCandidates, err := pc.offeredICE(pc.dataTransport.iceGatherer)
+		if err != nil {
+			return SessionDescription{}, err
+		}
+		pc.addDataMediaSection(d, dataMid, dataICEParams, dataCandidates, sdp.ConnectionRoleActpass)
+		d = d.WithValueAttribute(sdp.AttrKeyGroup, bundleValue)
+	} else {
+		pc.addDataMediaSection(d, dataMid, iceParams, candidates, sdp.ConnectionRoleActpass)
+		d = d.WithValueAttribute(sdp.AttrKeyGroup, bundleValue+" "+dataMid)
 	}
 
-	pc.addDataMediaSection(d, "data", iceParams, candidates, sdp.ConnectionRoleActpass)
-	d = d.WithValueAttribute(sdp.AttrKeyGroup, bundleValue+" data")
+	// The data section keeps its position among the media sections
+	if last := len(d.MediaDescriptions) - 1; dataIndex < last {
+		data := d.MediaDescriptions[last]
+		copy(d.MediaDescriptions[dataIndex+1:], d.MediaDescriptions[dataIndex:last])
+		d.MediaDescriptions[dataIndex] = data
+	}
 
 	for _, m := range d.MediaDescriptions {
 		m.WithPropertyAttribute("setup:actpass")
@@ -464,29 +828,67 @@ func (pc *PeerConnection) CreateOffer(options *OfferOptions) (SessionDescription
 		return SessionDescription{}, err
 	}
 
-	desc := SessionDescription{
+	desc, err := pc.filterDescription(SessionDescription{
 		Type:   SDPTypeOffer,
 		SDP:    string(sdp),
 		parsed: d,
+	})
+	if err != nil {
+		return SessionDescription{}, err
 	}
 	pc.lastOffer = desc.SDP
+
+	// The offer negotiates the changes made so far, they are needed again
+	// if it is rolled back
+	pc.mu.Lock()
+	pc.offerNegotiationNeeded = pc.offerNegotiationNeeded || pc.negotiationNeeded
+	pc.negotiationNeeded, pc.negotiationNeededFired = false, false
+	pc.mu.Unlock()
+	return desc, nil
+}
+
+// filterDescription runs a generated SessionDescription through the SDP filter
+// of the SettingEngine, the filtered SDP must still parse
+func (pc *PeerConnection) filterDescription(desc SessionDescription) (SessionDescription, error) {
+	filter := pc.api.settingEngine.sdpFilter
+	if filter == nil {
+		return desc, nil
+	}
+
+	desc.SDP = filter(desc.Type, desc.SDP)
+	desc.parsed = &sdp.SessionDescription{}
+	if err := desc.parsed.Unmarshal([]byte(desc.SDP)); err != nil {
+		return SessionDescription{}, errors.Wrap(err, "failed to parse the filtered SDP")
+	}
 	return desc, nil
 }
 
 func (pc *PeerConnection) createICEGatherer() (*ICEGatherer, error) {
 	g, err := pc.api.NewICEGatherer(ICEGatherOptions{
-		ICEServers: pc.configuration.ICEServers,
-		// TODO: GatherPolicy
+		ICEServers:      pc.configuration.ICEServers,
+		ICEGatherPolicy: pc.configuration.ICETransportPolicy,
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	pc.mu.RLock()
+	g.onErrorHdlr = pc.onICEGatheringErrorHandler
+	pc.mu.RUnlock()
+
 	return g, nil
 }
 
+// gather creates the ICE agent of the PeerConnection, the candidates are
+// gathered with it unless they are trickled from SetLocalDescription
 func (pc *PeerConnection) gather() error {
-	return pc.iceGatherer.Gather()
+	if err := pc.iceGatherer.createAgent(); err != nil {
+		return err
+	}
+	if !pc.api.settingEngine.trickle {
+		pc.ICEGatheringState = ICEGatheringStateComplete
+	}
+	return nil
 }
 
 func (pc *PeerConnection) createICETransport() *ICETransport {
@@ -510,7 +912,7 @@ func (pc *PeerConnection) createICETransport() *ICETransport {
 		case ICETransportStateClosed:
 			cs = ICEConnectionStateClosed
 		default:
-			pcLog.Warnf("OnConnectionStateChange: unhandled ICE state: %s", state)
+			pc.api.log.Warnf("OnConnectionStateChange: unhandled ICE state: %s", state)
 			return
 		}
 		pc.iceStateChange(cs)
@@ -528,11 +930,9 @@ func (pc *PeerConnection) createDTLSTransport() (*DTLSTransport, error) {
 func (pc *PeerConnection) CreateAnswer(options *AnswerOptions) (SessionDescription, error) {
 	useIdentity := pc.idpLoginURL != nil
 	switch {
-	case options != nil:
-		return SessionDescription{}, errors.Errorf("TODO handle options")
 	case useIdentity:
 		return SessionDescription{}, errors.Errorf("TODO handle identity provider")
-	case pc.isClosed:
+	case pc.closed():
 		return SessionDescription{}, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
 
@@ -541,13 +941,22 @@ func (pc *PeerConnection) CreateAnswer(options *AnswerOptions) (SessionDescripti
 		return SessionDescription{}, err
 	}
 
-	candidates, err := pc.iceGatherer.GetLocalCandidates()
+	candidates, err := gatheredCandidates(pc.iceGatherer)
 	if err != nil {
 		return SessionDescription{}, err
 	}
 
+	// An SDES answer has no DTLS transport to announce
 	d := sdp.NewJSEPSessionDescription(useIdentity)
-	pc.addFingerprint(d)
+	if _, useSDES := pc.remoteSDESCrypto(pc.RemoteDescription().parsed); !useSDES {
+		pc.addFingerprint(d)
+	}
+	pc.addICEOptions(d)
+
+	var offerAnswerOptions OfferAnswerOptions
+	if options != nil {
+		offerAnswerOptions = options.OfferAnswerOptions
+	}
 
 	bundleValue := "BUNDLE"
 	for _, remoteMedia := range pc.RemoteDescription().parsed.MediaDescriptions {
@@ -573,14 +982,28 @@ func (pc *PeerConnection) CreateAnswer(options *AnswerOptions) (SessionDescripti
 
 		switch {
 		case strings.HasPrefix(*remoteMedia.MediaName.String(), "audio"):
-			if pc.addRTPMediaSection(d, RTPCodecTypeAudio, midValue, iceParams, peerDirection, candidates, sdp.ConnectionRoleActive) {
+			if pc.addRTPMediaSection(d, RTPCodecTypeAudio, midValue, remoteMedia, offerAnswerOptions, iceParams, peerDirection, candidates, sdp.ConnectionRoleActive) {
 				appendBundle()
 			}
 		case strings.HasPrefix(*remoteMedia.MediaName.String(), "video"):
-			if pc.addRTPMediaSection(d, RTPCodecTypeVideo, midValue, iceParams, peerDirection, candidates, sdp.ConnectionRoleActive) {
+			if pc.addRTPMediaSection(d, RTPCodecTypeVideo, midValue, remoteMedia, offerAnswerOptions, iceParams, peerDirection, candidates, sdp.ConnectionRoleActive) {
 				appendBundle()
 			}
 		case strings.HasPrefix(*remoteMedia.MediaName.String(), "application"):
+			// The data channels stay out of the BUNDLE group if the offer
+			// left them out
+			if dataTransport := pc.unbundledData(); dataTransport != nil && dataTransport.mid == midValue {
+				dataICEParams, err := dataTransport.iceGatherer.GetLocalParameters()
+				if err != nil {
+					return SessionDescription{}, err
+				}
+				dataCandidates, err := gatheredCandidates(dataTransport.iceGatherer)
+				if err != nil {
+					return SessionDescription{}, err
+				}
+				pc.addDataMediaSection(d, midValue, dataICEParams, dataCandidates, sdp.ConnectionRoleActive)
+				continue
+			}
 			pc.addDataMediaSection(d, midValue, iceParams, candidates, sdp.ConnectionRoleActive)
 			appendBundle()
 		}
@@ -593,10 +1016,13 @@ func (pc *PeerConnection) CreateAnswer(options *AnswerOptions) (SessionDescripti
 		return SessionDescription{}, err
 	}
 
-	desc := SessionDescription{
+	desc, err := pc.filterDescription(SessionDescription{
 		Type:   SDPTypeAnswer,
 		SDP:    string(sdp),
 		parsed: d,
+	})
+	if err != nil {
+		return SessionDescription{}, err
 	}
 	pc.lastAnswer = desc.SDP
 	return desc, nil
@@ -604,108 +1030,101 @@ func (pc *PeerConnection) CreateAnswer(options *AnswerOptions) (SessionDescripti
 
 // 4.4.1.6 Set the SessionDescription
 func (pc *PeerConnection) setDescription(sd *SessionDescription, op stateChangeOp) error {
-	if pc.isClosed {
+	if pc.closed() {
 		return &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
 
-	cur := pc.SignalingState
-	setLocal := stateChangeOpSetLocal
-	setRemote := stateChangeOpSetRemote
+	// The descriptions set out of order are rejected before comparing them
+	// with the last offer or answer
+	nextState, err := nextSignalingState(pc.SignalingState, op, sd.Type)
+	if err != nil {
+		return err
+	}
+
 	newSDPDoesNotMatchOffer := &rtcerr.InvalidModificationError{Err: errors.New("New sdp does not match previous offer")}
 	newSDPDoesNotMatchAnswer := &rtcerr.InvalidModificationError{Err: errors.New("New sdp does not match previous answer")}
 
-	var nextState SignalingState
-	var err error
 	switch op {
-	case setLocal:
+	case stateChangeOpSetLocal:
 		switch sd.Type {
 		// stable->SetLocal(offer)->have-local-offer
 		case SDPTypeOffer:
 			if sd.SDP != pc.lastOffer {
 				return newSDPDoesNotMatchOffer
 			}
-			nextState, err = checkNextSignalingState(cur, SignalingStateHaveLocalOffer, setLocal, sd.Type)
-			if err == nil {
-				pc.PendingLocalDescription = sd
-			}
+			pc.PendingLocalDescription = sd
 		// have-remote-offer->SetLocal(answer)->stable
 		// have-local-pranswer->SetLocal(answer)->stable
 		case SDPTypeAnswer:
 			if sd.SDP != pc.lastAnswer {
 				return newSDPDoesNotMatchAnswer
 			}
-			nextState, err = checkNextSignalingState(cur, SignalingStateStable, setLocal, sd.Type)
-			if err == nil {
-				pc.CurrentLocalDescription = sd
-				pc.CurrentRemoteDescription = pc.PendingRemoteDescription
-				pc.PendingRemoteDescription = nil
-				pc.PendingLocalDescription = nil
-			}
+			pc.CurrentLocalDescription = sd
+			pc.CurrentRemoteDescription = pc.PendingRemoteDescription
+			pc.PendingRemoteDescription = nil
+			pc.PendingLocalDescription = nil
+		// have-local-offer->SetLocal(rollback)->stable
 		case SDPTypeRollback:
-			nextState, err = checkNextSignalingState(cur, SignalingStateStable, setLocal, sd.Type)
-			if err == nil {
-				pc.PendingLocalDescription = nil
-			}
+			pc.PendingLocalDescription = nil
+
+			// The changes the abandoned offer carried must be offered again
+			pc.mu.Lock()
+			pc.negotiationNeeded = pc.negotiationNeeded || pc.offerNegotiationNeeded
+			pc.offerNegotiationNeeded = false
+			pc.mu.Unlock()
 		// have-remote-offer->SetLocal(pranswer)->have-local-pranswer
 		case SDPTypePranswer:
 			if sd.SDP != pc.lastAnswer {
 				return newSDPDoesNotMatchAnswer
 			}
-			nextState, err = checkNextSignalingState(cur, SignalingStateHaveLocalPranswer, setLocal, sd.Type)
-			if err == nil {
-				pc.PendingLocalDescription = sd
-			}
-		default:
-			return &rtcerr.OperationError{Err: fmt.Errorf("invalid state change op: %s(%s)", op, sd.Type)}
+			pc.PendingLocalDescription = sd
 		}
-	case setRemote:
+	case stateChangeOpSetRemote:
 		switch sd.Type {
 		// stable->SetRemote(offer)->have-remote-offer
-		case SDPTypeOffer:
-			nextState, err = checkNextSignalingState(cur, SignalingStateHaveRemoteOffer, setRemote, sd.Type)
-			if err == nil {
-				pc.PendingRemoteDescription = sd
-			}
+		// have-local-offer->SetRemote(pranswer)->have-remote-pranswer
+		case SDPTypeOffer, SDPTypePranswer:
+			pc.PendingRemoteDescription = sd
 		// have-local-offer->SetRemote(answer)->stable
 		// have-remote-pranswer->SetRemote(answer)->stable
 		case SDPTypeAnswer:
-			nextState, err = checkNextSignalingState(cur, SignalingStateStable, setRemote, sd.Type)
-			if err == nil {
-				pc.CurrentRemoteDescription = sd
-				pc.CurrentLocalDescription = pc.PendingLocalDescription
-				pc.PendingRemoteDescription = nil
-				pc.PendingLocalDescription = nil
-			}
-		case SDPTypeRollback:
-			nextState, err = checkNextSignalingState(cur, SignalingStateStable, setRemote, sd.Type)
-			if err == nil {
-				pc.PendingRemoteDescription = nil
-			}
-		// have-local-offer->SetRemote(pranswer)->have-remote-pranswer
-		case SDPTypePranswer:
-			nextState, err = checkNextSignalingState(cur, SignalingStateHaveRemotePranswer, setRemote, sd.Type)
-			if err == nil {
-				pc.PendingRemoteDescription = sd
-			}
-		default:
-			return &rtcerr.OperationError{Err: fmt.Errorf("invalid state change op: %s(%s)", op, sd.Type)}
+			pc.CurrentRemoteDescription = sd
+			pc.CurrentLocalDescription = pc.PendingLocalDescription
+			pc.PendingRemoteDescription = nil
+			pc.PendingLocalDescription = nil
+			pc.mu.Lock()
+			pc.offerNegotiationNeeded = false
+			pc.mu.Unlock()
 		}
-	default:
-		return &rtcerr.OperationError{Err: fmt.Errorf("unhandled state change op: %q", op)}
 	}
 
-	if err == nil {
-		pc.SignalingState = nextState
-		pc.onSignalingStateChange(nextState)
+	pc.SignalingState = nextState
+	if nextState == SignalingStateStable && pc.CurrentLocalDescription != nil && pc.CurrentRemoteDescription != nil {
+		pc.updateCurrentDirections()
+		pc.updateRTP()
+	}
+	pc.onSignalingStateChange(nextState)
+	if nextState == SignalingStateStable {
+		pc.checkNegotiationNeeded()
 	}
-	return err
+	return nil
 }
 
-// SetLocalDescription sets the SessionDescription of the local peer
+// SetLocalDescription sets the SessionDescription of the local peer. A
+// description of type SDPTypeRollback, which needs no SDP, abandons the
+// negotiation of the local offer: the pending description is discarded and
+// the signaling state is stable again, such as to back off when both peers
+// offered at once. The changes the offer carried fire OnNegotiationNeeded
+// again. A pranswer can't be rolled back, an InvalidStateError is returned. Setting the local offer changes none of
+// the RTPTransceivers, they are left as they are. An offer created with
+// OfferOptions.ICERestart restarts ICE when it is set.
 func (pc *PeerConnection) SetLocalDescription(desc SessionDescription) error {
-	if pc.isClosed {
+	if pc.closed() {
 		return &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
+	if _, err := nextSignalingState(pc.SignalingState, stateChangeOpSetLocal, desc.Type); err != nil {
+		return err
+	}
 
 	// JSEP 5.4
 	if desc.SDP == "" {
@@ -714,6 +1133,7 @@ func (pc *PeerConnection) SetLocalDescription(desc SessionDescription) error {
 			desc.SDP = pc.lastAnswer
 		case SDPTypeOffer:
 			desc.SDP = pc.lastOffer
+		case SDPTypeRollback:
 		default:
 			return &rtcerr.InvalidModificationError{
 				Err: fmt.Errorf("invalid SDP type supplied to SetLocalDescription(): %s", desc.Type),
@@ -721,13 +1141,30 @@ func (pc *PeerConnection) SetLocalDescription(desc SessionDescription) error {
 		}
 	}
 
-	// TODO: Initiate ICE candidate gathering?
+	// A rollback carries no SDP, the pending description is discarded and
+	// the ICE sessions its offer restarted brought back
+	if desc.Type == SDPTypeRollback {
+		if err := pc.setDescription(&desc, stateChangeOpSetLocal); err != nil {
+			return err
+		}
+		return pc.rollbackICERestart()
+	}
 
 	desc.parsed = &sdp.SessionDescription{}
 	if err := desc.parsed.Unmarshal([]byte(desc.SDP)); err != nil {
 		return err
 	}
-	return pc.setDescription(&desc, stateChangeOpSetLocal)
+	if err := pc.setDescription(&desc, stateChangeOpSetLocal); err != nil {
+		return err
+	}
+	if desc.Type == SDPTypeOffer {
+		if err := pc.applyICERestart(&desc); err != nil {
+			return err
+		}
+	}
+
+	// The trickled candidates are gathered once, from the first description
+	return pc.iceGatherer.Gather()
 }
 
 // LocalDescription returns PendingLocalDescription if it is not null and
@@ -741,32 +1178,73 @@ func (pc *PeerConnection) LocalDescription() *SessionDescription {
 	return pc.CurrentLocalDescription
 }
 
-// SetRemoteDescription sets the SessionDescription of the remote peer
+// SetRemoteDescription sets the SessionDescription of the remote peer. The
+// transports are started with the first one, the following ones renegotiate
+// the media over them: their candidates are ignored unless their ICE
+// credentials changed, which restarts ICE with the new credentials and
+// candidates. It can't be rolled back and a description of type SDPTypeRollback fails with
+// ErrRemoteRollback.
 func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
-	// FIXME: Remove this when renegotiation is supported
-	if pc.CurrentRemoteDescription != nil {
-		return errors.Errorf("remoteDescription is already defined, SetRemoteDescription can only be called once")
-	}
-	if pc.isClosed {
+	if pc.closed() {
 		return &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
+	if desc.Type == SDPTypeRollback {
+		return &rtcerr.InvalidModificationError{Err: ErrRemoteRollback}
+	}
+	if _, err := nextSignalingState(pc.SignalingState, stateChangeOpSetRemote, desc.Type); err != nil {
+		return err
+	}
 
 	desc.parsed = &sdp.SessionDescription{}
 	if err := desc.parsed.Unmarshal([]byte(desc.SDP)); err != nil {
 		return err
 	}
+	renegotiation := pc.sctpTransport != nil
 	if err := pc.setDescription(&desc, stateChangeOpSetRemote); err != nil {
 		return err
 	}
+	if desc.Type == SDPTypeAnswer {
+		pc.appliedICERestart = nil
+	}
+	var reducedSizeRTCP int32
+	if pc.negotiatedReducedSizeRTCP() {
+		reducedSizeRTCP = 1
+	}
+	atomic.StoreInt32(&pc.reducedSizeRTCP, reducedSizeRTCP)
 
 	weOffer := true
 	remoteUfrag := ""
 	remotePwd := ""
 	if desc.Type == SDPTypeOffer {
 		weOffer = false
+		pc.matchRemoteMids(desc.parsed)
+	}
+	if renegotiation {
+		return pc.restartRemoteICE(&desc)
+	}
+
+	// The data channels get their own transport when the application
+	// section isn't bundled with the media
+	dataMid, unbundled := unbundledDataMid(desc.parsed)
+	if unbundled {
+		if pc.dataTransport == nil {
+			dataTransport, err := pc.createUnbundledTransport()
+			if err != nil {
+				return err
+			}
+			pc.dataTransport = dataTransport
+		}
+		pc.dataTransport.mid = dataMid
 	}
+	var dataICEParams ICEParameters
 
 	for _, m := range pc.RemoteDescription().parsed.MediaDescriptions {
+		iceTransport, ufrag, pwd := pc.iceTransport, &remoteUfrag, &remotePwd
+		if mid, _ := m.Attribute(sdp.AttrKeyMID); unbundled && mid == dataMid {
+			iceTransport = pc.dataTransport.iceTransport
+			ufrag, pwd = &dataICEParams.UsernameFragment, &dataICEParams.Password
+		}
+
 		for _, a := range m.Attributes {
 			switch {
 			case a.IsICECandidate():
@@ -779,56 +1257,108 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 				if err != nil {
 					return err
 				}
+				// RTCP is multiplexed, the RTCP candidates of a remote that
+				// can fall back to separate RTCP are never used
+				if candidate.Component == uint16(ICEComponentRTCP) {
+					continue
+				}
 
-				if err = pc.iceTransport.AddRemoteCandidate(candidate); err != nil {
+				if err = iceTransport.AddRemoteCandidate(candidate); err != nil {
 					return err
 				}
 			case strings.HasPrefix(*a.String(), "ice-ufrag"):
-				remoteUfrag = (*a.String())[len("ice-ufrag:"):]
+				*ufrag = (*a.String())[len("ice-ufrag:"):]
 			case strings.HasPrefix(*a.String(), "ice-pwd"):
-				remotePwd = (*a.String())[len("ice-pwd:"):]
+				*pwd = (*a.String())[len("ice-pwd:"):]
 			}
 		}
 	}
 
-	fingerprint, ok := desc.parsed.Attribute("fingerprint")
-	if !ok {
-		fingerprint, ok = desc.parsed.MediaDescriptions[0].Attribute("fingerprint")
-		if !ok {
+	// Without a fingerprint the keys of the remote can come from its a=crypto
+	// lines, the DTLS handshake is skipped
+	remoteCrypto, useSDES := pc.remoteSDESCrypto(desc.parsed)
+	var dtlsFingerprints []DTLSFingerprint
+	if useSDES {
+		if err := pc.ensureSDESKeySalt(); err != nil {
+			return err
+		}
+	} else {
+		// The remote can announce a fingerprint per hash function
+		var err error
+		if dtlsFingerprints, err = fingerprintsFromAttributes(desc.parsed.Attributes); err != nil {
+			return err
+		}
+		if len(dtlsFingerprints) == 0 {
+			if dtlsFingerprints, err = fingerprintsFromAttributes(desc.parsed.MediaDescriptions[0].Attributes); err != nil {
+				return err
+			}
+		}
+		if len(dtlsFingerprints) == 0 {
 			return errors.New("could not find fingerprint")
 		}
 	}
-	var fingerprintHash string
-	parts := strings.Split(fingerprint, " ")
-	if len(parts) != 2 {
-		return errors.New("invalid fingerprint")
-	}
-	fingerprint = parts[1]
-	fingerprintHash = parts[0]
 
 	// Create the SCTP transport
-	sctp := pc.api.NewSCTPTransport(pc.dtlsTransport)
+	sctpDTLSTransport := pc.dtlsTransport
+	if unbundled {
+		sctpDTLSTransport = pc.dataTransport.dtlsTransport
+	}
+	sctp := pc.api.NewSCTPTransport(sctpDTLSTransport)
 	pc.sctpTransport = sctp
 
 	// Wire up the on datachannel handler
 	sctp.OnDataChannel(func(d *DataChannel) {
-		pc.mu.RLock()
+		pc.mu.Lock()
+		pc.remoteDataChannels = append(pc.remoteDataChannels, d)
 		hdlr := pc.onDataChannelHandler
-		pc.mu.RUnlock()
+		pc.mu.Unlock()
+		d.onClosed(pc.removeRemoteDataChannel)
 		if hdlr != nil {
 			hdlr(d)
 		}
 	})
 
+	// The offerer controls the ICE sessions
+	iceRole := ICERoleControlled
+	if weOffer {
+		iceRole = ICERoleControlling
+	}
+
+	// The data channels need DTLS
+	if unbundled && !useSDES {
+		// A media section can announce the fingerprint of the data one
+		dataFingerprints := dtlsFingerprints
+		for _, m := range desc.parsed.MediaDescriptions {
+			if mid, _ := m.Attribute(sdp.AttrKeyMID); mid != dataMid {
+				continue
+			}
+			fingerprints, err := fingerprintsFromAttributes(m.Attributes)
+			if err != nil {
+				return err
+			}
+			if len(fingerprints) != 0 {
+				dataFingerprints = fingerprints
+			}
+		}
+
+		go func() {
+			err := pc.dataTransport.start(dataICEParams, iceRole, DTLSParameters{
+				Role:         DTLSRoleAuto,
+				Fingerprints: dataFingerprints,
+			})
+			if err != nil {
+				pc.api.log.Warnf("Failed to start the data channel transport: %s", err)
+				return
+			}
+			pc.startSCTP()
+		}()
+	}
+
 	go func() {
 		// Star the networking in a new routine since it will block until
 		// the connection is actually established.
 
 		// Start the ice transport
-		iceRole := ICERoleControlled
-		if weOffer {
-			iceRole = ICERoleControlling
-		}
 		err := pc.iceTransport.Start(
 			pc.iceGatherer,
 			ICEParameters{
@@ -841,53 +1371,148 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 
 		if err != nil {
 			// TODO: Handle error
-			pcLog.Warnf("Failed to start manager: %s", err)
+			pc.api.log.Warnf("Failed to start manager: %s", err)
 			return
 		}
 
 		// Start the dtls transport
-		err = pc.dtlsTransport.Start(DTLSParameters{
-			Role:         DTLSRoleAuto,
-			Fingerprints: []DTLSFingerprint{{Algorithm: fingerprintHash, Value: fingerprint}},
-		})
+		if useSDES {
+			err = pc.dtlsTransport.startSDES(sdesCrypto{keySalt: pc.sdesKeySalt}, remoteCrypto)
+		} else {
+			err = pc.dtlsTransport.Start(DTLSParameters{
+				Role:         DTLSRoleAuto,
+				Fingerprints: dtlsFingerprints,
+			})
+		}
 		if err != nil {
-			// TODO: Handle error
-			pcLog.Warnf("Failed to start manager: %s", err)
+			pc.api.log.Warnf("Failed to start DTLS transport: %s", err)
+			pc.updateConnectionState(PeerConnectionStateFailed, err)
 			return
 		}
+		pc.updateConnectionState(PeerConnectionStateConnected, nil)
 
-		if pc.onTrackHandler != nil {
-			pc.openSRTP()
-		} else {
-			pcLog.Warnf("OnTrack unset, unable to handle incoming media streams")
+		rtxPayloadTypes := map[uint8]bool{}
+		for _, kind := range []RTPCodecType{RTPCodecTypeAudio, RTPCodecTypeVideo} {
+			for rtx := range pc.negotiatedRTXPayloadTypes(kind) {
+				rtxPayloadTypes[rtx] = true
+			}
 		}
+		router := newRTPRouter(pc.negotiatedExtensionIDs(MIDURI), pc.negotiatedExtensionIDs(RIDURI), pc.negotiatedExtensionIDs(RepairedRIDURI), rtxPayloadTypes)
+		pc.startAnnouncedReceivers()
+		pc.rtpLock.Lock()
+		pc.router = router
+		pc.startRTP()
+		pc.rtpLock.Unlock()
 
-		for _, tranceiver := range pc.rtpTransceivers {
-			if tranceiver.Sender != nil {
-				tranceiver.Sender.Send(RTPSendParameters{
-					encodings: RTPEncodingParameters{
-						RTPCodingParameters{SSRC: tranceiver.Sender.Track.SSRC, PayloadType: tranceiver.Sender.Track.PayloadType},
-					}})
-			}
+		go router.run(pc.dtlsTransport)
+
+		if !unbundled && !useSDES {
+			pc.startSCTP()
 		}
+	}()
 
-		go pc.drainSRTP()
+	return nil
+}
+
+// updateRTP starts and stops the RTPSenders and RTPReceivers after a
+// negotiation, once the DTLS transport connected
+func (pc *PeerConnection) updateRTP() {
+	pc.rtpLock.Lock()
+	defer pc.rtpLock.Unlock()
+	if pc.router != nil {
+		pc.startRTP()
+	}
+}
 
-		// Start sctp
-		err = pc.sctpTransport.Start(SCTPCapabilities{
-			MaxMessageSize: 0,
+// startRTP starts the RTPReceivers of the streams of the RemoteDescription
+// and the RTPSenders of the negotiated media sections, the ones already
+// started are left as they are. The RTPReceivers of the streams the remote
+// no longer sends are stopped. The caller holds rtpLock.
+func (pc *PeerConnection) startRTP() {
+	if pc.onTrackHandler != nil {
+		pc.openSRTP(pc.router)
+	} else {
+		pc.api.log.Warnf("OnTrack unset, unable to handle incoming media streams")
+	}
+
+	for _, tranceiver := range pc.transceivers() {
+		sender := tranceiver.Sender()
+		if !tranceiver.isSending() || sender.hasStarted() || !pc.isMidNegotiated(tranceiver.Mid()) {
+			continue
+		}
+		payloadType := pc.negotiatedPayloadType(sender.Track)
+		rtxPayloadType, _ := rtxPayloadTypeFor(pc.negotiatedRTXPayloadTypes(sender.Track.Kind), payloadType)
+		redPayloadType, _ := rtxPayloadTypeFor(pc.negotiatedREDPayloadTypes(sender.Track.Kind), payloadType)
+		sender.Send(RTPSendParameters{
+			Encodings: RTPEncodingParameters{
+				RTPCodingParameters{
+					SSRC:        sender.Track.SSRC,
+					PayloadType: payloadType,
+					RTX:         RTPRtxParameters{SSRC: sender.Track.rtxSSRC},
+				},
+			},
+			HeaderExtensions: pc.negotiatedHeaderExtensions(sender.Track.Kind),
+			mid:              tranceiver.Mid(),
+			RTCPFeedback:     pc.negotiatedRTCPFeedback(sender.Track.Kind),
+			reducedSizeRTCP:  pc.negotiatedReducedSizeRTCP(sender.Track.Kind),
+			rtxPayloadType:   rtxPayloadType,
+			redPayloadType:   redPayloadType,
+			maxBitrate:       pc.negotiatedMaxBitrate(sender.Track.Kind),
 		})
-		if err != nil {
-			// TODO: Handle error
-			pcLog.Warnf("Failed to start SCTP: %s", err)
+	}
+}
+
+// isMidNegotiated returns whether the current local description has an
+// accepted media section with the mid
+func (pc *PeerConnection) isMidNegotiated(mid string) bool {
+	desc := pc.CurrentLocalDescription
+	if desc == nil || desc.parsed == nil {
+		return false
+	}
+	for _, media := range desc.parsed.MediaDescriptions {
+		if m, _ := media.Attribute(sdp.AttrKeyMID); m == mid {
+			return media.MediaName.Port.Value != 0
+		}
+	}
+	return false
+}
+
+// removeRemoteDataChannel forgets a DataChannel the remote opened once it is
+// closed, GetStats no longer reports it
+func (pc *PeerConnection) removeRemoteDataChannel(d *DataChannel) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	for i, remote := range pc.remoteDataChannels {
+		if remote == d {
+			pc.remoteDataChannels = append(pc.remoteDataChannels[:i], pc.remoteDataChannels[i+1:]...)
 			return
 		}
+	}
+}
 
-		// Open data channels that where created before signaling
-		pc.openDataChannels()
-	}()
+// startSCTP starts the SCTP transport once its DTLS transport is connected
+func (pc *PeerConnection) startSCTP() {
+	// The remote may open the streams of the negotiated data channels before
+	// they are opened here, they are kept for them once accepted
+	pc.mu.RLock()
+	for id, d := range pc.dataChannels {
+		if d.Negotiated {
+			pc.sctpTransport.registerNegotiated(id)
+		}
+	}
+	pc.mu.RUnlock()
 
-	return nil
+	err := pc.sctpTransport.Start(SCTPCapabilities{
+		MaxMessageSize: 0,
+	})
+	if err != nil {
+		// TODO: Handle error
+		pc.api.log.Warnf("Failed to start SCTP: %s", err)
+		return
+	}
+
+	// Open data channels that where created before signaling
+	pc.openDataChannels()
 }
 
 // openDataChannels opens the existing data channels
@@ -895,145 +1520,266 @@ func (pc *PeerConnection) openDataChannels() {
 	for _, d := range pc.dataChannels {
 		err := d.open(pc.sctpTransport)
 		if err != nil {
-			pcLog.Warnf("failed to open data channel: %s", err)
+			pc.api.log.Warnf("failed to open data channel: %s", err)
 			continue
 		}
 	}
 }
 
-// openSRTP opens knows inbound SRTP streams from the RemoteDescription
-func (pc *PeerConnection) openSRTP() {
+// openSRTP opens knows inbound SRTP streams from the RemoteDescription. The
+// media sections sending without a=ssrc get a RTPReceiver fed by the router:
+// with the stream carrying their mid if the mid header extension was
+// negotiated, otherwise the first one gets the first undeclared stream.
+func (pc *PeerConnection) openSRTP(router *rtpRouter) {
 	incomingSSRCes := map[uint32]RTPCodecType{}
-
+	incomingMids := map[uint32]string{}
+	incomingRTX := map[uint32]uint32{}
+	incomingLayers := map[string]map[string]RIDRestrictions{}
+
+	latchingMid := ""
+	latchingCodecType := RTPCodecType(0)
+	routedMids := map[string]RTPCodecType{}
+	routedRIDs := map[string][]string{}
+	routedRestrictions := map[string]map[string]RIDRestrictions{}
 	for _, media := range pc.RemoteDescription().parsed.MediaDescriptions {
-		for _, attr := range media.Attributes {
-			var codecType RTPCodecType
-			switch media.MediaName.Media {
-			case "audio":
-				codecType = RTPCodecTypeAudio
-			case "video":
-				codecType = RTPCodecTypeVideo
-			default:
-				continue
-			}
+		var codecType RTPCodecType
+		switch media.MediaName.Media {
+		case "audio":
+			codecType = RTPCodecTypeAudio
+		case "video":
+			codecType = RTPCodecTypeVideo
+		default:
+			continue
+		}
+
+		mid, _ := media.Attribute(sdp.AttrKeyMID)
 
+		// The RTX SSRCs are received along their media SSRC
+		rtxSSRCs := map[uint32]bool{}
+		for ssrc, rtx := range rtxSSRCsFromMedia(media) {
+			incomingRTX[ssrc] = rtx
+			rtxSSRCs[rtx] = true
+		}
+
+		hasSSRC := false
+		for _, attr := range media.Attributes {
 			if attr.Key == sdp.AttrKeySSRC {
+				hasSSRC = true
 				ssrc, err := strconv.ParseUint(strings.Split(attr.Value, " ")[0], 10, 32)
 				if err != nil {
-					pcLog.Warnf("Failed to parse SSRC: %v", err)
+					pc.api.log.Warnf("Failed to parse SSRC: %v", err)
+					continue
+				}
+				if rtxSSRCs[uint32(ssrc)] {
 					continue
 				}
 
 				incomingSSRCes[uint32(ssrc)] = codecType
+				incomingMids[uint32(ssrc)] = mid
 			}
 		}
-	}
 
-	for i := range incomingSSRCes {
-		go func(ssrc uint32, codecType RTPCodecType) {
-			receiver := pc.api.NewRTPReceiver(codecType, pc.dtlsTransport)
-			<-receiver.Receive(RTPReceiveParameters{
-				encodings: RTPDecodingParameters{
-					RTPCodingParameters{SSRC: ssrc},
-				}})
+		// The declared SSRCs of a simulcast media section are its layers
+		if hasSSRC {
+			_, ridNegotiated := pc.negotiatedHeaderExtensions(codecType)[RIDURI]
+			incomingLayers[mid] = pc.receivedLayers(media, ridNegotiated)
+		}
 
-			sdpCodec, err := pc.CurrentLocalDescription.parsed.GetCodecForPayloadType(receiver.Track.PayloadType)
-			if err != nil {
-				pcLog.Warnf("no codec could be found in RemoteDescription for payloadType %d", receiver.Track.PayloadType)
-				return
+		if !hasSSRC && remoteSends(media) {
+			if len(router.midExtensionIDs) != 0 && mid != "" {
+				routedMids[mid] = codecType
+				_, ridNegotiated := pc.negotiatedHeaderExtensions(codecType)[RIDURI]
+				routedRIDs[mid] = pc.receivedRIDs(media, ridNegotiated)
+				routedRestrictions[mid] = ridsFromMedia(media, simulcastDirectionSend)
+				continue
 			}
-
-			codec, err := pc.api.mediaEngine.getCodecSDP(sdpCodec)
-			if err != nil {
-				pcLog.Warnf("codec %s in not registered", sdpCodec)
-				return
+			if latchingCodecType != 0 {
+				pc.api.log.Warnf("Only one media section without SSRC can be received, ignoring %s", mid)
+				continue
 			}
+			latchingMid, latchingCodecType = mid, codecType
+		}
+	}
 
-			receiver.Track.Kind = codec.Type
-			receiver.Track.Codec = codec
-			pc.newRTPTransceiver(
-				receiver,
-				nil,
-				RTPTransceiverDirectionRecvonly,
-			)
+	// The SSRCs announced with ReceiveSSRC have their RTPReceiver already
+	if pc.ssrcReceivers == nil {
+		pc.ssrcReceivers = map[uint32]*RTPReceiver{}
+	}
+	for ssrc, codecType := range incomingSSRCes {
+		if pc.isSSRCAnnounced(ssrc) || pc.ssrcReceivers[ssrc] != nil {
+			continue
+		}
+		receiver := pc.api.NewRTPReceiver(codecType, pc.dtlsTransport)
+		pc.startSSRCReceiver(receiver, ssrc, incomingRTX[ssrc], incomingMids[ssrc], incomingLayers[incomingMids[ssrc]])
+		pc.ssrcReceivers[ssrc] = receiver
+	}
+	for ssrc, receiver := range pc.ssrcReceivers {
+		if _, ok := incomingSSRCes[ssrc]; !ok && len(pc.stopRemovedReceivers([]*RTPReceiver{receiver})) == 0 {
+			delete(pc.ssrcReceivers, ssrc)
+		}
+	}
 
-			pc.onTrack(receiver.Track)
-		}(i, incomingSSRCes[i])
+	// A simulcast media section gets a RTPReceiver for each requested layer
+	if pc.routedReceivers == nil {
+		pc.routedReceivers = map[string][]*RTPReceiver{}
+	}
+	for mid, codecType := range routedMids {
+		if len(pc.routedReceivers[mid]) != 0 {
+			continue
+		}
+		var receivers []*RTPReceiver
+		if len(routedRIDs[mid]) == 0 {
+			receivers = appendReceiver(receivers, pc.startRoutedReceiver(codecType, mid, RTPCodingParameters{}, router.addReceiver(mid, ""), router.addRTXReceiver(mid, "")))
+		}
+		for _, rid := range routedRIDs[mid] {
+			encoding := RTPCodingParameters{RID: rid, RIDRestrictions: routedRestrictions[mid][rid]}
+			receivers = appendReceiver(receivers, pc.startRoutedReceiver(codecType, mid, encoding, router.addReceiver(mid, rid), router.addRTXReceiver(mid, rid)))
+		}
+		pc.routedReceivers[mid] = receivers
+	}
+	for mid, receivers := range pc.routedReceivers {
+		if _, ok := routedMids[mid]; !ok {
+			pc.routedReceivers[mid] = pc.stopRemovedReceivers(receivers)
+		}
 	}
 
+	switch {
+	case latchingCodecType == 0:
+		pc.latchingReceivers = pc.stopRemovedReceivers(pc.latchingReceivers)
+	case len(pc.latchingReceivers) == 0:
+		pc.latchingReceivers = appendReceiver(nil, pc.startRoutedReceiver(latchingCodecType, latchingMid, RTPCodingParameters{}, router.addLatchingReceiver(), router.addLatchingRTXReceiver()))
+	}
+}
+
+// appendReceiver appends a started RTPReceiver, nil if it failed to start
+func appendReceiver(receivers []*RTPReceiver, receiver *RTPReceiver) []*RTPReceiver {
+	if receiver == nil {
+		return receivers
+	}
+	return append(receivers, receiver)
+}
+
+// stopRemovedReceivers stops the RTPReceivers of the streams the remote no
+// longer sends and closes their Track. Their ReadLoops, blocked on the closed
+// streams, return with the SRTP session. It returns the RTPReceivers that got
+// no stream yet, they are kept waiting for it in case the remote sends it
+// again.
+func (pc *PeerConnection) stopRemovedReceivers(receivers []*RTPReceiver) []*RTPReceiver {
+	var waiting []*RTPReceiver
+	for _, receiver := range receivers {
+		select {
+		case <-receiver.hasRecv:
+		default:
+			waiting = append(waiting, receiver)
+			continue
+		}
+		if err := receiver.stop(); err != nil {
+			pc.api.log.Debugf("Failed to stop the RTPReceiver of a removed stream: %v", err)
+		}
+		if err := receiver.Track.Close(); err != nil {
+			pc.api.log.Debugf("Failed to close the Track of a removed stream: %v", err)
+		}
+	}
+	return waiting
 }
 
-// drainSRTP pulls and discards RTP/RTCP packets that don't match any SRTP
-// These could be sent to the user, but right now we don't provide an API
-// to distribute orphaned RTCP messages. This is needed to make sure we don't block
-// and provides useful debugging messages
-func (pc *PeerConnection) drainSRTP() {
+// startSSRCReceiver starts a RTPReceiver for a stream whose SSRC is known,
+// declared by the RemoteDescription or announced with ReceiveSSRC. The
+// stream is claimed before it returns, the router never hands it out. The
+// layers are the received ones of a simulcast media section, nil otherwise.
+func (pc *PeerConnection) startSSRCReceiver(receiver *RTPReceiver, ssrc, rtxSSRC uint32, mid string, layers map[string]RIDRestrictions) {
+	codecType := receiver.kind
+	hasRecv, err := receiver.Receive(RTPReceiveParameters{
+		Encodings: RTPDecodingParameters{
+			RTPCodingParameters{SSRC: ssrc, RTX: RTPRtxParameters{SSRC: rtxSSRC}},
+		},
+		HeaderExtensions: pc.negotiatedHeaderExtensions(codecType),
+		RTCPFeedback:     pc.negotiatedRTCPFeedback(codecType),
+		reducedSizeRTCP:  pc.negotiatedReducedSizeRTCP(codecType),
+		rtxPayloadTypes:  pc.negotiatedRTXPayloadTypes(codecType),
+		redPayloadTypes:  pc.negotiatedREDPayloadTypes(codecType),
+		layers:           layers,
+	})
+	if err != nil {
+		pc.api.log.Warnf("Failed to start RTPReceiver for %d: %v", ssrc, err)
+		return
+	}
+
 	go func() {
-		for {
-			srtpSession, err := pc.dtlsTransport.getSRTPSession()
-			if err != nil {
-				pcLog.Warnf("drainSRTP failed to open SrtpSession: %v", err)
-				return
-			}
+		<-hasRecv
+		pc.onReceiverStarted(receiver, mid)
+	}()
+}
 
-			r, ssrc, err := srtpSession.AcceptStream()
-			if err != nil {
-				pcLog.Warnf("Failed to accept RTP %v \n", err)
-				return
-			}
+// startRoutedReceiver starts a RTPReceiver for a media section without
+// a=ssrc, or one of its simulcast layers, it receives the stream the router
+// delivers. The encoding holds the RID of the layer and its restrictions. It
+// returns nil if the RTPReceiver failed to start.
+func (pc *PeerConnection) startRoutedReceiver(codecType RTPCodecType, mid string, encoding RTPCodingParameters, streams, rtxStreams <-chan routedStream) *RTPReceiver {
+	receiver := pc.api.NewRTPReceiver(codecType, pc.dtlsTransport)
+	receiver.routedStreams = streams
+	receiver.routedRTXStreams = rtxStreams
+	hasRecv, err := receiver.Receive(RTPReceiveParameters{
+		Encodings:        RTPDecodingParameters{encoding},
+		HeaderExtensions: pc.negotiatedHeaderExtensions(codecType),
+		RTCPFeedback:     pc.negotiatedRTCPFeedback(codecType),
+		reducedSizeRTCP:  pc.negotiatedReducedSizeRTCP(codecType),
+		rtxPayloadTypes:  pc.negotiatedRTXPayloadTypes(codecType),
+		redPayloadTypes:  pc.negotiatedREDPayloadTypes(codecType),
+	})
+	if err != nil {
+		pc.api.log.Warnf("Failed to start RTPReceiver for %s: %v", mid, err)
+		return nil
+	}
 
-			go func() {
-				rtpBuf := make([]byte, receiveMTU)
-				rtpPacket := &rtp.Packet{}
-
-				for {
-					i, err := r.Read(rtpBuf)
-					if err != nil {
-						pcLog.Warnf("Failed to read, drainSRTP done for: %v %d \n", err, ssrc)
-						return
-					}
-
-					if err := rtpPacket.Unmarshal(rtpBuf[:i]); err != nil {
-						pcLog.Warnf("Failed to unmarshal RTP packet, discarding: %v \n", err)
-						continue
-					}
-					pcLog.Debugf("got RTP: %+v", rtpPacket)
-				}
-			}()
-		}
+	go func() {
+		<-hasRecv
+		pc.onReceiverStarted(receiver, mid)
 	}()
+	return receiver
+}
 
-	for {
-		srtcpSession, err := pc.dtlsTransport.getSRTCPSession()
-		if err != nil {
-			pcLog.Warnf("drainSRTP failed to open SrtcpSession: %v", err)
-			return
-		}
+// onReceiverStarted resolves the codec of a RTPReceiver that got its first
+// packet, creates its RTPTransceiver and fires OnTrack
+func (pc *PeerConnection) onReceiverStarted(receiver *RTPReceiver, mid string) {
+	sdpCodec, err := pc.CurrentLocalDescription.parsed.GetCodecForPayloadType(receiver.Track.PayloadType)
+	if err != nil {
+		pc.api.log.Warnf("no codec could be found in RemoteDescription for payloadType %d", receiver.Track.PayloadType)
+		return
+	}
 
-		r, ssrc, err := srtcpSession.AcceptStream()
-		if err != nil {
-			pcLog.Warnf("Failed to accept RTCP %v \n", err)
-			return
-		}
+	codec, err := pc.api.mediaEngine.getCodecSDP(sdpCodec)
+	if err != nil {
+		pc.api.log.Warnf("codec %s in not registered", sdpCodec)
+		return
+	}
 
-		go func() {
-			rtcpBuf := make([]byte, receiveMTU)
-			for {
-				i, err := r.Read(rtcpBuf)
-				if err != nil {
-					pcLog.Warnf("Failed to read, drainSRTCP done for: %v %d \n", err, ssrc)
-					return
-				}
+	receiver.Track.Kind = codec.Type
+	receiver.Track.Codec = codec
+	t := pc.newRTPTransceiver(
+		receiver,
+		nil,
+		RTPTransceiverDirectionRecvonly,
+	)
+	t.setMid(mid)
+	t.setCurrentDirection(pc.negotiatedDirection(mid))
 
-				rtcpPacket, _, err := rtcp.Unmarshal(rtcpBuf[:i])
-				if err != nil {
-					pcLog.Warnf("Failed to unmarshal RTCP packet, discarding: %v \n", err)
-					continue
-				}
-				pcLog.Debugf("got RTCP: %+v", rtcpPacket)
-			}
-		}()
+	pc.onTrack(receiver.Track)
+}
+
+// remoteSends checks if the direction of a remote media section allows it to send
+func remoteSends(media *sdp.MediaDescription) bool {
+	for _, a := range media.Attributes {
+		switch NewRTPTransceiverDirection(a.Key) {
+		case RTPTransceiverDirectionSendrecv, RTPTransceiverDirectionSendonly:
+			return true
+		case RTPTransceiverDirectionRecvonly, RTPTransceiverDirectionInactive:
+			return false
+		}
 	}
+
+	// sendrecv is the default direction
+	return true
 }
 
 // RemoteDescription returns PendingRemoteDescription if it is not null and
@@ -1048,27 +1794,105 @@ func (pc *PeerConnection) RemoteDescription() *SessionDescription {
 }
 
 // AddICECandidate accepts an ICE candidate string and adds it
-// to the existing set of candidates
+// to the existing set of candidates. The candidates trickled once the ICE
+// transport started are checked right away. An empty candidate string
+// signals the end of the remote candidates, the checks go on with the
+// candidates known.
 func (pc *PeerConnection) AddICECandidate(candidate ICECandidateInit) error {
 	if pc.RemoteDescription() == nil {
 		return &rtcerr.InvalidStateError{Err: ErrNoRemoteDescription}
 	}
 
-	candidateValue := strings.TrimPrefix(candidate.Candidate, "candidate:")
-	attribute := sdp.NewAttribute("candidate", candidateValue)
-	sdpCandidate, err := attribute.ToICECandidate()
-	if err != nil {
-		return err
+	if strings.TrimSpace(candidate.Candidate) == "" {
+		pc.api.log.Debug("The remote signaled the end of its candidates")
+		return nil
 	}
 
-	iceCandidate, err := newICECandidateFromSDP(sdpCandidate)
+	iceCandidate, err := UnmarshalICECandidate(candidate.Candidate)
 	if err != nil {
-		return err
+		return &rtcerr.OperationError{Err: errors.Wrapf(err, "malformed candidate %q", candidate.Candidate)}
+	}
+
+	// RTCP is always multiplexed with RTP, on the candidates of the RTP
+	// component
+	if iceCandidate.Component == uint16(ICEComponentRTCP) {
+		pc.api.log.Debugf("Dropping RTCP candidate %q, RTCP is multiplexed", candidate.Candidate)
+		return nil
+	}
+
+	if !pc.acceptsCandidateFor(candidate.SDPMid, candidate.SDPMLineIndex) {
+		pc.api.log.Debugf("Dropping candidate %q for a media section that is unknown or bundled", candidate.Candidate)
+		return nil
+	}
+
+	// The candidates of an unbundled data section are for its own transport
+	if dataTransport := pc.unbundledData(); dataTransport != nil && pc.candidateMid(candidate.SDPMid, candidate.SDPMLineIndex) == dataTransport.mid {
+		return dataTransport.iceTransport.AddRemoteCandidate(iceCandidate)
 	}
 
+	// Duplicates are ignored by the ICE agent
 	return pc.iceTransport.AddRemoteCandidate(iceCandidate)
 }
 
+// candidateMid returns the mid of the media section of the remote
+// description a trickled candidate belongs to, empty if unknown
+func (pc *PeerConnection) candidateMid(sdpMid *string, sdpMLineIndex *uint16) string {
+	medias := pc.RemoteDescription().parsed.MediaDescriptions
+	switch {
+	case sdpMid != nil:
+		return *sdpMid
+	case sdpMLineIndex != nil && int(*sdpMLineIndex) < len(medias):
+		mid, _ := medias[*sdpMLineIndex].Attribute(sdp.AttrKeyMID)
+		return mid
+	}
+	return ""
+}
+
+// acceptsCandidateFor checks that a trickled candidate belongs to a media section
+// of the remote description that carries a transport. When the media is bundled
+// only the candidates of the first media section in the group are used.
+func (pc *PeerConnection) acceptsCandidateFor(sdpMid *string, sdpMLineIndex *uint16) bool {
+	medias := pc.RemoteDescription().parsed.MediaDescriptions
+
+	mid := ""
+	switch {
+	case sdpMid != nil:
+		mid = *sdpMid
+		found := false
+		for _, m := range medias {
+			if v, ok := m.Attribute(sdp.AttrKeyMID); ok && v == mid {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	case sdpMLineIndex != nil:
+		if int(*sdpMLineIndex) >= len(medias) {
+			return false
+		}
+		mid, _ = medias[*sdpMLineIndex].Attribute(sdp.AttrKeyMID)
+	default:
+		return true
+	}
+
+	group, ok := pc.RemoteDescription().parsed.Attribute(sdp.AttrKeyGroup)
+	if !ok || mid == "" {
+		return true
+	}
+	bundle := strings.Fields(group)
+	if len(bundle) < 2 || bundle[0] != "BUNDLE" {
+		return true
+	}
+	for _, bundled := range bundle[2:] {
+		if bundled == mid {
+			return false
+		}
+	}
+	return true
+}
+
 // ICEConnectionState returns the ICE connection state of the
 // PeerConnection instance.
 func (pc *PeerConnection) ICEConnectionState() ICEConnectionState {
@@ -1089,8 +1913,8 @@ func (pc *PeerConnection) GetSenders() []*RTPSender {
 
 	result := make([]*RTPSender, len(pc.rtpTransceivers))
 	for i, tranceiver := range pc.rtpTransceivers {
-		if tranceiver.Sender != nil {
-			result[i] = tranceiver.Sender
+		if sender := tranceiver.Sender(); sender != nil {
+			result[i] = sender
 		}
 	}
 	return result
@@ -1103,42 +1927,130 @@ func (pc *PeerConnection) GetReceivers() []*RTPReceiver {
 
 	result := make([]*RTPReceiver, len(pc.rtpTransceivers))
 	for i, tranceiver := range pc.rtpTransceivers {
-		if tranceiver.Receiver != nil {
-			result[i] = tranceiver.Receiver
-
+		if receiver := tranceiver.Receiver(); receiver != nil {
+			result[i] = receiver
 		}
 	}
 	return result
 }
 
-// GetTransceivers returns the RTCRtpTransceiver that are currently attached to this RTCPeerConnection
+// GetTransceivers returns the RTCRtpTransceiver that are currently attached to this RTCPeerConnection.
+// They are ordered like the media sections of the current description, the
+// ones not negotiated yet come last. It is safe to call in any state.
 func (pc *PeerConnection) GetTransceivers() []*RTPTransceiver {
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
 
-	return pc.rtpTransceivers
+	mLineIndex := map[string]int{}
+	if desc := pc.currentDescription(); desc != nil && desc.parsed != nil {
+		for i, media := range desc.parsed.MediaDescriptions {
+			if mid, ok := media.Attribute(sdp.AttrKeyMID); ok {
+				mLineIndex[mid] = i
+			}
+		}
+	}
+	index := func(t *RTPTransceiver) int {
+		if i, ok := mLineIndex[t.Mid()]; ok {
+			return i
+		}
+		return len(mLineIndex)
+	}
+
+	result := make([]*RTPTransceiver, len(pc.rtpTransceivers))
+	copy(result, pc.rtpTransceivers)
+	sort.SliceStable(result, func(i, j int) bool {
+		return index(result[i]) < index(result[j])
+	})
+	return result
+}
+
+// currentDescription returns the description the media sections are taken
+// from, the local one when known
+func (pc *PeerConnection) currentDescription() *SessionDescription {
+	if desc := pc.LocalDescription(); desc != nil {
+		return desc
+	}
+	return pc.RemoteDescription()
+}
+
+// updateCurrentDirections sets the negotiated direction of every transceiver
+// once an offer/answer exchange completed
+func (pc *PeerConnection) updateCurrentDirections() {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+
+	for _, t := range pc.rtpTransceivers {
+		t.setCurrentDirection(pc.negotiatedDirection(t.Mid()))
+	}
+}
+
+// negotiatedDirection returns the direction of the media section with the given
+// mid, as determined by the current answer
+func (pc *PeerConnection) negotiatedDirection(mid string) RTPTransceiverDirection {
+	answer, weAnswered := pc.CurrentLocalDescription, true
+	if pc.CurrentRemoteDescription != nil && pc.CurrentRemoteDescription.Type == SDPTypeAnswer {
+		answer, weAnswered = pc.CurrentRemoteDescription, false
+	}
+	if answer == nil || answer.parsed == nil {
+		return RTPTransceiverDirection(Unknown)
+	}
+
+	for _, media := range answer.parsed.MediaDescriptions {
+		if v, ok := media.Attribute(sdp.AttrKeyMID); !ok || v != mid {
+			continue
+		}
+		for _, a := range media.Attributes {
+			direction := NewRTPTransceiverDirection(a.Key)
+			switch {
+			case direction == RTPTransceiverDirection(Unknown):
+				continue
+			case weAnswered:
+				return direction
+			case direction == RTPTransceiverDirectionSendonly:
+				return RTPTransceiverDirectionRecvonly
+			case direction == RTPTransceiverDirectionRecvonly:
+				return RTPTransceiverDirectionSendonly
+			}
+			return direction
+		}
+	}
+	return RTPTransceiverDirection(Unknown)
 }
 
 // AddTrack adds a Track to the PeerConnection
 func (pc *PeerConnection) AddTrack(track *Track) (*RTPSender, error) {
-	if pc.isClosed {
+	if pc.closed() {
 		return nil, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
 	for _, transceiver := range pc.rtpTransceivers {
-		if transceiver.Sender.Track == nil {
+		sender := transceiver.Sender()
+		if sender == nil || sender.Track == nil {
 			continue
 		}
-		if track.ID == transceiver.Sender.Track.ID {
+		if track.ID == sender.Track.ID {
 			return nil, &rtcerr.InvalidAccessError{Err: ErrExistingTrack}
 		}
 	}
+
+	// The retransmissions of the Track are sent on their own SSRC
+	if pc.api.settingEngine.nack && track.rtxSSRC == 0 && track.Codec != nil &&
+		hasRTCPFeedback(track.Codec.RTCPFeedback, RTCPFeedback{Type: TypeRTCPFBNACK}) {
+		buf := make([]byte, 4)
+		if _, err := io.ReadFull(pc.api.settingEngine.randomSource(), buf); err != nil {
+			return nil, err
+		}
+		track.rtxSSRC = binary.LittleEndian.Uint32(buf)
+	}
+
 	var transceiver *RTPTransceiver
 	for _, t := range pc.rtpTransceivers {
-		if !t.stopped &&
+		if !t.Stopped() &&
 			// t.Sender == nil && // TODO: check that the sender has never sent
-			t.Sender.Track == nil &&
-			t.Receiver.Track != nil &&
-			t.Receiver.Track.Kind == track.Kind {
+			t.Sender() != nil &&
+			t.Sender().Track == nil &&
+			t.Receiver() != nil &&
+			t.Receiver().Track != nil &&
+			t.Receiver().Track.Kind == track.Kind {
 			transceiver = t
 			break
 		}
@@ -1156,14 +2068,43 @@ func (pc *PeerConnection) AddTrack(track *Track) (*RTPSender, error) {
 		)
 	}
 
-	transceiver.Mid = track.Kind.String() // TODO: Mid generation
+	// With unified-plan a reused RTPTransceiver keeps its media section
+	if transceiver.Mid() == "" || pc.configuration.SDPSemantics == SDPSemanticsPlanB {
+		transceiver.setMid(pc.generateMid(track.Kind))
+	}
 
-	return transceiver.Sender, nil
+	pc.updateNegotiationNeeded()
+	return transceiver.Sender(), nil
 }
 
-// func (pc *PeerConnection) RemoveTrack() {
-// 	panic("not implemented yet") // FIXME NOT-IMPLEMENTED nolint
-// }
+// RemoveTrack stops sending the Track of the RTPSender, which is stopped as
+// RTPSender.Stop does. Its RTPTransceiver keeps the media section, which
+// only receives once renegotiated: OnNegotiationNeeded is invoked. Removing
+// a Track that isn't sent does nothing, ErrSenderNotCreatedByConnection is
+// returned for the RTPSender of another PeerConnection.
+func (pc *PeerConnection) RemoveTrack(sender *RTPSender) error {
+	if pc.closed() {
+		return &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
+	}
+
+	var transceiver *RTPTransceiver
+	for _, t := range pc.transceivers() {
+		if t.Sender() == sender {
+			transceiver = t
+			break
+		}
+	}
+	if transceiver == nil {
+		return &rtcerr.InvalidAccessError{Err: ErrSenderNotCreatedByConnection}
+	}
+	if !transceiver.removeSendingTrack() {
+		return nil
+	}
+
+	sender.Stop()
+	pc.updateNegotiationNeeded()
+	return nil
+}
 
 // func (pc *PeerConnection) AddTransceiver() RTPTransceiver {
 // 	panic("not implemented yet") // FIXME NOT-IMPLEMENTED nolint
@@ -1174,7 +2115,7 @@ func (pc *PeerConnection) AddTrack(track *Track) (*RTPSender, error) {
 // underlying channel such as data reliability.
 func (pc *PeerConnection) CreateDataChannel(label string, options *DataChannelInit) (*DataChannel, error) {
 	// https://w3c.github.io/webrtc-pc/#peer-to-peer-data-api (Step #2)
-	if pc.isClosed {
+	if pc.closed() {
 		return nil, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
 
@@ -1186,6 +2127,14 @@ func (pc *PeerConnection) CreateDataChannel(label string, options *DataChannelIn
 		Ordered: true,
 	}
 
+	// A negotiated channel is opened with the ID agreed on by the application
+	if options != nil && options.Negotiated != nil && *options.Negotiated {
+		if options.ID == nil {
+			return nil, &rtcerr.TypeError{Err: ErrNegotiatedWithoutID}
+		}
+		params.Negotiated = true
+	}
+
 	// https://w3c.github.io/webrtc-pc/#peer-to-peer-data-api (Step #19)
 	if options == nil || options.ID == nil {
 		var err error
@@ -1221,7 +2170,6 @@ func (pc *PeerConnection) CreateDataChannel(label string, options *DataChannelIn
 
 	// TODO: Enable validation of other parameters once they are implemented.
 	// - Protocol
-	// - Negotiated
 	// - Priority:
 	//
 	// See https://w3c.github.io/webrtc-pc/#peer-to-peer-data-api for details
@@ -1237,14 +2185,19 @@ func (pc *PeerConnection) CreateDataChannel(label string, options *DataChannelIn
 	}
 
 	// Remember datachannel
+	pc.mu.Lock()
 	pc.dataChannels[params.ID] = d
+	pc.mu.Unlock()
 
-	// Open if networking already started
+	// Open if networking already started, otherwise the application
+	// section must be negotiated
 	if pc.sctpTransport != nil {
 		err = d.open(pc.sctpTransport)
 		if err != nil {
 			return nil, err
 		}
+	} else {
+		pc.updateNegotiationNeeded()
 	}
 
 	return d, nil
@@ -1275,10 +2228,17 @@ func (pc *PeerConnection) SetIdentityProvider(provider string) error {
 	return errors.Errorf("TODO SetIdentityProvider")
 }
 
-// SendRTCP sends a user provided RTCP packet to the connected peer
-// If no peer is connected the packet is discarded
-func (pc *PeerConnection) SendRTCP(pkt rtcp.Packet) error {
-	raw, err := pkt.Marshal()
+// SendRTCP sends user provided RTCP packets to the connected peer, in a
+// single compound packet. Unless the remote negotiated reduced-size RTCP an
+// empty ReceiverReport is put first if the packets don't start with a
+// SenderReport or a ReceiverReport, as a compound packet must.
+// If no peer is connected the packets are discarded
+func (pc *PeerConnection) SendRTCP(pkts ...rtcp.Packet) error {
+	if pc.api.settingEngine.disableRTCP {
+		return ErrRTCPDisabled
+	}
+
+	raw, err := newCompoundRTCP(atomic.LoadInt32(&pc.reducedSizeRTCP) == 1, pkts...)
 	if err != nil {
 		return err
 	}
@@ -1287,27 +2247,82 @@ func (pc *PeerConnection) SendRTCP(pkt rtcp.Packet) error {
 	if err != nil {
 		return nil // TODO SendRTCP before would gracefully discard packets until ready
 	}
+	return writeCompoundRTCP(srtcpSession, raw)
+}
 
+// WriteRTCP sends RTCP packets about any SSRCs, sent or received, in a single
+// compound packet over the SRTCP session of the DTLS transport all the media
+// is bundled on. The packets are put together as SendRTCP does, but they are
+// never discarded: it fails with ErrDTLSTransportNotConnected until the
+// transport connected and with an InvalidStateError once the PeerConnection
+// is closed.
+func (pc *PeerConnection) WriteRTCP(pkts []rtcp.Packet) error {
+	switch {
+	case pc.closed():
+		return &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
+	case pc.api.settingEngine.disableRTCP:
+		return ErrRTCPDisabled
+	case pc.dtlsTransport == nil || pc.dtlsTransport.State() != DTLSTransportStateConnected:
+		return ErrDTLSTransportNotConnected
+	}
+
+	raw, err := newCompoundRTCP(atomic.LoadInt32(&pc.reducedSizeRTCP) == 1, pkts...)
+	if err != nil {
+		return err
+	}
+
+	srtcpSession, err := pc.dtlsTransport.getSRTCPSession()
+	if err != nil {
+		return err
+	}
+	return writeCompoundRTCP(srtcpSession, raw)
+}
+
+// writeCompoundRTCP writes a marshaled compound RTCP packet to the session
+func writeCompoundRTCP(srtcpSession *srtp.SessionSRTCP, raw []byte) error {
 	writeStream, err := srtcpSession.OpenWriteStream()
 	if err != nil {
-		return fmt.Errorf("SendRTCP failed to open WriteStream: %v", err)
+		return fmt.Errorf("failed to open WriteStream: %v", err)
 	}
 
 	if _, err := writeStream.Write(raw); err != nil {
-		return fmt.Errorf("SendRTCP failed to write: %v", err)
+		return fmt.Errorf("failed to write: %v", err)
 	}
 	return nil
 }
 
-// Close ends the PeerConnection
+// Close ends the PeerConnection, see CloseWithContext
 func (pc *PeerConnection) Close() error {
+	return pc.CloseWithContext(context.Background())
+}
+
+// closed returns whether Close was called
+func (pc *PeerConnection) closed() bool {
+	return atomic.LoadInt32(&pc.isClosed) == 1
+}
+
+// CloseWithContext ends the PeerConnection. The teardown runs in a fixed
+// order, each step completing before the next one: the senders are stopped
+// once the packets written to their Track are sent, the receivers close
+// their read streams, a RTCP BYE is sent for the SSRCs that were sent, the
+// SCTP and DTLS transports are closed, and the ICE transport is closed once
+// the read loops of the senders and receivers returned.
+//
+// If ctx is done before the teardown completes, the ICE transports are
+// closed right away, which unblocks the remaining steps, and ctx.Err() is
+// returned.
+func (pc *PeerConnection) CloseWithContext(ctx context.Context) error {
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #2)
-	if pc.isClosed {
+	pc.mu.Lock()
+	if pc.closed() {
+		pc.mu.Unlock()
 		return nil
 	}
 
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #3)
-	pc.isClosed = true
+	atomic.StoreInt32(&pc.isClosed, 1)
+	pc.mu.Unlock()
+	pc.stopStatsLoop()
 
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #4)
 	pc.SignalingState = SignalingStateClosed
@@ -1317,34 +2332,97 @@ func (pc *PeerConnection) Close() error {
 	pc.iceStateChange(ice.ConnectionStateClosed) // FIXME REMOVE
 
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #12)
+	pc.mu.Lock()
 	pc.ConnectionState = PeerConnectionStateClosed
+	close(pc.connectionStateChanged)
+	pc.connectionStateChanged = make(chan struct{})
+	pc.mu.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pc.teardown()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		pc.abortTeardown()
+		return ctx.Err()
+	}
+}
 
-	// Try closing everything and collect the errors
+// teardown stops the media and closes the transports in order, and
+// collects the errors
+func (pc *PeerConnection) teardown() error {
 	var closeErrs []error
 
-	// Shutdown strategy:
-	// 1. All Conn close by closing their underlying Conn.
-	// 2. A Mux stops this chain. It won't close the underlying
-	//    Conn if one of the endpoints is closed down. To
-	//    continue the chain the Mux has to be closed.
+	var senders []*RTPSender
+	var receivers []*RTPReceiver
+	for _, t := range pc.transceivers() {
+		sender, receiver := t.stop()
+		if sender != nil {
+			senders = append(senders, sender)
+		}
+		if receiver != nil {
+			receivers = append(receivers, receiver)
+		}
+	}
 
-	if err := pc.dtlsTransport.Stop(); err != nil {
-		closeErrs = append(closeErrs, err)
+	// 1. The senders flush the packets already written and close their RTCP
+	//    streams, a single BYE is sent for all of them
+	var ssrcs []uint32
+	for _, sender := range senders {
+		if sender.stop() {
+			ssrcs = append(ssrcs, sender.Track.SSRC)
+		}
 	}
 
-	for _, t := range pc.rtpTransceivers {
-		if err := t.Stop(); err != nil {
+	// 2. The receivers close their read streams, the ones of the streams a
+	//    renegotiation removed have closed them already
+	var stoppedReceivers []*RTPReceiver
+	for _, receiver := range receivers {
+		if receiver.isStopped() {
+			stoppedReceivers = append(stoppedReceivers, receiver)
+			continue
+		}
+		if err := receiver.stop(); err != nil {
 			closeErrs = append(closeErrs, err)
+			continue
 		}
+		stoppedReceivers = append(stoppedReceivers, receiver)
 	}
 
+	// 3. The remote learns the streams ended, a failure isn't an error of
+	//    the teardown as the remote may already be gone
+	if len(ssrcs) != 0 && !pc.api.settingEngine.disableRTCP && pc.dtlsTransport.State() == DTLSTransportStateConnected {
+		if err := pc.SendRTCP(&rtcp.Goodbye{Sources: ssrcs}); err != nil {
+			pc.api.log.Warnf("Failed to send RTCP BYE: %v", err)
+		}
+	}
+
+	// 4. The transports close from the top down. All Conn close by closing
+	//    their underlying Conn. A Mux stops this chain. It won't close the
+	//    underlying Conn if one of the endpoints is closed down. To
+	//    continue the chain the Mux has to be closed.
 	if pc.sctpTransport != nil {
 		if err := pc.sctpTransport.Stop(); err != nil {
 			closeErrs = append(closeErrs, err)
 		}
 	}
 
-	// TODO: Close DTLS?
+	if err := pc.dtlsTransport.Stop(); err != nil {
+		closeErrs = append(closeErrs, err)
+	}
+
+	// A read pending on a closed SRTP stream only fails once its session
+	// is closed, the loops of the senders and receivers are done by now
+	for _, sender := range senders {
+		sender.wait()
+	}
+	for _, receiver := range stoppedReceivers {
+		receiver.wait()
+	}
 
 	if pc.iceTransport != nil {
 		if err := pc.iceTransport.Stop(); err != nil {
@@ -1352,12 +2430,34 @@ func (pc *PeerConnection) Close() error {
 		}
 	}
 
+	if pc.dataTransport != nil {
+		if err := pc.dataTransport.stop(); err != nil {
+			closeErrs = append(closeErrs, err)
+		}
+	}
+
 	// TODO: Figure out stopping ICE transport & Gatherer independently.
 	// pc.iceGatherer()
 
 	return flattenErrs(closeErrs)
 }
 
+// abortTeardown closes the ICE transports under a teardown that didn't
+// complete in time, the reads and writes of the other steps fail and let
+// them complete
+func (pc *PeerConnection) abortTeardown() {
+	if pc.iceTransport != nil {
+		if err := pc.iceTransport.Stop(); err != nil {
+			pc.api.log.Warnf("Failed to close the ICE transport: %v", err)
+		}
+	}
+	if pc.dataTransport != nil {
+		if err := pc.dataTransport.iceTransport.Stop(); err != nil {
+			pc.api.log.Warnf("Failed to close the ICE transport of the data channels: %v", err)
+		}
+	}
+}
+
 func flattenErrs(errs []error) error {
 	var errstrings []string
 
@@ -1379,6 +2479,7 @@ func (pc *PeerConnection) iceStateChange(newState ICEConnectionState) {
 	pc.iceConnectionState = newState
 	pc.mu.Unlock()
 
+	pc.restartFailedICE(newState)
 	pc.onICEConnectionStateChange(newState)
 }
 
@@ -1403,10 +2504,36 @@ func (pc *PeerConnection) addFingerprint(d *sdp.SessionDescription) {
 	}
 }
 
-func (pc *PeerConnection) addRTPMediaSection(d *sdp.SessionDescription, codecType RTPCodecType, midValue string, iceParams ICEParameters, peerDirection RTPTransceiverDirection, candidates []ICECandidate, dtlsRole sdp.ConnectionRole) bool {
-	if codecs := pc.api.mediaEngine.getCodecsByKind(codecType); len(codecs) == 0 {
+// addICEOptions announces that the candidates are trickled
+func (pc *PeerConnection) addICEOptions(d *sdp.SessionDescription) {
+	if pc.api.settingEngine.trickle {
+		d.WithValueAttribute("ice-options", "trickle")
+	}
+}
+
+func (pc *PeerConnection) addRTPMediaSection(d *sdp.SessionDescription, codecType RTPCodecType, midValue string, remoteMedia *sdp.MediaDescription, options OfferAnswerOptions, iceParams ICEParameters, peerDirection RTPTransceiverDirection, candidates localCandidates, dtlsRole sdp.ConnectionRole) bool {
+	codecs := filterCodecsByName(pc.api.mediaEngine.getCodecsByKind(codecType), options.Codecs)
+	if len(codecs) == 0 {
+		return false
+	}
+
+	if (remoteMedia != nil && remoteMedia.MediaName.Port.Value == 0) || pc.isMediaSectionStopped(codecType, midValue) {
+		addRejectedMediaSection(d, codecType, midValue, codecs)
 		return false
 	}
+
+	// When answering only the codecs offered by the remote are kept, in the
+	// order of the MediaEngine and with the remote payload types
+	// https://tools.ietf.org/html/rfc3264#section-6.1
+	if remoteMedia != nil {
+		offered := matchRemoteCodecs(codecs, codecsFromMedia(remoteMedia))
+		if len(offered) == 0 {
+			addRejectedMediaSection(d, codecType, midValue, codecs)
+			return false
+		}
+		codecs = offered
+	}
+
 	media := sdp.NewJSEPMediaDescription(codecType.String(), []string{}).
 		WithValueAttribute(sdp.AttrKeyConnectionSetup, dtlsRole.String()). // TODO: Support other connection types
 		WithValueAttribute(sdp.AttrKeyMID, midValue).
@@ -1414,37 +2541,349 @@ func (pc *PeerConnection) addRTPMediaSection(d *sdp.SessionDescription, codecTyp
 		WithPropertyAttribute(sdp.AttrKeyRTCPMux).  // TODO: support RTCP fallback
 		WithPropertyAttribute(sdp.AttrKeyRTCPRsize) // TODO: Support Reduced-Size RTCP?
 
-	for _, codec := range pc.api.mediaEngine.getCodecsByKind(codecType) {
+	if options.MaxBitrate != 0 {
+		media.Bandwidth = bandwidthLines(options.MaxBitrate)
+	}
+
+	// When answering only the feedback and extensions offered by the remote
+	// are kept, the extensions with the remote IDs
+	var remoteFeedback []RTCPFeedback
+	var remoteExtensions map[string]uint8
+	if remoteMedia != nil {
+		remoteFeedback = rtcpFeedbackFromMedia(remoteMedia)
+		remoteExtensions = headerExtensionsFromMedia(remoteMedia)
+	}
+
+	for _, codec := range codecs {
 		media.WithCodec(codec.PayloadType, codec.Name, codec.ClockRate, codec.Channels, codec.SDPFmtpLine)
+		for _, feedback := range codec.RTCPFeedback {
+			if pc.api.settingEngine.disableRTCP {
+				break
+			}
+			if remoteMedia != nil && !hasRTCPFeedback(remoteFeedback, feedback) {
+				continue
+			}
+			media.WithValueAttribute("rtcp-fb", rtcpFeedbackAttribute(codec.PayloadType, feedback))
+		}
+	}
+
+	// With NACK the codecs offer RTX, an answer keeps the RTX payload types
+	// the remote offered
+	if pc.api.settingEngine.nack && !pc.api.settingEngine.disableRTCP {
+		rtxPayloadTypes := allocateRTXPayloadTypes(pc.api.mediaEngine, codecs)
+		if remoteMedia != nil {
+			rtxPayloadTypes = rtxPayloadTypesFromMedia(remoteMedia)
+		}
+		for _, codec := range codecs {
+			if !hasRTCPFeedback(codec.RTCPFeedback, RTCPFeedback{Type: TypeRTCPFBNACK}) {
+				continue
+			}
+			if rtx, ok := rtxPayloadTypeFor(rtxPayloadTypes, codec.PayloadType); ok {
+				media.WithCodec(rtx, rtxCodecName, codec.ClockRate, 0, rtxFmtp(codec.PayloadType))
+			}
+		}
+	}
+
+	ridNegotiated := false
+	for _, extension := range pc.api.mediaEngine.getHeaderExtensionsByKind(codecType) {
+		id := extension.id
+		if remoteMedia != nil {
+			var ok bool
+			if id, ok = remoteExtensions[extension.uri]; !ok {
+				continue
+			}
+		}
+		media.WithValueAttribute("extmap", fmt.Sprintf("%d %s", id, extension.uri))
+		ridNegotiated = ridNegotiated || extension.uri == RIDURI
+	}
+
+	// An answer to a simulcast offer requests the permitted layers
+	var recvRIDs []string
+	if remoteMedia != nil {
+		recvRIDs = pc.receivedRIDs(remoteMedia, ridNegotiated)
 	}
 
 	weSend := false
-	for _, transceiver := range pc.rtpTransceivers {
-		if transceiver.Sender == nil ||
-			transceiver.Sender.Track == nil ||
-			transceiver.Sender.Track.Kind != codecType {
+	sendRID, sendRestrictions := "", RIDRestrictions{}
+	for _, transceiver := range pc.mediaSectionTransceivers(codecType, midValue) {
+		if !transceiver.isSending() {
 			continue
 		}
 		weSend = true
-		track := transceiver.Sender.Track
+		if rid, restrictions := transceiver.Sender().ridLayer(); rid != "" && ridNegotiated && sendRID == "" {
+			sendRID, sendRestrictions = rid, restrictions
+		}
+		track := transceiver.Sender().Track
 		media = media.WithMediaSource(track.SSRC, track.Label /* cname */, track.Label /* streamLabel */, track.Label)
+		if track.rtxSSRC != 0 {
+			media = media.WithMediaSource(track.rtxSSRC, track.Label /* cname */, track.Label /* streamLabel */, track.Label)
+			media = media.WithValueAttribute(sdp.AttrKeySSRCGroup, ssrcGroup{semantics: ssrcGroupFID, ssrcs: []uint32{track.SSRC, track.rtxSSRC}}.String())
+		}
 	}
 	media = media.WithPropertyAttribute(localDirection(weSend, peerDirection).String())
+	if sendRID != "" || len(recvRIDs) != 0 {
+		addSimulcast(media, sendRID, sendRestrictions, recvRIDs)
+	}
+
+	candidates.addTo(media)
 
-	for _, c := range candidates {
-		sdpCandidate := c.toSDP()
-		sdpCandidate.ExtensionAttributes = append(sdpCandidate.ExtensionAttributes, sdp.ICECandidateAttribute{Key: "generation", Value: "0"})
-		sdpCandidate.Component = 1
-		media.WithICECandidate(sdpCandidate)
-		sdpCandidate.Component = 2
-		media.WithICECandidate(sdpCandidate)
+	// An SDES answer keeps the transport protocol of the remote, usually
+	// RTP/SAVPF
+	if crypto, ok := pc.localSDESCrypto(remoteMedia); ok {
+		media.WithValueAttribute(sdesAttributeKey, crypto.String())
+		if remoteMedia != nil {
+			media.MediaName.Protos = remoteMedia.MediaName.Protos
+		}
 	}
-	media.WithPropertyAttribute("end-of-candidates")
 	d.WithMedia(media)
 	return true
 }
 
-func (pc *PeerConnection) addDataMediaSection(d *sdp.SessionDescription, midValue string, iceParams ICEParameters, candidates []ICECandidate, dtlsRole sdp.ConnectionRole) {
+// hasActiveTransceivers returns true when an RTPTransceiver hasn't been
+// stopped
+func (pc *PeerConnection) hasActiveTransceivers() bool {
+	for _, transceiver := range pc.rtpTransceivers {
+		if !transceiver.Stopped() {
+			return true
+		}
+	}
+	return false
+}
+
+// isMediaSectionStopped returns true when every RTPTransceiver of the media
+// section has been stopped
+func (pc *PeerConnection) isMediaSectionStopped(codecType RTPCodecType, midValue string) bool {
+	stopped := false
+	for _, transceiver := range pc.mediaSectionTransceivers(codecType, midValue) {
+		if !transceiver.Stopped() {
+			return false
+		}
+		stopped = true
+	}
+	return stopped
+}
+
+// addRejectedMediaSection adds a media section with a zero port, it must be
+// kept in the following offers to preserve the m-line indexes
+// https://tools.ietf.org/html/draft-ietf-rtcweb-jsep-26#section-5.2.2
+func addRejectedMediaSection(d *sdp.SessionDescription, codecType RTPCodecType, midValue string, codecs []*RTPCodec) {
+	media := sdp.NewJSEPMediaDescription(codecType.String(), []string{}).
+		WithValueAttribute(sdp.AttrKeyMID, midValue).
+		WithPropertyAttribute(RTPTransceiverDirectionInactive.String())
+	media.MediaName.Port = sdp.RangedPort{Value: 0}
+
+	for _, codec := range codecs {
+		media.WithCodec(codec.PayloadType, codec.Name, codec.ClockRate, codec.Channels, codec.SDPFmtpLine)
+	}
+	d.WithMedia(media)
+}
+
+// headerExtensionsFromMedia returns the URIs of the extensions in the a=extmap
+// attributes of a media section, mapped to their ID
+func headerExtensionsFromMedia(media *sdp.MediaDescription) map[string]uint8 {
+	extensions := map[string]uint8{}
+	for _, a := range media.Attributes {
+		if a.Key != "extmap" {
+			continue
+		}
+
+		id, uri, err := parseExtMap(a.Value)
+		if err != nil {
+			pcLog.Warnf("Failed to parse extmap: %v", err)
+			continue
+		}
+		extensions[uri] = id
+	}
+	return extensions
+}
+
+// negotiatedHeaderExtensions returns the header extensions of the given kind that
+// are both registered in the MediaEngine and present in the remote description
+func (pc *PeerConnection) negotiatedHeaderExtensions(kind RTPCodecType) map[string]uint8 {
+	negotiated := map[string]uint8{}
+	remoteDescription := pc.RemoteDescription()
+	if remoteDescription == nil || remoteDescription.parsed == nil {
+		return negotiated
+	}
+
+	for _, media := range remoteDescription.parsed.MediaDescriptions {
+		if media.MediaName.Media != kind.String() {
+			continue
+		}
+
+		remoteExtensions := headerExtensionsFromMedia(media)
+		for _, extension := range pc.api.mediaEngine.getHeaderExtensionsByKind(kind) {
+			if id, ok := remoteExtensions[extension.uri]; ok {
+				negotiated[extension.uri] = id
+			}
+		}
+	}
+	return negotiated
+}
+
+// negotiatedExtensionIDs returns the IDs the header extension was
+// negotiated with, for any kind of media
+func (pc *PeerConnection) negotiatedExtensionIDs(uri string) []uint8 {
+	var ids []uint8
+	for _, kind := range []RTPCodecType{RTPCodecTypeAudio, RTPCodecTypeVideo} {
+		id, ok := pc.negotiatedHeaderExtensions(kind)[uri]
+		if !ok {
+			continue
+		}
+
+		known := false
+		for _, i := range ids {
+			known = known || i == id
+		}
+		if !known {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// negotiatedRTXPayloadTypes returns the RTX payload types of the kind in the
+// RemoteDescription, mapped to the payload type they retransmit
+func (pc *PeerConnection) negotiatedRTXPayloadTypes(kind RTPCodecType) map[uint8]uint8 {
+	negotiated := map[uint8]uint8{}
+	remoteDescription := pc.RemoteDescription()
+	if remoteDescription == nil || remoteDescription.parsed == nil || !pc.api.settingEngine.nack {
+		return negotiated
+	}
+
+	for _, media := range remoteDescription.parsed.MediaDescriptions {
+		if media.MediaName.Media != kind.String() {
+			continue
+		}
+		for rtx, apt := range rtxPayloadTypesFromMedia(media) {
+			negotiated[rtx] = apt
+		}
+	}
+	return negotiated
+}
+
+// negotiatedREDPayloadTypes returns the RED payload types of the kind in the
+// RemoteDescription, mapped to the payload type of their primary encoding.
+// RED is only negotiated if it is registered in the MediaEngine.
+func (pc *PeerConnection) negotiatedREDPayloadTypes(kind RTPCodecType) map[uint8]uint8 {
+	negotiated := map[uint8]uint8{}
+	remoteDescription := pc.RemoteDescription()
+	if remoteDescription == nil || remoteDescription.parsed == nil ||
+		len(filterCodecsByName(pc.api.mediaEngine.getCodecsByKind(kind), []string{RED})) == 0 {
+		return negotiated
+	}
+
+	for _, media := range remoteDescription.parsed.MediaDescriptions {
+		if media.MediaName.Media != kind.String() {
+			continue
+		}
+		for red, primary := range redPayloadTypesFromMedia(media) {
+			negotiated[red] = primary
+		}
+	}
+	return negotiated
+}
+
+// negotiatedPayloadType returns the payload type the codec of a Track was
+// negotiated with in the RemoteDescription, an answerer uses the payload types
+// of the offer. The payload type of the Track is returned if it wasn't found.
+func (pc *PeerConnection) negotiatedPayloadType(track *Track) uint8 {
+	remoteDescription := pc.RemoteDescription()
+	if remoteDescription == nil || remoteDescription.parsed == nil || track.Codec == nil {
+		return track.PayloadType
+	}
+
+	for _, media := range remoteDescription.parsed.MediaDescriptions {
+		if media.MediaName.Media != track.Kind.String() {
+			continue
+		}
+		if matched := matchRemoteCodecs([]*RTPCodec{track.Codec}, codecsFromMedia(media)); len(matched) != 0 {
+			return matched[0].PayloadType
+		}
+	}
+	return track.PayloadType
+}
+
+// negotiatedMaxBitrate returns the bitrate in bits per second the
+// RemoteDescription asks not to exceed for the media of the kind, the b= lines
+// of the media section take precedence over the session ones. 0 is returned
+// without a limit.
+func (pc *PeerConnection) negotiatedMaxBitrate(kind RTPCodecType) uint64 {
+	remoteDescription := pc.RemoteDescription()
+	if remoteDescription == nil || remoteDescription.parsed == nil {
+		return 0
+	}
+
+	for _, media := range remoteDescription.parsed.MediaDescriptions {
+		if media.MediaName.Media != kind.String() {
+			continue
+		}
+		if bitrate := maxBitrateFromBandwidth(media.Bandwidth); bitrate != 0 {
+			return bitrate
+		}
+	}
+	return maxBitrateFromBandwidth(remoteDescription.parsed.Bandwidth)
+}
+
+// negotiatedReducedSizeRTCP returns whether the media sections of the kind
+// of the RemoteDescription accept reduced-size RTCP, the local ones always
+// announce it. Without kind every media section must accept it.
+func (pc *PeerConnection) negotiatedReducedSizeRTCP(kinds ...RTPCodecType) bool {
+	remoteDescription := pc.RemoteDescription()
+	if remoteDescription == nil || remoteDescription.parsed == nil {
+		return false
+	}
+
+	negotiated := false
+	for _, media := range remoteDescription.parsed.MediaDescriptions {
+		if media.MediaName.Media == "application" {
+			continue
+		}
+		matches := len(kinds) == 0
+		for _, kind := range kinds {
+			matches = matches || media.MediaName.Media == kind.String()
+		}
+		if !matches {
+			continue
+		}
+
+		if _, ok := media.Attribute(sdp.AttrKeyRTCPRsize); !ok {
+			return false
+		}
+		negotiated = true
+	}
+	return negotiated
+}
+
+// negotiatedRTCPFeedback returns the RTCP feedback of the kind supported by
+// both the MediaEngine and the RemoteDescription
+func (pc *PeerConnection) negotiatedRTCPFeedback(kind RTPCodecType) []RTCPFeedback {
+	var negotiated []RTCPFeedback
+	remoteDescription := pc.RemoteDescription()
+	if remoteDescription == nil || remoteDescription.parsed == nil || pc.api.settingEngine.disableRTCP {
+		return negotiated
+	}
+
+	var local []RTCPFeedback
+	for _, codec := range pc.api.mediaEngine.getCodecsByKind(kind) {
+		local = append(local, codec.RTCPFeedback...)
+	}
+
+	for _, media := range remoteDescription.parsed.MediaDescriptions {
+		if media.MediaName.Media != kind.String() {
+			continue
+		}
+
+		for _, feedback := range rtcpFeedbackFromMedia(media) {
+			if hasRTCPFeedback(local, feedback) && !hasRTCPFeedback(negotiated, feedback) {
+				negotiated = append(negotiated, feedback)
+			}
+		}
+	}
+	return negotiated
+}
+
+func (pc *PeerConnection) addDataMediaSection(d *sdp.SessionDescription, midValue string, iceParams ICEParameters, candidates localCandidates, dtlsRole sdp.ConnectionRole) {
 	media := (&sdp.MediaDescription{
 		MediaName: sdp.MediaName{
 			Media:   "application",
@@ -1466,15 +2905,7 @@ func (pc *PeerConnection) addDataMediaSection(d *sdp.SessionDescription, midValu
 		WithPropertyAttribute("sctpmap:5000 webrtc-datachannel 1024").
 		WithICECredentials(iceParams.UsernameFragment, iceParams.Password)
 
-	for _, c := range candidates {
-		sdpCandidate := c.toSDP()
-		sdpCandidate.ExtensionAttributes = append(sdpCandidate.ExtensionAttributes, sdp.ICECandidateAttribute{Key: "generation", Value: "0"})
-		sdpCandidate.Component = 1
-		media.WithICECandidate(sdpCandidate)
-		sdpCandidate.Component = 2
-		media.WithICECandidate(sdpCandidate)
-	}
-	media.WithPropertyAttribute("end-of-candidates")
+	candidates.addTo(media)
 
 	d.WithMedia(media)
 }
@@ -1504,7 +2935,7 @@ func (pc *PeerConnection) NewSampleTrack(payloadType uint8, id, label string) (*
 		return nil, errors.New("codec payloader not set")
 	}
 
-	return NewSampleTrack(payloadType, id, label, codec)
+	return newSampleTrack(payloadType, id, label, codec, pc.api.settingEngine.randomSource())
 }
 
 // NewTrack is used to create a new Track
@@ -1521,12 +2952,20 @@ func (pc *PeerConnection) newRTPTransceiver(
 ) *RTPTransceiver {
 
 	t := &RTPTransceiver{
-		Receiver:  receiver,
-		Sender:    sender,
-		Direction: direction,
+		receiver:  receiver,
+		sender:    sender,
+		direction: direction,
 	}
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
 	pc.rtpTransceivers = append(pc.rtpTransceivers, t)
 	return t
 }
+
+// transceivers returns a copy of the RTPTransceivers, the ones of the
+// incoming streams are added concurrently as their first packet arrives
+func (pc *PeerConnection) transceivers() []*RTPTransceiver {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	return append([]*RTPTransceiver{}, pc.rtpTransceivers...)
+}
@@ -1,10 +1,13 @@
 package webrtc
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	"github.com/pions/rtcp"
 	"github.com/pions/transport/test"
+	"github.com/pions/webrtc/pkg/media"
 )
 
 // TestPeerConnection_Close is moved to it's on file because the tests
@@ -52,3 +55,74 @@ func TestPeerConnection_Close(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// TestPeerConnection_Close_Stress opens and closes connections sending
+// media, the teardown must not leak goroutines nor fail
+func TestPeerConnection_Close_Stress(t *testing.T) {
+	lim := test.TimeOut(time.Second * 60)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	for i := 0; i < 3; i++ {
+		api := NewAPI()
+		api.mediaEngine.RegisterDefaultCodecs()
+		pcOffer, pcAnswer, err := api.newPair()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = pcOffer.AddTrack(vp8Track); err != nil {
+			t.Fatal(err)
+		}
+
+		received := make(chan struct{})
+		goodbye := make(chan struct{})
+		pcAnswer.OnTrack(func(track *Track) {
+			go func() {
+				for p := range track.RTCPPackets {
+					if bye, ok := p.(*rtcp.Goodbye); ok && len(bye.Sources) == 1 && bye.Sources[0] == vp8Track.SSRC {
+						close(goodbye)
+					}
+				}
+			}()
+
+			<-track.Packets
+			close(received)
+			for range track.Packets {
+			}
+		})
+
+		if err = signalPair(pcOffer, pcAnswer); err != nil {
+			t.Fatal(err)
+		}
+
+	send:
+		for {
+			select {
+			case <-received:
+				break send
+			case <-time.After(20 * time.Millisecond):
+				vp8Track.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1}
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err = pcOffer.CloseWithContext(ctx); err != nil {
+			t.Fatal(err)
+		}
+		cancel()
+
+		// The remote was told the stream ended
+		<-goodbye
+
+		if err = pcAnswer.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -2,13 +2,20 @@ package webrtc
 
 import (
 	"bytes"
+	"fmt"
+	"io"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/pions/rtcp"
+	"github.com/pions/rtp"
 	"github.com/pions/transport/test"
 	"github.com/pions/webrtc/pkg/media"
+	"github.com/pions/webrtc/pkg/rtcerr"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestPeerConnection_Media_Sample(t *testing.T) {
@@ -134,6 +141,20 @@ func TestPeerConnection_Media_Sample(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	stats := pcOffer.dtlsTransport.GetStats()
+	if stats.PacketsSent == 0 || stats.PacketsReceived == 0 || stats.BytesSent == 0 || stats.BytesReceived == 0 {
+		t.Fatalf("Transport traffic was not counted: %+v", stats)
+	}
+	if profile, selected := pcOffer.dtlsTransport.SelectedSRTPProtectionProfile(); !selected || profile != SRTPProtectionProfileAes128CmHmacSha1_80 {
+		t.Fatalf("Unexpected SRTP protection profile %v", profile)
+	}
+	if stats.SRTPCipher != SRTPProtectionProfileAes128CmHmacSha1_80.String() {
+		t.Fatalf("Unexpected SRTP cipher in the stats %q", stats.SRTPCipher)
+	}
+	if stats.DTLSCipher == "" {
+		t.Fatalf("DTLS cipher suite was not in the stats")
+	}
+
 	err = pcOffer.Close()
 	if err != nil {
 		t.Fatal(err)
@@ -228,3 +249,3876 @@ func TestPeerConnection_Media_Shutdown(t *testing.T) {
 	onTrackFiredLock.Unlock()
 
 }
+
+func TestPeerConnection_Media_VideoOrientation(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	if _, err := api.mediaEngine.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: VideoOrientationURI}, RTPCodecTypeVideo); err != nil {
+		t.Fatal(err)
+	}
+
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := VideoOrientation{Flip: true, Rotation: VideoRotation90}
+	awaitOrientation := make(chan bool)
+	awaitRTPRecvClosed := make(chan bool)
+	awaitRTPSend := make(chan bool)
+
+	pcAnswer.OnTrack(func(track *Track) {
+		haveClosedAwaitOrientation := false
+		for {
+			p, ok := <-track.Packets
+			if !ok {
+				close(awaitRTPRecvClosed)
+				return
+			}
+
+			if o, ok := track.VideoOrientation(&p.Header); ok && o == expected && !haveClosedAwaitOrientation {
+				haveClosedAwaitOrientation = true
+				close(awaitOrientation)
+			}
+		}
+	})
+
+	vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := pcOffer.AddTrack(vp8Track)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender.SetVideoOrientation(expected)
+
+	go func() {
+		for {
+			time.Sleep(time.Millisecond * 100)
+			vp8Track.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1}
+
+			select {
+			case <-awaitOrientation:
+				close(awaitRTPSend)
+				return
+			default:
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	<-awaitOrientation
+	<-awaitRTPSend
+
+	if err = pcOffer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	<-awaitRTPRecvClosed
+}
+
+func TestPeerConnection_Media_UndeclaredSSRC(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(vp8Track); err != nil {
+		t.Fatal(err)
+	}
+
+	onTrackFired := make(chan *Track)
+	awaitRTPRecvClosed := make(chan bool)
+	pcAnswer.OnTrack(func(track *Track) {
+		onTrackFired <- track
+		for range track.Packets {
+		}
+		close(awaitRTPRecvClosed)
+	})
+
+	awaitRTPSend := make(chan bool)
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		for {
+			time.Sleep(time.Millisecond * 100)
+			vp8Track.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1}
+
+			select {
+			case <-awaitRTPSend:
+				return
+			default:
+			}
+		}
+	}()
+
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pcOffer.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+
+	// Remove the SSRCs from what the answerer gets
+	var lines []string
+	for _, line := range strings.Split(offer.SDP, "\r\n") {
+		if !strings.HasPrefix(line, "a=ssrc:") {
+			lines = append(lines, line)
+		}
+	}
+	offer.SDP = strings.Join(lines, "\r\n")
+
+	if err = pcAnswer.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := pcAnswer.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.SetLocalDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcOffer.SetRemoteDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+
+	track := <-onTrackFired
+	close(awaitRTPSend)
+	<-awaitRTPSendDone
+	if track.SSRC != vp8Track.SSRC {
+		t.Fatalf("Latched on SSRC %d instead of %d", track.SSRC, vp8Track.SSRC)
+	}
+
+	if err = pcOffer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	<-awaitRTPRecvClosed
+}
+
+func TestPeerConnection_Media_MIDRouting(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	for _, kind := range []RTPCodecType{RTPCodecTypeAudio, RTPCodecTypeVideo} {
+		if _, err := api.mediaEngine.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: MIDURI}, kind); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opusTrack, err := pcOffer.NewSampleTrack(DefaultPayloadTypeOpus, "audio", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(opusTrack); err != nil {
+		t.Fatal(err)
+	}
+	vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(vp8Track); err != nil {
+		t.Fatal(err)
+	}
+
+	onTrackFired := make(chan *Track)
+	var awaitRTPRecvClosed sync.WaitGroup
+	awaitRTPRecvClosed.Add(2)
+	pcAnswer.OnTrack(func(track *Track) {
+		onTrackFired <- track
+		for range track.Packets {
+		}
+		awaitRTPRecvClosed.Done()
+	})
+
+	awaitRTPSend := make(chan bool)
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		for {
+			time.Sleep(time.Millisecond * 100)
+			opusTrack.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1}
+			vp8Track.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1}
+
+			select {
+			case <-awaitRTPSend:
+				return
+			default:
+			}
+		}
+	}()
+
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pcOffer.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+
+	// Remove the SSRCs from what the answerer gets, both streams are undeclared
+	var lines []string
+	for _, line := range strings.Split(offer.SDP, "\r\n") {
+		if !strings.HasPrefix(line, "a=ssrc:") {
+			lines = append(lines, line)
+		}
+	}
+	offer.SDP = strings.Join(lines, "\r\n")
+
+	if err = pcAnswer.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := pcAnswer.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.SetLocalDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcOffer.SetRemoteDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		track := <-onTrackFired
+		expected := vp8Track.SSRC
+		if track.Kind == RTPCodecTypeAudio {
+			expected = opusTrack.SSRC
+		}
+		if track.SSRC != expected {
+			t.Fatalf("%s routed to SSRC %d instead of %d", track.Kind, track.SSRC, expected)
+		}
+	}
+	close(awaitRTPSend)
+	<-awaitRTPSendDone
+
+	if err = pcOffer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	awaitRTPRecvClosed.Wait()
+}
+
+func TestPeerConnection_Media_FullIntraRequest(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(vp8Track); err != nil {
+		t.Fatal(err)
+	}
+
+	awaitFIR := make(chan bool)
+	awaitFIRSend := make(chan error)
+	awaitRTPRecvClosed := make(chan bool)
+	awaitRTPSend := make(chan bool)
+
+	pcAnswer.OnTrack(func(track *Track) {
+		var receiver *RTPReceiver
+		for _, transceiver := range pcAnswer.GetTransceivers() {
+			if r := transceiver.Receiver(); r != nil && r.Track == track {
+				receiver = r
+			}
+		}
+		if receiver == nil {
+			awaitFIRSend <- fmt.Errorf("no RTPReceiver for the track")
+			return
+		}
+
+		go func() {
+			for range track.Packets {
+			}
+			close(awaitRTPRecvClosed)
+		}()
+
+		for {
+			if routineErr := receiver.RequestKeyFrameFIR(); routineErr != nil {
+				awaitFIRSend <- routineErr
+				return
+			}
+
+			time.Sleep(time.Millisecond * 100)
+			select {
+			case <-awaitFIR:
+				close(awaitFIRSend)
+				return
+			default:
+			}
+		}
+	})
+
+	go func() {
+		for {
+			time.Sleep(time.Millisecond * 100)
+			vp8Track.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1}
+
+			select {
+			case <-awaitFIR:
+				close(awaitRTPSend)
+				return
+			default:
+			}
+		}
+	}()
+
+	go func() {
+		for p := range vp8Track.RTCPPackets {
+			if fir, ok := p.(*FullIntraRequest); ok && len(fir.FIR) == 1 && fir.FIR[0].SSRC == vp8Track.SSRC {
+				close(awaitFIR)
+				return
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	if err, ok := <-awaitFIRSend; ok {
+		t.Fatal(err)
+	}
+	<-awaitRTPSend
+
+	if err = pcOffer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	<-awaitRTPRecvClosed
+}
+
+func TestPeerConnection_Media_PauseResume(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := pcOffer.AddTrack(vp8Track)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	awaitResumed := make(chan error)
+	pcAnswer.OnTrack(func(track *Track) {
+		defer close(awaitResumed)
+
+		var receiver *RTPReceiver
+		for _, transceiver := range pcAnswer.GetTransceivers() {
+			if r := transceiver.Receiver(); r != nil && r.Track == track {
+				receiver = r
+			}
+		}
+		if receiver == nil {
+			awaitResumed <- fmt.Errorf("no RTPReceiver for the track")
+			return
+		}
+
+		awaitPaused := make(chan bool)
+		go func() {
+			for p := range track.RTCPPackets {
+				if pauseResume, ok := p.(*PauseResume); ok && pauseResume.Messages[0].Type == PauseResumeTypePaused {
+					close(awaitPaused)
+					break
+				}
+			}
+			for range track.RTCPPackets {
+			}
+		}()
+
+		// The request is sent again until the sender confirms the pause
+		for paused := false; !paused; {
+			if routineErr := receiver.PauseStream(); routineErr != nil {
+				awaitResumed <- routineErr
+				return
+			}
+
+			select {
+			case <-awaitPaused:
+				paused = true
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+		if !sender.Paused() {
+			awaitResumed <- fmt.Errorf("PAUSED was sent but the RTPSender isn't paused")
+			return
+		}
+
+		for sender.Paused() {
+			if routineErr := receiver.ResumeStream(); routineErr != nil {
+				awaitResumed <- routineErr
+				return
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		// Drop what was buffered before the pause, media flows again
+		for len(track.Packets) != 0 {
+			<-track.Packets
+		}
+		<-track.Packets
+	})
+
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		for {
+			time.Sleep(time.Millisecond * 20)
+			vp8Track.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1}
+
+			select {
+			case <-awaitResumed:
+				return
+			default:
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	if err, ok := <-awaitResumed; ok {
+		t.Fatal(err)
+	}
+	<-awaitRTPSendDone
+
+	if err = pcOffer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPeerConnection_Media_OnFirstPacket(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(vp8Track); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first packet already arrived when OnTrack fires
+	awaitFirstPacket := make(chan bool)
+	pcAnswer.OnTrack(func(track *Track) {
+		for _, transceiver := range pcAnswer.GetTransceivers() {
+			if r := transceiver.Receiver(); r != nil && r.Track == track {
+				r.OnFirstPacket(func(ssrc uint32, payloadType uint8) {
+					if ssrc != vp8Track.SSRC || payloadType != DefaultPayloadTypeVP8 {
+						t.Errorf("Unexpected first packet %d %d", ssrc, payloadType)
+					}
+					close(awaitFirstPacket)
+				})
+			}
+		}
+	})
+
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		for {
+			time.Sleep(time.Millisecond * 20)
+			vp8Track.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1}
+
+			select {
+			case <-awaitFirstPacket:
+				return
+			default:
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	<-awaitRTPSendDone
+
+	if err = pcOffer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPeerConnection_Media_DisableRTCP(t *testing.T) {
+	s := SettingEngine{}
+	s.DisableRTCP()
+	api := NewAPI(WithSettingEngine(s))
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	awaitRTPRecv := make(chan bool)
+	awaitRTPRecvClosed := make(chan bool)
+	awaitRTPSend := make(chan bool)
+
+	pcAnswer.OnTrack(func(track *Track) {
+		if _, ok := <-track.RTCPPackets; ok {
+			t.Error("RTCPPackets should be closed when RTCP is disabled")
+		}
+
+		haveClosedAwaitRTPRecv := false
+		for range track.Packets {
+			if !haveClosedAwaitRTPRecv {
+				haveClosedAwaitRTPRecv = true
+				close(awaitRTPRecv)
+			}
+		}
+		close(awaitRTPRecvClosed)
+	})
+
+	vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(vp8Track); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			time.Sleep(time.Millisecond * 100)
+			vp8Track.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1}
+
+			select {
+			case <-awaitRTPRecv:
+				close(awaitRTPSend)
+				return
+			default:
+			}
+		}
+	}()
+
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(offer.SDP, "a=rtcp-fb") {
+		t.Fatal("No RTCP feedback should be offered when RTCP is disabled")
+	}
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	<-awaitRTPRecv
+	<-awaitRTPSend
+
+	if _, ok := <-vp8Track.RTCPPackets; ok {
+		t.Fatal("RTCPPackets should be closed when RTCP is disabled")
+	}
+	if err = pcOffer.SendRTCP(&rtcp.PictureLossIndication{MediaSSRC: vp8Track.SSRC}); err != ErrRTCPDisabled {
+		t.Fatalf("SendRTCP should fail with ErrRTCPDisabled, got %v", err)
+	}
+
+	if err = pcOffer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	<-awaitRTPRecvClosed
+}
+
+func TestPeerConnection_Media_SSRCGroupFID(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vp8Track.rtxSSRC = vp8Track.SSRC + 1
+	if _, err = pcOffer.AddTrack(vp8Track); err != nil {
+		t.Fatal(err)
+	}
+
+	onTrackFired := make(chan *RTPReceiver)
+	awaitRTPRecvClosed := make(chan bool)
+	pcAnswer.OnTrack(func(track *Track) {
+		for _, transceiver := range pcAnswer.GetTransceivers() {
+			if r := transceiver.Receiver(); r != nil && r.Track == track {
+				onTrackFired <- r
+			}
+		}
+		for range track.Packets {
+		}
+		close(awaitRTPRecvClosed)
+	})
+
+	awaitRTPSend := make(chan bool)
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		for {
+			time.Sleep(time.Millisecond * 100)
+			vp8Track.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1}
+
+			select {
+			case <-awaitRTPSend:
+				return
+			default:
+			}
+		}
+	}()
+
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(offer.SDP, fmt.Sprintf("a=ssrc-group:FID %d %d", vp8Track.SSRC, vp8Track.rtxSSRC)) {
+		t.Fatal("The offer doesn't associate the track with its RTX SSRC")
+	}
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	receiver := <-onTrackFired
+	close(awaitRTPSend)
+	<-awaitRTPSendDone
+	if receiver.Track.SSRC != vp8Track.SSRC || receiver.rtxSSRC != vp8Track.rtxSSRC {
+		t.Fatalf("Received SSRC %d with RTX %d instead of %d with %d", receiver.Track.SSRC, receiver.rtxSSRC, vp8Track.SSRC, vp8Track.rtxSSRC)
+	}
+
+	if err = pcOffer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	<-awaitRTPRecvClosed
+}
+
+func TestPeerConnection_Media_NACK(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.settingEngine.EnableNACK()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewRawRTPTrack(DefaultPayloadTypeVP8, 1234, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := pcOffer.AddTrack(vp8Track)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vp8Track.rtxSSRC == 0 {
+		t.Fatal("No RTX SSRC was allocated")
+	}
+
+	// Every tenth packet is lost, it only makes it to the history
+	const lost = 5
+	awaitRecovered := make(chan bool)
+	awaitRTPRecvClosed := make(chan bool)
+	pcAnswer.OnTrack(func(track *Track) {
+		var receiver *RTPReceiver
+		for _, transceiver := range pcAnswer.GetTransceivers() {
+			if r := transceiver.Receiver(); r != nil && r.Track == track {
+				receiver = r
+			}
+		}
+
+		recovered := false
+		for p := range track.Packets {
+			if !recovered && p.SequenceNumber%10 == lost && receiver != nil && receiver.NACKStats().PacketsRecovered != 0 {
+				recovered = true
+				close(awaitRecovered)
+			}
+		}
+		close(awaitRTPRecvClosed)
+	})
+
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		for sequenceNumber := uint16(1); ; sequenceNumber++ {
+			time.Sleep(time.Millisecond * 20)
+			packet := &rtp.Packet{
+				Header: rtp.Header{
+					Version:        2,
+					PayloadType:    DefaultPayloadTypeVP8,
+					SequenceNumber: sequenceNumber,
+					Timestamp:      uint32(sequenceNumber) * 3000,
+					SSRC:           vp8Track.SSRC,
+					Marker:         true,
+				},
+				Payload: []byte{0x10, 0x00},
+			}
+
+			sender.mu.RLock()
+			history := sender.history
+			sender.mu.RUnlock()
+			if history != nil && sequenceNumber%10 == lost {
+				history.add(packet)
+			} else {
+				vp8Track.RawRTP <- packet
+			}
+
+			select {
+			case <-awaitRecovered:
+				return
+			default:
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	<-awaitRecovered
+	<-awaitRTPSendDone
+
+	stats := sender.NACKStats()
+	if stats.NACKsReceived == 0 || stats.RetransmitsSent == 0 {
+		t.Fatalf("Unexpected sender NACK stats %+v", stats)
+	}
+
+	if err = pcOffer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	<-awaitRTPRecvClosed
+}
+
+func TestPeerConnection_Media_NACK_UndeclaredRTX(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.settingEngine.EnableNACK()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewRawRTPTrack(DefaultPayloadTypeVP8, 1234, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := pcOffer.AddTrack(vp8Track)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every tenth packet is lost, it only makes it to the history
+	const lost = 5
+	onTrack := make(chan *Track, 1)
+	awaitRecovered := make(chan bool)
+	awaitRTPRecvClosed := make(chan bool)
+	pcAnswer.OnTrack(func(track *Track) {
+		onTrack <- track
+		recovered := false
+		for p := range track.Packets {
+			if !recovered && p.SequenceNumber%10 == lost && track.receiver.NACKStats().PacketsRecovered != 0 {
+				recovered = true
+				close(awaitRecovered)
+			}
+		}
+		close(awaitRTPRecvClosed)
+	})
+
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		for sequenceNumber := uint16(1); ; sequenceNumber++ {
+			time.Sleep(time.Millisecond * 20)
+			packet := &rtp.Packet{
+				Header: rtp.Header{
+					Version:        2,
+					PayloadType:    DefaultPayloadTypeVP8,
+					SequenceNumber: sequenceNumber,
+					Timestamp:      uint32(sequenceNumber) * 3000,
+					SSRC:           vp8Track.SSRC,
+					Marker:         true,
+				},
+				Payload: []byte{0x10, 0x00},
+			}
+
+			sender.mu.RLock()
+			history := sender.history
+			sender.mu.RUnlock()
+			if history != nil && sequenceNumber%10 == lost {
+				history.add(packet)
+			} else {
+				vp8Track.RawRTP <- packet
+			}
+
+			select {
+			case <-awaitRecovered:
+				return
+			default:
+			}
+		}
+	}()
+
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pcOffer.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+
+	// Neither the media nor the RTX SSRC is declared to the answerer, the
+	// RTX stream is told apart by its payload type
+	var lines []string
+	for _, line := range strings.Split(offer.SDP, "\r\n") {
+		if !strings.HasPrefix(line, "a=ssrc:") && !strings.HasPrefix(line, "a=ssrc-group:") {
+			lines = append(lines, line)
+		}
+	}
+	offer.SDP = strings.Join(lines, "\r\n")
+
+	if err = pcAnswer.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := pcAnswer.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.SetLocalDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcOffer.SetRemoteDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+
+	track := <-onTrack
+	<-awaitRecovered
+	<-awaitRTPSendDone
+	assert.Equal(t, vp8Track.SSRC, track.SSRC, "the Track is the media stream, not its RTX stream")
+
+	if err = pcOffer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	<-awaitRTPRecvClosed
+}
+
+func TestPeerConnection_Media_RED(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	const redPayloadType = 63
+	if err := api.settingEngine.SetAudioRedundancy(1); err != nil {
+		t.Fatal(err)
+	}
+	api.mediaEngine.RegisterCodec(NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000, 2))
+	api.mediaEngine.RegisterCodec(NewRTPRedCodec(redPayloadType, 48000, 2, DefaultPayloadTypeOpus))
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opusTrack, err := pcOffer.NewRawRTPTrack(DefaultPayloadTypeOpus, 1234, "audio", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := pcOffer.AddTrack(opusTrack)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every tenth packet is lost, it is recovered from the next one
+	const lost = 5
+	awaitRecovered := make(chan bool)
+	awaitRTPRecvClosed := make(chan bool)
+	pcAnswer.OnTrack(func(track *Track) {
+		recovered := false
+		for p := range track.Packets {
+			if p.PayloadType != DefaultPayloadTypeOpus || len(p.Payload) != 1 || p.Payload[0] != byte(p.SequenceNumber) {
+				t.Errorf("Unexpected packet %v", p)
+			}
+			if !recovered && p.SequenceNumber%10 == lost {
+				recovered = true
+				close(awaitRecovered)
+			}
+		}
+		close(awaitRTPRecvClosed)
+	})
+
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		red := newREDEncoder(redPayloadType, 1)
+		for sequenceNumber := uint16(1); ; sequenceNumber++ {
+			time.Sleep(time.Millisecond * 20)
+			packet := red.encode(&rtp.Packet{
+				Header: rtp.Header{
+					Version:        2,
+					PayloadType:    DefaultPayloadTypeOpus,
+					SequenceNumber: sequenceNumber,
+					Timestamp:      uint32(sequenceNumber) * 960,
+					SSRC:           opusTrack.SSRC,
+				},
+				Payload: []byte{byte(sequenceNumber)},
+			})
+			if sequenceNumber%10 != lost {
+				sender.writeRTP(packet)
+			}
+
+			select {
+			case <-awaitRecovered:
+				return
+			default:
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	<-awaitRecovered
+	<-awaitRTPSendDone
+
+	// The sender would send the redundancy of the packets written to the Track
+	sender.mu.RLock()
+	senderRED := sender.red
+	sender.mu.RUnlock()
+	if senderRED == nil || senderRED.payloadType != redPayloadType {
+		t.Fatalf("RED was not negotiated for the sender")
+	}
+
+	if err = pcOffer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	<-awaitRTPRecvClosed
+}
+
+func TestPeerConnection_Media_UnhandledRTP(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(vp8Track); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without OnTrack no RTPReceiver reads the stream
+	unhandled := make(chan time.Time, 2)
+	pcAnswer.OnUnhandledRTP(func(ssrc uint32, payloadType uint8, raw []byte) {
+		packet := &rtp.Packet{}
+		if ssrc != vp8Track.SSRC || payloadType != DefaultPayloadTypeVP8 || packet.Unmarshal(raw) != nil || packet.SSRC != ssrc {
+			t.Errorf("Unexpected unhandled packet %d %d %v", ssrc, payloadType, raw)
+		}
+
+		select {
+		case unhandled <- time.Now():
+		default:
+		}
+	})
+
+	awaitRTPSend := make(chan bool)
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		for {
+			time.Sleep(time.Millisecond * 20)
+			vp8Track.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1}
+
+			select {
+			case <-awaitRTPSend:
+				return
+			default:
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	// The handler is rate limited
+	first, second := <-unhandled, <-unhandled
+	if second.Sub(first) < unhandledRTPInterval*9/10 {
+		t.Fatalf("OnUnhandledRTP fired twice in %v", second.Sub(first))
+	}
+	close(awaitRTPSend)
+	<-awaitRTPSendDone
+
+	if err = pcOffer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPeerConnection_Media_Passthrough(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.settingEngine.EnablePassthrough()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(vp8Track); err != nil {
+		t.Fatal(err)
+	}
+
+	awaitPassthrough := make(chan bool)
+	awaitRTPRecvClosed := make(chan bool)
+	awaitRTCPRecvClosed := make(chan bool)
+	pcAnswer.OnTrack(func(track *Track) {
+		var receiver *RTPReceiver
+		for _, transceiver := range pcAnswer.GetTransceivers() {
+			if r := transceiver.Receiver(); r != nil && r.Track == track {
+				receiver = r
+			}
+		}
+		if receiver == nil {
+			t.Error("No RTPReceiver for the Track")
+			return
+		}
+
+		// The packets are only handed out undecoded
+		if _, ok := <-track.Packets; ok {
+			t.Error("A packet was put in the Track with passthrough enabled")
+		}
+		if _, err := receiver.ReadPassthroughRTP(make([]byte, 1500)); err != io.ErrShortBuffer {
+			t.Errorf("ReadPassthroughRTP should fail with a short buffer: %v", err)
+		}
+
+		// The Sender Reports are handed out undecoded too
+		go func() {
+			defer close(awaitRTCPRecvClosed)
+			buf := make([]byte, receiveMTU)
+			for {
+				n, err := receiver.ReadPassthroughRTCP(buf)
+				if err == io.EOF {
+					return
+				} else if err != nil {
+					t.Errorf("ReadPassthroughRTCP should fail with io.EOF once stopped: %v", err)
+					return
+				}
+
+				packets, err := unmarshalCompoundRTCP(buf[:n])
+				if err != nil {
+					t.Error(err)
+				} else if sr, ok := packets[0].(*rtcp.SenderReport); !ok || sr.SSRC != vp8Track.SSRC {
+					t.Errorf("Unexpected passthrough RTCP packet %v", packets[0])
+				}
+			}
+		}()
+
+		buf := make([]byte, receiveMTU)
+		for i := 0; ; i++ {
+			n, err := receiver.ReadPassthroughRTP(buf)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Error(err)
+				break
+			}
+
+			packet := &rtp.Packet{}
+			if err = packet.Unmarshal(buf[:n]); err != nil || packet.SSRC != vp8Track.SSRC {
+				t.Errorf("Unexpected passthrough packet %v: %v", buf[:n], err)
+			}
+			if i == 10 {
+				close(awaitPassthrough)
+			}
+		}
+		close(awaitRTPRecvClosed)
+	})
+
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		for {
+			time.Sleep(time.Millisecond * 20)
+			vp8Track.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1}
+
+			select {
+			case <-awaitPassthrough:
+				return
+			default:
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	<-awaitPassthrough
+	<-awaitRTPSendDone
+
+	if err = pcOffer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	<-awaitRTPRecvClosed
+	<-awaitRTCPRecvClosed
+}
+
+func TestPeerConnection_Media_TransportCC(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	if _, err := api.mediaEngine.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: TransportCCURI}, RTPCodecTypeVideo); err != nil {
+		t.Fatal(err)
+	}
+
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The packets are numbered, the remote reports their arrival in a
+	// compound packet along a reception report
+	awaitFeedbackSent := make(chan bool)
+	awaitRTPRecvClosed := make(chan bool)
+	pcAnswer.OnTrack(func(track *Track) {
+		id, ok := track.headerExtensionID(TransportCCURI)
+		if !ok {
+			t.Error("transport-cc should be negotiated for the Track")
+		}
+
+		// The samples buffered before the connection are sent in a burst,
+		// the feedback is sent for packets past it
+		received := 0
+		var sequenceNumbers []uint16
+		for p := range track.Packets {
+			received++
+			payload, ok := getHeaderExtension(&p.Header, id)
+			if !ok || len(payload) != 2 || received <= 20 || len(sequenceNumbers) == 3 {
+				continue
+			}
+			sequenceNumbers = append(sequenceNumbers, uint16(payload[0])<<8|uint16(payload[1]))
+			if len(sequenceNumbers) != 3 {
+				continue
+			}
+
+			if sequenceNumbers[1] != sequenceNumbers[0]+1 || sequenceNumbers[2] != sequenceNumbers[1]+1 {
+				t.Errorf("transport-wide sequence numbers %v should be consecutive", sequenceNumbers)
+			}
+			err := track.receiver.writeRTCP(
+				&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{{SSRC: track.SSRC}}},
+				&TransportLayerCC{
+					MediaSSRC:          track.SSRC,
+					BaseSequenceNumber: sequenceNumbers[0],
+					Packets: []TransportCCPacketStatus{
+						{Received: true, Delta: 10 * time.Millisecond},
+						{Received: true, Delta: 100 * time.Millisecond},
+						{Received: true, Delta: 100 * time.Millisecond},
+					},
+				},
+			)
+			if err != nil {
+				t.Error(err)
+			}
+			close(awaitFeedbackSent)
+		}
+		close(awaitRTPRecvClosed)
+	})
+
+	vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := pcOffer.AddTrack(vp8Track)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The delay-based estimator grouped the packets reported received
+	feedbackHandled := func() bool {
+		sender.mu.RLock()
+		delayEstimator := sender.delayEstimator
+		sender.mu.RUnlock()
+		if delayEstimator == nil {
+			return false
+		}
+
+		delayEstimator.mu.Lock()
+		defer delayEstimator.mu.Unlock()
+		return delayEstimator.previousGroup != nil
+	}
+
+	awaitRTPSend := make(chan bool)
+	go func() {
+		defer close(awaitRTPSend)
+		for !feedbackHandled() {
+			time.Sleep(time.Millisecond * 20)
+			vp8Track.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	<-awaitFeedbackSent
+	<-awaitRTPSend
+
+	if err = pcOffer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	<-awaitRTPRecvClosed
+}
+
+func TestPeerConnection_Media_TrackClose(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(vp8Track); err != nil {
+		t.Fatal(err)
+	}
+
+	awaitTrackClosed := make(chan bool)
+	pcAnswer.OnTrack(func(track *Track) {
+		defer close(awaitTrackClosed)
+		<-track.Packets
+
+		if err := track.Close(); err != nil {
+			t.Error(err)
+		}
+		if err := track.Close(); err != nil {
+			t.Errorf("Close should be idempotent: %v", err)
+		}
+		for range track.Packets {
+		}
+		for range track.RTCPPackets {
+		}
+	})
+
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		// The samples sent after the Track is closed are discarded
+		for sent := 0; sent < 20; {
+			time.Sleep(time.Millisecond * 20)
+			vp8Track.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1}
+
+			select {
+			case <-awaitTrackClosed:
+				sent++
+			default:
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	<-awaitTrackClosed
+	<-awaitRTPSendDone
+
+	if err = pcOffer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPeerConnection_Media_SDES(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	api.settingEngine.EnableSDES()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(vp8Track); err != nil {
+		t.Fatal(err)
+	}
+
+	trackReceived := make(chan bool)
+	pcAnswer.OnTrack(func(track *Track) {
+		close(trackReceived)
+	})
+
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	video := offer.parsed.MediaDescriptions[1]
+	_, ok := sdesCryptoFromMedia(video)
+	assert.True(t, ok, "the offer should carry the SDES keys")
+	assert.Equal(t, []string{"UDP", "TLS", "RTP", "SAVPF"}, video.MediaName.Protos)
+	if err = pcOffer.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+
+	// A gateway without DTLS-SRTP offers no fingerprint
+	offer.SDP = fingerprintLine.ReplaceAllString(offer.SDP, "")
+	if err = pcAnswer.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := pcAnswer.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, hasFingerprint(answer.parsed), "an SDES answer has no fingerprint")
+	_, ok = sdesCryptoFromMedia(answer.parsed.MediaDescriptions[1])
+	assert.True(t, ok, "the answer should carry the SDES keys")
+	if err = pcAnswer.SetLocalDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcOffer.SetRemoteDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+
+	// The samples stop before the Track is closed with the PeerConnection
+	samplesDone := make(chan struct{})
+	go func() {
+		defer close(samplesDone)
+		for {
+			select {
+			case <-trackReceived:
+				return
+			case <-time.After(time.Millisecond * 20):
+				vp8Track.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1}
+			}
+		}
+	}()
+	<-trackReceived
+	<-samplesDone
+
+	// No DTLS handshake ran
+	assert.Nil(t, pcOffer.dtlsTransport.conn)
+	assert.Nil(t, pcAnswer.dtlsTransport.conn)
+	profile, ok := pcAnswer.dtlsTransport.SelectedSRTPProtectionProfile()
+	assert.True(t, ok)
+	assert.Equal(t, SRTPProtectionProfileAes128CmHmacSha1_80, profile)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_Media_InitialRTPState(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := pcOffer.AddTrack(vp8Track)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = sender.SetInitialRTPState(65000, 50000); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every sample is a single packet one tick apart, the sequence number
+	// and the timestamp move together from the initial state
+	awaitRTPRecv := make(chan bool)
+	pcAnswer.OnTrack(func(track *Track) {
+		p, ok := <-track.Packets
+		if !ok {
+			t.Error("no packet received")
+		} else if sent := p.SequenceNumber - 65000; sent > 100 || p.Timestamp != 50000+uint32(sent) {
+			t.Errorf("unexpected sequence number %d and timestamp %d", p.SequenceNumber, p.Timestamp)
+		}
+		close(awaitRTPRecv)
+		for range track.Packets {
+		}
+	})
+
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		for {
+			time.Sleep(time.Millisecond * 20)
+			vp8Track.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1}
+
+			select {
+			case <-awaitRTPRecv:
+				return
+			default:
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	<-awaitRTPSendDone
+	assert.Equal(t, ErrRTPSenderStarted, sender.SetInitialRTPState(0, 0))
+
+	if err = pcOffer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPeerConnection_Media_MarkerCSRC(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewRawRTPTrack(DefaultPayloadTypeVP8, 1234, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(vp8Track); err != nil {
+		t.Fatal(err)
+	}
+
+	// The header read carries the marker bit and the CSRCs a mixer set
+	awaitRTPRecv := make(chan bool)
+	pcAnswer.OnTrack(func(track *Track) {
+		for p := range track.Packets {
+			if !p.Marker || !track.IsFrameBoundary(p) {
+				continue
+			}
+			assert.Equal(t, []uint32{1, 2}, p.CSRC)
+			close(awaitRTPRecv)
+			break
+		}
+		for range track.Packets {
+		}
+	})
+
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		for sequenceNumber := uint16(0); ; sequenceNumber++ {
+			time.Sleep(time.Millisecond * 20)
+			vp8Track.RawRTP <- &rtp.Packet{
+				Header: rtp.Header{
+					Version:        2,
+					Marker:         sequenceNumber%2 == 1,
+					PayloadType:    DefaultPayloadTypeVP8,
+					SequenceNumber: sequenceNumber,
+					Timestamp:      uint32(sequenceNumber / 2),
+					SSRC:           vp8Track.SSRC,
+					CSRC:           []uint32{1, 2},
+				},
+				Payload: []byte{0x10, 0x00},
+			}
+
+			select {
+			case <-awaitRTPRecv:
+				return
+			default:
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	<-awaitRTPSendDone
+
+	if err = pcOffer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPeerConnection_Media_UnifiedPlan(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each Track of the offer gets its own video section, the Track of the
+	// answer is sent on the first one
+	var tracks []*Track
+	for _, pc := range []*PeerConnection{pcOffer, pcOffer, pcAnswer} {
+		track, err := pc.NewTrack(DefaultPayloadTypeVP8, fmt.Sprintf("video%d", len(tracks)), "pion")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = pc.AddTrack(track); err != nil {
+			t.Fatal(err)
+		}
+		tracks = append(tracks, track)
+	}
+
+	var received sync.WaitGroup
+	received.Add(3)
+	var mu sync.Mutex
+	receivedSSRCs := map[uint32]bool{}
+	onTrack := func(track *Track) {
+		if _, ok := <-track.Packets; ok {
+			mu.Lock()
+			receivedSSRCs[track.SSRC] = true
+			mu.Unlock()
+		}
+		received.Done()
+		for range track.Packets {
+		}
+	}
+	pcOffer.OnTrack(onTrack)
+	pcAnswer.OnTrack(onTrack)
+
+	done := make(chan struct{})
+	sendDone := make(chan struct{})
+	go func() {
+		defer close(sendDone)
+		for {
+			time.Sleep(time.Millisecond * 20)
+			for _, track := range tracks {
+				select {
+				case track.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1}:
+				default:
+				}
+			}
+
+			select {
+			case <-done:
+				return
+			default:
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	medias := pcOffer.RemoteMediaDescriptions()
+	if assert.Len(t, medias, 4) {
+		assert.Equal(t, "video", medias[1].Mid)
+		assert.Equal(t, RTPTransceiverDirectionSendrecv, medias[1].Direction)
+		assert.Equal(t, []uint32{tracks[2].SSRC}, medias[1].SSRCs)
+		assert.Equal(t, "video1", medias[2].Mid)
+		assert.Equal(t, RTPTransceiverDirectionRecvonly, medias[2].Direction)
+	}
+
+	received.Wait()
+	close(done)
+	<-sendDone
+	for _, track := range tracks {
+		assert.True(t, receivedSSRCs[track.SSRC], "no packet received from %d", track.SSRC)
+	}
+
+	if err = pcOffer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPeerConnection_Media_SenderStop(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := pcOffer.AddTrack(vp8Track)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan struct{})
+	goodbye := make(chan struct{})
+	pcAnswer.OnTrack(func(track *Track) {
+		go func() {
+			for p := range track.RTCPPackets {
+				if bye, ok := p.(*rtcp.Goodbye); ok && len(bye.Sources) == 1 && bye.Sources[0] == vp8Track.SSRC {
+					close(goodbye)
+				}
+			}
+		}()
+
+		<-track.Packets
+		close(received)
+		for range track.Packets {
+		}
+	})
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+send:
+	for {
+		select {
+		case <-received:
+			break send
+		case <-time.After(20 * time.Millisecond):
+			assert.NoError(t, vp8Track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}))
+		}
+	}
+
+	// The remote learns the stream ended before the PeerConnection closes
+	sender.Stop()
+	<-goodbye
+	sender.Stop()
+	assert.Equal(t, ErrRTPSenderStopped, vp8Track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}))
+
+	if err = pcOffer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPeerConnection_Media_DisconnectedBufferFlushedOnReconnect(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	assert.NoError(t, api.settingEngine.SetDisconnectedBuffer(time.Minute))
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	track, err := pcOffer.NewRawRTPTrack(DefaultPayloadTypeVP8, 1234, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := pcOffer.AddTrack(track)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	held := make(chan struct{})
+	pcAnswer.OnTrack(func(track *Track) {
+		for p := range track.Packets {
+			if p.SequenceNumber == 0xFFFF {
+				close(held)
+			}
+		}
+	})
+
+	connected := make(chan struct{})
+	var connectedOnce sync.Once
+	pcOffer.OnConnectionStateChange(func(state PeerConnectionState) {
+		if state == PeerConnectionStateConnected {
+			connectedOnce.Do(func() { close(connected) })
+		}
+	})
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+	<-connected
+
+	// A packet held back while disconnected is sent once connected, without
+	// waiting for the next one written
+	sender.disconnected.push(&rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			SSRC:           track.SSRC,
+			PayloadType:    DefaultPayloadTypeVP8,
+			SequenceNumber: 0xFFFF,
+		},
+		Payload: []byte{0x00},
+	}, time.Now())
+	pcOffer.iceTransport.onConnectionStateChange(ICETransportStateConnected)
+	<-held
+	assert.Zero(t, sender.stats().PacketsDroppedDisconnected)
+
+	if err = pcOffer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPeerConnection_Media_PayloadTransform(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	// The payloads are sent with their bits flipped and flipped back once
+	// received
+	flip := func(info PayloadTransformInfo, payload []byte) ([]byte, error) {
+		if info.Header.SSRC != info.Track.SSRC {
+			return nil, fmt.Errorf("unexpected SSRC %d", info.Header.SSRC)
+		}
+		flipped := make([]byte, len(payload))
+		for i, b := range payload {
+			flipped[i] = ^b
+		}
+		return flipped, nil
+	}
+	// The frames are two packets long
+	var sendErrors, receiveErrors, transformed uint32
+	send := func(info PayloadTransformInfo, payload []byte) ([]byte, error) {
+		if info.Sender == nil || info.Receiver != nil ||
+			info.FrameStart != (info.Header.SequenceNumber%2 == 0) || info.FrameEnd != info.Header.Marker {
+			atomic.AddUint32(&sendErrors, 1)
+		}
+		return flip(info, payload)
+	}
+	receive := func(info PayloadTransformInfo, payload []byte) ([]byte, error) {
+		if !bytes.Equal(payload, []byte{0xAA, 0xBB}) {
+			return nil, fmt.Errorf("unexpected payload %v", payload)
+		}
+		// The first packet received starts its stream
+		first := atomic.AddUint32(&transformed, 1) == 1
+		if info.Receiver == nil || info.Sender != nil ||
+			(info.FrameStart && info.Header.SequenceNumber%2 == 1 && !first) || info.FrameEnd != info.Header.Marker {
+			atomic.AddUint32(&receiveErrors, 1)
+		}
+		return flip(info, payload)
+	}
+	api.settingEngine.SetPayloadTransforms(send, receive)
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	track, err := pcOffer.NewRawRTPTrack(DefaultPayloadTypeVP8, 1234, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(track); err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan struct{})
+	pcAnswer.OnTrack(func(track *Track) {
+		p, ok := <-track.Packets
+		if !ok {
+			t.Error("no packet received")
+		} else if !bytes.Equal(p.Payload, []byte{0x55, 0x44}) {
+			t.Errorf("unexpected payload %v", p.Payload)
+		}
+		close(received)
+		for range track.Packets {
+		}
+	})
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	for sequenceNumber := uint16(0); ; sequenceNumber++ {
+		time.Sleep(20 * time.Millisecond)
+		assert.NoError(t, track.WriteRTP(&rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				SSRC:           track.SSRC,
+				PayloadType:    DefaultPayloadTypeVP8,
+				SequenceNumber: sequenceNumber,
+				Timestamp:      uint32(sequenceNumber/2) * 3000,
+				Marker:         sequenceNumber%2 == 1,
+			},
+			Payload: []byte{0x55, 0x44},
+		}, nil))
+
+		select {
+		case <-received:
+		default:
+			continue
+		}
+		break
+	}
+	assert.NotZero(t, atomic.LoadUint32(&transformed))
+	assert.Zero(t, atomic.LoadUint32(&sendErrors))
+	assert.Zero(t, atomic.LoadUint32(&receiveErrors))
+
+	if err = pcOffer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+func TestRTPReceiver_DisableRTCP(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	stackA, stackB, err := newORTCPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = signalORTCPair(stackA, stackB); err != nil {
+		t.Fatal(err)
+	}
+
+	track, err := NewRawRTPTrack(DefaultPayloadTypeVP8, 1234, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := stackA.api.NewRTPSender(track, stackA.dtls)
+	sender.Send(RTPSendParameters{
+		Encodings: RTPEncodingParameters{RTPCodingParameters{SSRC: track.SSRC, PayloadType: track.PayloadType}},
+	})
+
+	receiver := stackB.api.NewRTPReceiver(RTPCodecTypeVideo, stackB.dtls)
+	assert.NoError(t, receiver.DisableRTCP())
+	hasRecv, err := receiver.Receive(RTPReceiveParameters{
+		Encodings: RTPDecodingParameters{RTPCodingParameters{SSRC: track.SSRC}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ErrRTPReceiverStarted, receiver.DisableRTCP())
+
+	go func() {
+		for sequenceNumber := uint16(0); ; sequenceNumber++ {
+			select {
+			case <-hasRecv:
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+			packet := &rtp.Packet{Header: rtp.Header{Version: 2, SSRC: track.SSRC, PayloadType: track.PayloadType, SequenceNumber: sequenceNumber}, Payload: []byte{0x00}}
+			if err := track.WriteRTP(packet, nil); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	<-hasRecv
+
+	// The SRTP stream is read, the SRTCP one was never opened
+	if _, err = receiver.ReadRTP(); err != nil {
+		t.Fatal(err)
+	}
+	receiver.mu.Lock()
+	assert.Nil(t, receiver.rtcpReadStream)
+	receiver.mu.Unlock()
+	_, err = receiver.ReadRTCP()
+	assert.Equal(t, ErrSRTCPNotEstablished, err)
+	assert.Equal(t, ErrRTCPDisabled, receiver.RequestKeyFramePLI())
+	_, err = receiver.ReadPassthroughRTCP(make([]byte, receiveMTU))
+	assert.Equal(t, ErrPassthroughDisabled, err)
+
+	// The DTLS transport of the receiver drains the BYE of the sender.
+	// Closing the SRTP session ends the ReadLoop blocked on the stream.
+	sender.Stop()
+	assert.NoError(t, stackB.dtls.Stop())
+	assert.NoError(t, receiver.Stop())
+	assert.NoError(t, stackA.dtls.Stop())
+	assert.NoError(t, stackA.close())
+	assert.NoError(t, stackB.close())
+}
+
+func TestPeerConnection_RewritePayloadType(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	// The answerer numbers VP8 otherwise than the offerer
+	offerAPI := NewAPI()
+	offerAPI.mediaEngine.RegisterCodec(NewRTPVP8Codec(100, 90000))
+	answerAPI := NewAPI()
+	answerAPI.mediaEngine.RegisterCodec(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+
+	pcOffer, err := offerAPI.NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pcAnswer, err := answerAPI.NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pcOffer.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+
+	track, err := pcAnswer.NewRawRTPTrack(DefaultPayloadTypeVP8, 1234, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := pcAnswer.AddTrack(track)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The other payload types of the forwarded stream are mapped
+	sender.SetPayloadTypeMap(map[uint8]uint8{97: 110})
+
+	var mu sync.Mutex
+	received := map[uint8]bool{}
+	receivedAll := make(chan struct{})
+	pcOffer.OnTrack(func(track *Track) {
+		for p := range track.Packets {
+			mu.Lock()
+			received[p.PayloadType] = true
+			if len(received) == 2 {
+				select {
+				case <-receivedAll:
+				default:
+					close(receivedAll)
+				}
+			}
+			mu.Unlock()
+		}
+	})
+
+	answer, err := pcAnswer.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.SetLocalDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcOffer.SetRemoteDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for sequenceNumber := uint16(0); ; sequenceNumber++ {
+			payloadType := uint8(DefaultPayloadTypeVP8)
+			if sequenceNumber%2 == 1 {
+				payloadType = 97
+			}
+			if track.WriteRTP(&rtp.Packet{
+				Header: rtp.Header{
+					Version:        2,
+					PayloadType:    payloadType,
+					SequenceNumber: sequenceNumber,
+					SSRC:           track.SSRC,
+				},
+				Payload: []byte{0x00},
+			}, nil) != nil {
+				return
+			}
+
+			select {
+			case <-receivedAll:
+				return
+			case <-time.After(time.Millisecond * 10):
+			}
+		}
+	}()
+
+	<-receivedAll
+	<-done
+
+	mu.Lock()
+	assert.Equal(t, map[uint8]bool{100: true, 110: true}, received)
+	mu.Unlock()
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_Media_RIDRestrictions(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	for _, uri := range []string{MIDURI, RIDURI} {
+		if _, err := api.mediaEngine.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: uri}, RTPCodecTypeVideo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := pcOffer.AddTrack(vp8Track)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restrictions := RIDRestrictions{MaxWidth: 640, MaxHeight: 360, MaxFPS: 15}
+	if err = sender.SetRID("q", restrictions); err != nil {
+		t.Fatal(err)
+	}
+
+	onTrackFired := make(chan *Track)
+	awaitRTPRecvClosed := make(chan struct{})
+	pcAnswer.OnTrack(func(track *Track) {
+		onTrackFired <- track
+		for range track.Packets {
+		}
+		close(awaitRTPRecvClosed)
+	})
+
+	awaitRTPSend := make(chan bool)
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		for {
+			time.Sleep(time.Millisecond * 100)
+			vp8Track.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1}
+
+			select {
+			case <-awaitRTPSend:
+				return
+			default:
+			}
+		}
+	}()
+
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pcOffer.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the SSRCs the answerer routes the layer by its RID
+	var lines []string
+	for _, line := range strings.Split(offer.SDP, "\r\n") {
+		if !strings.HasPrefix(line, "a=ssrc:") {
+			lines = append(lines, line)
+		}
+	}
+	offer.SDP = strings.Join(lines, "\r\n")
+
+	if err = pcAnswer.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := pcAnswer.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, answer.SDP, "a=simulcast:recv q\r\n")
+	if err = pcAnswer.SetLocalDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcOffer.SetRemoteDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+
+	track := <-onTrackFired
+	assert.Equal(t, "q", track.RID)
+	assert.Equal(t, restrictions, track.RIDRestrictions)
+	assert.Equal(t, vp8Track.SSRC, track.SSRC)
+
+	close(awaitRTPSend)
+	<-awaitRTPSendDone
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+	<-awaitRTPRecvClosed
+}
+func TestPeerConnection_Media_SimulcastSSRC(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	for _, uri := range []string{MIDURI, RIDURI} {
+		if _, err := api.mediaEngine.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: uri}, RTPCodecTypeVideo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := pcOffer.AddTrack(vp8Track)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restrictions := RIDRestrictions{MaxWidth: 640, MaxHeight: 360}
+	if err = sender.SetRID("h", restrictions); err != nil {
+		t.Fatal(err)
+	}
+
+	onTrackFired := make(chan *Track)
+	awaitRTPRecvClosed := make(chan struct{})
+	pcAnswer.OnTrack(func(track *Track) {
+		onTrackFired <- track
+		for range track.Packets {
+		}
+		close(awaitRTPRecvClosed)
+	})
+
+	awaitRTPSend := make(chan bool)
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		for {
+			time.Sleep(time.Millisecond * 100)
+			vp8Track.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1}
+
+			select {
+			case <-awaitRTPSend:
+				return
+			default:
+			}
+		}
+	}()
+
+	// The layer is declared with its SSRC as well, it is named by the RID
+	// header extension of its packets
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, pcOffer.LocalDescription().SDP, fmt.Sprintf("a=ssrc:%d ", vp8Track.SSRC))
+
+	track := <-onTrackFired
+	assert.Equal(t, vp8Track.SSRC, track.SSRC)
+	assert.Equal(t, "h", track.RID)
+	assert.Equal(t, restrictions, track.RIDRestrictions)
+
+	close(awaitRTPSend)
+	<-awaitRTPSendDone
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+	<-awaitRTPRecvClosed
+}
+
+func TestPeerConnection_Media_ReceiveSSRC(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	if _, err := api.mediaEngine.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: MIDURI}, RTPCodecTypeVideo); err != nil {
+		t.Fatal(err)
+	}
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(vp8Track); err != nil {
+		t.Fatal(err)
+	}
+
+	// The SSRC is learned out of band, the receiver exists before the media
+	receiver, err := pcAnswer.ReceiveSSRC(RTPCodecTypeVideo, "video", vp8Track.SSRC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = pcAnswer.ReceiveSSRC(RTPCodecTypeVideo, "video", vp8Track.SSRC)
+	assert.Equal(t, ErrSSRCAnnounced, err)
+
+	onTrackFired := make(chan *Track)
+	awaitRTPRecvClosed := make(chan struct{})
+	pcAnswer.OnTrack(func(track *Track) {
+		onTrackFired <- track
+		for range track.Packets {
+		}
+		close(awaitRTPRecvClosed)
+	})
+
+	awaitRTPSend := make(chan bool)
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		for {
+			time.Sleep(time.Millisecond * 100)
+			vp8Track.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1}
+
+			select {
+			case <-awaitRTPSend:
+				return
+			default:
+			}
+		}
+	}()
+
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pcOffer.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the SSRCs the stream could be routed by its mid or latched on,
+	// the announced SSRC takes precedence
+	var lines []string
+	for _, line := range strings.Split(offer.SDP, "\r\n") {
+		if !strings.HasPrefix(line, "a=ssrc:") {
+			lines = append(lines, line)
+		}
+	}
+	offer.SDP = strings.Join(lines, "\r\n")
+
+	if err = pcAnswer.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := pcAnswer.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.SetLocalDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcOffer.SetRemoteDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+
+	track := <-onTrackFired
+	assert.Equal(t, receiver.Track, track)
+	assert.Equal(t, vp8Track.SSRC, track.SSRC)
+	assert.Equal(t, VP8, track.Codec.Name)
+
+	var transceiver *RTPTransceiver
+	for _, tr := range pcAnswer.GetTransceivers() {
+		if tr.Receiver() == receiver {
+			transceiver = tr
+		}
+	}
+	if assert.NotNil(t, transceiver) {
+		assert.Equal(t, "video", transceiver.Mid())
+	}
+
+	close(awaitRTPSend)
+	<-awaitRTPSendDone
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+	<-awaitRTPRecvClosed
+
+	_, err = pcAnswer.ReceiveSSRC(RTPCodecTypeVideo, "video", 1234)
+	assert.Error(t, err)
+}
+
+func TestPeerConnection_Media_PaddingOnly(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	for _, deliver := range []bool{false, true} {
+		api := NewAPI()
+		if deliver {
+			api.settingEngine.DeliverPaddingPackets()
+		}
+		api.mediaEngine.RegisterDefaultCodecs()
+		pcOffer, pcAnswer, err := api.newPair()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		vp8Track, err := pcOffer.NewRawRTPTrack(DefaultPayloadTypeVP8, 1234, "video", "pion")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = pcOffer.AddTrack(vp8Track); err != nil {
+			t.Fatal(err)
+		}
+
+		// Every other packet is a bandwidth probe
+		received := make(chan []bool)
+		pcAnswer.OnTrack(func(track *Track) {
+			var padding []bool
+			for p := range track.Packets {
+				if padding = append(padding, isPaddingOnly(p)); len(padding) == 10 {
+					break
+				}
+			}
+			received <- padding
+			for range track.Packets {
+			}
+		})
+
+		awaitRTPSend := make(chan bool)
+		awaitRTPSendDone := make(chan bool)
+		go func() {
+			defer close(awaitRTPSendDone)
+			for sequenceNumber := uint16(0); ; sequenceNumber++ {
+				time.Sleep(time.Millisecond * 20)
+				packet := &rtp.Packet{
+					Header: rtp.Header{
+						Version:        2,
+						PayloadType:    DefaultPayloadTypeVP8,
+						SequenceNumber: sequenceNumber,
+						SSRC:           vp8Track.SSRC,
+					},
+					Payload: []byte{0x10, 0x00},
+				}
+				if sequenceNumber%2 == 1 {
+					packet.Padding = true
+					packet.Payload = []byte{0x00, 0x00, 0x00, 0x04}
+				}
+				vp8Track.RawRTP <- packet
+
+				select {
+				case <-awaitRTPSend:
+					return
+				default:
+				}
+			}
+		}()
+
+		if err = signalPair(pcOffer, pcAnswer); err != nil {
+			t.Fatal(err)
+		}
+
+		padding := <-received
+		if deliver {
+			assert.Contains(t, padding, true)
+		} else {
+			assert.NotContains(t, padding, true)
+		}
+
+		close(awaitRTPSend)
+		<-awaitRTPSendDone
+
+		for _, transceiver := range pcAnswer.GetTransceivers() {
+			if receiver := transceiver.Receiver(); receiver != nil && receiver.Track != nil {
+				stats, ok := receiver.stats()
+				if assert.True(t, ok) {
+					assert.NotZero(t, stats.PacketsPadding)
+				}
+			}
+		}
+
+		assert.NoError(t, pcOffer.Close())
+		assert.NoError(t, pcAnswer.Close())
+	}
+}
+
+func TestPeerConnection_Media_ConcurrentOnTrack(t *testing.T) {
+	lim := test.TimeOut(time.Second * 60)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	const trackCount = 50
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tracks := make([]*Track, trackCount)
+	for i := range tracks {
+		if tracks[i], err = pcOffer.NewRawRTPTrack(DefaultPayloadTypeVP8, uint32(1000+i), fmt.Sprintf("video%d", i), "pion"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = pcOffer.AddTrack(tracks[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The handlers only return once every one of them was entered, they
+	// deadlock unless fired concurrently. Each one calls back into the
+	// PeerConnection meanwhile.
+	var entered, dispatched sync.WaitGroup
+	entered.Add(trackCount)
+	dispatched.Add(trackCount)
+	var mu sync.Mutex
+	receivedSSRCs := map[uint32]int{}
+	pcAnswer.OnTrack(func(track *Track) {
+		entered.Done()
+		entered.Wait()
+
+		found := false
+		for _, transceiver := range pcAnswer.GetTransceivers() {
+			if receiver := transceiver.Receiver(); receiver != nil && receiver.Track == track {
+				found = true
+			}
+		}
+		assert.True(t, found)
+
+		mu.Lock()
+		receivedSSRCs[track.SSRC]++
+		mu.Unlock()
+		dispatched.Done()
+
+		for range track.Packets {
+		}
+	})
+
+	awaitRTPSend := make(chan bool)
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		for sequenceNumber := uint16(0); ; sequenceNumber++ {
+			time.Sleep(time.Millisecond * 100)
+			for _, track := range tracks {
+				track.RawRTP <- &rtp.Packet{
+					Header: rtp.Header{
+						Version:        2,
+						PayloadType:    DefaultPayloadTypeVP8,
+						SequenceNumber: sequenceNumber,
+						SSRC:           track.SSRC,
+					},
+					Payload: []byte{0x10, 0x00},
+				}
+			}
+
+			select {
+			case <-awaitRTPSend:
+				return
+			default:
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	dispatched.Wait()
+	close(awaitRTPSend)
+	<-awaitRTPSendDone
+
+	// Each Track was handed to OnTrack once
+	assert.Len(t, receivedSSRCs, trackCount)
+	for _, track := range tracks {
+		assert.Equal(t, 1, receivedSSRCs[track.SSRC], "SSRC %d", track.SSRC)
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+func TestPeerConnection_Media_ReceiveTime(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewRawRTPTrack(DefaultPayloadTypeVP8, 1234, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(vp8Track); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each packet arrived before it is read, in order
+	start := time.Now()
+	done := make(chan error)
+	pcAnswer.OnTrack(func(track *Track) {
+		var last time.Time
+		var err error
+		count := 0
+		for p := range track.Packets {
+			read := time.Now()
+			received, ok := track.ReceiveTime(p)
+			switch {
+			case err != nil:
+			case !ok:
+				err = fmt.Errorf("packet %d has no receive time", p.SequenceNumber)
+			case received.Before(start) || received.After(read):
+				err = fmt.Errorf("packet %d received at %v, not between %v and %v", p.SequenceNumber, received, start, read)
+			case received.Before(last):
+				err = fmt.Errorf("packet %d received at %v, before the previous one at %v", p.SequenceNumber, received, last)
+			}
+			last = received
+			if count++; count == 10 {
+				done <- err
+			}
+		}
+	})
+
+	awaitRTPSend := make(chan bool)
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		for sequenceNumber := uint16(0); ; sequenceNumber++ {
+			time.Sleep(time.Millisecond * 20)
+			vp8Track.RawRTP <- &rtp.Packet{
+				Header: rtp.Header{
+					Version:        2,
+					PayloadType:    DefaultPayloadTypeVP8,
+					SequenceNumber: sequenceNumber,
+					SSRC:           vp8Track.SSRC,
+				},
+				Payload: []byte{0x10, 0x00},
+			}
+
+			select {
+			case <-awaitRTPSend:
+				return
+			default:
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, <-done)
+
+	// A sent Track has none
+	_, ok := vp8Track.ReceiveTime(&rtp.Packet{})
+	assert.False(t, ok)
+
+	close(awaitRTPSend)
+	<-awaitRTPSendDone
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+func TestPeerConnection_Media_ActiveStreams(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterCodec(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewRawRTPTrack(DefaultPayloadTypeVP8, 1234, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := pcOffer.AddTrack(vp8Track)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	onTrack := make(chan *RTPReceiver, 1)
+	pcAnswer.OnTrack(func(track *Track) {
+		onTrack <- track.receiver
+		for range track.Packets {
+		}
+	})
+
+	// Every other packet is of an undeclared SSRC
+	awaitRTPSend := make(chan bool)
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		for sequenceNumber := uint16(0); ; sequenceNumber++ {
+			time.Sleep(time.Millisecond * 20)
+			ssrc := vp8Track.SSRC
+			if sequenceNumber%2 == 1 {
+				ssrc = 5678
+			}
+			vp8Track.RawRTP <- &rtp.Packet{
+				Header: rtp.Header{
+					Version:        2,
+					PayloadType:    DefaultPayloadTypeVP8,
+					SequenceNumber: sequenceNumber,
+					SSRC:           ssrc,
+				},
+				Payload: []byte{0x10, 0x00},
+			}
+
+			select {
+			case <-awaitRTPSend:
+				return
+			default:
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+	receiver := <-onTrack
+
+	// The undeclared SSRC is listed once it arrived
+	var streams []ActiveStream
+	for len(streams) < 2 {
+		time.Sleep(time.Millisecond * 20)
+		streams = pcAnswer.dtlsTransport.ActiveStreams()
+	}
+	if assert.Len(t, streams, 2) {
+		assert.Equal(t, uint32(1234), streams[0].SSRC)
+		assert.Equal(t, RTPTransceiverDirectionRecvonly, streams[0].Direction)
+		assert.Equal(t, receiver, streams[0].Receiver)
+		assert.False(t, streams[0].LastActivity.IsZero())
+
+		// It is drained, nobody receives it
+		assert.Equal(t, uint32(5678), streams[1].SSRC)
+		assert.Equal(t, RTPTransceiverDirectionRecvonly, streams[1].Direction)
+		assert.Nil(t, streams[1].Receiver)
+		assert.False(t, streams[1].LastActivity.IsZero())
+	}
+
+	streams = pcOffer.dtlsTransport.ActiveStreams()
+	if assert.Len(t, streams, 1) {
+		assert.Equal(t, uint32(1234), streams[0].SSRC)
+		assert.Equal(t, RTPTransceiverDirectionSendonly, streams[0].Direction)
+		assert.Equal(t, sender, streams[0].Sender)
+		assert.False(t, streams[0].LastActivity.IsZero())
+	}
+
+	close(awaitRTPSend)
+	<-awaitRTPSendDone
+
+	// A stopped RTPSender is no longer listed
+	sender.Stop()
+	assert.Empty(t, pcOffer.dtlsTransport.ActiveStreams())
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+func TestPeerConnection_Media_WriteRTCP(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(vp8Track); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is discarded before the transport connected
+	pli := &rtcp.PictureLossIndication{MediaSSRC: vp8Track.SSRC}
+	assert.Equal(t, ErrDTLSTransportNotConnected, pcAnswer.WriteRTCP([]rtcp.Packet{pli}))
+
+	onTrack := make(chan *Track, 1)
+	pcAnswer.OnTrack(func(track *Track) {
+		onTrack <- track
+		for range track.Packets {
+		}
+	})
+
+	awaitRTPSend := make(chan bool)
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		for {
+			time.Sleep(time.Millisecond * 20)
+			vp8Track.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1}
+
+			select {
+			case <-awaitRTPSend:
+				return
+			default:
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+	track := <-onTrack
+
+	// The feedback about several SSRCs, one of them unknown, is batched
+	remb := &ReceiverEstimatedMaximumBitrate{Bitrate: 100000, SSRCs: []uint32{track.SSRC, 9999}}
+	assert.NoError(t, pcAnswer.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: track.SSRC}, remb}))
+	for p := range vp8Track.RTCPPackets {
+		if received, ok := p.(*rtcp.PictureLossIndication); ok {
+			assert.Equal(t, track.SSRC, received.MediaSSRC)
+			break
+		}
+	}
+
+	close(awaitRTPSend)
+	<-awaitRTPSendDone
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+
+	closedErr := &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
+	assert.Equal(t, closedErr, pcAnswer.WriteRTCP([]rtcp.Packet{pli}))
+}
+
+func TestPeerConnection_Media_GetStats(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := pcOffer.AddTrack(vp8Track)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, sender.GetStats(), "nothing is sent before Send")
+
+	dc, err := pcOffer.CreateDataChannel("stats", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := make(chan struct{})
+	dc.OnOpen(func() {
+		defer close(sent)
+		assert.NoError(t, dc.SendText("hello"))
+	})
+	received := make(chan struct{})
+	remoteClosed := make(chan struct{})
+	pcAnswer.OnDataChannel(func(d *DataChannel) {
+		d.OnMessage(func(DataChannelMessage) {
+			close(received)
+		})
+		d.OnClose(func() {
+			close(remoteClosed)
+		})
+	})
+
+	// The stats are sampled once 10 packets arrived
+	onTrack := make(chan *Track, 1)
+	pcAnswer.OnTrack(func(track *Track) {
+		count := 0
+		for range track.Packets {
+			if count++; count == 10 {
+				onTrack <- track
+			}
+		}
+	})
+
+	awaitRTPSend := make(chan bool)
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		for {
+			time.Sleep(time.Millisecond * 20)
+			vp8Track.Samples <- media.Sample{Data: []byte{0x00, 0x01, 0x02}, Samples: 1}
+
+			select {
+			case <-awaitRTPSend:
+				return
+			default:
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+	track := <-onTrack
+	<-sent
+	<-received
+
+	offerStats := pcOffer.GetStats()
+	outbound, ok := offerStats[fmt.Sprintf("RTCOutboundRTPStream_%d", vp8Track.SSRC)].(*OutboundRTPStreamStats)
+	if assert.True(t, ok) {
+		assert.Equal(t, StatsTypeOutboundRTP, outbound.Type)
+		assert.Equal(t, "video", outbound.Kind)
+		assert.Equal(t, transportStatsID, outbound.TransportID)
+		assert.True(t, outbound.PacketsSent >= 10)
+		assert.True(t, outbound.BytesSent >= outbound.PacketsSent)
+		assert.True(t, outbound.HeaderBytesSent >= 12*outbound.PacketsSent)
+	}
+	assert.Len(t, sender.GetStats(), 1)
+
+	transport, ok := offerStats[transportStatsID].(*DTLSTransportStats)
+	if assert.True(t, ok) {
+		assert.Equal(t, DTLSTransportStateConnected, transport.DTLSState)
+		assert.NotZero(t, transport.BytesSent)
+		pair, ok := offerStats[transport.SelectedCandidatePairID].(*ICECandidatePairStats)
+		if assert.True(t, ok, "the selected pair is in the collection") {
+			assert.Equal(t, StatsTypeCandidatePair, pair.Type)
+			assert.True(t, pair.Selected)
+			assert.Equal(t, ICECandidatePairStateSucceeded, pair.State)
+		}
+	}
+
+	channel, ok := offerStats[fmt.Sprintf("RTCDataChannel_%d", *dc.ID)].(*DataChannelStats)
+	if assert.True(t, ok) {
+		assert.Equal(t, "stats", channel.Label)
+		assert.Equal(t, DataChannelStateOpen, channel.State)
+		assert.Equal(t, uint64(1), channel.MessagesSent)
+		assert.Equal(t, uint64(5), channel.BytesSent)
+	}
+
+	answerStats := pcAnswer.GetStats()
+	inbound, ok := answerStats[fmt.Sprintf("RTCInboundRTPStream_%d", track.SSRC)].(*InboundRTPStreamStats)
+	if assert.True(t, ok) {
+		assert.Equal(t, StatsTypeInboundRTP, inbound.Type)
+		assert.Equal(t, "video", inbound.Kind)
+		assert.True(t, inbound.PacketsReceived >= 10)
+		assert.Equal(t, int64(0), inbound.PacketsLost)
+		assert.True(t, inbound.Jitter >= 0)
+		assert.False(t, inbound.LastPacketReceivedTimestamp.IsZero())
+	}
+	channels := 0
+	for _, s := range answerStats {
+		if channel, ok := s.(*DataChannelStats); ok {
+			channels++
+			assert.Equal(t, uint64(1), channel.MessagesReceived)
+			assert.Equal(t, uint64(5), channel.BytesReceived)
+		}
+	}
+	assert.Equal(t, 1, channels, "the channels of the remote are counted")
+
+	// The channels of the remote are forgotten once closed
+	assert.NoError(t, dc.Close())
+	<-remoteClosed
+	for _, s := range pcAnswer.GetStats() {
+		_, ok := s.(*DataChannelStats)
+		assert.False(t, ok, "a closed channel of the remote is counted")
+	}
+
+	close(awaitRTPSend)
+	<-awaitRTPSendDone
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_Media_ReceiverReports(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	api.settingEngine.EnableReceiverReports()
+	assert.NoError(t, api.settingEngine.SetReceiverReportInterval(100*time.Millisecond))
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(vp8Track); err != nil {
+		t.Fatal(err)
+	}
+
+	pcAnswer.OnTrack(func(track *Track) {
+		for range track.Packets {
+		}
+	})
+
+	awaitRTPSend := make(chan bool)
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		for {
+			time.Sleep(time.Millisecond * 20)
+			vp8Track.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1}
+
+			select {
+			case <-awaitRTPSend:
+				return
+			default:
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	// The reports come without the application sending any RTCP
+	for p := range vp8Track.RTCPPackets {
+		rr, ok := p.(*rtcp.ReceiverReport)
+		if !ok || len(rr.Reports) == 0 {
+			continue
+		}
+		assert.Equal(t, vp8Track.SSRC, rr.Reports[0].SSRC)
+		assert.NotZero(t, rr.Reports[0].LastSequenceNumber)
+		assert.Equal(t, uint32(0), rr.Reports[0].TotalLost)
+		break
+	}
+
+	close(awaitRTPSend)
+	<-awaitRTPSendDone
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+// pliInterceptor reports the PLIs written and read by the RTPSenders and
+// RTPReceivers it is bound to
+type pliInterceptor struct {
+	NoOpInterceptor
+	written, read chan uint32
+}
+
+func (i *pliInterceptor) BindRTCPWriter(info StreamInfo, writer RTCPWriter) RTCPWriter {
+	return RTCPWriterFunc(func(packets []rtcp.Packet) error {
+		for _, p := range packets {
+			if pli, ok := p.(*rtcp.PictureLossIndication); ok {
+				select {
+				case i.written <- pli.MediaSSRC:
+				default:
+				}
+			}
+		}
+		return writer.WriteRTCP(packets)
+	})
+}
+
+func (i *pliInterceptor) BindRTCPReader(info StreamInfo, reader RTCPReader) RTCPReader {
+	return RTCPReaderFunc(func() ([]rtcp.Packet, error) {
+		packets, err := reader.ReadRTCP()
+		for _, p := range packets {
+			if pli, ok := p.(*rtcp.PictureLossIndication); ok && pli.MediaSSRC == info.SSRC {
+				select {
+				case i.read <- info.SSRC:
+				default:
+				}
+			}
+		}
+		return packets, err
+	})
+}
+
+func TestPeerConnection_Media_Interceptors(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	plis := &pliInterceptor{written: make(chan uint32, 1), read: make(chan uint32, 1)}
+	api := NewAPI(WithInterceptors(&markingInterceptor{mark: 0x01}, plis))
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewRawRTPTrack(DefaultPayloadTypeVP8, 1234, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(vp8Track); err != nil {
+		t.Fatal(err)
+	}
+
+	awaitRTPRecv := make(chan bool)
+	pcAnswer.OnTrack(func(track *Track) {
+		// The packet is marked once written and once read
+		packet, ok := <-track.Packets
+		if ok {
+			assert.Equal(t, []byte{0x10, 0x00, 0x01, 0x01}, packet.Payload)
+			assert.NoError(t, track.receiver.RequestKeyFramePLI())
+		}
+		close(awaitRTPRecv)
+		for range track.Packets {
+		}
+	})
+
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		for sequenceNumber := uint16(0); ; sequenceNumber++ {
+			time.Sleep(time.Millisecond * 20)
+			vp8Track.RawRTP <- &rtp.Packet{
+				Header: rtp.Header{
+					Version:        2,
+					PayloadType:    DefaultPayloadTypeVP8,
+					SequenceNumber: sequenceNumber,
+					SSRC:           vp8Track.SSRC,
+				},
+				Payload: []byte{0x10, 0x00},
+			}
+
+			select {
+			case <-awaitRTPRecv:
+				return
+			default:
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	<-awaitRTPSendDone
+
+	// The PLI of the RTPReceiver is written and read through the Interceptor
+	assert.Equal(t, vp8Track.SSRC, <-plis.written)
+	assert.Equal(t, vp8Track.SSRC, <-plis.read)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_Media_Renegotiation(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	negotiationNeeded := make(chan struct{}, 1)
+	pcOffer.OnNegotiationNeeded(func() {
+		negotiationNeeded <- struct{}{}
+	})
+
+	received := make(chan *Track, 2)
+	ended := make(chan uint32, 2)
+	pcAnswer.OnTrack(func(track *Track) {
+		received <- track
+		for range track.Packets {
+		}
+		ended <- track.SSRC
+	})
+
+	awaitRTPSend := make(chan struct{})
+	var sending sync.WaitGroup
+	send := func(track *Track) {
+		sending.Add(1)
+		go func() {
+			defer sending.Done()
+			for {
+				time.Sleep(time.Millisecond * 20)
+				if track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}) != nil {
+					return
+				}
+
+				select {
+				case <-awaitRTPSend:
+					return
+				default:
+				}
+			}
+		}()
+	}
+
+	addTrack := func(id string) (*Track, *RTPSender) {
+		track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, id, "pion")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sender, err := pcOffer.AddTrack(track)
+		if err != nil {
+			t.Fatal(err)
+		}
+		<-negotiationNeeded
+		return track, sender
+	}
+
+	track1, sender1 := addTrack("video1")
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+	send(track1)
+	assert.Equal(t, track1.SSRC, (<-received).SSRC)
+
+	// The Track added once connected gets a media section after the
+	// negotiated ones
+	track2, _ := addTrack("video2")
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+	var mids []string
+	for _, media := range pcAnswer.RemoteMediaDescriptions() {
+		mids = append(mids, media.Mid)
+	}
+	assert.Equal(t, []string{"audio", "video", "data", "video1"}, mids)
+	send(track2)
+	assert.Equal(t, track2.SSRC, (<-received).SSRC)
+
+	// The removed Track ends on the remote once renegotiated
+	assert.NoError(t, pcOffer.RemoveTrack(sender1))
+	<-negotiationNeeded
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, track1.SSRC, <-ended)
+
+	close(awaitRTPSend)
+	sending.Wait()
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_Media_ReceiveBandwidthEstimation(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	// The feedback the remote negotiated is sent, with the estimate of the
+	// RTPReceiver for REMB
+	for _, transportCC := range []bool{false, true} {
+		api := NewAPI()
+		api.settingEngine.EnableReceiveBandwidthEstimation()
+		codec := NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000)
+		codec.RTCPFeedback = append(codec.RTCPFeedback, RTCPFeedback{Type: TypeRTCPFBGoogREMB})
+		api.mediaEngine.RegisterCodec(codec)
+		if transportCC {
+			if _, err := api.mediaEngine.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: TransportCCURI}, RTPCodecTypeVideo); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		pcOffer, pcAnswer, err := api.newPair()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sender, err := pcOffer.AddTrack(vp8Track)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		estimated := make(chan int, 1)
+		awaitRTPRecvClosed := make(chan bool)
+		pcAnswer.OnTrack(func(track *Track) {
+			track.receiver.OnBandwidthEstimate(func(bps int) {
+				select {
+				case estimated <- bps:
+				default:
+				}
+			})
+			for range track.Packets {
+			}
+			close(awaitRTPRecvClosed)
+		})
+
+		awaitRTPSend := make(chan bool)
+		awaitRTPSendDone := make(chan bool)
+		go func() {
+			defer close(awaitRTPSendDone)
+			for {
+				time.Sleep(time.Millisecond * 20)
+				vp8Track.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1800}
+
+				select {
+				case <-awaitRTPSend:
+					return
+				default:
+				}
+			}
+		}()
+
+		if err = signalPair(pcOffer, pcAnswer); err != nil {
+			t.Fatal(err)
+		}
+		assert.True(t, <-estimated >= defaultMinBitrate)
+
+		if transportCC {
+			// The delay-based estimator of the sender grouped the packets
+			// reported received
+			for handled := false; !handled; time.Sleep(20 * time.Millisecond) {
+				sender.mu.RLock()
+				delayEstimator := sender.delayEstimator
+				sender.mu.RUnlock()
+
+				delayEstimator.mu.Lock()
+				handled = delayEstimator.previousGroup != nil
+				delayEstimator.mu.Unlock()
+			}
+		} else {
+			for p := range vp8Track.RTCPPackets {
+				if remb, ok := p.(*ReceiverEstimatedMaximumBitrate); ok {
+					assert.Equal(t, []uint32{vp8Track.SSRC}, remb.SSRCs)
+					assert.True(t, remb.Bitrate >= defaultMinBitrate)
+					break
+				}
+			}
+		}
+
+		close(awaitRTPSend)
+		<-awaitRTPSendDone
+
+		assert.NoError(t, pcOffer.Close())
+		assert.NoError(t, pcAnswer.Close())
+		<-awaitRTPRecvClosed
+	}
+}
+
+func TestPeerConnection_Media_AbsSendTime(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	if _, err := api.mediaEngine.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: AbsSendTimeURI}, RTPCodecTypeVideo); err != nil {
+		t.Fatal(err)
+	}
+
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The send time is stamped on every packet
+	awaitSendTimes := make(chan []time.Duration)
+	awaitRTPRecvClosed := make(chan bool)
+	pcAnswer.OnTrack(func(track *Track) {
+		var sendTimes []time.Duration
+		for p := range track.Packets {
+			sendTime, ok := track.AbsSendTime(&p.Header)
+			if !ok {
+				t.Error("abs-send-time should be stamped on the packets")
+			}
+			if sendTimes = append(sendTimes, sendTime); len(sendTimes) == 2 {
+				awaitSendTimes <- sendTimes
+			}
+		}
+		close(awaitRTPRecvClosed)
+	})
+
+	vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(vp8Track); err != nil {
+		t.Fatal(err)
+	}
+
+	awaitRTPSend := make(chan bool)
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		for {
+			time.Sleep(time.Millisecond * 20)
+			vp8Track.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1800}
+
+			select {
+			case <-awaitRTPSend:
+				return
+			default:
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+	sendTimes := <-awaitSendTimes
+	elapsed := (sendTimes[1] - sendTimes[0] + 64*time.Second) % (64 * time.Second)
+	assert.True(t, elapsed < time.Second, "send times %v should grow, modulo 64 seconds", sendTimes)
+
+	close(awaitRTPSend)
+	<-awaitRTPSendDone
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+	<-awaitRTPRecvClosed
+}
+
+// BenchmarkRTPReceiver_ReadPassthroughRTP measures the cost per packet of the
+// passthrough path, the number of streams a forwarding unit can relay is
+// about the inverse of it. The allocations reported are those of both peers:
+// the sending side, and the decryption of pions/srtp on the receiving one,
+// the layers of the read path below it don't allocate, see
+// BenchmarkSRTPAuthConn_Read.
+func BenchmarkRTPReceiver_ReadPassthroughRTP(b *testing.B) {
+	for _, streams := range []int{1, 16} {
+		streams := streams
+		b.Run(fmt.Sprintf("Streams%d", streams), func(b *testing.B) {
+			benchmarkReadPassthroughRTP(b, streams)
+		})
+	}
+}
+
+// benchmarkReadPassthroughRTP reads b.N packets over the streams, each read
+// concurrently with the others
+func benchmarkReadPassthroughRTP(b *testing.B, streams int) {
+	api := NewAPI()
+	api.settingEngine.EnablePassthrough()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	// The packets are sent until the benchmark is done, with a bounded number
+	// in flight not to overflow the sockets. A lost packet keeps its place,
+	// the sender goes on after a while.
+	inFlight := 32 / streams
+	if inFlight < 2 {
+		inFlight = 2
+	}
+	windows := map[uint32]chan struct{}{}
+	payload := make([]byte, 1200)
+	awaitRTPSend := make(chan bool)
+	var sendersDone sync.WaitGroup
+	for i := 0; i < streams; i++ {
+		ssrc := uint32(1234 + i)
+		track, trackErr := pcOffer.NewRawRTPTrack(DefaultPayloadTypeVP8, ssrc, fmt.Sprintf("video%d", i), "pion")
+		if trackErr != nil {
+			b.Fatal(trackErr)
+		}
+		if _, err = pcOffer.AddTrack(track); err != nil {
+			b.Fatal(err)
+		}
+
+		window := make(chan struct{}, inFlight)
+		windows[ssrc] = window
+		sendersDone.Add(1)
+		go func() {
+			defer sendersDone.Done()
+			for sequenceNumber := uint16(0); ; sequenceNumber++ {
+				select {
+				case <-awaitRTPSend:
+					return
+				case window <- struct{}{}:
+				case <-time.After(10 * time.Millisecond):
+				}
+
+				select {
+				case <-awaitRTPSend:
+					return
+				case track.RawRTP <- &rtp.Packet{
+					Header: rtp.Header{
+						Version:        2,
+						PayloadType:    DefaultPayloadTypeVP8,
+						SequenceNumber: sequenceNumber,
+						SSRC:           ssrc,
+					},
+					Payload: payload,
+				}:
+				}
+			}
+		}()
+	}
+
+	// Each stream is read as soon as it fired OnTrack, with its RTCP, an
+	// unread stream stalls the others. The benchmark is timed once every
+	// stream is read.
+	var read, target int64
+	targetRead := make(chan struct{})
+	var targetOnce sync.Once
+	var readersDone sync.WaitGroup
+	tracks := make(chan struct{}, streams)
+	pcAnswer.OnTrack(func(track *Track) {
+		var receiver *RTPReceiver
+		for _, transceiver := range pcAnswer.GetTransceivers() {
+			if r := transceiver.Receiver(); r != nil && r.Track == track {
+				receiver = r
+			}
+		}
+
+		readersDone.Add(2)
+		go func() {
+			defer readersDone.Done()
+			buf := make([]byte, receiveMTU)
+			for {
+				if _, err := receiver.ReadPassthroughRTCP(buf); err != nil {
+					return
+				}
+			}
+		}()
+		go func() {
+			defer readersDone.Done()
+			window := windows[track.SSRC]
+			buf := make([]byte, receiveMTU)
+			for {
+				if _, err := receiver.ReadPassthroughRTP(buf); err != nil {
+					return
+				}
+				select {
+				case <-window:
+				default:
+				}
+				if t := atomic.LoadInt64(&target); atomic.AddInt64(&read, 1) >= t && t != 0 {
+					targetOnce.Do(func() { close(targetRead) })
+				}
+			}
+		}()
+		tracks <- struct{}{}
+	})
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < streams; i++ {
+		<-tracks
+	}
+
+	b.SetBytes(int64(len(payload)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	atomic.StoreInt64(&target, atomic.LoadInt64(&read)+int64(b.N))
+	<-targetRead
+	b.StopTimer()
+
+	close(awaitRTPSend)
+	sendersDone.Wait()
+	if err = pcOffer.Close(); err != nil {
+		b.Fatal(err)
+	}
+	if err = pcAnswer.Close(); err != nil {
+		b.Fatal(err)
+	}
+	readersDone.Wait()
+}
+
+func TestPeerConnection_Media_SenderRTCP(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := pcOffer.AddTrack(vp8Track)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var onPLI sync.Once
+	awaitPLI := make(chan struct{})
+	sender.OnPictureLossIndication(func() {
+		onPLI.Do(func() { close(awaitPLI) })
+	})
+	var onRTCP sync.Once
+	awaitRTCP := make(chan struct{})
+	sender.OnRTCP(func(p rtcp.Packet) {
+		if pli, ok := p.(*rtcp.PictureLossIndication); ok && pli.MediaSSRC == vp8Track.SSRC {
+			onRTCP.Do(func() { close(awaitRTCP) })
+		}
+	})
+
+	awaitSenderReport := make(chan *rtcp.SenderReport, 1)
+	pcAnswer.OnTrack(func(track *Track) {
+		go func() {
+			for range track.Packets {
+			}
+		}()
+
+		// The reports come without the application sending any RTCP
+		for p := range track.RTCPPackets {
+			if sr, ok := p.(*rtcp.SenderReport); ok && sr.SSRC == track.SSRC {
+				awaitSenderReport <- sr
+				break
+			}
+		}
+		for range track.RTCPPackets {
+		}
+	})
+
+	awaitRTPSend := make(chan bool)
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		for {
+			time.Sleep(time.Millisecond * 20)
+			vp8Track.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1}
+
+			select {
+			case <-awaitRTPSend:
+				return
+			default:
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	sr := <-awaitSenderReport
+	assert.NotZero(t, sr.PacketCount)
+	assert.Equal(t, 2*sr.PacketCount, sr.OctetCount)
+	assert.WithinDuration(t, time.Now(), fromNTPTime(sr.NTPTime), time.Second)
+
+	receiver := pcAnswer.GetTransceivers()[0].Receiver()
+	assert.NoError(t, receiver.RequestKeyFramePLI())
+	<-awaitPLI
+	<-awaitRTCP
+
+	close(awaitRTPSend)
+	<-awaitRTPSendDone
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_Media_JitterBuffer(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api := NewAPI()
+	if err := api.settingEngine.EnableJitterBuffer(100 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewRawRTPTrack(DefaultPayloadTypeVP8, 1234, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(vp8Track); err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan []uint16)
+	pcAnswer.OnTrack(func(track *Track) {
+		var sequenceNumbers []uint16
+		for p := range track.Packets {
+			if sequenceNumbers = append(sequenceNumbers, p.SequenceNumber); len(sequenceNumbers) == 10 {
+				break
+			}
+		}
+		received <- sequenceNumbers
+		for range track.Packets {
+		}
+	})
+
+	// The packets after the first one are sent in swapped pairs
+	awaitRTPSend := make(chan bool)
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		for i := uint16(0); ; i++ {
+			time.Sleep(time.Millisecond * 20)
+			sequenceNumber := i
+			if i != 0 {
+				sequenceNumber = i + 1 - 2*((i+1)%2)
+			}
+			vp8Track.RawRTP <- &rtp.Packet{
+				Header: rtp.Header{
+					Version:        2,
+					PayloadType:    DefaultPayloadTypeVP8,
+					SequenceNumber: sequenceNumber,
+					SSRC:           vp8Track.SSRC,
+				},
+				Payload: []byte{0x10, 0x00},
+			}
+
+			select {
+			case <-awaitRTPSend:
+				return
+			default:
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	sequenceNumbers := <-received
+	for i := 1; i < len(sequenceNumbers); i++ {
+		assert.Equal(t, sequenceNumbers[i-1]+1, sequenceNumbers[i])
+	}
+
+	close(awaitRTPSend)
+	<-awaitRTPSendDone
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
@@ -1,16 +1,25 @@
 package webrtc
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/x509"
+	"fmt"
 	"math/big"
+	"net"
 	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/pions/rtp"
+	"github.com/pions/sdp/v2"
+	"github.com/pions/transport/test"
 	"github.com/pions/webrtc/pkg/ice"
 	"github.com/pions/webrtc/pkg/media"
 
@@ -270,6 +279,16 @@ func TestPeerConnection_SetConfiguration(t *testing.T) {
 			},
 			wantErr: &rtcerr.InvalidModificationError{Err: ErrModifyingRTCPMuxPolicy},
 		},
+		{
+			name: "update SDPSemantics",
+			init: func() (*PeerConnection, error) {
+				return api.NewPeerConnection(Configuration{})
+			},
+			config: Configuration{
+				SDPSemantics: SDPSemanticsPlanB,
+			},
+			wantErr: &rtcerr.InvalidModificationError{Err: ErrModifyingSDPSemantics},
+		},
 		{
 			name: "update ICECandidatePoolSize",
 			init: func() (*PeerConnection, error) {
@@ -325,6 +344,67 @@ func TestPeerConnection_SetConfiguration(t *testing.T) {
 	}
 }
 
+func TestPeerConnection_SetConfiguration_ICETransportPolicy(t *testing.T) {
+	candidateTypes := func(pc *PeerConnection) []ICECandidateType {
+		candidates, err := pc.iceGatherer.GetLocalCandidates()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var types []ICECandidateType
+		for _, c := range candidates {
+			types = append(types, c.Typ)
+		}
+		return types
+	}
+
+	pc, err := NewPeerConnection(Configuration{ICETransportPolicy: ICETransportPolicyRelay})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nothing but relay candidates, which need a TURN server
+	assert.Empty(t, candidateTypes(pc))
+
+	// The gathering is widened until a description is set
+	assert.NoError(t, pc.SetConfiguration(Configuration{ICETransportPolicy: ICETransportPolicyAll}))
+	assert.Contains(t, candidateTypes(pc), ICECandidateTypeHost)
+	assert.NoError(t, pc.SetConfiguration(Configuration{ICETransportPolicy: ICETransportPolicyNoHost}))
+	assert.NotContains(t, candidateTypes(pc), ICECandidateTypeHost)
+	assert.NoError(t, pc.SetConfiguration(Configuration{ICETransportPolicy: ICETransportPolicyAll}))
+
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pc.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+
+	// The signaled candidates are kept, the policy is left for the next
+	// ICE restart
+	assert.NoError(t, pc.SetConfiguration(Configuration{ICETransportPolicy: ICETransportPolicyRelay}))
+	assert.Equal(t, ICETransportPolicyRelay, pc.GetConfiguration().ICETransportPolicy)
+	assert.Contains(t, candidateTypes(pc), ICECandidateTypeHost)
+	assert.NoError(t, pc.Close())
+
+	// The ICEConn of the SettingEngine can't be gathered again
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IP{127, 0, 0, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := SettingEngine{}
+	s.SetICEConn(conn)
+	pc, err = NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t,
+		&rtcerr.InvalidModificationError{Err: ErrModifyingICETransportPolicy},
+		pc.SetConfiguration(Configuration{ICETransportPolicy: ICETransportPolicyRelay}),
+	)
+	assert.Contains(t, candidateTypes(pc), ICECandidateTypeHost)
+	assert.NoError(t, pc.Close())
+}
+
 func TestPeerConnection_GetConfiguration(t *testing.T) {
 	api := NewAPI()
 	pc, err := api.NewPeerConnection(Configuration{})
@@ -335,6 +415,7 @@ func TestPeerConnection_GetConfiguration(t *testing.T) {
 		ICETransportPolicy:   ICETransportPolicyAll,
 		BundlePolicy:         BundlePolicyBalanced,
 		RTCPMuxPolicy:        RTCPMuxPolicyRequire,
+		SDPSemantics:         SDPSemanticsUnifiedPlan,
 		Certificates:         []Certificate{},
 		ICECandidatePoolSize: 0,
 	}
@@ -344,6 +425,7 @@ func TestPeerConnection_GetConfiguration(t *testing.T) {
 	assert.Equal(t, expected.ICETransportPolicy, actual.ICETransportPolicy)
 	assert.Equal(t, expected.BundlePolicy, actual.BundlePolicy)
 	assert.Equal(t, expected.RTCPMuxPolicy, actual.RTCPMuxPolicy)
+	assert.Equal(t, expected.SDPSemantics, actual.SDPSemantics)
 	assert.NotEqual(t, len(expected.Certificates), len(actual.Certificates))
 	assert.Equal(t, expected.ICECandidatePoolSize, actual.ICECandidatePoolSize)
 }
@@ -529,3 +611,1287 @@ func TestPeerConnection_EventHandlers(t *testing.T) {
 		<-onDataChannelCalled,
 	}))
 }
+
+func TestPeerConnection_AddICECandidate(t *testing.T) {
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pcOffer.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+
+	const validCandidate = "candidate:1 1 udp 2130706431 192.168.1.5 50000 typ host"
+	audioMid, bundledMid, unknownMid := "audio", "video", "unknown"
+	outOfRange := uint16(10)
+
+	testCases := []struct {
+		candidate ICECandidateInit
+		expectErr bool
+	}{
+		{ICECandidateInit{Candidate: validCandidate}, false},
+		// duplicates are silently ignored
+		{ICECandidateInit{Candidate: validCandidate}, false},
+		{ICECandidateInit{Candidate: validCandidate, SDPMid: &audioMid}, false},
+		// candidates for unknown or bundled media sections are dropped
+		{ICECandidateInit{Candidate: validCandidate, SDPMid: &bundledMid}, false},
+		{ICECandidateInit{Candidate: validCandidate, SDPMid: &unknownMid}, false},
+		{ICECandidateInit{Candidate: validCandidate, SDPMLineIndex: &outOfRange}, false},
+		// the end of the candidates
+		{ICECandidateInit{Candidate: ""}, false},
+		{ICECandidateInit{Candidate: "candidate:1 1 udp 2130706431 192.168.1.5 50000 host"}, true},
+		{ICECandidateInit{Candidate: "candidate:1 3 udp 2130706431 192.168.1.5 50000 typ host"}, true},
+		{ICECandidateInit{Candidate: "candidate:1 1 udp 2130706431 not-an-ip 50000 typ host"}, true},
+		{ICECandidateInit{Candidate: "candidate:1 1 udp 2130706431 192.168.1.5 0 typ host"}, true},
+		{ICECandidateInit{Candidate: "candidate:1 1 udp 2130706431 192.168.1.5 50000 typ bogus"}, true},
+		{ICECandidateInit{Candidate: "candidate:1 1 udp"}, true},
+	}
+
+	for i, testCase := range testCases {
+		err := pcAnswer.AddICECandidate(testCase.candidate)
+		if testCase.expectErr {
+			assert.Error(t, err, "testCase: %d %v", i, testCase)
+		} else {
+			assert.NoError(t, err, "testCase: %d %v", i, testCase)
+		}
+	}
+
+	assert.True(t, pcAnswer.acceptsCandidateFor(&audioMid, nil))
+	assert.False(t, pcAnswer.acceptsCandidateFor(&bundledMid, nil))
+	assert.False(t, pcAnswer.acceptsCandidateFor(&unknownMid, nil))
+	assert.False(t, pcAnswer.acceptsCandidateFor(nil, &outOfRange))
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_GetTransceivers(t *testing.T) {
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opusTrack, err := pcOffer.NewSampleTrack(DefaultPayloadTypeOpus, "audio", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(vp8Track); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(opusTrack); err != nil {
+		t.Fatal(err)
+	}
+
+	// Before negotiation the transceivers are in creation order
+	transceivers := pcOffer.GetTransceivers()
+	assert.Len(t, transceivers, 2)
+	assert.Equal(t, "video", transceivers[0].Mid())
+	assert.Equal(t, RTPTransceiverDirectionSendonly, transceivers[0].Direction())
+	assert.Equal(t, RTPTransceiverDirection(Unknown), transceivers[0].CurrentDirection())
+	assert.Equal(t, vp8Track, transceivers[0].Sender().Track)
+	assert.Nil(t, transceivers[0].Receiver())
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once negotiated they follow the media sections
+	transceivers = pcOffer.GetTransceivers()
+	assert.Len(t, transceivers, 2)
+	assert.Equal(t, "audio", transceivers[0].Mid())
+	assert.Equal(t, "video", transceivers[1].Mid())
+	for _, transceiver := range transceivers {
+		assert.Equal(t, RTPTransceiverDirectionSendonly, transceiver.CurrentDirection())
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_CreateOffer_Codecs(t *testing.T) {
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offer, err := pcOffer.CreateOffer(&OfferOptions{OfferAnswerOptions: OfferAnswerOptions{Codecs: []string{Opus, VP8}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, offer.SDP, "opus/48000")
+	assert.Contains(t, offer.SDP, "VP8/90000")
+	assert.NotContains(t, offer.SDP, "G722")
+	assert.NotContains(t, offer.SDP, "H264")
+	assert.NotContains(t, offer.SDP, "VP9")
+
+	if err = pcAnswer.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+
+	// Filtering out every codec of a kind drops its media section
+	answer, err := pcAnswer.CreateAnswer(&AnswerOptions{OfferAnswerOptions: OfferAnswerOptions{Codecs: []string{VP8}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, answer.SDP, "VP8/90000")
+	assert.NotContains(t, answer.SDP, "m=audio")
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_CreateAnswer_CodecPreference(t *testing.T) {
+	offerAPI := NewAPI()
+	offerAPI.mediaEngine.RegisterCodec(NewRTPVP8Codec(100, 90000))
+	offerAPI.mediaEngine.RegisterCodec(NewRTPVP9Codec(101, 90000))
+
+	// The answerer prefers VP9, with other payload types than the offerer
+	answerAPI := NewAPI()
+	answerAPI.mediaEngine.RegisterCodec(NewRTPVP9Codec(DefaultPayloadTypeVP9, 90000))
+	answerAPI.mediaEngine.RegisterCodec(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+
+	pcOffer, err := offerAPI.NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pcAnswer, err := answerAPI.NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, offer.SDP, "m=video 9 UDP/TLS/RTP/SAVPF 100 101\r\n")
+
+	if err = pcAnswer.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+
+	vp9, err := answerAPI.mediaEngine.getCodec(DefaultPayloadTypeVP9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	track, err := NewSampleTrack(DefaultPayloadTypeVP9, "video", "pion", vp9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcAnswer.AddTrack(track); err != nil {
+		t.Fatal(err)
+	}
+
+	answer, err := pcAnswer.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, answer.SDP, "m=video 9 UDP/TLS/RTP/SAVPF 101 100\r\n")
+	assert.Contains(t, answer.SDP, "a=rtpmap:101 VP9/90000")
+	assert.Contains(t, answer.SDP, "a=rtpmap:100 VP8/90000")
+	assert.NotContains(t, answer.SDP, fmt.Sprintf("a=rtpmap:%d ", DefaultPayloadTypeVP9))
+
+	// The samples of the Track are sent with the payload type of the offer
+	assert.Equal(t, uint8(101), pcAnswer.negotiatedPayloadType(track))
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_SDPFilter(t *testing.T) {
+	var filtered []SDPType
+	s := SettingEngine{}
+	s.SetSDPFilter(func(sdpType SDPType, sdp string) string {
+		filtered = append(filtered, sdpType)
+		return sdp + "a=x-filtered:" + sdpType.String() + "\r\n"
+	})
+
+	api := NewAPI(WithSettingEngine(s))
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, strings.HasSuffix(offer.SDP, "a=x-filtered:offer\r\n"))
+	if err = pcOffer.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = pcAnswer.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := pcAnswer.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, strings.HasSuffix(answer.SDP, "a=x-filtered:answer\r\n"))
+	assert.Equal(t, []SDPType{SDPTypeOffer, SDPTypeAnswer}, filtered)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+
+	// A filtered SDP that doesn't parse fails the creation
+	s.SetSDPFilter(func(SDPType, string) string { return "v=bogus" })
+	pc, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pc.CreateOffer(nil); err == nil {
+		t.Fatal("CreateOffer should fail with a malformed filtered SDP")
+	}
+	assert.NoError(t, pc.Close())
+}
+
+func TestPeerConnection_MaxBitrate(t *testing.T) {
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offer, err := pcOffer.CreateOffer(&OfferOptions{OfferAnswerOptions: OfferAnswerOptions{MaxBitrate: 500000}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, strings.Count(offer.SDP, "b=AS:500\r\n"))
+
+	if err = pcOffer.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, uint64(500000), pcAnswer.negotiatedMaxBitrate(RTPCodecTypeVideo))
+
+	answer, err := pcAnswer.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotContains(t, answer.SDP, "b=")
+
+	// The media lines take precedence over the session ones
+	answer.parsed.Bandwidth = []sdp.Bandwidth{{Type: "AS", Bandwidth: 64}}
+	answer.parsed.MediaDescriptions[1].Bandwidth = []sdp.Bandwidth{{Type: "AS", Bandwidth: 1000}}
+	raw, err := answer.parsed.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	answer.SDP = string(raw)
+	if err = pcOffer.SetRemoteDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, uint64(64000), pcOffer.negotiatedMaxBitrate(RTPCodecTypeAudio))
+	assert.Equal(t, uint64(1000000), pcOffer.negotiatedMaxBitrate(RTPCodecTypeVideo))
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_RTCPFeedback(t *testing.T) {
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, offer.SDP, fmt.Sprintf("a=rtcp-fb:%d nack pli", DefaultPayloadTypeVP8))
+	assert.Contains(t, offer.SDP, fmt.Sprintf("a=rtcp-fb:%d ccm fir", DefaultPayloadTypeVP8))
+	assert.NotContains(t, offer.SDP, fmt.Sprintf("a=rtcp-fb:%d", DefaultPayloadTypeOpus))
+
+	// A legacy endpoint only offering FIR
+	offer.SDP = regexp.MustCompile(`a=rtcp-fb:\d+ nack pli\r\n`).ReplaceAllString(offer.SDP, "")
+	if err = pcAnswer.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+
+	answer, err := pcAnswer.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotContains(t, answer.SDP, "nack pli")
+	assert.Contains(t, answer.SDP, fmt.Sprintf("a=rtcp-fb:%d ccm fir", DefaultPayloadTypeVP8))
+
+	feedback := pcAnswer.negotiatedRTCPFeedback(RTPCodecTypeVideo)
+	assert.True(t, hasRTCPFeedback(feedback, RTCPFeedback{Type: TypeRTCPFBCCM, Parameter: RTCPFBParameterFIR}))
+	assert.False(t, hasRTCPFeedback(feedback, RTCPFeedback{Type: TypeRTCPFBNACK, Parameter: RTCPFBParameterPLI}))
+	assert.Empty(t, pcAnswer.negotiatedRTCPFeedback(RTPCodecTypeAudio))
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_ReducedSizeRTCP(t *testing.T) {
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The video section of the offer doesn't accept reduced-size RTCP
+	sections := strings.SplitAfter(offer.SDP, "m=")
+	for i, section := range sections {
+		if strings.HasPrefix(section, "video") {
+			sections[i] = strings.Replace(section, "a=rtcp-rsize\r\n", "", 1)
+		}
+	}
+	offer.SDP = strings.Join(sections, "")
+	if err = pcAnswer.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, pcAnswer.negotiatedReducedSizeRTCP(RTPCodecTypeAudio))
+	assert.False(t, pcAnswer.negotiatedReducedSizeRTCP(RTPCodecTypeVideo))
+	assert.False(t, pcAnswer.negotiatedReducedSizeRTCP())
+	assert.False(t, pcOffer.negotiatedReducedSizeRTCP())
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_TransceiverStop(t *testing.T) {
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(track); err != nil {
+		t.Fatal(err)
+	}
+
+	transceivers := pcOffer.GetTransceivers()
+	if len(transceivers) != 1 {
+		t.Fatalf("expected 1 transceiver, got %d", len(transceivers))
+	}
+	transceiver := transceivers[0]
+	assert.False(t, transceiver.Stopped())
+
+	assert.NoError(t, transceiver.Stop())
+	assert.True(t, transceiver.Stopped())
+	assert.Equal(t, RTPTransceiverDirectionInactive, transceiver.Direction())
+	assert.Equal(t, RTPTransceiverDirectionInactive, transceiver.CurrentDirection())
+	assert.NoError(t, transceiver.Stop(), "Stop should be idempotent")
+	assert.Error(t, transceiver.setSendingTrack(track))
+
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, offer.SDP, "m=video 0 ")
+	assert.Contains(t, offer.SDP, "a=group:BUNDLE audio data")
+	assert.NotContains(t, offer.SDP, fmt.Sprintf("a=ssrc:%d", track.SSRC))
+
+	// The remote rejects the media section too
+	if err = pcAnswer.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := pcAnswer.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, answer.SDP, "m=video 0 ")
+	assert.NotContains(t, answer.SDP, "BUNDLE audio video")
+
+	// A new track doesn't reuse the stopped RTPTransceiver
+	track, err = pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video2", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(track); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, pcOffer.GetTransceivers(), 2)
+
+	offer, err = pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, offer.SDP, "m=video 9 ")
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_DTLSFailure(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offerFailed := make(chan bool)
+	pcOffer.OnConnectionStateChange(func(state PeerConnectionState) {
+		if state == PeerConnectionStateFailed {
+			close(offerFailed)
+		}
+	})
+	answerConnected := make(chan bool)
+	pcAnswer.OnConnectionStateChange(func(state PeerConnectionState) {
+		if state == PeerConnectionStateConnected {
+			close(answerConnected)
+		}
+	})
+
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pcOffer.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := pcAnswer.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.SetLocalDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+
+	// The offerer can't authenticate the certificate of the answerer
+	answer.SDP = regexp.MustCompile(`a=fingerprint:sha-256 [0-9A-Fa-f:]+`).
+		ReplaceAllString(answer.SDP, "a=fingerprint:sha-256 "+strings.Repeat("00:", 31)+"00")
+	if err = pcOffer.SetRemoteDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+
+	<-offerFailed
+	<-answerConnected
+	assert.Equal(t, PeerConnectionStateFailed, pcOffer.ConnectionState)
+	assert.NoError(t, pcAnswer.ConnectionError())
+
+	// The mismatch tells the fingerprint of the certificate presented
+	answerFingerprint := pcAnswer.configuration.Certificates[0].GetFingerprints()[0]
+	mismatch, ok := pcOffer.ConnectionError().(*DTLSFingerprintMismatchError)
+	if assert.True(t, ok, "unexpected error %v", pcOffer.ConnectionError()) {
+		assert.Equal(t, []DTLSFingerprint{{Algorithm: "sha-256", Value: strings.Repeat("00:", 31) + "00"}}, mismatch.Expected)
+		assert.Equal(t, []DTLSFingerprint{answerFingerprint}, mismatch.Presented)
+	}
+	presented, err := CertificateFingerprint(pcOffer.dtlsTransport.RemoteCertificate(), "sha-256")
+	assert.NoError(t, err)
+	assert.Equal(t, answerFingerprint, presented)
+
+	// The handshake of the answerer checked the certificate of the offerer
+	accepted, err := CertificateFingerprint(pcAnswer.dtlsTransport.RemoteCertificate(), "sha-256")
+	assert.NoError(t, err)
+	assert.Equal(t, pcOffer.configuration.Certificates[0].GetFingerprints()[0], accepted)
+
+	_, err = pcOffer.dtlsTransport.getSRTPSession()
+	assert.Error(t, err, "SRTP must not start after a failed handshake")
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_Rollback(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcPolite, pcImpolite, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing to roll back while stable
+	assert.Error(t, pcPolite.SetLocalDescription(SessionDescription{Type: SDPTypeRollback}))
+
+	// Both peers offer at once
+	politeOffer, err := pcPolite.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pcPolite.SetLocalDescription(politeOffer); err != nil {
+		t.Fatal(err)
+	}
+	impoliteOffer, err := pcImpolite.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pcImpolite.SetLocalDescription(impoliteOffer); err != nil {
+		t.Fatal(err)
+	}
+	assert.Error(t, pcPolite.SetRemoteDescription(impoliteOffer))
+
+	// The polite peer backs off and answers
+	if err = pcPolite.SetLocalDescription(SessionDescription{Type: SDPTypeRollback}); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, SignalingStateStable, pcPolite.SignalingState)
+	assert.Nil(t, pcPolite.PendingLocalDescription)
+	assert.Nil(t, pcPolite.LocalDescription())
+
+	var connected sync.WaitGroup
+	connected.Add(2)
+	for _, pc := range []*PeerConnection{pcPolite, pcImpolite} {
+		pc.OnConnectionStateChange(func(state PeerConnectionState) {
+			if state == PeerConnectionStateConnected {
+				connected.Done()
+			}
+		})
+	}
+
+	if err = pcPolite.SetRemoteDescription(impoliteOffer); err != nil {
+		t.Fatal(err)
+	}
+	err = pcPolite.SetRemoteDescription(SessionDescription{Type: SDPTypeRollback})
+	assert.Equal(t, &rtcerr.InvalidModificationError{Err: ErrRemoteRollback}, err)
+	assert.Equal(t, SignalingStateHaveRemoteOffer, pcPolite.SignalingState)
+
+	answer, err := pcPolite.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pcPolite.SetLocalDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcImpolite.SetRemoteDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, SignalingStateStable, pcPolite.SignalingState)
+	assert.Equal(t, SignalingStateStable, pcImpolite.SignalingState)
+
+	connected.Wait()
+	assert.NoError(t, pcPolite.Close())
+	assert.NoError(t, pcImpolite.Close())
+}
+
+func TestPeerConnection_SignalingStateChange(t *testing.T) {
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pc, err := api.NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := make(chan SignalingState, 2)
+	pc.OnSignalingStateChange(func(state SignalingState) {
+		changes <- state
+	})
+
+	// Nothing was offered yet, the answers are out of order
+	for _, desc := range []SessionDescription{
+		{Type: SDPTypeAnswer},
+		{Type: SDPTypePranswer},
+	} {
+		err = pc.SetLocalDescription(desc)
+		assert.IsType(t, &rtcerr.InvalidModificationError{}, err, desc.Type)
+		assert.Contains(t, err.Error(), "stable->SetLocal("+desc.Type.String()+")")
+		err = pc.SetRemoteDescription(SessionDescription{Type: desc.Type, SDP: "v=0"})
+		assert.IsType(t, &rtcerr.InvalidModificationError{}, err, desc.Type)
+	}
+	assert.Equal(t, SignalingStateStable, pc.SignalingState)
+
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pc.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, SignalingStateHaveLocalOffer, pc.SignalingState)
+	assert.Equal(t, SignalingStateHaveLocalOffer, <-changes)
+
+	// A second offer can't be set on top of the pending one
+	assert.Error(t, pc.SetLocalDescription(offer))
+	if err = pc.SetLocalDescription(SessionDescription{Type: SDPTypeRollback}); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, SignalingStateStable, <-changes)
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestPeerConnection_WaitUntilConnected(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	pcOffer, pcAnswer, err := NewAPI().newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The context bounds the wait
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, pcOffer.WaitUntilConnected(ctx))
+
+	connected := make(chan error, 2)
+	for _, pc := range []*PeerConnection{pcOffer, pcAnswer} {
+		go func(pc *PeerConnection) {
+			connected <- pc.WaitUntilConnected(context.Background())
+		}(pc)
+	}
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, <-connected)
+	assert.NoError(t, <-connected)
+
+	// It returns immediately once connected
+	assert.NoError(t, pcOffer.WaitUntilConnected(context.Background()))
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+	assert.Equal(t, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}, pcOffer.WaitUntilConnected(context.Background()))
+}
+
+func TestPeerConnection_WaitUntilConnected_Closed(t *testing.T) {
+	pc, err := NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Closing unblocks the wait
+	closed := make(chan error)
+	go func() {
+		closed <- pc.WaitUntilConnected(context.Background())
+	}()
+	assert.NoError(t, pc.Close())
+	assert.Equal(t, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}, <-closed)
+}
+
+func TestPeerConnection_RTCPMuxOnly(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(track); err != nil {
+		t.Fatal(err)
+	}
+
+	rtcpCandidate := regexp.MustCompile(`(?m)^a=candidate:(\S+) 2 `)
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, offer.SDP, "a=rtcp-mux\r\n")
+	assert.False(t, rtcpCandidate.MatchString(offer.SDP), "only RTP candidates should be offered")
+	if err = pcOffer.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+
+	// A remote that can fall back to separate RTCP lists RTCP candidates,
+	// here ahead of the RTP ones on the same addresses, they are ignored. The
+	// lines keep their \r as .* stops at the \n.
+	offer.SDP = regexp.MustCompile(`(?m)^a=candidate:(\S+) 1 (.*)$`).ReplaceAllString(offer.SDP, "a=candidate:$1 2 $2\na=candidate:$1 1 $2")
+	assert.True(t, rtcpCandidate.MatchString(offer.SDP))
+	if err = pcAnswer.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+
+	answer, err := pcAnswer.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, answer.SDP, "a=rtcp-mux\r\n")
+	assert.False(t, rtcpCandidate.MatchString(answer.SDP), "only RTP candidates should be answered")
+	if err = pcAnswer.SetLocalDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcOffer.SetRemoteDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	if err = pcAnswer.WaitUntilConnected(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	pairs, err := pcAnswer.iceTransport.GetCandidatePairs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, pairs)
+	for _, pair := range pairs {
+		assert.Equal(t, uint16(ICEComponentRTP), pair.Remote.Component)
+	}
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_OnNegotiationNeeded(t *testing.T) {
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pc, err := api.NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fired := make(chan struct{}, 3)
+	pc.OnNegotiationNeeded(func() {
+		fired <- struct{}{}
+	})
+	addTrack := func(id string) *RTPSender {
+		track, err := pc.NewSampleTrack(DefaultPayloadTypeVP8, id, "pion")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sender, err := pc.AddTrack(track)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sender
+	}
+
+	// The handler is invoked once for the changes until an offer is created
+	sender := addTrack("video1")
+	<-fired
+	addTrack("video2")
+
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pc.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+
+	// The changes made while negotiating wait for the stable state
+	assert.NoError(t, pc.RemoveTrack(sender))
+	assert.Len(t, fired, 0)
+	if err = pc.SetLocalDescription(SessionDescription{Type: SDPTypeRollback}); err != nil {
+		t.Fatal(err)
+	}
+	<-fired
+
+	// The changes a rolled back offer carried are offered again
+	offer, err = pc.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pc.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	if err = pc.SetLocalDescription(SessionDescription{Type: SDPTypeRollback}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-fired:
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnNegotiationNeeded wasn't invoked for the rolled back offer")
+	}
+
+	// Removing it again changes nothing
+	assert.NoError(t, pc.RemoveTrack(sender))
+	other, err := api.NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Error(t, other.RemoveTrack(sender))
+
+	assert.NoError(t, other.Close())
+	assert.NoError(t, pc.Close())
+	assert.Len(t, fired, 0)
+}
+
+func TestPeerConnection_Trickle(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.EnableTrickle()
+	s.SetNetworkTypes([]NetworkType{NetworkTypeUDP4})
+	pcOffer, pcAnswer, err := NewAPI(WithSettingEngine(s)).newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The candidates are signaled once both descriptions are set
+	offerCandidates, answerCandidates := make(chan ICECandidateInit, 100), make(chan ICECandidateInit, 100)
+	pcOffer.OnICECandidate(func(c *ICECandidate) {
+		if c == nil {
+			assert.Equal(t, ICEGatheringStateComplete, pcOffer.ICEGatheringState)
+			close(offerCandidates)
+			return
+		}
+		offerCandidates <- c.ToJSON()
+	})
+	pcAnswer.OnICECandidate(func(c *ICECandidate) {
+		if c == nil {
+			close(answerCandidates)
+			return
+		}
+		answerCandidates <- c.ToJSON()
+	})
+
+	var connected sync.WaitGroup
+	connected.Add(2)
+	for _, pc := range []*PeerConnection{pcOffer, pcAnswer} {
+		pc.OnConnectionStateChange(func(state PeerConnectionState) {
+			if state == PeerConnectionStateConnected {
+				connected.Done()
+			}
+		})
+	}
+
+	// Nothing is gathered before the offer is set
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, offer.SDP, "a=ice-options:trickle")
+	assert.NotContains(t, offer.SDP, "a=candidate:")
+	assert.NotContains(t, offer.SDP, "a=end-of-candidates")
+	assert.Equal(t, ICEGatheringStateNew, pcOffer.ICEGatheringState)
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	signal := func(candidates chan ICECandidateInit, pc *PeerConnection) {
+		count := 0
+		for c := range candidates {
+			if err := pc.AddICECandidate(c); err != nil {
+				t.Error(err)
+			}
+			count++
+		}
+		assert.NotZero(t, count)
+		assert.NoError(t, pc.AddICECandidate(ICECandidateInit{}))
+	}
+	signal(offerCandidates, pcAnswer)
+	signal(answerCandidates, pcOffer)
+
+	connected.Wait()
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_ICERestart(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.SetNetworkTypes([]NetworkType{NetworkTypeUDP4})
+	pcOffer, pcAnswer, err := NewAPI(WithSettingEngine(s)).newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dc, err := pcOffer.CreateDataChannel("data", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pong := make(chan struct{}, 2)
+	dc.OnMessage(func(msg DataChannelMessage) {
+		pong <- struct{}{}
+	})
+	opened := make(chan struct{})
+	dc.OnOpen(func() {
+		close(opened)
+	})
+	pcAnswer.OnDataChannel(func(d *DataChannel) {
+		d.OnMessage(func(msg DataChannelMessage) {
+			assert.NoError(t, d.SendText("Pong"))
+		})
+	})
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+	<-opened
+	assert.NoError(t, dc.SendText("Ping"))
+	<-pong
+
+	ufrag := regexp.MustCompile(`a=ice-ufrag:(\S+)`)
+	offerUfrag := ufrag.FindStringSubmatch(pcOffer.LocalDescription().SDP)[1]
+	answerUfrag := ufrag.FindStringSubmatch(pcAnswer.LocalDescription().SDP)[1]
+
+	offer, err := pcOffer.CreateOffer(&OfferOptions{ICERestart: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, offerUfrag, ufrag.FindStringSubmatch(offer.SDP)[1])
+
+	// The session restarts once the offer is set, the candidates gathered
+	// again are then added to the LocalDescription
+	params, err := pcOffer.iceGatherer.GetLocalParameters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, offerUfrag, params.UsernameFragment)
+	assert.NotContains(t, offer.SDP, "a=candidate:")
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	offer = *pcOffer.LocalDescription()
+	assert.Contains(t, offer.SDP, "a=candidate:")
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+
+	answer, err := pcAnswer.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, answerUfrag, ufrag.FindStringSubmatch(answer.SDP)[1])
+	assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+	assert.NoError(t, pcOffer.SetRemoteDescription(answer))
+
+	// The pairs of the previous session are gone, the traffic moves on to
+	// the new one once a pair of it is selected
+	for selected := false; !selected; time.Sleep(10 * time.Millisecond) {
+		pairs, err := pcOffer.iceGatherer.agent.GetCandidatePairs()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range pairs {
+			selected = selected || p.Selected
+		}
+	}
+
+	// The DTLS and SCTP associations survived the restart
+	assert.NoError(t, dc.SendText("Ping"))
+	<-pong
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_ICERestart_Rollback(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	// The idle connection only stays up while the keepalives refresh the
+	// consent of the remote
+	s := SettingEngine{}
+	s.SetNetworkTypes([]NetworkType{NetworkTypeUDP4})
+	s.SetConnectionTimeout(time.Second, 100*time.Millisecond)
+	pcOffer, pcAnswer, err := NewAPI(WithSettingEngine(s)).newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dc, err := pcOffer.CreateDataChannel("data", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pong := make(chan struct{}, 2)
+	dc.OnMessage(func(msg DataChannelMessage) {
+		pong <- struct{}{}
+	})
+	opened := make(chan struct{})
+	dc.OnOpen(func() {
+		close(opened)
+	})
+	pcAnswer.OnDataChannel(func(d *DataChannel) {
+		d.OnMessage(func(msg DataChannelMessage) {
+			assert.NoError(t, d.SendText("Pong"))
+		})
+	})
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+	<-opened
+
+	ufrag := regexp.MustCompile(`a=ice-ufrag:(\S+)`)
+	offerUfrag := ufrag.FindStringSubmatch(pcOffer.LocalDescription().SDP)[1]
+
+	offer, err := pcOffer.CreateOffer(&OfferOptions{ICERestart: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	assert.NoError(t, pcOffer.SetLocalDescription(SessionDescription{Type: SDPTypeRollback}))
+	assert.Equal(t, SignalingStateStable, pcOffer.SignalingState)
+
+	// The previous session is back and stays up past the connection timeout
+	params, err := pcOffer.iceGatherer.GetLocalParameters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, offerUfrag, params.UsernameFragment)
+	time.Sleep(2 * time.Second)
+	assert.Equal(t, ICEConnectionStateConnected, pcOffer.ICEConnectionState())
+	assert.Equal(t, ICEConnectionStateConnected, pcAnswer.ICEConnectionState())
+	assert.NoError(t, dc.SendText("Ping"))
+	<-pong
+
+	// The next offer no longer restarts ICE
+	offer, err = pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, offerUfrag, ufrag.FindStringSubmatch(offer.SDP)[1])
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+// droppingPacketConn drops the packets both ways while drop is set,
+// which is accessed atomically
+type droppingPacketConn struct {
+	net.PacketConn
+	drop int32
+}
+
+func (c *droppingPacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	for {
+		n, addr, err := c.PacketConn.ReadFrom(p)
+		if err != nil || atomic.LoadInt32(&c.drop) == 0 {
+			return n, addr, err
+		}
+	}
+}
+
+func (c *droppingPacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	if atomic.LoadInt32(&c.drop) == 1 {
+		return len(p), nil
+	}
+	return c.PacketConn.WriteTo(p, addr)
+}
+
+func TestPeerConnection_ICERestartOnFailure(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	newConn := func() net.PacketConn {
+		conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IP{127, 0, 0, 1}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn
+	}
+
+	// The offerer restarts the failed connection twice, the first restart
+	// fails as well
+	offerEngine := SettingEngine{}
+	offerEngine.SetICEConn(newConn())
+	offerEngine.SetConnectionTimeout(time.Second, 100*time.Millisecond)
+	offerEngine.SetICEFailedTimeout(500 * time.Millisecond)
+	assert.NoError(t, offerEngine.SetICERestartOnFailure(2, 100*time.Millisecond))
+	pcOffer, err := NewAPI(WithSettingEngine(offerEngine)).NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	answerConn := &droppingPacketConn{PacketConn: newConn()}
+	answerEngine := SettingEngine{}
+	answerEngine.SetICEConn(answerConn)
+	answerEngine.SetConnectionTimeout(time.Second, 100*time.Millisecond)
+	answerEngine.SetICEFailedTimeout(500 * time.Millisecond)
+	pcAnswer, err := NewAPI(WithSettingEngine(answerEngine)).NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dc, err := pcOffer.CreateDataChannel("data", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pong := make(chan struct{}, 2)
+	dc.OnMessage(func(msg DataChannelMessage) {
+		pong <- struct{}{}
+	})
+	opened := make(chan struct{})
+	dc.OnOpen(func() {
+		close(opened)
+	})
+	pcAnswer.OnDataChannel(func(d *DataChannel) {
+		d.OnMessage(func(msg DataChannelMessage) {
+			assert.NoError(t, d.SendText("Pong"))
+		})
+	})
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+	<-opened
+
+	states := make(chan ICEConnectionState, 10)
+	pcOffer.OnICEConnectionStateChange(func(state ICEConnectionState) {
+		states <- state
+	})
+	negotiationNeeded := make(chan struct{}, 1)
+	pcOffer.OnNegotiationNeeded(func() {
+		negotiationNeeded <- struct{}{}
+	})
+	awaitState := func(expected ICEConnectionState) {
+		for state := range states {
+			if state == expected {
+				return
+			}
+		}
+	}
+	restart := func() {
+		offer, offerErr := pcOffer.CreateOffer(nil)
+		if offerErr != nil {
+			t.Fatal(offerErr)
+		}
+		assert.NoError(t, pcOffer.SetLocalDescription(offer))
+		assert.NoError(t, pcAnswer.SetRemoteDescription(*pcOffer.LocalDescription()))
+		answer, answerErr := pcAnswer.CreateAnswer(nil)
+		if answerErr != nil {
+			t.Fatal(answerErr)
+		}
+		assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+		assert.NoError(t, pcOffer.SetRemoteDescription(*pcAnswer.LocalDescription()))
+	}
+
+	ufrag := regexp.MustCompile(`a=ice-ufrag:(\S+)`)
+	offerUfrag := ufrag.FindStringSubmatch(pcOffer.LocalDescription().SDP)[1]
+
+	// The dead path fails, the next offer restarts ICE
+	atomic.StoreInt32(&answerConn.drop, 1)
+	awaitState(ICEConnectionStateFailed)
+	<-negotiationNeeded
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, offerUfrag, ufrag.FindStringSubmatch(offer.SDP)[1])
+	restart()
+
+	// The restart that can't connect fails after the failed timeout, the
+	// second one connects once the path is back
+	awaitState(ICEConnectionStateFailed)
+	<-negotiationNeeded
+	atomic.StoreInt32(&answerConn.drop, 0)
+	restart()
+	awaitState(ICEConnectionStateConnected)
+
+	// The DTLS and SCTP associations survived the restarts
+	assert.NoError(t, dc.SendText("Ping"))
+	<-pong
+
+	// The restarts are counted again once connected
+	pcOffer.mu.RLock()
+	attempts := pcOffer.iceRestartAttempts
+	pcOffer.mu.RUnlock()
+	assert.Equal(t, 0, attempts)
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestPeerConnection_CodecFmtp(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	// The offerer has two H264 codecs the answerer only supports one of
+	offerEngine := MediaEngine{}
+	offerEngine.RegisterCodec(NewRTPCodec(RTPCodecTypeVideo, H264, 90000, 0, "packetization-mode=0;profile-level-id=42e01f", 102, nil))
+	offerEngine.RegisterCodec(NewRTPCodec(RTPCodecTypeVideo, H264, 90000, 0, "packetization-mode=1;profile-level-id=42e01f", 125, nil))
+	pcOffer, err := NewAPI(WithMediaEngine(offerEngine)).NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A custom codec with its own format parameters and feedback
+	answerEngine := MediaEngine{}
+	codec := NewRTPCodec(RTPCodecTypeVideo, H264, 90000, 0, "packetization-mode=1;profile-level-id=42e01f;sprop-maxcapturerate=30", DefaultPayloadTypeH264, nil)
+	codec.RTCPFeedback = []RTCPFeedback{{Type: TypeRTCPFBNACK}}
+	answerEngine.RegisterCodec(codec)
+	pcAnswer, err := NewAPI(WithMediaEngine(answerEngine)).NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offer, err := pcAnswer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, offer.SDP, fmt.Sprintf("a=fmtp:%d packetization-mode=1;profile-level-id=42e01f;sprop-maxcapturerate=30", DefaultPayloadTypeH264))
+	assert.Contains(t, offer.SDP, fmt.Sprintf("a=rtcp-fb:%d nack\r\n", DefaultPayloadTypeH264))
+
+	offer, err = pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offer.SDP = strings.Replace(offer.SDP, "a=rtpmap:125 H264/90000\r\n", "a=rtpmap:125 H264/90000\r\na=rtcp-fb:125 nack\r\n", 1)
+	if err = pcAnswer.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := pcAnswer.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The answer uses the payload type of the compatible offered codec
+	assert.Contains(t, answer.SDP, "a=rtpmap:125 H264/90000")
+	assert.Contains(t, answer.SDP, "a=fmtp:125 packetization-mode=1;profile-level-id=42e01f;sprop-maxcapturerate=30")
+	assert.Contains(t, answer.SDP, "a=rtcp-fb:125 nack\r\n")
+	assert.NotContains(t, answer.SDP, "a=rtpmap:102")
+	assert.NotContains(t, answer.SDP, fmt.Sprintf("a=rtpmap:%d", DefaultPayloadTypeH264))
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
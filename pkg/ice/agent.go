@@ -3,15 +3,21 @@
 package ice
 
 import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
 	"fmt"
-	"math/rand"
+	"io"
 	"net"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/pions/stun"
+	"github.com/pions/webrtc/internal/mdns"
 	"github.com/pions/webrtc/internal/util"
+	"github.com/pions/webrtc/pkg/logging"
 	"github.com/pkg/errors"
 )
 
@@ -24,10 +30,25 @@ const (
 
 	// defaultConnectionTimeout used to declare a connection dead
 	defaultConnectionTimeout = 30 * time.Second
+
+	// defaultFailedTimeout is how long a connection stays disconnected
+	// before it fails
+	defaultFailedTimeout = 30 * time.Second
 )
 
 // Agent represents the ICE agent
 type Agent struct {
+	// Traffic counters, accessed atomically. Kept first for the 64-bit
+	// alignment required on 32-bit platforms.
+	bytesSent       uint64
+	bytesReceived   uint64
+	packetsSent     uint64
+	packetsReceived uint64
+
+	// controlling mirrors isControlling for GetTransportStats, accessed
+	// atomically
+	controlling uint32
+
 	onConnectionStateChangeHdlr func(ConnectionState)
 
 	// Used to block double Dial/Accept
@@ -48,8 +69,39 @@ type Agent struct {
 	haveStarted   bool
 	isControlling bool
 
-	portmin uint16
-	portmax uint16
+	// bindingRequests are the last binding requests sent, with the role
+	// they were sent with
+	bindingRequests []bindingRequest
+
+	portmin        uint16
+	portmax        uint16
+	dscp           uint8
+	networkTypes   []NetworkType
+	candidateTypes []CandidateType
+	tlsConfig      *tls.Config
+
+	maxHostCandidates   int
+	hostCandidatePolicy HostCandidatePolicy
+
+	interfaceFilter func(string) bool
+	ipFilter        func(net.IP) bool
+
+	// extIPMapper advertises the NAT 1:1 IPs of the config, nil without
+	extIPMapper *externalIPMapper
+
+	// packetConn is the conn of the config, or udpMuxConn, the one of the
+	// agent on the UDPMux of the config
+	packetConn net.PacketConn
+	udpMuxConn *udpMuxedConn
+
+	// mDNSConn resolves the names of the remote host candidates and answers
+	// the queries for the local ones, it is opened with the first name to
+	// resolve, or by NewAgent to gather the names. It is guarded by
+	// mDNSLock.
+	mDNSMode   MulticastDNSMode
+	mDNSLock   sync.Mutex
+	mDNSConn   *mdns.Conn
+	mDNSClosed bool
 
 	//How long should a pair stay quiet before we declare it dead?
 	//0 means never timeout
@@ -59,6 +111,15 @@ type Agent struct {
 	//0 means never
 	keepaliveInterval time.Duration
 
+	// failedTimeout is how long the agent stays disconnected before it
+	// fails, since disconnectedAt or since the restart of the disconnected
+	// connection. 0 means never.
+	failedTimeout  time.Duration
+	disconnectedAt time.Time
+
+	// random is the source of the local credentials
+	random io.Reader
+
 	localUfrag      string
 	localPwd        string
 	localCandidates map[NetworkType][]*Candidate
@@ -70,6 +131,39 @@ type Agent struct {
 	selectedPair *candidatePair
 	validPairs   []*candidatePair
 
+	// restarting is set from Restart until a pair of the new session is
+	// selected. previousCandidate is the local candidate of the pair of the
+	// previous session carrying the traffic meanwhile, previous is that
+	// session.
+	restarting        bool
+	previousCandidate *Candidate
+	previous          *previousSession
+
+	// checks records the connectivity checks of the pairs of the
+	// checklist, see GetChecklist
+	checks map[pairKey]*pairCheck
+
+	// selectedPairForced is set while the selected pair was set by
+	// SetSelectedCandidatePair, the nominations are ignored until it fails
+	selectedPairForced bool
+
+	// gatheringErrors are the failures of the servers to gather candidates
+	// from, set before the taskLoop starts
+	gatheringErrors []*GatheringError
+
+	// trickle defers the gathering to GatherCandidates, the candidates are
+	// recorded by the taskLoop and passed to onCandidateHdlr as they are
+	// gathered from urls
+	trickle         bool
+	urls            []*URL
+	onCandidateHdlr func(*Candidate)
+
+	// running is set before the taskLoop starts, the candidates gathered
+	// afterwards are recorded by it
+	running bool
+
+	log logging.LeveledLogger
+
 	// Channel for reading
 	rcvCh chan *bufIn
 
@@ -78,9 +172,32 @@ type Agent struct {
 	err  atomicError
 }
 
+// previousSession is the session a Restart ended. Its selected pair keeps
+// carrying the traffic until a pair of the new session is selected, the
+// keepalives and the responses on it use its credentials. RollbackRestart
+// brings it back.
+type previousSession struct {
+	localUfrag, localPwd   string
+	remoteUfrag, remotePwd string
+	remoteCandidates       map[NetworkType][]*Candidate
+
+	// local and remote are the candidates of the selected pair, nil when
+	// none was
+	local, remote *Candidate
+}
+
 type bufIn struct {
 	buf  []byte
 	size chan int
+
+	// timestamp is the receive time of the packet, set before its size
+	timestamp time.Time
+}
+
+var bufInPool = sync.Pool{
+	New: func() interface{} {
+		return &bufIn{size: make(chan int)}
+	},
 }
 
 func (a *Agent) ok() error {
@@ -109,6 +226,25 @@ type AgentConfig struct {
 	PortMin uint16
 	PortMax uint16
 
+	// InterfaceFilter and IPFilter restrict the host candidates to the
+	// addresses of the interfaces, and to the addresses, they return true
+	// for, such as to leave out the bridges of the containers. All of them
+	// are gathered when these properties are nil.
+	InterfaceFilter func(string) bool
+	IPFilter        func(net.IP) bool
+
+	// NAT1To1IPs are the external IPs of a 1:1 NAT, such as the public IP
+	// of a cloud instance, advertised instead of the local ones. Each is
+	// either the sole external IP of its family, or an "external/local"
+	// mapping of a local IP. The IPs replace the addresses of the host
+	// candidates when NAT1To1IPCandidateType is CandidateTypeHost, the
+	// default. When it is CandidateTypeServerReflexive a srflx candidate
+	// is gathered with the sole external IP of each family instead, on a
+	// socket bound to all the interfaces, and the host candidates keep
+	// their addresses.
+	NAT1To1IPs             []string
+	NAT1To1IPCandidateType CandidateType
+
 	// ConnectionTimeout defaults to 30 seconds when this property is nil.
 	// If the duration is 0, we will never timeout this connection.
 	ConnectionTimeout *time.Duration
@@ -117,6 +253,77 @@ type AgentConfig struct {
 	// when this is nil, it defaults to 10 seconds.
 	// A keepalive interval of 0 means we never send keepalive packets
 	KeepaliveInterval *time.Duration
+	// FailedTimeout is how long the connection stays disconnected, once
+	// the selected pair timed out, before it fails. It defaults to 30
+	// seconds when this property is nil. If the duration is 0, the
+	// connection never fails.
+	FailedTimeout *time.Duration
+
+	// RandomSource is used to generate the local credentials and the
+	// tie-breaker. It defaults to crypto/rand when this property is nil, any
+	// other reader should only be used for testing.
+	RandomSource io.Reader
+
+	// DSCP is the code point outgoing packets are marked with. It is left to
+	// the operating system when this property is 0, setting it may require
+	// privileges and is not supported on Windows.
+	DSCP uint8
+
+	// TLSConfig is the config of the TLS connections to the turns: servers,
+	// such as the RootCAs of a self-signed server. The certificates are
+	// verified against the system roots when this property is nil, the
+	// ServerName defaults to the host of the URL.
+	TLSConfig *tls.Config
+
+	// NetworkTypes restricts the candidates gathered to the ones of these
+	// network types, such as only NetworkTypeUDP6 on IPv6-only hosts. The
+	// candidates of all the supported network types are gathered when this
+	// property is empty.
+	NetworkTypes []NetworkType
+
+	// CandidateTypes restricts the local candidates gathered, and so the
+	// pairs checked, to the ones of these types, such as only the relay
+	// candidates to keep the host addresses private. The candidates of all
+	// the types are gathered when this property is empty.
+	CandidateTypes []CandidateType
+
+	// MaxHostCandidates limits the number of host candidates gathered on
+	// hosts with many interfaces, such as VPNs and virtual adapters. The
+	// addresses are kept in the order of HostCandidatePolicy, the dropped
+	// ones are logged. There is no limit when this property is 0.
+	MaxHostCandidates int
+
+	// HostCandidatePolicy orders the addresses kept under MaxHostCandidates
+	HostCandidatePolicy HostCandidatePolicy
+
+	// Logger is the logger the agent logs through, to tell apart the logs
+	// of the agents. It defaults to the logger of the ice scope when this
+	// property is nil.
+	Logger logging.LeveledLogger
+
+	// PacketConn is the conn the agent sends and receives on instead of
+	// binding sockets, such as one of a userspace network stack. The single
+	// host candidate is the UDP address of its LocalAddr, the interfaces
+	// aren't gathered and the STUN servers aren't queried. The agent closes
+	// it once closed.
+	PacketConn net.PacketConn
+
+	// UDPMux is the socket the agent shares with the other agents of the
+	// UDPMux instead of binding sockets, its host candidate is the address
+	// of the socket as with PacketConn. The UDPMux is left open once the
+	// agent is closed.
+	UDPMux *UDPMux
+
+	// Trickle defers the gathering of the local candidates from NewAgent to
+	// GatherCandidates, which returns right away and passes them to the
+	// OnCandidate handler as they are gathered.
+	Trickle bool
+
+	// MulticastDNSMode decides whether the .local names of the remote host
+	// candidates are resolved, the default, and whether the addresses of
+	// the local ones are hidden behind such names. The mDNS group is joined
+	// on the default multicast interface.
+	MulticastDNSMode MulticastDNSMode
 }
 
 // NewAgent creates a new Agent
@@ -124,22 +331,84 @@ func NewAgent(config *AgentConfig) (*Agent, error) {
 	if config.PortMax < config.PortMin {
 		return nil, ErrPort
 	}
+	if config.UDPMux != nil && config.PacketConn != nil {
+		return nil, ErrUDPMuxPacketConn
+	}
+
+	extIPMapper, err := newExternalIPMapper(config.NAT1To1IPCandidateType, config.NAT1To1IPs)
+	if err != nil {
+		return nil, err
+	}
+	if extIPMapper != nil && extIPMapper.candidateType == CandidateTypeHost && config.MulticastDNSMode == MulticastDNSModeQueryAndGather {
+		return nil, ErrMulticastDNSWithNAT1To1IPs
+	}
+
+	random := config.RandomSource
+	if random == nil {
+		random = rand.Reader
+	}
+
+	tieBreaker := make([]byte, 8)
+	if _, err := io.ReadFull(random, tieBreaker); err != nil {
+		return nil, err
+	}
+	localUfrag, err := util.RandSeqFrom(random, 16)
+	if err != nil {
+		return nil, err
+	}
+	localPwd, err := util.RandSeqFrom(random, 32)
+	if err != nil {
+		return nil, err
+	}
 
 	a := &Agent{
-		tieBreaker:       rand.New(rand.NewSource(time.Now().UnixNano())).Uint64(),
-		gatheringState:   GatheringStateComplete, // TODO trickle-ice
+		tieBreaker:       binary.BigEndian.Uint64(tieBreaker),
+		gatheringState:   GatheringStateComplete,
 		connectionState:  ConnectionStateNew,
 		localCandidates:  make(map[NetworkType][]*Candidate),
 		remoteCandidates: make(map[NetworkType][]*Candidate),
+		checks:           make(map[pairKey]*pairCheck),
 
-		localUfrag:  util.RandSeq(16),
-		localPwd:    util.RandSeq(32),
+		localUfrag:  localUfrag,
+		localPwd:    localPwd,
 		taskChan:    make(chan task),
 		onConnected: make(chan struct{}),
 		rcvCh:       make(chan *bufIn),
 		done:        make(chan struct{}),
 		portmin:     config.PortMin,
 		portmax:     config.PortMax,
+		dscp:        config.DSCP,
+		tlsConfig:   config.TLSConfig,
+
+		maxHostCandidates:   config.MaxHostCandidates,
+		hostCandidatePolicy: config.HostCandidatePolicy,
+
+		interfaceFilter: config.InterfaceFilter,
+		ipFilter:        config.IPFilter,
+		extIPMapper:     extIPMapper,
+
+		packetConn: config.PacketConn,
+		mDNSMode:   config.MulticastDNSMode,
+
+		trickle: config.Trickle,
+		urls:    config.Urls,
+
+		random: random,
+
+		log: config.Logger,
+	}
+	if a.log == nil {
+		a.log = iceLog
+	}
+
+	a.networkTypes = config.NetworkTypes
+	if len(a.networkTypes) == 0 {
+		a.networkTypes = supportedNetworkTypes
+	}
+
+	a.candidateTypes = config.CandidateTypes
+	if len(a.candidateTypes) == 0 {
+		a.candidateTypes = []CandidateType{CandidateTypeHost, CandidateTypeServerReflexive, CandidateTypeRelay}
 	}
 
 	// connectionTimeout used to declare a connection dead
@@ -155,14 +424,178 @@ func NewAgent(config *AgentConfig) (*Agent, error) {
 		a.keepaliveInterval = *config.KeepaliveInterval
 	}
 
+	if config.FailedTimeout == nil {
+		a.failedTimeout = defaultFailedTimeout
+	} else {
+		a.failedTimeout = *config.FailedTimeout
+	}
+
+	// The queries for the names of the local host candidates are answered
+	// as soon as they are signaled
+	if a.mDNSMode == MulticastDNSModeQueryAndGather && a.packetConn == nil && a.isCandidateTypeEnabled(CandidateTypeHost) {
+		if _, err := a.multicastDNSConn(); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.UDPMux != nil {
+		if !a.isCandidateTypeEnabled(CandidateTypeHost) {
+			return nil, ErrPacketConnCandidateType
+		}
+		if a.udpMuxConn, err = config.UDPMux.getConn(localUfrag); err != nil {
+			return nil, err
+		}
+		a.packetConn = a.udpMuxConn
+	}
+
 	// Initialize local candidates
-	a.gatherCandidatesLocal()
-	a.gatherCandidatesReflective(config.Urls)
+	switch {
+	case a.packetConn != nil && !a.isCandidateTypeEnabled(CandidateTypeHost):
+		return nil, ErrPacketConnCandidateType
+	case a.trickle:
+		a.gatheringState = GatheringStateNew
+	case a.packetConn != nil:
+		if err := a.gatherCandidatePacketConn(config.Urls); err != nil {
+			if a.udpMuxConn != nil {
+				// The UDPMux stops expecting the agent
+				_ = a.udpMuxConn.Close()
+			}
+			return nil, err
+		}
+	default:
+		if a.isCandidateTypeEnabled(CandidateTypeHost) {
+			a.gatherCandidatesLocal()
+		}
+		a.gatherCandidatesSrflxMapped()
+		a.gatherCandidatesReflective(config.Urls)
+		a.gatherCandidatesRelay(config.Urls)
+	}
 
+	a.running = true
 	go a.taskLoop()
 	return a, nil
 }
 
+// GatherCandidates starts gathering the local candidates of an agent
+// created with Trickle. They are passed to the OnCandidate handler as they
+// are gathered, followed by nil once the gathering is complete.
+func (a *Agent) GatherCandidates() error {
+	if !a.trickle {
+		return ErrGatherWithoutTrickle
+	}
+
+	res := make(chan error, 1)
+	err := a.run(func(agent *Agent) {
+		if agent.gatheringState != GatheringStateNew {
+			res <- ErrMultipleGather
+			return
+		}
+		agent.gatheringState = GatheringStateGathering
+		res <- nil
+	})
+	if err != nil {
+		return err
+	}
+	if err = <-res; err != nil {
+		return err
+	}
+
+	go a.gatherCandidates()
+	return nil
+}
+
+func (a *Agent) gatherCandidates() {
+	switch {
+	case a.packetConn != nil:
+		if err := a.gatherCandidatePacketConn(a.urls); err != nil {
+			a.log.Warnf("could not gather the candidate of the PacketConn: %v\n", err)
+		}
+	default:
+		if a.isCandidateTypeEnabled(CandidateTypeHost) {
+			a.gatherCandidatesLocal()
+		}
+		a.gatherCandidatesSrflxMapped()
+		a.gatherCandidatesReflective(a.urls)
+		a.gatherCandidatesRelay(a.urls)
+	}
+
+	res := make(chan func(*Candidate), 1)
+	err := a.run(func(agent *Agent) {
+		agent.gatheringState = GatheringStateComplete
+		res <- agent.onCandidateHdlr
+	})
+	if err != nil {
+		return
+	}
+	if hdlr := <-res; hdlr != nil {
+		hdlr(nil)
+	}
+}
+
+// OnCandidate sets a handler that is fired with each local candidate
+// gathered by GatherCandidates, and with nil once the gathering is complete
+func (a *Agent) OnCandidate(f func(*Candidate)) error {
+	return a.run(func(agent *Agent) {
+		agent.onCandidateHdlr = f
+	})
+}
+
+// addLocalCandidate records a gathered candidate and starts reading its
+// conn. The candidates trickled or gathered again by Restart are recorded by
+// the taskLoop, paired with the remote candidates and passed to the
+// OnCandidate handler, their conn is closed if the agent was closed
+// meanwhile.
+func (a *Agent) addLocalCandidate(c *Candidate, conn net.PacketConn) {
+	if !a.running {
+		a.localCandidates[c.NetworkType] = append(a.localCandidates[c.NetworkType], c)
+		c.start(a, conn)
+		return
+	}
+
+	res := make(chan func(*Candidate), 1)
+	err := a.run(func(agent *Agent) {
+		agent.localCandidates[c.NetworkType] = append(agent.localCandidates[c.NetworkType], c)
+		c.start(agent, conn)
+		if agent.isChecking() {
+			for _, remote := range agent.remoteCandidates[c.NetworkType] {
+				agent.pingCandidate(c, remote)
+			}
+		}
+		res <- agent.onCandidateHdlr
+	})
+	if err != nil {
+		if closeErr := conn.Close(); closeErr != nil {
+			a.log.Warnf("Failed to close the conn of candidate %s: %v", c, closeErr)
+		}
+		return
+	}
+	if hdlr := <-res; hdlr != nil {
+		hdlr(c)
+	}
+}
+
+// addGatheringError records the failure of a server, the errors of a
+// gathering run by the taskLoop are dropped once the agent is closed
+func (a *Agent) addGatheringError(e *GatheringError) {
+	if !a.running {
+		a.gatheringErrors = append(a.gatheringErrors, e)
+		return
+	}
+	if err := a.run(func(agent *Agent) {
+		agent.gatheringErrors = append(agent.gatheringErrors, e)
+	}); err != nil {
+		a.log.Debugf("Dropping gathering error %v: %v", e, err)
+	}
+}
+
+// isChecking returns true while the connectivity checks are running and no
+// pair is selected, or none of the session started by Restart, the
+// candidates added meanwhile are checked right away
+// Note: the caller should hold the agent lock.
+func (a *Agent) isChecking() bool {
+	return a.connectivityTicker != nil && (a.selectedPair == nil || a.restarting)
+}
+
 // OnConnectionStateChange sets a handler that is fired when the connection state changes
 func (a *Agent) OnConnectionStateChange(f func(ConnectionState)) error {
 	return a.run(func(agent *Agent) {
@@ -193,48 +626,191 @@ func (a *Agent) listenUDP(network string, laddr *net.UDPAddr) (*net.UDPConn, err
 	return nil, ErrPort
 }
 
+// gatherCandidatePacketConn makes the host candidate of the PacketConn of
+// the config, the servers can't be used on it
+func (a *Agent) gatherCandidatePacketConn(urls []*URL) error {
+	addr, ok := a.packetConn.LocalAddr().(*net.UDPAddr)
+	if !ok || addr.IP == nil || addr.IP.IsUnspecified() || addr.Port == 0 {
+		return ErrPacketConnAddr
+	}
+	c, err := NewCandidateHost(udp, a.hostCandidateIP(addr.IP), addr.Port, ComponentRTP)
+	if err != nil {
+		return err
+	}
+	if conn, ok := a.packetConn.(*net.UDPConn); ok {
+		a.setDSCP(conn)
+	}
+	a.addLocalCandidate(c, a.packetConn)
+
+	for _, url := range urls {
+		a.log.Warnf("server %s is not used with a PacketConn\n", url)
+		a.addGatheringError(&GatheringError{
+			URL:         url,
+			NetworkType: c.NetworkType,
+			Err:         errors.Errorf("server %s is not used with a PacketConn", url),
+		})
+	}
+	return nil
+}
+
+// setDSCP marks the packets of a candidate with the DSCP of the config
+func (a *Agent) setDSCP(conn *net.UDPConn) {
+	if a.dscp == 0 {
+		return
+	}
+	if err := setDSCP(conn, a.dscp); err != nil {
+		a.log.Warnf("could not set DSCP %d on %s: %v\n", a.dscp, conn.LocalAddr(), err)
+	}
+}
+
+// isNetworkTypeEnabled returns true if the candidates of the network type
+// are gathered
+func (a *Agent) isNetworkTypeEnabled(networkType NetworkType) bool {
+	for _, t := range a.networkTypes {
+		if t == networkType {
+			return true
+		}
+	}
+	return false
+}
+
+// isCandidateTypeEnabled returns true if the local candidates of the type
+// are gathered
+func (a *Agent) isCandidateTypeEnabled(candidateType CandidateType) bool {
+	for _, t := range a.candidateTypes {
+		if t == candidateType {
+			return true
+		}
+	}
+	return false
+}
+
 func (a *Agent) gatherCandidatesLocal() {
-	localIPs := localInterfaces()
+	localIPs := localInterfaces(a.interfaceFilter, a.ipFilter)
+	if a.maxHostCandidates > 0 && a.hostCandidatePolicy == HostCandidatePolicyDefaultRouteFirst {
+		localIPs = orderHostIPs(localIPs, defaultRouteIPs())
+	}
+
+	gathered := 0
 	for _, ip := range localIPs {
+		// Only IPv4 addresses are answered for the names
+		if a.mDNSMode == MulticastDNSModeQueryAndGather && ip.To4() == nil {
+			continue
+		}
 		for _, network := range supportedNetworks {
+			// The sockets of the disabled families are never bound
+			if networkType, err := determineNetworkType(network, ip); err != nil || !a.isNetworkTypeEnabled(networkType) {
+				continue
+			}
+
+			if a.maxHostCandidates > 0 && gathered >= a.maxHostCandidates {
+				a.log.Infof("Dropping host candidate %s %s, the limit of %d host candidates is reached", network, ip, a.maxHostCandidates)
+				continue
+			}
+
 			conn, err := a.listenUDP(network, &net.UDPAddr{IP: ip, Port: 0})
 			if err != nil {
-				iceLog.Warnf("could not listen %s %s\n", network, ip)
+				a.log.Warnf("could not listen %s %s\n", network, ip)
 				continue
 			}
+			a.setDSCP(conn)
 
 			port := conn.LocalAddr().(*net.UDPAddr).Port
-			c, err := NewCandidateHost(network, ip, port, ComponentRTP)
+			c, err := NewCandidateHost(network, a.hostCandidateIP(ip), port, ComponentRTP)
 			if err != nil {
-				iceLog.Warnf("Failed to create host candidate: %s %s %d: %v\n", network, ip, port, err)
+				a.log.Warnf("Failed to create host candidate: %s %s %d: %v\n", network, ip, port, err)
 				continue
 			}
+			if a.mDNSMode == MulticastDNSModeQueryAndGather {
+				if c.Hostname, err = a.multicastDNSName(ip); err != nil {
+					a.log.Warnf("Failed to name host candidate: %s %s %d: %v\n", network, ip, port, err)
+					if closeErr := conn.Close(); closeErr != nil {
+						a.log.Warnf("Failed to close %s %s: %v", network, ip, closeErr)
+					}
+					continue
+				}
+			}
 
-			networkType := c.NetworkType
-			set := a.localCandidates[networkType]
-			set = append(set, c)
-			a.localCandidates[networkType] = set
+			a.addLocalCandidate(c, conn)
+			gathered++
+		}
+	}
+}
 
-			c.start(a, conn)
+// hostCandidateIP returns the IP a host candidate bound to ip advertises,
+// its NAT 1:1 IP when the config maps the host candidates
+func (a *Agent) hostCandidateIP(ip net.IP) net.IP {
+	if a.extIPMapper == nil || a.extIPMapper.candidateType != CandidateTypeHost {
+		return ip
+	}
+	external, ok := a.extIPMapper.findExternalIP(ip)
+	if !ok {
+		a.log.Warnf("No NAT 1:1 IP is mapped to %s, the host candidate advertises it", ip)
+		return ip
+	}
+	return external
+}
+
+// gatherCandidatesSrflxMapped gathers a srflx candidate with the sole NAT
+// 1:1 IP of each network type, when the config maps the srflx candidates
+func (a *Agent) gatherCandidatesSrflxMapped() {
+	if a.extIPMapper == nil || a.extIPMapper.candidateType != CandidateTypeServerReflexive ||
+		!a.isCandidateTypeEnabled(CandidateTypeServerReflexive) {
+		return
+	}
+
+	for _, networkType := range a.networkTypes {
+		network := networkType.String()
+		laddr := &net.UDPAddr{IP: net.IPv4zero}
+		if networkType.IsIPv6() {
+			laddr.IP = net.IPv6unspecified
+		}
+		ip, ok := a.extIPMapper.findExternalIP(laddr.IP)
+		if !ok {
+			continue
 		}
+
+		conn, err := a.listenUDP(network, laddr)
+		if err != nil {
+			a.log.Warnf("could not listen %s %s: %v\n", network, laddr, err)
+			continue
+		}
+		a.setDSCP(conn)
+
+		port := conn.LocalAddr().(*net.UDPAddr).Port
+		c, err := NewCandidateServerReflexive(network, ip, port, ComponentRTP, laddr.IP.String(), port)
+		if err != nil {
+			a.log.Warnf("Failed to create server reflexive candidate: %s %s %d: %v\n", network, ip, port, err)
+			if closeErr := conn.Close(); closeErr != nil {
+				a.log.Warnf("Failed to close %s %s: %v", network, laddr, closeErr)
+			}
+			continue
+		}
+
+		a.addLocalCandidate(c, conn)
 	}
 }
 
 func (a *Agent) gatherCandidatesReflective(urls []*URL) {
-	for _, networkType := range supportedNetworkTypes {
+	for _, networkType := range a.networkTypes {
 		network := networkType.String()
 		for _, url := range urls {
-			switch url.Scheme {
-			case SchemeTypeSTUN:
+			switch {
+			case url.Scheme == SchemeTypeSTUN && !a.isCandidateTypeEnabled(CandidateTypeServerReflexive):
+				continue
+			case url.Scheme == SchemeTypeSTUN:
 				laddr, xoraddr, err := allocateUDP(network, url)
 				if err != nil {
-					iceLog.Warnf("could not allocate %s %s: %v\n", network, url, err)
+					a.log.Warnf("could not allocate %s %s: %v\n", network, url, err)
+					a.addGatheringError(newGatheringError(url, networkType, err))
 					continue
 				}
 				conn, err := net.ListenUDP(network, laddr)
 				if err != nil {
-					iceLog.Warnf("could not listen %s %s: %v\n", network, laddr, err)
+					a.log.Warnf("could not listen %s %s: %v\n", network, laddr, err)
+					continue
 				}
+				a.setDSCP(conn)
 
 				ip := xoraddr.IP
 				port := xoraddr.Port
@@ -242,25 +818,105 @@ func (a *Agent) gatherCandidatesReflective(urls []*URL) {
 				relPort := laddr.Port
 				c, err := NewCandidateServerReflexive(network, ip, port, ComponentRTP, relIP, relPort)
 				if err != nil {
-					iceLog.Warnf("Failed to create server reflexive candidate: %s %s %d: %v\n", network, ip, port, err)
+					a.log.Warnf("Failed to create server reflexive candidate: %s %s %d: %v\n", network, ip, port, err)
 					continue
 				}
 
-				networkType := c.NetworkType
-				set := a.localCandidates[networkType]
-				set = append(set, c)
-				a.localCandidates[networkType] = set
+				a.addLocalCandidate(c, conn)
 
-				c.start(a, conn)
+			case url.Scheme == SchemeTypeTURN || url.Scheme == SchemeTypeTURNS:
+				// The relay candidates are gathered by gatherCandidatesRelay
+				continue
 
 			default:
-				iceLog.Warnf("scheme %s is not implemented\n", url.Scheme)
+				a.log.Warnf("scheme %s is not implemented\n", url.Scheme)
+				a.addGatheringError(&GatheringError{
+					URL:         url,
+					NetworkType: networkType,
+					Err:         errors.Errorf("scheme %s is not implemented", url.Scheme),
+				})
 				continue
 			}
 		}
 	}
 }
 
+// gatherCandidatesRelay allocates a relay candidate on each TURN server,
+// over UDP
+func (a *Agent) gatherCandidatesRelay(urls []*URL) {
+	if !a.isCandidateTypeEnabled(CandidateTypeRelay) {
+		return
+	}
+
+	for _, networkType := range a.networkTypes {
+		network := networkType.String()
+		for _, url := range urls {
+			switch {
+			case url.Scheme != SchemeTypeTURN && url.Scheme != SchemeTypeTURNS:
+				continue
+			case url.Scheme == SchemeTypeTURNS && url.Proto != ProtoTypeTCP:
+				a.log.Warnf("%s is not implemented, only TURN over UDP, TCP and TLS is\n", url)
+				a.addGatheringError(&GatheringError{
+					URL:         url,
+					NetworkType: networkType,
+					Err:         errors.Errorf("%s is not implemented, only TURN over UDP, TCP and TLS is", url),
+				})
+				continue
+			case url.Username == "" || url.Password == "":
+				a.log.Warnf("TURN server %s has no long-term credentials\n", url)
+				a.addGatheringError(&GatheringError{
+					URL:         url,
+					NetworkType: networkType,
+					Err:         errors.Errorf("TURN server %s has no long-term credentials", url),
+				})
+				continue
+			}
+
+			relay, localPreference, err := a.allocateRelay(network, url)
+			if err != nil {
+				a.log.Warnf("could not allocate %s %s: %v\n", network, url, err)
+				a.addGatheringError(newGatheringError(url, networkType, err))
+				continue
+			}
+
+			c, err := NewCandidateRelay(network, relay.relayedAddr.IP, relay.relayedAddr.Port, ComponentRTP, relay.mappedAddr.IP.String(), relay.mappedAddr.Port)
+			if err != nil {
+				a.log.Warnf("Failed to create relay candidate: %s %s: %v\n", network, relay.relayedAddr, err)
+				if closeErr := relay.Close(); closeErr != nil {
+					a.log.Warnf("Failed to close the allocation of %s: %v", url, closeErr)
+				}
+				continue
+			}
+			c.LocalPreference = localPreference
+			a.addLocalCandidate(c, relay)
+		}
+	}
+}
+
+// allocateRelay creates an allocation on the TURN server of url, over UDP
+// or over a TCP or TLS stream. It returns the local preference of its relay
+// candidate, which prefers the allocations over UDP to the ones over TCP,
+// and those to the ones over TLS.
+func (a *Agent) allocateRelay(network string, url *URL) (*relayConn, uint16, error) {
+	switch {
+	case url.Scheme == SchemeTypeTURNS:
+		relay, err := allocateRelayStream(network, url, a.tlsConfig, a.log)
+		return relay, defaultLocalPreference - 2, err
+	case url.Proto == ProtoTypeTCP:
+		relay, err := allocateRelayStream(network, url, nil, a.log)
+		return relay, defaultLocalPreference - 1, err
+	}
+
+	conn, err := net.ListenUDP(network, &net.UDPAddr{})
+	if err != nil {
+		return nil, 0, err
+	}
+	a.setDSCP(conn)
+
+	relay, err := allocateRelay(network, url, conn, a.log)
+	return relay, defaultLocalPreference, err
+}
+
 func allocateUDP(network string, url *URL) (*net.UDPAddr, *stun.XorAddress, error) {
 	// TODO Do we want the timeout to be configurable?
 	client, err := stun.NewClient(network, fmt.Sprintf("%s:%d", url.Host, url.Port), time.Second*5)
@@ -281,6 +937,10 @@ func allocateUDP(network string, url *URL) (*net.UDPAddr, *stun.XorAddress, erro
 		return nil, nil, errors.Wrapf(err, "Failed to close STUN client")
 	}
 
+	if resp.Class == stun.ClassErrorResponse {
+		return nil, nil, newSTUNErrorResponse(resp)
+	}
+
 	attr, ok := resp.GetOneAttribute(stun.AttrXORMappedAddress)
 	if !ok {
 		return nil, nil, errors.Errorf("Got respond from STUN server that did not contain XORAddress")
@@ -303,10 +963,10 @@ func (a *Agent) startConnectivityChecks(isControlling bool, remoteUfrag, remoteP
 	case remotePwd == "":
 		return errors.Errorf("remotePwd is empty")
 	}
-	iceLog.Debugf("Started agent: isControlling? %t, remoteUfrag: %q, remotePwd: %q", isControlling, remoteUfrag, remotePwd)
+	a.log.Debugf("Started agent: isControlling? %t, remoteUfrag: %q, remotePwd: %q", isControlling, remoteUfrag, remotePwd)
 
 	return a.run(func(agent *Agent) {
-		agent.isControlling = isControlling
+		agent.setControlling(isControlling)
 		agent.remoteUfrag = remoteUfrag
 		agent.remotePwd = remotePwd
 
@@ -323,6 +983,8 @@ func (a *Agent) pingCandidate(local, remote *Candidate) {
 	var msg *stun.Message
 	var err error
 
+	username, remotePwd := a.pingCredentials(local, remote)
+
 	// The controlling agent MUST include the USE-CANDIDATE attribute in
 	// order to nominate a candidate pair (Section 8.1.1).  The controlled
 	// agent MUST NOT include the USE-CANDIDATE attribute in a Binding
@@ -330,36 +992,62 @@ func (a *Agent) pingCandidate(local, remote *Candidate) {
 
 	if a.isControlling {
 		msg, err = stun.Build(stun.ClassRequest, stun.MethodBinding, stun.GenerateTransactionID(),
-			&stun.Username{Username: a.remoteUfrag + ":" + a.localUfrag},
+			&stun.Username{Username: username},
 			&stun.UseCandidate{},
 			&stun.IceControlling{TieBreaker: a.tieBreaker},
 			&stun.Priority{Priority: uint32(local.Priority())},
 			&stun.MessageIntegrity{
-				Key: []byte(a.remotePwd),
+				Key: []byte(remotePwd),
 			},
 			&stun.Fingerprint{},
 		)
 	} else {
 		msg, err = stun.Build(stun.ClassRequest, stun.MethodBinding, stun.GenerateTransactionID(),
-			&stun.Username{Username: a.remoteUfrag + ":" + a.localUfrag},
+			&stun.Username{Username: username},
 			&stun.IceControlled{TieBreaker: a.tieBreaker},
 			&stun.Priority{Priority: uint32(local.Priority())},
 			&stun.MessageIntegrity{
-				Key: []byte(a.remotePwd),
+				Key: []byte(remotePwd),
 			},
 			&stun.Fingerprint{},
 		)
 	}
 
 	if err != nil {
-		iceLog.Debug(err.Error())
+		a.log.Debug(err.Error())
 		return
 	}
 
-	iceLog.Tracef("ping STUN from %s to %s\n", local.String(), remote.String())
+	a.log.Tracef("ping STUN from %s to %s\n", local.String(), remote.String())
+	a.recordBindingRequest(msg)
+	a.checkSent(local, remote)
 	a.sendSTUN(msg, local, remote)
 }
 
+// pingCredentials returns the USERNAME and the key of a Binding Request on a
+// pair, the ones of the previous session on its selected pair during a
+// restart
+// Note: the caller should hold the agent lock.
+func (a *Agent) pingCredentials(local, remote *Candidate) (username, remotePwd string) {
+	if p := a.previous; p != nil && p.local == local && p.remote == remote {
+		return p.remoteUfrag + ":" + p.localUfrag, p.remotePwd
+	}
+	return a.remoteUfrag + ":" + a.localUfrag, a.remotePwd
+}
+
+// responseKey returns the key of the responses to a Binding Request, the
+// local password of the session its USERNAME names
+// Note: the caller should hold the agent lock.
+func (a *Agent) responseKey(m *stun.Message) []byte {
+	if p := a.previous; p != nil {
+		if username, ok := m.GetOneAttribute(stun.AttrUsername); ok &&
+			strings.HasPrefix(string(username.Value), p.localUfrag+":") {
+			return []byte(p.localPwd)
+		}
+	}
+	return []byte(a.localPwd)
+}
+
 func (a *Agent) updateConnectionState(newState ConnectionState) {
 	if a.connectionState != newState {
 		a.connectionState = newState
@@ -385,22 +1073,27 @@ func (bp byPairPriority) Less(i, j int) bool {
 }
 
 func (a *Agent) setValidPair(local, remote *Candidate, selected, controlling bool) {
-	// TODO: avoid duplicates
-	p := newCandidatePair(local, remote, controlling)
-	iceLog.Tracef("Found valid candidate pair: %s (selected? %t)", p, selected)
+	// keep track of pairs with succesfull bindings since any of them
+	// can be used for communication until the final pair is selected:
+	// https://tools.ietf.org/html/draft-ietf-ice-rfc5245bis-20#section-12
+	p := a.findValidPair(local, remote)
+	if p == nil {
+		p = newCandidatePair(local, remote, controlling)
+		a.validPairs = append(a.validPairs, p)
+	}
+	p.iceRoleControlling = controlling
+	// Sort the candidate pairs by priority of the remotes
+	sort.Sort(byPairPriority{a.validPairs})
+	a.log.Tracef("Found valid candidate pair: %s (selected? %t)", p, selected)
 
 	if selected {
+		a.check(local, remote).nominated = true
+	}
+	if selected && !a.selectedPairForced {
 		a.selectedPair = p
-		a.validPairs = nil
+		a.endRestart()
 		// TODO: only set state to connected on selecting final pair?
 		a.updateConnectionState(ConnectionStateConnected)
-	} else {
-		// keep track of pairs with succesfull bindings since any of them
-		// can be used for communication until the final pair is selected:
-		// https://tools.ietf.org/html/draft-ietf-ice-rfc5245bis-20#section-12
-		a.validPairs = append(a.validPairs, p)
-		// Sort the candidate pairs by priority of the remotes
-		sort.Sort(byPairPriority{a.validPairs})
 	}
 
 	// Signal connected
@@ -429,10 +1122,11 @@ func (a *Agent) taskLoop() {
 		select {
 		case <-a.connectivityChan:
 			if a.validateSelectedPair() {
-				iceLog.Trace("checking keepalive")
+				a.log.Trace("checking keepalive")
 				a.checkKeepalive()
-			} else {
-				iceLog.Trace("pinging all candidates")
+			}
+			if a.isChecking() {
+				a.log.Trace("pinging all candidates")
 				a.pingAllCandidates()
 			}
 
@@ -450,13 +1144,24 @@ func (a *Agent) taskLoop() {
 // Note: the caller should hold the agent lock.
 func (a *Agent) validateSelectedPair() bool {
 	if a.selectedPair == nil {
-		// Not valid since not selected
+		// Not valid since not selected. The first valid pairs carry the
+		// traffic until one is nominated, such as the DTLS handshake started
+		// once the first one was found, it moves on to the next one when
+		// the best one fails.
+		a.pruneValidPairs()
+		a.checkFailed()
 		return false
 	}
 
 	if (a.connectionTimeout != 0) &&
 		(time.Since(a.selectedPair.remote.LastReceived()) > a.connectionTimeout) {
 		a.selectedPair = nil
+		a.selectedPairForced = false
+
+		// The pairs that went quiet as well are no longer valid
+		a.pruneValidPairs()
+
+		a.disconnectedAt = time.Now()
 		a.updateConnectionState(ConnectionStateDisconnected)
 		return false
 	}
@@ -464,17 +1169,50 @@ func (a *Agent) validateSelectedPair() bool {
 	return true
 }
 
-// checkKeepalive sends STUN Binding Indications to the selected pair
-// if no packet has been sent on that pair in the last keepaliveInterval
+// checkFailed fails the connection once it stayed disconnected for the
+// failed timeout, or once the restart of a disconnected connection selected
+// no pair for it. The candidates are still checked in case it comes back.
+// Note: the caller should hold the agent lock.
+func (a *Agent) checkFailed() {
+	disconnected := a.connectionState == ConnectionStateDisconnected ||
+		(a.connectionState == ConnectionStateChecking && a.restarting)
+	if disconnected &&
+		a.failedTimeout != 0 &&
+		time.Since(a.disconnectedAt) > a.failedTimeout {
+		a.updateConnectionState(ConnectionStateFailed)
+	}
+}
+
+// pruneValidPairs drops the valid pairs whose remote went quiet for the
+// connection timeout
+// Note: the caller should hold the agent lock.
+func (a *Agent) pruneValidPairs() {
+	if a.connectionTimeout == 0 {
+		return
+	}
+
+	var validPairs []*candidatePair
+	for _, p := range a.validPairs {
+		if time.Since(p.remote.LastReceived()) <= a.connectionTimeout {
+			validPairs = append(validPairs, p)
+		}
+	}
+	a.validPairs = validPairs
+}
+
+// checkKeepalive sends a STUN Binding Request on the selected pair if no
+// packet has been sent on it, or received from the remote, in the last
+// keepaliveInterval. The responses refresh the consent of the remote
+// (RFC 7675), a remote that sends nothing else doesn't time out.
 // Note: the caller should hold the agent lock.
 func (a *Agent) checkKeepalive() {
-	if a.selectedPair == nil {
+	if a.selectedPair == nil || a.keepaliveInterval == 0 {
 		return
 	}
 
-	if (a.keepaliveInterval != 0) &&
-		(time.Since(a.selectedPair.local.LastSent()) > a.keepaliveInterval) {
-		a.keepaliveCandidate(a.selectedPair.local, a.selectedPair.remote)
+	if time.Since(a.selectedPair.local.LastSent()) > a.keepaliveInterval ||
+		time.Since(a.selectedPair.remote.LastReceived()) > a.keepaliveInterval {
+		a.pingCandidate(a.selectedPair.local, a.selectedPair.remote)
 	}
 }
 
@@ -494,8 +1232,13 @@ func (a *Agent) pingAllCandidates() {
 	}
 }
 
-// AddRemoteCandidate adds a new remote candidate
+// AddRemoteCandidate adds a new remote candidate. A host candidate whose
+// address is hidden behind a name, see NewCandidateHostName, is added once
+// the name is resolved.
 func (a *Agent) AddRemoteCandidate(c *Candidate) error {
+	if c.IP == nil && c.Hostname != "" {
+		return a.resolveRemoteCandidate(c)
+	}
 	return a.run(func(agent *Agent) {
 		agent.addRemoteCandidate(c)
 	})
@@ -514,6 +1257,12 @@ func (a *Agent) addRemoteCandidate(c *Candidate) {
 
 	set = append(set, c)
 	a.remoteCandidates[networkType] = set
+
+	if a.isChecking() {
+		for _, local := range a.localCandidates[networkType] {
+			a.pingCandidate(local, c)
+		}
+	}
 }
 
 // GetLocalCandidates returns the local candidates
@@ -534,9 +1283,215 @@ func (a *Agent) GetLocalCandidates() ([]*Candidate, error) {
 	return <-res, nil
 }
 
-// GetLocalUserCredentials returns the local user credentials
+// GetGatheringErrors returns the failures of the STUN and TURN servers the
+// candidates were gathered from
+func (a *Agent) GetGatheringErrors() ([]*GatheringError, error) {
+	res := make(chan []*GatheringError)
+
+	err := a.run(func(agent *Agent) {
+		res <- append([]*GatheringError{}, agent.gatheringErrors...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return <-res, nil
+}
+
+// GetLocalUserCredentials returns the local user credentials, they are
+// empty once the agent is closed
 func (a *Agent) GetLocalUserCredentials() (frag string, pwd string) {
-	return a.localUfrag, a.localPwd
+	res := make(chan [2]string, 1)
+	if err := a.run(func(agent *Agent) {
+		res <- [2]string{agent.localUfrag, agent.localPwd}
+	}); err != nil {
+		return "", ""
+	}
+	credentials := <-res
+	return credentials[0], credentials[1]
+}
+
+// Restart starts a new ICE session with the local credentials ufrag and
+// pwd, new random ones when they are empty. The remote candidates of the
+// previous session are dropped, the new session is checked once
+// SetRemoteCredentials is given the remote credentials. The local
+// candidates are gathered again, before Restart returns or with
+// GatherCandidates when the agent trickles, the candidate of a PacketConn is
+// kept. The selected pair keeps carrying the traffic of the Conn until a
+// pair of the new session is selected, its keepalives and responses still
+// use the credentials of the previous session. RollbackRestart goes back to
+// the previous session until then.
+func (a *Agent) Restart(ufrag, pwd string) error {
+	var err error
+	if ufrag == "" {
+		if ufrag, err = util.RandSeqFrom(a.random, 16); err != nil {
+			return err
+		}
+	}
+	if pwd == "" {
+		if pwd, err = util.RandSeqFrom(a.random, 32); err != nil {
+			return err
+		}
+	}
+
+	res := make(chan error, 1)
+	err = a.run(func(agent *Agent) {
+		if agent.gatheringState == GatheringStateGathering {
+			res <- ErrRestartWhileGathering
+			return
+		}
+
+		// The session carrying the traffic is kept when restarting again
+		// before a pair of the new one is selected
+		if agent.connectivityTicker != nil && !agent.restarting {
+			agent.previous = &previousSession{
+				localUfrag:       agent.localUfrag,
+				localPwd:         agent.localPwd,
+				remoteUfrag:      agent.remoteUfrag,
+				remotePwd:        agent.remotePwd,
+				remoteCandidates: agent.remoteCandidates,
+			}
+			if p := agent.selectedPair; p != nil {
+				agent.previous.local, agent.previous.remote = p.local, p.remote
+			}
+		}
+
+		agent.localUfrag, agent.localPwd = ufrag, pwd
+		if agent.udpMuxConn != nil {
+			agent.udpMuxConn.mux.addUfrag(agent.udpMuxConn, ufrag)
+		}
+		agent.remoteUfrag, agent.remotePwd = "", ""
+		agent.remoteCandidates = make(map[NetworkType][]*Candidate)
+		agent.validPairs = nil
+		agent.bindingRequests = nil
+		agent.checks = make(map[pairKey]*pairCheck)
+		agent.restarting = agent.connectivityTicker != nil
+
+		if agent.packetConn == nil {
+			agent.dropLocalCandidates()
+			agent.gatheringErrors = nil
+			if agent.trickle {
+				agent.gatheringState = GatheringStateNew
+			} else {
+				agent.gatheringState = GatheringStateGathering
+			}
+		}
+
+		if agent.restarting && agent.selectedPair == nil {
+			agent.disconnectedAt = time.Now()
+			agent.updateConnectionState(ConnectionStateChecking)
+		}
+		res <- nil
+	})
+	if err != nil {
+		return err
+	}
+	if err = <-res; err != nil {
+		return err
+	}
+
+	if !a.trickle && a.packetConn == nil {
+		a.gatherCandidates()
+	}
+	return nil
+}
+
+// RollbackRestart ends the session started by Restart, such as for an offer
+// that was rolled back, once it is known it won't be checked. The previous
+// session and its remote candidates are brought back, the candidates
+// gathered for the new session are kept as its own. It does nothing once a
+// pair of the new session is selected.
+func (a *Agent) RollbackRestart() error {
+	return a.run(func(agent *Agent) {
+		p := agent.previous
+		if !agent.restarting || p == nil {
+			return
+		}
+
+		agent.localUfrag, agent.localPwd = p.localUfrag, p.localPwd
+		agent.remoteUfrag, agent.remotePwd = p.remoteUfrag, p.remotePwd
+		agent.remoteCandidates = p.remoteCandidates
+		if c := agent.previousCandidate; c != nil {
+			agent.localCandidates[c.NetworkType] = append(agent.localCandidates[c.NetworkType], c)
+			agent.previousCandidate = nil
+		}
+		agent.validPairs = nil
+		if agent.selectedPair != nil {
+			agent.validPairs = []*candidatePair{agent.selectedPair}
+		}
+		agent.checks = make(map[pairKey]*pairCheck)
+		agent.restarting = false
+		agent.previous = nil
+	})
+}
+
+// SetRemoteCredentials sets the credentials of the remote session that
+// follows a Restart, the remote candidates added since are checked with them
+func (a *Agent) SetRemoteCredentials(ufrag, pwd string) error {
+	switch {
+	case ufrag == "":
+		return errors.Errorf("remoteUfrag is empty")
+	case pwd == "":
+		return errors.Errorf("remotePwd is empty")
+	}
+
+	return a.run(func(agent *Agent) {
+		agent.remoteUfrag, agent.remotePwd = ufrag, pwd
+		if agent.isChecking() {
+			agent.pingAllCandidates()
+		}
+	})
+}
+
+// dropLocalCandidates closes the local candidates of the session a Restart
+// ends but the one of the selected pair, kept as the previousCandidate
+// until a pair of the new session is selected. The candidates are closed in
+// the background, their recvLoop may be waiting for the taskLoop.
+// Note: the caller should hold the agent lock.
+func (a *Agent) dropLocalCandidates() {
+	var dropped []*Candidate
+	for networkType, candidates := range a.localCandidates {
+		dropped = append(dropped, candidates...)
+		delete(a.localCandidates, networkType)
+	}
+	if a.previousCandidate != nil {
+		dropped = append(dropped, a.previousCandidate)
+		a.previousCandidate = nil
+	}
+
+	for _, c := range dropped {
+		if a.selectedPair != nil && c == a.selectedPair.local {
+			a.previousCandidate = c
+			continue
+		}
+		a.closeCandidate(c)
+	}
+}
+
+// endRestart closes the previousCandidate once a pair of the session
+// started by Restart is selected
+// Note: the caller should hold the agent lock.
+func (a *Agent) endRestart() {
+	if p := a.previous; !a.restarting ||
+		(p != nil && a.selectedPair.local == p.local && a.selectedPair.remote == p.remote) {
+		return
+	}
+	a.restarting = false
+	a.previous = nil
+
+	if a.previousCandidate != nil {
+		a.closeCandidate(a.previousCandidate)
+		a.previousCandidate = nil
+	}
+}
+
+// closeCandidate closes a local candidate in the background
+func (a *Agent) closeCandidate(c *Candidate) {
+	go func() {
+		if err := c.close(); err != nil {
+			a.log.Warnf("Failed to close candidate %s: %v", c, err)
+		}
+	}()
 }
 
 // Close cleans up the Agent
@@ -554,16 +1509,21 @@ func (a *Agent) Close() error {
 			for _, c := range cs {
 				err := c.close()
 				if err != nil {
-					iceLog.Warnf("Failed to close candidate %s: %v", c, err)
+					a.log.Warnf("Failed to close candidate %s: %v", c, err)
 				}
 			}
 			delete(agent.localCandidates, net)
 		}
+		if c := agent.previousCandidate; c != nil {
+			if err := c.close(); err != nil {
+				a.log.Warnf("Failed to close candidate %s: %v", c, err)
+			}
+		}
 		for net, cs := range agent.remoteCandidates {
 			for _, c := range cs {
 				err := c.close()
 				if err != nil {
-					iceLog.Warnf("Failed to close candidate %s: %v", c, err)
+					a.log.Warnf("Failed to close candidate %s: %v", c, err)
 				}
 			}
 			delete(agent.remoteCandidates, net)
@@ -575,22 +1535,22 @@ func (a *Agent) Close() error {
 
 	<-done
 
-	return nil
+	return a.closeMulticastDNS()
 }
 
 func (a *Agent) findRemoteCandidate(networkType NetworkType, addr net.Addr) *Candidate {
 	var ip net.IP
 	var port int
 
-	switch a := addr.(type) {
+	switch addr := addr.(type) {
 	case *net.UDPAddr:
-		ip = a.IP
-		port = a.Port
+		ip = addr.IP
+		port = addr.Port
 	case *net.TCPAddr:
-		ip = a.IP
-		port = a.Port
+		ip = addr.IP
+		port = addr.Port
 	default:
-		iceLog.Warnf("unsupported address type %T", a)
+		a.log.Warnf("unsupported address type %T", addr)
 		return nil
 	}
 
@@ -602,6 +1562,13 @@ func (a *Agent) findRemoteCandidate(networkType NetworkType, addr net.Addr) *Can
 			return c
 		}
 	}
+
+	// The remote of the pair of the previous session still carries the
+	// traffic during a restart
+	if p := a.selectedPair; a.restarting && p != nil &&
+		p.remote.NetworkType == networkType && p.remote.IP.Equal(ip) && p.remote.Port == port {
+		return p.remote
+	}
 	return nil
 }
 
@@ -615,11 +1582,11 @@ func (a *Agent) sendBindingSuccess(m *stun.Message, local, remote *Candidate) {
 			},
 		},
 		&stun.MessageIntegrity{
-			Key: []byte(a.localPwd),
+			Key: a.responseKey(m),
 		},
 		&stun.Fingerprint{},
 	); err != nil {
-		iceLog.Warnf("Failed to handle inbound ICE from: %s to: %s error: %s", local, remote, err)
+		a.log.Warnf("Failed to handle inbound ICE from: %s to: %s error: %s", local, remote, err)
 	} else {
 		a.sendSTUN(out, local, remote)
 	}
@@ -627,13 +1594,13 @@ func (a *Agent) sendBindingSuccess(m *stun.Message, local, remote *Candidate) {
 
 func (a *Agent) handleInboundControlled(m *stun.Message, localCandidate, remoteCandidate *Candidate) {
 	if _, isControlled := m.GetOneAttribute(stun.AttrIceControlled); isControlled && !a.isControlling {
-		iceLog.Debug("inbound isControlled && a.isControlling == false")
+		a.log.Debug("inbound isControlled && a.isControlling == false")
 		return
 	}
 
 	successResponse := m.Method == stun.MethodBinding && m.Class == stun.ClassSuccessResponse
 	_, usepair := m.GetOneAttribute(stun.AttrUseCandidate)
-	iceLog.Tracef("got controlled message (success? %t, usepair? %t)", successResponse, usepair)
+	a.log.Tracef("got controlled message (success? %t, usepair? %t)", successResponse, usepair)
 	// Remember the working pair and select it when marked with usepair
 	a.setValidPair(localCandidate, remoteCandidate, usepair, false)
 
@@ -645,13 +1612,13 @@ func (a *Agent) handleInboundControlled(m *stun.Message, localCandidate, remoteC
 
 func (a *Agent) handleInboundControlling(m *stun.Message, localCandidate, remoteCandidate *Candidate) {
 	if _, isControlling := m.GetOneAttribute(stun.AttrIceControlling); isControlling && a.isControlling {
-		iceLog.Debug("inbound isControlling && a.isControlling == true")
+		a.log.Debug("inbound isControlling && a.isControlling == true")
 		return
 	} else if _, useCandidate := m.GetOneAttribute(stun.AttrUseCandidate); useCandidate && a.isControlling {
-		iceLog.Debug("useCandidate && a.isControlling == true")
+		a.log.Debug("useCandidate && a.isControlling == true")
 		return
 	}
-	iceLog.Tracef("got controlling message: %#v", m)
+	a.log.Tracef("got controlling message: %#v", m)
 
 	successResponse := m.Method == stun.MethodBinding && m.Class == stun.ClassSuccessResponse
 	// Remember the working pair and select it when receiving a success response
@@ -703,22 +1670,36 @@ func (a *Agent) handleNewPeerReflexiveCandidate(local *Candidate, remote net.Add
 
 // handleInbound processes STUN traffic from a remote candidate
 func (a *Agent) handleInbound(m *stun.Message, local *Candidate, remote net.Addr) {
-	iceLog.Tracef("inbound STUN from %s to %s", remote.String(), local.String())
+	a.log.Tracef("inbound STUN from %s to %s", remote.String(), local.String())
 	remoteCandidate := a.findRemoteCandidate(local.NetworkType, remote)
 	if remoteCandidate == nil {
-		iceLog.Debugf("detected a new peer-reflexive candiate: %s ", remote)
+		a.log.Debugf("detected a new peer-reflexive candiate: %s ", remote)
 		err := a.handleNewPeerReflexiveCandidate(local, remote)
 		if err != nil {
 			// Log warning, then move on..
-			iceLog.Warn(err.Error())
+			a.log.Warn(err.Error())
 		}
 		return
 	}
 
 	remoteCandidate.seen(false)
 
-	if m.Class == stun.ClassIndication {
+	var rtt time.Duration
+	switch m.Class {
+	case stun.ClassIndication:
+		return
+	case stun.ClassErrorResponse:
+		a.handleErrorResponse(m, local, remoteCandidate)
 		return
+	case stun.ClassSuccessResponse:
+		if request, ok := a.popBindingRequest(m.TransactionID); ok {
+			rtt = time.Since(request.timestamp)
+		}
+		a.checkAnswered(local, remoteCandidate, true)
+	case stun.ClassRequest:
+		if !a.handleRoleConflict(m, local, remoteCandidate) {
+			return
+		}
 	}
 
 	if a.isControlling {
@@ -726,6 +1707,10 @@ func (a *Agent) handleInbound(m *stun.Message, local *Candidate, remote net.Addr
 	} else {
 		a.handleInboundControlled(m, local, remoteCandidate)
 	}
+
+	if p := a.findValidPair(local, remoteCandidate); p != nil && rtt != 0 {
+		p.rtt = rtt
+	}
 }
 
 // noSTUNSeen processes non STUN traffic from a remote candidate
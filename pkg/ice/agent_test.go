@@ -1,6 +1,8 @@
 package ice
 
 import (
+	"bytes"
+	"math/rand"
 	"net"
 	"testing"
 	"time"
@@ -114,6 +116,65 @@ func TestPairPriority(t *testing.T) {
 	}
 }
 
+func TestValidPairFailsBeforeNomination(t *testing.T) {
+	defer test.TimeOut(1 * time.Second).Stop()
+
+	timeout := time.Second
+	a, err := NewAgent(&AgentConfig{ConnectionTimeout: &timeout})
+	if err != nil {
+		t.Fatalf("Failed to create agent: %s", err)
+	}
+
+	hostLocal, err := NewCandidateHost("udp", net.ParseIP("192.168.1.1"), 19216, 1)
+	if err != nil {
+		t.Fatalf("Failed to construct local host candidate: %s", err)
+	}
+	hostRemote, err := NewCandidateHost("udp", net.ParseIP("1.2.3.5"), 12350, 1)
+	if err != nil {
+		t.Fatalf("Failed to construct remote host candidate: %s", err)
+	}
+	srflxRemote, err := NewCandidateServerReflexive("udp", net.ParseIP("10.10.10.2"), 19218, 1, "4.3.2.1", 43212)
+	if err != nil {
+		t.Fatalf("Failed to construct remote srflx candidate: %s", err)
+	}
+
+	// Both pairs work, nothing is nominated yet
+	for _, remote := range []*Candidate{srflxRemote, hostRemote} {
+		remote.seen(false)
+		a.setValidPair(hostLocal, remote, false, false)
+	}
+	bestPair, err := a.getBestPair()
+	if err != nil {
+		t.Fatalf("Failed to get best candidate pair: %s", err)
+	}
+	if bestPair.remote != hostRemote {
+		t.Fatalf("Unexpected bestPair %s (expected remote: %s)", bestPair, hostRemote)
+	}
+
+	// The best pair goes quiet, the traffic moves on to the other one
+	hostRemote.setLastReceived(time.Now().Add(-2 * timeout))
+	validated := make(chan bool)
+	if err = a.run(func(agent *Agent) {
+		validated <- agent.validateSelectedPair()
+	}); err != nil {
+		t.Fatalf("Failed to validate the selected pair: %s", err)
+	}
+	if <-validated {
+		t.Fatalf("No pair should be selected")
+	}
+	bestPair, err = a.getBestPair()
+	if err != nil {
+		t.Fatalf("Failed to get best candidate pair: %s", err)
+	}
+	if bestPair.remote != srflxRemote {
+		t.Fatalf("Unexpected bestPair %s (expected remote: %s)", bestPair, srflxRemote)
+	}
+
+	if err := a.Close(); err != nil {
+		t.Fatalf("Error on agent.Close(): %s", err)
+	}
+}
+
 type BadAddr struct{}
 
 func (ba *BadAddr) Network() string {
@@ -257,3 +318,563 @@ func TestHandlePeerReflexive(t *testing.T) {
 		}
 	})
 }
+
+func TestAgentRandomSource(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	newAgent := func() *Agent {
+		a, err := NewAgent(&AgentConfig{RandomSource: rand.New(rand.NewSource(1))})
+		if err != nil {
+			t.Fatalf("Error constructing ice.Agent: %v", err)
+		}
+		return a
+	}
+
+	a, b := newAgent(), newAgent()
+	if a.localUfrag != b.localUfrag || a.localPwd != b.localPwd || a.tieBreaker != b.tieBreaker {
+		t.Fatalf("Agents sharing a seeded random source should have the same credentials")
+	}
+	if len(a.localUfrag) != 16 || len(a.localPwd) != 32 {
+		t.Fatalf("Unexpected credential lengths %d/%d", len(a.localUfrag), len(a.localPwd))
+	}
+
+	if err := a.Close(); err != nil {
+		t.Fatalf("Close agent emits error %v", err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close agent emits error %v", err)
+	}
+
+	if _, err := NewAgent(&AgentConfig{RandomSource: bytes.NewReader(nil)}); err == nil {
+		t.Fatalf("NewAgent should fail when the random source is exhausted")
+	}
+}
+
+func TestAgentNetworkTypes(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	for _, networkType := range supportedNetworkTypes {
+		a, err := NewAgent(&AgentConfig{NetworkTypes: []NetworkType{networkType}})
+		if err != nil {
+			t.Fatalf("Error constructing ice.Agent: %v", err)
+		}
+
+		candidates, err := a.GetLocalCandidates()
+		if err != nil {
+			t.Fatalf("Failed to get local candidates: %v", err)
+		}
+		for _, c := range candidates {
+			if c.NetworkType != networkType {
+				t.Fatalf("Gathered a %s candidate with only %s enabled", c.NetworkType, networkType)
+			}
+		}
+
+		if err := a.Close(); err != nil {
+			t.Fatalf("Close agent emits error %v", err)
+		}
+	}
+}
+
+func TestAgentCandidateTypes(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	url, err := ParseURL("stun:127.0.0.1:3478")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the host candidates are gathered, the STUN server isn't queried
+	a, err := NewAgent(&AgentConfig{CandidateTypes: []CandidateType{CandidateTypeHost}, Urls: []*URL{url}})
+	if err != nil {
+		t.Fatalf("Error constructing ice.Agent: %v", err)
+	}
+	candidates, err := a.GetLocalCandidates()
+	if err != nil {
+		t.Fatalf("Failed to get local candidates: %v", err)
+	}
+	if len(candidates) == 0 {
+		t.Fatalf("Expected host candidates")
+	}
+	for _, c := range candidates {
+		if c.Type != CandidateTypeHost {
+			t.Fatalf("Gathered a %s candidate with only host enabled", c.Type)
+		}
+	}
+	if gatheringErrors, _ := a.GetGatheringErrors(); len(gatheringErrors) != 0 {
+		t.Fatalf("Expected no gathering error, got %v", gatheringErrors)
+	}
+	if err = a.Close(); err != nil {
+		t.Fatalf("Close agent emits error %v", err)
+	}
+
+	// Nothing is gathered with only the relay candidates
+	a, err = NewAgent(&AgentConfig{CandidateTypes: []CandidateType{CandidateTypeRelay}, Urls: []*URL{url}})
+	if err != nil {
+		t.Fatalf("Error constructing ice.Agent: %v", err)
+	}
+	if candidates, err = a.GetLocalCandidates(); err != nil || len(candidates) != 0 {
+		t.Fatalf("Expected no candidate, got %v %v", candidates, err)
+	}
+	if err = a.Close(); err != nil {
+		t.Fatalf("Close agent emits error %v", err)
+	}
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewAgent(&AgentConfig{PacketConn: conn, CandidateTypes: []CandidateType{CandidateTypeRelay}}); err != ErrPacketConnCandidateType {
+		t.Fatalf("Expected ErrPacketConnCandidateType, got %v", err)
+	}
+	if err = conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAgentFilters(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	a, err := NewAgent(&AgentConfig{
+		InterfaceFilter: func(string) bool { return false },
+	})
+	if err != nil {
+		t.Fatalf("Error constructing ice.Agent: %v", err)
+	}
+	candidates, err := a.GetLocalCandidates()
+	if err != nil || len(candidates) != 0 {
+		t.Fatalf("Expected no candidate, got %v %v", candidates, err)
+	}
+	if err = a.Close(); err != nil {
+		t.Fatalf("Close agent emits error %v", err)
+	}
+
+	a, err = NewAgent(&AgentConfig{
+		IPFilter: func(ip net.IP) bool { return ip.To4() != nil },
+	})
+	if err != nil {
+		t.Fatalf("Error constructing ice.Agent: %v", err)
+	}
+	if candidates, err = a.GetLocalCandidates(); err != nil {
+		t.Fatalf("Failed to get local candidates: %v", err)
+	}
+	for _, c := range candidates {
+		if c.IP.To4() == nil {
+			t.Fatalf("Gathered the filtered out candidate %s", c)
+		}
+	}
+	if err = a.Close(); err != nil {
+		t.Fatalf("Close agent emits error %v", err)
+	}
+}
+
+func TestAgentNAT1To1IPs(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	external := net.IPv4(203, 0, 113, 1)
+	a, err := NewAgent(&AgentConfig{
+		NetworkTypes: []NetworkType{NetworkTypeUDP4},
+		NAT1To1IPs:   []string{external.String()},
+	})
+	if err != nil {
+		t.Fatalf("Error constructing ice.Agent: %v", err)
+	}
+	candidates, err := a.GetLocalCandidates()
+	if err != nil {
+		t.Fatalf("Failed to get local candidates: %v", err)
+	}
+	if len(candidates) == 0 {
+		t.Fatal("No host candidate was gathered")
+	}
+	for _, c := range candidates {
+		if c.Type != CandidateTypeHost || !c.IP.Equal(external) {
+			t.Fatalf("Expected the host candidates to advertise %s, got %s", external, c)
+		}
+	}
+	if err = a.Close(); err != nil {
+		t.Fatalf("Close agent emits error %v", err)
+	}
+
+	// The srflx candidate is gathered on its own socket
+	a, err = NewAgent(&AgentConfig{
+		NetworkTypes:           []NetworkType{NetworkTypeUDP4},
+		CandidateTypes:         []CandidateType{CandidateTypeServerReflexive},
+		NAT1To1IPs:             []string{external.String()},
+		NAT1To1IPCandidateType: CandidateTypeServerReflexive,
+	})
+	if err != nil {
+		t.Fatalf("Error constructing ice.Agent: %v", err)
+	}
+	if candidates, err = a.GetLocalCandidates(); err != nil {
+		t.Fatalf("Failed to get local candidates: %v", err)
+	}
+	if len(candidates) != 1 {
+		t.Fatalf("Expected a single candidate, got %v", candidates)
+	}
+	if c := candidates[0]; c.Type != CandidateTypeServerReflexive || !c.IP.Equal(external) ||
+		c.RelatedAddress == nil || c.RelatedAddress.Port != c.Port {
+		t.Fatalf("Unexpected candidate %s", c)
+	}
+	if err = a.Close(); err != nil {
+		t.Fatalf("Close agent emits error %v", err)
+	}
+
+	if _, err = NewAgent(&AgentConfig{
+		NAT1To1IPs:       []string{external.String()},
+		MulticastDNSMode: MulticastDNSModeQueryAndGather,
+	}); err != ErrMulticastDNSWithNAT1To1IPs {
+		t.Fatalf("Expected ErrMulticastDNSWithNAT1To1IPs, got %v", err)
+	}
+}
+
+func TestAgentPacketConn(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IP{127, 0, 0, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, err := ParseURL("stun:stun.l.google.com:19302")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := NewAgent(&AgentConfig{PacketConn: conn, Urls: []*URL{url}})
+	if err != nil {
+		t.Fatalf("Error constructing ice.Agent: %v", err)
+	}
+
+	// The only candidate is the address of the conn
+	candidates, err := a.GetLocalCandidates()
+	if err != nil {
+		t.Fatalf("Failed to get local candidates: %v", err)
+	}
+	if len(candidates) != 1 {
+		t.Fatalf("Expected a single candidate, got %d", len(candidates))
+	}
+	addr := conn.LocalAddr().(*net.UDPAddr)
+	if c := candidates[0]; c.Type != CandidateTypeHost || !c.IP.Equal(addr.IP) || c.Port != addr.Port {
+		t.Fatalf("Unexpected candidate %s", c)
+	}
+
+	gatheringErrors, err := a.GetGatheringErrors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gatheringErrors) != 1 || gatheringErrors[0].URL != url {
+		t.Fatalf("Expected the STUN server to be reported unused, got %v", gatheringErrors)
+	}
+
+	// The agent owns the conn
+	if err = a.Close(); err != nil {
+		t.Fatalf("Close agent emits error %v", err)
+	}
+	if _, err = conn.WriteTo([]byte{0x00}, addr); err == nil {
+		t.Fatalf("The conn should be closed with the agent")
+	}
+
+	unspecified, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewAgent(&AgentConfig{PacketConn: unspecified}); err != ErrPacketConnAddr {
+		t.Fatalf("Expected ErrPacketConnAddr, got %v", err)
+	}
+	if err = unspecified.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAgentTrickle(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewAgent(&AgentConfig{PacketConn: conn, Trickle: true})
+	if err != nil {
+		t.Fatalf("Error constructing ice.Agent: %v", err)
+	}
+
+	// Nothing is gathered until GatherCandidates
+	if candidates, err := a.GetLocalCandidates(); err != nil || len(candidates) != 0 {
+		t.Fatalf("Expected no candidate before the gathering, got %v %v", candidates, err)
+	}
+
+	gathered := make(chan *Candidate, 2)
+	if err = a.OnCandidate(func(c *Candidate) {
+		gathered <- c
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.GatherCandidates(); err != nil {
+		t.Fatal(err)
+	}
+
+	c := <-gathered
+	if c == nil || c.Port != conn.LocalAddr().(*net.UDPAddr).Port {
+		t.Fatalf("Expected the host candidate of the PacketConn, got %v", c)
+	}
+	if end := <-gathered; end != nil {
+		t.Fatalf("Expected the end of the gathering, got %v", end)
+	}
+	if candidates, err := a.GetLocalCandidates(); err != nil || len(candidates) != 1 || candidates[0] != c {
+		t.Fatalf("Expected the trickled candidate, got %v %v", candidates, err)
+	}
+
+	if err = a.GatherCandidates(); err != ErrMultipleGather {
+		t.Fatalf("Expected ErrMultipleGather, got %v", err)
+	}
+	if err = a.Close(); err != nil {
+		t.Fatalf("Close agent emits error %v", err)
+	}
+
+	// The agents gathering in NewAgent can't gather again
+	a, err = NewAgent(&AgentConfig{NetworkTypes: []NetworkType{NetworkTypeUDP4}})
+	if err != nil {
+		t.Fatalf("Error constructing ice.Agent: %v", err)
+	}
+	if err = a.GatherCandidates(); err != ErrGatherWithoutTrickle {
+		t.Fatalf("Expected ErrGatherWithoutTrickle, got %v", err)
+	}
+	if err = a.Close(); err != nil {
+		t.Fatalf("Close agent emits error %v", err)
+	}
+}
+
+// restarting returns whether the agent didn't select a pair of the session
+// started by Restart yet
+func restarting(t *testing.T, a *Agent) bool {
+	res := make(chan bool, 1)
+	if err := a.run(func(agent *Agent) {
+		res <- agent.restarting
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return <-res
+}
+
+// selectedUsername returns the USERNAME of the Binding Requests sent on the
+// selected pair
+func selectedUsername(t *testing.T, a *Agent) string {
+	res := make(chan string, 1)
+	if err := a.run(func(agent *Agent) {
+		username, _ := agent.pingCredentials(agent.selectedPair.local, agent.selectedPair.remote)
+		res <- username
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return <-res
+}
+
+// exchange writes a message on one conn and reads it from the other
+func exchange(t *testing.T, from, to *Conn, msg string) {
+	if _, err := from.Write([]byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, receiveMTU)
+	n, err := to.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != msg {
+		t.Fatalf("Expected %q, got %q", msg, buf[:n])
+	}
+}
+
+func TestAgentRestart(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	config := &AgentConfig{NetworkTypes: []NetworkType{NetworkTypeUDP4}}
+	aAgent, err := NewAgent(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bAgent, err := NewAgent(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aNotifier, aConnected := onConnected()
+	bNotifier, bConnected := onConnected()
+	check(aAgent.OnConnectionStateChange(aNotifier))
+	check(bAgent.OnConnectionStateChange(bNotifier))
+
+	aConn, bConn := connect(aAgent, bAgent)
+	<-aConnected
+	<-bConnected
+	exchange(t, aConn, bConn, "before")
+
+	aUfrag, aPwd := aAgent.GetLocalUserCredentials()
+	previousUsername := selectedUsername(t, aAgent)
+	aCandidates, err := aAgent.GetLocalCandidates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = aAgent.Restart("", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err = bAgent.Restart("", ""); err != nil {
+		t.Fatal(err)
+	}
+	if username := selectedUsername(t, aAgent); username != previousUsername {
+		t.Fatalf("Expected the keepalives to use %s until the restart ends, got %s", previousUsername, username)
+	}
+
+	// The new session has new credentials and candidates, the traffic
+	// keeps going over the previous one meanwhile
+	newUfrag, newPwd := aAgent.GetLocalUserCredentials()
+	if newUfrag == aUfrag || newPwd == aPwd {
+		t.Fatalf("Expected new credentials, got %s %s", newUfrag, newPwd)
+	}
+	newCandidates, err := aAgent.GetLocalCandidates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range newCandidates {
+		for _, previous := range aCandidates {
+			if c == previous {
+				t.Fatalf("Expected the candidates to be gathered again, %s is kept", c)
+			}
+		}
+	}
+	exchange(t, aConn, bConn, "restarting")
+
+	bUfrag, bPwd := bAgent.GetLocalUserCredentials()
+	check(aAgent.SetRemoteCredentials(bUfrag, bPwd))
+	check(bAgent.SetRemoteCredentials(newUfrag, newPwd))
+	for _, c := range newCandidates {
+		check(bAgent.AddRemoteCandidate(copyCandidate(c)))
+	}
+	bCandidates, err := bAgent.GetLocalCandidates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range bCandidates {
+		check(aAgent.AddRemoteCandidate(copyCandidate(c)))
+	}
+
+	for restarting(t, aAgent) || restarting(t, bAgent) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	exchange(t, aConn, bConn, "after")
+	exchange(t, bConn, aConn, "back")
+
+	check(aConn.Close())
+	check(bConn.Close())
+}
+
+func TestAgentRollbackRestart(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	config := &AgentConfig{NetworkTypes: []NetworkType{NetworkTypeUDP4}}
+	aAgent, err := NewAgent(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bAgent, err := NewAgent(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aNotifier, aConnected := onConnected()
+	bNotifier, bConnected := onConnected()
+	check(aAgent.OnConnectionStateChange(aNotifier))
+	check(bAgent.OnConnectionStateChange(bNotifier))
+
+	aConn, bConn := connect(aAgent, bAgent)
+	<-aConnected
+	<-bConnected
+
+	aUfrag, aPwd := aAgent.GetLocalUserCredentials()
+	username := selectedUsername(t, aAgent)
+	if err = aAgent.Restart("", ""); err != nil {
+		t.Fatal(err)
+	}
+	exchange(t, aConn, bConn, "restarting")
+
+	// The previous session is back, its pair kept carrying the traffic
+	check(aAgent.RollbackRestart())
+	if ufrag, pwd := aAgent.GetLocalUserCredentials(); ufrag != aUfrag || pwd != aPwd {
+		t.Fatalf("Expected the credentials %s %s to be back, got %s %s", aUfrag, aPwd, ufrag, pwd)
+	}
+	if restarting(t, aAgent) {
+		t.Fatal("Expected the restart to be rolled back")
+	}
+	if got := selectedUsername(t, aAgent); got != username {
+		t.Fatalf("Expected the keepalives to use %s, got %s", username, got)
+	}
+	exchange(t, aConn, bConn, "after")
+	exchange(t, bConn, aConn, "back")
+
+	// Without a restart in progress nothing changes
+	check(aAgent.RollbackRestart())
+	if ufrag, _ := aAgent.GetLocalUserCredentials(); ufrag != aUfrag {
+		t.Fatalf("Expected the credentials to be kept, got %s", ufrag)
+	}
+
+	check(aConn.Close())
+	check(bConn.Close())
+}
+
+func TestAgentFailed(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	connectionTimeout := time.Second
+	keepalive := time.Duration(0)
+	failedTimeout := time.Second
+	config := &AgentConfig{
+		NetworkTypes:      []NetworkType{NetworkTypeUDP4},
+		ConnectionTimeout: &connectionTimeout,
+		KeepaliveInterval: &keepalive,
+		FailedTimeout:     &failedTimeout,
+	}
+	aAgent, err := NewAgent(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bAgent, err := NewAgent(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	states := make(chan ConnectionState, 8)
+	check(aAgent.OnConnectionStateChange(func(state ConnectionState) {
+		states <- state
+	}))
+	aConn, bConn := connect(aAgent, bAgent)
+	for state := range states {
+		if state == ConnectionStateConnected {
+			break
+		}
+	}
+
+	// The remote goes away, the connection is disconnected and then fails
+	check(bConn.Close())
+	for _, expected := range []ConnectionState{ConnectionStateDisconnected, ConnectionStateFailed} {
+		if state := <-states; state != expected {
+			t.Fatalf("Expected %s, got %s", expected, state)
+		}
+	}
+
+	check(aConn.Close())
+}
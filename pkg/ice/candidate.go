@@ -3,6 +3,7 @@ package ice
 import (
 	"fmt"
 	"net"
+	"strings"
 	"sync"
 	"time"
 
@@ -30,6 +31,11 @@ type Candidate struct {
 	Port            int
 	RelatedAddress  *CandidateRelatedAddress
 
+	// Hostname is the multicast DNS name a host candidate hides its address
+	// behind, such as the UUID.local names of the browsers. It is signaled
+	// instead of IP, the IP of a remote candidate is resolved from it.
+	Hostname string
+
 	lock         sync.RWMutex
 	lastSent     time.Time
 	lastReceived time.Time
@@ -38,6 +44,10 @@ type Candidate struct {
 	conn     net.PacketConn
 	closeCh  chan struct{}
 	closedCh chan struct{}
+
+	// timestampConn is the conn once the kernel timestamps its packets,
+	// they are read with their timestamp
+	timestampConn *net.UDPConn
 }
 
 // NewCandidateHost creates a new host candidate
@@ -57,6 +67,28 @@ func NewCandidateHost(network string, ip net.IP, port int, component uint16) (*C
 	}, nil
 }
 
+// NewCandidateHostName creates a remote host candidate whose address is
+// hidden behind a multicast DNS name, the agent resolves its IPv4 address
+// once it is added
+func NewCandidateHostName(network string, hostname string, port int, component uint16) (*Candidate, error) {
+	if !IsMulticastDNSName(hostname) {
+		return nil, fmt.Errorf("%s is not a multicast DNS name", hostname)
+	}
+	networkType, err := determineNetworkType(network, net.IPv4zero)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Candidate{
+		Type:            CandidateTypeHost,
+		NetworkType:     networkType,
+		Hostname:        hostname,
+		Port:            port,
+		LocalPreference: defaultLocalPreference,
+		Component:       component,
+	}, nil
+}
+
 // NewCandidateServerReflexive creates a new server reflective candidate
 func NewCandidateServerReflexive(network string, ip net.IP, port int, component uint16, relAddr string, relPort int) (*Candidate, error) {
 	networkType, err := determineNetworkType(network, ip)
@@ -124,6 +156,14 @@ func (c *Candidate) start(a *Agent, conn net.PacketConn) {
 	c.closeCh = make(chan struct{})
 	c.closedCh = make(chan struct{})
 
+	if udpConn, ok := conn.(*net.UDPConn); ok {
+		if err := enableTimestamps(udpConn); err != nil {
+			a.log.Debugf("Failed to enable the receive timestamps of %s, using the read time: %v", c.addr(), err)
+		} else {
+			c.timestampConn = udpConn
+		}
+	}
+
 	go c.recvLoop()
 }
 
@@ -133,23 +173,25 @@ func (c *Candidate) recvLoop() {
 	}()
 
 	buffer := make([]byte, receiveMTU)
+	oob := make([]byte, timestampOOBSize)
 	for {
-		n, srcAddr, err := c.conn.ReadFrom(buffer)
+		n, srcAddr, timestamp, err := c.readFrom(buffer, oob)
 		if err != nil {
 			return
 		}
+		c.agent.countReceived(n)
 
 		if stun.IsSTUN(buffer[:n]) {
 			m, err := stun.NewMessage(buffer[:n])
 			if err != nil {
-				iceLog.Warnf("Failed to handle decode ICE from %s to %s: %v", c.addr(), srcAddr, err)
+				c.agent.log.Warnf("Failed to handle decode ICE from %s to %s: %v", c.addr(), srcAddr, err)
 				continue
 			}
 			err = c.agent.run(func(agent *Agent) {
 				agent.handleInbound(m, c, srcAddr)
 			})
 			if err != nil {
-				iceLog.Warnf("Failed to handle message: %v", err)
+				c.agent.log.Warnf("Failed to handle message: %v", err)
 			}
 
 			continue
@@ -158,16 +200,50 @@ func (c *Candidate) recvLoop() {
 				agent.noSTUNSeen(c, srcAddr)
 			})
 			if err != nil {
-				iceLog.Warnf("Failed to handle message: %v", err)
+				c.agent.log.Warnf("Failed to handle message: %v", err)
 			}
 		}
 
+		if !c.deliver(buffer[:n], timestamp) {
+			return
+		}
+	}
+}
+
+// readFrom reads the next packet with its receive time: the kernel timestamp
+// when it is enabled on the conn, the time it is read otherwise
+func (c *Candidate) readFrom(buffer, oob []byte) (int, net.Addr, time.Time, error) {
+	if c.timestampConn == nil {
+		n, srcAddr, err := c.conn.ReadFrom(buffer)
+		return n, srcAddr, time.Now(), err
+	}
+
+	n, oobn, _, srcAddr, err := c.timestampConn.ReadMsgUDP(buffer, oob)
+	if err != nil {
+		return 0, nil, time.Time{}, err
+	}
+	timestamp, ok := parseTimestamp(oob[:oobn])
+	if !ok {
+		timestamp = time.Now()
+	}
+	return n, srcAddr, timestamp, nil
+}
+
+// deliver hands a packet to the next Read with a buffer large enough for
+// it, the smaller ones are told its size. It returns false once closed.
+func (c *Candidate) deliver(packet []byte, timestamp time.Time) bool {
+	for {
 		select {
 		case bufin := <-c.agent.rcvCh:
-			copy(bufin.buf, buffer[:n]) // TODO: avoid copy in common case?
-			bufin.size <- n
+			if len(bufin.buf) < len(packet) {
+				bufin.size <- len(packet)
+				continue
+			}
+			bufin.timestamp = timestamp
+			bufin.size <- copy(bufin.buf, packet) // TODO: avoid copy in common case?
+			return true
 		case <-c.closeCh:
-			return
+			return false
 		}
 	}
 }
@@ -197,6 +273,7 @@ func (c *Candidate) writeTo(raw []byte, dst *Candidate) (int, error) {
 	if err != nil {
 		return n, fmt.Errorf("failed to send packet: %v", err)
 	}
+	c.agent.countSent(n)
 	c.seen(true)
 	return n, nil
 }
@@ -218,13 +295,25 @@ func (c *Candidate) Priority() uint16 {
 func (c *Candidate) Equal(other *Candidate) bool {
 	return c.NetworkType == other.NetworkType &&
 		c.Type == other.Type &&
-		c.IP.Equal(other.IP) &&
+		c.addressEqual(other) &&
 		c.Port == other.Port &&
 		c.RelatedAddress.Equal(other.RelatedAddress)
 }
 
+// addressEqual compares the addresses of the candidates, by their names
+// when either is only known by its name
+func (c *Candidate) addressEqual(other *Candidate) bool {
+	if c.IP == nil || other.IP == nil {
+		return c.Hostname != "" && strings.EqualFold(c.Hostname, other.Hostname)
+	}
+	return c.IP.Equal(other.IP)
+}
+
 // String makes the CandidateHost printable
 func (c *Candidate) String() string {
+	if c.IP == nil {
+		return fmt.Sprintf("%s %s:%d%s", c.Type, c.Hostname, c.Port, c.RelatedAddress)
+	}
 	return fmt.Sprintf("%s %s:%d%s", c.Type, c.IP, c.Port, c.RelatedAddress)
 }
 
@@ -2,8 +2,10 @@ package ice
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/pions/stun"
+	"github.com/pkg/errors"
 )
 
 func newCandidatePair(local, remote *Candidate, controlling bool) *candidatePair {
@@ -19,6 +21,10 @@ type candidatePair struct {
 	iceRoleControlling bool
 	remote             *Candidate
 	local              *Candidate
+
+	// rtt is the round-trip time of the last connectivity check answered
+	// on the pair
+	rtt time.Duration
 }
 
 func (p *candidatePair) String() string {
@@ -66,31 +72,96 @@ func (p *candidatePair) Priority() uint32 {
 	return (2^32)*min(g, d) + 2*max(g, d) + cmp(g, d)
 }
 
-func (p *candidatePair) Write(b []byte) (int, error) {
-	return p.local.writeTo(b, p.remote)
+// CandidatePairStats describes a candidate pair that passed the connectivity
+// checks
+type CandidatePairStats struct {
+	Local  *Candidate
+	Remote *Candidate
+
+	Priority uint32
+
+	// RTT is the round-trip time of the last connectivity check answered on
+	// the pair, 0 until one is
+	RTT time.Duration
+
+	// Selected is true for the pair the Conn sends on
+	Selected bool
 }
 
-// keepaliveCandidate sends a STUN Binding Indication to the remote candidate
-func (a *Agent) keepaliveCandidate(local, remote *Candidate) {
-	msg, err := stun.Build(stun.ClassIndication, stun.MethodBinding, stun.GenerateTransactionID(),
-		&stun.Username{Username: a.remoteUfrag + ":" + a.localUfrag},
-		&stun.MessageIntegrity{
-			Key: []byte(a.remotePwd),
-		},
-		&stun.Fingerprint{},
-	)
+// GetCandidatePairs returns the candidate pairs that passed the connectivity
+// checks, by decreasing priority
+func (a *Agent) GetCandidatePairs() ([]CandidatePairStats, error) {
+	res := make(chan []CandidatePairStats)
 
+	err := a.run(func(agent *Agent) {
+		var pairs []CandidatePairStats
+		for _, p := range agent.validPairs {
+			pairs = append(pairs, CandidatePairStats{
+				Local:    p.local,
+				Remote:   p.remote,
+				Priority: p.Priority(),
+				RTT:      p.rtt,
+				Selected: p == agent.selectedPair,
+			})
+		}
+		res <- pairs
+	})
 	if err != nil {
-		iceLog.Warn(err.Error())
-		return
+		return nil, err
 	}
 
-	a.sendSTUN(msg, local, remote)
+	return <-res, nil
+}
+
+// SetSelectedCandidatePair forces the Conn to send on the valid pair of the
+// given candidates, instead of the one nominated by the connectivity
+// checks. The controlling Agent nominates the pair to the remote as well.
+// The pair is monitored like a nominated one and the Agent goes back to the
+// nominations once it fails.
+func (a *Agent) SetSelectedCandidatePair(local, remote *Candidate) error {
+	res := make(chan error)
+
+	err := a.run(func(agent *Agent) {
+		for _, p := range agent.validPairs {
+			if !p.local.Equal(local) || !p.remote.Equal(remote) {
+				continue
+			}
+
+			agent.selectedPair = p
+			agent.selectedPairForced = true
+			agent.updateConnectionState(ConnectionStateConnected)
+			if agent.isControlling {
+				agent.pingCandidate(p.local, p.remote)
+			}
+			res <- nil
+			return
+		}
+		res <- errors.Errorf("no valid candidate pair %s <-> %s", local, remote)
+	})
+	if err != nil {
+		return err
+	}
+
+	return <-res
+}
+
+// findValidPair returns the valid pair of the candidates of the Agent
+func (a *Agent) findValidPair(local, remote *Candidate) *candidatePair {
+	for _, p := range a.validPairs {
+		if p.local == local && p.remote == remote {
+			return p
+		}
+	}
+	return nil
+}
+
+func (p *candidatePair) Write(b []byte) (int, error) {
+	return p.local.writeTo(b, p.remote)
 }
 
 func (a *Agent) sendSTUN(msg *stun.Message, local, remote *Candidate) {
 	_, err := local.writeTo(msg.Pack(), remote)
 	if err != nil {
-		iceLog.Tracef("failed to send STUN message: %s", err)
+		a.log.Tracef("failed to send STUN message: %s", err)
 	}
 }
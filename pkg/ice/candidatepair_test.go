@@ -0,0 +1,96 @@
+package ice
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pions/transport/test"
+)
+
+func TestAgentSelectedCandidatePair(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	ca, cb := pipe()
+	fatalf := func(format string, args ...interface{}) {
+		check(ca.Close())
+		check(cb.Close())
+		t.Fatalf(format, args...)
+	}
+
+	// A pair validated by an inbound request has no RTT until a check of its
+	// own is answered. The controlling agent selected its pair on the success
+	// response of its check, the RTT is measured with it.
+	rttMeasured := false
+	for deadline := time.Now().Add(5 * time.Second); !rttMeasured && time.Now().Before(deadline); {
+		bPairs, err := cb.agent.GetCandidatePairs()
+		check(err)
+		for _, p := range bPairs {
+			if p.Selected && p.RTT > 0 {
+				rttMeasured = true
+			}
+		}
+		if !rttMeasured {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	if !rttMeasured {
+		fatalf("The RTT of the selected pair wasn't measured")
+	}
+
+	pairs, err := ca.agent.GetCandidatePairs()
+	check(err)
+	if len(pairs) == 0 {
+		fatalf("No valid candidate pair")
+	}
+
+	var selected, other *CandidatePairStats
+	for i := range pairs {
+		if pairs[i].Selected {
+			selected = &pairs[i]
+		} else {
+			other = &pairs[i]
+		}
+	}
+	if selected == nil {
+		fatalf("No candidate pair is selected: %v", pairs)
+	}
+
+	// Force another pair when there is one
+	forced := selected
+	if other != nil {
+		forced = other
+	}
+	check(ca.agent.SetSelectedCandidatePair(forced.Local, forced.Remote))
+
+	pairs, err = ca.agent.GetCandidatePairs()
+	check(err)
+	for _, p := range pairs {
+		if p.Selected != (p.Local == forced.Local && p.Remote == forced.Remote) {
+			fatalf("The forced pair should be the only one selected: %v", pairs)
+		}
+	}
+
+	// Media still flows on the forced pair
+	msg := []byte("hello")
+	if _, err = ca.Write(msg); err != nil {
+		fatalf("%v", err)
+	}
+	buf := make([]byte, 10)
+	if _, err = cb.Read(buf); err != nil {
+		fatalf("%v", err)
+	}
+
+	unknown, err := NewCandidateHost("udp", net.ParseIP("192.0.2.1"), 9, ComponentRTP)
+	check(err)
+	if err = ca.agent.SetSelectedCandidatePair(unknown, forced.Remote); err == nil {
+		fatalf("Forcing a pair that isn't valid should fail")
+	}
+
+	check(ca.Close())
+	check(cb.Close())
+}
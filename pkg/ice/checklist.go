@@ -0,0 +1,186 @@
+package ice
+
+import (
+	"sort"
+	"time"
+)
+
+// CandidatePairState is the state of the connectivity checks of a candidate
+// pair, as defined in https://tools.ietf.org/html/rfc8445#section-6.1.2.6
+type CandidatePairState int
+
+const (
+	// CandidatePairStateWaiting indicates no check was sent on the pair yet
+	CandidatePairStateWaiting CandidatePairState = iota + 1
+
+	// CandidatePairStateInProgress indicates checks were sent on the pair
+	// and none was answered yet
+	CandidatePairStateInProgress
+
+	// CandidatePairStateSucceeded indicates a check of the pair was answered
+	// with a success response
+	CandidatePairStateSucceeded
+
+	// CandidatePairStateFailed indicates the last check of the pair was
+	// answered with an error, or that no check was answered within the
+	// connection timeout of the first one
+	CandidatePairStateFailed
+)
+
+func (s CandidatePairState) String() string {
+	switch s {
+	case CandidatePairStateWaiting:
+		return "Waiting"
+	case CandidatePairStateInProgress:
+		return "In Progress"
+	case CandidatePairStateSucceeded:
+		return "Succeeded"
+	case CandidatePairStateFailed:
+		return "Failed"
+	default:
+		return "Invalid"
+	}
+}
+
+// pairKey identifies a candidate pair of the checklist
+type pairKey struct {
+	local, remote *Candidate
+}
+
+// pairCheck records the connectivity checks of a candidate pair
+type pairCheck struct {
+	requestsSent      uint64
+	responsesReceived uint64
+
+	firstRequest time.Time
+	lastRequest  time.Time
+	lastResponse time.Time
+
+	// failed is set when the last response was an error
+	failed    bool
+	nominated bool
+}
+
+// state returns the state of the checks at now, a pair whose checks were
+// never answered fails after the timeout, 0 never failing it
+func (c *pairCheck) state(now time.Time, timeout time.Duration) CandidatePairState {
+	switch {
+	case c == nil || c.requestsSent == 0:
+		return CandidatePairStateWaiting
+	case c.failed:
+		return CandidatePairStateFailed
+	case c.responsesReceived != 0:
+		return CandidatePairStateSucceeded
+	case timeout != 0 && now.Sub(c.firstRequest) > timeout:
+		return CandidatePairStateFailed
+	default:
+		return CandidatePairStateInProgress
+	}
+}
+
+// check returns the record of the checks of a pair, created on first use
+// Note: the caller should hold the agent lock.
+func (a *Agent) check(local, remote *Candidate) *pairCheck {
+	key := pairKey{local, remote}
+	c, ok := a.checks[key]
+	if !ok {
+		c = &pairCheck{}
+		a.checks[key] = c
+	}
+	return c
+}
+
+// checkSent records a binding request sent on a pair
+// Note: the caller should hold the agent lock.
+func (a *Agent) checkSent(local, remote *Candidate) {
+	c := a.check(local, remote)
+	now := time.Now()
+	if c.requestsSent == 0 {
+		c.firstRequest = now
+	}
+	c.requestsSent++
+	c.lastRequest = now
+}
+
+// checkAnswered records a response received on a pair
+// Note: the caller should hold the agent lock.
+func (a *Agent) checkAnswered(local, remote *Candidate, success bool) {
+	c := a.check(local, remote)
+	c.responsesReceived++
+	c.lastResponse = time.Now()
+	c.failed = !success
+}
+
+// CandidatePairCheck is a candidate pair of the checklist, with the state of
+// its connectivity checks
+type CandidatePairCheck struct {
+	CandidatePairStats
+
+	State CandidatePairState
+
+	// Nominated is set once the pair was nominated, by the USE-CANDIDATE
+	// attribute of the controlling Agent
+	Nominated bool
+
+	RequestsSent      uint64
+	ResponsesReceived uint64
+
+	// LastRequestSent and LastResponseReceived are zero until a check was
+	// sent or answered
+	LastRequestSent      time.Time
+	LastResponseReceived time.Time
+}
+
+// GetChecklist returns a snapshot of the checklist: every pair of a local
+// and a remote candidate of the same network type, by decreasing priority.
+// Unlike GetCandidatePairs it lists the pairs that were not validated, with
+// the state of their checks.
+func (a *Agent) GetChecklist() ([]CandidatePairCheck, error) {
+	res := make(chan []CandidatePairCheck)
+
+	err := a.run(func(agent *Agent) {
+		now := time.Now()
+		var checks []CandidatePairCheck
+		for networkType, localCandidates := range agent.localCandidates {
+			for _, local := range localCandidates {
+				for _, remote := range agent.remoteCandidates[networkType] {
+					checks = append(checks, agent.pairCheck(local, remote, now))
+				}
+			}
+		}
+		sort.Slice(checks, func(i, j int) bool { return checks[i].Priority > checks[j].Priority })
+		res <- checks
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return <-res, nil
+}
+
+// pairCheck returns the entry of the checklist of a pair
+// Note: the caller should hold the agent lock.
+func (a *Agent) pairCheck(local, remote *Candidate, now time.Time) CandidatePairCheck {
+	check := CandidatePairCheck{
+		CandidatePairStats: CandidatePairStats{
+			Local:    local,
+			Remote:   remote,
+			Priority: newCandidatePair(local, remote, a.isControlling).Priority(),
+			Selected: a.selectedPair != nil && a.selectedPair.local == local && a.selectedPair.remote == remote,
+		},
+	}
+	if p := a.findValidPair(local, remote); p != nil {
+		check.RTT = p.rtt
+	}
+
+	c := a.checks[pairKey{local, remote}]
+	check.State = c.state(now, a.connectionTimeout)
+	if c != nil {
+		check.Nominated = c.nominated
+		check.RequestsSent = c.requestsSent
+		check.ResponsesReceived = c.responsesReceived
+		check.LastRequestSent = c.lastRequest
+		check.LastResponseReceived = c.lastResponse
+	}
+	return check
+}
@@ -0,0 +1,86 @@
+package ice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pions/transport/test"
+)
+
+func TestPairCheckState(t *testing.T) {
+	now := time.Now()
+	for i, testCase := range []struct {
+		check    *pairCheck
+		expected CandidatePairState
+	}{
+		{nil, CandidatePairStateWaiting},
+		{&pairCheck{}, CandidatePairStateWaiting},
+		{&pairCheck{requestsSent: 1, firstRequest: now}, CandidatePairStateInProgress},
+		{&pairCheck{requestsSent: 5, firstRequest: now.Add(-time.Minute)}, CandidatePairStateFailed},
+		{&pairCheck{requestsSent: 5, responsesReceived: 1, firstRequest: now.Add(-time.Minute)}, CandidatePairStateSucceeded},
+		{&pairCheck{requestsSent: 1, responsesReceived: 1, failed: true}, CandidatePairStateFailed},
+	} {
+		if state := testCase.check.state(now, 30*time.Second); state != testCase.expected {
+			t.Errorf("testCase %d: expected %s, got %s", i, testCase.expected, state)
+		}
+	}
+
+	// Without timeout the checks never fail unanswered
+	check := &pairCheck{requestsSent: 5, firstRequest: now.Add(-time.Hour)}
+	if state := check.state(now, 0); state != CandidatePairStateInProgress {
+		t.Errorf("expected %s without timeout, got %s", CandidatePairStateInProgress, state)
+	}
+}
+
+func TestAgentChecklist(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	ca, cb := pipe()
+
+	checks, err := ca.agent.GetChecklist()
+	check(err)
+	if len(checks) == 0 {
+		t.Fatalf("The checklist is empty")
+	}
+
+	selected := 0
+	for i, c := range checks {
+		if i > 0 && c.Priority > checks[i-1].Priority {
+			t.Fatalf("The checklist should be sorted by decreasing priority: %v", checks)
+		}
+		if !c.Selected {
+			continue
+		}
+		selected++
+		if c.State != CandidatePairStateSucceeded || !c.Nominated || c.RequestsSent == 0 || c.ResponsesReceived == 0 {
+			t.Fatalf("The selected pair should have succeeded and been nominated: %+v", c)
+		}
+		if c.LastRequestSent.IsZero() || c.LastResponseReceived.IsZero() || c.RTT <= 0 {
+			t.Fatalf("The checks of the selected pair weren't timed: %+v", c)
+		}
+	}
+	if selected != 1 {
+		t.Fatalf("One pair of the checklist should be selected, %d are", selected)
+	}
+
+	check(ca.Close())
+	check(cb.Close())
+}
+
+func TestCandidatePairState_String(t *testing.T) {
+	for state, expected := range map[CandidatePairState]string{
+		CandidatePairStateWaiting:    "Waiting",
+		CandidatePairStateInProgress: "In Progress",
+		CandidatePairStateSucceeded:  "Succeeded",
+		CandidatePairStateFailed:     "Failed",
+		CandidatePairState(0):        "Invalid",
+	} {
+		if state.String() != expected {
+			t.Errorf("expected %q, got %q", expected, state.String())
+		}
+	}
+}
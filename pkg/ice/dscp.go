@@ -0,0 +1,24 @@
+package ice
+
+import (
+	"net"
+)
+
+// setDSCP marks the packets sent on conn with the given DSCP code point,
+// the code point is the upper six bits of the IPv4 TOS and IPv6 traffic
+// class octets
+func setDSCP(conn *net.UDPConn, dscp uint8) error {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	ipv6 := conn.LocalAddr().(*net.UDPAddr).IP.To4() == nil
+	var sockErr error
+	if err := rawConn.Control(func(fd uintptr) {
+		sockErr = setTrafficClass(fd, ipv6, int(dscp)<<2)
+	}); err != nil {
+		return err
+	}
+	return sockErr
+}
@@ -0,0 +1,42 @@
+package ice
+
+import (
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestSetDSCP(t *testing.T) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if err = setDSCP(conn, 46); err != nil {
+		t.Fatal(err)
+	}
+
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tos int
+	var sockErr error
+	if err = rawConn.Control(func(fd uintptr) {
+		tos, sockErr = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TOS)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if sockErr != nil {
+		t.Fatal(sockErr)
+	}
+	if tos != 46<<2 {
+		t.Fatalf("TOS is %#x instead of %#x", tos, 46<<2)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package ice
+
+// Windows ignores IP_TOS unless it is enabled in the registry and expects
+// the qWAVE API to be used instead, other platforms are not supported.
+func setTrafficClass(fd uintptr, ipv6 bool, tos int) error {
+	return ErrDSCPUnsupported
+}
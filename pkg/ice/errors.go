@@ -28,4 +28,51 @@ var (
 
 	// ErrClosed indicates the agent is closed
 	ErrClosed = errors.New("the agent is closed")
+
+	// ErrDSCPUnsupported indicates the DSCP code point can't be set on this platform
+	ErrDSCPUnsupported = errors.New("setting the DSCP is not supported on this platform")
+
+	// ErrPacketConnAddr indicates the local address of the PacketConn of the
+	// config is not an UDP address a host candidate can be made of
+	ErrPacketConnAddr = errors.New("the PacketConn has no usable UDP address")
+
+	// ErrPacketConnCandidateType indicates the CandidateTypes of the config
+	// exclude the host candidate of its PacketConn, the only one it has
+	ErrPacketConnCandidateType = errors.New("the host candidate of the PacketConn is excluded by the candidate types")
+
+	// ErrTimestampsUnsupported indicates the kernel can't timestamp the
+	// received packets on this platform
+	ErrTimestampsUnsupported = errors.New("receive timestamps are not supported on this platform")
+
+	// ErrGatherWithoutTrickle indicates GatherCandidates was called on an
+	// agent that gathered its candidates when it was created
+	ErrGatherWithoutTrickle = errors.New("the candidates are gathered by NewAgent unless Trickle is set")
+
+	// ErrMultipleGather indicates GatherCandidates was called more than once
+	// in a session
+	ErrMultipleGather = errors.New("the candidates are already gathered")
+
+	// ErrRestartWhileGathering indicates Restart was called while the
+	// candidates of the session are being gathered
+	ErrRestartWhileGathering = errors.New("the agent can't restart while gathering the candidates")
+
+	// ErrInvalidNAT1To1IPMapping indicates the NAT 1:1 IPs of the config
+	// aren't "external" or "external/local" IPs of the same family, or mix
+	// a sole external IP of a family with the mapped ones
+	ErrInvalidNAT1To1IPMapping = errors.New("invalid NAT 1:1 IP mapping")
+
+	// ErrNAT1To1IPCandidateType indicates the NAT 1:1 IPs of the config are
+	// advertised in candidates of a type other than host or srflx
+	ErrNAT1To1IPCandidateType = errors.New("the NAT 1:1 IPs are only advertised in host or srflx candidates")
+
+	// ErrMulticastDNSWithNAT1To1IPs indicates the config hides the
+	// host candidates behind mDNS names while advertising NAT 1:1 IPs in them
+	ErrMulticastDNSWithNAT1To1IPs = errors.New("the host candidates can't both be named and advertise NAT 1:1 IPs")
+
+	// ErrUDPMuxPacketConn indicates the config has both a PacketConn and a
+	// UDPMux, the agent uses only one conn
+	ErrUDPMuxPacketConn = errors.New("the agent can't use both a PacketConn and a UDPMux")
+
+	// ErrUDPMuxClosed indicates the UDPMux of the config is closed
+	ErrUDPMuxClosed = errors.New("the UDPMux is closed")
 )
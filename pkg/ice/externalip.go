@@ -0,0 +1,110 @@
+package ice
+
+import (
+	"net"
+	"strings"
+)
+
+// ipMapping maps the local IPs of a family to their external ones, or all
+// of them to the sole one
+type ipMapping struct {
+	ipSole net.IP
+	ipMap  map[string]net.IP
+}
+
+func (m *ipMapping) set(external, local net.IP) error {
+	if local == nil {
+		if m.ipSole != nil || len(m.ipMap) > 0 {
+			return ErrInvalidNAT1To1IPMapping
+		}
+		m.ipSole = external
+		return nil
+	}
+
+	if m.ipSole != nil {
+		return ErrInvalidNAT1To1IPMapping
+	}
+	if m.ipMap == nil {
+		m.ipMap = map[string]net.IP{}
+	}
+	if _, ok := m.ipMap[local.String()]; ok {
+		return ErrInvalidNAT1To1IPMapping
+	}
+	m.ipMap[local.String()] = external
+	return nil
+}
+
+func (m *ipMapping) find(local net.IP) (net.IP, bool) {
+	if m.ipSole != nil {
+		return m.ipSole, true
+	}
+	external, ok := m.ipMap[local.String()]
+	return external, ok
+}
+
+// externalIPMapper advertises the external IPs of a 1:1 NAT, such as the
+// public IP of a cloud instance, in the candidates of the local ones
+type externalIPMapper struct {
+	candidateType CandidateType
+	v4            ipMapping
+	v6            ipMapping
+}
+
+// newExternalIPMapper parses the "external" or "external/local" IPs of the
+// config, nil is returned when there are none. The candidate type defaults
+// to host.
+func newExternalIPMapper(candidateType CandidateType, ips []string) (*externalIPMapper, error) {
+	if len(ips) == 0 {
+		return nil, nil
+	}
+	switch candidateType {
+	case 0:
+		candidateType = CandidateTypeHost
+	case CandidateTypeHost, CandidateTypeServerReflexive:
+	default:
+		return nil, ErrNAT1To1IPCandidateType
+	}
+
+	m := &externalIPMapper{candidateType: candidateType}
+	for _, extIPStr := range ips {
+		external, local, err := parseNAT1To1IP(extIPStr)
+		if err != nil {
+			return nil, err
+		}
+		mapping := &m.v6
+		if external.To4() != nil {
+			mapping = &m.v4
+		}
+		if err := mapping.set(external, local); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// parseNAT1To1IP parses an "external" or "external/local" IP, both of the
+// same family
+func parseNAT1To1IP(s string) (external, local net.IP, err error) {
+	parts := strings.Split(s, "/")
+	if len(parts) > 2 {
+		return nil, nil, ErrInvalidNAT1To1IPMapping
+	}
+	if external = net.ParseIP(parts[0]); external == nil {
+		return nil, nil, ErrInvalidNAT1To1IPMapping
+	}
+	if len(parts) == 1 {
+		return external, nil, nil
+	}
+	if local = net.ParseIP(parts[1]); local == nil || (local.To4() == nil) != (external.To4() == nil) {
+		return nil, nil, ErrInvalidNAT1To1IPMapping
+	}
+	return external, local, nil
+}
+
+// findExternalIP returns the external IP advertised for a local one
+func (m *externalIPMapper) findExternalIP(local net.IP) (net.IP, bool) {
+	if local.To4() != nil {
+		return m.v4.find(local)
+	}
+	return m.v6.find(local)
+}
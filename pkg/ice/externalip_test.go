@@ -0,0 +1,56 @@
+package ice
+
+import (
+	"net"
+	"testing"
+)
+
+func TestExternalIPMapper(t *testing.T) {
+	m, err := newExternalIPMapper(0, nil)
+	if err != nil || m != nil {
+		t.Fatalf("Expected no mapper without IPs, got %v %v", m, err)
+	}
+
+	m, err = newExternalIPMapper(0, []string{"203.0.113.1", "2001:db8::1/fd00::2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.candidateType != CandidateTypeHost {
+		t.Fatalf("Expected the host candidates to be mapped, got %s", m.candidateType)
+	}
+	for local, expected := range map[string]string{
+		"192.0.2.2":  "203.0.113.1",
+		"10.0.0.1":   "203.0.113.1",
+		"fd00::2":    "2001:db8::1",
+		"fe80::1234": "",
+	} {
+		external, ok := m.findExternalIP(net.ParseIP(local))
+		if expected == "" {
+			if ok {
+				t.Fatalf("Expected no external IP for %s, got %s", local, external)
+			}
+			continue
+		}
+		if !ok || !external.Equal(net.ParseIP(expected)) {
+			t.Fatalf("Expected %s for %s, got %s", expected, local, external)
+		}
+	}
+
+	for _, ips := range [][]string{
+		{"not an IP"},
+		{"203.0.113.1/"},
+		{"203.0.113.1/192.0.2.2/10.0.0.1"},
+		{"203.0.113.1/fd00::2"},
+		{"203.0.113.1", "203.0.113.2"},
+		{"203.0.113.1", "203.0.113.2/192.0.2.2"},
+		{"203.0.113.1/192.0.2.2", "203.0.113.2/192.0.2.2"},
+	} {
+		if _, err = newExternalIPMapper(0, ips); err != ErrInvalidNAT1To1IPMapping {
+			t.Fatalf("Expected ErrInvalidNAT1To1IPMapping for %v, got %v", ips, err)
+		}
+	}
+
+	if _, err = newExternalIPMapper(CandidateTypeRelay, []string{"203.0.113.1"}); err != ErrNAT1To1IPCandidateType {
+		t.Fatalf("Expected ErrNAT1To1IPCandidateType, got %v", err)
+	}
+}
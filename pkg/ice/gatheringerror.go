@@ -0,0 +1,64 @@
+package ice
+
+import (
+	"fmt"
+
+	"github.com/pions/stun"
+	"github.com/pkg/errors"
+)
+
+// ErrorCodeServerUnreachable is the error code of a GatheringError when the
+// server could not be reached or sent no response, as defined in
+// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnectioniceerrorevent-errorcode
+const ErrorCodeServerUnreachable = 701
+
+// GatheringError describes the failure to gather candidates from a STUN or
+// TURN server
+type GatheringError struct {
+	URL         *URL
+	NetworkType NetworkType
+
+	// ErrorCode is the STUN error code the server responded with, such as
+	// 401 or 437, or ErrorCodeServerUnreachable. It is 0 when the request
+	// wasn't sent, such as for the schemes that aren't implemented.
+	ErrorCode int
+	Err       error
+}
+
+func (e *GatheringError) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.NetworkType, e.URL, e.Err)
+}
+
+// stunErrorResponse is the error of a request a STUN server answered with
+// an error response
+type stunErrorResponse struct {
+	code   int
+	reason string
+}
+
+func (e *stunErrorResponse) Error() string {
+	return fmt.Sprintf("STUN error response %d: %s", e.code, e.reason)
+}
+
+// newSTUNErrorResponse parses the ERROR-CODE attribute of an error response
+// https://tools.ietf.org/html/rfc5389#section-15.6
+func newSTUNErrorResponse(m *stun.Message) *stunErrorResponse {
+	attr, ok := m.GetOneAttribute(stun.AttrErrorCode)
+	if !ok || len(attr.Value) < 4 {
+		return &stunErrorResponse{reason: "no error code"}
+	}
+	return &stunErrorResponse{
+		code:   int(attr.Value[2]&0x07)*100 + int(attr.Value[3]),
+		reason: string(attr.Value[4:]),
+	}
+}
+
+// newGatheringError returns the GatheringError of a failed request to a
+// server, the error code is the one the server responded with if it did
+func newGatheringError(url *URL, networkType NetworkType, err error) *GatheringError {
+	code := ErrorCodeServerUnreachable
+	if response, ok := errors.Cause(err).(*stunErrorResponse); ok {
+		code = response.code
+	}
+	return &GatheringError{URL: url, NetworkType: networkType, ErrorCode: code, Err: err}
+}
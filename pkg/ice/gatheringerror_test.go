@@ -0,0 +1,72 @@
+package ice
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pions/stun"
+	"github.com/pions/transport/test"
+)
+
+func TestAgentGatheringErrors(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	// A STUN server answering its requests with 401
+	server, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		buf := make([]byte, 1500)
+		n, addr, readErr := server.ReadFrom(buf)
+		if readErr != nil {
+			return
+		}
+		request, readErr := stun.NewMessage(buf[:n])
+		if readErr != nil {
+			t.Error(readErr)
+			return
+		}
+		response, readErr := stun.Build(stun.ClassErrorResponse, stun.MethodBinding, request.TransactionID, &stun.Err401Unauthorized)
+		if readErr != nil {
+			t.Error(readErr)
+			return
+		}
+		if _, readErr = server.WriteTo(response.Pack(), addr); readErr != nil {
+			t.Error(readErr)
+		}
+	}()
+
+	stunURL := &URL{Scheme: SchemeTypeSTUN, Host: "127.0.0.1", Port: server.LocalAddr().(*net.UDPAddr).Port}
+	turnURL := &URL{Scheme: SchemeTypeTURN, Host: "127.0.0.1", Port: 3478}
+	a, err := NewAgent(&AgentConfig{
+		Urls:         []*URL{stunURL, turnURL},
+		NetworkTypes: []NetworkType{NetworkTypeUDP4},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gatheringErrors, err := a.GetGatheringErrors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gatheringErrors) != 2 {
+		t.Fatalf("Expected 2 gathering errors, got %v", gatheringErrors)
+	}
+	if e := gatheringErrors[0]; e.URL != stunURL || e.NetworkType != NetworkTypeUDP4 || e.ErrorCode != 401 {
+		t.Fatalf("Unexpected STUN gathering error %+v", e)
+	}
+	if e := gatheringErrors[1]; e.URL != turnURL || e.ErrorCode != 0 {
+		t.Fatalf("Unexpected TURN gathering error %+v", e)
+	}
+
+	if err = a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = server.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
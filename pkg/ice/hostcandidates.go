@@ -0,0 +1,67 @@
+package ice
+
+import "net"
+
+// HostCandidatePolicy decides which host candidates are kept first when their
+// number is limited by AgentConfig.MaxHostCandidates
+type HostCandidatePolicy int
+
+const (
+	// HostCandidatePolicyInterfaceOrder keeps the addresses in the order the
+	// operating system lists the interfaces in
+	HostCandidatePolicyInterfaceOrder HostCandidatePolicy = iota
+
+	// HostCandidatePolicyDefaultRouteFirst keeps the addresses of the default
+	// routes first, then the others in the order of the interfaces
+	HostCandidatePolicyDefaultRouteFirst
+)
+
+// defaultRouteDestinations are the addresses the default route of each
+// family is looked up with. No packet is sent: connecting a UDP socket only
+// selects its source address.
+var defaultRouteDestinations = []string{
+	"8.8.8.8:53",
+	"[2001:4860:4860::8888]:53",
+}
+
+// defaultRouteIPs returns the local addresses of the default routes
+func defaultRouteIPs() []net.IP {
+	var ips []net.IP
+	for _, destination := range defaultRouteDestinations {
+		conn, err := net.Dial(udp, destination)
+		if err != nil {
+			continue
+		}
+		ips = append(ips, conn.LocalAddr().(*net.UDPAddr).IP)
+		if err := conn.Close(); err != nil {
+			iceLog.Warnf("Failed to close default route lookup: %v", err)
+		}
+	}
+	return ips
+}
+
+// orderHostIPs moves the preferred addresses first, keeping the order of
+// the others
+func orderHostIPs(ips, preferred []net.IP) []net.IP {
+	ordered := make([]net.IP, 0, len(ips))
+	for _, ip := range ips {
+		if containsIP(preferred, ip) {
+			ordered = append(ordered, ip)
+		}
+	}
+	for _, ip := range ips {
+		if !containsIP(preferred, ip) {
+			ordered = append(ordered, ip)
+		}
+	}
+	return ordered
+}
+
+func containsIP(ips []net.IP, ip net.IP) bool {
+	for _, i := range ips {
+		if i.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
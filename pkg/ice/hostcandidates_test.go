@@ -0,0 +1,60 @@
+package ice
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderHostIPs(t *testing.T) {
+	ips := []net.IP{
+		net.ParseIP("10.0.0.1"),
+		net.ParseIP("192.168.1.2"),
+		net.ParseIP("fd00::1"),
+		net.ParseIP("172.16.0.3"),
+	}
+
+	assert.Equal(t, []net.IP{ips[1], ips[2], ips[0], ips[3]},
+		orderHostIPs(ips, []net.IP{net.ParseIP("192.168.1.2"), net.ParseIP("fd00::1")}))
+	assert.Equal(t, ips, orderHostIPs(ips, nil))
+}
+
+func TestAgentMaxHostCandidates(t *testing.T) {
+	countHostCandidates := func(a *Agent) int {
+		count := 0
+		for _, candidates := range a.localCandidates {
+			count += len(candidates)
+		}
+		return count
+	}
+
+	a, err := NewAgent(&AgentConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := countHostCandidates(a)
+	assert.NoError(t, a.Close())
+	if all == 0 {
+		t.Skip("No interface to gather host candidates on")
+	}
+
+	a, err = NewAgent(&AgentConfig{
+		MaxHostCandidates:   1,
+		HostCandidatePolicy: HostCandidatePolicyDefaultRouteFirst,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, countHostCandidates(a))
+
+	// The address of a default route is kept first
+	if defaultRoutes := defaultRouteIPs(); len(defaultRoutes) != 0 {
+		for _, candidates := range a.localCandidates {
+			for _, c := range candidates {
+				assert.True(t, containsIP(defaultRoutes, c.IP), "%s is not a default route address", c.IP)
+			}
+		}
+	}
+	assert.NoError(t, a.Close())
+}
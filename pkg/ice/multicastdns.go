@@ -0,0 +1,153 @@
+package ice
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pions/webrtc/internal/mdns"
+)
+
+// MulticastDNSMode decides how the agent uses multicast DNS, the .local
+// names host candidates hide their addresses behind
+type MulticastDNSMode int
+
+const (
+	// MulticastDNSModeQueryOnly resolves the names of the remote host
+	// candidates, the local ones keep their addresses
+	MulticastDNSModeQueryOnly MulticastDNSMode = iota
+
+	// MulticastDNSModeQueryAndGather also hides the IPv4 addresses of the
+	// local host candidates behind generated names, and answers the queries
+	// for them. The IPv6 host candidates aren't gathered.
+	MulticastDNSModeQueryAndGather
+
+	// MulticastDNSModeDisabled ignores the remote host candidates with a
+	// name
+	MulticastDNSModeDisabled
+)
+
+const (
+	multicastDNSSuffix = ".local"
+
+	// multicastDNSResolveTimeout is how long the name of a remote candidate
+	// is queried for
+	multicastDNSResolveTimeout = 10 * time.Second
+)
+
+// listenMulticastDNS joins the mDNS group, it is replaced by the tests
+var listenMulticastDNS = mdns.Listen
+
+// IsMulticastDNSName returns whether the address of a candidate is a
+// multicast DNS name
+func IsMulticastDNSName(address string) bool {
+	return strings.HasSuffix(strings.ToLower(strings.TrimSuffix(address, ".")), multicastDNSSuffix)
+}
+
+// generateMulticastDNSName returns a random UUID.local name, as the ones of
+// the browsers
+func generateMulticastDNSName(random io.Reader) (string, error) {
+	u := make([]byte, 16)
+	if _, err := io.ReadFull(random, u); err != nil {
+		return "", err
+	}
+	// A version 4 UUID of the variant of RFC 4122
+	u[6] = u[6]&0x0F | 0x40
+	u[8] = u[8]&0x3F | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x%s", u[0:4], u[4:6], u[6:8], u[8:10], u[10:], multicastDNSSuffix), nil
+}
+
+// multicastDNSConn returns the mDNS conn of the agent, joining the group
+// the first time
+func (a *Agent) multicastDNSConn() (*mdns.Conn, error) {
+	a.mDNSLock.Lock()
+	defer a.mDNSLock.Unlock()
+	if a.mDNSClosed {
+		return nil, ErrClosed
+	}
+
+	if a.mDNSConn == nil {
+		conn, err := listenMulticastDNS(a.log)
+		if err != nil {
+			return nil, err
+		}
+		a.mDNSConn = conn
+	}
+	return a.mDNSConn, nil
+}
+
+// multicastDNSName generates the name of a local host candidate, the
+// queries for it are answered with ip
+func (a *Agent) multicastDNSName(ip net.IP) (string, error) {
+	conn, err := a.multicastDNSConn()
+	if err != nil {
+		return "", err
+	}
+	name, err := generateMulticastDNSName(a.random)
+	if err != nil {
+		return "", err
+	}
+	conn.AddName(name, ip)
+	return name, nil
+}
+
+// closeMulticastDNS closes the mDNS conn, ending the pending resolutions
+func (a *Agent) closeMulticastDNS() error {
+	a.mDNSLock.Lock()
+	defer a.mDNSLock.Unlock()
+	a.mDNSClosed = true
+
+	if a.mDNSConn == nil {
+		return nil
+	}
+	return a.mDNSConn.Close()
+}
+
+// resolveRemoteCandidate adds a remote candidate once the name it hides its
+// address behind is resolved. The candidate is dropped if it can't be.
+func (a *Agent) resolveRemoteCandidate(c *Candidate) error {
+	if err := a.ok(); err != nil {
+		return err
+	}
+	if a.mDNSMode == MulticastDNSModeDisabled {
+		a.log.Infof("Ignoring remote candidate %s, multicast DNS is disabled", c.Hostname)
+		return nil
+	}
+
+	conn, err := a.multicastDNSConn()
+	if err != nil {
+		a.log.Warnf("Failed to resolve remote candidate %s: %v", c.Hostname, err)
+		return nil
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), multicastDNSResolveTimeout)
+		defer cancel()
+
+		ip, err := conn.Query(ctx, c.Hostname)
+		if err != nil {
+			if a.ok() == nil {
+				a.log.Warnf("Failed to resolve remote candidate %s: %v", c.Hostname, err)
+			}
+			return
+		}
+
+		networkType, err := determineNetworkType(c.NetworkType.NetworkShort(), ip)
+		if err != nil {
+			a.log.Warnf("Failed to resolve remote candidate %s: %v", c.Hostname, err)
+			return
+		}
+		c.IP = ip
+		c.NetworkType = networkType
+
+		if err := a.run(func(agent *Agent) {
+			agent.addRemoteCandidate(c)
+		}); err != nil {
+			a.log.Debugf("Dropping resolved candidate %s: %v", c.Hostname, err)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,149 @@
+package ice
+
+import (
+	"context"
+	"crypto/rand"
+	"net"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/pions/transport/test"
+	"github.com/pions/webrtc/internal/mdns"
+	"github.com/pions/webrtc/pkg/logging"
+)
+
+func TestIsMulticastDNSName(t *testing.T) {
+	for address, expected := range map[string]bool{
+		"1f4712db-ea17-4bcf-a596-105139dfd8bf.local":  true,
+		"1F4712DB-EA17-4BCF-A596-105139DFD8BF.LOCAL.": true,
+		"192.168.1.2": false,
+		"example.com": false,
+	} {
+		if IsMulticastDNSName(address) != expected {
+			t.Fatalf("IsMulticastDNSName(%q) should be %v", address, expected)
+		}
+	}
+}
+
+func TestGenerateMulticastDNSName(t *testing.T) {
+	name, err := generateMulticastDNSName(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}\.local$`).MatchString(name) {
+		t.Fatalf("Unexpected name %s", name)
+	}
+}
+
+// linkMulticastDNS makes the agents created next join a link of their own,
+// the first two to join it exchange their messages
+func linkMulticastDNS(t *testing.T) func() {
+	a, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conns := []*net.UDPConn{a, b}
+	dsts := []net.Addr{b.LocalAddr(), a.LocalAddr()}
+
+	listenMulticastDNS = func(log logging.LeveledLogger) (*mdns.Conn, error) {
+		if len(conns) == 0 {
+			t.Fatal("Only two agents join the link")
+		}
+		conn := mdns.Server(conns[0], dsts[0], log)
+		conns, dsts = conns[1:], dsts[1:]
+		return conn, nil
+	}
+	return func() {
+		listenMulticastDNS = mdns.Listen
+		for _, conn := range conns {
+			if err := conn.Close(); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+}
+
+func TestAgentMulticastDNS(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	unlink := linkMulticastDNS(t)
+	defer unlink()
+
+	aNotifier, aConnected := onConnected()
+	aAgent, err := NewAgent(&AgentConfig{MulticastDNSMode: MulticastDNSModeQueryAndGather})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(aAgent.OnConnectionStateChange(aNotifier))
+	bNotifier, bConnected := onConnected()
+	bAgent, err := NewAgent(&AgentConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(bAgent.OnConnectionStateChange(bNotifier))
+
+	// Only the names of the host candidates are signaled, the checks of the
+	// agent resolving them are the only way in
+	candidates, err := aAgent.GetLocalCandidates()
+	check(err)
+	if len(candidates) == 0 {
+		t.Fatal("No host candidate was gathered")
+	}
+	for _, c := range candidates {
+		if c.Type != CandidateTypeHost || c.IP.To4() == nil || !IsMulticastDNSName(c.Hostname) {
+			t.Fatalf("Unexpected candidate %s %s", c, c.Hostname)
+		}
+		remote, err := NewCandidateHostName(c.NetworkType.NetworkShort(), c.Hostname, c.Port, c.Component)
+		check(err)
+		check(bAgent.AddRemoteCandidate(remote))
+	}
+
+	aUfrag, aPwd := aAgent.GetLocalUserCredentials()
+	bUfrag, bPwd := bAgent.GetLocalUserCredentials()
+	accepted := make(chan error)
+	go func() {
+		_, acceptErr := aAgent.Accept(context.TODO(), bUfrag, bPwd)
+		accepted <- acceptErr
+	}()
+	if _, err = bAgent.Dial(context.TODO(), aUfrag, aPwd); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-accepted; err != nil {
+		t.Fatal(err)
+	}
+	<-aConnected
+	<-bConnected
+
+	check(aAgent.Close())
+	check(bAgent.Close())
+}
+
+func TestAgentMulticastDNSDisabled(t *testing.T) {
+	a, err := NewAgent(&AgentConfig{MulticastDNSMode: MulticastDNSModeDisabled})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The named candidates are ignored without joining the group
+	remote, err := NewCandidateHostName(udp, "1f4712db-ea17-4bcf-a596-105139dfd8bf.local", 5000, ComponentRTP)
+	check(err)
+	check(a.AddRemoteCandidate(remote))
+	if a.mDNSConn != nil {
+		t.Fatal("The agent shouldn't join the mDNS group")
+	}
+
+	if _, err = NewCandidateHostName(udp, "example.com", 5000, ComponentRTP); err == nil {
+		t.Fatal("Only multicast DNS names should be accepted")
+	}
+	check(a.Close())
+}
@@ -78,6 +78,11 @@ func (t NetworkType) IsReliable() bool {
 	return false
 }
 
+// IsIPv6 returns true if the network is over IPv6
+func (t NetworkType) IsIPv6() bool {
+	return t == NetworkTypeUDP6 || t == NetworkTypeTCP6
+}
+
 // determineNetworkType determines the type of network based on
 // the short network string and an IP address.
 func determineNetworkType(network string, ip net.IP) (NetworkType, error) {
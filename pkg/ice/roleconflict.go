@@ -0,0 +1,153 @@
+package ice
+
+import (
+	"bytes"
+	"sync/atomic"
+	"time"
+
+	"github.com/pions/stun"
+)
+
+const (
+	// maxBindingRequests is the number of binding requests remembered to
+	// handle a 487 Role Conflict response and measure the round-trip time
+	maxBindingRequests = 64
+
+	roleConflictClass  = 4
+	roleConflictNumber = 87
+)
+
+// bindingRequest is a binding request sent by the Agent, with the role it
+// was sent with and when
+type bindingRequest struct {
+	transactionID []byte
+	isControlling bool
+	timestamp     time.Time
+}
+
+// setControlling sets the role of the Agent, it is mirrored for
+// GetTransportStats
+func (a *Agent) setControlling(isControlling bool) {
+	a.isControlling = isControlling
+
+	var controlling uint32
+	if isControlling {
+		controlling = 1
+	}
+	atomic.StoreUint32(&a.controlling, controlling)
+}
+
+// recordBindingRequest remembers the role a binding request was sent with,
+// and when
+func (a *Agent) recordBindingRequest(m *stun.Message) {
+	a.bindingRequests = append(a.bindingRequests, bindingRequest{
+		transactionID: m.TransactionID,
+		isControlling: a.isControlling,
+		timestamp:     time.Now(),
+	})
+	if len(a.bindingRequests) > maxBindingRequests {
+		a.bindingRequests = a.bindingRequests[len(a.bindingRequests)-maxBindingRequests:]
+	}
+}
+
+// popBindingRequest returns the binding request a response is for
+func (a *Agent) popBindingRequest(transactionID []byte) (bindingRequest, bool) {
+	for i, r := range a.bindingRequests {
+		if bytes.Equal(r.transactionID, transactionID) {
+			a.bindingRequests = append(a.bindingRequests[:i], a.bindingRequests[i+1:]...)
+			return r, true
+		}
+	}
+	return bindingRequest{}, false
+}
+
+// handleRoleConflict detects an inbound binding request sent with the role of
+// the Agent and resolves the conflict with the tie-breakers as defined in
+// https://tools.ietf.org/html/rfc8445#section-7.3.1.1
+// The Agent with the larger tie-breaker keeps controlling, the request is
+// answered with a 487 Role Conflict error if its sender has to switch. It
+// returns false if the request must not be processed.
+func (a *Agent) handleRoleConflict(m *stun.Message, local, remote *Candidate) bool {
+	var remoteTieBreaker uint64
+	if a.isControlling {
+		raw, ok := m.GetOneAttribute(stun.AttrIceControlling)
+		if !ok {
+			return true
+		}
+		attr := stun.IceControlling{}
+		if err := attr.Unpack(m, raw); err != nil {
+			a.log.Warnf("Failed to unpack ICE-CONTROLLING from %s: %v", remote, err)
+			return false
+		}
+		remoteTieBreaker = attr.TieBreaker
+	} else {
+		raw, ok := m.GetOneAttribute(stun.AttrIceControlled)
+		if !ok {
+			return true
+		}
+		attr := stun.IceControlled{}
+		if err := attr.Unpack(m, raw); err != nil {
+			a.log.Warnf("Failed to unpack ICE-CONTROLLED from %s: %v", remote, err)
+			return false
+		}
+		remoteTieBreaker = attr.TieBreaker
+	}
+
+	if a.isControlling == (a.tieBreaker >= remoteTieBreaker) {
+		a.log.Debugf("Role conflict with %s, the remote has to switch", remote)
+		a.sendRoleConflict(m, local, remote)
+		return false
+	}
+
+	a.log.Debugf("Role conflict with %s, switching to controlling? %t", remote, !a.isControlling)
+	a.setControlling(!a.isControlling)
+	return true
+}
+
+func (a *Agent) sendRoleConflict(m *stun.Message, local, remote *Candidate) {
+	out, err := stun.Build(stun.ClassErrorResponse, stun.MethodBinding, m.TransactionID,
+		&stun.ErrorCode{
+			ErrorClass:  roleConflictClass,
+			ErrorNumber: roleConflictNumber,
+			Reason:      []byte("Role Conflict"),
+		},
+		&stun.MessageIntegrity{
+			Key: a.responseKey(m),
+		},
+		&stun.Fingerprint{},
+	)
+	if err != nil {
+		a.log.Warnf("Failed to build role conflict response from: %s to: %s error: %s", local, remote, err)
+		return
+	}
+	a.sendSTUN(out, local, remote)
+}
+
+// handleErrorResponse switches the role of the Agent and retries the check
+// when a binding request is answered with a 487 Role Conflict error, as
+// defined in https://tools.ietf.org/html/rfc8445#section-7.2.5.1
+func (a *Agent) handleErrorResponse(m *stun.Message, local, remote *Candidate) {
+	request, ok := a.popBindingRequest(m.TransactionID)
+	if !ok {
+		return
+	}
+
+	raw, ok := m.GetOneAttribute(stun.AttrErrorCode)
+	if !ok || len(raw.Value) < 4 {
+		a.log.Debugf("Binding error response without error code from %s", remote)
+		return
+	}
+	class, number := int(raw.Value[2]&0x07), int(raw.Value[3])
+	if class != roleConflictClass || number != roleConflictNumber {
+		a.log.Debugf("Binding error response %d%02d from %s", class, number, remote)
+		a.checkAnswered(local, remote, false)
+		return
+	}
+
+	// The role may have been switched since by an earlier response
+	if request.isControlling == a.isControlling {
+		a.log.Debugf("Role conflict reported by %s, switching to controlling? %t", remote, !a.isControlling)
+		a.setControlling(!a.isControlling)
+	}
+	a.pingCandidate(local, remote)
+}
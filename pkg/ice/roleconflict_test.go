@@ -0,0 +1,70 @@
+package ice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pions/transport/test"
+)
+
+func TestAgentRoleConflict(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	for _, controlling := range []bool{true, false} {
+		aNotifier, aConnected := onConnected()
+		bNotifier, bConnected := onConnected()
+
+		aAgent, err := NewAgent(&AgentConfig{})
+		check(err)
+		check(aAgent.OnConnectionStateChange(aNotifier))
+		bAgent, err := NewAgent(&AgentConfig{})
+		check(err)
+		check(bAgent.OnConnectionStateChange(bNotifier))
+
+		aUfrag, aPwd := aAgent.GetLocalUserCredentials()
+		bUfrag, bPwd := bAgent.GetLocalUserCredentials()
+		candidates, err := aAgent.GetLocalCandidates()
+		check(err)
+		for _, c := range candidates {
+			check(bAgent.AddRemoteCandidate(copyCandidate(c)))
+		}
+		candidates, err = bAgent.GetLocalCandidates()
+		check(err)
+		for _, c := range candidates {
+			check(aAgent.AddRemoteCandidate(copyCandidate(c)))
+		}
+
+		// Both agents start with the same role
+		connected := make(chan *Conn)
+		for _, c := range []struct {
+			agent      *Agent
+			ufrag, pwd string
+		}{{aAgent, bUfrag, bPwd}, {bAgent, aUfrag, aPwd}} {
+			go func(agent *Agent, ufrag, pwd string) {
+				conn, connErr := agent.connect(context.TODO(), controlling, ufrag, pwd)
+				check(connErr)
+				connected <- conn
+			}(c.agent, c.ufrag, c.pwd)
+		}
+		<-connected
+		<-connected
+		<-aConnected
+		<-bConnected
+
+		aStats, bStats := aAgent.GetTransportStats(), bAgent.GetTransportStats()
+		if aStats.Controlling == bStats.Controlling {
+			t.Fatalf("The role conflict started as controlling? %t wasn't resolved", controlling)
+		}
+		if aStats.Controlling != (aAgent.tieBreaker > bAgent.tieBreaker) {
+			t.Fatalf("The agent with the larger tie-breaker should be controlling")
+		}
+
+		check(aAgent.Close())
+		check(bAgent.Close())
+	}
+}
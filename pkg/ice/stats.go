@@ -0,0 +1,39 @@
+package ice
+
+import "sync/atomic"
+
+// TransportStats holds the cumulative traffic on the sockets of the local
+// candidates, including STUN as well as everything sent over the Conn.
+// It also carries the current role of the Agent.
+type TransportStats struct {
+	BytesSent       uint64
+	BytesReceived   uint64
+	PacketsSent     uint64
+	PacketsReceived uint64
+
+	// Controlling is true while the Agent is the controlling one, it can
+	// differ from the role it was started with after a role conflict
+	Controlling bool
+}
+
+// GetTransportStats returns the traffic on the sockets of the Agent so far.
+// It is safe to call from any goroutine.
+func (a *Agent) GetTransportStats() TransportStats {
+	return TransportStats{
+		BytesSent:       atomic.LoadUint64(&a.bytesSent),
+		BytesReceived:   atomic.LoadUint64(&a.bytesReceived),
+		PacketsSent:     atomic.LoadUint64(&a.packetsSent),
+		PacketsReceived: atomic.LoadUint64(&a.packetsReceived),
+		Controlling:     atomic.LoadUint32(&a.controlling) == 1,
+	}
+}
+
+func (a *Agent) countSent(n int) {
+	atomic.AddUint64(&a.bytesSent, uint64(n))
+	atomic.AddUint64(&a.packetsSent, 1)
+}
+
+func (a *Agent) countReceived(n int) {
+	atomic.AddUint64(&a.bytesReceived, uint64(n))
+	atomic.AddUint64(&a.packetsReceived, 1)
+}
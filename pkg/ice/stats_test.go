@@ -0,0 +1,49 @@
+package ice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pions/transport/test"
+)
+
+func TestTransportStats(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	ca, cb := pipe()
+
+	// The connectivity checks are already counted
+	before := ca.agent.GetTransportStats()
+	if before.PacketsSent == 0 || before.PacketsReceived == 0 {
+		t.Fatalf("STUN traffic should be counted: %+v", before)
+	}
+
+	msg := []byte("hello")
+	if _, err := ca.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 10)
+	if _, err := cb.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	after := ca.agent.GetTransportStats()
+	if after.PacketsSent <= before.PacketsSent ||
+		after.BytesSent < before.BytesSent+uint64(len(msg)) {
+		t.Fatalf("Sent traffic should be counted: %+v %+v", before, after)
+	}
+	if received := cb.agent.GetTransportStats(); received.BytesReceived < uint64(len(msg)) {
+		t.Fatalf("Received traffic should be counted: %+v", received)
+	}
+
+	if err := ca.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cb.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,22 @@
+package ice
+
+import (
+	"net"
+)
+
+// enableTimestamps has the kernel record the time each packet received on
+// conn arrived, it is read with the packet by ReadMsgUDP
+func enableTimestamps(conn *net.UDPConn) error {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var sockErr error
+	if err := rawConn.Control(func(fd uintptr) {
+		sockErr = setTimestamping(fd)
+	}); err != nil {
+		return err
+	}
+	return sockErr
+}
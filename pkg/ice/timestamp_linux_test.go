@@ -0,0 +1,55 @@
+package ice
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestEnableTimestamps(t *testing.T) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if err = enableTimestamps(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now()
+	if _, err = conn.WriteTo([]byte("ping"), conn.LocalAddr()); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, receiveMTU)
+	oob := make([]byte, timestampOOBSize)
+	n, oobn, _, _, err := conn.ReadMsgUDP(buf, oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != "ping" {
+		t.Fatalf("read %q instead of ping", buf[:n])
+	}
+
+	timestamp, ok := parseTimestamp(oob[:oobn])
+	if !ok {
+		t.Fatal("the packet has no receive timestamp")
+	}
+	// The timestamp has a microsecond resolution
+	if timestamp.Before(before.Truncate(time.Microsecond)) || timestamp.After(time.Now()) {
+		t.Fatalf("receive timestamp %v is not between %v and now", timestamp, before)
+	}
+
+	if allocs := testing.AllocsPerRun(100, func() { parseTimestamp(oob[:oobn]) }); allocs != 0 {
+		t.Fatalf("parsing the timestamp allocated %v times", allocs)
+	}
+
+	if _, ok = parseTimestamp(nil); ok {
+		t.Fatal("a timestamp was parsed out of no control message")
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package ice
+
+import (
+	"time"
+)
+
+// The receive time of the packets is the time they are read on the other
+// platforms
+const timestampOOBSize = 0
+
+func setTimestamping(fd uintptr) error {
+	return ErrTimestampsUnsupported
+}
+
+func parseTimestamp(oob []byte) (time.Time, bool) {
+	return time.Time{}, false
+}
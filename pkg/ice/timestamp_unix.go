@@ -0,0 +1,48 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package ice
+
+import (
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// timestampOOBSize is the size of the control message carrying the
+// SO_TIMESTAMP timestamp of a packet
+var timestampOOBSize = syscall.CmsgSpace(int(unsafe.Sizeof(syscall.Timeval{})))
+
+func setTimestamping(fd uintptr) error {
+	return syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_TIMESTAMP, 1)
+}
+
+// cmsgAlignment is the alignment of the control messages and their data
+var cmsgAlignment = syscall.CmsgSpace(1) - syscall.CmsgLen(0)
+
+// parseTimestamp returns the SO_TIMESTAMP timestamp of the control messages
+// read with a packet. They are walked in place, unlike
+// syscall.ParseSocketControlMessage it doesn't allocate for each packet.
+func parseTimestamp(oob []byte) (time.Time, bool) {
+	for len(oob) >= syscall.SizeofCmsghdr {
+		header := (*syscall.Cmsghdr)(unsafe.Pointer(&oob[0])) // #nosec
+		length := int(header.Len)
+		if length < syscall.CmsgLen(0) || length > len(oob) {
+			return time.Time{}, false
+		}
+
+		data := oob[syscall.CmsgLen(0):length]
+		if header.Level == syscall.SOL_SOCKET && header.Type == syscall.SCM_TIMESTAMP &&
+			len(data) >= int(unsafe.Sizeof(syscall.Timeval{})) {
+			tv := (*syscall.Timeval)(unsafe.Pointer(&data[0])) // #nosec
+			return time.Unix(tv.Unix()), true
+		}
+
+		next := (length + cmsgAlignment - 1) &^ (cmsgAlignment - 1)
+		if next >= len(oob) {
+			break
+		}
+		oob = oob[next:]
+	}
+	return time.Time{}, false
+}
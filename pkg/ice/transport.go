@@ -3,6 +3,7 @@ package ice
 import (
 	"context"
 	"errors"
+	"io"
 	"net"
 	"time"
 
@@ -45,6 +46,8 @@ func (a *Agent) connect(ctx context.Context, isControlling bool, remoteUfrag, re
 	case <-ctx.Done():
 		// TODO: Stop connectivity checks?
 		return nil, errors.New("connecting canceled by caller")
+	case <-a.done:
+		return nil, a.getErr()
 	case <-a.onConnected:
 	}
 
@@ -54,21 +57,40 @@ func (a *Agent) connect(ctx context.Context, isControlling bool, remoteUfrag, re
 
 }
 
-// Read implements the Conn Read method.
+// Read implements the Conn Read method. A packet larger than p is never
+// truncated, io.ErrShortBuffer is returned and the packet is kept for the
+// next Read.
 func (c *Conn) Read(p []byte) (int, error) {
+	n, _, err := c.ReadWithTimestamp(p)
+	return n, err
+}
+
+// ReadWithTimestamp is Read returning the receive time of the packet as well,
+// the timestamp of the kernel where the socket supports SO_TIMESTAMP and the
+// time the candidate read the packet otherwise.
+func (c *Conn) ReadWithTimestamp(p []byte) (int, time.Time, error) {
 	err := c.agent.ok()
 	if err != nil {
-		return 0, err
+		return 0, time.Time{}, err
 	}
 
-	resN := make(chan int)
+	// The bufIns are reused, a read doesn't allocate
+	in := bufInPool.Get().(*bufIn)
+	in.buf = p
+	defer func() {
+		in.buf = nil
+		bufInPool.Put(in)
+	}()
 
 	select {
-	case c.agent.rcvCh <- &bufIn{p, resN}:
-		n := <-resN
-		return n, nil
+	case c.agent.rcvCh <- in:
+		n := <-in.size
+		if n > len(p) {
+			return 0, time.Time{}, io.ErrShortBuffer
+		}
+		return n, in.timestamp, nil
 	case <-c.agent.done:
-		return 0, c.agent.getErr()
+		return 0, time.Time{}, c.agent.getErr()
 	}
 }
 
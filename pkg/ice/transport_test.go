@@ -1,7 +1,9 @@
 package ice
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"testing"
 	"time"
 
@@ -99,6 +101,39 @@ func TestReadClosed(t *testing.T) {
 
 }
 
+func TestReadShortBuffer(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	ca, cb := pipe()
+	defer func() {
+		if err := ca.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := cb.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	packet := bytes.Repeat([]byte{0x01}, 100)
+	if _, err := cb.Write(packet); err != nil {
+		t.Fatal(err)
+	}
+
+	// The packet isn't truncated, it is kept for a large enough buffer
+	if _, err := ca.Read(make([]byte, 10)); err != io.ErrShortBuffer {
+		t.Fatalf("Read with a short buffer should fail with io.ErrShortBuffer, got %v", err)
+	}
+	buf := make([]byte, 200)
+	n, err := ca.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packet, buf[:n]) {
+		t.Fatalf("The packet read after a short buffer should be whole, got %d bytes", n)
+	}
+}
+
 func stressDuplex(t *testing.T) {
 	ca, cb := pipe()
 
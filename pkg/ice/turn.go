@@ -0,0 +1,632 @@
+package ice
+
+import (
+	"crypto/md5" // #nosec
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pions/stun"
+	"github.com/pions/webrtc/pkg/logging"
+	"github.com/pkg/errors"
+)
+
+const (
+	// turnRequestTimeout is how long a request to a TURN server is
+	// retransmitted before the server is deemed unreachable
+	turnRequestTimeout = 5 * time.Second
+
+	// turnRetransmitInterval is the interval the requests are retransmitted
+	// at until a response arrives
+	turnRetransmitInterval = 500 * time.Millisecond
+
+	// turnLifetime is the lifetime requested for the allocations, the
+	// server may grant a shorter one
+	turnLifetime = 10 * time.Minute
+
+	// turnPermissionRefresh is the interval the permissions and the channel
+	// bindings are refreshed at, they expire after 5 and 10 minutes
+	turnPermissionRefresh = 4 * time.Minute
+
+	// The channel numbers a client may bind
+	// https://tools.ietf.org/html/rfc5766#section-11
+	turnChannelMin = 0x4000
+	turnChannelMax = 0x7FFF
+
+	turnChannelDataHeaderLength = 4
+)
+
+// relayConn is the conn of a relay candidate: the packets are relayed to the
+// peers by a TURN server, through an allocation made over UDP, TCP or TLS
+// https://tools.ietf.org/html/rfc5766
+// https://tools.ietf.org/html/rfc6062
+//
+// A permission for the IP of a peer is created the first time a packet is
+// written to it, then a channel is bound to it. The packets written until
+// the channel is bound are sent in Send indications. The allocation, the
+// permissions and the channels are refreshed until the conn is closed.
+type relayConn struct {
+	conn   net.PacketConn
+	server *net.UDPAddr
+	log    logging.LeveledLogger
+
+	// reliable is set for the allocations made over a stream, whose
+	// requests aren't retransmitted
+	reliable bool
+
+	username string
+	password string
+
+	// relayedAddr is the address the server relays from, mappedAddr the
+	// address it saw the allocation come from
+	relayedAddr *net.UDPAddr
+	mappedAddr  *net.UDPAddr
+
+	lock     sync.Mutex
+	realm    string
+	nonce    string
+	key      []byte
+	lifetime time.Duration
+
+	// transactions are the responses awaited, by transaction ID
+	transactions map[string]chan *stun.Message
+
+	// permissions are the peer IPs the server relays from, channels the
+	// channels bound to the peer addresses and peers their reverse. The
+	// peer addresses in binding are being given a permission and a channel.
+	permissions map[string]bool
+	channels    map[string]uint16
+	peers       map[uint16]*net.UDPAddr
+	binding     map[string]bool
+	nextChannel uint16
+	isClosed    bool
+
+	packets   chan relayedPacket
+	closed    chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// relayedPacket is a packet a peer sent through the server
+type relayedPacket struct {
+	data []byte
+	from *net.UDPAddr
+}
+
+// allocateRelay creates an allocation on the TURN server of the url, with
+// the long-term credentials of the url
+func allocateRelay(network string, url *URL, conn *net.UDPConn, log logging.LeveledLogger) (*relayConn, error) {
+	server, err := net.ResolveUDPAddr(network, net.JoinHostPort(url.Host, fmt.Sprint(url.Port)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to resolve TURN server")
+	}
+
+	return newRelayConn(conn, server, false, url, log)
+}
+
+// newRelayConn creates an allocation on the server over conn, which is
+// closed when the allocation fails
+func newRelayConn(conn net.PacketConn, server *net.UDPAddr, reliable bool, url *URL, log logging.LeveledLogger) (*relayConn, error) {
+	r := &relayConn{
+		conn:     conn,
+		server:   server,
+		log:      log,
+		reliable: reliable,
+		username: url.Username,
+		password: url.Password,
+
+		transactions: make(map[string]chan *stun.Message),
+		permissions:  make(map[string]bool),
+		channels:     make(map[string]uint16),
+		peers:        make(map[uint16]*net.UDPAddr),
+		binding:      make(map[string]bool),
+		nextChannel:  turnChannelMin,
+
+		packets: make(chan relayedPacket),
+		closed:  make(chan struct{}),
+	}
+
+	r.wg.Add(1)
+	go r.readLoop()
+
+	if err := r.allocate(); err != nil {
+		if closeErr := r.closeConn(); closeErr != nil {
+			log.Debugf("Failed to close the conn to TURN server %s: %v", server, closeErr)
+		}
+		return nil, err
+	}
+
+	r.wg.Add(1)
+	go r.refreshLoop()
+
+	return r, nil
+}
+
+// allocate requests the allocation and keeps its addresses and lifetime
+func (r *relayConn) allocate() error {
+	response, err := r.request(stun.MethodAllocate,
+		requestedTransportUDP{},
+		&stun.Lifetime{Duration: uint32(turnLifetime / time.Second)},
+	)
+	if err != nil {
+		return err
+	}
+
+	var relayed stun.XorRelayedAddress
+	attr, ok := response.GetOneAttribute(stun.AttrXORRelayedAddress)
+	if !ok {
+		return errors.Errorf("Allocate response has no XOR-RELAYED-ADDRESS")
+	}
+	if err = relayed.Unpack(response, attr); err != nil {
+		return errors.Wrapf(err, "Failed to unpack XOR-RELAYED-ADDRESS")
+	}
+	r.relayedAddr = &net.UDPAddr{IP: relayed.IP, Port: relayed.Port}
+
+	// The related address defaults to the local address of the conn
+	r.mappedAddr = r.conn.LocalAddr().(*net.UDPAddr)
+	if attr, ok = response.GetOneAttribute(stun.AttrXORMappedAddress); ok {
+		var mapped stun.XorAddress
+		if err = mapped.Unpack(response, attr); err == nil {
+			r.mappedAddr = &net.UDPAddr{IP: mapped.IP, Port: mapped.Port}
+		}
+	}
+
+	r.lock.Lock()
+	r.lifetime = responseLifetime(response)
+	r.lock.Unlock()
+	return nil
+}
+
+// responseLifetime returns the lifetime granted by a response
+func responseLifetime(m *stun.Message) time.Duration {
+	var lifetime stun.Lifetime
+	attr, ok := m.GetOneAttribute(stun.AttrLifetime)
+	if !ok || lifetime.Unpack(m, attr) != nil || lifetime.Duration == 0 {
+		return turnLifetime
+	}
+	return time.Duration(lifetime.Duration) * time.Second
+}
+
+// request sends a request authenticated with the long-term credentials. The
+// first request is challenged by the server for its realm and nonce, the
+// stale nonces are replaced once.
+func (r *relayConn) request(method stun.Method, attrs ...stun.Attribute) (*stun.Message, error) {
+	for attempt := 0; ; attempt++ {
+		msg, err := r.buildRequest(method, attrs...)
+		if err != nil {
+			return nil, err
+		}
+		response, err := r.roundTrip(msg)
+		if err != nil {
+			return nil, err
+		}
+		if response.Class == stun.ClassSuccessResponse {
+			return response, nil
+		}
+
+		stunErr := newSTUNErrorResponse(response)
+		r.lock.Lock()
+		authenticated := r.key != nil
+		r.lock.Unlock()
+		retry := attempt == 0 && ((stunErr.code == 401 && !authenticated) || stunErr.code == 438)
+		if !retry {
+			return nil, stunErr
+		}
+		if err = r.setRealmAndNonce(response); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// setRealmAndNonce keeps the realm and the nonce of an error response, and
+// derives the key of the long-term credentials
+// https://tools.ietf.org/html/rfc5389#section-15.4
+func (r *relayConn) setRealmAndNonce(m *stun.Message) error {
+	var realm stun.Realm
+	var nonce stun.Nonce
+	realmAttr, ok := m.GetOneAttribute(stun.AttrRealm)
+	if !ok {
+		return errors.Errorf("TURN server sent no REALM")
+	}
+	nonceAttr, ok := m.GetOneAttribute(stun.AttrNonce)
+	if !ok {
+		return errors.Errorf("TURN server sent no NONCE")
+	}
+	if err := realm.Unpack(m, realmAttr); err != nil {
+		return err
+	}
+	if err := nonce.Unpack(m, nonceAttr); err != nil {
+		return err
+	}
+
+	key := md5.Sum([]byte(r.username + ":" + realm.Realm + ":" + r.password)) // #nosec
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.realm = realm.Realm
+	r.nonce = nonce.Nonce
+	r.key = key[:]
+	return nil
+}
+
+// buildRequest builds a request, with the credentials once the server sent
+// its realm and nonce
+func (r *relayConn) buildRequest(method stun.Method, attrs ...stun.Attribute) (*stun.Message, error) {
+	r.lock.Lock()
+	if r.key != nil {
+		attrs = append(append([]stun.Attribute{}, attrs...),
+			&stun.Username{Username: r.username},
+			&stun.Realm{Realm: r.realm},
+			&stun.Nonce{Nonce: r.nonce},
+			&stun.MessageIntegrity{Key: r.key},
+		)
+	}
+	r.lock.Unlock()
+
+	return stun.Build(stun.ClassRequest, method, stun.GenerateTransactionID(), attrs...)
+}
+
+// roundTrip sends a request until its response arrives, only once over a
+// stream
+func (r *relayConn) roundTrip(msg *stun.Message) (*stun.Message, error) {
+	id := string(msg.TransactionID)
+	response := make(chan *stun.Message, 1)
+	r.lock.Lock()
+	r.transactions[id] = response
+	r.lock.Unlock()
+	defer func() {
+		r.lock.Lock()
+		delete(r.transactions, id)
+		r.lock.Unlock()
+	}()
+
+	timeout := time.NewTimer(turnRequestTimeout)
+	defer timeout.Stop()
+	var retransmit <-chan time.Time
+	if !r.reliable {
+		ticker := time.NewTicker(turnRetransmitInterval)
+		defer ticker.Stop()
+		retransmit = ticker.C
+	}
+
+	raw := msg.Pack()
+	for {
+		if _, err := r.conn.WriteTo(raw, r.server); err != nil {
+			return nil, errors.Wrapf(err, "Failed to send %s request", msg.Method)
+		}
+
+		select {
+		case m := <-response:
+			return m, nil
+		case <-retransmit:
+		case <-timeout.C:
+			return nil, errors.Errorf("TURN server %s sent no response to %s", r.server, msg.Method)
+		case <-r.closed:
+			return nil, ErrClosed
+		}
+	}
+}
+
+// readLoop reads the packets of the server: the responses are passed to
+// their transaction, the relayed packets to ReadFrom
+func (r *relayConn) readLoop() {
+	defer r.wg.Done()
+
+	buf := make([]byte, receiveMTU)
+	for {
+		n, from, err := r.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if addr, ok := from.(*net.UDPAddr); !ok || !addr.IP.Equal(r.server.IP) || addr.Port != r.server.Port {
+			continue
+		}
+		packet := append([]byte{}, buf[:n]...)
+
+		switch {
+		case stun.IsSTUN(packet):
+			m, err := stun.NewMessage(packet)
+			if err != nil {
+				r.log.Debugf("Failed to decode a message of TURN server %s: %v", r.server, err)
+				continue
+			}
+			r.handleMessage(m)
+		case len(packet) >= turnChannelDataHeaderLength && packet[0]&0xC0 == 0x40:
+			r.handleChannelData(packet)
+		}
+	}
+}
+
+func (r *relayConn) handleMessage(m *stun.Message) {
+	switch {
+	case m.Class == stun.ClassIndication && m.Method == stun.MethodData:
+		var peer stun.XorPeerAddress
+		var data stun.Data
+		peerAttr, okPeer := m.GetOneAttribute(stun.AttrXORPeerAddress)
+		dataAttr, okData := m.GetOneAttribute(stun.AttrData)
+		if !okPeer || !okData || peer.Unpack(m, peerAttr) != nil || data.Unpack(m, dataAttr) != nil {
+			r.log.Debugf("Dropping malformed Data indication of TURN server %s", r.server)
+			return
+		}
+		r.deliver(relayedPacket{data: data.Data, from: &net.UDPAddr{IP: peer.IP, Port: peer.Port}})
+
+	case m.Class == stun.ClassSuccessResponse || m.Class == stun.ClassErrorResponse:
+		r.lock.Lock()
+		response, ok := r.transactions[string(m.TransactionID)]
+		r.lock.Unlock()
+		if ok {
+			select {
+			case response <- m:
+			default:
+			}
+		}
+	}
+}
+
+func (r *relayConn) handleChannelData(packet []byte) {
+	channel := binary.BigEndian.Uint16(packet[0:])
+	length := int(binary.BigEndian.Uint16(packet[2:]))
+	if turnChannelDataHeaderLength+length > len(packet) {
+		return
+	}
+
+	r.lock.Lock()
+	peer, ok := r.peers[channel]
+	r.lock.Unlock()
+	if !ok {
+		return
+	}
+	r.deliver(relayedPacket{data: packet[turnChannelDataHeaderLength : turnChannelDataHeaderLength+length], from: peer})
+}
+
+// deliver hands a relayed packet to the next ReadFrom
+func (r *relayConn) deliver(packet relayedPacket) {
+	select {
+	case r.packets <- packet:
+	case <-r.closed:
+	}
+}
+
+// refreshLoop refreshes the allocation before its lifetime ends, and the
+// permissions and channels before they expire
+func (r *relayConn) refreshLoop() {
+	defer r.wg.Done()
+
+	r.lock.Lock()
+	allocation := time.NewTimer(r.lifetime / 2)
+	r.lock.Unlock()
+	defer allocation.Stop()
+	permissions := time.NewTicker(turnPermissionRefresh)
+	defer permissions.Stop()
+
+	for {
+		select {
+		case <-allocation.C:
+			response, err := r.request(stun.MethodRefresh, &stun.Lifetime{Duration: uint32(turnLifetime / time.Second)})
+			lifetime := turnRetransmitInterval * 2
+			if err != nil {
+				r.log.Warnf("Failed to refresh the allocation of TURN server %s: %v", r.server, err)
+			} else {
+				lifetime = responseLifetime(response) / 2
+			}
+			allocation.Reset(lifetime)
+
+		case <-permissions.C:
+			r.lock.Lock()
+			channels := make(map[uint16]*net.UDPAddr, len(r.peers))
+			for channel, peer := range r.peers {
+				channels[channel] = peer
+			}
+			ips := make([]string, 0, len(r.permissions))
+			for ip := range r.permissions {
+				ips = append(ips, ip)
+			}
+			r.lock.Unlock()
+
+			// Binding a channel again refreshes it, with the permission
+			// of its peer
+			refreshed := map[string]bool{}
+			for channel, peer := range channels {
+				if err := r.channelBind(channel, peer); err != nil {
+					r.log.Warnf("Failed to refresh the channel of %s on TURN server %s: %v", peer, r.server, err)
+					continue
+				}
+				refreshed[peer.IP.String()] = true
+			}
+			for _, ip := range ips {
+				if refreshed[ip] {
+					continue
+				}
+				if err := r.createPermission(net.ParseIP(ip)); err != nil {
+					r.log.Warnf("Failed to refresh the permission of %s on TURN server %s: %v", ip, r.server, err)
+				}
+			}
+
+		case <-r.closed:
+			return
+		}
+	}
+}
+
+func (r *relayConn) createPermission(ip net.IP) error {
+	_, err := r.request(stun.MethodCreatePermission, &stun.XorPeerAddress{XorAddress: stun.XorAddress{IP: ip}})
+	return err
+}
+
+func (r *relayConn) channelBind(channel uint16, peer *net.UDPAddr) error {
+	_, err := r.request(stun.MethodChannelBind,
+		channelNumber(channel),
+		&stun.XorPeerAddress{XorAddress: stun.XorAddress{IP: peer.IP, Port: peer.Port}},
+	)
+	return err
+}
+
+// bind gives the peer a permission and a channel, in the background
+func (r *relayConn) bind(peer *net.UDPAddr) {
+	key := peer.String()
+	r.lock.Lock()
+	if r.isClosed || r.binding[key] || r.nextChannel > turnChannelMax {
+		r.lock.Unlock()
+		return
+	}
+	r.binding[key] = true
+	r.wg.Add(1)
+	r.lock.Unlock()
+
+	go func() {
+		defer r.wg.Done()
+
+		err := r.createPermission(peer.IP)
+		if err != nil {
+			r.log.Warnf("Failed to create a permission for %s on TURN server %s: %v", peer, r.server, err)
+			r.lock.Lock()
+			delete(r.binding, key)
+			r.lock.Unlock()
+			return
+		}
+		r.lock.Lock()
+		r.permissions[peer.IP.String()] = true
+		channel := r.nextChannel
+		r.nextChannel++
+		r.lock.Unlock()
+
+		// The packets go in Send indications without a channel
+		if err = r.channelBind(channel, peer); err != nil {
+			r.log.Warnf("Failed to bind a channel to %s on TURN server %s: %v", peer, r.server, err)
+			return
+		}
+		r.lock.Lock()
+		r.channels[key] = channel
+		r.peers[channel] = peer
+		r.lock.Unlock()
+	}()
+}
+
+// ReadFrom reads the next packet relayed from a peer
+func (r *relayConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	select {
+	case packet := <-r.packets:
+		return copy(p, packet.data), packet.from, nil
+	case <-r.closed:
+		return 0, nil, ErrClosed
+	}
+}
+
+// WriteTo relays a packet to a peer, on its channel once bound
+func (r *relayConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	peer, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return 0, errors.Errorf("Failed to relay to %s: not an UDP address", addr)
+	}
+
+	r.lock.Lock()
+	channel, bound := r.channels[peer.String()]
+	r.lock.Unlock()
+	if !bound {
+		r.bind(peer)
+	}
+
+	var raw []byte
+	if bound {
+		raw = make([]byte, turnChannelDataHeaderLength+len(p), turnChannelDataHeaderLength+len(p)+3)
+		binary.BigEndian.PutUint16(raw[0:], channel)
+		binary.BigEndian.PutUint16(raw[2:], uint16(len(p)))
+		copy(raw[turnChannelDataHeaderLength:], p)
+		// The ChannelData messages are padded to 4 bytes
+		for len(raw)%4 != 0 {
+			raw = append(raw, 0)
+		}
+	} else {
+		msg, err := stun.Build(stun.ClassIndication, stun.MethodSend, stun.GenerateTransactionID(),
+			&stun.XorPeerAddress{XorAddress: stun.XorAddress{IP: peer.IP, Port: peer.Port}},
+			&stun.Data{Data: p},
+		)
+		if err != nil {
+			return 0, err
+		}
+		raw = msg.Pack()
+	}
+
+	if _, err := r.conn.WriteTo(raw, r.server); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close releases the allocation and closes the conn
+func (r *relayConn) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		// The release isn't retransmitted, the allocation expires otherwise
+		if msg, buildErr := r.buildRequest(stun.MethodRefresh, &stun.Lifetime{Duration: 0}); buildErr == nil {
+			if _, writeErr := r.conn.WriteTo(msg.Pack(), r.server); writeErr != nil {
+				r.log.Debugf("Failed to release the allocation of TURN server %s: %v", r.server, writeErr)
+			}
+		}
+		err = r.closeConn()
+	})
+	return err
+}
+
+// closeConn stops the loops and closes the conn to the server
+func (r *relayConn) closeConn() error {
+	r.lock.Lock()
+	r.isClosed = true
+	r.lock.Unlock()
+
+	close(r.closed)
+	err := r.conn.Close()
+	r.wg.Wait()
+	return err
+}
+
+// LocalAddr returns the relayed address
+func (r *relayConn) LocalAddr() net.Addr {
+	return r.relayedAddr
+}
+
+// SetDeadline is a stub
+func (r *relayConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+// SetReadDeadline is a stub
+func (r *relayConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+// SetWriteDeadline is a stub
+func (r *relayConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+// requestedTransportUDP is the REQUESTED-TRANSPORT of the allocations, the
+// one of package stun can't be packed
+type requestedTransportUDP struct{}
+
+func (requestedTransportUDP) Pack(message *stun.Message) error {
+	message.AddAttribute(stun.AttrRequestedTransport, []byte{byte(stun.ProtocolUDP), 0, 0, 0})
+	return nil
+}
+
+func (requestedTransportUDP) Unpack(message *stun.Message, rawAttribute *stun.RawAttribute) error {
+	return nil
+}
+
+// channelNumber is the CHANNEL-NUMBER attribute with its reserved bytes,
+// which the one of package stun leaves out
+// https://tools.ietf.org/html/rfc5766#section-14.1
+type channelNumber uint16
+
+func (c channelNumber) Pack(message *stun.Message) error {
+	v := make([]byte, 4)
+	binary.BigEndian.PutUint16(v, uint16(c))
+	message.AddAttribute(stun.AttrChannelNumber, v)
+	return nil
+}
+
+func (c channelNumber) Unpack(message *stun.Message, rawAttribute *stun.RawAttribute) error {
+	return nil
+}
@@ -0,0 +1,439 @@
+package ice
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"  // #nosec
+	"crypto/sha1" // #nosec
+	"encoding/binary"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pions/stun"
+	"github.com/pions/transport/test"
+)
+
+// testTURNServer serves a single allocation on the loopback: it challenges
+// the requests for the long-term credentials, and relays the packets of the
+// permitted peers in Data indications or on their channel
+type testTURNServer struct {
+	t        *testing.T
+	conn     *net.UDPConn
+	relay    *net.UDPConn
+	username string
+	password string
+
+	lock        sync.Mutex
+	client      *net.UDPAddr
+	permissions map[string]bool
+	channels    map[uint16]*net.UDPAddr
+	peers       map[string]uint16
+	refreshes   []uint32
+
+	wg sync.WaitGroup
+}
+
+const (
+	testTURNRealm = "pion"
+	testTURNNonce = "nonce"
+)
+
+func newTestTURNServer(t *testing.T, username, password string) *testTURNServer {
+	loopback := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}
+	conn, err := net.ListenUDP("udp4", loopback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	relay, err := net.ListenUDP("udp4", loopback)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testTURNServer{
+		t:           t,
+		conn:        conn,
+		relay:       relay,
+		username:    username,
+		password:    password,
+		permissions: make(map[string]bool),
+		channels:    make(map[uint16]*net.UDPAddr),
+		peers:       make(map[string]uint16),
+	}
+	s.wg.Add(2)
+	go s.serve()
+	go s.relayLoop()
+	return s
+}
+
+func (s *testTURNServer) url() *URL {
+	return &URL{
+		Scheme:   SchemeTypeTURN,
+		Host:     "127.0.0.1",
+		Port:     s.conn.LocalAddr().(*net.UDPAddr).Port,
+		Proto:    ProtoTypeUDP,
+		Username: s.username,
+		Password: s.password,
+	}
+}
+
+func (s *testTURNServer) close() {
+	if err := s.conn.Close(); err != nil {
+		s.t.Error(err)
+	}
+	if err := s.relay.Close(); err != nil {
+		s.t.Error(err)
+	}
+	s.wg.Wait()
+}
+
+// authenticated checks the MESSAGE-INTEGRITY of a request
+func (s *testTURNServer) authenticated(m *stun.Message) bool {
+	attr, ok := m.GetOneAttribute(stun.AttrMessageIntegrity)
+	if !ok {
+		return false
+	}
+	key := md5.Sum([]byte(s.username + ":" + testTURNRealm + ":" + s.password)) // #nosec
+
+	// The length covers the attributes up to the MESSAGE-INTEGRITY
+	raw := append([]byte{}, m.Raw[:attr.Offset]...)
+	binary.BigEndian.PutUint16(raw[2:], uint16(attr.Offset-20+24))
+	mac := hmac.New(sha1.New, key[:])
+	if _, err := mac.Write(raw); err != nil {
+		return false
+	}
+	return hmac.Equal(mac.Sum(nil), attr.Value)
+}
+
+func (s *testTURNServer) respond(to net.Addr, m *stun.Message, class stun.MessageClass, attrs ...stun.Attribute) {
+	response, err := stun.Build(class, m.Method, m.TransactionID, attrs...)
+	if err != nil {
+		s.t.Error(err)
+		return
+	}
+	if _, err = s.conn.WriteTo(response.Pack(), to); err != nil {
+		s.t.Error(err)
+	}
+}
+
+func (s *testTURNServer) serve() {
+	defer s.wg.Done()
+
+	buf := make([]byte, receiveMTU)
+	for {
+		n, from, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		client := from.(*net.UDPAddr)
+		packet := append([]byte{}, buf[:n]...)
+
+		if !stun.IsSTUN(packet) {
+			// ChannelData
+			s.lock.Lock()
+			peer, ok := s.channels[binary.BigEndian.Uint16(packet)]
+			s.lock.Unlock()
+			length := int(binary.BigEndian.Uint16(packet[2:]))
+			if ok {
+				if _, err = s.relay.WriteTo(packet[4:4+length], peer); err != nil {
+					s.t.Error(err)
+				}
+			}
+			continue
+		}
+
+		m, err := stun.NewMessage(packet)
+		if err != nil {
+			s.t.Error(err)
+			continue
+		}
+
+		if m.Class == stun.ClassIndication && m.Method == stun.MethodSend {
+			var peer stun.XorPeerAddress
+			var data stun.Data
+			peerAttr, _ := m.GetOneAttribute(stun.AttrXORPeerAddress)
+			dataAttr, _ := m.GetOneAttribute(stun.AttrData)
+			if peer.Unpack(m, peerAttr) != nil || data.Unpack(m, dataAttr) != nil {
+				s.t.Error("malformed Send indication")
+				continue
+			}
+			s.lock.Lock()
+			permitted := s.permissions[peer.IP.String()]
+			s.lock.Unlock()
+			if permitted {
+				if _, err = s.relay.WriteTo(data.Data, &net.UDPAddr{IP: peer.IP, Port: peer.Port}); err != nil {
+					s.t.Error(err)
+				}
+			}
+			continue
+		}
+
+		if !s.authenticated(m) {
+			s.respond(from, m, stun.ClassErrorResponse, &stun.Err401Unauthorized, &stun.Realm{Realm: testTURNRealm}, &stun.Nonce{Nonce: testTURNNonce})
+			continue
+		}
+
+		switch m.Method {
+		case stun.MethodAllocate:
+			s.lock.Lock()
+			s.client = client
+			s.lock.Unlock()
+			relayed := s.relay.LocalAddr().(*net.UDPAddr)
+			s.respond(from, m, stun.ClassSuccessResponse,
+				&stun.XorRelayedAddress{XorAddress: stun.XorAddress{IP: relayed.IP, Port: relayed.Port}},
+				&stun.XorMappedAddress{XorAddress: stun.XorAddress{IP: client.IP, Port: client.Port}},
+				&stun.Lifetime{Duration: 600},
+			)
+
+		case stun.MethodRefresh:
+			var lifetime stun.Lifetime
+			if attr, ok := m.GetOneAttribute(stun.AttrLifetime); ok {
+				if err = lifetime.Unpack(m, attr); err != nil {
+					s.t.Error(err)
+				}
+			}
+			s.lock.Lock()
+			s.refreshes = append(s.refreshes, lifetime.Duration)
+			s.lock.Unlock()
+			s.respond(from, m, stun.ClassSuccessResponse, &lifetime)
+
+		case stun.MethodCreatePermission, stun.MethodChannelBind:
+			var peer stun.XorPeerAddress
+			attr, _ := m.GetOneAttribute(stun.AttrXORPeerAddress)
+			if err = peer.Unpack(m, attr); err != nil {
+				s.t.Error(err)
+				continue
+			}
+			s.lock.Lock()
+			s.permissions[peer.IP.String()] = true
+			if attr, ok := m.GetOneAttribute(stun.AttrChannelNumber); ok && m.Method == stun.MethodChannelBind {
+				channel := binary.BigEndian.Uint16(attr.Value)
+				addr := &net.UDPAddr{IP: peer.IP, Port: peer.Port}
+				s.channels[channel] = addr
+				s.peers[addr.String()] = channel
+			}
+			s.lock.Unlock()
+			s.respond(from, m, stun.ClassSuccessResponse)
+		}
+	}
+}
+
+// relayLoop relays the packets the permitted peers send to the relayed address
+func (s *testTURNServer) relayLoop() {
+	defer s.wg.Done()
+
+	buf := make([]byte, receiveMTU)
+	for {
+		n, from, err := s.relay.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		peer := from.(*net.UDPAddr)
+
+		s.lock.Lock()
+		client, permitted := s.client, s.permissions[peer.IP.String()]
+		channel, bound := s.peers[peer.String()]
+		s.lock.Unlock()
+		if client == nil || !permitted {
+			continue
+		}
+
+		var raw []byte
+		if bound {
+			raw = make([]byte, 4+n)
+			binary.BigEndian.PutUint16(raw, channel)
+			binary.BigEndian.PutUint16(raw[2:], uint16(n))
+			copy(raw[4:], buf[:n])
+		} else {
+			m, err := stun.Build(stun.ClassIndication, stun.MethodData, stun.GenerateTransactionID(),
+				&stun.XorPeerAddress{XorAddress: stun.XorAddress{IP: peer.IP, Port: peer.Port}},
+				&stun.Data{Data: append([]byte{}, buf[:n]...)},
+			)
+			if err != nil {
+				s.t.Error(err)
+				continue
+			}
+			raw = m.Pack()
+		}
+		if _, err = s.conn.WriteTo(raw, client); err != nil {
+			s.t.Error(err)
+		}
+	}
+}
+
+func TestRelayConn(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	server := newTestTURNServer(t, "user", "pass")
+	defer server.close()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	relay, err := allocateRelay("udp4", server.url(), conn, iceLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if relayed := server.relay.LocalAddr().(*net.UDPAddr); !relay.LocalAddr().(*net.UDPAddr).IP.Equal(relayed.IP) || relay.LocalAddr().(*net.UDPAddr).Port != relayed.Port {
+		t.Fatalf("Expected the relayed address %s, got %s", relayed, relay.LocalAddr())
+	}
+
+	peer, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if closeErr := peer.Close(); closeErr != nil {
+			t.Error(closeErr)
+		}
+	}()
+	peerAddr := peer.LocalAddr().(*net.UDPAddr)
+
+	// The packets written before the permission are dropped by the server,
+	// the following ones go on the channel once bound
+	received := make(chan []byte)
+	go func() {
+		buf := make([]byte, 100)
+		for {
+			n, _, readErr := peer.ReadFrom(buf)
+			if readErr != nil {
+				close(received)
+				return
+			}
+			received <- append([]byte{}, buf[:n]...)
+		}
+	}()
+	for bound := false; !bound; {
+		if _, err = relay.WriteTo([]byte("ping"), peerAddr); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case packet := <-received:
+			if !bytes.Equal(packet, []byte("ping")) {
+				t.Fatalf("Expected ping, got %q", packet)
+			}
+			relay.lock.Lock()
+			_, bound = relay.channels[peerAddr.String()]
+			relay.lock.Unlock()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	// The packets of the peer are relayed back from its address
+	if _, err = peer.WriteTo([]byte("pong"), server.relay.LocalAddr()); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 100)
+	n, from, err := relay.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf[:n], []byte("pong")) || from.String() != peerAddr.String() {
+		t.Fatalf("Expected pong from %s, got %q from %s", peerAddr, buf[:n], from)
+	}
+
+	// The allocation is released once closed
+	if err = relay.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = relay.ReadFrom(buf); err != ErrClosed {
+		t.Fatalf("Expected ErrClosed, got %v", err)
+	}
+	for released := false; !released; {
+		server.lock.Lock()
+		released = len(server.refreshes) == 1 && server.refreshes[0] == 0
+		server.lock.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAgentRelay(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	server := newTestTURNServer(t, "user", "pass")
+	defer server.close()
+
+	// The credentials are required, and checked by the server
+	noCredentials := server.url()
+	noCredentials.Password = ""
+	wrongPassword := server.url()
+	wrongPassword.Password = "wrong"
+	a, err := NewAgent(&AgentConfig{
+		Urls:           []*URL{noCredentials, wrongPassword},
+		NetworkTypes:   []NetworkType{NetworkTypeUDP4},
+		CandidateTypes: []CandidateType{CandidateTypeRelay},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gatheringErrors, err := a.GetGatheringErrors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gatheringErrors) != 2 || gatheringErrors[0].ErrorCode != 0 || gatheringErrors[1].ErrorCode != 401 {
+		t.Fatalf("Expected a missing credentials and a 401 gathering error, got %v", gatheringErrors)
+	}
+	if err = a.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The relay candidate connects to a peer
+	aNotifier, aConnected := onConnected()
+	bNotifier, bConnected := onConnected()
+	aAgent, err := NewAgent(&AgentConfig{
+		Urls:           []*URL{server.url()},
+		NetworkTypes:   []NetworkType{NetworkTypeUDP4},
+		CandidateTypes: []CandidateType{CandidateTypeRelay},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	candidates, err := aAgent.GetLocalCandidates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 1 || candidates[0].Type != CandidateTypeRelay || candidates[0].Port != server.relay.LocalAddr().(*net.UDPAddr).Port {
+		t.Fatalf("Expected the relay candidate of the server, got %v", candidates)
+	}
+	check(aAgent.OnConnectionStateChange(aNotifier))
+
+	peer, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bAgent, err := NewAgent(&AgentConfig{PacketConn: peer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(bAgent.OnConnectionStateChange(bNotifier))
+
+	aConn, bConn := connect(aAgent, bAgent)
+	<-aConnected
+	<-bConnected
+
+	if _, err = aConn.Write([]byte("relayed")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 100)
+	n, err := bConn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf[:n], []byte("relayed")) {
+		t.Fatalf("Expected the relayed packet, got %q", buf[:n])
+	}
+
+	if err = aConn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = bConn.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
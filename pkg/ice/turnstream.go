@@ -0,0 +1,129 @@
+package ice
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pions/webrtc/pkg/logging"
+	"github.com/pkg/errors"
+)
+
+// stunHeaderLength is the length of the header of the STUN messages, whose
+// length field leaves it out
+const stunHeaderLength = 20
+
+// allocateRelayStream creates an allocation on the TURN server of the url
+// over TCP, or over TLS for the turns: urls. The certificate of the server
+// is verified against the ServerName of config, which defaults to the host
+// of the url.
+func allocateRelayStream(network string, url *URL, config *tls.Config, log logging.LeveledLogger) (*relayConn, error) {
+	// The relayed transport stays UDP, of the family of network
+	streamNetwork := strings.Replace(network, "udp", "tcp", 1)
+	address := net.JoinHostPort(url.Host, fmt.Sprint(url.Port))
+	dialer := &net.Dialer{Timeout: turnRequestTimeout}
+
+	var conn net.Conn
+	var err error
+	if url.Scheme == SchemeTypeTURNS {
+		if config == nil {
+			config = &tls.Config{}
+		} else {
+			config = config.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName = url.Host
+		}
+		conn, err = tls.DialWithDialer(dialer, streamNetwork, address, config)
+	} else {
+		conn, err = dialer.Dial(streamNetwork, address)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to connect to TURN server")
+	}
+
+	remote := conn.RemoteAddr().(*net.TCPAddr)
+	server := &net.UDPAddr{IP: remote.IP, Port: remote.Port, Zone: remote.Zone}
+	return newRelayConn(newStreamConn(conn, server), server, true, url, log)
+}
+
+// streamConn frames the STUN messages and the ChannelData messages sent to
+// a TURN server over a stream, as the packets of a net.PacketConn. The
+// messages carry their length, the ChannelData messages are padded to 4
+// bytes over streams.
+// https://tools.ietf.org/html/rfc5766#section-11.5
+type streamConn struct {
+	conn   net.Conn
+	server *net.UDPAddr
+}
+
+func newStreamConn(conn net.Conn, server *net.UDPAddr) *streamConn {
+	return &streamConn{conn: conn, server: server}
+}
+
+// ReadFrom reads the next message of the server, truncated to p as the
+// datagrams are
+func (s *streamConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	header := make([]byte, turnChannelDataHeaderLength)
+	if _, err := io.ReadFull(s.conn, header); err != nil {
+		return 0, nil, err
+	}
+
+	length := int(binary.BigEndian.Uint16(header[2:]))
+	switch {
+	case header[0]&0xC0 == 0:
+		length += stunHeaderLength
+	case header[0]&0xC0 == 0x40:
+		length += turnChannelDataHeaderLength
+		for length%4 != 0 {
+			length++
+		}
+	default:
+		return 0, nil, errors.Errorf("TURN server %s sent an unknown message", s.server)
+	}
+
+	frame := make([]byte, length)
+	copy(frame, header)
+	if _, err := io.ReadFull(s.conn, frame[len(header):]); err != nil {
+		return 0, nil, err
+	}
+	return copy(p, frame), s.server, nil
+}
+
+// WriteTo writes a message to the server, addr is the address of the server
+func (s *streamConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	return s.conn.Write(p)
+}
+
+// Close closes the stream
+func (s *streamConn) Close() error {
+	return s.conn.Close()
+}
+
+// LocalAddr returns the local address of the stream, as an UDP address
+func (s *streamConn) LocalAddr() net.Addr {
+	local, ok := s.conn.LocalAddr().(*net.TCPAddr)
+	if !ok {
+		return &net.UDPAddr{}
+	}
+	return &net.UDPAddr{IP: local.IP, Port: local.Port, Zone: local.Zone}
+}
+
+// SetDeadline sets the deadline of the stream
+func (s *streamConn) SetDeadline(t time.Time) error {
+	return s.conn.SetDeadline(t)
+}
+
+// SetReadDeadline sets the read deadline of the stream
+func (s *streamConn) SetReadDeadline(t time.Time) error {
+	return s.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the write deadline of the stream
+func (s *streamConn) SetWriteDeadline(t time.Time) error {
+	return s.conn.SetWriteDeadline(t)
+}
@@ -0,0 +1,279 @@
+package ice
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pions/transport/test"
+)
+
+// testTURNStreamProxy serves a testTURNServer over TLS: it forwards the
+// messages of the stream to the server, and pads the ChannelData messages
+// of the server as they are over streams
+type testTURNStreamProxy struct {
+	t        *testing.T
+	server   *testTURNServer
+	listener net.Listener
+	cert     *x509.Certificate
+
+	lock  sync.Mutex
+	conns []net.Conn
+
+	wg sync.WaitGroup
+}
+
+func newTestTURNStreamProxy(t *testing.T, server *testTURNServer) *testTURNStreamProxy {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "pion"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := tls.Listen("tcp4", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &testTURNStreamProxy{t: t, server: server, listener: listener, cert: cert}
+	p.wg.Add(1)
+	go p.accept()
+	return p
+}
+
+func (p *testTURNStreamProxy) url() *URL {
+	url := p.server.url()
+	url.Scheme = SchemeTypeTURNS
+	url.Port = p.listener.Addr().(*net.TCPAddr).Port
+	url.Proto = ProtoTypeTCP
+	return url
+}
+
+// rootCAs returns a pool with the self-signed certificate of the proxy
+func (p *testTURNStreamProxy) rootCAs() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(p.cert)
+	return pool
+}
+
+func (p *testTURNStreamProxy) close() {
+	if err := p.listener.Close(); err != nil {
+		p.t.Error(err)
+	}
+	p.lock.Lock()
+	for _, conn := range p.conns {
+		if err := conn.Close(); err != nil {
+			p.t.Error(err)
+		}
+	}
+	p.lock.Unlock()
+	p.wg.Wait()
+}
+
+func (p *testTURNStreamProxy) accept() {
+	defer p.wg.Done()
+
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		upstream, err := net.DialUDP("udp4", nil, p.server.conn.LocalAddr().(*net.UDPAddr))
+		if err != nil {
+			p.t.Error(err)
+			return
+		}
+		p.lock.Lock()
+		p.conns = append(p.conns, conn, upstream)
+		p.lock.Unlock()
+
+		p.wg.Add(2)
+		go func() {
+			defer p.wg.Done()
+
+			stream := newStreamConn(conn, nil)
+			buf := make([]byte, receiveMTU)
+			for {
+				n, _, readErr := stream.ReadFrom(buf)
+				if readErr != nil {
+					return
+				}
+				if _, writeErr := upstream.Write(buf[:n]); writeErr != nil {
+					return
+				}
+			}
+		}()
+		go func() {
+			defer p.wg.Done()
+
+			buf := make([]byte, receiveMTU)
+			for {
+				n, readErr := upstream.Read(buf)
+				if readErr != nil {
+					return
+				}
+				packet := append([]byte{}, buf[:n]...)
+				if packet[0]&0xC0 == 0x40 {
+					for len(packet)%4 != 0 {
+						packet = append(packet, 0)
+					}
+				}
+				if _, writeErr := conn.Write(packet); writeErr != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+func TestAgentRelayTLS(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	server := newTestTURNServer(t, "user", "pass")
+	defer server.close()
+	proxy := newTestTURNStreamProxy(t, server)
+	defer proxy.close()
+
+	// The self-signed certificate of the server isn't trusted by default
+	a, err := NewAgent(&AgentConfig{
+		Urls:           []*URL{proxy.url()},
+		NetworkTypes:   []NetworkType{NetworkTypeUDP4},
+		CandidateTypes: []CandidateType{CandidateTypeRelay},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gatheringErrors, err := a.GetGatheringErrors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gatheringErrors) != 1 {
+		t.Fatalf("Expected a certificate gathering error, got %v", gatheringErrors)
+	}
+	if err = a.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The relay candidate, allocated over TLS, relays UDP with a lower local
+	// preference than the ones allocated over UDP
+	aNotifier, aConnected := onConnected()
+	bNotifier, bConnected := onConnected()
+	aAgent, err := NewAgent(&AgentConfig{
+		Urls:           []*URL{proxy.url()},
+		NetworkTypes:   []NetworkType{NetworkTypeUDP4},
+		CandidateTypes: []CandidateType{CandidateTypeRelay},
+		TLSConfig:      &tls.Config{RootCAs: proxy.rootCAs()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	candidates, err := aAgent.GetLocalCandidates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 1 || candidates[0].Type != CandidateTypeRelay || candidates[0].Port != server.relay.LocalAddr().(*net.UDPAddr).Port {
+		t.Fatalf("Expected the relay candidate of the server, got %v", candidates)
+	}
+	if candidates[0].NetworkType != NetworkTypeUDP4 || candidates[0].LocalPreference >= defaultLocalPreference {
+		t.Fatalf("Expected an UDP4 candidate with a lower local preference, got %s %d", candidates[0].NetworkType, candidates[0].LocalPreference)
+	}
+	check(aAgent.OnConnectionStateChange(aNotifier))
+
+	peer, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bAgent, err := NewAgent(&AgentConfig{PacketConn: peer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(bAgent.OnConnectionStateChange(bNotifier))
+
+	aConn, bConn := connect(aAgent, bAgent)
+	<-aConnected
+	<-bConnected
+
+	// The packets of both ways go on the channel once bound
+	for _, size := range []int{5, 6, 7, 8} {
+		packet := bytes.Repeat([]byte{byte(size)}, size)
+		if _, err = aConn.Write(packet); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, 100)
+		n, readErr := bConn.Read(buf)
+		if readErr != nil {
+			t.Fatal(readErr)
+		}
+		if !bytes.Equal(buf[:n], packet) {
+			t.Fatalf("Expected the relayed packet %v, got %v", packet, buf[:n])
+		}
+
+		if _, err = bConn.Write(packet); err != nil {
+			t.Fatal(err)
+		}
+		if n, readErr = aConn.Read(buf); readErr != nil {
+			t.Fatal(readErr)
+		}
+		if !bytes.Equal(buf[:n], packet) {
+			t.Fatalf("Expected the relayed packet %v, got %v", packet, buf[:n])
+		}
+	}
+
+	if err = aConn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = bConn.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRelayConnTLSInsecureSkipVerify(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	server := newTestTURNServer(t, "user", "pass")
+	defer server.close()
+	proxy := newTestTURNStreamProxy(t, server)
+	defer proxy.close()
+
+	relay, err := allocateRelayStream("udp4", proxy.url(), &tls.Config{InsecureSkipVerify: true}, iceLog) // #nosec
+	if err != nil {
+		t.Fatal(err)
+	}
+	if relayed := server.relay.LocalAddr().(*net.UDPAddr); relay.LocalAddr().(*net.UDPAddr).Port != relayed.Port {
+		t.Fatalf("Expected the relayed address %s, got %s", relayed, relay.LocalAddr())
+	}
+	if err = relay.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
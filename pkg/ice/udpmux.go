@@ -0,0 +1,257 @@
+package ice
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pions/stun"
+	"github.com/pions/webrtc/pkg/logging"
+)
+
+// udpMuxQueueSize is the number of packets queued for an agent, the
+// following ones are dropped until it reads them
+const udpMuxQueueSize = 128
+
+// UDPMux shares a single UDP socket between agents, such as one bound to
+// the only port a container exposes. The packets are handed to the agent
+// whose local ufrag the USERNAME of the first binding request of their
+// remote address names, then to the agent that remote address belongs to.
+// The socket must be bound to a specific IP, the address of the host
+// candidate of the agents.
+type UDPMux struct {
+	conn net.PacketConn
+	log  logging.LeveledLogger
+
+	lock      sync.Mutex
+	ufrags    map[string]*udpMuxedConn
+	addrs     map[string]*udpMuxedConn
+	muxClosed bool
+
+	closeOnce sync.Once
+	closed    chan struct{}
+	readDone  chan struct{}
+}
+
+// NewUDPMux creates an UDPMux reading from conn, it is closed with the
+// UDPMux. The logger defaults to the one of the ice scope when log is nil.
+func NewUDPMux(conn net.PacketConn, log logging.LeveledLogger) *UDPMux {
+	if log == nil {
+		log = iceLog
+	}
+	m := &UDPMux{
+		conn:     conn,
+		log:      log,
+		ufrags:   map[string]*udpMuxedConn{},
+		addrs:    map[string]*udpMuxedConn{},
+		closed:   make(chan struct{}),
+		readDone: make(chan struct{}),
+	}
+	go m.readLoop()
+	return m
+}
+
+// LocalAddr returns the address of the socket
+func (m *UDPMux) LocalAddr() net.Addr {
+	return m.conn.LocalAddr()
+}
+
+// Close closes the socket, the reads of the agents using it fail
+func (m *UDPMux) Close() error {
+	var err error
+	m.closeOnce.Do(func() {
+		m.lock.Lock()
+		m.muxClosed = true
+		m.lock.Unlock()
+
+		close(m.closed)
+		err = m.conn.Close()
+		<-m.readDone
+	})
+	return err
+}
+
+// getConn returns the conn of an agent, the binding requests naming ufrag
+// are handed to it
+func (m *UDPMux) getConn(ufrag string) (*udpMuxedConn, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.muxClosed {
+		return nil, ErrUDPMuxClosed
+	}
+
+	c := &udpMuxedConn{
+		mux:     m,
+		packets: make(chan udpMuxedPacket, udpMuxQueueSize),
+		closed:  make(chan struct{}),
+	}
+	m.ufrags[ufrag] = c
+	return c, nil
+}
+
+// addUfrag hands the binding requests naming ufrag to c too, the ufrag of
+// the session a Restart starts
+func (m *UDPMux) addUfrag(c *udpMuxedConn, ufrag string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.ufrags[ufrag] = c
+}
+
+// addAddr hands the packets of a remote address to c, it returns false
+// once c is closed
+func (m *UDPMux) addAddr(c *udpMuxedConn, addr net.Addr) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	select {
+	case <-c.closed:
+		return false
+	default:
+	}
+	m.addrs[addr.String()] = c
+	return true
+}
+
+// removeConn closes c and stops handing packets to it
+func (m *UDPMux) removeConn(c *udpMuxedConn) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	close(c.closed)
+	for ufrag, conn := range m.ufrags {
+		if conn == c {
+			delete(m.ufrags, ufrag)
+		}
+	}
+	for addr, conn := range m.addrs {
+		if conn == c {
+			delete(m.addrs, addr)
+		}
+	}
+}
+
+// findConn returns the conn a packet is handed to, registering its remote
+// address when it is the first binding request of it
+func (m *UDPMux) findConn(packet []byte, addr *net.UDPAddr) *udpMuxedConn {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if c, ok := m.addrs[addr.String()]; ok {
+		return c
+	}
+	if !stun.IsSTUN(packet) {
+		return nil
+	}
+
+	msg, err := stun.NewMessage(packet)
+	if err != nil {
+		return nil
+	}
+	username, ok := msg.GetOneAttribute(stun.AttrUsername)
+	if !ok {
+		return nil
+	}
+	// The USERNAME of a request is the local ufrag followed by the remote one
+	ufrag := strings.SplitN(string(username.Value), ":", 2)[0]
+	c, ok := m.ufrags[ufrag]
+	if !ok {
+		return nil
+	}
+	m.addrs[addr.String()] = c
+	return c
+}
+
+func (m *UDPMux) readLoop() {
+	defer close(m.readDone)
+
+	buffer := make([]byte, receiveMTU)
+	for {
+		n, addr, err := m.conn.ReadFrom(buffer)
+		if err != nil {
+			select {
+			case <-m.closed:
+			default:
+				m.log.Warnf("Failed to read from UDPMux %s: %v", m.conn.LocalAddr(), err)
+			}
+			return
+		}
+		udpAddr, ok := addr.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+
+		c := m.findConn(buffer[:n], udpAddr)
+		if c == nil {
+			m.log.Debugf("Dropping packet from %s, no agent of UDPMux %s expects it", addr, m.conn.LocalAddr())
+			continue
+		}
+		select {
+		case c.packets <- udpMuxedPacket{data: append([]byte{}, buffer[:n]...), from: udpAddr}:
+		default:
+			m.log.Debugf("Dropping packet from %s, the agent of UDPMux %s is not reading", addr, m.conn.LocalAddr())
+		}
+	}
+}
+
+// udpMuxedConn is the conn of an agent on an UDPMux, closing it leaves the
+// socket open
+type udpMuxedConn struct {
+	mux *UDPMux
+
+	packets   chan udpMuxedPacket
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// udpMuxedPacket is a packet of a remote address of an agent
+type udpMuxedPacket struct {
+	data []byte
+	from *net.UDPAddr
+}
+
+// ReadFrom reads the next packet handed to the agent
+func (c *udpMuxedConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	select {
+	case packet := <-c.packets:
+		return copy(p, packet.data), packet.from, nil
+	case <-c.closed:
+		return 0, nil, ErrClosed
+	case <-c.mux.closed:
+		return 0, nil, ErrUDPMuxClosed
+	}
+}
+
+// WriteTo sends a packet on the socket, the packets of addr are handed to
+// the agent from now on
+func (c *udpMuxedConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	if !c.mux.addAddr(c, addr) {
+		return 0, ErrClosed
+	}
+	return c.mux.conn.WriteTo(p, addr)
+}
+
+// Close stops handing packets to the agent
+func (c *udpMuxedConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.mux.removeConn(c)
+	})
+	return nil
+}
+
+// LocalAddr returns the address of the socket
+func (c *udpMuxedConn) LocalAddr() net.Addr {
+	return c.mux.conn.LocalAddr()
+}
+
+// SetDeadline is a stub
+func (c *udpMuxedConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+// SetReadDeadline is a stub
+func (c *udpMuxedConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+// SetWriteDeadline is a stub
+func (c *udpMuxedConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
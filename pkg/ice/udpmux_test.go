@@ -0,0 +1,92 @@
+package ice
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pions/transport/test"
+)
+
+func TestUDPMux(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	socket, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := NewUDPMux(socket, nil)
+
+	// Each agent of the mux connects to its own peer
+	var conns []*Conn
+	for i := 0; i < 2; i++ {
+		aNotifier, aConnected := onConnected()
+		aAgent, err := NewAgent(&AgentConfig{UDPMux: mux})
+		if err != nil {
+			t.Fatalf("Error constructing ice.Agent: %v", err)
+		}
+		check(aAgent.OnConnectionStateChange(aNotifier))
+
+		candidates, err := aAgent.GetLocalCandidates()
+		check(err)
+		addr := socket.LocalAddr().(*net.UDPAddr)
+		if len(candidates) != 1 || !candidates[0].IP.Equal(addr.IP) || candidates[0].Port != addr.Port {
+			t.Fatalf("Expected the host candidate of the mux, got %v", candidates)
+		}
+
+		peer, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		bNotifier, bConnected := onConnected()
+		bAgent, err := NewAgent(&AgentConfig{PacketConn: peer})
+		if err != nil {
+			t.Fatalf("Error constructing ice.Agent: %v", err)
+		}
+		check(bAgent.OnConnectionStateChange(bNotifier))
+
+		aConn, bConn := connect(aAgent, bAgent)
+		<-aConnected
+		<-bConnected
+		conns = append(conns, aConn, bConn)
+	}
+
+	// The packets reach the agent of their peer
+	buf := make([]byte, receiveMTU)
+	for i := 0; i < len(conns); i += 2 {
+		message := []byte{byte(i)}
+		if _, err = conns[i+1].Write(message); err != nil {
+			t.Fatal(err)
+		}
+		n, err := conns[i].Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf[:n], message) {
+			t.Fatalf("Expected %v, got %v", message, buf[:n])
+		}
+	}
+
+	// Closing the agents leaves the socket open
+	for _, conn := range conns {
+		if err = conn.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = socket.WriteTo([]byte{0x00}, socket.LocalAddr()); err != nil {
+		t.Fatalf("The socket should be left open by the agents: %v", err)
+	}
+
+	if err = mux.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewAgent(&AgentConfig{UDPMux: mux}); err != ErrUDPMuxClosed {
+		t.Fatalf("Expected ErrUDPMuxClosed, got %v", err)
+	}
+	if _, err = NewAgent(&AgentConfig{UDPMux: mux, PacketConn: socket}); err != ErrUDPMuxPacketConn {
+		t.Fatalf("Expected ErrUDPMuxPacketConn, got %v", err)
+	}
+}
@@ -5,7 +5,10 @@ import (
 	"sync/atomic"
 )
 
-func localInterfaces() (ips []net.IP) {
+// localInterfaces returns the addresses of the interfaces that are up, but
+// the loopback ones and the ones the filters of the config reject, a nil
+// filter accepts all of them
+func localInterfaces(interfaceFilter func(string) bool, ipFilter func(net.IP) bool) (ips []net.IP) {
 	ifaces, err := net.Interfaces()
 	if err != nil {
 		return ips
@@ -18,6 +21,9 @@ func localInterfaces() (ips []net.IP) {
 		if iface.Flags&net.FlagLoopback != 0 {
 			continue // loopback interface
 		}
+		if interfaceFilter != nil && !interfaceFilter(iface.Name) {
+			continue
+		}
 		addrs, err := iface.Addrs()
 		if err != nil {
 			return ips
@@ -33,6 +39,9 @@ func localInterfaces() (ips []net.IP) {
 			if ip == nil || ip.IsLoopback() {
 				continue
 			}
+			if ipFilter != nil && !ipFilter(ip) {
+				continue
+			}
 			ips = append(ips, ip)
 		}
 	}
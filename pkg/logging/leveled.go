@@ -88,9 +88,12 @@ func SetDefaultWriter(w io.Writer) {
 	defaultWriter.SetOutput(w)
 }
 
-// LeveledLogger encapsulates functionality for providing logging at
-// user-defined levels
-type LeveledLogger struct {
+// DefaultLeveledLogger encapsulates functionality for providing logging at
+// user-defined levels. It was named LeveledLogger, which is now the interface
+// it implements, so no alias can keep the old name: code naming the
+// *LeveledLogger type has to name *DefaultLeveledLogger instead, or the
+// LeveledLogger interface to accept any logger.
+type DefaultLeveledLogger struct {
 	level  LogLevel
 	writer *loggerWriter
 	trace  *log.Logger
@@ -102,59 +105,59 @@ type LeveledLogger struct {
 
 // WithTraceLogger is a chainable configuration function which sets the
 // Trace-level logger
-func (ll *LeveledLogger) WithTraceLogger(log *log.Logger) *LeveledLogger {
+func (ll *DefaultLeveledLogger) WithTraceLogger(log *log.Logger) *DefaultLeveledLogger {
 	ll.trace = log
 	return ll
 }
 
 // WithDebugLogger is a chainable configuration function which sets the
 // Debug-level logger
-func (ll *LeveledLogger) WithDebugLogger(log *log.Logger) *LeveledLogger {
+func (ll *DefaultLeveledLogger) WithDebugLogger(log *log.Logger) *DefaultLeveledLogger {
 	ll.debug = log
 	return ll
 }
 
 // WithInfoLogger is a chainable configuration function which sets the
 // Info-level logger
-func (ll *LeveledLogger) WithInfoLogger(log *log.Logger) *LeveledLogger {
+func (ll *DefaultLeveledLogger) WithInfoLogger(log *log.Logger) *DefaultLeveledLogger {
 	ll.info = log
 	return ll
 }
 
 // WithWarnLogger is a chainable configuration function which sets the
 // Warn-level logger
-func (ll *LeveledLogger) WithWarnLogger(log *log.Logger) *LeveledLogger {
+func (ll *DefaultLeveledLogger) WithWarnLogger(log *log.Logger) *DefaultLeveledLogger {
 	ll.warn = log
 	return ll
 }
 
 // WithErrorLogger is a chainable configuration function which sets the
 // Error-level logger
-func (ll *LeveledLogger) WithErrorLogger(log *log.Logger) *LeveledLogger {
+func (ll *DefaultLeveledLogger) WithErrorLogger(log *log.Logger) *DefaultLeveledLogger {
 	ll.err = log
 	return ll
 }
 
 // WithLogLevel is a chainable configuration function which sets the logger's
 // logging level threshold, at or below which all messages will be logged
-func (ll *LeveledLogger) WithLogLevel(level LogLevel) *LeveledLogger {
+func (ll *DefaultLeveledLogger) WithLogLevel(level LogLevel) *DefaultLeveledLogger {
 	ll.level.Set(level)
 	return ll
 }
 
 // WithOutput is a chainable configuration function which sets the logger's
 // logging output to the supplied io.Writer
-func (ll *LeveledLogger) WithOutput(output io.Writer) *LeveledLogger {
+func (ll *DefaultLeveledLogger) WithOutput(output io.Writer) *DefaultLeveledLogger {
 	ll.writer.SetOutput(output)
 	return ll
 }
 
 // SetLevel sets the logger's logging level
-func (ll *LeveledLogger) SetLevel(newLevel LogLevel) {
+func (ll *DefaultLeveledLogger) SetLevel(newLevel LogLevel) {
 	ll.level.Set(newLevel)
 }
 
-func (ll *LeveledLogger) logf(logger *log.Logger, level LogLevel, format string, args ...interface{}) {
+func (ll *DefaultLeveledLogger) logf(logger *log.Logger, level LogLevel, format string, args ...interface{}) {
 	if ll.level.Get() < level {
 		return
 	}
@@ -167,63 +170,63 @@ func (ll *LeveledLogger) logf(logger *log.Logger, level LogLevel, format string,
 }
 
 // Trace emits the preformatted message if the logger is at or below LogLevelTrace
-func (ll *LeveledLogger) Trace(msg string) {
+func (ll *DefaultLeveledLogger) Trace(msg string) {
 	ll.logf(ll.trace, LogLevelTrace, msg)
 }
 
 // Tracef formats and emits a message if the logger is at or below LogLevelTrace
-func (ll *LeveledLogger) Tracef(format string, args ...interface{}) {
+func (ll *DefaultLeveledLogger) Tracef(format string, args ...interface{}) {
 	ll.logf(ll.trace, LogLevelTrace, format, args...)
 }
 
 // Debug emits the preformatted message if the logger is at or below LogLevelDebug
-func (ll *LeveledLogger) Debug(msg string) {
+func (ll *DefaultLeveledLogger) Debug(msg string) {
 	ll.logf(ll.debug, LogLevelDebug, msg)
 }
 
 // Debugf formats and emits a message if the logger is at or below LogLevelDebug
-func (ll *LeveledLogger) Debugf(format string, args ...interface{}) {
+func (ll *DefaultLeveledLogger) Debugf(format string, args ...interface{}) {
 	ll.logf(ll.debug, LogLevelDebug, format, args...)
 }
 
 // Info emits the preformatted message if the logger is at or below LogLevelInfo
-func (ll *LeveledLogger) Info(msg string) {
+func (ll *DefaultLeveledLogger) Info(msg string) {
 	ll.logf(ll.info, LogLevelInfo, msg)
 }
 
 // Infof formats and emits a message if the logger is at or below LogLevelInfo
-func (ll *LeveledLogger) Infof(format string, args ...interface{}) {
+func (ll *DefaultLeveledLogger) Infof(format string, args ...interface{}) {
 	ll.logf(ll.info, LogLevelInfo, format, args...)
 }
 
 // Warn emits the preformatted message if the logger is at or below LogLevelWarn
-func (ll *LeveledLogger) Warn(msg string) {
+func (ll *DefaultLeveledLogger) Warn(msg string) {
 	ll.logf(ll.warn, LogLevelWarn, msg)
 }
 
 // Warnf formats and emits a message if the logger is at or below LogLevelWarn
-func (ll *LeveledLogger) Warnf(format string, args ...interface{}) {
+func (ll *DefaultLeveledLogger) Warnf(format string, args ...interface{}) {
 	ll.logf(ll.warn, LogLevelWarn, format, args...)
 }
 
 // Error emits the preformatted message if the logger is at or below LogLevelError
-func (ll *LeveledLogger) Error(msg string) {
+func (ll *DefaultLeveledLogger) Error(msg string) {
 	ll.logf(ll.err, LogLevelError, msg)
 }
 
 // Errorf formats and emits a message if the logger is at or below LogLevelError
-func (ll *LeveledLogger) Errorf(format string, args ...interface{}) {
+func (ll *DefaultLeveledLogger) Errorf(format string, args ...interface{}) {
 	ll.logf(ll.err, LogLevelError, format, args...)
 }
 
-// NewLeveledLogger returns a configured *LeveledLogger
-func NewLeveledLogger() *LeveledLogger {
+// NewLeveledLogger returns a configured *DefaultLeveledLogger
+func NewLeveledLogger() *DefaultLeveledLogger {
 	return NewLeveledLoggerForScope("PIONS")
 }
 
-// NewLeveledLoggerForScope returns a configured *LeveledLogger for the given scope
-func NewLeveledLoggerForScope(scope string) *LeveledLogger {
-	logger := &LeveledLogger{
+// NewLeveledLoggerForScope returns a configured *DefaultLeveledLogger for the given scope
+func NewLeveledLoggerForScope(scope string) *DefaultLeveledLogger {
+	logger := &DefaultLeveledLogger{
 		writer: &loggerWriter{
 			output: defaultWriter,
 		},
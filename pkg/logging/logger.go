@@ -0,0 +1,69 @@
+package logging
+
+// LeveledLogger is the basic pions Logger interface, it is implemented by
+// DefaultLeveledLogger and can be implemented to route the logs of the
+// packages to the logging of the application. Before the interface
+// existed, LeveledLogger named the struct now called DefaultLeveledLogger.
+type LeveledLogger interface {
+	Trace(msg string)
+	Tracef(format string, args ...interface{})
+	Debug(msg string)
+	Debugf(format string, args ...interface{})
+	Info(msg string)
+	Infof(format string, args ...interface{})
+	Warn(msg string)
+	Warnf(format string, args ...interface{})
+	Error(msg string)
+	Errorf(format string, args ...interface{})
+}
+
+// LoggerFactory creates the LeveledLogger of a logging scope, such as "pc"
+// or "ice"
+type LoggerFactory interface {
+	NewLogger(scope string) LeveledLogger
+}
+
+// DefaultLoggerFactory creates the loggers of NewScopedLogger, their level
+// is set by SetLogLevelForScope and the PIONS_LOG_* environment variables
+type DefaultLoggerFactory struct{}
+
+// NewLogger returns the scoped logger of the scope
+func (DefaultLoggerFactory) NewLogger(scope string) LeveledLogger {
+	return NewScopedLogger(scope)
+}
+
+// NopLogger is a LeveledLogger discarding every message
+type NopLogger struct{}
+
+// Trace discards the message
+func (NopLogger) Trace(msg string) {}
+
+// Tracef discards the message
+func (NopLogger) Tracef(format string, args ...interface{}) {}
+
+// Debug discards the message
+func (NopLogger) Debug(msg string) {}
+
+// Debugf discards the message
+func (NopLogger) Debugf(format string, args ...interface{}) {}
+
+// Info discards the message
+func (NopLogger) Info(msg string) {}
+
+// Infof discards the message
+func (NopLogger) Infof(format string, args ...interface{}) {}
+
+// Warn discards the message
+func (NopLogger) Warn(msg string) {}
+
+// Warnf discards the message
+func (NopLogger) Warnf(format string, args ...interface{}) {}
+
+// Error discards the message
+func (NopLogger) Error(msg string) {}
+
+// Errorf discards the message
+func (NopLogger) Errorf(format string, args ...interface{}) {}
+
+var _ LeveledLogger = (*DefaultLeveledLogger)(nil)
+var _ LeveledLogger = NopLogger{}
@@ -77,3 +77,10 @@ func TestPackageLevelSettings(t *testing.T) {
 		t.Errorf("Expected to find %q in %q, but didn't", traceMsg, outBuf.String())
 	}
 }
+
+func TestDefaultLoggerFactory(t *testing.T) {
+	var factory logging.LoggerFactory = logging.DefaultLoggerFactory{}
+	if factory.NewLogger("test3") != logging.LeveledLogger(logging.NewScopedLogger("test3")) {
+		t.Error("DefaultLoggerFactory should return the scoped logger")
+	}
+}
@@ -9,13 +9,13 @@ import (
 
 type loggerRegistry struct {
 	sync.RWMutex
-	scopeLoggers map[string]*LeveledLogger
+	scopeLoggers map[string]*DefaultLeveledLogger
 	scopeLevels  map[string]LogLevel
 }
 
 var (
 	registry = &loggerRegistry{
-		scopeLoggers: make(map[string]*LeveledLogger),
+		scopeLoggers: make(map[string]*DefaultLeveledLogger),
 		scopeLevels:  make(map[string]LogLevel),
 	}
 )
@@ -46,7 +46,7 @@ func SetLogLevelForScope(scope string, level LogLevel) {
 
 // NewScopedLogger returns a predefined logger for the given logging scope
 // NB: Can be used idempotently
-func NewScopedLogger(scope string) *LeveledLogger {
+func NewScopedLogger(scope string) *DefaultLeveledLogger {
 	registry.Lock()
 	defer registry.Unlock()
 
@@ -0,0 +1,100 @@
+// Package ogg holds the parts of the Ogg Opus format shared by the Ogg
+// reader and writer
+package ogg
+
+import "encoding/binary"
+
+const (
+	// PageHeaderSize is the size of a page header, before its segment
+	// table
+	PageHeaderSize = 27
+
+	// MaxSegments is the most segments of a page, and MaxSegmentSize the
+	// largest one
+	MaxSegments    = 255
+	MaxSegmentSize = 255
+
+	// The flags of the header type of a page
+	PageHeaderTypeContinuation      = 0x01
+	PageHeaderTypeBeginningOfStream = 0x02
+	PageHeaderTypeEndOfStream       = 0x04
+
+	// OpusClockRate is the rate the Opus samples are counted at, whatever
+	// the sample rate of the audio
+	OpusClockRate = 48000
+
+	checksumOffset = 22
+)
+
+var crcTable = generateCRCTable()
+
+// Checksum returns the CRC of a page, computed with its checksum field
+// zeroed
+// https://tools.ietf.org/html/rfc3533#section-6
+func Checksum(page []byte) uint32 {
+	var crc uint32
+	for i, b := range page {
+		if i >= checksumOffset && i < checksumOffset+4 {
+			b = 0
+		}
+		crc = (crc << 8) ^ crcTable[byte(crc>>24)^b]
+	}
+	return crc
+}
+
+// SetChecksum sets the checksum field of a page
+func SetChecksum(page []byte) {
+	binary.LittleEndian.PutUint32(page[checksumOffset:], Checksum(page))
+}
+
+// generateCRCTable returns the table of the CRC-32 of Ogg: the polynomial
+// 0x04c11db7, without reflection nor final XOR
+func generateCRCTable() *[256]uint32 {
+	const poly = 0x04c11db7
+
+	var table [256]uint32
+	for i := range table {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = (r << 1) ^ poly
+			} else {
+				r <<= 1
+			}
+		}
+		table[i] = r
+	}
+	return &table
+}
+
+// OpusSamples returns the duration of an Opus packet in samples at 48kHz,
+// from the configuration and the frame count of its TOC byte. 0 is
+// returned for a malformed packet.
+// https://tools.ietf.org/html/rfc6716#section-3.1
+func OpusSamples(packet []byte) int {
+	if len(packet) == 0 {
+		return 0
+	}
+
+	var frameSamples int
+	switch config := packet[0] >> 3; {
+	case config < 12: // SILK: 10, 20, 40 and 60 ms
+		frameSamples = []int{480, 960, 1920, 2880}[config%4]
+	case config < 16: // Hybrid: 10 and 20 ms
+		frameSamples = []int{480, 960}[config%2]
+	default: // CELT: 2.5, 5, 10 and 20 ms
+		frameSamples = []int{120, 240, 480, 960}[config%4]
+	}
+
+	frames := 1
+	switch packet[0] & 0x03 {
+	case 1, 2:
+		frames = 2
+	case 3:
+		if len(packet) < 2 {
+			return 0
+		}
+		frames = int(packet[1] & 0x3f)
+	}
+	return frames * frameSamples
+}
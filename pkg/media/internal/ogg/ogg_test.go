@@ -0,0 +1,58 @@
+package ogg
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// crc is the bitwise CRC of Ogg the table is checked against
+func crc(b []byte) uint32 {
+	var r uint32
+	for _, c := range b {
+		r ^= uint32(c) << 24
+		for i := 0; i < 8; i++ {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ 0x04c11db7
+			} else {
+				r <<= 1
+			}
+		}
+	}
+	return r
+}
+
+func TestChecksum(t *testing.T) {
+	page := make([]byte, PageHeaderSize+3)
+	copy(page, "OggS")
+	for i := PageHeaderSize; i < len(page); i++ {
+		page[i] = byte(i)
+	}
+	expected := crc(page)
+
+	// The checksum field is ignored
+	binary.LittleEndian.PutUint32(page[checksumOffset:], 0xDEADBEEF)
+	assert.Equal(t, expected, Checksum(page))
+
+	SetChecksum(page)
+	assert.Equal(t, expected, binary.LittleEndian.Uint32(page[checksumOffset:]))
+}
+
+func TestOpusSamples(t *testing.T) {
+	for _, testCase := range []struct {
+		packet  []byte
+		samples int
+	}{
+		{[]byte{0x08}, 960},        // SILK 20ms
+		{[]byte{0x18}, 2880},       // SILK 60ms
+		{[]byte{0x78}, 960},        // Hybrid 20ms
+		{[]byte{0x80}, 120},        // CELT 2.5ms
+		{[]byte{0xF9}, 1920},       // Two CELT 20ms frames
+		{[]byte{0xFB, 0x03}, 2880}, // Three CELT 20ms frames
+		{[]byte{0xFB}, 0},
+		{nil, 0},
+	} {
+		assert.Equal(t, testCase.samples, OpusSamples(testCase.packet), "%x", testCase.packet)
+	}
+}
@@ -0,0 +1,136 @@
+// Package vp8 assembles the VP8 frames of RTP packets for the media writers
+package vp8
+
+import (
+	"errors"
+
+	"github.com/pions/rtp"
+)
+
+const (
+	extendedBit      = 0x80
+	startBit         = 0x10
+	partitionMask    = 0x07
+	pictureIDBit     = 0x80
+	tl0PICIDXBit     = 0x40
+	tidKeyIDXMask    = 0x30
+	pictureIDLongBit = 0x80
+
+	// The P bit of the frame tag is unset for the keyframes
+	// https://tools.ietf.org/html/rfc6386#section-9.1
+	interframeBit = 0x01
+)
+
+var errDescriptorTruncated = errors.New("VP8 payload descriptor is truncated")
+
+// Assembler assembles the VP8 frames carried by RTP packets received in
+// order, a frame ends with the packet that has the marker bit. The frames
+// missing a packet are dropped, along the ones after them until a keyframe,
+// those can't be decoded. The first frame is a keyframe too.
+type Assembler struct {
+	frame   []byte
+	started bool
+
+	lastSequenceNumber uint16
+	received           bool
+
+	// waitKeyframe is set until a keyframe starts
+	waitKeyframe bool
+	keyframe     bool
+}
+
+// NewAssembler creates an Assembler waiting for a keyframe
+func NewAssembler() *Assembler {
+	return &Assembler{waitKeyframe: true}
+}
+
+// Push adds the next packet, the frame it ends is returned with whether it
+// is a keyframe. The frame is nil while it isn't complete or when it is
+// dropped.
+func (a *Assembler) Push(packet *rtp.Packet) (frame []byte, keyframe bool, err error) {
+	lost := a.received && packet.SequenceNumber != a.lastSequenceNumber+1
+	a.lastSequenceNumber = packet.SequenceNumber
+	a.received = true
+	if lost {
+		a.drop()
+	}
+
+	payload, frameStart, err := depacketize(packet.Payload)
+	if err != nil {
+		a.drop()
+		return nil, false, err
+	}
+
+	if frameStart {
+		// The packet ending the previous frame was lost
+		if a.started {
+			a.drop()
+		}
+		a.keyframe = len(payload) != 0 && payload[0]&interframeBit == 0
+		if a.keyframe {
+			a.waitKeyframe = false
+		}
+		a.started = !a.waitKeyframe
+		a.frame = a.frame[:0]
+	}
+
+	if !a.started {
+		return nil, false, nil
+	}
+	a.frame = append(a.frame, payload...)
+	if !packet.Marker {
+		return nil, false, nil
+	}
+
+	a.started = false
+	frame = append([]byte{}, a.frame...)
+	return frame, a.keyframe, nil
+}
+
+// drop drops the frame being assembled and the next ones until a keyframe
+func (a *Assembler) drop() {
+	a.started = false
+	a.waitKeyframe = true
+	a.frame = a.frame[:0]
+}
+
+// depacketize strips the VP8 payload descriptor, and returns whether the
+// payload starts a frame: the beginning of its first partition.
+// https://tools.ietf.org/html/rfc7741#section-4.2
+func depacketize(payload []byte) ([]byte, bool, error) {
+	if len(payload) == 0 {
+		return nil, false, errors.New("VP8 payload is empty")
+	}
+	frameStart := payload[0]&startBit != 0 && payload[0]&partitionMask == 0
+
+	i := 1
+	if payload[0]&extendedBit != 0 {
+		if len(payload) <= i {
+			return nil, false, errDescriptorTruncated
+		}
+		extension := payload[i]
+		i++
+
+		if extension&pictureIDBit != 0 {
+			if len(payload) <= i {
+				return nil, false, errDescriptorTruncated
+			}
+			if payload[i]&pictureIDLongBit != 0 {
+				i += 2
+			} else {
+				i++
+			}
+		}
+		if extension&tl0PICIDXBit != 0 {
+			i++
+		}
+		if extension&tidKeyIDXMask != 0 {
+			i++
+		}
+	}
+
+	if i > len(payload) {
+		return nil, false, errDescriptorTruncated
+	}
+	return payload[i:], frameStart, nil
+}
@@ -0,0 +1,55 @@
+package vp8
+
+import (
+	"testing"
+
+	"github.com/pions/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func packet(sequenceNumber uint16, marker bool, payload ...byte) *rtp.Packet {
+	return &rtp.Packet{
+		Header:  rtp.Header{SequenceNumber: sequenceNumber, Marker: marker},
+		Payload: payload,
+	}
+}
+
+func TestAssembler(t *testing.T) {
+	a := NewAssembler()
+
+	push := func(p *rtp.Packet) ([]byte, bool) {
+		frame, keyframe, err := a.Push(p)
+		assert.NoError(t, err)
+		return frame, keyframe
+	}
+
+	// The frames before the first keyframe are dropped
+	frame, _ := push(packet(1, true, 0x10, 0x01, 0xAA))
+	assert.Nil(t, frame)
+
+	// A keyframe over two packets, the second one with a picture ID
+	frame, _ = push(packet(2, false, 0x10, 0x00, 0xBB))
+	assert.Nil(t, frame)
+	frame, keyframe := push(packet(3, true, 0x80, 0x80, 0x05, 0xCC))
+	assert.Equal(t, []byte{0x00, 0xBB, 0xCC}, frame)
+	assert.True(t, keyframe)
+
+	frame, keyframe = push(packet(4, true, 0x10, 0x01, 0xDD))
+	assert.Equal(t, []byte{0x01, 0xDD}, frame)
+	assert.False(t, keyframe)
+
+	// The frame missing a packet is dropped, the next ones until a keyframe
+	// as well
+	frame, _ = push(packet(5, false, 0x10, 0x01, 0xEE))
+	assert.Nil(t, frame)
+	frame, _ = push(packet(7, true, 0x10, 0x01, 0xFF))
+	assert.Nil(t, frame)
+	frame, keyframe = push(packet(8, true, 0x10, 0x00, 0x11))
+	assert.Equal(t, []byte{0x00, 0x11}, frame)
+	assert.True(t, keyframe)
+
+	_, _, err := a.Push(packet(9, true, 0x80))
+	assert.Error(t, err)
+	_, _, err = a.Push(packet(10, true))
+	assert.Error(t, err)
+}
@@ -0,0 +1,243 @@
+// Package ivfreader reads the frames of IVF files, such as the ones written
+// by ivfwriter
+package ivfreader
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/pions/webrtc/pkg/media"
+)
+
+const (
+	headerSize      = 32
+	frameHeaderSize = 12
+
+	// maxFrameSize bounds the allocation of a frame read from a corrupted
+	// file
+	maxFrameSize = 1 << 24
+
+	// The samples are counted at the RTP clock rate of video
+	videoClockRate = 90000
+)
+
+var errSignature = errors.New("IVF signature mismatch")
+
+// IVFFileHeader is the header of an IVF file
+type IVFFileHeader struct {
+	Version             uint16
+	HeaderSize          uint16
+	FourCC              string
+	Width               uint16
+	Height              uint16
+	TimebaseDenominator uint32
+	TimebaseNumerator   uint32
+
+	// NumFrames is the frame count of the header, 0 when the file was
+	// written as a stream
+	NumFrames uint32
+}
+
+// IVFFrameHeader is the header of a frame, its Timestamp counts the
+// timebase of the file
+type IVFFrameHeader struct {
+	FrameSize uint32
+	Timestamp uint64
+}
+
+type frame struct {
+	header IVFFrameHeader
+	data   []byte
+}
+
+// IVFReader is used to read the frames of an IVF file or stream, it only
+// reads forward so pipes are read as well as files
+type IVFReader struct {
+	in     io.Reader
+	header IVFFileHeader
+
+	// next is the frame read ahead by NextSample, to know the duration of
+	// the one before it
+	next         *frame
+	lastDuration uint32
+}
+
+// New opens an IVF file and reads its header
+func New(fileName string) (*IVFReader, *IVFFileHeader, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r, header, err := NewWith(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, err
+	}
+	return r, header, nil
+}
+
+// NewWith reads the header of an IVF stream from in, in is closed on Close
+// if it is an io.Closer
+func NewWith(in io.Reader) (*IVFReader, *IVFFileHeader, error) {
+	if in == nil {
+		return nil, nil, errors.New("stream is nil")
+	}
+
+	b := make([]byte, headerSize)
+	if _, err := io.ReadFull(in, b); err != nil {
+		return nil, nil, err
+	}
+	if string(b[0:4]) != "DKIF" {
+		return nil, nil, errSignature
+	}
+
+	header := IVFFileHeader{
+		Version:             binary.LittleEndian.Uint16(b[4:]),
+		HeaderSize:          binary.LittleEndian.Uint16(b[6:]),
+		FourCC:              string(b[8:12]),
+		Width:               binary.LittleEndian.Uint16(b[12:]),
+		Height:              binary.LittleEndian.Uint16(b[14:]),
+		TimebaseDenominator: binary.LittleEndian.Uint32(b[16:]),
+		TimebaseNumerator:   binary.LittleEndian.Uint32(b[20:]),
+		NumFrames:           binary.LittleEndian.Uint32(b[24:]),
+	}
+	if header.TimebaseDenominator == 0 {
+		return nil, nil, errors.New("IVF timebase is invalid")
+	}
+
+	// A longer header is skipped
+	if header.HeaderSize > headerSize {
+		if _, err := io.CopyN(ioutil.Discard, in, int64(header.HeaderSize-headerSize)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	r := &IVFReader{in: in, header: header}
+	return r, &r.header, nil
+}
+
+// ParseNextFrame returns the next frame of the file and its header, io.EOF
+// is returned at the end of the file
+func (r *IVFReader) ParseNextFrame() ([]byte, *IVFFrameHeader, error) {
+	if r.next != nil {
+		next := r.next
+		r.next = nil
+		return next.data, &next.header, nil
+	}
+
+	f, err := r.readFrame()
+	if err != nil {
+		return nil, nil, err
+	}
+	return f.data, &f.header, nil
+}
+
+func (r *IVFReader) readFrame() (*frame, error) {
+	b := make([]byte, frameHeaderSize)
+	if _, err := io.ReadFull(r.in, b); err != nil {
+		return nil, err
+	}
+
+	header := IVFFrameHeader{
+		FrameSize: binary.LittleEndian.Uint32(b[0:]),
+		Timestamp: binary.LittleEndian.Uint64(b[4:]),
+	}
+	if header.FrameSize > maxFrameSize {
+		return nil, errors.New("IVF frame is too large")
+	}
+
+	data := make([]byte, header.FrameSize)
+	if _, err := io.ReadFull(r.in, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return &frame{header: header, data: data}, nil
+}
+
+// NextSample returns the next frame as a sample, with its presentation
+// time. Its Samples are the time until the next frame at the 90kHz clock
+// of video, the last frame lasts as long as the one before it. The
+// keyframes of VP8 and VP9 are flagged. io.EOF is returned at the end of
+// the file.
+func (r *IVFReader) NextSample() (media.Sample, time.Duration, error) {
+	current, header, err := r.ParseNextFrame()
+	if err != nil {
+		return media.Sample{}, 0, err
+	}
+
+	next, err := r.readFrame()
+	switch {
+	case err == nil:
+		r.next = next
+		r.lastDuration = r.ticks(next.header.Timestamp - header.Timestamp)
+	case err != io.EOF:
+		return media.Sample{}, 0, err
+	}
+
+	sample := media.Sample{
+		Data:       current,
+		Samples:    r.lastDuration,
+		IsKeyFrame: isKeyFrame(r.header.FourCC, current),
+	}
+	return sample, r.presentationTime(header.Timestamp), nil
+}
+
+// presentationTime converts a timestamp of the file to a time since its
+// start
+func (r *IVFReader) presentationTime(timestamp uint64) time.Duration {
+	units := timestamp * uint64(r.header.TimebaseNumerator)
+	denominator := uint64(r.header.TimebaseDenominator)
+	return time.Duration(units/denominator)*time.Second +
+		time.Duration(units%denominator)*time.Second/time.Duration(denominator)
+}
+
+// ticks converts a duration in the timebase of the file to the clock rate
+// of video
+func (r *IVFReader) ticks(duration uint64) uint32 {
+	return uint32(duration * uint64(r.header.TimebaseNumerator) * videoClockRate / uint64(r.header.TimebaseDenominator))
+}
+
+// isKeyFrame returns whether a frame is a keyframe, false for a codec
+// other than VP8 and VP9
+func isKeyFrame(fourCC string, frame []byte) bool {
+	if len(frame) == 0 {
+		return false
+	}
+
+	switch fourCC {
+	case "VP80":
+		// The P bit of the frame tag is unset for the keyframes
+		// https://tools.ietf.org/html/rfc6386#section-9.1
+		return frame[0]&0x01 == 0
+	case "VP90":
+		// The frame_type of the uncompressed header follows the frame
+		// marker, the profile and show_existing_frame, a reserved bit comes
+		// first for the profile 3
+		b := frame[0]
+		profile := (b>>5)&0x01 | (b>>3)&0x02
+		shift := uint(3)
+		if profile == 3 {
+			shift--
+		}
+		if b>>shift&0x01 != 0 { // show_existing_frame
+			return false
+		}
+		return b>>(shift-1)&0x01 == 0
+	}
+	return false
+}
+
+// Close closes the file
+func (r *IVFReader) Close() error {
+	if closer, ok := r.in.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
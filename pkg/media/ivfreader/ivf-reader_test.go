@@ -0,0 +1,104 @@
+package ivfreader
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pions/rtp"
+	"github.com/pions/webrtc/pkg/media/ivfwriter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIVFReader(t *testing.T) {
+	var file bytes.Buffer
+	w, err := ivfwriter.NewWith(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, payload := range [][]byte{
+		{0x10, 0x00, 0xAA}, // Keyframe
+		{0x10, 0x01, 0xBB},
+		{0x10, 0x01, 0xCC},
+	} {
+		assert.NoError(t, w.WriteRTP(&rtp.Packet{
+			Header:  rtp.Header{SequenceNumber: uint16(i), Timestamp: uint32(i) * 3000, Marker: true},
+			Payload: payload,
+		}))
+	}
+	assert.NoError(t, w.Close())
+
+	r, header, err := NewWith(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "VP80", header.FourCC)
+	assert.Equal(t, uint32(90000), header.TimebaseDenominator)
+	assert.Equal(t, uint32(1), header.TimebaseNumerator)
+
+	frame, frameHeader, err := r.ParseNextFrame()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0xAA}, frame)
+	assert.Equal(t, uint64(0), frameHeader.Timestamp)
+
+	// The last frame lasts as long as the one before it
+	for _, expected := range []struct {
+		data []byte
+		pts  time.Duration
+	}{
+		{[]byte{0x01, 0xBB}, time.Second / 30},
+		{[]byte{0x01, 0xCC}, 2 * time.Second / 30},
+	} {
+		sample, pts, sampleErr := r.NextSample()
+		assert.NoError(t, sampleErr)
+		assert.Equal(t, expected.data, sample.Data)
+		assert.Equal(t, uint32(3000), sample.Samples)
+		assert.False(t, sample.IsKeyFrame)
+		assert.Equal(t, expected.pts, pts)
+	}
+
+	_, _, err = r.NextSample()
+	assert.Equal(t, io.EOF, err)
+	assert.NoError(t, r.Close())
+}
+
+func TestIVFReader_Invalid(t *testing.T) {
+	_, _, err := NewWith(bytes.NewReader([]byte("DKIF")))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	_, _, err = NewWith(bytes.NewReader(make([]byte, headerSize)))
+	assert.Equal(t, errSignature, err)
+
+	header := make([]byte, headerSize)
+	copy(header, "DKIF")
+	header[6] = headerSize
+	header[16] = 30
+	header[20] = 1
+	r, _, err := NewWith(bytes.NewReader(append(header, 0x02, 0x00, 0x00, 0x00)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = r.ParseNextFrame()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+func TestIsKeyFrame(t *testing.T) {
+	for _, testCase := range []struct {
+		fourCC   string
+		frame    []byte
+		keyFrame bool
+	}{
+		{"VP80", []byte{0x00}, true},
+		{"VP80", []byte{0x01}, false},
+		{"VP90", []byte{0x80}, true},  // Profile 0
+		{"VP90", []byte{0x84}, false}, // Profile 0 interframe
+		{"VP90", []byte{0x88}, false}, // Profile 0 existing frame
+		{"VP90", []byte{0xB0}, true},  // Profile 3
+		{"VP90", []byte{0xB2}, false}, // Profile 3 interframe
+		{"H264", []byte{0x00}, false},
+		{"VP80", nil, false},
+	} {
+		assert.Equal(t, testCase.keyFrame, isKeyFrame(testCase.fourCC, testCase.frame), "%s %x", testCase.fourCC, testCase.frame)
+	}
+}
@@ -1,19 +1,46 @@
+// Package ivfwriter writes the VP8 frames of RTP packets to IVF files
 package ivfwriter
 
 import (
 	"encoding/binary"
-	"fmt"
+	"errors"
+	"io"
 	"os"
 
 	"github.com/pions/rtp"
-	"github.com/pions/rtp/codecs"
+	"github.com/pions/webrtc/pkg/media/internal/vp8"
 )
 
-// IVFWriter is used to take RTP packets and write them to an IVF on disk
+const (
+	headerSize       = 32
+	frameHeaderSize  = 12
+	frameCountOffset = 24
+
+	// The presentation timestamps of the frames are their RTP timestamps,
+	// counted at the VP8 clock rate
+	timebaseDenominator = 90000
+	timebaseNumerator   = 1
+)
+
+var errClosed = errors.New("IVFWriter is closed")
+
+// IVFWriter is used to take RTP packets and write them to an IVF on disk.
+// The packets are expected in order, a frame missing a packet is dropped
+// along the following ones until a keyframe. The file starts with a
+// keyframe, the presentation timestamps of the frames follow their RTP
+// timestamps.
 type IVFWriter struct {
-	fd           *os.File
-	count        uint64
-	currentFrame []byte
+	out       io.Writer
+	assembler *vp8.Assembler
+
+	count uint32
+
+	// The RTP timestamps are unwrapped into ticks since the first frame
+	started       bool
+	lastTimestamp uint32
+	ticks         int64
+
+	closed bool
 }
 
 // New builds a new IVF writer
@@ -23,55 +50,105 @@ func New(fileName string) (*IVFWriter, error) {
 		return nil, err
 	}
 
-	header := make([]byte, 32)
-	copy(header[0:], []byte("DKIF"))                // DKIF
-	binary.LittleEndian.PutUint16(header[4:], 0)    // Version
-	binary.LittleEndian.PutUint16(header[6:], 32)   // Header Size
-	copy(header[8:], []byte("VP80"))                // FOURCC
-	binary.LittleEndian.PutUint16(header[12:], 640) // Version
-	binary.LittleEndian.PutUint16(header[14:], 480) // Header Size
-	binary.LittleEndian.PutUint32(header[16:], 30)  // Framerate numerator
-	binary.LittleEndian.PutUint32(header[20:], 1)   // Framerate Denominator
-	binary.LittleEndian.PutUint32(header[24:], 900) // Frame count
-	binary.LittleEndian.PutUint32(header[28:], 0)   // Unused
-
-	if _, err := f.Write(header); err != nil {
+	w, err := NewWith(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// NewWith builds a new IVF writer writing to out. The frame count of the
+// header is updated on Close if out is an io.WriteSeeker, and out is closed
+// if it is an io.Closer.
+func NewWith(out io.Writer) (*IVFWriter, error) {
+	if out == nil {
+		return nil, errors.New("file not opened")
+	}
+
+	header := make([]byte, headerSize)
+	copy(header[0:], "DKIF")                                        // Signature
+	binary.LittleEndian.PutUint16(header[4:], 0)                    // Version
+	binary.LittleEndian.PutUint16(header[6:], headerSize)           // Header size
+	copy(header[8:], "VP80")                                        // FOURCC
+	binary.LittleEndian.PutUint16(header[12:], 640)                 // Width
+	binary.LittleEndian.PutUint16(header[14:], 480)                 // Height
+	binary.LittleEndian.PutUint32(header[16:], timebaseDenominator) // Timebase denominator
+	binary.LittleEndian.PutUint32(header[20:], timebaseNumerator)   // Timebase numerator
+	binary.LittleEndian.PutUint32(header[frameCountOffset:], 0)     // Frame count
+	binary.LittleEndian.PutUint32(header[28:], 0)                   // Unused
+
+	if _, err := out.Write(header); err != nil {
 		return nil, err
 	}
 
-	return &IVFWriter{fd: f}, nil
+	return &IVFWriter{out: out, assembler: vp8.NewAssembler()}, nil
 }
 
 // AddPacket adds a new packet and writes the appropriate headers for it
+//
+// Deprecated: use WriteRTP
 func (i *IVFWriter) AddPacket(packet *rtp.Packet) error {
+	return i.WriteRTP(packet)
+}
 
-	vp8Packet := codecs.VP8Packet{}
-	_, err := vp8Packet.Unmarshal(packet)
-	if err != nil {
-		return err
+// WriteRTP adds a new packet, the frame it ends is written to the file
+func (i *IVFWriter) WriteRTP(packet *rtp.Packet) error {
+	if i.closed {
+		return errClosed
 	}
 
-	i.currentFrame = append(i.currentFrame, vp8Packet.Payload[0:]...)
+	frame, _, err := i.assembler.Push(packet)
+	if err != nil || frame == nil {
+		return err
+	}
 
-	if !packet.Marker {
-		return nil
-	} else if len(i.currentFrame) == 0 {
-		fmt.Println("skipping")
-		return nil
+	if !i.started {
+		i.started = true
+		i.lastTimestamp = packet.Timestamp
 	}
+	i.ticks += int64(int32(packet.Timestamp - i.lastTimestamp))
+	i.lastTimestamp = packet.Timestamp
 
-	frameHeader := make([]byte, 12)
-	binary.LittleEndian.PutUint32(frameHeader[0:], uint32(len(i.currentFrame))) // Frame length
-	binary.LittleEndian.PutUint64(frameHeader[4:], i.count)                     // PTS
+	frameHeader := make([]byte, frameHeaderSize)
+	binary.LittleEndian.PutUint32(frameHeader[0:], uint32(len(frame))) // Frame length
+	binary.LittleEndian.PutUint64(frameHeader[4:], uint64(i.ticks))    // PTS
 
+	if _, err := i.out.Write(append(frameHeader, frame...)); err != nil {
+		return err
+	}
 	i.count++
+	return nil
+}
+
+// Close writes the frame count to the header and closes the file
+func (i *IVFWriter) Close() error {
+	if i.closed {
+		return nil
+	}
+	i.closed = true
 
-	if _, err := i.fd.Write(frameHeader); err != nil {
+	var err error
+	if seeker, ok := i.out.(io.WriteSeeker); ok {
+		err = writeFrameCount(seeker, i.count)
+	}
+	if closer, ok := i.out.(io.Closer); ok {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+func writeFrameCount(w io.WriteSeeker, count uint32) error {
+	if _, err := w.Seek(frameCountOffset, io.SeekStart); err != nil {
 		return err
-	} else if _, err := i.fd.Write(i.currentFrame); err != nil {
+	}
+	frameCount := make([]byte, 4)
+	binary.LittleEndian.PutUint32(frameCount, count)
+	if _, err := w.Write(frameCount); err != nil {
 		return err
 	}
-
-	i.currentFrame = nil
-	return nil
+	_, err := w.Seek(0, io.SeekEnd)
+	return err
 }
@@ -0,0 +1,79 @@
+package ivfwriter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pions/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func vp8Packet(sequenceNumber uint16, timestamp uint32, payload ...byte) *rtp.Packet {
+	return &rtp.Packet{
+		Header:  rtp.Header{SequenceNumber: sequenceNumber, Timestamp: timestamp, Marker: true},
+		Payload: payload,
+	}
+}
+
+func TestIVFWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ivfwriter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileName := filepath.Join(dir, "output.ivf")
+	w, err := New(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The timestamps wrap around, the lost packet drops the last frame
+	assert.NoError(t, w.WriteRTP(vp8Packet(1, 0xFFFFFFFF-2999, 0x10, 0x00, 0xAA)))
+	assert.NoError(t, w.WriteRTP(vp8Packet(2, 0, 0x10, 0x01, 0xBB)))
+	assert.NoError(t, w.WriteRTP(vp8Packet(4, 3000, 0x10, 0x01, 0xCC)))
+	assert.NoError(t, w.Close())
+	assert.Error(t, w.WriteRTP(vp8Packet(5, 6000, 0x10, 0x00, 0xDD)))
+
+	b, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Len(t, b, headerSize+2*(frameHeaderSize+2)) {
+		return
+	}
+	assert.Equal(t, "DKIF", string(b[:4]))
+	assert.Equal(t, "VP80", string(b[8:12]))
+	assert.Equal(t, uint32(90000), binary.LittleEndian.Uint32(b[16:]))
+	assert.Equal(t, uint32(2), binary.LittleEndian.Uint32(b[frameCountOffset:]))
+
+	frames := b[headerSize:]
+	assert.Equal(t, uint32(2), binary.LittleEndian.Uint32(frames))
+	assert.Equal(t, uint64(0), binary.LittleEndian.Uint64(frames[4:]))
+	assert.Equal(t, []byte{0x00, 0xAA}, frames[frameHeaderSize:frameHeaderSize+2])
+
+	frames = frames[frameHeaderSize+2:]
+	assert.Equal(t, uint64(3000), binary.LittleEndian.Uint64(frames[4:]))
+	assert.Equal(t, []byte{0x01, 0xBB}, frames[frameHeaderSize:])
+}
+
+func TestIVFWriter_NotSeekable(t *testing.T) {
+	var out bytes.Buffer
+	w, err := NewWith(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, w.WriteRTP(vp8Packet(1, 0, 0x10, 0x00, 0xAA)))
+	assert.NoError(t, w.Close())
+
+	// The frame count can't be updated
+	assert.Equal(t, uint32(0), binary.LittleEndian.Uint32(out.Bytes()[frameCountOffset:]))
+	assert.Len(t, out.Bytes(), headerSize+frameHeaderSize+2)
+
+	_, err = NewWith(nil)
+	assert.Error(t, err)
+}
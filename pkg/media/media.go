@@ -1,7 +1,28 @@
 package media
 
+import "time"
+
 // Sample contains media, and the amount of samples in it
 type Sample struct {
 	Data    []byte
 	Samples uint32
+
+	// Timestamp is the optional capture time of the sample. When set, the
+	// RTP timestamp of the sample follows the time elapsed since the first
+	// timestamped sample, and it is sent as the abs-capture-time. When it
+	// is zero the RTP timestamp advances by the Samples of the previous
+	// sample, and the time the sample is sent stands for its capture time.
+	Timestamp time.Time
+
+	// IsKeyFrame marks a sample the remote can decode without the previous
+	// ones. It is optional and only counted in the stats of the RTPSender.
+	IsKeyFrame bool
+}
+
+// SampleReader reads the samples of a media file, such as the IVF and Ogg
+// readers of ivfreader and oggreader
+type SampleReader interface {
+	// NextSample returns the next sample with its presentation time, the
+	// time since the start of the file. io.EOF is returned at the end.
+	NextSample() (Sample, time.Duration, error)
 }
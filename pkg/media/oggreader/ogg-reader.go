@@ -0,0 +1,254 @@
+// Package oggreader reads the Opus packets of Ogg files, such as the ones
+// written by oggwriter
+package oggreader
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pions/webrtc/pkg/media"
+	"github.com/pions/webrtc/pkg/media/internal/ogg"
+)
+
+const opusHeadSize = 19
+
+var (
+	errSignature = errors.New("Ogg capture pattern mismatch")
+	errChecksum  = errors.New("Ogg page checksum mismatch")
+	errNotOpus   = errors.New("Ogg stream isn't Opus")
+)
+
+// OggHeader is the identification header of the Opus stream
+// https://tools.ietf.org/html/rfc7845#section-5.1
+type OggHeader struct {
+	Version    uint8
+	Channels   uint8
+	PreSkip    uint16
+	SampleRate uint32
+	OutputGain uint16
+	ChannelMap uint8
+}
+
+// pageHeader is the header of an Ogg page
+// https://tools.ietf.org/html/rfc3533#section-6
+type pageHeader struct {
+	headerType      uint8
+	granulePosition uint64
+	serial          uint32
+}
+
+type packet struct {
+	data    []byte
+	granule int64
+}
+
+// OggReader is used to read the Opus packets of an Ogg file or stream, it
+// only reads forward so pipes are read as well as files. The pages of the
+// other logical streams of the file are skipped.
+type OggReader struct {
+	in io.Reader
+
+	serial    uint32
+	hasSerial bool
+
+	// partial is the beginning of a packet continued on the next page,
+	// packets are the ones of the last page not returned yet
+	partial []byte
+	packets []packet
+}
+
+// New opens an Ogg file and reads its Opus headers
+func New(fileName string) (*OggReader, *OggHeader, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r, header, err := NewWith(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, err
+	}
+	return r, header, nil
+}
+
+// NewWith reads the Opus headers of an Ogg stream from in, in is closed on
+// Close if it is an io.Closer
+func NewWith(in io.Reader) (*OggReader, *OggHeader, error) {
+	if in == nil {
+		return nil, nil, errors.New("stream is nil")
+	}
+	r := &OggReader{in: in}
+
+	head, err := r.nextPacket()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(head.data) < opusHeadSize || string(head.data[:8]) != "OpusHead" {
+		return nil, nil, errNotOpus
+	}
+	header := &OggHeader{
+		Version:    head.data[8],
+		Channels:   head.data[9],
+		PreSkip:    binary.LittleEndian.Uint16(head.data[10:]),
+		SampleRate: binary.LittleEndian.Uint32(head.data[12:]),
+		OutputGain: binary.LittleEndian.Uint16(head.data[16:]),
+		ChannelMap: head.data[18],
+	}
+
+	// The comment header is skipped
+	tags, err := r.nextPacket()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tags.data) < 8 || string(tags.data[:8]) != "OpusTags" {
+		return nil, nil, errNotOpus
+	}
+
+	return r, header, nil
+}
+
+// NextPacket returns the next Opus packet of the stream, with the granule
+// position it starts at: the samples at 48kHz before it. io.EOF is
+// returned at the end of the file.
+func (r *OggReader) NextPacket() ([]byte, int64, error) {
+	p, err := r.nextPacket()
+	if err != nil {
+		return nil, 0, err
+	}
+	return p.data, p.granule, nil
+}
+
+// NextSample returns the next Opus packet as a sample, with its
+// presentation time, io.EOF is returned at the end of the file. The gaps
+// in the granule positions are kept in the presentation times.
+func (r *OggReader) NextSample() (media.Sample, time.Duration, error) {
+	p, err := r.nextPacket()
+	if err != nil {
+		return media.Sample{}, 0, err
+	}
+
+	sample := media.Sample{
+		Data:    p.data,
+		Samples: uint32(ogg.OpusSamples(p.data)),
+	}
+	return sample, time.Duration(p.granule) * time.Second / ogg.OpusClockRate, nil
+}
+
+func (r *OggReader) nextPacket() (packet, error) {
+	for len(r.packets) == 0 {
+		if err := r.readPackets(); err != nil {
+			return packet{}, err
+		}
+	}
+
+	p := r.packets[0]
+	r.packets = r.packets[1:]
+	return p, nil
+}
+
+// readPackets reads the next page of the stream, and the packets it ends.
+// The granule position of the page is the one at the end of its last
+// packet, the ones before are counted back from the durations of the
+// packets.
+func (r *OggReader) readPackets() error {
+	header, segments, payload, err := r.readPage()
+	if err != nil {
+		return err
+	}
+
+	if !r.hasSerial {
+		if header.headerType&ogg.PageHeaderTypeBeginningOfStream == 0 {
+			return errors.New("Ogg stream doesn't start with its first page")
+		}
+		r.serial, r.hasSerial = header.serial, true
+	} else if header.serial != r.serial {
+		return nil
+	}
+
+	// The beginning of a continued packet is lost when the page doesn't
+	// continue it, and the end of one is dropped when its beginning is
+	discard := header.headerType&ogg.PageHeaderTypeContinuation != 0 && r.partial == nil
+	if header.headerType&ogg.PageHeaderTypeContinuation == 0 {
+		r.partial = nil
+	}
+
+	var completed [][]byte
+	offset := 0
+	for _, size := range segments {
+		r.partial = append(r.partial, payload[offset:offset+int(size)]...)
+		offset += int(size)
+
+		// A segment shorter than 255 bytes ends its packet
+		if size < ogg.MaxSegmentSize {
+			if !discard {
+				completed = append(completed, r.partial)
+			}
+			discard = false
+			r.partial = nil
+		}
+	}
+
+	granule := int64(header.granulePosition)
+	packets := make([]packet, len(completed))
+	for i := len(completed) - 1; i >= 0; i-- {
+		granule -= int64(ogg.OpusSamples(completed[i]))
+		packets[i] = packet{data: completed[i], granule: granule}
+	}
+	r.packets = packets
+	return nil
+}
+
+func (r *OggReader) readPage() (*pageHeader, []byte, []byte, error) {
+	b := make([]byte, ogg.PageHeaderSize)
+	if _, err := io.ReadFull(r.in, b); err != nil {
+		return nil, nil, nil, err
+	}
+	if string(b[:4]) != "OggS" {
+		return nil, nil, nil, errSignature
+	}
+
+	segments := make([]byte, b[26])
+	if _, err := io.ReadFull(r.in, segments); err != nil {
+		return nil, nil, nil, unexpectedEOF(err)
+	}
+	size := 0
+	for _, s := range segments {
+		size += int(s)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r.in, payload); err != nil {
+		return nil, nil, nil, unexpectedEOF(err)
+	}
+
+	page := append(append(b, segments...), payload...)
+	if ogg.Checksum(page) != binary.LittleEndian.Uint32(b[22:]) {
+		return nil, nil, nil, errChecksum
+	}
+
+	header := &pageHeader{
+		headerType:      b[5],
+		granulePosition: binary.LittleEndian.Uint64(b[6:]),
+		serial:          binary.LittleEndian.Uint32(b[14:]),
+	}
+	return header, segments, payload, nil
+}
+
+// unexpectedEOF reports the end of the stream in the middle of a page
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Close closes the file
+func (r *OggReader) Close() error {
+	if closer, ok := r.in.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package oggreader
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pions/rtp"
+	"github.com/pions/webrtc/pkg/media/internal/ogg"
+	"github.com/pions/webrtc/pkg/media/oggwriter"
+	"github.com/stretchr/testify/assert"
+)
+
+// buildPage returns a page carrying the given segments of payload
+func buildPage(headerType uint8, granule uint64, serial uint32, segments []byte, payload []byte) []byte {
+	page := make([]byte, ogg.PageHeaderSize)
+	copy(page, "OggS")
+	page[5] = headerType
+	binary.LittleEndian.PutUint64(page[6:], granule)
+	binary.LittleEndian.PutUint32(page[14:], serial)
+	page[26] = uint8(len(segments))
+	page = append(append(page, segments...), payload...)
+	ogg.SetChecksum(page)
+	return page
+}
+
+func opusHeaders(serial uint32) []byte {
+	head := make([]byte, opusHeadSize)
+	copy(head, "OpusHead")
+	head[8] = 1
+	head[9] = 2
+	binary.LittleEndian.PutUint32(head[12:], 48000)
+
+	tags := append([]byte("OpusTags"), make([]byte, 8)...)
+	return append(
+		buildPage(ogg.PageHeaderTypeBeginningOfStream, 0, serial, []byte{opusHeadSize}, head),
+		buildPage(0, 0, serial, []byte{uint8(len(tags))}, tags)...,
+	)
+}
+
+func TestOggReader(t *testing.T) {
+	var file bytes.Buffer
+	w, err := oggwriter.NewWith(&file, 44100, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 20ms CELT packets, the second one is lost
+	large := append([]byte{0xF8}, bytes.Repeat([]byte{0xAA}, 300)...)
+	assert.NoError(t, w.WriteRTP(&rtp.Packet{Header: rtp.Header{Timestamp: 1000}, Payload: []byte{0xF8, 0x01}}))
+	assert.NoError(t, w.WriteRTP(&rtp.Packet{Header: rtp.Header{Timestamp: 1000 + 2*960}, Payload: large}))
+	assert.NoError(t, w.Close())
+
+	r, header, err := NewWith(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, uint8(2), header.Channels)
+	assert.Equal(t, uint32(44100), header.SampleRate)
+
+	data, granule, err := r.NextPacket()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xF8, 0x01}, data)
+	assert.Equal(t, int64(0), granule)
+
+	// The gap of the lost packet is kept
+	sample, pts, err := r.NextSample()
+	assert.NoError(t, err)
+	assert.Equal(t, large, sample.Data)
+	assert.Equal(t, uint32(960), sample.Samples)
+	assert.Equal(t, 40*time.Millisecond, pts)
+
+	_, _, err = r.NextSample()
+	assert.Equal(t, io.EOF, err)
+	assert.NoError(t, r.Close())
+}
+
+func TestOggReader_Pages(t *testing.T) {
+	const serial = 5678
+
+	continued := append([]byte{0xF8}, bytes.Repeat([]byte{0xAA}, 300)...)
+	file := opusHeaders(serial)
+
+	// Two packets on a page, the second one continued on the next
+	file = append(file, buildPage(0, 960, serial, []byte{1, 255}, append([]byte{0xF8}, continued[:255]...))...)
+
+	// A page of another stream is skipped
+	file = append(file, buildPage(ogg.PageHeaderTypeBeginningOfStream, 0, serial+1, []byte{1}, []byte{0x00})...)
+
+	file = append(file, buildPage(ogg.PageHeaderTypeContinuation, 3*960, serial, []byte{46, 1}, append(continued[255:], 0xF8))...)
+
+	r, _, err := NewWith(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []struct {
+		data    []byte
+		granule int64
+	}{
+		{[]byte{0xF8}, 0},
+		{continued, 960},
+		{[]byte{0xF8}, 2 * 960},
+	} {
+		data, granule, packetErr := r.NextPacket()
+		assert.NoError(t, packetErr)
+		assert.Equal(t, expected.data, data)
+		assert.Equal(t, expected.granule, granule)
+	}
+
+	_, _, err = r.NextPacket()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestOggReader_Invalid(t *testing.T) {
+	headers := opusHeaders(1)
+
+	_, _, err := NewWith(bytes.NewReader(headers[:10]))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	corrupted := append([]byte{}, headers...)
+	corrupted[ogg.PageHeaderSize+1] = 'X'
+	_, _, err = NewWith(bytes.NewReader(corrupted))
+	assert.Equal(t, errChecksum, err)
+
+	_, _, err = NewWith(bytes.NewReader(append([]byte("Ogg"), headers...)))
+	assert.Equal(t, errSignature, err)
+
+	notOpus := buildPage(ogg.PageHeaderTypeBeginningOfStream, 0, 1, []byte{8}, []byte("OpusTags"))
+	_, _, err = NewWith(bytes.NewReader(notOpus))
+	assert.Equal(t, errNotOpus, err)
+}
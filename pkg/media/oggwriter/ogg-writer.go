@@ -0,0 +1,208 @@
+// Package oggwriter writes the Opus packets of RTP packets to Ogg files
+package oggwriter
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+
+	"github.com/pions/rtp"
+	"github.com/pions/webrtc/pkg/media/internal/ogg"
+)
+
+const vendor = "pions-webrtc"
+
+var errClosed = errors.New("OggWriter is closed")
+
+// OggWriter is used to take the RTP packets of an Opus stream and write
+// them to an Ogg file on disk (RFC 7845), a page per packet. The granule
+// positions follow the RTP timestamps, the time of the lost packets stays
+// a gap in the stream. The last page is written on Close, ending the
+// stream.
+type OggWriter struct {
+	out          io.Writer
+	serial       uint32
+	pageSequence uint32
+
+	// The RTP timestamps are unwrapped into samples since the first packet,
+	// the Opus ones are counted at 48kHz whatever the sample rate
+	started       bool
+	lastTimestamp uint32
+	samples       int64
+
+	// pending is the page of the last packet, written once the next one
+	// tells if it ends the stream
+	pending []byte
+
+	closed bool
+}
+
+// New builds a new Ogg Opus writer, sampleRate is the rate of the audio
+// before it was encoded
+func New(fileName string, sampleRate uint32, channelCount uint16) (*OggWriter, error) {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := NewWith(f, sampleRate, channelCount)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// NewWith builds a new Ogg Opus writer writing to out, out is closed on
+// Close if it is an io.Closer
+func NewWith(out io.Writer, sampleRate uint32, channelCount uint16) (*OggWriter, error) {
+	if out == nil {
+		return nil, errors.New("file not opened")
+	}
+	if channelCount == 0 || channelCount > 2 {
+		return nil, errors.New("the Ogg Opus writer only supports mono and stereo")
+	}
+
+	w := &OggWriter{
+		out:    out,
+		serial: rand.Uint32(),
+	}
+
+	// The identification header
+	// https://tools.ietf.org/html/rfc7845#section-5.1
+	head := make([]byte, 19)
+	copy(head[0:], "OpusHead")
+	head[8] = 1                                          // Version
+	head[9] = uint8(channelCount)                        // Channel count
+	binary.LittleEndian.PutUint16(head[10:], 0)          // Pre-skip
+	binary.LittleEndian.PutUint32(head[12:], sampleRate) // Input sample rate
+	binary.LittleEndian.PutUint16(head[16:], 0)          // Output gain
+	head[18] = 0                                         // Channel mapping family
+	if err := w.writePage(head, ogg.PageHeaderTypeBeginningOfStream, 0); err != nil {
+		return nil, err
+	}
+
+	// The comment header
+	// https://tools.ietf.org/html/rfc7845#section-5.2
+	tags := make([]byte, 8+4+len(vendor)+4)
+	copy(tags[0:], "OpusTags")
+	binary.LittleEndian.PutUint32(tags[8:], uint32(len(vendor))) // Vendor string length
+	copy(tags[12:], vendor)                                      // Vendor string
+	binary.LittleEndian.PutUint32(tags[12+len(vendor):], 0)      // User comment list length
+	if err := w.writePage(tags, 0, 0); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// WriteRTP adds the Opus packet of a RTP packet to the file
+func (w *OggWriter) WriteRTP(packet *rtp.Packet) error {
+	if w.closed {
+		return errClosed
+	}
+	if len(packet.Payload) == 0 {
+		return nil
+	}
+
+	if !w.started {
+		w.started = true
+		w.lastTimestamp = packet.Timestamp
+	}
+	w.samples += int64(int32(packet.Timestamp - w.lastTimestamp))
+	w.lastTimestamp = packet.Timestamp
+
+	// The granule position counts the samples up to the end of the page
+	granule := w.samples + int64(ogg.OpusSamples(packet.Payload))
+	if granule < 0 {
+		return nil
+	}
+
+	page, err := w.page(packet.Payload, 0, uint64(granule))
+	if err != nil {
+		return err
+	}
+	if err := w.flush(); err != nil {
+		return err
+	}
+	w.pending = page
+	return nil
+}
+
+// Close writes the last page, ending the stream, and closes the file
+func (w *OggWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	var err error
+	if w.pending == nil {
+		err = w.writePage(nil, ogg.PageHeaderTypeEndOfStream, uint64(w.samples))
+	} else {
+		w.pending[5] |= ogg.PageHeaderTypeEndOfStream
+		ogg.SetChecksum(w.pending)
+		err = w.flush()
+	}
+
+	if closer, ok := w.out.(io.Closer); ok {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+func (w *OggWriter) flush() error {
+	if w.pending == nil {
+		return nil
+	}
+	_, err := w.out.Write(w.pending)
+	w.pending = nil
+	return err
+}
+
+func (w *OggWriter) writePage(payload []byte, headerType uint8, granule uint64) error {
+	page, err := w.page(payload, headerType, granule)
+	if err != nil {
+		return err
+	}
+	_, err = w.out.Write(page)
+	return err
+}
+
+// page builds the page of a packet
+// https://tools.ietf.org/html/rfc3533#section-6
+func (w *OggWriter) page(payload []byte, headerType uint8, granule uint64) ([]byte, error) {
+	// The size of the packet is laced in segments of 255 bytes, a shorter
+	// one ends it. The page ending an empty stream has no packet.
+	segments := 0
+	if payload != nil {
+		segments = len(payload)/ogg.MaxSegmentSize + 1
+	}
+	if segments > ogg.MaxSegments {
+		return nil, errors.New("packet is too large for an Ogg page")
+	}
+
+	page := make([]byte, ogg.PageHeaderSize+segments+len(payload))
+	copy(page[0:], "OggS")                                   // Capture pattern
+	page[4] = 0                                              // Version
+	page[5] = headerType                                     // Header type
+	binary.LittleEndian.PutUint64(page[6:], granule)         // Granule position
+	binary.LittleEndian.PutUint32(page[14:], w.serial)       // Bitstream serial number
+	binary.LittleEndian.PutUint32(page[18:], w.pageSequence) // Page sequence number
+	page[26] = uint8(segments)                               // Page segments
+	for i := 0; i < segments; i++ {
+		page[ogg.PageHeaderSize+i] = ogg.MaxSegmentSize
+	}
+	if segments != 0 {
+		page[ogg.PageHeaderSize+segments-1] = uint8(len(payload) % ogg.MaxSegmentSize)
+	}
+	copy(page[ogg.PageHeaderSize+segments:], payload)
+
+	w.pageSequence++
+	ogg.SetChecksum(page)
+	return page, nil
+}
@@ -0,0 +1,102 @@
+package oggwriter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/pions/rtp"
+	"github.com/pions/webrtc/pkg/media/internal/ogg"
+	"github.com/stretchr/testify/assert"
+)
+
+type page struct {
+	headerType uint8
+	granule    uint64
+	sequence   uint32
+	payload    []byte
+}
+
+func readPages(t *testing.T, b []byte) []page {
+	var pages []page
+	for len(b) != 0 {
+		if !assert.True(t, len(b) >= ogg.PageHeaderSize) || !assert.Equal(t, "OggS", string(b[:4])) {
+			return nil
+		}
+		segments := int(b[26])
+		size := 0
+		for _, s := range b[ogg.PageHeaderSize : ogg.PageHeaderSize+segments] {
+			size += int(s)
+		}
+		raw := b[:ogg.PageHeaderSize+segments+size]
+		b = b[len(raw):]
+
+		assert.Equal(t, ogg.Checksum(raw), binary.LittleEndian.Uint32(raw[22:]))
+
+		pages = append(pages, page{
+			headerType: raw[5],
+			granule:    binary.LittleEndian.Uint64(raw[6:]),
+			sequence:   binary.LittleEndian.Uint32(raw[18:]),
+			payload:    raw[ogg.PageHeaderSize+segments:],
+		})
+	}
+	return pages
+}
+
+func TestOggWriter(t *testing.T) {
+	var out bytes.Buffer
+	w, err := NewWith(&out, 48000, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 20ms CELT packets, the second one is lost
+	large := append([]byte{0xF8}, bytes.Repeat([]byte{0xAA}, 300)...)
+	assert.NoError(t, w.WriteRTP(&rtp.Packet{Header: rtp.Header{Timestamp: 0xFFFFFFFF - 959}, Payload: []byte{0xF8, 0x01}}))
+	assert.NoError(t, w.WriteRTP(&rtp.Packet{Header: rtp.Header{Timestamp: 960}, Payload: large}))
+	assert.NoError(t, w.Close())
+	assert.Error(t, w.WriteRTP(&rtp.Packet{Payload: []byte{0xF8}}))
+
+	pages := readPages(t, out.Bytes())
+	if !assert.Len(t, pages, 4) {
+		return
+	}
+
+	assert.Equal(t, uint8(ogg.PageHeaderTypeBeginningOfStream), pages[0].headerType)
+	assert.Equal(t, "OpusHead", string(pages[0].payload[:8]))
+	assert.Equal(t, uint8(2), pages[0].payload[9])
+	assert.Equal(t, uint32(48000), binary.LittleEndian.Uint32(pages[0].payload[12:]))
+	assert.Equal(t, "OpusTags", string(pages[1].payload[:8]))
+
+	assert.Equal(t, uint64(960), pages[2].granule)
+	assert.Equal(t, []byte{0xF8, 0x01}, pages[2].payload)
+	assert.Equal(t, uint8(0), pages[2].headerType)
+
+	// The gap of the lost packet is kept, the last page ends the stream
+	assert.Equal(t, uint64(3*960), pages[3].granule)
+	assert.Equal(t, large, pages[3].payload)
+	assert.Equal(t, uint8(ogg.PageHeaderTypeEndOfStream), pages[3].headerType)
+
+	for i, p := range pages {
+		assert.Equal(t, uint32(i), p.sequence)
+	}
+}
+
+func TestOggWriter_Empty(t *testing.T) {
+	var out bytes.Buffer
+	w, err := NewWith(&out, 48000, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, w.Close())
+
+	pages := readPages(t, out.Bytes())
+	if !assert.Len(t, pages, 3) {
+		return
+	}
+	assert.Equal(t, uint8(ogg.PageHeaderTypeEndOfStream), pages[2].headerType)
+	assert.Empty(t, pages[2].payload)
+
+	_, err = NewWith(&out, 48000, 3)
+	assert.Error(t, err)
+}
@@ -0,0 +1,115 @@
+package webmwriter
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// The EBML and Matroska element IDs, their marker bits included
+// https://www.matroska.org/technical/specs/index.html
+const (
+	idEBML               = 0x1A45DFA3
+	idEBMLVersion        = 0x4286
+	idEBMLReadVersion    = 0x42F7
+	idEBMLMaxIDLength    = 0x42F2
+	idEBMLMaxSizeLength  = 0x42F3
+	idDocType            = 0x4282
+	idDocTypeVersion     = 0x4287
+	idDocTypeReadVersion = 0x4285
+
+	idSegment       = 0x18538067
+	idInfo          = 0x1549A966
+	idTimecodeScale = 0x2AD7B1
+	idMuxingApp     = 0x4D80
+	idWritingApp    = 0x5741
+
+	idTracks            = 0x1654AE6B
+	idTrackEntry        = 0xAE
+	idTrackNumber       = 0xD7
+	idTrackUID          = 0x73C5
+	idTrackType         = 0x83
+	idCodecID           = 0x86
+	idCodecPrivate      = 0x63A2
+	idSeekPreRoll       = 0x56BB
+	idVideo             = 0xE0
+	idPixelWidth        = 0xB0
+	idPixelHeight       = 0xBA
+	idAudio             = 0xE1
+	idSamplingFrequency = 0xB5
+	idChannels          = 0x9F
+
+	idCluster     = 0x1F43B675
+	idTimecode    = 0xE7
+	idSimpleBlock = 0xA3
+)
+
+// unknownSize is the size of the elements written before their content is
+// known, the Segment and the Clusters
+var unknownSize = []byte{0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+
+// encodeID returns the bytes of an element ID, its marker bits tell its
+// length
+func encodeID(id uint32) []byte {
+	switch {
+	case id > 0xFFFFFF:
+		return []byte{byte(id >> 24), byte(id >> 16), byte(id >> 8), byte(id)}
+	case id > 0xFFFF:
+		return []byte{byte(id >> 16), byte(id >> 8), byte(id)}
+	case id > 0xFF:
+		return []byte{byte(id >> 8), byte(id)}
+	default:
+		return []byte{byte(id)}
+	}
+}
+
+// encodeSize returns the shortest variable size integer of size, the
+// values with all their bits set are reserved
+func encodeSize(size uint64) []byte {
+	length := 1
+	for ; length < 8 && size >= 1<<(7*uint(length))-1; length++ {
+	}
+
+	b := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		b[i] = byte(size)
+		size >>= 8
+	}
+	b[0] |= 0x80 >> uint(length-1)
+	return b
+}
+
+func element(id uint32, data ...[]byte) []byte {
+	var size int
+	for _, d := range data {
+		size += len(d)
+	}
+
+	b := append(encodeID(id), encodeSize(uint64(size))...)
+	for _, d := range data {
+		b = append(b, d...)
+	}
+	return b
+}
+
+func uintElement(id uint32, v uint64) []byte {
+	length := 1
+	for ; length < 8 && v >= 1<<(8*uint(length)); length++ {
+	}
+
+	b := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+	return element(id, b)
+}
+
+func floatElement(id uint32, v float64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, math.Float64bits(v))
+	return element(id, b)
+}
+
+func stringElement(id uint32, v string) []byte {
+	return element(id, []byte(v))
+}
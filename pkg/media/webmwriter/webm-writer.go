@@ -0,0 +1,281 @@
+// Package webmwriter writes the VP8 video and Opus audio of RTP packets to
+// WebM files
+package webmwriter
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/pions/rtp"
+	"github.com/pions/webrtc/pkg/media/internal/vp8"
+)
+
+const (
+	videoTrackNumber = 1
+	audioTrackNumber = 2
+
+	videoClockRate = 90000
+	opusClockRate  = 48000
+
+	// The timecodes are in milliseconds
+	timecodeScale = 1000000
+
+	// opusSeekPreRoll is the audio decoded before a position it seeks to,
+	// in nanoseconds
+	opusSeekPreRoll = 80000000
+
+	simpleBlockKeyframe = 0x80
+
+	muxingApp = "pions-webrtc"
+)
+
+var errClosed = errors.New("WebMWriter is closed")
+
+// Config are the tracks of a WebM file
+type Config struct {
+	// Video adds a VP8 track, Width and Height are the size announced for
+	// it
+	Video         bool
+	Width, Height uint16
+
+	// Audio adds an Opus track, with AudioChannels channels
+	Audio         bool
+	AudioChannels uint16
+}
+
+// WebMWriter is used to take the RTP packets of a VP8 and an Opus stream
+// and write them to a WebM file on disk. The video packets are expected in
+// order, a frame missing a packet is dropped along the following ones until
+// a keyframe, and the video starts with a keyframe; a Cluster starts with
+// each keyframe.
+//
+// The time of the frames follows their RTP timestamps, the tracks are
+// aligned with the arrival of their first packet. The Segment and the
+// Clusters are written with an unknown size, like a live stream, the file
+// has no Cues.
+type WebMWriter struct {
+	out    io.Writer
+	config Config
+
+	start      time.Time
+	video      track
+	audio      track
+	assembler  *vp8.Assembler
+	cluster    int64
+	hasCluster bool
+
+	now func() time.Time
+
+	closed bool
+}
+
+// track unwraps the RTP timestamps of a track into a time since its first
+// packet, counted at clockRate
+type track struct {
+	clockRate     int64
+	started       bool
+	offset        time.Duration
+	lastTimestamp uint32
+	ticks         int64
+}
+
+// New builds a new WebM writer
+func New(fileName string, config Config) (*WebMWriter, error) {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := NewWith(f, config)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// NewWith builds a new WebM writer writing to out, out is closed on Close
+// if it is an io.Closer
+func NewWith(out io.Writer, config Config) (*WebMWriter, error) {
+	if out == nil {
+		return nil, errors.New("file not opened")
+	}
+	if !config.Video && !config.Audio {
+		return nil, errors.New("the WebM writer needs a track")
+	}
+	if config.Audio && (config.AudioChannels == 0 || config.AudioChannels > 2) {
+		return nil, errors.New("the WebM writer only supports mono and stereo")
+	}
+
+	w := &WebMWriter{
+		out:       out,
+		config:    config,
+		video:     track{clockRate: videoClockRate},
+		audio:     track{clockRate: opusClockRate},
+		assembler: vp8.NewAssembler(),
+		now:       time.Now,
+	}
+	if _, err := out.Write(w.header()); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// header returns the EBML header, and the beginning of the Segment up to
+// its Clusters
+func (w *WebMWriter) header() []byte {
+	header := element(idEBML,
+		uintElement(idEBMLVersion, 1),
+		uintElement(idEBMLReadVersion, 1),
+		uintElement(idEBMLMaxIDLength, 4),
+		uintElement(idEBMLMaxSizeLength, 8),
+		stringElement(idDocType, "webm"),
+		uintElement(idDocTypeVersion, 4),
+		uintElement(idDocTypeReadVersion, 2),
+	)
+
+	info := element(idInfo,
+		uintElement(idTimecodeScale, timecodeScale),
+		stringElement(idMuxingApp, muxingApp),
+		stringElement(idWritingApp, muxingApp),
+	)
+
+	var entries [][]byte
+	if w.config.Video {
+		entries = append(entries, element(idTrackEntry,
+			uintElement(idTrackNumber, videoTrackNumber),
+			uintElement(idTrackUID, videoTrackNumber),
+			uintElement(idTrackType, 1),
+			stringElement(idCodecID, "V_VP8"),
+			element(idVideo,
+				uintElement(idPixelWidth, uint64(w.config.Width)),
+				uintElement(idPixelHeight, uint64(w.config.Height)),
+			),
+		))
+	}
+	if w.config.Audio {
+		entries = append(entries, element(idTrackEntry,
+			uintElement(idTrackNumber, audioTrackNumber),
+			uintElement(idTrackUID, audioTrackNumber),
+			uintElement(idTrackType, 2),
+			stringElement(idCodecID, "A_OPUS"),
+			element(idCodecPrivate, opusHead(w.config.AudioChannels)),
+			uintElement(idSeekPreRoll, opusSeekPreRoll),
+			element(idAudio,
+				floatElement(idSamplingFrequency, opusClockRate),
+				uintElement(idChannels, uint64(w.config.AudioChannels)),
+			),
+		))
+	}
+
+	header = append(header, encodeID(idSegment)...)
+	header = append(header, unknownSize...)
+	header = append(header, info...)
+	return append(header, element(idTracks, entries...)...)
+}
+
+// opusHead returns the identification header of Opus, the CodecPrivate of
+// the track
+// https://tools.ietf.org/html/rfc7845#section-5.1
+func opusHead(channels uint16) []byte {
+	head := make([]byte, 19)
+	copy(head[0:], "OpusHead")
+	head[8] = 1                                             // Version
+	head[9] = uint8(channels)                               // Channel count
+	binary.LittleEndian.PutUint32(head[12:], opusClockRate) // Input sample rate
+	return head
+}
+
+// WriteVideoRTP adds a RTP packet of the VP8 stream, the frame it ends is
+// written to the file
+func (w *WebMWriter) WriteVideoRTP(packet *rtp.Packet) error {
+	if w.closed {
+		return errClosed
+	}
+	if !w.config.Video {
+		return errors.New("the WebM file has no video track")
+	}
+
+	frame, keyframe, err := w.assembler.Push(packet)
+	if err != nil || frame == nil {
+		return err
+	}
+	return w.writeBlock(videoTrackNumber, w.timecode(&w.video, packet.Timestamp), keyframe, frame)
+}
+
+// WriteAudioRTP adds the Opus packet of a RTP packet to the file
+func (w *WebMWriter) WriteAudioRTP(packet *rtp.Packet) error {
+	if w.closed {
+		return errClosed
+	}
+	if !w.config.Audio {
+		return errors.New("the WebM file has no audio track")
+	}
+	if len(packet.Payload) == 0 {
+		return nil
+	}
+	return w.writeBlock(audioTrackNumber, w.timecode(&w.audio, packet.Timestamp), true, packet.Payload)
+}
+
+// timecode returns the time of a RTP timestamp of a track in milliseconds
+// since the first packet written
+func (w *WebMWriter) timecode(t *track, timestamp uint32) int64 {
+	if w.start.IsZero() {
+		w.start = w.now()
+	}
+	if !t.started {
+		t.started = true
+		t.offset = w.now().Sub(w.start)
+		t.lastTimestamp = timestamp
+	}
+	t.ticks += int64(int32(timestamp - t.lastTimestamp))
+	t.lastTimestamp = timestamp
+
+	return t.offset.Nanoseconds()/timecodeScale + t.ticks*1000/t.clockRate
+}
+
+// writeBlock writes a frame in a SimpleBlock, in a new Cluster for a video
+// keyframe or when the timecode is out of reach of the current one
+func (w *WebMWriter) writeBlock(trackNumber uint64, timecode int64, keyframe bool, frame []byte) error {
+	relative := timecode - w.cluster
+	if !w.hasCluster ||
+		(keyframe && trackNumber == videoTrackNumber) ||
+		relative > math.MaxInt16 || relative < math.MinInt16 {
+		if timecode < 0 {
+			timecode = 0
+		}
+		cluster := append(encodeID(idCluster), unknownSize...)
+		cluster = append(cluster, uintElement(idTimecode, uint64(timecode))...)
+		if _, err := w.out.Write(cluster); err != nil {
+			return err
+		}
+		w.cluster, w.hasCluster = timecode, true
+		relative = 0
+	}
+
+	block := make([]byte, 4, 4+len(frame))
+	block[0] = 0x80 | byte(trackNumber) // Track number as a variable size integer
+	binary.BigEndian.PutUint16(block[1:], uint16(int16(relative)))
+	if keyframe {
+		block[3] = simpleBlockKeyframe
+	}
+	_, err := w.out.Write(element(idSimpleBlock, append(block, frame...)))
+	return err
+}
+
+// Close closes the file
+func (w *WebMWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if closer, ok := w.out.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package webmwriter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/pions/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+type ebmlElement struct {
+	id   uint32
+	data []byte
+}
+
+// readElements reads the elements of b in order, the children of the
+// elements of unknown size and of the master elements in masters follow
+// them
+func readElements(t *testing.T, b []byte, masters map[uint32]bool) []ebmlElement {
+	var elements []ebmlElement
+	for len(b) != 0 {
+		idLength := 1
+		for ; idLength <= 4 && b[0]&(0x80>>uint(idLength-1)) == 0; idLength++ {
+		}
+		var id uint32
+		for _, c := range b[:idLength] {
+			id = id<<8 | uint32(c)
+		}
+		b = b[idLength:]
+
+		if bytes.HasPrefix(b, unknownSize) {
+			elements = append(elements, ebmlElement{id: id})
+			b = b[len(unknownSize):]
+			continue
+		}
+		sizeLength := 1
+		for ; sizeLength <= 8 && b[0]&(0x80>>uint(sizeLength-1)) == 0; sizeLength++ {
+		}
+		size := uint64(b[0] & (0xFF >> uint(sizeLength)))
+		for _, c := range b[1:sizeLength] {
+			size = size<<8 | uint64(c)
+		}
+		b = b[sizeLength:]
+		if !assert.True(t, uint64(len(b)) >= size) {
+			return nil
+		}
+
+		if masters[id] {
+			elements = append(elements, ebmlElement{id: id})
+			elements = append(elements, readElements(t, b[:size], masters)...)
+		} else {
+			elements = append(elements, ebmlElement{id: id, data: b[:size]})
+		}
+		b = b[size:]
+	}
+	return elements
+}
+
+func TestEncodeSize(t *testing.T) {
+	assert.Equal(t, []byte{0x80}, encodeSize(0))
+	assert.Equal(t, []byte{0xFE}, encodeSize(126))
+	assert.Equal(t, []byte{0x40, 0x7F}, encodeSize(127))
+	assert.Equal(t, []byte{0x20, 0x40, 0x00}, encodeSize(0x4000))
+	assert.Equal(t, []byte{0xD7, 0x81, 0x01}, uintElement(idTrackNumber, 1))
+	assert.Equal(t, []byte{0x2A, 0xD7, 0xB1, 0x83, 0x0F, 0x42, 0x40}, uintElement(idTimecodeScale, timecodeScale))
+}
+
+func TestWebMWriter(t *testing.T) {
+	var out bytes.Buffer
+	w, err := NewWith(&out, Config{Video: true, Width: 640, Height: 480, Audio: true, AudioChannels: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1000, 0)
+	w.now = func() time.Time { return now }
+
+	vp8 := func(sequenceNumber uint16, timestamp uint32, payload ...byte) *rtp.Packet {
+		return &rtp.Packet{Header: rtp.Header{SequenceNumber: sequenceNumber, Timestamp: timestamp, Marker: true}, Payload: payload}
+	}
+
+	// The audio starts 10ms before the video
+	assert.NoError(t, w.WriteAudioRTP(&rtp.Packet{Header: rtp.Header{Timestamp: 100}, Payload: []byte{0xF8, 0x01}}))
+	now = now.Add(10 * time.Millisecond)
+	assert.NoError(t, w.WriteVideoRTP(vp8(1, 5000, 0x10, 0x00, 0xAA)))
+	assert.NoError(t, w.WriteAudioRTP(&rtp.Packet{Header: rtp.Header{Timestamp: 100 + 960}, Payload: []byte{0xF8, 0x02}}))
+	assert.NoError(t, w.WriteVideoRTP(vp8(2, 5000+2700, 0x10, 0x01, 0xBB)))
+	assert.NoError(t, w.WriteVideoRTP(vp8(3, 5000+5400, 0x10, 0x00, 0xCC)))
+	assert.NoError(t, w.Close())
+	assert.Error(t, w.WriteAudioRTP(&rtp.Packet{Payload: []byte{0xF8}}))
+
+	elements := readElements(t, out.Bytes(), map[uint32]bool{
+		idEBML: true, idInfo: true, idTracks: true, idTrackEntry: true, idVideo: true, idAudio: true,
+	})
+	var ids []uint32
+	var blocks, clusters [][]byte
+	for _, e := range elements {
+		ids = append(ids, e.id)
+		switch e.id {
+		case idDocType:
+			assert.Equal(t, "webm", string(e.data))
+		case idCodecID:
+			assert.Contains(t, []string{"V_VP8", "A_OPUS"}, string(e.data))
+		case idSimpleBlock:
+			blocks = append(blocks, e.data)
+		case idTimecode:
+			clusters = append(clusters, e.data)
+		}
+	}
+	assert.Equal(t, []uint32{idEBML, idEBMLVersion, idEBMLReadVersion, idEBMLMaxIDLength, idEBMLMaxSizeLength, idDocType, idDocTypeVersion, idDocTypeReadVersion,
+		idSegment,
+		idInfo, idTimecodeScale, idMuxingApp, idWritingApp,
+		idTracks,
+		idTrackEntry, idTrackNumber, idTrackUID, idTrackType, idCodecID, idVideo, idPixelWidth, idPixelHeight,
+		idTrackEntry, idTrackNumber, idTrackUID, idTrackType, idCodecID, idCodecPrivate, idSeekPreRoll, idAudio, idSamplingFrequency, idChannels,
+		idCluster, idTimecode, idSimpleBlock,
+		idCluster, idTimecode, idSimpleBlock, idSimpleBlock, idSimpleBlock,
+		idCluster, idTimecode, idSimpleBlock,
+	}, ids)
+
+	// A Cluster starts with the first block and each keyframe
+	assert.Equal(t, [][]byte{{0}, {10}, {70}}, clusters)
+
+	block := func(track byte, timecode int16, flags byte, frame ...byte) []byte {
+		b := []byte{0x80 | track, 0, 0, flags}
+		binary.BigEndian.PutUint16(b[1:], uint16(timecode))
+		return append(b, frame...)
+	}
+	assert.Equal(t, [][]byte{
+		block(audioTrackNumber, 0, simpleBlockKeyframe, 0xF8, 0x01),
+		block(videoTrackNumber, 0, simpleBlockKeyframe, 0x00, 0xAA),
+		block(audioTrackNumber, 10, simpleBlockKeyframe, 0xF8, 0x02),
+		block(videoTrackNumber, 30, 0, 0x01, 0xBB),
+		block(videoTrackNumber, 0, simpleBlockKeyframe, 0x00, 0xCC),
+	}, blocks)
+}
+
+func TestWebMWriter_Config(t *testing.T) {
+	var out bytes.Buffer
+	_, err := NewWith(&out, Config{})
+	assert.Error(t, err)
+	_, err = NewWith(&out, Config{Audio: true})
+	assert.Error(t, err)
+
+	w, err := NewWith(&out, Config{Video: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Error(t, w.WriteAudioRTP(&rtp.Packet{Payload: []byte{0xF8}}))
+	assert.NoError(t, w.WriteVideoRTP(&rtp.Packet{Header: rtp.Header{Marker: true}, Payload: []byte{0x10, 0x00}}))
+	assert.NoError(t, w.Close())
+}
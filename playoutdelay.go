@@ -0,0 +1,70 @@
+package webrtc
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// playoutDelayGranularity is the unit of the values carried by the extension
+	playoutDelayGranularity = 10 * time.Millisecond
+
+	// playoutDelayMax is the largest delay that fits in the 12 bits of the extension
+	playoutDelayMax = 0xFFF * playoutDelayGranularity
+)
+
+// PlayoutDelay is the range of delay the receiver is hinted to render the
+// media with, as carried by the playout-delay header extension
+// http://www.webrtc.org/experiments/rtp-hdrext/playout-delay
+type PlayoutDelay struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+func clampPlayoutDelay(d time.Duration) time.Duration {
+	switch {
+	case d < 0:
+		return 0
+	case d > playoutDelayMax:
+		return playoutDelayMax
+	}
+	return d
+}
+
+// newPlayoutDelay creates a PlayoutDelay with both values clamped to the
+// range the extension can carry
+func newPlayoutDelay(min, max time.Duration) PlayoutDelay {
+	p := PlayoutDelay{
+		Min: clampPlayoutDelay(min),
+		Max: clampPlayoutDelay(max),
+	}
+	if p.Max < p.Min {
+		p.Max = p.Min
+	}
+	return p
+}
+
+// The extension is laid out as a 12-bit minimum followed by a 12-bit maximum
+func (p PlayoutDelay) marshal() []byte {
+	min := uint16(p.Min / playoutDelayGranularity)
+	max := uint16(p.Max / playoutDelayGranularity)
+	return []byte{
+		byte(min >> 4),
+		byte(min<<4) | byte(max>>8),
+		byte(max),
+	}
+}
+
+func unmarshalPlayoutDelay(payload []byte) (PlayoutDelay, error) {
+	if len(payload) != 3 {
+		return PlayoutDelay{}, errors.Errorf("invalid playout delay extension size %d", len(payload))
+	}
+
+	min := uint16(payload[0])<<4 | uint16(payload[1]>>4)
+	max := uint16(payload[1]&0x0F)<<8 | uint16(payload[2])
+	return PlayoutDelay{
+		Min: time.Duration(min) * playoutDelayGranularity,
+		Max: time.Duration(max) * playoutDelayGranularity,
+	}, nil
+}
@@ -0,0 +1,45 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlayoutDelay_Marshal(t *testing.T) {
+	testCases := []struct {
+		delay    PlayoutDelay
+		expected []byte
+	}{
+		{PlayoutDelay{}, []byte{0x00, 0x00, 0x00}},
+		{PlayoutDelay{Min: 100 * time.Millisecond, Max: 200 * time.Millisecond}, []byte{0x00, 0xA0, 0x14}},
+		{PlayoutDelay{Min: playoutDelayMax, Max: playoutDelayMax}, []byte{0xFF, 0xFF, 0xFF}},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t, testCase.expected, testCase.delay.marshal(), "testCase: %d %v", i, testCase)
+
+		p, err := unmarshalPlayoutDelay(testCase.expected)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.delay, p, "testCase: %d %v", i, testCase)
+	}
+
+	_, err := unmarshalPlayoutDelay([]byte{0x00})
+	assert.Error(t, err)
+}
+
+func TestPlayoutDelay_Clamp(t *testing.T) {
+	testCases := []struct {
+		min, max time.Duration
+		expected PlayoutDelay
+	}{
+		{-time.Second, time.Second, PlayoutDelay{Min: 0, Max: time.Second}},
+		{time.Second, time.Minute, PlayoutDelay{Min: time.Second, Max: playoutDelayMax}},
+		{2 * time.Second, time.Second, PlayoutDelay{Min: 2 * time.Second, Max: 2 * time.Second}},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t, testCase.expected, newPlayoutDelay(testCase.min, testCase.max), "testCase: %d %v", i, testCase)
+	}
+}
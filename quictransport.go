@@ -3,7 +3,6 @@ package webrtc
 import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
-	"crypto/rand"
 	"crypto/x509"
 	"errors"
 	"fmt"
@@ -46,11 +45,11 @@ func (api *API) NewQUICTransport(transport *ICETransport, certificates []Certifi
 			t.certificates = append(t.certificates, x509Cert)
 		}
 	} else {
-		sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		sk, err := ecdsa.GenerateKey(elliptic.P256(), api.settingEngine.randomSource())
 		if err != nil {
 			return nil, &rtcerr.UnknownError{Err: err}
 		}
-		certificate, err := GenerateCertificate(sk)
+		certificate, err := generateCertificate(sk, api.settingEngine.randomSource())
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,92 @@
+package webrtc
+
+import (
+	"time"
+)
+
+const (
+	// receiveEstimateInterval is the interval at which the receive estimate
+	// is updated with the packets that arrived in between
+	receiveEstimateInterval = 100 * time.Millisecond
+
+	// rembInterval is the longest interval between two REMB messages, a
+	// decrease of the estimate by more than rembDecreaseRatio is sent at once
+	rembInterval      = time.Second
+	rembDecreaseRatio = 0.97
+)
+
+// receiveBandwidthEstimator estimates the bitrate a stream can be received
+// at, as the remote bitrate estimator of the REMB feedback does: the
+// delay-based controller of the send side is fed with the abs-send-time of
+// the packets, or else with their RTP timestamp standing for their send
+// time, the packets of a frame being sent in a burst.
+type receiveBandwidthEstimator struct {
+	delay *delayBasedBandwidthEstimator
+
+	// The RTP timestamps are unwrapped into ticks since the first packet,
+	// counted at clockRate
+	clockRate     uint32
+	lastTimestamp uint32
+	ticks         int64
+
+	firstArrival time.Time
+	arrivals     []transportCCArrival
+	lastUpdate   time.Time
+
+	// lastREMB is the time the last REMB message was sent at, with the
+	// estimate lastREMBBitrate
+	lastREMB        time.Time
+	lastREMBBitrate int
+}
+
+func newReceiveBandwidthEstimator(min, max int) *receiveBandwidthEstimator {
+	return &receiveBandwidthEstimator{delay: newDelayBasedBandwidthEstimator(min, max)}
+}
+
+// add counts a packet of the given size, with the RTP timestamp and the clock
+// rate of its payload type, that arrived at the given time. The updated
+// estimate is returned once receiveEstimateInterval elapsed since the
+// previous one. The timestamps start over when the clock rate changes.
+func (e *receiveBandwidthEstimator) add(timestamp, clockRate uint32, size int, arrival time.Time) (int, bool) {
+	if clockRate == 0 {
+		return 0, false
+	}
+
+	if clockRate != e.clockRate {
+		e.clockRate = clockRate
+		e.ticks = 0
+		e.lastTimestamp = timestamp
+		e.firstArrival = arrival
+		e.lastUpdate = arrival
+		e.arrivals = nil
+	}
+	e.ticks += int64(int32(timestamp - e.lastTimestamp))
+	e.lastTimestamp = timestamp
+
+	rate := int64(clockRate)
+	elapsed := time.Duration(e.ticks/rate)*time.Second + time.Duration(e.ticks%rate)*time.Second/time.Duration(rate)
+	sent := e.firstArrival.Add(elapsed)
+	e.arrivals = append(e.arrivals, transportCCArrival{
+		transportCCPacket: transportCCPacket{sent: sent, size: size},
+		arrival:           arrival.Sub(e.firstArrival),
+	})
+
+	if arrival.Sub(e.lastUpdate) < receiveEstimateInterval {
+		return 0, false
+	}
+	estimate := e.delay.onFeedback(e.arrivals, arrival)
+	e.arrivals = e.arrivals[:0]
+	e.lastUpdate = arrival
+	return estimate, true
+}
+
+// dueREMB returns whether the estimate is to be sent in a REMB message now,
+// once a second or as soon as it decreased
+func (e *receiveBandwidthEstimator) dueREMB(estimate int, now time.Time) bool {
+	if !e.lastREMB.IsZero() && now.Sub(e.lastREMB) < rembInterval && float64(estimate) >= rembDecreaseRatio*float64(e.lastREMBBitrate) {
+		return false
+	}
+	e.lastREMB = now
+	e.lastREMBBitrate = estimate
+	return true
+}
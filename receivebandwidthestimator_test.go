@@ -0,0 +1,48 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceiveBandwidthEstimator(t *testing.T) {
+	// A frame of 2 packets every 33ms at 90kHz, about 480kbps
+	receive := func(e *receiveBandwidthEstimator, frames int, queueing time.Duration) int {
+		start := time.Now()
+		estimate := 0
+		for frame := 0; frame < frames; frame++ {
+			arrival := start.Add(time.Duration(frame) * 33 * time.Millisecond).Add(time.Duration(frame) * queueing)
+			for i := 0; i < 2; i++ {
+				if bps, ok := e.add(uint32(frame*2970), 90000, 1000, arrival.Add(time.Duration(i)*100*time.Microsecond)); ok {
+					estimate = bps
+				}
+			}
+		}
+		return estimate
+	}
+
+	// The estimate isn't updated without a clock rate
+	e := newReceiveBandwidthEstimator(defaultMinBitrate, defaultMaxBitrate)
+	_, ok := e.add(0, 0, 1000, time.Now())
+	assert.False(t, ok)
+
+	// A stable delay raises the estimate, a growing one holds it back
+	stable := receive(newReceiveBandwidthEstimator(defaultMinBitrate, defaultMaxBitrate), 300, 0)
+	assert.True(t, stable > defaultStartBitrate, "estimate %d should increase with a stable delay", stable)
+
+	growing := receive(newReceiveBandwidthEstimator(defaultMinBitrate, defaultMaxBitrate), 300, 5*time.Millisecond)
+	assert.True(t, growing <= defaultStartBitrate, "estimate %d shouldn't increase with a growing delay", growing)
+}
+
+func TestReceiveBandwidthEstimator_DueREMB(t *testing.T) {
+	e := newReceiveBandwidthEstimator(defaultMinBitrate, defaultMaxBitrate)
+	now := time.Now()
+
+	assert.True(t, e.dueREMB(100000, now), "the first estimate is sent")
+	assert.False(t, e.dueREMB(100000, now.Add(100*time.Millisecond)))
+	assert.False(t, e.dueREMB(98000, now.Add(200*time.Millisecond)), "a small decrease waits")
+	assert.True(t, e.dueREMB(90000, now.Add(300*time.Millisecond)), "a decrease is sent at once")
+	assert.True(t, e.dueREMB(90000, now.Add(1300*time.Millisecond)), "the estimate is sent every second")
+}
@@ -0,0 +1,33 @@
+package webrtc
+
+// ReceiveDropPolicy decides which packet is dropped when a packet of an
+// incoming stream arrives while its receive buffer is full, see
+// SettingEngine.SetReceiveDropPolicy
+type ReceiveDropPolicy int
+
+const (
+	// ReceiveDropPolicyNewest drops the packet arriving, the buffered ones
+	// are read first.
+	ReceiveDropPolicyNewest ReceiveDropPolicy = iota + 1
+
+	// ReceiveDropPolicyOldest drops the oldest buffered packet to make room
+	// for the one arriving, a slow reader skips to the latest media.
+	ReceiveDropPolicyOldest
+)
+
+// This is done this way because of a linter.
+const (
+	receiveDropPolicyNewestStr = "newest"
+	receiveDropPolicyOldestStr = "oldest"
+)
+
+func (p ReceiveDropPolicy) String() string {
+	switch p {
+	case ReceiveDropPolicyNewest:
+		return receiveDropPolicyNewestStr
+	case ReceiveDropPolicyOldest:
+		return receiveDropPolicyOldestStr
+	default:
+		return ErrUnknownType.Error()
+	}
+}
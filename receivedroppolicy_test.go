@@ -0,0 +1,26 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceiveDropPolicy_String(t *testing.T) {
+	testCases := []struct {
+		policy         ReceiveDropPolicy
+		expectedString string
+	}{
+		{ReceiveDropPolicy(Unknown), unknownStr},
+		{ReceiveDropPolicyNewest, "newest"},
+		{ReceiveDropPolicyOldest, "oldest"},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedString,
+			testCase.policy.String(),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
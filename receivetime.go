@@ -0,0 +1,100 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+)
+
+// receiveTimesSize is the number of packets of a SSRC whose receive time is
+// remembered, the older ones are looked up in vain
+const receiveTimesSize = 1024
+
+type receiveTime struct {
+	sequenceNumber uint16
+
+	// nanos is the receive time in nanoseconds since the epoch, 0 until set
+	nanos int64
+}
+
+// ssrcReceiveTimes are the receive times of the packets of a SSRC
+type ssrcReceiveTimes struct {
+	// latest is the receive time of the last packet, in nanoseconds since
+	// the epoch
+	latest int64
+
+	times [receiveTimesSize]receiveTime
+}
+
+// receiveTimes are the receive times of the last RTP packets of the remote
+// SSRCs. They are recorded by the SRTP authentication, with the timestamps of
+// the ICE transport, and looked up by sequence number once the packets are
+// parsed.
+type receiveTimes struct {
+	lock  sync.RWMutex
+	ssrcs map[uint32]*ssrcReceiveTimes
+}
+
+func newReceiveTimes() *receiveTimes {
+	return &receiveTimes{ssrcs: map[uint32]*ssrcReceiveTimes{}}
+}
+
+func (r *receiveTimes) record(ssrc uint32, sequenceNumber uint16, received time.Time) {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	times, ok := r.ssrcs[ssrc]
+	if !ok {
+		times = &ssrcReceiveTimes{}
+		r.ssrcs[ssrc] = times
+	}
+	times.times[sequenceNumber%receiveTimesSize] = receiveTime{sequenceNumber, received.UnixNano()}
+	if nanos := received.UnixNano(); nanos > times.latest {
+		times.latest = nanos
+	}
+}
+
+func (r *receiveTimes) lookup(ssrc uint32, sequenceNumber uint16) (time.Time, bool) {
+	if r == nil {
+		return time.Time{}, false
+	}
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	times, ok := r.ssrcs[ssrc]
+	if !ok {
+		return time.Time{}, false
+	}
+	t := times.times[sequenceNumber%receiveTimesSize]
+	if t.nanos == 0 || t.sequenceNumber != sequenceNumber {
+		return time.Time{}, false
+	}
+	return time.Unix(0, t.nanos), true
+}
+
+// latest returns the receive time of the last packet of each SSRC
+func (r *receiveTimes) latest() map[uint32]time.Time {
+	latest := map[uint32]time.Time{}
+	if r == nil {
+		return latest
+	}
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for ssrc, times := range r.ssrcs {
+		latest[ssrc] = time.Unix(0, times.latest)
+	}
+	return latest
+}
+
+// forget drops the receive times of a SSRC no longer received
+func (r *receiveTimes) forget(ssrc uint32) {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.ssrcs, ssrc)
+}
@@ -0,0 +1,55 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceiveTimes(t *testing.T) {
+	times := newReceiveTimes()
+	received := time.Unix(1234, 5678)
+
+	_, ok := times.lookup(1, 10)
+	assert.False(t, ok)
+
+	times.record(1, 10, received)
+	found, ok := times.lookup(1, 10)
+	assert.True(t, ok)
+	assert.True(t, found.Equal(received))
+
+	// The SSRCs and sequence numbers don't mix
+	_, ok = times.lookup(2, 10)
+	assert.False(t, ok)
+	_, ok = times.lookup(1, 11)
+	assert.False(t, ok)
+
+	// A packet is forgotten once its slot is reused
+	times.record(1, 10+receiveTimesSize, received.Add(time.Second))
+	_, ok = times.lookup(1, 10)
+	assert.False(t, ok)
+	found, ok = times.lookup(1, 10+receiveTimesSize)
+	assert.True(t, ok)
+	assert.True(t, found.Equal(received.Add(time.Second)))
+
+	// The latest is kept for each SSRC, the packets arrive out of order
+	times.record(2, 5, received)
+	times.record(2, 4, received.Add(-time.Second))
+	latest := times.latest()
+	if assert.Len(t, latest, 2) {
+		assert.True(t, latest[1].Equal(received.Add(time.Second)))
+		assert.True(t, latest[2].Equal(received))
+	}
+
+	times.forget(1)
+	_, ok = times.lookup(1, 10+receiveTimesSize)
+	assert.False(t, ok)
+
+	// The transports without one record nothing
+	var none *receiveTimes
+	none.record(1, 10, received)
+	_, ok = none.lookup(1, 10)
+	assert.False(t, ok)
+	assert.Empty(t, none.latest())
+}
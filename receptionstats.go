@@ -0,0 +1,227 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pions/rtcp"
+	"github.com/pions/rtp"
+)
+
+const (
+	// initialRTCPReportInterval is the interval of the first report of a
+	// stream, before its bitrate is known
+	initialRTCPReportInterval = time.Second
+
+	// maxRTCPReportInterval is the longest interval between two reports
+	// https://tools.ietf.org/html/rfc3550#section-6.2
+	maxRTCPReportInterval = 5 * time.Second
+
+	// maxTotalLost is the largest value of the 24-bit signed cumulative
+	// number of packets lost of a reception report
+	maxTotalLost = 1<<23 - 1
+)
+
+// receptionStats counts the packets of a received stream, the expected ones
+// that were lost and the interarrival jitter, as a receiver computes them for
+// its reception reports https://tools.ietf.org/html/rfc3550#appendix-A.3
+type receptionStats struct {
+	lock sync.Mutex
+
+	packetsReceived     uint64
+	bytesReceived       uint64
+	headerBytesReceived uint64
+	lastReceived        time.Time
+
+	// baseSequence and highestSequence are the extended sequence numbers of
+	// the lowest and highest packets received, the wraparounds are counted
+	// above the 16 bits of the sequence number
+	baseSequence    uint64
+	highestSequence uint64
+
+	// lastArrival is the arrival of the previous packet with a known clock
+	// rate, in seconds since the first one, lastTimestamp its RTP timestamp.
+	// jitter is the estimate in seconds.
+	firstReceived time.Time
+	hasTransit    bool
+	lastArrival   float64
+	lastTimestamp uint32
+	jitter        float64
+	clockRate     uint32
+
+	// lastReport is the time the previous reception report was built at,
+	// the counters of the packets are the ones it reported
+	lastReport     time.Time
+	reportInterval time.Duration
+	expectedPrior  uint64
+	receivedPrior  uint64
+	bytesPrior     uint64
+}
+
+// add counts a packet received at the given time. The jitter is only
+// estimated with the clock rate of the payload type of the packet, 0 when it
+// is unknown.
+func (s *receptionStats) add(packet *rtp.Packet, received time.Time, clockRate uint32) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// The extended numbers start one cycle in, the packets reordered before
+	// the first one don't wrap below 0
+	if s.packetsReceived == 0 {
+		s.baseSequence = 1<<16 | uint64(packet.SequenceNumber)
+		s.highestSequence = s.baseSequence
+		s.firstReceived = received
+	}
+	sequence := uint64(int64(s.highestSequence) + int64(int16(packet.SequenceNumber-uint16(s.highestSequence))))
+	if sequence > s.highestSequence {
+		s.highestSequence = sequence
+	}
+	if sequence < s.baseSequence {
+		s.baseSequence = sequence
+	}
+
+	padding := paddingSize(&packet.Header, packet.Payload)
+	s.packetsReceived++
+	s.bytesReceived += uint64(len(packet.Payload) - padding)
+	s.headerBytesReceived += uint64(rtpPacketSize(&packet.Header, nil) + padding)
+	s.lastReceived = received
+
+	if clockRate == 0 {
+		return
+	}
+	// https://tools.ietf.org/html/rfc3550#appendix-A.8, the difference of
+	// the timestamps wraps around on 32 bits like the timestamps do
+	arrival := received.Sub(s.firstReceived).Seconds()
+	if s.hasTransit {
+		d := (arrival - s.lastArrival) - float64(int32(packet.Timestamp-s.lastTimestamp))/float64(clockRate)
+		if d < 0 {
+			d = -d
+		}
+		s.jitter += (d - s.jitter) / 16
+	}
+	s.hasTransit = true
+	s.lastArrival = arrival
+	s.lastTimestamp = packet.Timestamp
+	s.clockRate = clockRate
+}
+
+// expected returns the number of packets expected from the sequence numbers
+func (s *receptionStats) expected() uint64 {
+	return s.highestSequence - s.baseSequence + 1
+}
+
+// dueReport returns the reception report of the stream once the interval
+// elapsed since the previous one, or since the first packet. The interval
+// is the given one when set, it is otherwise derived from the bitrate as the
+// reduced minimum of https://tools.ietf.org/html/rfc3550#section-6.2: 360
+// seconds divided by the kbps received, at most 5 seconds. The SSRC and the
+// Sender Report fields are left to the caller.
+func (s *receptionStats) dueReport(now time.Time, interval *time.Duration) (rtcp.ReceptionReport, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.packetsReceived == 0 {
+		return rtcp.ReceptionReport{}, false
+	}
+	if s.lastReport.IsZero() {
+		s.lastReport = s.firstReceived
+		s.reportInterval = initialRTCPReportInterval
+	}
+	if interval != nil {
+		s.reportInterval = *interval
+	}
+	elapsed := now.Sub(s.lastReport)
+	if elapsed < s.reportInterval {
+		return rtcp.ReceptionReport{}, false
+	}
+
+	report := s.report()
+	expected := s.expected()
+
+	bytes := s.bytesReceived + s.headerBytesReceived
+	s.reportInterval = reducedReportInterval(bytes-s.bytesPrior, elapsed)
+
+	s.lastReport = now
+	s.expectedPrior = expected
+	s.receivedPrior = s.packetsReceived
+	s.bytesPrior = bytes
+
+	return report, true
+}
+
+// reducedReportInterval returns the interval of the next RTCP report of a
+// stream the given bytes were sent or received on during elapsed, the
+// reduced minimum of https://tools.ietf.org/html/rfc3550#section-6.2: 360
+// seconds divided by the kbps, at most 5 seconds
+func reducedReportInterval(bytes uint64, elapsed time.Duration) time.Duration {
+	interval := maxRTCPReportInterval
+	if kbps := float64(bytes) * 8 / elapsed.Seconds() / 1000; kbps > 0 {
+		interval = time.Duration(360 / kbps * float64(time.Second))
+	}
+
+	switch {
+	case interval < minRTCPReportInterval:
+		return minRTCPReportInterval
+	case interval > maxRTCPReportInterval:
+		return maxRTCPReportInterval
+	}
+	return interval
+}
+
+// currentReport returns the reception report of the stream without starting
+// a new interval, the fraction lost is the one since the previous report. It
+// is sent along the congestion control feedback, for the remote to route it
+// to the stream.
+func (s *receptionStats) currentReport() (rtcp.ReceptionReport, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.packetsReceived == 0 {
+		return rtcp.ReceptionReport{}, false
+	}
+	return s.report(), true
+}
+
+// report returns the reception report of the counters since the previous
+// one https://tools.ietf.org/html/rfc3550#appendix-A.3
+func (s *receptionStats) report() rtcp.ReceptionReport {
+	expected := s.expected()
+	lost := int64(expected) - int64(s.packetsReceived)
+	switch {
+	case lost < 0:
+		lost = 0
+	case lost > maxTotalLost:
+		lost = maxTotalLost
+	}
+	var fractionLost uint8
+	expectedInterval := expected - s.expectedPrior
+	lostInterval := int64(expectedInterval) - int64(s.packetsReceived-s.receivedPrior)
+	if expectedInterval != 0 && lostInterval > 0 {
+		fractionLost = uint8((lostInterval << 8) / int64(expectedInterval))
+	}
+
+	// The extended numbers started one cycle in
+	return rtcp.ReceptionReport{
+		FractionLost:       fractionLost,
+		TotalLost:          uint32(lost),
+		LastSequenceNumber: uint32(s.highestSequence - 1<<16),
+		Jitter:             uint32(s.jitter * float64(s.clockRate)),
+	}
+}
+
+// fill sets the counters of the stream in its inbound-rtp stats. The lost
+// packets are the expected ones not received, they are negative when
+// duplicates were received.
+func (s *receptionStats) fill(stats *InboundRTPStreamStats) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stats.PacketsReceived = s.packetsReceived
+	stats.BytesReceived = s.bytesReceived
+	stats.HeaderBytesReceived = s.headerBytesReceived
+	stats.LastPacketReceivedTimestamp = s.lastReceived
+	stats.Jitter = s.jitter
+	if s.packetsReceived != 0 {
+		stats.PacketsLost = int64(s.expected()) - int64(s.packetsReceived)
+	}
+}
@@ -0,0 +1,103 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pions/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceptionStats(t *testing.T) {
+	start := time.Unix(1000, 0)
+	packet := func(sequenceNumber uint16, timestamp uint32) *rtp.Packet {
+		return &rtp.Packet{
+			Header:  rtp.Header{SequenceNumber: sequenceNumber, Timestamp: timestamp},
+			Payload: make([]byte, 100),
+		}
+	}
+
+	// Packets sent every 20ms arriving every 20ms have no jitter, the
+	// numbering wraps around
+	var s receptionStats
+	for i := 0; i < 10; i++ {
+		s.add(packet(uint16(65530+i), uint32(i*960)), start.Add(time.Duration(i)*20*time.Millisecond), 48000)
+	}
+	var stats InboundRTPStreamStats
+	s.fill(&stats)
+	assert.Equal(t, uint64(10), stats.PacketsReceived)
+	assert.Equal(t, uint64(1000), stats.BytesReceived)
+	assert.Equal(t, uint64(120), stats.HeaderBytesReceived)
+	assert.Equal(t, int64(0), stats.PacketsLost)
+	assert.InDelta(t, 0, stats.Jitter, 1e-9)
+	assert.Equal(t, start.Add(180*time.Millisecond), stats.LastPacketReceivedTimestamp)
+
+	// A gap is lost, a reordered packet before the first one is expected
+	// and a duplicate makes up for a lost one
+	s = receptionStats{}
+	s.add(packet(10, 0), start, 0)
+	s.add(packet(13, 0), start, 0)
+	s.add(packet(9, 0), start, 0)
+	s.add(packet(13, 0), start, 0)
+	s.fill(&stats)
+	assert.Equal(t, int64(5-4), stats.PacketsLost)
+	assert.Equal(t, 0.0, stats.Jitter, "no jitter without a clock rate")
+
+	// A packet 16ms late moves the estimate by 1/16 of its deviation
+	s = receptionStats{}
+	s.add(packet(1, 0), start, 48000)
+	s.add(packet(2, 960), start.Add(36*time.Millisecond), 48000)
+	s.fill(&stats)
+	assert.InDelta(t, 0.001, stats.Jitter, 1e-9)
+
+	// The padding is counted with the header
+	s = receptionStats{}
+	padded := packet(1, 0)
+	padded.Padding = true
+	padded.Payload[99] = 4
+	s.add(padded, start, 0)
+	s.fill(&stats)
+	assert.Equal(t, uint64(96), stats.BytesReceived)
+	assert.Equal(t, uint64(16), stats.HeaderBytesReceived)
+}
+
+func TestReceptionStats_DueReport(t *testing.T) {
+	start := time.Unix(1000, 0)
+	var s receptionStats
+	_, ok := s.dueReport(start, nil)
+	assert.False(t, ok, "no report before the first packet")
+
+	// 1 packet of 20 is lost, the numbering wraps around
+	for i := 0; i < 20; i++ {
+		if i == 5 {
+			continue
+		}
+		s.add(&rtp.Packet{
+			Header:  rtp.Header{SequenceNumber: uint16(65530 + i), Timestamp: uint32(i * 960)},
+			Payload: make([]byte, 1000),
+		}, start.Add(time.Duration(i)*20*time.Millisecond), 48000)
+	}
+	_, ok = s.dueReport(start.Add(500*time.Millisecond), nil)
+	assert.False(t, ok, "the first report waits for the initial interval")
+
+	report, ok := s.dueReport(start.Add(time.Second), nil)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(1), report.TotalLost)
+	assert.Equal(t, uint8(256/20), report.FractionLost)
+	assert.Equal(t, uint32(1<<16|13), report.LastSequenceNumber)
+
+	// About 150kbps were received, the next report is 2.3 seconds later
+	_, ok = s.dueReport(start.Add(3200*time.Millisecond), nil)
+	assert.False(t, ok)
+	report, ok = s.dueReport(start.Add(3500*time.Millisecond), nil)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(1), report.TotalLost)
+	assert.Equal(t, uint8(0), report.FractionLost, "nothing was lost since the previous report")
+
+	// A configured interval is followed
+	interval := 200 * time.Millisecond
+	_, ok = s.dueReport(start.Add(3600*time.Millisecond), &interval)
+	assert.False(t, ok)
+	_, ok = s.dueReport(start.Add(3700*time.Millisecond), &interval)
+	assert.True(t, ok)
+}
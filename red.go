@@ -0,0 +1,201 @@
+package webrtc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pions/rtp"
+	"github.com/pions/sdp/v2"
+)
+
+// Sizes and limits of the RED payload format
+// https://tools.ietf.org/html/rfc2198#section-3
+const (
+	redHeaderSize        = 4
+	redPrimaryHeaderSize = 1
+
+	redMaxTimestampOffset = 1<<14 - 1
+	redMaxBlockLength     = 1<<10 - 1
+)
+
+// redPayloadTypesFromMedia returns the RED payload types of a media section,
+// mapped to the payload type of the primary encoding their format parameters
+// refer to, such as 111 for a=fmtp:63 111/111
+func redPayloadTypesFromMedia(media *sdp.MediaDescription) map[uint8]uint8 {
+	payloadTypes := map[uint8]uint8{}
+	for _, codec := range codecsFromMedia(media) {
+		if !strings.EqualFold(codec.Name, RED) {
+			continue
+		}
+		primary, err := strconv.ParseUint(strings.Split(codec.Fmtp, "/")[0], 10, 8)
+		if err != nil {
+			continue
+		}
+		payloadTypes[codec.PayloadType] = uint8(primary)
+	}
+	return payloadTypes
+}
+
+// redFmtp returns the format parameters of a RED payload type carrying
+// a single redundant encoding of the given payload type
+func redFmtp(primary uint8) string {
+	return fmt.Sprintf("%d/%d", primary, primary)
+}
+
+// unmarshalRED splits a RED packet into the packets of its encodings, the
+// redundant ones first and the primary one last. The redundant encodings
+// are those of the packets preceding the primary one, the sequence number
+// of each is derived from its position.
+func unmarshalRED(packet *rtp.Packet) ([]*rtp.Packet, error) {
+	type block struct {
+		payloadType     uint8
+		timestampOffset uint32
+		length          int
+	}
+
+	var blocks []block
+	buf := packet.Payload
+	for {
+		if len(buf) < redPrimaryHeaderSize {
+			return nil, fmt.Errorf("RED header is too short")
+		}
+		if buf[0]&0x80 == 0 {
+			blocks = append(blocks, block{payloadType: buf[0] & 0x7F, length: -1})
+			buf = buf[redPrimaryHeaderSize:]
+			break
+		}
+		if len(buf) < redHeaderSize {
+			return nil, fmt.Errorf("RED header is too short")
+		}
+		header := binary.BigEndian.Uint32(buf)
+		blocks = append(blocks, block{
+			payloadType:     uint8(header>>24) & 0x7F,
+			timestampOffset: header >> 10 & redMaxTimestampOffset,
+			length:          int(header & redMaxBlockLength),
+		})
+		buf = buf[redHeaderSize:]
+	}
+
+	packets := make([]*rtp.Packet, 0, len(blocks))
+	for i, b := range blocks {
+		p := &rtp.Packet{Header: packet.Header}
+		p.PayloadType = b.payloadType
+		p.SequenceNumber = packet.SequenceNumber - uint16(len(blocks)-1-i)
+		p.Timestamp = packet.Timestamp - b.timestampOffset
+		if b.length < 0 {
+			p.Payload = buf
+		} else if b.length > len(buf) {
+			return nil, fmt.Errorf("RED block exceeds the payload")
+		} else {
+			p.Payload, buf = buf[:b.length], buf[b.length:]
+			p.Marker = false
+		}
+		packets = append(packets, p)
+	}
+	return packets, nil
+}
+
+// redEncoder wraps the packets of a stream in RED packets carrying the
+// payloads of the packets sent before them
+type redEncoder struct {
+	payloadType uint8
+	distance    int
+	previous    []*rtp.Packet
+}
+
+func newREDEncoder(payloadType uint8, distance int) *redEncoder {
+	return &redEncoder{payloadType: payloadType, distance: distance}
+}
+
+// encode returns the RED packet carrying the primary packet and as many of
+// the previous ones as fit. The receiver derives the sequence number of a
+// redundant encoding from its position, only the run of previous packets
+// with consecutive sequence numbers is carried.
+func (e *redEncoder) encode(packet *rtp.Packet) *rtp.Packet {
+	if n := len(e.previous); n != 0 && e.previous[n-1].SequenceNumber+1 != packet.SequenceNumber {
+		e.previous = e.previous[:0]
+	}
+
+	size := rtpPacketSize(&packet.Header, packet.Payload) + redPrimaryHeaderSize
+	first := len(e.previous)
+	for first > 0 {
+		p := e.previous[first-1]
+		offset := packet.Timestamp - p.Timestamp
+		if offset > redMaxTimestampOffset || len(p.Payload) > redMaxBlockLength ||
+			size+redHeaderSize+len(p.Payload) > rtpOutboundMTU {
+			break
+		}
+		size += redHeaderSize + len(p.Payload)
+		first--
+	}
+	redundant := e.previous[first:]
+
+	payload := make([]byte, 0, size)
+	for _, p := range redundant {
+		header := uint32(0x80|p.PayloadType)<<24 | (packet.Timestamp-p.Timestamp)<<10 | uint32(len(p.Payload))
+		payload = append(payload, byte(header>>24), byte(header>>16), byte(header>>8), byte(header))
+	}
+	payload = append(payload, packet.PayloadType&0x7F)
+	for _, p := range redundant {
+		payload = append(payload, p.Payload...)
+	}
+	payload = append(payload, packet.Payload...)
+
+	// The payload is copied, the caller may reuse its buffer
+	e.previous = append(e.previous, &rtp.Packet{
+		Header:  rtp.Header{PayloadType: packet.PayloadType, SequenceNumber: packet.SequenceNumber, Timestamp: packet.Timestamp},
+		Payload: append([]byte{}, packet.Payload...),
+	})
+	if len(e.previous) > e.distance {
+		e.previous = e.previous[len(e.previous)-e.distance:]
+	}
+
+	red := &rtp.Packet{Header: packet.Header, Payload: payload}
+	red.PayloadType = e.payloadType
+	return red
+}
+
+// redWindowSize is the number of sequence numbers the receivedWindow
+// remembers, older redundant encodings are discarded
+const redWindowSize = 64
+
+// receivedWindow remembers the sequence numbers received recently, so the
+// redundant encodings of the packets already received are discarded
+type receivedWindow struct {
+	started  bool
+	highest  uint16
+	received uint64
+}
+
+// push records a sequence number and returns whether it is the first time
+// it was received
+func (w *receivedWindow) push(sequenceNumber uint16) bool {
+	if !w.started {
+		w.started, w.highest, w.received = true, sequenceNumber, 1
+		return true
+	}
+
+	diff := int16(sequenceNumber - w.highest)
+	switch {
+	case diff > 0:
+		if diff >= redWindowSize {
+			w.received = 0
+		} else {
+			w.received <<= uint(diff)
+		}
+		w.received |= 1
+		w.highest = sequenceNumber
+		return true
+	case -int(diff) >= redWindowSize:
+		return false
+	default:
+		bit := uint64(1) << uint(-diff)
+		if w.received&bit != 0 {
+			return false
+		}
+		w.received |= bit
+		return true
+	}
+}
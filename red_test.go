@@ -0,0 +1,80 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/rtp"
+	"github.com/pions/sdp/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestREDPayloadTypesFromMedia(t *testing.T) {
+	media := (&sdp.MediaDescription{}).
+		WithCodec(111, Opus, 48000, 2, "minptime=10;useinbandfec=1").
+		WithCodec(63, "RED", 48000, 2, redFmtp(111)).
+		WithCodec(64, RED, 48000, 2, "")
+
+	assert.Equal(t, map[uint8]uint8{63: 111}, redPayloadTypesFromMedia(media))
+}
+
+func TestRED(t *testing.T) {
+	e := newREDEncoder(63, 2)
+
+	var packets []*rtp.Packet
+	for i, sequenceNumber := range []uint16{65534, 65535, 0, 5} {
+		packet := &rtp.Packet{
+			Header:  rtp.Header{SSRC: 1, PayloadType: 111, SequenceNumber: sequenceNumber, Timestamp: uint32(i) * 960},
+			Payload: []byte{byte(i), byte(i)},
+		}
+		red := e.encode(packet)
+		assert.Equal(t, uint8(63), red.PayloadType)
+		assert.Equal(t, sequenceNumber, red.SequenceNumber)
+		packets = append(packets, red)
+	}
+
+	// The first packet carries no redundancy
+	decoded, err := unmarshalRED(packets[0])
+	assert.NoError(t, err)
+	assert.Equal(t, []*rtp.Packet{{
+		Header:  rtp.Header{SSRC: 1, PayloadType: 111, SequenceNumber: 65534, Timestamp: 0},
+		Payload: []byte{0x00, 0x00},
+	}}, decoded)
+
+	// The distance is kept across the sequence number wrap around
+	decoded, err = unmarshalRED(packets[2])
+	assert.NoError(t, err)
+	assert.Len(t, decoded, 3)
+	for i, sequenceNumber := range []uint16{65534, 65535, 0} {
+		assert.Equal(t, uint8(111), decoded[i].PayloadType)
+		assert.Equal(t, sequenceNumber, decoded[i].SequenceNumber)
+		assert.Equal(t, uint32(i)*960, decoded[i].Timestamp)
+		assert.Equal(t, []byte{byte(i), byte(i)}, decoded[i].Payload)
+	}
+
+	// The previous packets aren't consecutive after a gap
+	decoded, err = unmarshalRED(packets[3])
+	assert.NoError(t, err)
+	assert.Len(t, decoded, 1)
+
+	for _, payload := range [][]byte{{}, {0x80}, {0x80 | 111, 0x00, 0x00, 0x05, 111, 0x00}} {
+		_, err = unmarshalRED(&rtp.Packet{Payload: payload})
+		assert.Error(t, err)
+	}
+}
+
+func TestReceivedWindow(t *testing.T) {
+	w := &receivedWindow{}
+	assert.True(t, w.push(65535))
+	assert.True(t, w.push(1))
+	assert.False(t, w.push(1))
+
+	// The packet lost is received once
+	assert.True(t, w.push(0))
+	assert.False(t, w.push(0))
+
+	// Too old to be told apart
+	highest := uint16(1)
+	assert.False(t, w.push(highest-redWindowSize))
+	assert.True(t, w.push(highest+redWindowSize))
+	assert.False(t, w.push(highest+redWindowSize))
+}
@@ -0,0 +1,130 @@
+package webrtc
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/pions/rtcp"
+	"github.com/pkg/errors"
+)
+
+// ReceiverEstimatedMaximumBitrate is the REMB RTCP feedback message defined
+// in https://tools.ietf.org/html/draft-alvestrand-rmcat-remb-03#section-2.2,
+// it carries the bitrate the receiver estimated it can receive the streams
+// of the SSRCs at. It is signaled with the goog-remb feedback.
+type ReceiverEstimatedMaximumBitrate struct {
+	// SSRC of sender
+	SenderSSRC uint32
+
+	// Bitrate is the estimated total bitrate of the SSRCs, in bits per
+	// second. It is carried with an 18 bits mantissa, the lowest bits of
+	// large values are lost.
+	Bitrate uint64
+
+	// SSRCs are the streams the estimate applies to
+	SSRCs []uint32
+}
+
+const (
+	formatREMB = 15
+
+	rembHeaderLength  = 16
+	rembMantissaBits  = 18
+	rembMaxExponent   = 63
+	rembMaxSSRCs      = 0xFF
+	rembUniqueIDValue = "REMB"
+)
+
+var _ rtcp.Packet = (*ReceiverEstimatedMaximumBitrate)(nil)
+
+func (p ReceiverEstimatedMaximumBitrate) len() int {
+	return rtcpHeaderLength + rembHeaderLength + 4*len(p.SSRCs)
+}
+
+// Header returns the Header associated with this packet.
+func (p *ReceiverEstimatedMaximumBitrate) Header() rtcp.Header {
+	return rtcp.Header{
+		Count:  formatREMB,
+		Type:   rtcp.TypePayloadSpecificFeedback,
+		Length: uint16(p.len()/4 - 1),
+	}
+}
+
+// Marshal encodes the ReceiverEstimatedMaximumBitrate in binary
+func (p ReceiverEstimatedMaximumBitrate) Marshal() ([]byte, error) {
+	if len(p.SSRCs) > rembMaxSSRCs {
+		return nil, errors.New("rtcp: too many SSRCs")
+	}
+
+	mantissa, exponent := p.Bitrate, uint64(0)
+	for mantissa >= 1<<rembMantissaBits {
+		mantissa >>= 1
+		exponent++
+	}
+
+	rawPacket := make([]byte, p.len())
+	hData, err := p.Header().Marshal()
+	if err != nil {
+		return nil, err
+	}
+	copy(rawPacket, hData)
+
+	// The media source SSRC is unused and always 0
+	packetBody := rawPacket[rtcpHeaderLength:]
+	binary.BigEndian.PutUint32(packetBody, p.SenderSSRC)
+	copy(packetBody[8:], rembUniqueIDValue)
+	binary.BigEndian.PutUint32(packetBody[12:], uint32(len(p.SSRCs))<<24|uint32(exponent)<<rembMantissaBits|uint32(mantissa))
+	for i, ssrc := range p.SSRCs {
+		binary.BigEndian.PutUint32(packetBody[rembHeaderLength+4*i:], ssrc)
+	}
+
+	return rawPacket, nil
+}
+
+// Unmarshal decodes the ReceiverEstimatedMaximumBitrate from binary
+func (p *ReceiverEstimatedMaximumBitrate) Unmarshal(rawPacket []byte) error {
+	if len(rawPacket) < rtcpHeaderLength+rembHeaderLength {
+		return errors.New("rtcp: packet too short")
+	}
+
+	var h rtcp.Header
+	if err := h.Unmarshal(rawPacket); err != nil {
+		return err
+	}
+
+	if h.Type != rtcp.TypePayloadSpecificFeedback || h.Count != formatREMB || !isREMB(rawPacket) {
+		return errors.New("rtcp: wrong packet type")
+	}
+
+	packetBody := rawPacket[rtcpHeaderLength:]
+	bitrate := binary.BigEndian.Uint32(packetBody[12:])
+	count := int(bitrate >> 24)
+	end := (int(h.Length) + 1) * 4
+	if end > len(rawPacket) || end < rtcpHeaderLength+rembHeaderLength+4*count {
+		return errors.New("rtcp: invalid packet length")
+	}
+
+	p.SenderSSRC = binary.BigEndian.Uint32(packetBody)
+	p.Bitrate = uint64(bitrate&(1<<rembMantissaBits-1)) << (bitrate >> rembMantissaBits & rembMaxExponent)
+	p.SSRCs = nil
+	for i := 0; i < count; i++ {
+		p.SSRCs = append(p.SSRCs, binary.BigEndian.Uint32(packetBody[rembHeaderLength+4*i:]))
+	}
+	return nil
+}
+
+// isREMB returns whether an application layer feedback message is a REMB,
+// which is told apart from the others by its unique identifier
+func isREMB(rawPacket []byte) bool {
+	return len(rawPacket) >= rtcpHeaderLength+rembHeaderLength &&
+		string(rawPacket[rtcpHeaderLength+8:rtcpHeaderLength+12]) == rembUniqueIDValue
+}
+
+// DestinationSSRC returns an array of SSRC values that this packet refers to.
+func (p *ReceiverEstimatedMaximumBitrate) DestinationSSRC() []uint32 {
+	return append([]uint32{}, p.SSRCs...)
+}
+
+func (p *ReceiverEstimatedMaximumBitrate) String() string {
+	return fmt.Sprintf("ReceiverEstimatedMaximumBitrate %x %d %x", p.SenderSSRC, p.Bitrate, p.SSRCs)
+}
@@ -0,0 +1,93 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceiverEstimatedMaximumBitrate(t *testing.T) {
+	remb := ReceiverEstimatedMaximumBitrate{
+		SenderSSRC: 0x902f9e2e,
+		Bitrate:    2000000,
+		SSRCs:      []uint32{0x12345678, 0x9abcdef0},
+	}
+
+	raw, err := remb.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x8f, 0xce, 0x00, 0x06,
+		0x90, 0x2f, 0x9e, 0x2e,
+		0x00, 0x00, 0x00, 0x00,
+		'R', 'E', 'M', 'B',
+		0x02, 0x0f, 0xd0, 0x90,
+		0x12, 0x34, 0x56, 0x78,
+		0x9a, 0xbc, 0xde, 0xf0,
+	}, raw)
+
+	packet, err := unmarshalRTCP(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, &remb, packet)
+	assert.Equal(t, []uint32{0x12345678, 0x9abcdef0}, packet.DestinationSSRC())
+
+	// The lowest bits of a bitrate beyond the mantissa are lost
+	remb.Bitrate = 1<<20 + 1
+	raw, err = remb.Marshal()
+	assert.NoError(t, err)
+	var decoded ReceiverEstimatedMaximumBitrate
+	assert.NoError(t, decoded.Unmarshal(raw))
+	assert.Equal(t, uint64(1<<20), decoded.Bitrate)
+
+	// Truncated SSRCs
+	assert.Error(t, decoded.Unmarshal(raw[:len(raw)-4]))
+
+	// The other application layer feedback messages are left to the rtcp
+	// package
+	copy(raw[12:], "ABCD")
+	packet, err = unmarshalRTCP(raw)
+	assert.NoError(t, err)
+	assert.IsType(t, &rtcp.RawPacket{}, packet)
+	assert.Error(t, decoded.Unmarshal(raw))
+}
+
+func TestCongestionControlFeedback(t *testing.T) {
+	for _, test := range []struct {
+		transportCC, remb, preferREMB bool
+		followTransportCC, followREMB bool
+	}{
+		{false, false, false, false, false},
+		{true, false, false, true, false},
+		{false, true, false, false, true},
+		{true, false, true, true, false},
+		// Only one of the feedbacks is followed when both are negotiated
+		{true, true, false, true, false},
+		{true, true, true, false, true},
+	} {
+		followTransportCC, followREMB := congestionControlFeedback(test.transportCC, test.remb, test.preferREMB)
+		assert.Equal(t, test.followTransportCC, followTransportCC)
+		assert.Equal(t, test.followREMB, followREMB)
+	}
+}
+
+func TestRTPSender_HandleREMB(t *testing.T) {
+	api := NewAPI()
+	r := &RTPSender{Track: &Track{SSRC: 5}, api: api}
+	r.bandwidthEstimator = newLossBasedBandwidthEstimator(api.settingEngine.bandwidthEstimationBounds())
+
+	// The REMB feedback isn't followed
+	r.handleREMB(&ReceiverEstimatedMaximumBitrate{Bitrate: 100000, SSRCs: []uint32{5}})
+	assert.Equal(t, 200000, r.bandwidthEstimate(200000))
+
+	r.remb = true
+	r.handleREMB(&ReceiverEstimatedMaximumBitrate{Bitrate: 100000, SSRCs: []uint32{4}})
+	assert.Equal(t, 200000, r.bandwidthEstimate(200000))
+
+	r.handleREMB(&ReceiverEstimatedMaximumBitrate{Bitrate: 100000, SSRCs: []uint32{4, 5}})
+	assert.Equal(t, 100000, r.bandwidthEstimate(200000))
+	assert.Equal(t, 50000, r.bandwidthEstimate(50000))
+
+	// The estimate stays within the bounds
+	r.handleREMB(&ReceiverEstimatedMaximumBitrate{Bitrate: 1000, SSRCs: []uint32{5}})
+	assert.Equal(t, defaultMinBitrate, r.bandwidthEstimate(200000))
+}
@@ -0,0 +1,137 @@
+package webrtc
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/pions/rtcp"
+	"github.com/pkg/errors"
+)
+
+// unmarshalRTCP unmarshals a RTCP packet like rtcp.Unmarshal, with support
+// for the feedback messages the rtcp package doesn't know about
+func unmarshalRTCP(rawPacket []byte) (rtcp.Packet, error) {
+	packet, header, err := rtcp.Unmarshal(rawPacket)
+	if err != nil {
+		return nil, err
+	}
+
+	if header.Type == rtcp.TypePayloadSpecificFeedback && header.Count == formatFIR {
+		fir := &FullIntraRequest{}
+		if err := fir.Unmarshal(rawPacket); err != nil {
+			return nil, err
+		}
+		return fir, nil
+	}
+
+	if header.Type == rtcp.TypePayloadSpecificFeedback && header.Count == formatREMB && isREMB(rawPacket) {
+		remb := &ReceiverEstimatedMaximumBitrate{}
+		if err := remb.Unmarshal(rawPacket); err != nil {
+			return nil, err
+		}
+		return remb, nil
+	}
+
+	if header.Type == rtcp.TypeTransportSpecificFeedback && header.Count == formatPauseResume {
+		pauseResume := &PauseResume{}
+		if err := pauseResume.Unmarshal(rawPacket); err != nil {
+			return nil, err
+		}
+		return pauseResume, nil
+	}
+
+	if header.Type == rtcp.TypeTransportSpecificFeedback && header.Count == formatTransportCC {
+		transportCC := &TransportLayerCC{}
+		if err := transportCC.Unmarshal(rawPacket); err != nil {
+			return nil, err
+		}
+		return transportCC, nil
+	}
+	return packet, nil
+}
+
+// unmarshalCompoundRTCP unmarshals every packet of a compound RTCP packet
+func unmarshalCompoundRTCP(rawPacket []byte) ([]rtcp.Packet, error) {
+	var packets []rtcp.Packet
+	reader := rtcp.NewReader(bytes.NewReader(rawPacket))
+	for {
+		_, data, err := reader.ReadPacket()
+		if err == io.EOF {
+			return packets, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		packet, err := unmarshalRTCP(data)
+		if err != nil {
+			return nil, err
+		}
+		packets = append(packets, packet)
+	}
+}
+
+// unmarshalCompoundRTCPRaw unmarshals every packet of a compound RTCP packet
+// like unmarshalCompoundRTCP, the packets that fail to parse are returned as
+// a rtcp.RawPacket instead of failing the whole compound packet. The rest of
+// a compound packet whose header can't be read is a single RawPacket.
+func unmarshalCompoundRTCPRaw(rawPacket []byte) []rtcp.Packet {
+	var packets []rtcp.Packet
+	for len(rawPacket) != 0 {
+		length := len(rawPacket)
+		var header rtcp.Header
+		if err := header.Unmarshal(rawPacket); err == nil && (int(header.Length)+1)*4 <= length {
+			length = (int(header.Length) + 1) * 4
+		}
+
+		data := rawPacket[:length]
+		rawPacket = rawPacket[length:]
+
+		packet, err := unmarshalRTCP(data)
+		if err != nil {
+			raw := rtcp.RawPacket(data)
+			packet = &raw
+		}
+		packets = append(packets, packet)
+	}
+	return packets
+}
+
+// newCompoundRTCP marshals the packets into a compound RTCP packet written
+// in a single SRTCP operation. A compound packet starts with a
+// SenderReport or a ReceiverReport as required by
+// https://tools.ietf.org/html/rfc3550#section-6.1, an empty ReceiverReport
+// is put first when the packets don't. The packets are sent as they are
+// when the remote negotiated reduced-size RTCP (RFC5506).
+func newCompoundRTCP(reducedSize bool, packets ...rtcp.Packet) ([]byte, error) {
+	if len(packets) == 0 {
+		return nil, errors.New("no RTCP packet to send")
+	}
+
+	if !reducedSize && !isRTCPReport(packets[0]) {
+		packets = append([]rtcp.Packet{&rtcp.ReceiverReport{}}, packets...)
+	}
+	return marshalCompoundRTCP(packets...)
+}
+
+// isRTCPReport returns whether the packet is a SenderReport or a
+// ReceiverReport, which can start a compound packet
+func isRTCPReport(packet rtcp.Packet) bool {
+	switch packet.(type) {
+	case *rtcp.SenderReport, *rtcp.ReceiverReport:
+		return true
+	}
+	return false
+}
+
+// marshalCompoundRTCP marshals the packets into a single compound RTCP packet
+func marshalCompoundRTCP(packets ...rtcp.Packet) ([]byte, error) {
+	var out []byte
+	for _, p := range packets {
+		raw, err := p.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, raw...)
+	}
+	return out, nil
+}
@@ -0,0 +1,63 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCompoundRTCP(t *testing.T) {
+	pli := &rtcp.PictureLossIndication{MediaSSRC: 1}
+	report := &rtcp.ReceiverReport{SSRC: 2, Reports: []rtcp.ReceptionReport{{SSRC: 1}}}
+
+	for _, test := range []struct {
+		reducedSize bool
+		packets     []rtcp.Packet
+		expected    []rtcp.Packet
+	}{
+		// A compound packet starts with a report
+		{false, []rtcp.Packet{pli}, []rtcp.Packet{&rtcp.ReceiverReport{}, pli}},
+		{false, []rtcp.Packet{report, pli}, []rtcp.Packet{report, pli}},
+		{true, []rtcp.Packet{pli}, []rtcp.Packet{pli}},
+	} {
+		raw, err := newCompoundRTCP(test.reducedSize, test.packets...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := marshalCompoundRTCP(test.expected...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, expected, raw)
+	}
+
+	_, err := newCompoundRTCP(false)
+	assert.Error(t, err)
+}
+
+func TestUnmarshalCompoundRTCPRaw(t *testing.T) {
+	report := &rtcp.ReceiverReport{SSRC: 2, Reports: []rtcp.ReceptionReport{{SSRC: 1}}}
+	pli := &rtcp.PictureLossIndication{MediaSSRC: 1}
+	raw, err := marshalCompoundRTCP(report, pli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := unmarshalCompoundRTCP(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, parsed, unmarshalCompoundRTCPRaw(raw))
+
+	// A FullIntraRequest without its media SSRC, followed by bytes that
+	// aren't a RTCP header
+	fir := []byte{0x84, 0xce, 0x00, 0x01, 0x90, 0x2f, 0x9e, 0x2e}
+	garbage := []byte{0x00, 0x01}
+	raw = append(append(append([]byte{}, raw...), fir...), garbage...)
+
+	_, err = unmarshalCompoundRTCP(raw)
+	assert.Error(t, err)
+
+	rawFIR, rawGarbage := rtcp.RawPacket(fir), rtcp.RawPacket(garbage)
+	assert.Equal(t, append(parsed, &rawFIR, &rawGarbage), unmarshalCompoundRTCPRaw(raw))
+}
@@ -0,0 +1,89 @@
+package webrtc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pions/sdp/v2"
+)
+
+// RTCPFeedback signals the connection to use additional RTCP packet types.
+// https://draft.ortc.org/#dom-rtcrtcpfeedback
+type RTCPFeedback struct {
+	// Type is the type of feedback, e.g. "nack" or "ccm"
+	Type string
+
+	// Parameter is the parameter of the feedback, e.g. "pli" or "fir".
+	// It is empty for the generic NACK.
+	Parameter string
+}
+
+// Types and parameters of the RTCP feedback messages supported by pions-webrtc
+const (
+	TypeRTCPFBNACK        = "nack"
+	TypeRTCPFBCCM         = "ccm"
+	TypeRTCPFBTransportCC = "transport-cc"
+	TypeRTCPFBGoogREMB    = "goog-remb"
+
+	RTCPFBParameterPLI   = "pli"
+	RTCPFBParameterFIR   = "fir"
+	RTCPFBParameterPause = "pause"
+)
+
+// String returns the feedback the way it is written in a a=rtcp-fb attribute
+func (f RTCPFeedback) String() string {
+	if f.Parameter == "" {
+		return f.Type
+	}
+	return f.Type + " " + f.Parameter
+}
+
+// defaultVideoRTCPFeedback is the feedback offered for the video codecs
+func defaultVideoRTCPFeedback() []RTCPFeedback {
+	return []RTCPFeedback{
+		{Type: TypeRTCPFBNACK},
+		{Type: TypeRTCPFBNACK, Parameter: RTCPFBParameterPLI},
+		{Type: TypeRTCPFBCCM, Parameter: RTCPFBParameterFIR},
+		{Type: TypeRTCPFBCCM, Parameter: RTCPFBParameterPause},
+	}
+}
+
+func hasRTCPFeedback(feedback []RTCPFeedback, f RTCPFeedback) bool {
+	for _, candidate := range feedback {
+		if candidate == f {
+			return true
+		}
+	}
+	return false
+}
+
+// rtcpFeedbackFromMedia returns the feedback of the a=rtcp-fb attributes of a
+// media section, regardless of the payload type they were signaled for
+func rtcpFeedbackFromMedia(media *sdp.MediaDescription) []RTCPFeedback {
+	var feedback []RTCPFeedback
+	for _, a := range media.Attributes {
+		if a.Key != "rtcp-fb" {
+			continue
+		}
+
+		// https://tools.ietf.org/html/rfc4585#section-4.2
+		fields := strings.Fields(a.Value)
+		if len(fields) < 2 {
+			pcLog.Warnf("Failed to parse rtcp-fb: %q", a.Value)
+			continue
+		}
+
+		f := RTCPFeedback{Type: fields[1]}
+		if len(fields) > 2 {
+			f.Parameter = fields[2]
+		}
+		if !hasRTCPFeedback(feedback, f) {
+			feedback = append(feedback, f)
+		}
+	}
+	return feedback
+}
+
+func rtcpFeedbackAttribute(payloadType uint8, f RTCPFeedback) string {
+	return fmt.Sprintf("%d %s", payloadType, f)
+}
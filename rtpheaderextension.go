@@ -0,0 +1,261 @@
+package webrtc
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pions/rtp"
+	"github.com/pkg/errors"
+)
+
+// URIs of the RTP header extensions supported by pions-webrtc
+const (
+	// VideoOrientationURI is the coordination of video orientation (CVO)
+	// extension defined in 3GPP TS 26.114
+	VideoOrientationURI = "urn:3gpp:video-orientation"
+
+	// PlayoutDelayURI is the extension used to hint the receiver about the
+	// delay the media should be rendered with
+	PlayoutDelayURI = "http://www.webrtc.org/experiments/rtp-hdrext/playout-delay"
+
+	// AbsCaptureTimeURI is the extension carrying the NTP time at which a
+	// frame was captured
+	AbsCaptureTimeURI = "http://www.webrtc.org/experiments/rtp-hdrext/abs-capture-time"
+
+	// TransmissionOffsetURI is the extension carrying the offset between the
+	// RTP timestamp of a packet and the time it was sent, used by legacy
+	// bandwidth estimation https://tools.ietf.org/html/rfc5450
+	TransmissionOffsetURI = "urn:ietf:params:rtp-hdrext:toffset"
+
+	// AbsSendTimeURI is the extension carrying the time a packet was sent
+	// at, used by the remote bitrate estimation of the REMB feedback
+	// http://www.webrtc.org/experiments/rtp-hdrext/abs-send-time
+	AbsSendTimeURI = "http://www.webrtc.org/experiments/rtp-hdrext/abs-send-time"
+
+	// AudioLevelURI is the extension carrying the level of the audio of a
+	// packet https://tools.ietf.org/html/rfc6464
+	AudioLevelURI = "urn:ietf:params:rtp-hdrext:ssrc-audio-level"
+
+	// MIDURI is the extension carrying the mid of the media section a packet
+	// belongs to, used to route bundled streams with undeclared SSRCs
+	// https://tools.ietf.org/html/draft-ietf-mmusic-sdp-bundle-negotiation-54#section-15
+	MIDURI = "urn:ietf:params:rtp-hdrext:sdes:mid"
+
+	// TransportCCURI is the extension carrying the transport-wide sequence
+	// number the TransportLayerCC feedback reports the arrival of
+	// https://tools.ietf.org/html/draft-holmer-rmcat-transport-wide-cc-extensions-01#section-2
+	TransportCCURI = "http://www.ietf.org/id/draft-holmer-rmcat-transport-wide-cc-extensions-01"
+
+	// RIDURI is the extension carrying the RID of the simulcast layer a
+	// packet belongs to
+	// https://tools.ietf.org/html/draft-ietf-avtext-rid-09#section-3
+	RIDURI = "urn:ietf:params:rtp-hdrext:sdes:rtp-stream-id"
+
+	// RepairedRIDURI is the extension carrying the RID of the simulcast
+	// layer a RTX packet repairs
+	// https://tools.ietf.org/html/draft-ietf-avtext-rid-09#section-4
+	RepairedRIDURI = "urn:ietf:params:rtp-hdrext:sdes:repaired-rtp-stream-id"
+)
+
+const (
+	// https://tools.ietf.org/html/rfc5285#section-4.2
+	headerExtensionProfileOneByte = 0xBEDE
+	// https://tools.ietf.org/html/rfc5285#section-4.3
+	headerExtensionProfileTwoByte     = 0x1000
+	headerExtensionProfileTwoByteMask = 0xFFF0
+
+	headerExtensionOneByteMaxID     = 14
+	headerExtensionOneByteMaxLength = 16
+	headerExtensionTwoByteMaxLength = 255
+)
+
+// headerExtensionElement is a single RFC5285 extension element
+type headerExtensionElement struct {
+	id      uint8
+	payload []byte
+}
+
+func isOneByteHeaderExtension(h *rtp.Header) bool {
+	return h.ExtensionProfile == headerExtensionProfileOneByte
+}
+
+func isTwoByteHeaderExtension(h *rtp.Header) bool {
+	return h.ExtensionProfile&headerExtensionProfileTwoByteMask == headerExtensionProfileTwoByte
+}
+
+// parseHeaderExtensions splits the extension of a RTP header into RFC5285 elements
+func parseHeaderExtensions(h *rtp.Header) ([]headerExtensionElement, error) {
+	if !h.Extension {
+		return nil, nil
+	}
+
+	oneByte := isOneByteHeaderExtension(h)
+	if !oneByte && !isTwoByteHeaderExtension(h) {
+		return nil, errors.Errorf("header extension profile %#x is not RFC5285", h.ExtensionProfile)
+	}
+
+	var elements []headerExtensionElement
+	buf := h.ExtensionPayload
+	for i := 0; i < len(buf); {
+		// Padding
+		if buf[i] == 0 {
+			i++
+			continue
+		}
+
+		var id uint8
+		var length int
+		if oneByte {
+			id = buf[i] >> 4
+			length = int(buf[i]&0x0F) + 1
+			i++
+
+			// The ID 15 is reserved, processing of the extension must stop
+			if id == 15 {
+				break
+			}
+		} else {
+			if i+1 >= len(buf) {
+				return nil, errors.New("header extension element is too short")
+			}
+			id = buf[i]
+			length = int(buf[i+1])
+			i += 2
+		}
+
+		if i+length > len(buf) {
+			return nil, errors.Errorf("header extension element %d exceeds the extension size", id)
+		}
+		elements = append(elements, headerExtensionElement{id: id, payload: buf[i : i+length]})
+		i += length
+	}
+
+	return elements, nil
+}
+
+// marshalHeaderExtensions replaces the extension of a RTP header with the given
+// elements, the one-byte form is used unless an element requires the two-byte one
+func marshalHeaderExtensions(h *rtp.Header, elements []headerExtensionElement) error {
+	if len(elements) == 0 {
+		h.Extension = false
+		h.ExtensionProfile = 0
+		h.ExtensionPayload = nil
+		return nil
+	}
+
+	oneByte := true
+	for _, e := range elements {
+		switch {
+		case e.id == 0:
+			return errors.New("header extension ID 0 is reserved")
+		case len(e.payload) > headerExtensionTwoByteMaxLength:
+			return errors.Errorf("header extension %d payload is too large", e.id)
+		case e.id > headerExtensionOneByteMaxID, len(e.payload) == 0, len(e.payload) > headerExtensionOneByteMaxLength:
+			oneByte = false
+		}
+	}
+
+	var buf []byte
+	for _, e := range elements {
+		if oneByte {
+			buf = append(buf, e.id<<4|uint8(len(e.payload)-1))
+		} else {
+			buf = append(buf, e.id, uint8(len(e.payload)))
+		}
+		buf = append(buf, e.payload...)
+	}
+
+	// The extension is counted in 32-bit words
+	for len(buf)%4 != 0 {
+		buf = append(buf, 0)
+	}
+
+	h.Extension = true
+	if oneByte {
+		h.ExtensionProfile = headerExtensionProfileOneByte
+	} else {
+		h.ExtensionProfile = headerExtensionProfileTwoByte
+	}
+	h.ExtensionPayload = buf
+	return nil
+}
+
+// getHeaderExtension returns the payload of the extension element with the given ID
+func getHeaderExtension(h *rtp.Header, id uint8) ([]byte, bool) {
+	elements, err := parseHeaderExtensions(h)
+	if err != nil {
+		return nil, false
+	}
+
+	for _, e := range elements {
+		if e.id == id {
+			return e.payload, true
+		}
+	}
+	return nil, false
+}
+
+// setHeaderExtension adds or replaces the extension element with the given ID
+func setHeaderExtension(h *rtp.Header, id uint8, payload []byte) error {
+	elements, err := parseHeaderExtensions(h)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for i := range elements {
+		if elements[i].id == id {
+			elements[i].payload = payload
+			found = true
+		}
+	}
+	if !found {
+		elements = append(elements, headerExtensionElement{id: id, payload: payload})
+	}
+
+	return marshalHeaderExtensions(h, elements)
+}
+
+// remapHeaderExtensions rewrites the IDs of the extension elements of a RTP
+// header from the mapping of the session the packet was received on to the
+// mapping of the session it is sent on, both mapping URIs to IDs. The elements
+// whose URI isn't in both mappings are stripped.
+func remapHeaderExtensions(h *rtp.Header, from, to map[string]uint8) error {
+	elements, err := parseHeaderExtensions(h)
+	if err != nil {
+		return err
+	}
+
+	ids := map[uint8]uint8{}
+	for uri, fromID := range from {
+		if toID, ok := to[uri]; ok {
+			ids[fromID] = toID
+		}
+	}
+
+	remapped := make([]headerExtensionElement, 0, len(elements))
+	for _, e := range elements {
+		if id, ok := ids[e.id]; ok {
+			remapped = append(remapped, headerExtensionElement{id: id, payload: e.payload})
+		}
+	}
+	return marshalHeaderExtensions(h, remapped)
+}
+
+// parseExtMap parses the value of a a=extmap attribute as defined in
+// https://tools.ietf.org/html/rfc5285#section-5
+func parseExtMap(value string) (uint8, string, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 2 {
+		return 0, "", errors.Errorf("invalid extmap %q", value)
+	}
+
+	// The direction is optional
+	idStr := strings.Split(fields[0], "/")[0]
+	id, err := strconv.ParseUint(idStr, 10, 8)
+	if err != nil || id == 0 {
+		return 0, "", errors.Errorf("invalid extmap id %q", idStr)
+	}
+
+	return uint8(id), fields[1], nil
+}
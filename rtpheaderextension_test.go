@@ -0,0 +1,102 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaderExtension_OneByte(t *testing.T) {
+	h := &rtp.Header{}
+
+	assert.NoError(t, setHeaderExtension(h, 1, []byte{0x05}))
+	assert.NoError(t, setHeaderExtension(h, 2, []byte{0x01, 0x02}))
+
+	assert.True(t, h.Extension)
+	assert.Equal(t, uint16(headerExtensionProfileOneByte), h.ExtensionProfile)
+	assert.Equal(t, []byte{0x10, 0x05, 0x21, 0x01, 0x02, 0x00, 0x00, 0x00}, h.ExtensionPayload)
+
+	payload, ok := getHeaderExtension(h, 2)
+	assert.True(t, ok)
+	assert.Equal(t, []byte{0x01, 0x02}, payload)
+
+	// Replacing keeps the other elements intact
+	assert.NoError(t, setHeaderExtension(h, 1, []byte{0x06}))
+	payload, ok = getHeaderExtension(h, 1)
+	assert.True(t, ok)
+	assert.Equal(t, []byte{0x06}, payload)
+
+	_, ok = getHeaderExtension(h, 3)
+	assert.False(t, ok)
+}
+
+func TestHeaderExtension_TwoByte(t *testing.T) {
+	h := &rtp.Header{}
+
+	assert.NoError(t, setHeaderExtension(h, 1, []byte{0x05}))
+	assert.NoError(t, setHeaderExtension(h, 20, []byte{0x01}))
+
+	assert.Equal(t, uint16(headerExtensionProfileTwoByte), h.ExtensionProfile)
+	assert.Equal(t, []byte{0x01, 0x01, 0x05, 0x14, 0x01, 0x01, 0x00, 0x00}, h.ExtensionPayload)
+
+	payload, ok := getHeaderExtension(h, 1)
+	assert.True(t, ok)
+	assert.Equal(t, []byte{0x05}, payload)
+}
+
+func TestHeaderExtension_Invalid(t *testing.T) {
+	h := &rtp.Header{Extension: true, ExtensionProfile: 0x1234, ExtensionPayload: []byte{0x10, 0x00, 0x00, 0x00}}
+	_, err := parseHeaderExtensions(h)
+	assert.Error(t, err)
+
+	h = &rtp.Header{Extension: true, ExtensionProfile: headerExtensionProfileOneByte, ExtensionPayload: []byte{0x13, 0x00, 0x00, 0x00}}
+	_, err = parseHeaderExtensions(h)
+	assert.Error(t, err)
+
+	assert.Error(t, setHeaderExtension(&rtp.Header{}, 0, []byte{0x00}))
+}
+
+func TestRemapHeaderExtensions(t *testing.T) {
+	h := &rtp.Header{}
+	assert.NoError(t, setHeaderExtension(h, 1, []byte("0")))
+	assert.NoError(t, setHeaderExtension(h, 3, []byte{0x01, 0x02, 0x03}))
+	assert.NoError(t, setHeaderExtension(h, 4, []byte{0x05}))
+
+	from := map[string]uint8{MIDURI: 1, "http://www.webrtc.org/experiments/rtp-hdrext/abs-send-time": 3, PlayoutDelayURI: 4}
+	to := map[string]uint8{MIDURI: 2, "http://www.webrtc.org/experiments/rtp-hdrext/abs-send-time": 5}
+	assert.NoError(t, remapHeaderExtensions(h, from, to))
+
+	elements, err := parseHeaderExtensions(h)
+	assert.NoError(t, err)
+	assert.Equal(t, []headerExtensionElement{{2, []byte("0")}, {5, []byte{0x01, 0x02, 0x03}}}, elements)
+
+	// Nothing negotiated on the outgoing side
+	assert.NoError(t, remapHeaderExtensions(h, to, nil))
+	assert.False(t, h.Extension)
+}
+
+func TestParseExtMap(t *testing.T) {
+	testCases := []struct {
+		value       string
+		expectedID  uint8
+		expectedURI string
+		expectErr   bool
+	}{
+		{"1 urn:3gpp:video-orientation", 1, VideoOrientationURI, false},
+		{"4/sendonly urn:3gpp:video-orientation", 4, VideoOrientationURI, false},
+		{"urn:3gpp:video-orientation", 0, "", true},
+		{"0 urn:3gpp:video-orientation", 0, "", true},
+	}
+
+	for i, testCase := range testCases {
+		id, uri, err := parseExtMap(testCase.value)
+		if testCase.expectErr {
+			assert.Error(t, err, "testCase: %d %v", i, testCase)
+			continue
+		}
+		assert.NoError(t, err, "testCase: %d %v", i, testCase)
+		assert.Equal(t, testCase.expectedID, id, "testCase: %d %v", i, testCase)
+		assert.Equal(t, testCase.expectedURI, uri, "testCase: %d %v", i, testCase)
+	}
+}
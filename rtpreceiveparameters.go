@@ -1,6 +1,75 @@
 package webrtc
 
-// RTPReceiveParameters contains the RTP stack settings used by receivers
+// RTPReceiveParameters contains the RTP stack settings used by receivers. A
+// PeerConnection derives them from the negotiation, the exported ones are
+// set by the callers of RTPReceiver.Receive that use the ORTC objects
+// without SDP.
 type RTPReceiveParameters struct {
-	encodings RTPDecodingParameters
+	// Encodings are the SSRC of the stream, 0 to latch on the first
+	// undeclared one, and the SSRC of its retransmissions
+	Encodings RTPDecodingParameters
+
+	// HeaderExtensions maps the URI of the header extensions the remote
+	// sends to their ID
+	HeaderExtensions map[string]uint8
+
+	// RTCPFeedback is the RTCP feedback the remote supports, such as the
+	// NACKs requesting the retransmission of the lost packets
+	RTCPFeedback []RTCPFeedback
+
+	// reducedSizeRTCP is set when the remote negotiated reduced-size RTCP
+	reducedSizeRTCP bool
+
+	// rtxPayloadTypes maps the negotiated RTX payload types to the payload
+	// type they retransmit
+	rtxPayloadTypes map[uint8]uint8
+
+	// redPayloadTypes maps the negotiated RED payload types to the payload
+	// type of their primary encoding
+	redPayloadTypes map[uint8]uint8
+
+	// layers are the simulcast layers the stream of a declared SSRC may be,
+	// with their restrictions. The layer is named by the RID header
+	// extension of its first packet.
+	layers map[string]RIDRestrictions
+}
+
+// Clone returns a deep copy of the parameters, it shares no map or slice
+// with the original so either can be modified without affecting the other.
+func (p RTPReceiveParameters) Clone() RTPReceiveParameters {
+	c := RTPReceiveParameters{Encodings: p.Encodings, reducedSizeRTCP: p.reducedSizeRTCP}
+
+	if p.HeaderExtensions != nil {
+		c.HeaderExtensions = make(map[string]uint8, len(p.HeaderExtensions))
+		for uri, id := range p.HeaderExtensions {
+			c.HeaderExtensions[uri] = id
+		}
+	}
+
+	if p.RTCPFeedback != nil {
+		c.RTCPFeedback = append([]RTCPFeedback{}, p.RTCPFeedback...)
+	}
+
+	if p.rtxPayloadTypes != nil {
+		c.rtxPayloadTypes = make(map[uint8]uint8, len(p.rtxPayloadTypes))
+		for rtx, apt := range p.rtxPayloadTypes {
+			c.rtxPayloadTypes[rtx] = apt
+		}
+	}
+
+	if p.redPayloadTypes != nil {
+		c.redPayloadTypes = make(map[uint8]uint8, len(p.redPayloadTypes))
+		for red, primary := range p.redPayloadTypes {
+			c.redPayloadTypes[red] = primary
+		}
+	}
+
+	if p.layers != nil {
+		c.layers = make(map[string]RIDRestrictions, len(p.layers))
+		for rid, restrictions := range p.layers {
+			c.layers[rid] = restrictions
+		}
+	}
+
+	return c
 }
@@ -0,0 +1,39 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTPReceiveParametersClone(t *testing.T) {
+	parameters := RTPReceiveParameters{
+		Encodings: RTPDecodingParameters{
+			RTPCodingParameters{SSRC: 1, RTX: RTPRtxParameters{SSRC: 2}},
+		},
+		HeaderExtensions: map[string]uint8{PlayoutDelayURI: 1},
+		RTCPFeedback:     []RTCPFeedback{{Type: TypeRTCPFBNACK}},
+		rtxPayloadTypes:  map[uint8]uint8{97: 96},
+		redPayloadTypes:  map[uint8]uint8{63: 111},
+		layers:           map[string]RIDRestrictions{"q": {MaxWidth: 320}},
+	}
+
+	clone := parameters.Clone()
+	assert.Equal(t, parameters, clone)
+
+	clone.Encodings.SSRC = 3
+	clone.HeaderExtensions[VideoOrientationURI] = 2
+	clone.RTCPFeedback[0].Parameter = RTCPFBParameterPLI
+	clone.rtxPayloadTypes[99] = 98
+	clone.redPayloadTypes[64] = 109
+	clone.layers["f"] = RIDRestrictions{}
+
+	assert.Equal(t, uint32(1), parameters.Encodings.SSRC)
+	assert.Equal(t, map[string]uint8{PlayoutDelayURI: 1}, parameters.HeaderExtensions)
+	assert.Equal(t, []RTCPFeedback{{Type: TypeRTCPFBNACK}}, parameters.RTCPFeedback)
+	assert.Equal(t, map[uint8]uint8{97: 96}, parameters.rtxPayloadTypes)
+	assert.Equal(t, map[uint8]uint8{63: 111}, parameters.redPayloadTypes)
+	assert.Equal(t, map[string]RIDRestrictions{"q": {MaxWidth: 320}}, parameters.layers)
+
+	assert.Equal(t, RTPReceiveParameters{}, RTPReceiveParameters{}.Clone())
+}
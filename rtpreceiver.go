@@ -1,8 +1,12 @@
 package webrtc
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/pions/rtcp"
 	"github.com/pions/rtp"
@@ -11,6 +15,28 @@ import (
 
 // RTPReceiver allows an application to inspect the receipt of a Track
 type RTPReceiver struct {
+	// Accessed atomically, kept first for the 64-bit alignment required on
+	// 32-bit platforms
+	nackStats          nackStats
+	packetsDelivered   uint64
+	packetsDropped     uint64
+	packetsDelayed     uint64
+	packetsRateLimited uint64
+	packetsPadding     uint64
+	packetsLate        uint64
+
+	keyFrameRequestsSent       uint64
+	keyFrameRequestsSuppressed uint64
+
+	// lastPacket is the time the last RTP packet was read, in nanoseconds
+	// since the epoch, 0 until the first one
+	lastPacket int64
+
+	// reception counts the packets of the stream for the inbound-rtp stats
+	// and the Receiver Reports, which are sent when receiverReports is set
+	reception       receptionStats
+	receiverReports bool
+
 	kind      RTPCodecType
 	transport *DTLSTransport
 
@@ -18,6 +44,10 @@ type RTPReceiver struct {
 
 	Track *Track
 
+	// frames finds the first packets of the frames given to the receive
+	// PayloadTransform
+	frames frameBoundaries
+
 	closed bool
 	mu     sync.Mutex
 
@@ -29,20 +59,128 @@ type RTPReceiver struct {
 	rtcpReadStream *srtp.ReadStreamSRTCP
 	rtcpOutDone    chan struct{}
 
+	// readDeadline bounds the reads of rtpOut and rtcpOut
+	readDeadline deadline
+
+	// outLock guards the delivery to rtpOut and rtcpOut, which are closed
+	// either by their ReadLoop or by Track.Close
+	outLock       sync.Mutex
+	rtpOutClosed  bool
+	rtcpOutClosed bool
+
+	// passthroughPacket is the first packet, read to fire OnTrack, until it is
+	// read by ReadPassthroughRTP
+	passthroughPacket []byte
+
+	// paused is accessed atomically, packets are discarded while it is set
+	paused int32
+
+	headerExtensionsLock sync.RWMutex
+	playoutDelay         *PlayoutDelay
+
+	rtcpFeedback []RTCPFeedback
+
+	// reducedSizeRTCP sends the feedback without a report first
+	reducedSizeRTCP bool
+
+	// rtcpDisabled receives the Track without RTCP, set by DisableRTCP
+	rtcpDisabled bool
+
+	// jitterBuffer is nil unless SettingEngine.EnableJitterBuffer is set, it
+	// puts the packets in the Track in order
+	jitterBuffer *jitterBuffer
+
+	// drainLimiter spaces out the delivery of the packets, set by
+	// SetDrainRate
+	drainLimiter drainLimiter
+
+	// rtxSSRC is the SSRC of the retransmissions of the Track, as declared by
+	// a a=ssrc-group:FID attribute
+	rtxSSRC uint32
+
+	// nack is nil unless NACK is enabled and negotiated, the RTX stream is
+	// only read with it
+	nack            *nackGenerator
+	rtxPayloadTypes map[uint8]uint8
+	rtxReadStream   *srtp.ReadStreamSRTP
+	rtxDone         chan struct{}
+
+	// redReceived is nil unless RED is negotiated, it discards the
+	// redundant encodings of the packets already received
+	redPayloadTypes map[uint8]uint8
+	redReceived     *receivedWindow
+
+	// layers are the simulcast layers the stream of a declared SSRC may
+	// be, the Track is named after the one of its first packet
+	layers map[string]RIDRestrictions
+
+	// rtcpWriter writes the RTCP of the Track through the Interceptors of
+	// the API, it is bound once the SSRC is known
+	rtcpWriter RTCPWriter
+
+	// routedStreams delivers the stream a latching RTPReceiver of a
+	// PeerConnection gets from its rtpRouter, instead of accepting one itself
+	routedStreams <-chan routedStream
+
+	// routedRTXStreams delivers the RTX stream of the Track when its SSRC
+	// isn't declared, the rtpRouter identifies it by its payload type.
+	// rtxStopped is set once the RTX stream may no longer be started.
+	routedRTXStreams <-chan routedStream
+	rtxStopped       bool
+
+	// firstPacket is set once the first packet arrived, with its SSRC and
+	// payload type
+	onFirstPacketHandler func(ssrc uint32, payloadType uint8)
+	firstPacket          bool
+	firstSSRC            uint32
+	firstPayloadType     uint8
+
+	// keyFrameLock guards the keyframe requests, lastKeyFrameRequest is the
+	// time the last one was sent
+	keyFrameLock        sync.Mutex
+	firSequenceNumber   uint8
+	lastKeyFrameRequest time.Time
+
+	// senderReportLock guards the last Sender Report of the Track, which
+	// correlates the RTP timestamp srRTPTime with the NTP time srNTPTime. It
+	// arrived at srArrival.
+	senderReportLock sync.RWMutex
+	srReceived       bool
+	srNTPTime        uint64
+	srRTPTime        uint32
+	srArrival        time.Time
+
+	// bandwidthEstimator is nil unless the receive bandwidth estimation is
+	// enabled, its estimate is sent in REMB messages when sendREMB is set.
+	// transportCC records the packets numbered for the transport-wide
+	// feedback, it is nil unless the feedback is sent.
+	bandwidthEstimator         *receiveBandwidthEstimator
+	sendREMB                   bool
+	transportCC                *transportCCRecorder
+	onBandwidthEstimateHandler func(bps int)
+
+	// pauseLock guards the state of the RTCP PAUSE requests, pauseID is
+	// the one of the last pause
+	pauseLock    sync.Mutex
+	pauseID      uint16
+	streamPaused bool
+	hasPaused    bool
+
 	// A reference to the associated api object
 	api *API
 }
 
 // NewRTPReceiver constructs a new RTPReceiver
 func (api *API) NewRTPReceiver(kind RTPCodecType, transport *DTLSTransport) *RTPReceiver {
+	rtpBufferSize, rtcpBufferSize := api.settingEngine.receiveBufferSize(kind)
 	return &RTPReceiver{
 		kind:      kind,
 		transport: transport,
 
-		rtpOut:     make(chan *rtp.Packet, 15),
+		rtpOut:     make(chan *rtp.Packet, rtpBufferSize),
 		rtpOutDone: make(chan struct{}),
 
-		rtcpOut:     make(chan rtcp.Packet, 15),
+		rtcpOut:     make(chan rtcp.Packet, rtcpBufferSize),
 		rtcpOutDone: make(chan struct{}),
 
 		hasRecv: make(chan bool),
@@ -51,14 +189,85 @@ func (api *API) NewRTPReceiver(kind RTPCodecType, transport *DTLSTransport) *RTP
 	}
 }
 
-// Receive blocks until the Track is available
-func (r *RTPReceiver) Receive(parameters RTPReceiveParameters) chan bool {
+// Transport returns the DTLSTransport the Track is received over
+func (r *RTPReceiver) Transport() *DTLSTransport {
+	return r.transport
+}
+
+// Receive initializes the Track and starts reading from the transport, the
+// returned channel is closed once the first packet arrived.
+//
+// A zero SSRC causes the RTPReceiver to latch on the first stream with an SSRC
+// nobody else is reading. The SDP carries no SSRC for a media section that
+// sends without a=ssrc lines, which is legal for bundled media sections
+// whose streams are identified through the MID or RID header extensions. The
+// RTPReceivers of a PeerConnection are handed the stream carrying their mid
+// when the mid header extension is negotiated.
+// ErrSSRCLatchingDisabled is returned instead if latching is disabled in
+// the SettingEngine.
+//
+// The stream of a known SSRC is claimed before Receive returns: it is never
+// routed or latched on by another RTPReceiver, even if its first packet
+// arrived before the RTPReceiver opened it. When the SSRC is declared in a
+// simulcast media section, the RID and RIDRestrictions of the Track are
+// those of the layer named by the RID header extension of its first packet.
+//
+// The RTPReceiver keeps a clone of the parameters, the caller keeps the
+// ownership of the given ones and may reuse them for other receivers.
+func (r *RTPReceiver) Receive(parameters RTPReceiveParameters) (chan bool, error) {
+	parameters = parameters.Clone()
+	latching := parameters.Encodings.SSRC == 0
+	if latching && r.api.settingEngine.disableSSRCLatching {
+		return nil, ErrSSRCLatchingDisabled
+	}
+
 	// TODO atomic only allow this to fire once
 	r.Track = &Track{
-		Kind:        r.kind,
-		SSRC:        parameters.encodings.SSRC,
-		Packets:     r.rtpOut,
-		RTCPPackets: r.rtcpOut,
+		Kind:            r.kind,
+		SSRC:            parameters.Encodings.SSRC,
+		RID:             parameters.Encodings.RID,
+		RIDRestrictions: parameters.Encodings.RIDRestrictions,
+		Packets:         r.rtpOut,
+		RTCPPackets:     r.rtcpOut,
+
+		receiver: r,
+
+		headerExtensions: parameters.HeaderExtensions,
+	}
+	r.rtcpFeedback = parameters.RTCPFeedback
+	r.reducedSizeRTCP = parameters.reducedSizeRTCP
+	r.rtxSSRC = parameters.Encodings.RTX.SSRC
+	r.rtxPayloadTypes = parameters.rtxPayloadTypes
+	r.layers = parameters.layers
+	if len(parameters.redPayloadTypes) != 0 && !r.api.settingEngine.passthrough {
+		r.redPayloadTypes = parameters.redPayloadTypes
+		r.redReceived = &receivedWindow{}
+	}
+	r.receiverReports = r.api.settingEngine.receiverReports && !r.api.settingEngine.passthrough && !r.rtcpDisabled
+	if r.api.settingEngine.nack && !r.api.settingEngine.passthrough && !r.rtcpDisabled && hasRTCPFeedback(r.rtcpFeedback, RTCPFeedback{Type: TypeRTCPFBNACK}) {
+		reorder := r.api.settingEngine.nackReorder
+		rtx := r.rtxSSRC != 0 || (r.routedRTXStreams != nil && len(r.rtxPayloadTypes) != 0)
+		r.nack = newNACKGenerator(reorder.Window, reorder.Adaptive, rtx)
+	}
+	if latency := r.api.settingEngine.jitterBufferLatency; latency != 0 && !r.api.settingEngine.passthrough {
+		r.jitterBuffer = newJitterBuffer(latency, r.put)
+	}
+	if r.api.settingEngine.receiveBandwidthEstimation && !r.api.settingEngine.passthrough {
+		r.bandwidthEstimator = newReceiveBandwidthEstimator(r.api.settingEngine.bandwidthEstimationBounds())
+
+		// The transport-wide feedback supersedes REMB, as for the senders
+		_, transportCC := r.Track.headerExtensionID(TransportCCURI)
+		if !r.isRTCPDisabled() && transportCC {
+			r.transportCC = r.transport.transportCCRecorder()
+		}
+		r.sendREMB = !r.isRTCPDisabled() && !transportCC && hasRTCPFeedback(r.rtcpFeedback, RTCPFeedback{Type: TypeRTCPFBGoogREMB})
+	}
+
+	// The SSRC is only known to the RTCP ReadLoop once latched
+	ssrcKnown := make(chan uint32, 1)
+	if !latching {
+		r.transport.claimSSRC(parameters.Encodings.SSRC, r)
+		ssrcKnown <- parameters.Encodings.SSRC
 	}
 
 	// RTP ReadLoop
@@ -68,122 +277,1348 @@ func (r *RTPReceiver) Receive(parameters RTPReceiveParameters) chan bool {
 			if !payloadSet {
 				close(r.hasRecv)
 			}
-			close(r.rtpOut)
+			close(ssrcKnown)
+
+			// The recovered packets are put in the Track until the RTX stream is done
+			r.mu.Lock()
+			rtxReadStream, rtxSSRC := r.rtxReadStream, r.rtxSSRC
+			r.rtxStopped = true
+			r.mu.Unlock()
+			if rtxReadStream != nil {
+				if err := rtxReadStream.Close(); err != nil {
+					r.api.log.Warnf("Failed to close RTX ReadStream: %v", err)
+				}
+				<-r.rtxDone
+			}
+			if r.nack != nil && rtxSSRC != 0 {
+				r.transport.releaseSSRC(rtxSSRC)
+			}
+
+			if r.jitterBuffer != nil {
+				r.jitterBuffer.close()
+			}
+			r.closeRTPOut()
 			close(r.rtpOutDone)
 		}()
 
 		srtpSession, err := r.transport.getSRTPSession()
 		if err != nil {
-			pcLog.Warnf("Failed to open SRTPSession, Track done for: %v %d \n", err, parameters.encodings.SSRC)
+			r.api.log.Warnf("Failed to open SRTPSession, Track done for: %v %d \n", err, parameters.Encodings.SSRC)
 			return
 		}
 
-		readStream, err := srtpSession.OpenReadStream(parameters.encodings.SSRC)
-		if err != nil {
-			pcLog.Warnf("Failed to open RTCP ReadStream, Track done for: %v %d \n", err, parameters.encodings.SSRC)
-			return
+		var readStream *srtp.ReadStreamSRTP
+		var firstPacket []byte
+		if latching {
+			var ssrc uint32
+			if r.routedStreams != nil {
+				stream, ok := <-r.routedStreams
+				if !ok {
+					r.api.log.Warnf("No stream was routed to the RTPReceiver, Track done")
+					return
+				}
+				readStream, ssrc, firstPacket = stream.readStream, stream.ssrc, stream.firstPacket
+			} else if readStream, ssrc, err = srtpSession.AcceptStream(); err != nil {
+				r.api.log.Warnf("Failed to latch on an undeclared SSRC, Track done for: %v \n", err)
+				return
+			}
+			r.api.log.Debugf("Latched on undeclared SSRC %d", ssrc)
+			r.transport.claimSSRC(ssrc, r)
+			r.Track.SSRC = ssrc
+			ssrcKnown <- ssrc
+		} else {
+			if err = r.retryReadStream(parameters.Encodings.SSRC, func() (openErr error) {
+				readStream, openErr = srtpSession.OpenReadStream(parameters.Encodings.SSRC)
+				return openErr
+			}); err != nil {
+				r.api.log.Warnf("Failed to open RTCP ReadStream, Track done for: %v %d \n", err, parameters.Encodings.SSRC)
+				return
+			}
+		}
+		reader := r.srtpReader(readStream, firstPacket)
+		if !r.api.settingEngine.passthrough {
+			info := r.streamInfo(r.Track.SSRC)
+			reader = r.api.bindRemoteStream(info, reader)
+			rtcpWriter := r.api.bindRTCPWriter(info, RTCPWriterFunc(r.writeSRTCP))
+			r.mu.Lock()
+			r.rtcpWriter = rtcpWriter
+			r.mu.Unlock()
 		}
 		r.mu.Lock()
 		r.rtpReadStream = readStream
 		r.mu.Unlock()
 
-		readBuf := make([]byte, receiveMTU)
+		if r.nack != nil && r.rtxSSRC != 0 {
+			r.receiveRTX(srtpSession)
+		} else if r.routedRTXStreams != nil {
+			go r.receiveRoutedRTX()
+		}
+
+		var clockRate uint32
+		var clockRatePayloadType uint8
 		for {
-			rtpLen, err := readStream.Read(readBuf)
+			packet, err := reader.ReadRTP()
 			if err != nil {
-				pcLog.Warnf("Failed to read, Track done for: %v %d \n", err, parameters.encodings.SSRC)
+				r.api.log.Warnf("Failed to read, Track done for: %v %d \n", err, r.Track.SSRC)
 				return
 			}
+			rtpPacket := packet
+			rtpLen := rtpPacketSize(&rtpPacket.Header, rtpPacket.Payload)
+			received := r.receiveTime(rtpPacket.SSRC, rtpPacket.SequenceNumber)
+			r.packetReceived(received)
+			if clockRate == 0 || rtpPacket.PayloadType != clockRatePayloadType {
+				clockRate, clockRatePayloadType = r.clockRate(rtpPacket.PayloadType), rtpPacket.PayloadType
+			}
+			r.reception.add(rtpPacket, received, clockRate)
+			if r.receiverReports {
+				r.sendReceiverReport(rtpPacket.SSRC, received)
+			}
+			r.estimateBandwidth(rtpPacket, clockRate, rtpLen, received)
+			padding := isPaddingOnly(rtpPacket)
 
-			var rtpPacket rtp.Packet
-			if err = rtpPacket.Unmarshal(append([]byte{}, readBuf[:rtpLen]...)); err != nil {
-				pcLog.Warnf("Failed to unmarshal RTP packet, discarding: %v \n", err)
-				continue
+			var redundant []*rtp.Packet
+			if r.redReceived != nil && !padding {
+				var primary *rtp.Packet
+				if primary, redundant, err = r.unwrapRED(rtpPacket); err != nil {
+					r.api.log.Warnf("Failed to unwrap RED packet, discarding: %v \n", err)
+					continue
+				}
+				rtpPacket = primary
+				r.redReceived.push(rtpPacket.SequenceNumber)
+			}
+
+			r.readHeaderExtensions(rtpPacket)
+			if r.nack != nil {
+				r.detectLoss(rtpPacket.SequenceNumber)
 			}
 
 			if !payloadSet {
+				r.readLayer(rtpPacket)
 				r.Track.PayloadType = rtpPacket.PayloadType
 				payloadSet = true
 				close(r.hasRecv)
+				r.onFirstPacket(rtpPacket.SSRC, rtpPacket.PayloadType)
+			}
+
+			// The stream is left to ReadPassthroughRTP
+			if r.api.settingEngine.passthrough {
+				r.mu.Lock()
+				r.passthroughPacket = rtpPacket.Raw
+				r.mu.Unlock()
+				return
 			}
 
-			select {
-			case r.rtpOut <- &rtpPacket:
-			default:
+			// The padding-only packets still count for the loss detection
+			// above, they carry no media to decode
+			if padding {
+				atomic.AddUint64(&r.packetsPadding, 1)
+				if !r.api.settingEngine.deliverPadding {
+					continue
+				}
 			}
+
+			if !r.drain(rtpLen) {
+				continue
+			}
+			r.deliverRedundant(redundant, received)
+			r.deliver(rtpPacket)
 		}
 	}()
 
+	if r.isRTCPDisabled() {
+		r.closeRTCPOut()
+		close(r.rtcpOutDone)
+		return r.hasRecv, nil
+	}
+
 	// RTCP ReadLoop
 	go func() {
 		defer func() {
-			close(r.rtcpOut)
+			r.closeRTCPOut()
 			close(r.rtcpOutDone)
 		}()
 
+		ssrc, ok := <-ssrcKnown
+		if !ok {
+			return
+		}
+
 		srtcpSession, err := r.transport.getSRTCPSession()
 		if err != nil {
-			pcLog.Warnf("Failed to open SRTCPSession, Track done for: %v %d \n", err, parameters.encodings.SSRC)
+			r.api.log.Warnf("Failed to open SRTCPSession, Track done for: %v %d \n", err, ssrc)
 			return
 		}
 
-		readStream, err := srtcpSession.OpenReadStream(parameters.encodings.SSRC)
-		if err != nil {
-			pcLog.Warnf("Failed to open RTCP ReadStream, Track done for: %v %d \n", err, parameters.encodings.SSRC)
+		var readStream *srtp.ReadStreamSRTCP
+		if err = r.retryReadStream(ssrc, func() (openErr error) {
+			readStream, openErr = srtcpSession.OpenReadStream(ssrc)
+			return openErr
+		}); err != nil {
+			r.api.log.Warnf("Failed to open RTCP ReadStream, Track done for: %v %d \n", err, ssrc)
 			return
 		}
 		r.mu.Lock()
 		r.rtcpReadStream = readStream
 		r.mu.Unlock()
 
-		readBuf := make([]byte, receiveMTU)
+		// The stream is left to ReadPassthroughRTCP
+		if r.api.settingEngine.passthrough {
+			return
+		}
+
+		reader := r.api.bindRTCPReader(r.streamInfo(ssrc), r.Track.srtcpReader(readStream, r.api.log))
 		for {
-			rtcpLen, err := readStream.Read(readBuf)
+			packets, err := reader.ReadRTCP()
 			if err != nil {
-				pcLog.Warnf("Failed to read, Track done for: %v %d \n", err, parameters.encodings.SSRC)
+				r.api.log.Warnf("Failed to read, Track done for: %v %d \n", err, ssrc)
 				return
 			}
+			for _, rtcpPacket := range packets {
+				r.readSenderReport(rtcpPacket)
+				r.deliverRTCP(rtcpPacket)
+			}
+		}
+	}()
+
+	return r.hasRecv, nil
+}
+
+// DisableRTCP receives the Track without RTCP, for the one-way streams
+// whose sender takes no feedback. Unlike SettingEngine.DisableRTCP it only
+// applies to this RTPReceiver: the SRTCP stream of the Track is never opened,
+// the SRTP stream is read as usual, and no NACK, keyframe request or other
+// RTCP is sent for it. It must be called before Receive, ErrRTPReceiverStarted
+// is returned afterwards.
+func (r *RTPReceiver) DisableRTCP() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Track != nil {
+		return ErrRTPReceiverStarted
+	}
+	r.rtcpDisabled = true
+	return nil
+}
+
+// SetDrainRate caps the bitrate the packets of the Track are delivered at,
+// for the forwarding units that share their capacity between the streams
+// they receive. The packets are spaced out to the bitrate, which holds the
+// stream back so the congestion control of the sender slows it down. A
+// packet that would wait longer than maxDelay is dropped. The packets are
+// counted in RTPReceiverStats.PacketsDelayed and PacketsRateLimited, the
+// ones read with ReadPassthroughRTP aren't capped. ErrInvalidDrainRate is
+// returned for a negative bitrate or delay, a bitrate of 0 removes the cap.
+func (r *RTPReceiver) SetDrainRate(bitrate int, maxDelay time.Duration) error {
+	if bitrate < 0 || maxDelay < 0 {
+		return ErrInvalidDrainRate
+	}
+	r.drainLimiter.set(bitrate, maxDelay)
+	return nil
+}
+
+// drain waits for a packet of size bytes to be delivered at the drain
+// rate, it returns false if the packet is dropped
+func (r *RTPReceiver) drain(size int) bool {
+	wait, ok := r.drainLimiter.admit(size, time.Now())
+	if !ok {
+		atomic.AddUint64(&r.packetsRateLimited, 1)
+		return false
+	}
+	if wait > 0 {
+		atomic.AddUint64(&r.packetsDelayed, 1)
+		time.Sleep(wait)
+	}
+	return true
+}
+
+// isRTCPDisabled returns whether the Track is received without RTCP, by the
+// RTPReceiver or every stream of the SettingEngine
+func (r *RTPReceiver) isRTCPDisabled() bool {
+	return r.rtcpDisabled || r.api.settingEngine.disableRTCP
+}
+
+// ReadRTCP returns the next RTCP packet about the Track, it competes with
+// the readers of Track.RTCPPackets. It fails with ErrSRTCPNotEstablished if
+// the Track is received without RTCP, with ErrReadTimeout once the read
+// deadline elapses, and with io.EOF once the RTPReceiver stopped.
+func (r *RTPReceiver) ReadRTCP() (rtcp.Packet, error) {
+	return r.ReadRTCPContext(context.Background())
+}
+
+// ReadRTCPContext is ReadRTCP bound to a context, it fails with the error of
+// the context once it is done.
+func (r *RTPReceiver) ReadRTCPContext(ctx context.Context) (rtcp.Packet, error) {
+	if r.isRTCPDisabled() {
+		return nil, ErrSRTCPNotEstablished
+	}
+
+	for {
+		if r.readDeadline.exceeded() {
+			return nil, ErrReadTimeout
+		}
+		elapsed, changed, stop := r.readDeadline.wait()
+		select {
+		case packet, ok := <-r.rtcpOut:
+			stop()
+			if !ok {
+				return nil, io.EOF
+			}
+			return packet, nil
+		case <-elapsed:
+			return nil, ErrReadTimeout
+		case <-changed:
+			stop()
+		case <-ctx.Done():
+			stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// ReadRTCPBatch reads the RTCP packets about the Track into packets and
+// returns how many it read. It waits for the first one as ReadRTCP does,
+// then takes the ones already received without waiting, until packets is
+// full. A SFU reading the RTCP of many receivers wakes up once per batch
+// rather than once per packet. The RTCP is already received from the
+// socket shared by the streams of the PeerConnection, a batch saves no
+// system call.
+func (r *RTPReceiver) ReadRTCPBatch(packets []rtcp.Packet) (int, error) {
+	if len(packets) == 0 {
+		return 0, nil
+	}
+
+	packet, err := r.ReadRTCP()
+	if err != nil {
+		return 0, err
+	}
+	packets[0] = packet
+
+	n := 1
+	for ; n < len(packets); n++ {
+		select {
+		case packet, ok := <-r.rtcpOut:
+			if !ok {
+				return n, nil
+			}
+			packets[n] = packet
+		default:
+			return n, nil
+		}
+	}
+	return n, nil
+}
+
+// OnFirstPacket sets an event handler which is invoked once, when the first
+// RTP packet of the Track arrives after Receive, with the SSRC and payload
+// type it carries. Unlike the binding of the Track, it waits for media and
+// measures when it actually starts flowing, the SSRC confirms which stream
+// was latched on when the SSRC was undeclared. The handler is invoked right
+// away if the first packet already arrived, as it has for the RTPReceivers a
+// PeerConnection hands out in OnTrack.
+func (r *RTPReceiver) OnFirstPacket(f func(ssrc uint32, payloadType uint8)) {
+	r.mu.Lock()
+	r.onFirstPacketHandler = f
+	firstPacket, ssrc, payloadType := r.firstPacket, r.firstSSRC, r.firstPayloadType
+	r.mu.Unlock()
+
+	if firstPacket && f != nil {
+		go f(ssrc, payloadType)
+	}
+}
+
+func (r *RTPReceiver) onFirstPacket(ssrc uint32, payloadType uint8) (done chan struct{}) {
+	// The handlers set from now on are invoked by OnFirstPacket
+	r.mu.Lock()
+	r.firstPacket = true
+	r.firstSSRC, r.firstPayloadType = ssrc, payloadType
+	hdlr := r.onFirstPacketHandler
+	r.mu.Unlock()
+
+	done = make(chan struct{})
+	if hdlr == nil {
+		close(done)
+		return
+	}
+
+	go func() {
+		hdlr(ssrc, payloadType)
+		close(done)
+	}()
+
+	return
+}
+
+// readLayer names the Track of a declared SSRC after the simulcast layer its
+// first packet carries, before OnTrack fires
+func (r *RTPReceiver) readLayer(packet *rtp.Packet) {
+	if len(r.layers) == 0 || r.Track.RID != "" {
+		return
+	}
+	id, ok := r.Track.headerExtensionID(RIDURI)
+	if !ok {
+		return
+	}
+	rid, ok := getHeaderExtension(&packet.Header, id)
+	if !ok {
+		return
+	}
+	if restrictions, ok := r.layers[string(rid)]; ok {
+		r.Track.RID = string(rid)
+		r.Track.RIDRestrictions = restrictions
+	}
+}
+
+// receiveTime returns the time a RTP packet of the remote was received by
+// the transport, the current time if it wasn't recorded
+func (r *RTPReceiver) receiveTime(ssrc uint32, sequenceNumber uint16) time.Time {
+	if received, ok := r.transport.receiveTimes.lookup(ssrc, sequenceNumber); ok {
+		return received
+	}
+	return time.Now()
+}
+
+// clockRate returns the clock rate of the codec of a payload type, 0 when it
+// isn't registered
+func (r *RTPReceiver) clockRate(payloadType uint8) uint32 {
+	codec, err := r.api.mediaEngine.getCodec(payloadType)
+	if err != nil {
+		return 0
+	}
+	return codec.ClockRate
+}
+
+// packetReceived records the arrival of a RTP packet for IsReceiving
+func (r *RTPReceiver) packetReceived(now time.Time) {
+	atomic.StoreInt64(&r.lastPacket, now.UnixNano())
+}
+
+// IsReceiving returns whether media is flowing: a RTP packet arrived within
+// the timeout set with SettingEngine.SetReceivingTimeout, 2 seconds by
+// default. Unlike the ICE and DTLS states, which stay connected while the
+// remote is silent, it follows the packets themselves, paused RTPReceivers
+// and closed Tracks included. It is false until the first packet arrives.
+func (r *RTPReceiver) IsReceiving() bool {
+	last := atomic.LoadInt64(&r.lastPacket)
+	if last == 0 {
+		return false
+	}
+	return time.Since(time.Unix(0, last)) < r.api.settingEngine.receivingTimeout()
+}
+
+// deliver puts a packet in the Track, through the jitter buffer when there
+// is one, unless the RTPReceiver is paused
+func (r *RTPReceiver) deliver(packet *rtp.Packet) {
+	if r.isPaused() {
+		return
+	}
+
+	if transform := r.api.settingEngine.payloadTransform.Receive; transform != nil {
+		if err := transformPayload(transform, PayloadTransformInfo{Receiver: r, Track: r.Track}, r.kind, &r.frames, packet); err != nil {
+			r.api.log.Warnf("Failed to transform the payload of %d, discarding: %v", packet.SSRC, err)
+			return
+		}
+	}
+
+	if r.jitterBuffer != nil {
+		if !r.jitterBuffer.push(packet, time.Now()) {
+			atomic.AddUint64(&r.packetsLate, 1)
+		}
+		return
+	}
+	r.put(packet)
+}
+
+// put puts a packet in the Packets of the Track unless it is closed. If the
+// Track isn't read fast enough, the packet or the oldest one buffered is
+// dropped as the drop policy of the SettingEngine says.
+func (r *RTPReceiver) put(packet *rtp.Packet) {
+	r.outLock.Lock()
+	defer r.outLock.Unlock()
+	if r.rtpOutClosed {
+		return
+	}
+
+	for {
+		select {
+		case r.rtpOut <- packet:
+			atomic.AddUint64(&r.packetsDelivered, 1)
+			return
+		default:
+		}
+
+		atomic.AddUint64(&r.packetsDropped, 1)
+		if r.api.settingEngine.receiveDropPolicy != ReceiveDropPolicyOldest {
+			return
+		}
+		// The readers may take the oldest packet first, the buffer has room
+		// either way
+		select {
+		case <-r.rtpOut:
+		default:
+		}
+	}
+}
+
+// deliverRTCP puts a packet in the RTCPPackets of the Track unless it is
+// closed, following the drop policy as put does
+func (r *RTPReceiver) deliverRTCP(packet rtcp.Packet) {
+	r.outLock.Lock()
+	defer r.outLock.Unlock()
+	if r.rtcpOutClosed {
+		return
+	}
+
+	for {
+		select {
+		case r.rtcpOut <- packet:
+			return
+		default:
+		}
+
+		if r.api.settingEngine.receiveDropPolicy != ReceiveDropPolicyOldest {
+			return
+		}
+		select {
+		case <-r.rtcpOut:
+		default:
+		}
+	}
+}
+
+func (r *RTPReceiver) closeRTPOut() {
+	r.outLock.Lock()
+	defer r.outLock.Unlock()
+	if !r.rtpOutClosed {
+		r.rtpOutClosed = true
+		close(r.rtpOut)
+	}
+}
+
+func (r *RTPReceiver) closeRTCPOut() {
+	r.outLock.Lock()
+	defer r.outLock.Unlock()
+	if !r.rtcpOutClosed {
+		r.rtcpOutClosed = true
+		close(r.rtcpOut)
+	}
+}
+
+// detectLoss records a packet of the Track and NACKs the missing ones
+func (r *RTPReceiver) detectLoss(sequenceNumber uint16) {
+	// A late packet that was NACKed is a retransmission on the media stream,
+	// or the original that was reordered when the retransmissions use RTX
+	if r.nack.push(sequenceNumber, time.Now()) {
+		if r.nack.rtx {
+			atomic.AddUint64(&r.nackStats.spuriousNACKs, 1)
+		} else {
+			atomic.AddUint64(&r.nackStats.retransmitsReceived, 1)
+			atomic.AddUint64(&r.nackStats.packetsRecovered, 1)
+		}
+	}
+
+	missing := r.nack.pending(time.Now())
+	if len(missing) == 0 {
+		return
+	}
+
+	pairs := nackPairs(missing)
+	if len(pairs) > rtcpMaxNACKPairs {
+		pairs = pairs[:rtcpMaxNACKPairs]
+	}
+	if err := r.writeRTCP(&rtcp.TransportLayerNack{MediaSSRC: r.Track.SSRC, Nacks: pairs}); err != nil {
+		r.api.log.Warnf("Failed to send NACK: %v", err)
+		return
+	}
+	atomic.AddUint64(&r.nackStats.nacksSent, 1)
+}
+
+// retryReadStream calls open until it opens the ReadStream of ssrc, retrying
+// as configured by SetReadStreamRetry while the failure can heal
+func (r *RTPReceiver) retryReadStream(ssrc uint32, open func() error) error {
+	return retryReadStream(r.api.settingEngine.readStreamRetry, ssrc, func() bool {
+		r.mu.Lock()
+		closed := r.closed
+		r.mu.Unlock()
+		state := r.transport.State()
+		return closed || state == DTLSTransportStateClosed || state == DTLSTransportStateFailed
+	}, open)
+}
+
+// retryReadStream calls open up to 1+retry.Attempts times, doubling the
+// backoff between the attempts. A zero SSRC is never retried, and it gives
+// up as soon as permanent reports that the failure can't heal.
+func retryReadStream(retry readStreamRetry, ssrc uint32, permanent func() bool, open func() error) error {
+	backoff := retry.Backoff
+	for attempt := 0; ; attempt++ {
+		err := open()
+		if err == nil || attempt >= retry.Attempts || ssrc == 0 || permanent() {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// receiveRTX reads the RTX stream of the Track, the retransmitted packets
+// are put in the Track if they are still missing
+func (r *RTPReceiver) receiveRTX(srtpSession *srtp.SessionSRTP) {
+	r.transport.claimSSRC(r.rtxSSRC, r)
+	var readStream *srtp.ReadStreamSRTP
+	if err := r.retryReadStream(r.rtxSSRC, func() (openErr error) {
+		readStream, openErr = srtpSession.OpenReadStream(r.rtxSSRC)
+		return openErr
+	}); err != nil {
+		r.api.log.Warnf("Failed to open RTX ReadStream: %v %d \n", err, r.rtxSSRC)
+		return
+	}
+
+	r.mu.Lock()
+	r.rtxReadStream = readStream
+	r.rtxDone = make(chan struct{})
+	r.mu.Unlock()
+
+	go r.readRTX(readStream, r.rtxSSRC, nil)
+}
+
+// receiveRoutedRTX reads the RTX stream of the Track once the rtpRouter
+// delivers it, until then the Track is received without retransmissions.
+// Without NACK nothing is retransmitted, a RTX stream is only probing for
+// bandwidth and it is drained.
+func (r *RTPReceiver) receiveRoutedRTX() {
+	var stream routedStream
+	select {
+	case routed, ok := <-r.routedRTXStreams:
+		if !ok {
+			return
+		}
+		stream = routed
+	case <-r.rtpOutDone:
+		return
+	}
+
+	if r.nack == nil {
+		drainRTPStream(r.transport, stream.readStream, stream.ssrc, stream.firstPacket)
+		return
+	}
+
+	r.mu.Lock()
+	if r.rtxStopped {
+		r.mu.Unlock()
+		if err := stream.readStream.Close(); err != nil {
+			r.api.log.Warnf("Failed to close RTX ReadStream: %v", err)
+		}
+		return
+	}
+	r.rtxSSRC = stream.ssrc
+	r.rtxReadStream = stream.readStream
+	r.rtxDone = make(chan struct{})
+	r.mu.Unlock()
+
+	r.api.log.Debugf("Receiving the undeclared RTX SSRC %d for %d", stream.ssrc, r.Track.SSRC)
+	r.transport.claimSSRC(stream.ssrc, r)
+	r.readRTX(stream.readStream, stream.ssrc, stream.firstPacket)
+}
+
+// srtpReader reads the RTP packets of the Track from its SRTP stream,
+// starting with firstPacket when it was already read. The packets failing to
+// unmarshal are discarded.
+func (r *RTPReceiver) srtpReader(readStream *srtp.ReadStreamSRTP, firstPacket []byte) RTPReader {
+	readBuf := make([]byte, receiveMTU)
+	return RTPReaderFunc(func() (*rtp.Packet, error) {
+		for {
+			rtpLen := copy(readBuf, firstPacket)
+			if firstPacket != nil {
+				firstPacket = nil
+			} else {
+				var err error
+				if rtpLen, err = readStream.Read(readBuf); err != nil {
+					return nil, err
+				}
+			}
 
-			rtcpPacket, _, err := rtcp.Unmarshal(append([]byte{}, readBuf[:rtcpLen]...))
+			packet, err := newReceivedPacket(readBuf[:rtpLen])
 			if err != nil {
-				pcLog.Warnf("Failed to unmarshal RTCP packet, discarding: %v \n", err)
+				r.api.log.Warnf("Failed to unmarshal RTP packet, discarding: %v \n", err)
 				continue
 			}
-			select {
-			case r.rtcpOut <- rtcpPacket:
-			default:
+			return packet, nil
+		}
+	})
+}
+
+// readRTX reads the packets of the RTX stream of the Track, starting with
+// the one it was identified by if any, until it is closed
+func (r *RTPReceiver) readRTX(readStream *srtp.ReadStreamSRTP, rtxSSRC uint32, firstPacket []byte) {
+	defer close(r.rtxDone)
+
+	readBuf := make([]byte, receiveMTU)
+	for {
+		rtpLen := copy(readBuf, firstPacket)
+		if firstPacket != nil {
+			firstPacket = nil
+		} else {
+			var err error
+			if rtpLen, err = readStream.Read(readBuf); err != nil {
+				r.api.log.Debugf("RTX stream done: %v %d", err, rtxSSRC)
+				return
 			}
 		}
-	}()
 
-	return r.hasRecv
+		var rtx rtp.Packet
+		if err := rtx.Unmarshal(append([]byte{}, readBuf[:rtpLen]...)); err != nil {
+			r.api.log.Warnf("Failed to unmarshal RTX packet, discarding: %v \n", err)
+			continue
+		}
+		atomic.AddUint64(&r.nackStats.retransmitsReceived, 1)
+		if r.transportCC != nil {
+			r.recordTransportCC(&rtx.Header, r.receiveTime(rtx.SSRC, rtx.SequenceNumber))
+		}
+
+		payloadType, ok := r.rtxPayloadTypes[rtx.PayloadType]
+		if !ok {
+			payloadType = r.Track.PayloadType
+		}
+		packet, err := unmarshalRTX(&rtx, r.Track.SSRC, payloadType)
+		if err != nil {
+			r.api.log.Warnf("Failed to unwrap RTX packet, discarding: %v \n", err)
+			continue
+		}
+
+		if r.nack.recover(packet.SequenceNumber) {
+			atomic.AddUint64(&r.nackStats.packetsRecovered, 1)
+			// The recovered packet was received with its retransmission
+			r.transport.receiveTimes.record(packet.SSRC, packet.SequenceNumber, r.receiveTime(rtx.SSRC, rtx.SequenceNumber))
+			r.deliver(packet)
+		}
+	}
+}
+
+// unwrapRED returns the primary encoding of a RED packet and its redundant
+// encodings, the other packets are returned as they are
+func (r *RTPReceiver) unwrapRED(packet *rtp.Packet) (*rtp.Packet, []*rtp.Packet, error) {
+	if _, ok := r.redPayloadTypes[packet.PayloadType]; !ok {
+		return packet, nil, nil
+	}
+
+	packets, err := unmarshalRED(packet)
+	if err != nil {
+		return nil, nil, err
+	}
+	return packets[len(packets)-1], packets[:len(packets)-1], nil
 }
 
-// Stop irreversibly stops the RTPReceiver
-func (r *RTPReceiver) Stop() error {
+// deliverRedundant puts in the Track the redundant encodings of the packets
+// that were lost, ahead of the primary encoding carrying them. They were
+// received with it.
+func (r *RTPReceiver) deliverRedundant(packets []*rtp.Packet, received time.Time) {
+	for _, packet := range packets {
+		if !r.redReceived.push(packet.SequenceNumber) {
+			continue
+		}
+		r.transport.receiveTimes.record(packet.SSRC, packet.SequenceNumber, received)
+		if r.nack != nil {
+			r.nack.recover(packet.SequenceNumber)
+		}
+		r.deliver(packet)
+	}
+}
+
+// readSenderReport keeps the timestamps of a Sender Report about the Track
+func (r *RTPReceiver) readSenderReport(packet rtcp.Packet) {
+	sr, ok := packet.(*rtcp.SenderReport)
+	if !ok || sr.SSRC != r.Track.SSRC {
+		return
+	}
+
+	r.senderReportLock.Lock()
+	defer r.senderReportLock.Unlock()
+	r.srReceived = true
+	r.srNTPTime = sr.NTPTime
+	r.srRTPTime = sr.RTPTime
+	r.srArrival = time.Now()
+}
+
+// captureTime maps a RTP timestamp of the Track to the wall-clock time of
+// the sender, through the last Sender Report
+func (r *RTPReceiver) captureTime(timestamp uint32, clockRate uint32) (time.Time, bool) {
+	r.senderReportLock.RLock()
+	defer r.senderReportLock.RUnlock()
+	if !r.srReceived || clockRate == 0 {
+		return time.Time{}, false
+	}
+
+	// The timestamps wrap around, the packet is within half the range of
+	// the report on either side
+	elapsed := int64(int32(timestamp-r.srRTPTime)) * int64(time.Second) / int64(clockRate)
+	return fromNTPTime(r.srNTPTime).Add(time.Duration(elapsed)), true
+}
+
+// sendReceiverReport sends a Receiver Report about the stream once the
+// report interval elapsed, see SettingEngine.EnableReceiverReports. The
+// remote gets its round-trip time from the last Sender Report fields.
+func (r *RTPReceiver) sendReceiverReport(ssrc uint32, now time.Time) {
+	report, ok := r.reception.dueReport(now, r.api.settingEngine.rtcpReport.ReceiverInterval)
+	if !ok {
+		return
+	}
+	report.SSRC = ssrc
+
+	// https://tools.ietf.org/html/rfc3550#section-6.4.1, the delay is in
+	// units of 1/65536 seconds
+	r.senderReportLock.RLock()
+	if r.srReceived {
+		report.LastSenderReport = uint32(r.srNTPTime >> 16)
+		report.Delay = uint32(now.Sub(r.srArrival) * 65536 / time.Second)
+	}
+	r.senderReportLock.RUnlock()
+
+	if err := r.writeRTCP(&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{report}}); err != nil {
+		r.api.log.Warnf("Failed to send Receiver Report for %d: %v", ssrc, err)
+	}
+}
+
+// OnBandwidthEstimate sets an event handler which is invoked each time the
+// estimate of the bitrate the Track can be received at is updated, in bits
+// per second, every 100ms while packets arrive. It is only estimated with
+// SettingEngine.EnableReceiveBandwidthEstimation, which sends the estimate to
+// the remote as well.
+func (r *RTPReceiver) OnBandwidthEstimate(f func(bps int)) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.onBandwidthEstimateHandler = f
+}
 
-	if r.closed {
-		return fmt.Errorf("RTPReceiver has already been closed")
+func (r *RTPReceiver) onBandwidthEstimate(bps int) (done chan struct{}) {
+	r.mu.Lock()
+	hdlr := r.onBandwidthEstimateHandler
+	r.mu.Unlock()
+
+	done = make(chan struct{})
+	if hdlr == nil {
+		close(done)
+		return
+	}
+
+	go func() {
+		hdlr(bps)
+		close(done)
+	}()
+
+	return
+}
+
+// estimateBandwidth updates the receive estimate with a packet of the Track,
+// and sends the congestion control feedback once due
+func (r *RTPReceiver) estimateBandwidth(packet *rtp.Packet, clockRate uint32, size int, received time.Time) {
+	if r.transportCC != nil {
+		r.recordTransportCC(&packet.Header, received)
+		if feedback, ok := r.transportCC.dueFeedback(received); ok {
+			feedback.MediaSSRC = packet.SSRC
+			r.sendBandwidthFeedback(packet.SSRC, feedback)
+		}
+	}
+
+	if r.bandwidthEstimator == nil {
+		return
+	}
+	// The abs-send-time stands for the RTP timestamp when negotiated, it is
+	// shifted to wrap around on 32 bits like the timestamps
+	timestamp := packet.Timestamp
+	if sendTime, ok := r.Track.absSendTime(&packet.Header); ok {
+		timestamp, clockRate = sendTime<<8, 1<<(absSendTimeFractionBits+8)
+	}
+	estimate, ok := r.bandwidthEstimator.add(timestamp, clockRate, size, received)
+	if !ok {
+		return
+	}
+	r.onBandwidthEstimate(estimate)
+	if r.sendREMB && r.bandwidthEstimator.dueREMB(estimate, received) {
+		r.sendBandwidthFeedback(packet.SSRC, &ReceiverEstimatedMaximumBitrate{Bitrate: uint64(estimate), SSRCs: []uint32{packet.SSRC}})
+	}
+}
+
+// recordTransportCC records the arrival of a packet carrying a transport-wide
+// sequence number
+func (r *RTPReceiver) recordTransportCC(header *rtp.Header, received time.Time) {
+	id, ok := r.Track.headerExtensionID(TransportCCURI)
+	if !ok {
+		return
+	}
+	if payload, ok := getHeaderExtension(header, id); ok && len(payload) == 2 {
+		r.transportCC.record(uint16(payload[0])<<8|uint16(payload[1]), received)
+	}
+}
+
+// sendBandwidthFeedback sends a congestion control feedback message after the
+// current reception report of the stream, which routes the compound packet
+// to the RTPSender of the stream on the remote
+func (r *RTPReceiver) sendBandwidthFeedback(ssrc uint32, feedback rtcp.Packet) {
+	report, _ := r.reception.currentReport()
+	report.SSRC = ssrc
+	if err := r.writeRTCP(&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{report}}, feedback); err != nil {
+		r.api.log.Warnf("Failed to send bandwidth feedback for %d: %v", ssrc, err)
+	}
+}
+
+// NACKStats returns the NACKs sent and the retransmissions received by the
+// RTPReceiver when NACK is enabled in the SettingEngine
+func (r *RTPReceiver) NACKStats() NACKStats {
+	return r.nackStats.snapshot()
+}
+
+// stats returns the counters of the RTPReceiver, once started
+func (r *RTPReceiver) stats() (RTPReceiverStats, bool) {
+	ssrc, err := r.receivingSSRC()
+	if err != nil {
+		return RTPReceiverStats{}, false
+	}
+
+	return RTPReceiverStats{
+		TrackID:          r.Track.ID,
+		SSRC:             ssrc,
+		PacketsDelivered: atomic.LoadUint64(&r.packetsDelivered),
+		PacketsDropped:   atomic.LoadUint64(&r.packetsDropped),
+		NACK:             r.NACKStats(),
+		KeyFrameRequests: r.KeyFrameRequestStats(),
+
+		PacketsDelayed:     atomic.LoadUint64(&r.packetsDelayed),
+		PacketsRateLimited: atomic.LoadUint64(&r.packetsRateLimited),
+		PacketsPadding:     atomic.LoadUint64(&r.packetsPadding),
+		PacketsLate:        atomic.LoadUint64(&r.packetsLate),
+	}, true
+}
+
+// readHeaderExtensions keeps the values of the negotiated header extensions
+// carried by an incoming packet
+func (r *RTPReceiver) readHeaderExtensions(packet *rtp.Packet) {
+	id, ok := r.Track.headerExtensionID(PlayoutDelayURI)
+	if !ok {
+		return
+	}
+
+	payload, ok := getHeaderExtension(&packet.Header, id)
+	if !ok {
+		return
+	}
+
+	p, err := unmarshalPlayoutDelay(payload)
+	if err != nil {
+		r.api.log.Warnf("Failed to unmarshal playout delay, discarding: %v \n", err)
+		return
+	}
+
+	r.headerExtensionsLock.Lock()
+	r.playoutDelay = &p
+	r.headerExtensionsLock.Unlock()
+}
+
+// PlayoutDelay returns the last playout delay hinted by the remote, if the
+// playout-delay extension was negotiated and received
+func (r *RTPReceiver) PlayoutDelay() (PlayoutDelay, bool) {
+	r.headerExtensionsLock.RLock()
+	defer r.headerExtensionsLock.RUnlock()
+
+	if r.playoutDelay == nil {
+		return PlayoutDelay{}, false
+	}
+	return *r.playoutDelay, true
+}
+
+// ReadRTP returns the next RTP packet of the Track, it competes with the
+// readers of Track.Packets. It fails with ErrReadTimeout once the read
+// deadline elapses, or the read timeout of the SettingEngine without a
+// deadline, and io.EOF once the RTPReceiver stopped.
+func (r *RTPReceiver) ReadRTP() (*rtp.Packet, error) {
+	return r.ReadRTPContext(context.Background())
+}
+
+// ReadRTPContext is ReadRTP bound to a context, the deadline of the context
+// overrides the read timeout of the SettingEngine. It fails with the error
+// of the context once it is done.
+func (r *RTPReceiver) ReadRTPContext(ctx context.Context) (*rtp.Packet, error) {
+	var timeout <-chan time.Time
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline && !r.readDeadline.isSet() && r.api.settingEngine.timeout.RTPRead != 0 {
+		timer := time.NewTimer(r.api.settingEngine.timeout.RTPRead)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		if r.readDeadline.exceeded() {
+			return nil, ErrReadTimeout
+		}
+		elapsed, changed, stop := r.readDeadline.wait()
+		select {
+		case p, ok := <-r.rtpOut:
+			stop()
+			if !ok {
+				return nil, io.EOF
+			}
+			return p, nil
+		case <-elapsed:
+			return nil, ErrReadTimeout
+		case <-changed:
+			stop()
+		case <-timeout:
+			stop()
+			return nil, ErrReadTimeout
+		case <-ctx.Done():
+			stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// SetReadDeadline sets the deadline of ReadRTP, ReadRTCP, ReadRTCPBatch and
+// their context variants, as the one of a net.Conn: the reads pending and
+// the following ones fail with ErrReadTimeout once it elapses, until it is
+// set again. The zero time removes it. The reads of the Packets and
+// RTCPPackets channels and the passthrough reads aren't bound by it.
+func (r *RTPReceiver) SetReadDeadline(t time.Time) error {
+	r.readDeadline.set(t)
+	return nil
+}
+
+// ReadPassthroughRTP reads the next RTP packet of the Track, decrypted but not
+// parsed, into b and returns its size. The packet is decrypted straight into
+// b, which must be able to hold the largest packet, 8192 bytes, or
+// io.ErrShortBuffer is returned. Reusing b across reads keeps the path free
+// of allocations. It fails with ErrPassthroughDisabled unless passthrough is
+// enabled in the SettingEngine, and with io.EOF once the RTPReceiver stopped.
+func (r *RTPReceiver) ReadPassthroughRTP(b []byte) (int, error) {
+	if !r.api.settingEngine.passthrough {
+		return 0, ErrPassthroughDisabled
+	} else if len(b) < receiveMTU {
+		return 0, io.ErrShortBuffer
+	}
+
+	// The stream is left once its first packet fired OnTrack
+	<-r.rtpOutDone
+
+	r.mu.Lock()
+	readStream, firstPacket := r.rtpReadStream, r.passthroughPacket
+	r.passthroughPacket = nil
+	r.mu.Unlock()
+
+	switch {
+	case firstPacket != nil:
+		return copy(b, firstPacket), nil
+	case readStream == nil:
+		return 0, io.EOF
+	}
+
+	n, err := readStream.Read(b)
+	if err != nil {
+		return 0, io.EOF
+	}
+	r.packetReceived(time.Now())
+	return n, nil
+}
+
+// ReadPassthroughRTCP reads the next RTCP packet about the Track, decrypted
+// but not parsed, into b and returns its size. It follows the rules of
+// ReadPassthroughRTP, and fails with ErrRTCPDisabled if RTCP is disabled in
+// the SettingEngine.
+func (r *RTPReceiver) ReadPassthroughRTCP(b []byte) (int, error) {
+	switch {
+	case !r.api.settingEngine.passthrough:
+		return 0, ErrPassthroughDisabled
+	case r.isRTCPDisabled():
+		return 0, ErrRTCPDisabled
+	case len(b) < receiveMTU:
+		return 0, io.ErrShortBuffer
 	}
 
+	<-r.rtcpOutDone
+
+	r.mu.Lock()
+	readStream := r.rtcpReadStream
+	r.mu.Unlock()
+	if readStream == nil {
+		return 0, io.EOF
+	}
+
+	n, err := readStream.Read(b)
+	if err != nil {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// RequestKeyFrame asks the remote for a keyframe of the Track, with a
+// PictureLossIndication unless the remote only negotiated FullIntraRequest
+// in its a=rtcp-fb attributes. The requests following the previous one
+// within the interval set with SettingEngine.SetKeyFrameRequestInterval are
+// suppressed, nil is returned for them.
+func (r *RTPReceiver) RequestKeyFrame() error {
+	pli := RTCPFeedback{Type: TypeRTCPFBNACK, Parameter: RTCPFBParameterPLI}
+	fir := RTCPFeedback{Type: TypeRTCPFBCCM, Parameter: RTCPFBParameterFIR}
+	if !hasRTCPFeedback(r.rtcpFeedback, pli) && hasRTCPFeedback(r.rtcpFeedback, fir) {
+		return r.RequestKeyFrameFIR()
+	}
+	return r.RequestKeyFramePLI()
+}
+
+// RequestKeyFramePLI asks the remote for a keyframe of the Track with a
+// PictureLossIndication, it is throttled as RequestKeyFrame
+func (r *RTPReceiver) RequestKeyFramePLI() error {
+	ssrc, err := r.receivingSSRC()
+	if err != nil {
+		return err
+	}
+	return r.sendKeyFrameRequest(func() error {
+		return r.writeRTCP(&rtcp.PictureLossIndication{MediaSSRC: ssrc})
+	})
+}
+
+// RequestKeyFrameFIR asks the remote for a keyframe of the Track with a
+// FullIntraRequest, for the endpoints that don't answer PLI. Every request
+// sent is a new one and increments the sequence number as required by
+// RFC5104, it is throttled as RequestKeyFrame.
+func (r *RTPReceiver) RequestKeyFrameFIR() error {
+	ssrc, err := r.receivingSSRC()
+	if err != nil {
+		return err
+	}
+
+	return r.sendKeyFrameRequest(func() error {
+		// The SRTCP session routes packets through the SSRCs of the report
+		// blocks and knows nothing about FIR, it is sent in a compound
+		// packet behind a reception report for the SSRC
+		err := r.writeRTCP(
+			&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{{SSRC: ssrc}}},
+			&FullIntraRequest{FIR: []FIREntry{{SSRC: ssrc, SequenceNumber: r.firSequenceNumber}}},
+		)
+		if err != nil {
+			return err
+		}
+		r.firSequenceNumber++
+		return nil
+	})
+}
+
+// sendKeyFrameRequest sends a keyframe request with send, unless it follows
+// the previous one sent within the interval of the SettingEngine
+func (r *RTPReceiver) sendKeyFrameRequest(send func() error) error {
+	r.keyFrameLock.Lock()
+	defer r.keyFrameLock.Unlock()
+
+	now := time.Now()
+	interval := r.api.settingEngine.keyFrameInterval
+	if interval > 0 && !r.lastKeyFrameRequest.IsZero() && now.Sub(r.lastKeyFrameRequest) < interval {
+		atomic.AddUint64(&r.keyFrameRequestsSuppressed, 1)
+		return nil
+	}
+
+	if err := send(); err != nil {
+		return err
+	}
+	r.lastKeyFrameRequest = now
+	atomic.AddUint64(&r.keyFrameRequestsSent, 1)
+	return nil
+}
+
+// KeyFrameRequestStats returns the keyframe requests the RTPReceiver sent
+// and suppressed
+func (r *RTPReceiver) KeyFrameRequestStats() KeyFrameRequestStats {
+	return KeyFrameRequestStats{
+		Sent:       atomic.LoadUint64(&r.keyFrameRequestsSent),
+		Suppressed: atomic.LoadUint64(&r.keyFrameRequestsSuppressed),
+	}
+}
+
+// PauseStream asks the sender of the Track to stop sending it with a RTCP
+// PAUSE request as defined in RFC7728. Unlike Pause, which only discards the
+// packets locally, the stream stops using bandwidth until ResumeStream is
+// called. The request isn't acknowledged reliably, PauseStream can be called
+// again if media keeps arriving. ErrRTCPFeedbackNotNegotiated is returned if
+// the remote didn't negotiate ccm pause.
+func (r *RTPReceiver) PauseStream() error {
+	return r.sendPauseResume(PauseResumeTypePause)
+}
+
+// ResumeStream asks the sender of a Track paused by PauseStream to send it
+// again with a RTCP RESUME request, it does nothing if the Track was never
+// paused.
+func (r *RTPReceiver) ResumeStream() error {
+	return r.sendPauseResume(PauseResumeTypeResume)
+}
+
+func (r *RTPReceiver) sendPauseResume(t PauseResumeType) error {
+	if !hasRTCPFeedback(r.rtcpFeedback, RTCPFeedback{Type: TypeRTCPFBCCM, Parameter: RTCPFBParameterPause}) {
+		return ErrRTCPFeedbackNotNegotiated
+	}
+
+	ssrc, err := r.receivingSSRC()
+	if err != nil {
+		return err
+	}
+
+	r.pauseLock.Lock()
+	defer r.pauseLock.Unlock()
+
+	pauseID := r.pauseID
+	switch {
+	case t == PauseResumeTypeResume && !r.hasPaused:
+		return nil
+	case t == PauseResumeTypePause && r.hasPaused && !r.streamPaused:
+		// A new pause, the previous one was resumed
+		pauseID++
+	}
+
+	// Like FIR, the SRTCP session routes the request through the reception
+	// report it follows
+	err = r.writeRTCP(
+		&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{{SSRC: ssrc}}},
+		&PauseResume{Messages: []PauseResumeMessage{{Type: t, SSRC: ssrc, PauseID: pauseID}}},
+	)
+	if err != nil {
+		return err
+	}
+
+	r.pauseID = pauseID
+	r.streamPaused = t == PauseResumeTypePause
+	r.hasPaused = true
+	return nil
+}
+
+// receivingSSRC returns the SSRC of the Track once the first packet arrived
+func (r *RTPReceiver) receivingSSRC() (uint32, error) {
 	select {
 	case <-r.hasRecv:
 	default:
-		return fmt.Errorf("RTPReceiver has not been started")
+		return 0, fmt.Errorf("RTPReceiver has not been started")
+	}
+
+	if r.Track.SSRC == 0 {
+		return 0, fmt.Errorf("RTPReceiver has no SSRC")
+	}
+	return r.Track.SSRC, nil
+}
+
+// writeRTCP sends the packets in a single compound RTCP packet
+func (r *RTPReceiver) writeRTCP(packets ...rtcp.Packet) error {
+	if r.isRTCPDisabled() {
+		return ErrRTCPDisabled
 	}
 
-	if err := r.rtcpReadStream.Close(); err != nil {
+	r.mu.Lock()
+	writer := r.rtcpWriter
+	r.mu.Unlock()
+	if writer == nil {
+		return r.writeSRTCP(packets)
+	}
+	return writer.WriteRTCP(packets)
+}
+
+// writeSRTCP writes the packets to the SRTCP session, under the Interceptors
+func (r *RTPReceiver) writeSRTCP(packets []rtcp.Packet) error {
+	raw, err := newCompoundRTCP(r.reducedSizeRTCP, packets...)
+	if err != nil {
 		return err
 	}
-	if err := r.rtpReadStream.Close(); err != nil {
+
+	srtcpSession, err := r.transport.getSRTCPSession()
+	if err != nil {
 		return err
 	}
 
-	<-r.rtcpOutDone
-	<-r.rtpOutDone
+	writeStream, err := srtcpSession.OpenWriteStream()
+	if err != nil {
+		return fmt.Errorf("failed to open WriteStream: %v", err)
+	}
+
+	if _, err := writeStream.Write(raw); err != nil {
+		return fmt.Errorf("failed to write: %v", err)
+	}
+	return nil
+}
+
+// Pause stops delivering the incoming RTP packets to the Track without tearing
+// down the transport, the packets are discarded until Resume is called.
+func (r *RTPReceiver) Pause() {
+	atomic.StoreInt32(&r.paused, 1)
+}
+
+// Resume delivers the incoming RTP packets to the Track again, the packets
+// that were buffered before Pause are flushed so the Track doesn't start from
+// stale media.
+func (r *RTPReceiver) Resume() {
+	if !r.isPaused() {
+		return
+	}
+
+	// Nothing is queued while paused, so only the stale packets are flushed
+	flushed := false
+	for !flushed {
+		select {
+		case _, ok := <-r.rtpOut:
+			flushed = !ok
+		default:
+			flushed = true
+		}
+	}
+
+	atomic.StoreInt32(&r.paused, 0)
+}
+
+func (r *RTPReceiver) isPaused() bool {
+	return atomic.LoadInt32(&r.paused) == 1
+}
+
+// Stop irreversibly stops the RTPReceiver, its Track is closed once the
+// ReadLoops are done
+func (r *RTPReceiver) Stop() error {
+	if err := r.stop(); err != nil {
+		return err
+	}
+	r.wait()
+	return nil
+}
+
+// stop closes the read streams of the RTPReceiver without waiting for its
+// ReadLoops
+func (r *RTPReceiver) stop() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return fmt.Errorf("RTPReceiver has already been closed")
+	}
+
+	select {
+	case <-r.hasRecv:
+	default:
+		r.mu.Unlock()
+		return fmt.Errorf("RTPReceiver has not been started")
+	}
 
 	r.closed = true
+	rtcpReadStream, rtpReadStream := r.rtcpReadStream, r.rtpReadStream
+	r.mu.Unlock()
+
+	if rtcpReadStream != nil {
+		if err := rtcpReadStream.Close(); err != nil {
+			return err
+		}
+	}
+	if rtpReadStream != nil {
+		if err := rtpReadStream.Close(); err != nil {
+			return err
+		}
+		r.transport.releaseSSRC(rtpReadStream.GetSSRC())
+	}
 	return nil
 }
+
+// isStopped returns whether the RTPReceiver was stopped
+func (r *RTPReceiver) isStopped() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closed
+}
+
+// wait returns once the ReadLoops of a stopped RTPReceiver returned
+func (r *RTPReceiver) wait() {
+	// The ReadLoops lock mu on their way out
+	<-r.rtcpOutDone
+	<-r.rtpOutDone
+}
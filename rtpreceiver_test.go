@@ -0,0 +1,485 @@
+package webrtc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pions/rtcp"
+	"github.com/pions/rtp"
+)
+
+func TestRTPReceiver_Receive_DisabledLatching(t *testing.T) {
+	s := SettingEngine{}
+	s.DisableSSRCLatching()
+	api := NewAPI(WithSettingEngine(s))
+
+	receiver := api.NewRTPReceiver(RTPCodecTypeVideo, nil)
+	if _, err := receiver.Receive(RTPReceiveParameters{}); err != ErrSSRCLatchingDisabled {
+		t.Fatalf("Receive with a zero SSRC should fail when latching is disabled: %v", err)
+	}
+}
+
+func TestRTPReceiver_PauseResume(t *testing.T) {
+	api := NewAPI()
+	receiver := api.NewRTPReceiver(RTPCodecTypeVideo, nil)
+
+	receiver.rtpOut <- &rtp.Packet{}
+	receiver.rtpOut <- &rtp.Packet{}
+
+	receiver.Pause()
+	if !receiver.isPaused() {
+		t.Fatalf("RTPReceiver should be paused")
+	}
+
+	receiver.Resume()
+	if receiver.isPaused() {
+		t.Fatalf("RTPReceiver should not be paused after Resume")
+	}
+	if len(receiver.rtpOut) != 0 {
+		t.Fatalf("Resume should flush the packets buffered before Pause, %d left", len(receiver.rtpOut))
+	}
+
+	// Resume without Pause keeps the buffered packets
+	receiver.rtpOut <- &rtp.Packet{}
+	receiver.Resume()
+	if len(receiver.rtpOut) != 1 {
+		t.Fatalf("Resume without Pause should not flush")
+	}
+}
+
+func TestRTPReceiver_ReadRTP_Timeout(t *testing.T) {
+	s := SettingEngine{}
+	s.SetReadTimeout(10 * time.Millisecond)
+	api := NewAPI(WithSettingEngine(s))
+	receiver := api.NewRTPReceiver(RTPCodecTypeVideo, nil)
+
+	if _, err := receiver.ReadRTP(); err != ErrReadTimeout {
+		t.Fatalf("ReadRTP should time out, got %v", err)
+	}
+
+	// The deadline of the context overrides the global timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		receiver.rtpOut <- &rtp.Packet{Header: rtp.Header{SequenceNumber: 5}}
+	}()
+	p, err := receiver.ReadRTPContext(ctx)
+	if err != nil {
+		t.Fatalf("ReadRTPContext should use the context deadline: %v", err)
+	}
+	if p.SequenceNumber != 5 {
+		t.Fatalf("Unexpected packet %v", p)
+	}
+
+	if _, err = receiver.ReadRTPContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("ReadRTPContext should fail with the context error, got %v", err)
+	}
+
+	close(receiver.rtpOut)
+	if _, err = receiver.ReadRTP(); err != io.EOF {
+		t.Fatalf("ReadRTP should return io.EOF once stopped, got %v", err)
+	}
+}
+
+func TestRTPReceiver_ReadRTP_NoTimeout(t *testing.T) {
+	api := NewAPI()
+	receiver := api.NewRTPReceiver(RTPCodecTypeVideo, nil)
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		receiver.rtpOut <- &rtp.Packet{}
+	}()
+	if _, err := receiver.ReadRTP(); err != nil {
+		t.Fatalf("ReadRTP should block until a packet arrives: %v", err)
+	}
+}
+
+func TestRTPReceiver_SetReadDeadline(t *testing.T) {
+	api := NewAPI()
+	receiver := api.NewRTPReceiver(RTPCodecTypeVideo, nil)
+
+	if err := receiver.SetReadDeadline(time.Now().Add(10 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	_, err := receiver.ReadRTP()
+	if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+		t.Fatalf("ReadRTP should fail with a timeout net.Error, got %v", err)
+	}
+	if _, err = receiver.ReadRTCP(); err != ErrReadTimeout {
+		t.Fatalf("ReadRTCP should time out, got %v", err)
+	}
+
+	// An elapsed deadline fails the reads even with a packet buffered
+	receiver.rtpOut <- &rtp.Packet{}
+	if _, err = receiver.ReadRTP(); err != ErrReadTimeout {
+		t.Fatalf("ReadRTP should fail once the deadline elapsed, got %v", err)
+	}
+
+	// Setting the deadline again rearms the pending reads
+	if err = receiver.SetReadDeadline(time.Now().Add(50 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	<-receiver.rtpOut
+	read := make(chan error)
+	go func() {
+		_, readErr := receiver.ReadRTP()
+		read <- readErr
+	}()
+	time.Sleep(5 * time.Millisecond)
+	if err = receiver.SetReadDeadline(time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	receiver.rtpOut <- &rtp.Packet{}
+	if err = <-read; err != nil {
+		t.Fatalf("ReadRTP should wait once the deadline is removed: %v", err)
+	}
+
+	// A canceled context fails a read bound to it
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = receiver.ReadRTCPContext(ctx); err != context.Canceled {
+		t.Fatalf("ReadRTCPContext should fail with the context error, got %v", err)
+	}
+}
+
+func TestRTPReceiver_PassthroughDisabled(t *testing.T) {
+	api := NewAPI()
+	receiver := api.NewRTPReceiver(RTPCodecTypeVideo, nil)
+
+	buf := make([]byte, receiveMTU)
+	if _, err := receiver.ReadPassthroughRTP(buf); err != ErrPassthroughDisabled {
+		t.Fatalf("ReadPassthroughRTP should fail without passthrough: %v", err)
+	}
+	if _, err := receiver.ReadPassthroughRTCP(buf); err != ErrPassthroughDisabled {
+		t.Fatalf("ReadPassthroughRTCP should fail without passthrough: %v", err)
+	}
+}
+
+func TestTrack_Close(t *testing.T) {
+	api := NewAPI()
+	receiver := api.NewRTPReceiver(RTPCodecTypeVideo, nil)
+	track := &Track{Packets: receiver.rtpOut, RTCPPackets: receiver.rtcpOut, receiver: receiver}
+
+	receiver.deliver(&rtp.Packet{Header: rtp.Header{SequenceNumber: 1}})
+	if err := track.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := track.Close(); err != nil {
+		t.Fatalf("Close should be idempotent: %v", err)
+	}
+
+	// The packets delivered after Close are discarded, the buffered ones are
+	// still read before io.EOF
+	receiver.deliver(&rtp.Packet{Header: rtp.Header{SequenceNumber: 2}})
+	receiver.deliverRTCP(&rtcp.PictureLossIndication{})
+	if p, err := receiver.ReadRTP(); err != nil || p.SequenceNumber != 1 {
+		t.Fatalf("ReadRTP should return the packet buffered before Close: %v %v", p, err)
+	}
+	if _, err := receiver.ReadRTP(); err != io.EOF {
+		t.Fatalf("ReadRTP should return io.EOF once the Track is closed, got %v", err)
+	}
+	if _, ok := <-track.RTCPPackets; ok {
+		t.Fatalf("RTCPPackets should be closed")
+	}
+
+	// The ReadLoops close the Track again on their way out
+	receiver.closeRTPOut()
+	receiver.closeRTCPOut()
+
+	sent := &Track{}
+	if err := sent.Close(); err != nil {
+		t.Fatalf("Close of a sent Track should be a no-op: %v", err)
+	}
+}
+
+func TestRTPReceiver_PauseStream_NotNegotiated(t *testing.T) {
+	api := NewAPI()
+	receiver := api.NewRTPReceiver(RTPCodecTypeVideo, nil)
+
+	if err := receiver.PauseStream(); err != ErrRTCPFeedbackNotNegotiated {
+		t.Fatalf("PauseStream should fail without ccm pause: %v", err)
+	}
+	if err := receiver.ResumeStream(); err != ErrRTCPFeedbackNotNegotiated {
+		t.Fatalf("ResumeStream should fail without ccm pause: %v", err)
+	}
+}
+
+func TestRTPReceiver_OnFirstPacket(t *testing.T) {
+	api := NewAPI()
+	receiver := api.NewRTPReceiver(RTPCodecTypeVideo, nil)
+
+	fired := make(chan uint32, 2)
+	receiver.OnFirstPacket(func(ssrc uint32, payloadType uint8) {
+		fired <- ssrc
+	})
+	<-receiver.onFirstPacket(1234, DefaultPayloadTypeVP8)
+	if ssrc := <-fired; ssrc != 1234 {
+		t.Fatalf("OnFirstPacket fired with SSRC %d", ssrc)
+	}
+
+	// A handler set afterwards is invoked right away
+	receiver.OnFirstPacket(func(ssrc uint32, payloadType uint8) {
+		if payloadType != DefaultPayloadTypeVP8 {
+			t.Errorf("OnFirstPacket fired with payload type %d", payloadType)
+		}
+		fired <- ssrc
+	})
+	if ssrc := <-fired; ssrc != 1234 {
+		t.Fatalf("OnFirstPacket fired with SSRC %d", ssrc)
+	}
+}
+
+func TestRTPReceiver_IsReceiving(t *testing.T) {
+	s := SettingEngine{}
+	s.SetReceivingTimeout(time.Minute)
+	api := NewAPI(WithSettingEngine(s))
+	receiver := api.NewRTPReceiver(RTPCodecTypeVideo, nil)
+
+	if receiver.IsReceiving() {
+		t.Fatalf("IsReceiving should be false before the first packet")
+	}
+
+	receiver.packetReceived(time.Now().Add(-30 * time.Second))
+	if !receiver.IsReceiving() {
+		t.Fatalf("IsReceiving should be true within the timeout")
+	}
+
+	receiver.packetReceived(time.Now().Add(-2 * time.Minute))
+	if receiver.IsReceiving() {
+		t.Fatalf("IsReceiving should be false once the last packet is stale")
+	}
+}
+
+func TestRTPReceiver_KeyFrameRequestInterval(t *testing.T) {
+	s := SettingEngine{}
+	if err := s.SetKeyFrameRequestInterval(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	receiver := NewAPI(WithSettingEngine(s)).NewRTPReceiver(RTPCodecTypeVideo, nil)
+
+	sent := 0
+	send := func() error {
+		sent++
+		return nil
+	}
+
+	// The first request is sent at once, the following ones are suppressed
+	for i := 0; i < 3; i++ {
+		if err := receiver.sendKeyFrameRequest(send); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sent != 1 {
+		t.Fatalf("Expected a single request sent, got %d", sent)
+	}
+	if stats := receiver.KeyFrameRequestStats(); stats != (KeyFrameRequestStats{Sent: 1, Suppressed: 2}) {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+
+	// Once the interval elapsed the next request is sent
+	receiver.lastKeyFrameRequest = time.Now().Add(-time.Hour)
+	if err := receiver.sendKeyFrameRequest(send); err != nil {
+		t.Fatal(err)
+	}
+	if sent != 2 {
+		t.Fatalf("Expected the request after the interval to be sent")
+	}
+
+	// A request that failed doesn't hold the following ones back
+	receiver = NewAPI(WithSettingEngine(s)).NewRTPReceiver(RTPCodecTypeVideo, nil)
+	if err := receiver.sendKeyFrameRequest(func() error { return io.ErrClosedPipe }); err != io.ErrClosedPipe {
+		t.Fatalf("Expected the error of the request, got %v", err)
+	}
+	if err := receiver.sendKeyFrameRequest(send); err != nil || sent != 3 {
+		t.Fatalf("Expected the request following a failed one to be sent: %v", err)
+	}
+}
+
+func TestRetryReadStream(t *testing.T) {
+	errTransient := errors.New("transient")
+	retry := readStreamRetry{Attempts: 3, Backoff: time.Millisecond}
+	healing := func() bool { return false }
+
+	// The transient failures are retried until the stream opens
+	attempts := 0
+	err := retryReadStream(retry, 1, healing, func() error {
+		if attempts++; attempts < 3 {
+			return errTransient
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Fatalf("The stream should open on the third attempt, got %v after %d", err, attempts)
+	}
+
+	// The retries are bounded
+	attempts = 0
+	err = retryReadStream(retry, 1, healing, func() error {
+		attempts++
+		return errTransient
+	})
+	if err != errTransient || attempts != 4 {
+		t.Fatalf("The stream should fail after 4 attempts, got %v after %d", err, attempts)
+	}
+
+	// The permanent failures aren't retried
+	for _, c := range []struct {
+		ssrc      uint32
+		permanent func() bool
+	}{
+		{0, healing},
+		{1, func() bool { return true }},
+	} {
+		attempts = 0
+		err = retryReadStream(retry, c.ssrc, c.permanent, func() error {
+			attempts++
+			return errTransient
+		})
+		if err != errTransient || attempts != 1 {
+			t.Fatalf("A permanent failure should not be retried, got %v after %d", err, attempts)
+		}
+	}
+}
+
+func TestRTPReceiver_SetDrainRate(t *testing.T) {
+	receiver := NewAPI().NewRTPReceiver(RTPCodecTypeVideo, nil)
+
+	if err := receiver.SetDrainRate(-1, 0); err != ErrInvalidDrainRate {
+		t.Fatalf("SetDrainRate should fail a negative bitrate, got %v", err)
+	}
+	if err := receiver.SetDrainRate(80000, -time.Millisecond); err != ErrInvalidDrainRate {
+		t.Fatalf("SetDrainRate should fail a negative delay, got %v", err)
+	}
+
+	// At 160kbps a packet of 1000 bytes takes 50ms to drain, the second one
+	// is delayed and the third dropped as the second takes 200ms
+	if err := receiver.SetDrainRate(160000, 75*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	for i, packet := range []struct {
+		size      int
+		delivered bool
+	}{{1000, true}, {4000, true}, {1000, false}} {
+		if delivered := receiver.drain(packet.size); delivered != packet.delivered {
+			t.Fatalf("Packet %d should be delivered %v, got %v", i, packet.delivered, delivered)
+		}
+	}
+	if delayed, dropped := atomic.LoadUint64(&receiver.packetsDelayed), atomic.LoadUint64(&receiver.packetsRateLimited); delayed != 1 || dropped != 1 {
+		t.Fatalf("Expected 1 packet delayed and 1 dropped, got %d and %d", delayed, dropped)
+	}
+
+	// Without a cap every packet is delivered
+	if err := receiver.SetDrainRate(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if !receiver.drain(1000) {
+			t.Fatalf("Packet %d should be delivered without a cap", i)
+		}
+	}
+}
+
+func TestRTPReceiver_ReadRTCPBatch(t *testing.T) {
+	receiver := NewAPI().NewRTPReceiver(RTPCodecTypeVideo, nil)
+
+	for i := 0; i < 3; i++ {
+		receiver.deliverRTCP(&rtcp.PictureLossIndication{MediaSSRC: uint32(i)})
+	}
+
+	// A batch takes the packets already received, up to its size
+	packets := make([]rtcp.Packet, 2)
+	n, err := receiver.ReadRTCPBatch(packets)
+	if err != nil || n != 2 {
+		t.Fatalf("ReadRTCPBatch read %d packets: %v", n, err)
+	}
+	if packets[1].(*rtcp.PictureLossIndication).MediaSSRC != 1 {
+		t.Fatalf("ReadRTCPBatch should keep the order of the packets")
+	}
+	if n, err = receiver.ReadRTCPBatch(packets); err != nil || n != 1 {
+		t.Fatalf("ReadRTCPBatch read %d packets instead of the one left: %v", n, err)
+	}
+
+	receiver.closeRTCPOut()
+	if _, err = receiver.ReadRTCPBatch(packets); err != io.EOF {
+		t.Fatalf("ReadRTCPBatch should return io.EOF once closed: %v", err)
+	}
+}
+
+func benchmarkRTPReceiverReadRTCP(b *testing.B, read func(*RTPReceiver, []rtcp.Packet) (int, error)) {
+	receiver := NewAPI().NewRTPReceiver(RTPCodecTypeVideo, nil)
+	packet := &rtcp.PictureLossIndication{}
+
+	// The packets are delivered as fast as they are read, in bursts
+	go func() {
+		for i := 0; i < b.N; i++ {
+			receiver.rtcpOut <- packet
+		}
+		receiver.closeRTCPOut()
+	}()
+
+	packets := make([]rtcp.Packet, 16)
+	b.ResetTimer()
+	for received := 0; received < b.N; {
+		n, err := read(receiver, packets)
+		if err != nil {
+			b.Fatal(err)
+		}
+		received += n
+	}
+}
+
+func BenchmarkRTPReceiver_ReadRTCP(b *testing.B) {
+	benchmarkRTPReceiverReadRTCP(b, func(r *RTPReceiver, packets []rtcp.Packet) (int, error) {
+		packet, err := r.ReadRTCP()
+		packets[0] = packet
+		return 1, err
+	})
+}
+
+func BenchmarkRTPReceiver_ReadRTCPBatch(b *testing.B) {
+	benchmarkRTPReceiverReadRTCP(b, (*RTPReceiver).ReadRTCPBatch)
+}
+
+func TestRTPReceiver_ReceiveDropPolicy(t *testing.T) {
+	for _, testCase := range []struct {
+		policy ReceiveDropPolicy
+		read   []uint16
+	}{
+		{ReceiveDropPolicyNewest, []uint16{1, 2}},
+		{ReceiveDropPolicyOldest, []uint16{2, 3}},
+	} {
+		s := SettingEngine{}
+		if err := s.SetReceiveBufferSize(RTPCodecTypeVideo, 2, 2); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SetReceiveDropPolicy(testCase.policy); err != nil {
+			t.Fatal(err)
+		}
+		receiver := NewAPI(WithSettingEngine(s)).NewRTPReceiver(RTPCodecTypeVideo, nil)
+
+		for i := uint16(1); i <= 3; i++ {
+			receiver.put(&rtp.Packet{Header: rtp.Header{SequenceNumber: i}})
+			receiver.deliverRTCP(&rtcp.PictureLossIndication{MediaSSRC: uint32(i)})
+		}
+		if dropped := atomic.LoadUint64(&receiver.packetsDropped); dropped != 1 {
+			t.Fatalf("%s should drop one packet, dropped %d", testCase.policy, dropped)
+		}
+		for _, sequenceNumber := range testCase.read {
+			p := <-receiver.rtpOut
+			if p.SequenceNumber != sequenceNumber {
+				t.Fatalf("%s should keep packet %d, read %d", testCase.policy, sequenceNumber, p.SequenceNumber)
+			}
+			pli := (<-receiver.rtcpOut).(*rtcp.PictureLossIndication)
+			if pli.MediaSSRC != uint32(sequenceNumber) {
+				t.Fatalf("%s should keep RTCP packet %d, read %d", testCase.policy, sequenceNumber, pli.MediaSSRC)
+			}
+		}
+	}
+}
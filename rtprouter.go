@@ -0,0 +1,252 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pions/rtp"
+	"github.com/pions/srtp"
+)
+
+// routedStream is an SRTP stream with an undeclared SSRC handed to a
+// RTPReceiver, along with the packet it was identified by
+type routedStream struct {
+	readStream  *srtp.ReadStreamSRTP
+	ssrc        uint32
+	firstPacket []byte
+}
+
+// routeKey identifies the RTPReceiver of a media section, and of a simulcast
+// layer when the rid is set
+type routeKey struct {
+	mid string
+	rid string
+}
+
+// rtpRouter dispatches the SRTP streams whose SSRC isn't declared in the
+// RemoteDescription. A stream goes to the RTPReceiver of the media section
+// named by the mid header extension of its first packet, or of its simulcast
+// layer named by the RID header extension, or to the latching RTPReceiver if
+// the packet carries no mid. A stream with a RTX payload type goes to the
+// RTPReceiver of the stream it repairs instead, the layer of which is named
+// by the repaired RID header extension. Streams nobody receives are drained,
+// such as the simulcast layers that weren't requested.
+type rtpRouter struct {
+	midExtensionIDs         []uint8
+	ridExtensionIDs         []uint8
+	repairedRIDExtensionIDs []uint8
+	rtxPayloadTypes         map[uint8]bool
+
+	mu           sync.Mutex
+	receivers    map[routeKey]chan routedStream
+	rtxReceivers map[routeKey]chan routedStream
+	latching     chan routedStream
+	latchingRTX  chan routedStream
+}
+
+func newRTPRouter(midExtensionIDs, ridExtensionIDs, repairedRIDExtensionIDs []uint8, rtxPayloadTypes map[uint8]bool) *rtpRouter {
+	return &rtpRouter{
+		midExtensionIDs:         midExtensionIDs,
+		ridExtensionIDs:         ridExtensionIDs,
+		repairedRIDExtensionIDs: repairedRIDExtensionIDs,
+		rtxPayloadTypes:         rtxPayloadTypes,
+		receivers:               map[routeKey]chan routedStream{},
+		rtxReceivers:            map[routeKey]chan routedStream{},
+	}
+}
+
+// addReceiver returns the channel the stream of the given mid and simulcast
+// layer is delivered on, rid is empty for a media section without simulcast
+func (r *rtpRouter) addReceiver(mid, rid string) <-chan routedStream {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := make(chan routedStream, 1)
+	r.receivers[routeKey{mid: mid, rid: rid}] = c
+	return c
+}
+
+// addRTXReceiver returns the channel the RTX stream repairing the stream of
+// the given mid and simulcast layer is delivered on
+func (r *rtpRouter) addRTXReceiver(mid, rid string) <-chan routedStream {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := make(chan routedStream, 1)
+	r.rtxReceivers[routeKey{mid: mid, rid: rid}] = c
+	return c
+}
+
+// addLatchingReceiver returns the channel the first stream without mid is delivered on
+func (r *rtpRouter) addLatchingReceiver() <-chan routedStream {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.latching = make(chan routedStream, 1)
+	return r.latching
+}
+
+// addLatchingRTXReceiver returns the channel the first RTX stream without mid
+// is delivered on
+func (r *rtpRouter) addLatchingRTXReceiver() <-chan routedStream {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.latchingRTX = make(chan routedStream, 1)
+	return r.latchingRTX
+}
+
+// headerExtensionValue returns the value of the first of the header
+// extensions with the given IDs a packet carries
+func headerExtensionValue(packet *rtp.Packet, ids []uint8) (string, bool) {
+	for _, id := range ids {
+		if payload, ok := getHeaderExtension(&packet.Header, id); ok {
+			return string(payload), true
+		}
+	}
+	return "", false
+}
+
+// receiver returns and removes the channel a stream should be delivered on
+func (r *rtpRouter) receiver(packet *rtp.Packet) (chan routedStream, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	receivers, latching, ridExtensionIDs := r.receivers, &r.latching, r.ridExtensionIDs
+	if r.rtxPayloadTypes[packet.PayloadType] {
+		receivers, latching, ridExtensionIDs = r.rtxReceivers, &r.latchingRTX, r.repairedRIDExtensionIDs
+	}
+
+	if mid, ok := headerExtensionValue(packet, r.midExtensionIDs); ok {
+		key := routeKey{mid: mid}
+		if rid, ok := headerExtensionValue(packet, ridExtensionIDs); ok {
+			// A layer of a media section received without simulcast
+			// goes to its only RTPReceiver
+			if _, layered := receivers[routeKey{mid: mid, rid: rid}]; layered {
+				key.rid = rid
+			}
+		}
+
+		c, ok := receivers[key]
+		delete(receivers, key)
+		return c, ok
+	}
+
+	c := *latching
+	*latching = nil
+	return c, c != nil
+}
+
+// close ends the RTPReceivers that didn't get a stream
+func (r *rtpRouter) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, receivers := range []map[routeKey]chan routedStream{r.receivers, r.rtxReceivers} {
+		for key, c := range receivers {
+			close(c)
+			delete(receivers, key)
+		}
+	}
+	for _, latching := range []*chan routedStream{&r.latching, &r.latchingRTX} {
+		if *latching != nil {
+			close(*latching)
+			*latching = nil
+		}
+	}
+}
+
+// run accepts the undeclared streams until the SRTP session is closed
+func (r *rtpRouter) run(transport *DTLSTransport) {
+	defer r.close()
+
+	srtpSession, err := transport.getSRTPSession()
+	if err != nil {
+		transport.api.log.Warnf("rtpRouter failed to open SrtpSession: %v", err)
+		return
+	}
+
+	for {
+		readStream, ssrc, err := srtpSession.AcceptStream()
+		if err != nil {
+			transport.api.log.Warnf("Failed to accept RTP %v \n", err)
+			return
+		}
+
+		go r.route(transport, readStream, ssrc)
+	}
+}
+
+// route identifies a stream by its first packet
+func (r *rtpRouter) route(transport *DTLSTransport, readStream *srtp.ReadStreamSRTP, ssrc uint32) {
+	rtpBuf := make([]byte, receiveMTU)
+	rtpPacket := &rtp.Packet{}
+
+	for {
+		i, err := readStream.Read(rtpBuf)
+		if err != nil {
+			transport.api.log.Warnf("Failed to read, rtpRouter done for: %v %d \n", err, ssrc)
+			return
+		}
+
+		// The stream is read by the RTPReceiver of its SSRC, which opened it
+		// after its first packet arrived
+		if transport.isSSRCClaimed(ssrc) {
+			return
+		}
+
+		if err := rtpPacket.Unmarshal(rtpBuf[:i]); err != nil {
+			transport.api.log.Warnf("Failed to unmarshal RTP packet, discarding: %v \n", err)
+			continue
+		}
+
+		if c, ok := r.receiver(rtpPacket); ok {
+			c <- routedStream{readStream: readStream, ssrc: ssrc, firstPacket: append([]byte{}, rtpBuf[:i]...)}
+			return
+		}
+
+		drainRTPStream(transport, readStream, ssrc, rtpBuf[:i])
+		return
+	}
+}
+
+// unhandledRTPInterval is the minimum interval between two invocations of the
+// OnUnhandledRTP handler for a stream
+const unhandledRTPInterval = time.Second
+
+// drainRTPStream pulls and discards the packets of a stream nobody receives
+// and hands them to the OnUnhandledRTP handler, until a RTPReceiver claims it
+func drainRTPStream(transport *DTLSTransport, readStream *srtp.ReadStreamSRTP, ssrc uint32, firstPacket []byte) {
+	rtpBuf := make([]byte, receiveMTU)
+	rtpPacket := &rtp.Packet{}
+	var lastUnhandled time.Time
+
+	for {
+		i := copy(rtpBuf, firstPacket)
+		if firstPacket != nil {
+			firstPacket = nil
+		} else {
+			var err error
+			if i, err = readStream.Read(rtpBuf); err != nil {
+				transport.api.log.Warnf("Failed to read, drainSRTP done for: %v %d \n", err, ssrc)
+				return
+			}
+		}
+
+		if transport.isSSRCClaimed(ssrc) {
+			transport.api.log.Debugf("SSRC %d was claimed by a RTPReceiver, drainSRTP done", ssrc)
+			return
+		}
+
+		if err := rtpPacket.Unmarshal(rtpBuf[:i]); err != nil {
+			transport.api.log.Warnf("Failed to unmarshal RTP packet, discarding: %v \n", err)
+			continue
+		}
+		transport.api.log.Debugf("got RTP: %+v", rtpPacket)
+
+		if now := time.Now(); now.Sub(lastUnhandled) >= unhandledRTPInterval {
+			lastUnhandled = now
+			transport.onUnhandledRTP(ssrc, rtpPacket.PayloadType, append([]byte{}, rtpBuf[:i]...))
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTPRouterReceiver(t *testing.T) {
+	router := newRTPRouter([]uint8{3}, []uint8{4}, nil, nil)
+	audio := router.addReceiver("audio", "")
+	latching := router.addLatchingReceiver()
+
+	withMID := func(mid string) *rtp.Packet {
+		p := &rtp.Packet{}
+		assert.NoError(t, setHeaderExtension(&p.Header, 3, []byte(mid)))
+		return p
+	}
+
+	// A receiver gets a single stream
+	c, ok := router.receiver(withMID("audio"))
+	assert.True(t, ok)
+	assert.Equal(t, audio, (<-chan routedStream)(c))
+	_, ok = router.receiver(withMID("audio"))
+	assert.False(t, ok)
+
+	// Unknown mids are drained, packets without mid go to the latching receiver
+	_, ok = router.receiver(withMID("video"))
+	assert.False(t, ok)
+	c, ok = router.receiver(&rtp.Packet{})
+	assert.True(t, ok)
+	assert.Equal(t, latching, (<-chan routedStream)(c))
+	_, ok = router.receiver(&rtp.Packet{})
+	assert.False(t, ok)
+
+	// The receivers left without stream are closed
+	video := router.addReceiver("video", "")
+	router.close()
+	_, ok = <-video
+	assert.False(t, ok)
+}
+
+func TestRTPRouterReceiver_Simulcast(t *testing.T) {
+	router := newRTPRouter([]uint8{3}, []uint8{4}, nil, nil)
+	low := router.addReceiver("video", "l")
+	audio := router.addReceiver("audio", "")
+
+	withRID := func(mid, rid string) *rtp.Packet {
+		p := &rtp.Packet{}
+		assert.NoError(t, setHeaderExtension(&p.Header, 3, []byte(mid)))
+		assert.NoError(t, setHeaderExtension(&p.Header, 4, []byte(rid)))
+		return p
+	}
+
+	// The layers that weren't requested are drained
+	_, ok := router.receiver(withRID("video", "h"))
+	assert.False(t, ok)
+	c, ok := router.receiver(withRID("video", "l"))
+	assert.True(t, ok)
+	assert.Equal(t, low, (<-chan routedStream)(c))
+
+	// A media section without simulcast takes the stream whatever its RID
+	c, ok = router.receiver(withRID("audio", "a"))
+	assert.True(t, ok)
+	assert.Equal(t, audio, (<-chan routedStream)(c))
+}
+
+func TestRTPRouterReceiver_RTX(t *testing.T) {
+	router := newRTPRouter([]uint8{3}, []uint8{4}, []uint8{5}, map[uint8]bool{97: true})
+	video := router.addReceiver("video", "")
+	videoRTX := router.addRTXReceiver("video", "")
+	highRTX := router.addRTXReceiver("simulcast", "h")
+	latching := router.addLatchingReceiver()
+	latchingRTX := router.addLatchingRTXReceiver()
+
+	packet := func(payloadType uint8, mid, repairedRID string) *rtp.Packet {
+		p := &rtp.Packet{Header: rtp.Header{PayloadType: payloadType}}
+		if mid != "" {
+			assert.NoError(t, setHeaderExtension(&p.Header, 3, []byte(mid)))
+		}
+		if repairedRID != "" {
+			assert.NoError(t, setHeaderExtension(&p.Header, 5, []byte(repairedRID)))
+		}
+		return p
+	}
+
+	// The RTX streams never take the place of a media stream
+	c, ok := router.receiver(packet(97, "video", ""))
+	assert.True(t, ok)
+	assert.Equal(t, videoRTX, (<-chan routedStream)(c))
+	c, ok = router.receiver(packet(96, "video", ""))
+	assert.True(t, ok)
+	assert.Equal(t, video, (<-chan routedStream)(c))
+
+	// The layer repaired is named by the repaired RID
+	c, ok = router.receiver(packet(97, "simulcast", "h"))
+	assert.True(t, ok)
+	assert.Equal(t, highRTX, (<-chan routedStream)(c))
+
+	c, ok = router.receiver(packet(97, "", ""))
+	assert.True(t, ok)
+	assert.Equal(t, latchingRTX, (<-chan routedStream)(c))
+	_, ok = router.receiver(packet(97, "", ""))
+	assert.False(t, ok, "a second RTX stream without mid is drained")
+	c, ok = router.receiver(packet(96, "", ""))
+	assert.True(t, ok)
+	assert.Equal(t, latching, (<-chan routedStream)(c))
+
+	// The RTX receivers left without stream are closed
+	audioRTX := router.addRTXReceiver("audio", "")
+	router.close()
+	_, ok = <-audioRTX
+	assert.False(t, ok)
+}
@@ -0,0 +1,7 @@
+package webrtc
+
+// RTPRtxParameters dictionary contains information relating to retransmission (RTX) settings.
+// http://draft.ortc.org/#dom-rtcrtprtxparameters
+type RTPRtxParameters struct {
+	SSRC uint32 `json:"ssrc"`
+}
@@ -1,8 +1,16 @@
 package webrtc
 
 import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/pions/rtcp"
 	"github.com/pions/rtp"
+	"github.com/pions/srtp"
 	"github.com/pions/webrtc/pkg/media"
 )
 
@@ -10,10 +18,124 @@ const rtpOutboundMTU = 1400
 
 // RTPSender allows an application to control how a given Track is encoded and transmitted to a remote peer
 type RTPSender struct {
+	// Accessed atomically, kept first for the 64-bit alignment required on
+	// 32-bit platforms
+	nackStats                  nackStats
+	keyFramesSent              uint64
+	packetsDroppedDisconnected uint64
+
+	// packetsSent, bytesSent and headerBytesSent count the packets sent on
+	// the SSRC of the Track for the outbound-rtp stats
+	packetsSent     uint64
+	bytesSent       uint64
+	headerBytesSent uint64
+
+	// lastSent and lastRTXSent are the times the last packet of the Track
+	// and of its RTX stream were sent, in nanoseconds since the epoch, 0
+	// until the first one
+	lastSent    int64
+	lastRTXSent int64
+
 	Track *Track
 
+	// frames finds the first packets of the frames given to the send
+	// PayloadTransform
+	frames frameBoundaries
+
 	transport *DTLSTransport
 
+	mu               sync.RWMutex
+	videoOrientation *VideoOrientation
+	playoutDelay     *PlayoutDelay
+	clockOffset      *int64
+	mid              string
+
+	// payloadType is the negotiated payload type the samples are sent
+	// with, it may differ from the one of the Track when answering
+	payloadType uint8
+
+	// payloadTypes rewrites the other payload types of a raw RTP Track,
+	// set by SetPayloadTypeMap
+	payloadTypes map[uint8]uint8
+
+	// rid is the simulcast layer the Track is sent as, set by SetRID along
+	// the restrictions announced for it
+	rid             string
+	ridRestrictions RIDRestrictions
+
+	bandwidthEstimator         *lossBasedBandwidthEstimator
+	onBandwidthEstimateHandler func(bps int)
+	maxBitrate                 uint64
+
+	onRTCPHandler                  func(rtcp.Packet)
+	onPictureLossIndicationHandler func()
+
+	// senderReports are only sent unless RTCP is disabled
+	senderReports senderReports
+
+	// reducedSizeRTCP sends the feedback without a report first
+	reducedSizeRTCP bool
+
+	// delayEstimator and transportCC are nil unless the transport-wide
+	// sequence number extension was negotiated
+	delayEstimator *delayBasedBandwidthEstimator
+	transportCC    *transportCCHistory
+
+	// remb is set when the REMB feedback of the remote is followed,
+	// rembBitrate is the last estimate it sent, 0 until then
+	remb        bool
+	rembBitrate int
+
+	// paused is accessed atomically, it is set while the remote paused the
+	// Track with a RTCP PAUSE request. pauseID is the one of the last pause.
+	paused  int32
+	pauseID uint16
+	resumed bool
+
+	// keyFrameRequested is accessed atomically, it is set when the remote
+	// requests a keyframe with a PLI or a FIR until Track.SendSamples
+	// skips to one
+	keyFrameRequested int32
+
+	// history is nil unless NACK was negotiated
+	history           *rtpHistory
+	rtxSSRC           uint32
+	rtxPayloadType    uint8
+	rtxSequenceNumber uint16
+
+	// rtpWriter and rtcpWriter write the packets of the Track through the
+	// Interceptors of the API, they are bound by Send
+	rtpWriter  RTPWriter
+	rtcpWriter RTCPWriter
+
+	// red is nil unless RED is negotiated and audio redundancy enabled, it is
+	// only used by the send loop
+	red *redEncoder
+
+	// splicer is nil unless SetInitialRTPState was called, it offsets the
+	// packets so the stream starts at the given state
+	splicer *RTPSplicer
+
+	// disconnected holds the packets written while the ICE connection is
+	// disconnected, the send loop flushes them when reconnected is signaled
+	disconnected *disconnectedQueue
+	reconnected  chan struct{}
+
+	// inputMu is held to write to the Track and to close its input channels
+	// once inputClosed is set, so WriteRTP and WriteSample never write to a
+	// closed channel. inputStopped is closed before, to release the writes
+	// blocked on a full channel.
+	inputMu      sync.RWMutex
+	inputClosed  bool
+	inputStopped chan struct{}
+
+	// sendDone and rtcpDone are closed once the send and RTCP loops started
+	// by Send returned, they are nil until then
+	stopped        bool
+	sendDone       chan struct{}
+	rtcpDone       chan struct{}
+	rtcpReadStream *srtp.ReadStreamSRTCP
+
 	// A reference to the associated api object
 	api *API
 }
@@ -24,7 +146,12 @@ func (api *API) NewRTPSender(track *Track, transport *DTLSTransport) *RTPSender
 		Track:     track,
 		transport: transport,
 		api:       api,
+
+		disconnected: &disconnectedQueue{buffer: api.settingEngine.disconnectedBuffer},
+		reconnected:  make(chan struct{}, 1),
+		inputStopped: make(chan struct{}),
 	}
+	r.bandwidthEstimator = newLossBasedBandwidthEstimator(api.settingEngine.bandwidthEstimationBounds())
 
 	r.Track.sampleInput = make(chan media.Sample, 15) // Is the buffering needed?
 	r.Track.rawInput = make(chan *rtp.Packet, 15)     // Is the buffering needed?
@@ -33,6 +160,7 @@ func (api *API) NewRTPSender(track *Track, transport *DTLSTransport) *RTPSender
 	r.Track.Samples = r.Track.sampleInput
 	r.Track.RawRTP = r.Track.rawInput
 	r.Track.RTCPPackets = r.Track.rtcpInput
+	r.Track.sender = r
 
 	if r.Track.isRawRTP {
 		close(r.Track.Samples)
@@ -43,55 +171,327 @@ func (api *API) NewRTPSender(track *Track, transport *DTLSTransport) *RTPSender
 	return r
 }
 
+// Transport returns the DTLSTransport the Track is sent over
+func (r *RTPSender) Transport() *DTLSTransport {
+	return r.transport
+}
+
 // Send Attempts to set the parameters controlling the sending of media.
 func (r *RTPSender) Send(parameters RTPSendParameters) {
-	if r.Track.isRawRTP {
-		go r.handleRawRTP(r.Track.rawInput)
-	} else {
-		go r.handleSampleRTP(r.Track.sampleInput)
+	r.Track.setHeaderExtensions(parameters.HeaderExtensions)
+	info := r.Track.streamInfo(r.Track.SSRC)
+	rtpWriter := r.api.bindLocalStream(info, RTPWriterFunc(r.writeSRTP))
+	rtcpWriter := r.api.bindRTCPWriter(info, RTCPWriterFunc(r.writeSRTCP))
+	r.mu.Lock()
+	r.rtpWriter, r.rtcpWriter = rtpWriter, rtcpWriter
+	r.mid = parameters.mid
+	r.payloadType = parameters.Encodings.PayloadType
+	r.maxBitrate = parameters.maxBitrate
+	r.reducedSizeRTCP = parameters.reducedSizeRTCP
+	_, transportCC := r.Track.headerExtensionID(TransportCCURI)
+	transportCC = transportCC && !r.api.settingEngine.disableRTCP
+	remb := hasRTCPFeedback(parameters.RTCPFeedback, RTCPFeedback{Type: TypeRTCPFBGoogREMB})
+	followTransportCC, followREMB := congestionControlFeedback(transportCC, remb, r.api.settingEngine.preferREMB)
+	if transportCC && remb {
+		r.transport.congestionControlLog.Do(func() {
+			r.api.log.Infof("both goog-remb and transport-cc are negotiated, following only one (goog-remb: %t)", followREMB)
+		})
+	}
+	if followTransportCC {
+		r.delayEstimator = newDelayBasedBandwidthEstimator(r.api.settingEngine.bandwidthEstimationBounds())
 	}
+	if transportCC {
+		r.transportCC = r.transport.transportCCHistory()
+	}
+	r.remb = followREMB
+	if parameters.maxBitrate != 0 {
+		r.bandwidthEstimator.limit(parameters.maxBitrate)
+		if r.delayEstimator != nil {
+			r.delayEstimator.limit(parameters.maxBitrate)
+		}
+	}
+	if r.api.settingEngine.nack && hasRTCPFeedback(parameters.RTCPFeedback, RTCPFeedback{Type: TypeRTCPFBNACK}) {
+		r.history = &rtpHistory{}
+		if parameters.rtxPayloadType != 0 && parameters.Encodings.RTX.SSRC != 0 {
+			r.rtxSSRC = parameters.Encodings.RTX.SSRC
+			r.rtxPayloadType = parameters.rtxPayloadType
 
-	go r.handleRTCP(r.transport, r.Track.rtcpInput)
+			buf := make([]byte, 2)
+			if _, err := io.ReadFull(r.api.settingEngine.randomSource(), buf); err == nil {
+				r.rtxSequenceNumber = binary.BigEndian.Uint16(buf)
+			}
+		}
+	}
+	sendDone, rtcpDone := make(chan struct{}), make(chan struct{})
+	r.sendDone, r.rtcpDone = sendDone, rtcpDone
+	r.transport.addSender(r)
+	if distance := r.api.settingEngine.audioRedundancy; distance > 0 && parameters.redPayloadType != 0 && r.Track.Kind == RTPCodecTypeAudio {
+		r.red = newREDEncoder(parameters.redPayloadType, distance)
+	}
+	r.mu.Unlock()
+
+	go func() {
+		defer close(sendDone)
+		if r.Track.isRawRTP {
+			r.handleRawRTP(r.Track.rawInput)
+		} else {
+			r.handleSampleRTP(r.Track.sampleInput)
+		}
+	}()
+
+	if r.api.settingEngine.disableRTCP {
+		close(r.Track.rtcpInput)
+		close(rtcpDone)
+		return
+	}
+	go func() {
+		defer close(rtcpDone)
+		r.handleRTCP(r.transport, r.Track.rtcpInput)
+	}()
 }
 
-// Stop irreversibly stops the RTPSender
+// hasStarted returns whether Send was called
+func (r *RTPSender) hasStarted() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.sendDone != nil
+}
+
+// Stop irreversibly stops the RTPSender. It returns once the packets
+// already written to the Track are sent, then sends a RTCP BYE for the Track
+// so the remote learns the stream ended, and closes its RTCP stream. Stop
+// can be called several times, WriteRTP and WriteSample return
+// ErrRTPSenderStopped once it was called.
 func (r *RTPSender) Stop() {
+	if !r.stop() || r.api.settingEngine.disableRTCP || r.transport.State() != DTLSTransportStateConnected {
+		return
+	}
+	if err := r.writeRTCP(&rtcp.Goodbye{Sources: []uint32{r.Track.SSRC}}); err != nil {
+		r.api.log.Warnf("Failed to send RTCP BYE for %d: %v", r.Track.SSRC, err)
+	}
+}
+
+// stop stops the RTPSender without sending a RTCP BYE, it returns whether
+// the RTPSender was sending
+func (r *RTPSender) stop() bool {
+	r.mu.Lock()
+	if r.stopped {
+		r.mu.Unlock()
+		return false
+	}
+	r.stopped = true
+	sendDone, rtcpReadStream := r.sendDone, r.rtcpReadStream
+	r.mu.Unlock()
+
+	r.closeInput()
+	if sendDone != nil {
+		<-sendDone
+		r.transport.removeSender(r)
+	}
+
+	if rtcpReadStream != nil {
+		if err := rtcpReadStream.Close(); err != nil {
+			r.api.log.Warnf("Failed to close the RTCP ReadStream of %d: %v", r.Track.SSRC, err)
+		}
+	}
+	return sendDone != nil
+}
+
+// closeInput closes the input channel of the Track, once the writes in
+// flight returned
+func (r *RTPSender) closeInput() {
+	close(r.inputStopped)
+	r.inputMu.Lock()
+	defer r.inputMu.Unlock()
+
+	r.inputClosed = true
 	if r.Track.isRawRTP {
 		close(r.Track.RawRTP)
 	} else {
 		close(r.Track.Samples)
 	}
+}
+
+// writeInput runs write unless the input of the Track is closed
+func (r *RTPSender) writeInput(write func() error) error {
+	r.inputMu.RLock()
+	defer r.inputMu.RUnlock()
+
+	if r.inputClosed {
+		return ErrRTPSenderStopped
+	}
+	return write()
+}
+
+// wait returns once the RTCP loop of a stopped RTPSender returned. A read
+// pending on a closed SRTCP stream only fails once the session closes, wait
+// is called after the DTLSTransport stopped.
+func (r *RTPSender) wait() {
+	r.mu.RLock()
+	rtcpDone := r.rtcpDone
+	r.mu.RUnlock()
+	if rtcpDone != nil {
+		<-rtcpDone
+	}
+}
+
+// SetInitialRTPState sets the sequence number and timestamp the packets of
+// the Track start at, rather than random ones. The packets of a raw RTP
+// Track keep their spacing, they are offset so the first one starts there.
+// It must be called before the RTPSender starts sending, once the
+// PeerConnection is connected ErrRTPSenderStarted is returned.
+//
+// Sources switched mid-stream on a raw RTP Track are spliced with a
+// RTPSplicer.
+func (r *RTPSender) SetInitialRTPState(sequenceNumber uint16, timestamp uint32) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.sendDone != nil {
+		return ErrRTPSenderStarted
+	}
+	r.splicer = NewRTPSplicer(sequenceNumber, timestamp)
+	return nil
+}
+
+// SetRID sends the Track as the simulcast layer rid, announced with its
+// restrictions in the a=rid line of the following descriptions. The
+// packets carry the RID header extension once negotiated, the layer is
+// only announced when it is. An empty rid stops announcing a layer.
+func (r *RTPSender) SetRID(rid string, restrictions RIDRestrictions) error {
+	if rid != "" && !validRID(rid) {
+		return ErrInvalidRID
+	}
 
-	// TODO properly tear down all loops (and test that)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rid = rid
+	r.ridRestrictions = restrictions
+	return nil
+}
+
+// ridLayer returns the simulcast layer set by SetRID
+func (r *RTPSender) ridLayer() (string, RIDRestrictions) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.rid, r.ridRestrictions
+}
+
+// SetPayloadTypeMap sets the payload types the packets written to a raw RTP
+// Track are sent with, keyed by the payload type they are written with. The
+// packets carrying the payload type of the Track are always sent with the
+// one negotiated for it, which differs when the remote numbered the codec
+// otherwise. The mapping is for the other payload types of a forwarded
+// stream, such as its RTX or RED ones. The rest of the packets is kept.
+func (r *RTPSender) SetPayloadTypeMap(payloadTypes map[uint8]uint8) {
+	mapped := make(map[uint8]uint8, len(payloadTypes))
+	for from, to := range payloadTypes {
+		mapped[from] = to
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.payloadTypes = mapped
+}
+
+// rewritePayloadType rewrites the payload type of a packet written to a raw
+// RTP Track to the one the remote negotiated
+func (r *RTPSender) rewritePayloadType(header *rtp.Header) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if to, ok := r.payloadTypes[header.PayloadType]; ok {
+		header.PayloadType = to
+	} else if header.PayloadType == r.Track.PayloadType && r.payloadType != 0 {
+		header.PayloadType = r.payloadType
+	}
 }
 
 func (r *RTPSender) handleRawRTP(rtpPackets chan *rtp.Packet) {
+	r.mu.RLock()
+	splicer := r.splicer
+	r.mu.RUnlock()
+
 	for {
-		p, ok := <-rtpPackets
-		if !ok {
-			return
+		var p *rtp.Packet
+		select {
+		case <-r.reconnected:
+			r.flushDisconnected(time.Now())
+			continue
+		case packet, ok := <-rtpPackets:
+			if !ok {
+				return
+			}
+			p = packet
 		}
 
+		if splicer != nil {
+			splicer.Rewrite(&p.Header)
+		}
+		r.rewritePayloadType(&p.Header)
+		if p.SSRC == r.Track.SSRC {
+			r.senderReports.setReference(p.Timestamp, time.Now())
+		}
 		r.sendRTP(p)
 	}
 }
 
 func (r *RTPSender) handleSampleRTP(rtpPackets chan media.Sample) {
+	r.mu.RLock()
+	payloadType, splicer := r.payloadType, r.splicer
+	r.mu.RUnlock()
+	if payloadType == 0 {
+		payloadType = r.Track.PayloadType
+	}
+
 	packetizer := rtp.NewPacketizer(
 		rtpOutboundMTU,
-		r.Track.PayloadType,
+		payloadType,
 		r.Track.SSRC,
 		r.Track.Codec.Payloader,
 		rtp.NewRandomSequencer(),
 		r.Track.Codec.ClockRate,
 	)
+	clock := sampleClock{clockRate: r.Track.Codec.ClockRate}
 
 	for {
-		in, ok := <-rtpPackets
-		if !ok {
-			return
+		var in media.Sample
+		select {
+		case <-r.reconnected:
+			r.flushDisconnected(time.Now())
+			continue
+		case sample, ok := <-rtpPackets:
+			if !ok {
+				return
+			}
+			in = sample
+		}
+		if r.Paused() {
+			// Skipped before packetizing so the sequence numbers stay contiguous
+			continue
+		}
+		captureTime := in.Timestamp
+		if captureTime.IsZero() {
+			captureTime = time.Now()
 		}
 		packets := packetizer.Packetize(in.Data, in.Samples)
+		if len(packets) == 0 {
+			continue
+		}
+		timestamp := clock.timestamp(packets[0].Timestamp, in.Timestamp)
+		for _, p := range packets {
+			p.Timestamp = timestamp
+		}
+		r.writeAbsCaptureTime(packets[0], captureTime)
+		if in.IsKeyFrame {
+			atomic.AddUint64(&r.keyFramesSent, 1)
+		}
+		if splicer != nil {
+			for _, p := range packets {
+				splicer.Rewrite(&p.Header)
+			}
+		}
+		r.senderReports.setReference(packets[0].Timestamp, captureTime)
 		for _, p := range packets {
 			r.sendRTP(p)
 		}
@@ -102,53 +502,628 @@ func (r *RTPSender) handleSampleRTP(rtpPackets chan media.Sample) {
 func (r *RTPSender) handleRTCP(transport *DTLSTransport, rtcpPackets chan rtcp.Packet) {
 	srtcpSession, err := transport.getSRTCPSession()
 	if err != nil {
-		pcLog.Warnf("Failed to open SRTCPSession, Track done for: %v %d \n", err, r.Track.SSRC)
+		r.api.log.Warnf("Failed to open SRTCPSession, Track done for: %v %d \n", err, r.Track.SSRC)
 		return
 	}
 
 	readStream, err := srtcpSession.OpenReadStream(r.Track.SSRC)
 	if err != nil {
-		pcLog.Warnf("Failed to open RTCP ReadStream, Track done for: %v %d \n", err, r.Track.SSRC)
+		r.api.log.Warnf("Failed to open RTCP ReadStream, Track done for: %v %d \n", err, r.Track.SSRC)
 		return
 	}
 
-	var rtcpPacket rtcp.Packet
+	// Stop closes the stream once it is known
+	r.mu.Lock()
+	if r.stopped {
+		r.mu.Unlock()
+		if err := readStream.Close(); err != nil {
+			r.api.log.Warnf("Failed to close the RTCP ReadStream of %d: %v", r.Track.SSRC, err)
+		}
+		return
+	}
+	r.rtcpReadStream = readStream
+	r.mu.Unlock()
+
+	reader := r.api.bindRTCPReader(r.Track.streamInfo(r.Track.SSRC), r.Track.srtcpReader(readStream, r.api.log))
 	for {
-		rtcpBuf := make([]byte, receiveMTU)
-		i, err := readStream.Read(rtcpBuf)
+		packets, err := reader.ReadRTCP()
 		if err != nil {
-			pcLog.Warnf("Failed to read, Track done for: %v %d \n", err, r.Track.SSRC)
+			r.api.log.Warnf("Failed to read, Track done for: %v %d \n", err, r.Track.SSRC)
 			return
 		}
 
-		rtcpPacket, _, err = rtcp.Unmarshal(rtcpBuf[:i])
-		if err != nil {
-			pcLog.Warnf("Failed to unmarshal RTCP packet, discarding: %v \n", err)
+		for _, rtcpPacket := range packets {
+			if nack, ok := rtcpPacket.(*rtcp.TransportLayerNack); ok && nack.MediaSSRC == r.Track.SSRC {
+				r.retransmit(nack)
+			}
+			if pauseResume, ok := rtcpPacket.(*PauseResume); ok {
+				r.handlePauseResume(pauseResume)
+			}
+			if requestsKeyFrame(rtcpPacket, r.Track.SSRC) {
+				atomic.StoreInt32(&r.keyFrameRequested, 1)
+				r.onPictureLossIndication()
+			}
+			r.onRTCP(rtcpPacket)
+			for _, report := range receptionReportsFor(rtcpPacket, r.Track.SSRC) {
+				r.onBandwidthEstimate(r.bandwidthEstimate(r.bandwidthEstimator.onReceptionReport(report)))
+			}
+			if transportCC, ok := rtcpPacket.(*TransportLayerCC); ok && transportCC.MediaSSRC == r.Track.SSRC {
+				r.handleTransportCC(transportCC)
+			}
+			if remb, ok := rtcpPacket.(*ReceiverEstimatedMaximumBitrate); ok {
+				r.handleREMB(remb)
+			}
+
+			select {
+			case rtcpPackets <- rtcpPacket:
+			default:
+			}
+		}
+	}
+
+}
+
+// requestsKeyFrame returns whether a RTCP packet is a PLI or a FIR asking
+// for a keyframe of ssrc
+func requestsKeyFrame(packet rtcp.Packet, ssrc uint32) bool {
+	switch p := packet.(type) {
+	case *rtcp.PictureLossIndication:
+		return p.MediaSSRC == ssrc
+	case *FullIntraRequest:
+		for _, entry := range p.FIR {
+			if entry.SSRC == ssrc {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// takeKeyFrameRequest returns whether a keyframe was requested since the
+// last call
+func (r *RTPSender) takeKeyFrameRequest() bool {
+	return atomic.SwapInt32(&r.keyFrameRequested, 0) == 1
+}
+
+// Paused returns true while the remote paused the Track with a RTCP PAUSE
+// request, the media written to the Track is discarded until it is resumed.
+func (r *RTPSender) Paused() bool {
+	return atomic.LoadInt32(&r.paused) == 1
+}
+
+// handlePauseResume pauses or resumes the Track as requested by the remote,
+// a pause is confirmed with a PAUSED message
+func (r *RTPSender) handlePauseResume(pauseResume *PauseResume) {
+	for _, m := range pauseResume.Messages {
+		if m.SSRC != r.Track.SSRC {
 			continue
 		}
 
-		select {
-		case rtcpPackets <- rtcpPacket:
+		r.mu.Lock()
+		switch {
+		case m.Type == PauseResumeTypePause && !(r.resumed && m.PauseID == r.pauseID):
+			r.pauseID = m.PauseID
+			r.resumed = false
+			atomic.StoreInt32(&r.paused, 1)
+		case m.Type == PauseResumeTypeResume && r.Paused() && m.PauseID == r.pauseID:
+			r.resumed = true
+			atomic.StoreInt32(&r.paused, 0)
+		}
+		paused, pauseID := r.Paused(), r.pauseID
+		r.mu.Unlock()
+
+		if m.Type != PauseResumeTypePause || !paused {
+			continue
+		}
+		err := r.writeRTCP(
+			&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{{SSRC: r.Track.SSRC}}},
+			&PauseResume{
+				SenderSSRC: r.Track.SSRC,
+				Messages:   []PauseResumeMessage{{Type: PauseResumeTypePaused, SSRC: r.Track.SSRC, PauseID: pauseID}},
+			},
+		)
+		if err != nil {
+			r.api.log.Warnf("Failed to send PAUSED: %v", err)
+		}
+	}
+}
+
+// OnBandwidthEstimate sets an event handler which is invoked each time the
+// estimate of the available send bandwidth is updated, in bits per second.
+// The estimate is driven by the loss the remote reports for the Track and is
+// clamped to the bounds set in the SettingEngine and to the MaxBitrate of the
+// remote, an encoder can use it as its target bitrate.
+//
+// When the transport-wide sequence number extension (TransportCCURI) is
+// negotiated the packets of every stream are numbered, and the delay
+// variation measured with the TransportLayerCC feedback of the remote lowers
+// the estimate as soon as a queue builds up. The feedback covers the whole
+// transport, it updates the estimate of the RTPSender it is addressed to.
+// The feedback is only read when the remote sends it in a compound packet
+// along a reception report, as the SRTCP session can't route it otherwise.
+func (r *RTPSender) OnBandwidthEstimate(f func(bps int)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onBandwidthEstimateHandler = f
+}
+
+// OnRTCP sets an event handler which is invoked for each RTCP packet the
+// remote sends about the Track, as they are put in Track.RTCPPackets. It is
+// invoked whether or not RTCPPackets is read, and in its own goroutine so
+// the feedback of the Track keeps being handled.
+func (r *RTPSender) OnRTCP(f func(rtcp.Packet)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onRTCPHandler = f
+}
+
+func (r *RTPSender) onRTCP(packet rtcp.Packet) (done chan struct{}) {
+	r.mu.RLock()
+	hdlr := r.onRTCPHandler
+	r.mu.RUnlock()
+
+	done = make(chan struct{})
+	if hdlr == nil {
+		close(done)
+		return
+	}
+
+	go func() {
+		hdlr(packet)
+		close(done)
+	}()
+
+	return
+}
+
+// OnPictureLossIndication sets an event handler which is invoked when the
+// remote requests a keyframe of the Track, with a PictureLossIndication or
+// a FullIntraRequest, such as when a viewer joins mid-stream. The encoder
+// of the Track is expected to send a keyframe soon after.
+func (r *RTPSender) OnPictureLossIndication(f func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onPictureLossIndicationHandler = f
+}
+
+func (r *RTPSender) onPictureLossIndication() (done chan struct{}) {
+	r.mu.RLock()
+	hdlr := r.onPictureLossIndicationHandler
+	r.mu.RUnlock()
+
+	done = make(chan struct{})
+	if hdlr == nil {
+		close(done)
+		return
+	}
+
+	go func() {
+		hdlr()
+		close(done)
+	}()
+
+	return
+}
+
+// MaxBitrate returns the bitrate in bits per second the remote asked not to
+// exceed with the b=AS lines of its description, 0 if it didn't.
+// The estimates given to OnBandwidthEstimate never exceed it.
+func (r *RTPSender) MaxBitrate() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.maxBitrate
+}
+
+func (r *RTPSender) onBandwidthEstimate(bps int) (done chan struct{}) {
+	r.mu.RLock()
+	hdlr := r.onBandwidthEstimateHandler
+	r.mu.RUnlock()
+
+	done = make(chan struct{})
+	if hdlr == nil {
+		close(done)
+		return
+	}
+
+	go func() {
+		hdlr(bps)
+		close(done)
+	}()
+
+	return
+}
+
+// handleTransportCC updates the delay-based estimate with a feedback message
+func (r *RTPSender) handleTransportCC(feedback *TransportLayerCC) {
+	r.mu.RLock()
+	delayEstimator, history := r.delayEstimator, r.transportCC
+	r.mu.RUnlock()
+	if delayEstimator == nil {
+		return
+	}
+
+	delayEstimator.onFeedback(history.arrivals(feedback), time.Now())
+	r.onBandwidthEstimate(r.bandwidthEstimate(r.bandwidthEstimator.bitrate()))
+}
+
+// congestionControlFeedback returns which of the transport-wide congestion
+// control and REMB feedbacks negotiated are followed. Both report the same
+// congestion, only transport-cc is followed when both are negotiated unless
+// REMB is preferred.
+func congestionControlFeedback(transportCC, remb, preferREMB bool) (followTransportCC, followREMB bool) {
+	if transportCC && remb {
+		return !preferREMB, preferREMB
+	}
+	return transportCC, remb
+}
+
+// handleREMB limits the estimate to the bitrate a REMB message of the remote
+// reported for the Track, unless the REMB feedback isn't followed
+func (r *RTPSender) handleREMB(remb *ReceiverEstimatedMaximumBitrate) {
+	followed := false
+	for _, ssrc := range remb.SSRCs {
+		followed = followed || ssrc == r.Track.SSRC
+	}
+
+	r.mu.Lock()
+	followed = followed && r.remb
+	if followed {
+		minBitrate, maxBitrate := r.api.settingEngine.bandwidthEstimationBounds()
+		switch {
+		case remb.Bitrate < uint64(minBitrate):
+			r.rembBitrate = minBitrate
+		case remb.Bitrate > uint64(maxBitrate):
+			r.rembBitrate = maxBitrate
 		default:
+			r.rembBitrate = int(remb.Bitrate)
 		}
 	}
+	r.mu.Unlock()
+
+	if followed {
+		r.onBandwidthEstimate(r.bandwidthEstimate(r.bandwidthEstimator.bitrate()))
+	}
+}
+
+// bandwidthEstimate returns the lowest of the loss-based estimate, of the
+// delay-based one if transport-cc was negotiated and of the last REMB
+// estimate if the REMB feedback is followed
+func (r *RTPSender) bandwidthEstimate(lossBased int) int {
+	r.mu.RLock()
+	delayEstimator, rembBitrate := r.delayEstimator, r.rembBitrate
+	r.mu.RUnlock()
+
+	estimate := lossBased
+	if delayEstimator != nil {
+		if delayBased := delayEstimator.bitrate(); delayBased < estimate {
+			estimate = delayBased
+		}
+	}
+	if rembBitrate != 0 && rembBitrate < estimate {
+		estimate = rembBitrate
+	}
+	return estimate
+}
+
+// SetVideoOrientation sets the CVO information written into the outgoing
+// packets of the Track, it is carried by the last packet of each frame as
+// signaled by the marker bit. Nothing is written unless the
+// urn:3gpp:video-orientation extension was negotiated.
+func (r *RTPSender) SetVideoOrientation(o VideoOrientation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.videoOrientation = &o
+}
+
+// SetPlayoutDelay sets the minimum and maximum playout delay hinted to the
+// receiver in every outgoing packet of the Track. The values are clamped to
+// the 0 to 40.95s range of the extension, nothing is written unless the
+// playout-delay extension was negotiated.
+func (r *RTPSender) SetPlayoutDelay(min, max time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p := newPlayoutDelay(min, max)
+	r.playoutDelay = &p
+}
+
+// SetEstimatedCaptureClockOffset sets the estimated offset between the clock
+// media is captured with and the sender clock, it accompanies the capture
+// time the RTPSender stamps on each sample when abs-capture-time is negotiated.
+func (r *RTPSender) SetEstimatedCaptureClockOffset(offset time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	o := toClockOffset(offset)
+	r.clockOffset = &o
+}
+
+// writeAbsCaptureTime stamps the capture time of a sample on its first packet,
+// the Timestamp of the sample or else the time it was taken from the Track
+func (r *RTPSender) writeAbsCaptureTime(packet *rtp.Packet, captureTime time.Time) {
+	if _, ok := r.Track.headerExtensionID(AbsCaptureTimeURI); !ok {
+		return
+	}
+
+	r.mu.RLock()
+	a := NewAbsCaptureTime(captureTime)
+	a.EstimatedCaptureClockOffset = r.clockOffset
+	r.mu.RUnlock()
+
+	if err := r.Track.SetAbsCaptureTime(&packet.Header, a); err != nil {
+		r.api.log.Warnf("Failed to write abs capture time: %v", err)
+	}
+}
+
+// writeHeaderExtensions adds the negotiated header extensions to an outgoing packet
+func (r *RTPSender) writeHeaderExtensions(packet *rtp.Packet) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if id, ok := r.Track.headerExtensionID(VideoOrientationURI); ok && r.videoOrientation != nil && packet.Marker {
+		if err := setHeaderExtension(&packet.Header, id, r.videoOrientation.marshal()); err != nil {
+			return err
+		}
+	}
+
+	if id, ok := r.Track.headerExtensionID(PlayoutDelayURI); ok && r.playoutDelay != nil {
+		if err := setHeaderExtension(&packet.Header, id, r.playoutDelay.marshal()); err != nil {
+			return err
+		}
+	}
+
+	if id, ok := r.Track.headerExtensionID(MIDURI); ok && r.mid != "" {
+		if err := setHeaderExtension(&packet.Header, id, []byte(r.mid)); err != nil {
+			return err
+		}
+	}
+
+	if id, ok := r.Track.headerExtensionID(RIDURI); ok && r.rid != "" {
+		if err := setHeaderExtension(&packet.Header, id, []byte(r.rid)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NACKStats returns the NACKs received and the retransmissions sent by the
+// RTPSender when NACK is enabled in the SettingEngine
+func (r *RTPSender) NACKStats() NACKStats {
+	return r.nackStats.snapshot()
+}
+
+// stats returns the counters of the RTPSender
+func (r *RTPSender) stats() RTPSenderStats {
+	return RTPSenderStats{
+		TrackID: r.Track.ID,
+		SSRC:    r.Track.SSRC,
+		Paused:  r.Paused(),
+		NACK:    r.NACKStats(),
+
+		KeyFramesSent:              atomic.LoadUint64(&r.keyFramesSent),
+		PacketsDroppedDisconnected: atomic.LoadUint64(&r.packetsDroppedDisconnected),
+	}
+}
+
+// retransmit sends again the packets of a NACK that are still in the history,
+// unless they were sent longer than the retransmit deadline ago
+func (r *RTPSender) retransmit(nack *rtcp.TransportLayerNack) {
+	r.mu.Lock()
+	history := r.history
+	r.mu.Unlock()
+	if history == nil {
+		return
+	}
+	atomic.AddUint64(&r.nackStats.nacksReceived, 1)
+	deadline := r.api.settingEngine.retransmitDeadline
+
+	for _, pair := range nack.Nacks {
+		for _, sequenceNumber := range pair.PacketList() {
+			packet, sent, ok := history.get(sequenceNumber)
+			if !ok {
+				continue
+			}
+
+			// The receiver has given up on a packet sent too long ago: it
+			// would only take bandwidth from the fresh ones
+			if deadline > 0 && time.Since(sent) > deadline {
+				atomic.AddUint64(&r.nackStats.retransmitsAbandoned, 1)
+				continue
+			}
+
+			r.mu.Lock()
+			if r.rtxSSRC != 0 {
+				packet = marshalRTX(packet, r.rtxSSRC, r.rtxPayloadType, r.rtxSequenceNumber)
+				r.rtxSequenceNumber++
+			}
+			r.mu.Unlock()
 
+			r.writeRTP(packet)
+			atomic.AddUint64(&r.nackStats.retransmitsSent, 1)
+		}
+	}
 }
 
 func (r *RTPSender) sendRTP(packet *rtp.Packet) {
+	if r.Paused() {
+		return
+	}
+
+	// The packets written while the ICE connection is disconnected are
+	// held back, so they don't burst out once the path recovers
+	now := time.Now()
+	if r.transport.iceTransport != nil && r.transport.iceTransport.State() == ICETransportStateDisconnected {
+		atomic.AddUint64(&r.packetsDroppedDisconnected, uint64(r.disconnected.push(packet, now)))
+		return
+	}
+	r.flushDisconnected(now)
+	r.send(packet)
+}
+
+// flushDisconnected sends the packets held back while the ICE connection was
+// disconnected, it is only called by the send loop
+func (r *RTPSender) flushDisconnected(now time.Time) {
+	packets, dropped := r.disconnected.flush(now)
+	atomic.AddUint64(&r.packetsDroppedDisconnected, uint64(dropped))
+	for _, p := range packets {
+		r.send(p)
+	}
+}
+
+// signalReconnected asks the send loop to flush the packets held back while
+// the ICE connection was disconnected
+func (r *RTPSender) signalReconnected() {
+	select {
+	case r.reconnected <- struct{}{}:
+	default:
+	}
+}
+
+// send sends a packet of the Track, with its header extensions and
+// redundancy
+func (r *RTPSender) send(packet *rtp.Packet) {
+	if err := r.writeHeaderExtensions(packet); err != nil {
+		r.api.log.Warnf("SendRTP failed to write header extensions: %v", err)
+	}
+
+	if transform := r.api.settingEngine.payloadTransform.Send; transform != nil {
+		if err := transformPayload(transform, PayloadTransformInfo{Sender: r, Track: r.Track}, r.Track.Kind, &r.frames, packet); err != nil {
+			r.api.log.Warnf("SendRTP failed to transform the payload, discarding: %v", err)
+			return
+		}
+	}
+
+	r.mu.RLock()
+	history, red, payloadType := r.history, r.red, r.payloadType
+	r.mu.RUnlock()
+
+	// The retransmissions carry the redundancy as well
+	if red != nil && packet.PayloadType == payloadType {
+		packet = red.encode(packet)
+	}
+	if history != nil {
+		history.add(packet)
+	}
+
+	r.writeRTP(packet)
+}
+
+func (r *RTPSender) writeRTP(packet *rtp.Packet) {
+	// Every packet is numbered, retransmissions included, the header is
+	// copied as the packets of the history are shared
+	header := packet.Header
+	r.mu.RLock()
+	history, rtxSSRC, writer := r.transportCC, r.rtxSSRC, r.rtpWriter
+	r.mu.RUnlock()
+	if id, ok := r.Track.headerExtensionID(TransportCCURI); ok && history != nil {
+		sequenceNumber := history.add(rtpPacketSize(&header, packet.Payload), time.Now())
+		if err := setHeaderExtension(&header, id, []byte{uint8(sequenceNumber >> 8), uint8(sequenceNumber)}); err != nil {
+			r.api.log.Warnf("SendRTP failed to write the transport-wide sequence number: %v", err)
+		}
+	}
+	if id, ok := r.Track.headerExtensionID(AbsSendTimeURI); ok {
+		if err := setHeaderExtension(&header, id, marshalAbsSendTime(toAbsSendTime(time.Now()))); err != nil {
+			r.api.log.Warnf("SendRTP failed to write the abs send time: %v", err)
+		}
+	}
+
+	if writer == nil {
+		writer = RTPWriterFunc(r.writeSRTP)
+	}
+	if err := writer.WriteRTP(&rtp.Packet{Header: header, Payload: packet.Payload}); err != nil {
+		r.api.log.Warnf("SendRTP failed to write: %v", err)
+		return
+	}
+	if rtxSSRC != 0 && header.SSRC == rtxSSRC {
+		atomic.StoreInt64(&r.lastRTXSent, time.Now().UnixNano())
+	} else {
+		atomic.StoreInt64(&r.lastSent, time.Now().UnixNano())
+		padding := paddingSize(&header, packet.Payload)
+		atomic.AddUint64(&r.packetsSent, 1)
+		atomic.AddUint64(&r.bytesSent, uint64(len(packet.Payload)-padding))
+		atomic.AddUint64(&r.headerBytesSent, uint64(rtpPacketSize(&header, nil)+padding))
+		r.sendSenderReport(time.Now())
+	}
+}
+
+// sendSenderReport sends a Sender Report about the Track once the report
+// interval elapsed, see SettingEngine.SetSenderReportInterval, it maps the
+// current time to the RTP clock of the media written last
+func (r *RTPSender) sendSenderReport(now time.Time) {
+	if r.api.settingEngine.disableRTCP || r.Track.Codec == nil {
+		return
+	}
+
+	bytesSent := atomic.LoadUint64(&r.bytesSent)
+	sr, ok := r.senderReports.dueReport(now, r.api.settingEngine.rtcpReport.SenderInterval, r.Track.Codec.ClockRate,
+		atomic.LoadUint64(&r.packetsSent), bytesSent, bytesSent+atomic.LoadUint64(&r.headerBytesSent))
+	if !ok {
+		return
+	}
+	sr.SSRC = r.Track.SSRC
+
+	// The CNAME of the Track completes the compound packet as required by
+	// https://tools.ietf.org/html/rfc3550#section-6.1, it also routes the
+	// report to the stream of the Track on the SRTCP session of the remote
+	sdes := &rtcp.SourceDescription{Chunks: []rtcp.SourceDescriptionChunk{{
+		Source: r.Track.SSRC,
+		Items:  []rtcp.SourceDescriptionItem{{Type: rtcp.SDESCNAME, Text: r.Track.Label}},
+	}}}
+	if err := r.writeRTCP(sr, sdes); err != nil {
+		r.api.log.Warnf("Failed to send Sender Report for %d: %v", r.Track.SSRC, err)
+	}
+}
+
+// writeSRTP writes a packet to the SRTP session, under the Interceptors
+func (r *RTPSender) writeSRTP(packet *rtp.Packet) error {
 	srtpSession, err := r.transport.getSRTPSession()
 	if err != nil {
-		pcLog.Warnf("SendRTP failed to open SrtpSession: %v", err)
-		return
+		return err
 	}
 
 	writeStream, err := srtpSession.OpenWriteStream()
 	if err != nil {
-		pcLog.Warnf("SendRTP failed to open WriteStream: %v", err)
-		return
+		return fmt.Errorf("failed to open WriteStream: %v", err)
+	}
+
+	_, err = writeStream.WriteRTP(&packet.Header, packet.Payload)
+	return err
+}
+
+// writeRTCP sends the packets in a single compound RTCP packet
+func (r *RTPSender) writeRTCP(packets ...rtcp.Packet) error {
+	r.mu.RLock()
+	writer := r.rtcpWriter
+	r.mu.RUnlock()
+
+	if writer == nil {
+		return r.writeSRTCP(packets)
+	}
+	return writer.WriteRTCP(packets)
+}
+
+// writeSRTCP writes the packets to the SRTCP session, under the Interceptors
+func (r *RTPSender) writeSRTCP(packets []rtcp.Packet) error {
+	r.mu.RLock()
+	reducedSize := r.reducedSizeRTCP
+	r.mu.RUnlock()
+
+	raw, err := newCompoundRTCP(reducedSize, packets...)
+	if err != nil {
+		return err
+	}
+
+	srtcpSession, err := r.transport.getSRTCPSession()
+	if err != nil {
+		return err
+	}
+
+	writeStream, err := srtcpSession.OpenWriteStream()
+	if err != nil {
+		return fmt.Errorf("failed to open WriteStream: %v", err)
 	}
 
-	if _, err := writeStream.WriteRTP(&packet.Header, packet.Payload); err != nil {
-		pcLog.Warnf("SendRTP failed to write: %v", err)
+	if _, err := writeStream.Write(raw); err != nil {
+		return fmt.Errorf("failed to write: %v", err)
 	}
+	return nil
 }
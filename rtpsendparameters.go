@@ -1,6 +1,37 @@
 package webrtc
 
-// RTPSendParameters contains the RTP stack settings used by receivers
+// RTPSendParameters contains the RTP stack settings used by senders. A
+// PeerConnection derives them from the negotiation, the exported ones are
+// set by the callers of RTPSender.Send that use the ORTC objects without
+// SDP.
 type RTPSendParameters struct {
-	encodings RTPEncodingParameters
+	// Encodings are the SSRC and the payload type the Track is sent with,
+	// and the SSRC of its retransmissions
+	Encodings RTPEncodingParameters
+
+	// HeaderExtensions maps the URI of the header extensions the remote
+	// supports to their ID, the Track sends the ones it implements
+	HeaderExtensions map[string]uint8
+
+	// mid is the mid of the media section the sender belongs to
+	mid string
+
+	// RTCPFeedback is the RTCP feedback the remote supports, such as the
+	// NACKs the packets are retransmitted for
+	RTCPFeedback []RTCPFeedback
+
+	// reducedSizeRTCP is set when the remote negotiated reduced-size RTCP
+	reducedSizeRTCP bool
+
+	// rtxPayloadType is the payload type retransmissions are sent with, 0
+	// when RTX wasn't negotiated
+	rtxPayloadType uint8
+
+	// redPayloadType is the RED payload type negotiated for the payload type
+	// of the encodings, 0 when RED wasn't negotiated
+	redPayloadType uint8
+
+	// maxBitrate is the bitrate in bits per second the remote asked not to
+	// exceed, 0 without a limit
+	maxBitrate uint64
 }
@@ -0,0 +1,83 @@
+package webrtc
+
+import (
+	"sync"
+
+	"github.com/pions/rtp"
+)
+
+// RTPSplicer rewrites the sequence numbers and timestamps of the packets of
+// successive sources, such as two encoders or an inserted ad, into a single
+// continuous stream. Each source keeps its own numbering, the splicer adds
+// an offset to it that is computed again at every Switch: the receiver sees
+// monotonic sequence numbers with no discontinuity.
+//
+// An RTPSplicer can be used concurrently.
+type RTPSplicer struct {
+	mu sync.Mutex
+
+	// switching is set until the first packet of the current source
+	// arrived, the offsets then map it to nextSequenceNumber and
+	// nextTimestamp
+	switching          bool
+	nextSequenceNumber uint16
+	nextTimestamp      uint32
+
+	sequenceNumberOffset uint16
+	timestampOffset      uint32
+
+	// lastSequenceNumber and lastTimestamp are the ones of the highest
+	// packet written
+	started            bool
+	lastSequenceNumber uint16
+	lastTimestamp      uint32
+}
+
+// NewRTPSplicer returns a RTPSplicer whose stream starts at the given
+// sequence number and timestamp
+func NewRTPSplicer(sequenceNumber uint16, timestamp uint32) *RTPSplicer {
+	return &RTPSplicer{
+		switching:          true,
+		nextSequenceNumber: sequenceNumber,
+		nextTimestamp:      timestamp,
+	}
+}
+
+// Switch marks the start of a new source. Its next packet follows the
+// highest one written, with a timestamp timestampGap after it: the duration
+// of the last frame of the previous source, in units of the clock rate.
+// Switch is a no-op until the first packet was written.
+func (s *RTPSplicer) Switch(timestampGap uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.started {
+		return
+	}
+	s.switching = true
+	s.nextSequenceNumber = s.lastSequenceNumber + 1
+	s.nextTimestamp = s.lastTimestamp + timestampGap
+}
+
+// Rewrite rewrites the sequence number and timestamp of a header of the
+// current source in place
+func (s *RTPSplicer) Rewrite(header *rtp.Header) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.switching {
+		s.switching = false
+		s.sequenceNumberOffset = s.nextSequenceNumber - header.SequenceNumber
+		s.timestampOffset = s.nextTimestamp - header.Timestamp
+	}
+
+	header.SequenceNumber += s.sequenceNumberOffset
+	header.Timestamp += s.timestampOffset
+
+	// A packet reordered within the source keeps its place
+	if !s.started || int16(header.SequenceNumber-s.lastSequenceNumber) > 0 {
+		s.started = true
+		s.lastSequenceNumber = header.SequenceNumber
+		s.lastTimestamp = header.Timestamp
+	}
+}
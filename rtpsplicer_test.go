@@ -0,0 +1,40 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTPSplicer(t *testing.T) {
+	s := NewRTPSplicer(65534, 1000)
+
+	// Nothing to continue yet, the initial state applies
+	s.Switch(3000)
+
+	type packet struct {
+		sequenceNumber uint16
+		timestamp      uint32
+	}
+	rewrite := func(sequenceNumber uint16, timestamp uint32) packet {
+		h := &rtp.Header{SequenceNumber: sequenceNumber, Timestamp: timestamp}
+		s.Rewrite(h)
+		return packet{h.SequenceNumber, h.Timestamp}
+	}
+
+	// The first source starts at the initial state
+	assert.Equal(t, packet{65534, 1000}, rewrite(100, 50000))
+	assert.Equal(t, packet{0, 4000}, rewrite(102, 53000))
+	// A reordered packet is offset like the others
+	assert.Equal(t, packet{65535, 4000}, rewrite(101, 53000))
+
+	// The second source follows the highest packet
+	s.Switch(3000)
+	assert.Equal(t, packet{1, 7000}, rewrite(7, 123))
+	assert.Equal(t, packet{2, 10000}, rewrite(8, 3123))
+
+	// Switching back to the first source continues the stream too
+	s.Switch(3000)
+	assert.Equal(t, packet{3, 13000}, rewrite(103, 56000))
+}
@@ -1,44 +1,159 @@
 package webrtc
 
 import (
+	"sync"
+
 	"github.com/pkg/errors"
 )
 
 // RTPTransceiver represents a combination of an RTPSender and an RTPReceiver that share a common mid.
 type RTPTransceiver struct {
-	Mid       string
-	Sender    *RTPSender
-	Receiver  *RTPReceiver
-	Direction RTPTransceiverDirection
-	// currentDirection RTPTransceiverDirection
+	mu sync.RWMutex
+
+	mid              string
+	sender           *RTPSender
+	receiver         *RTPReceiver
+	direction        RTPTransceiverDirection
+	currentDirection RTPTransceiverDirection
 	// firedDirection   RTPTransceiverDirection
 	// receptive bool
 	stopped bool
 }
 
+// Mid returns the mid of the media section the RTPTransceiver is associated
+// with, it is empty until negotiated
+func (t *RTPTransceiver) Mid() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.mid
+}
+
+func (t *RTPTransceiver) setMid(mid string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.mid = mid
+}
+
+// Sender returns the RTPSender of the RTPTransceiver, nil if it doesn't send
+func (t *RTPTransceiver) Sender() *RTPSender {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.sender
+}
+
+// Receiver returns the RTPReceiver of the RTPTransceiver, nil if it doesn't receive
+func (t *RTPTransceiver) Receiver() *RTPReceiver {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.receiver
+}
+
+// Direction returns the preferred direction of the RTPTransceiver
+func (t *RTPTransceiver) Direction() RTPTransceiverDirection {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.direction
+}
+
+// CurrentDirection returns the direction negotiated for the RTPTransceiver,
+// it is Unknown until an offer/answer exchange has completed
+func (t *RTPTransceiver) CurrentDirection() RTPTransceiverDirection {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.currentDirection
+}
+
+func (t *RTPTransceiver) setCurrentDirection(direction RTPTransceiverDirection) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.currentDirection = direction
+}
+
+// kind returns the kind of the media of the RTPTransceiver
+func (t *RTPTransceiver) kind() RTPCodecType {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	switch {
+	case t.sender != nil && t.sender.Track != nil:
+		return t.sender.Track.Kind
+	case t.receiver != nil:
+		return t.receiver.kind
+	}
+	return RTPCodecType(0)
+}
+
 func (t *RTPTransceiver) setSendingTrack(track *Track) error {
-	t.Sender.Track = track
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped {
+		return errors.Errorf("RTPTransceiver has been stopped")
+	}
+
+	t.sender.Track = track
 
-	switch t.Direction {
+	switch t.direction {
 	case RTPTransceiverDirectionRecvonly:
-		t.Direction = RTPTransceiverDirectionSendrecv
+		t.direction = RTPTransceiverDirectionSendrecv
 	case RTPTransceiverDirectionInactive:
-		t.Direction = RTPTransceiverDirectionSendonly
+		t.direction = RTPTransceiverDirectionSendonly
 	default:
 		return errors.Errorf("Invalid state change in RTPTransceiver.setSending")
 	}
 	return nil
 }
 
-// Stop irreversibly stops the RTPTransceiver
+// removeSendingTrack stops sending on the media section, the Track is kept
+// by the RTPSender. It returns false if the RTPTransceiver wasn't sending.
+func (t *RTPTransceiver) removeSendingTrack() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch t.direction {
+	case RTPTransceiverDirectionSendrecv:
+		t.direction = RTPTransceiverDirectionRecvonly
+	case RTPTransceiverDirectionSendonly:
+		t.direction = RTPTransceiverDirectionInactive
+	default:
+		return false
+	}
+	return true
+}
+
+// Stopped returns true once the RTPTransceiver has been stopped
+func (t *RTPTransceiver) Stopped() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.stopped
+}
+
+// Stop irreversibly stops the RTPTransceiver, its sender and receiver are
+// stopped and its media section is rejected by the following offers and
+// answers. A stopped RTPTransceiver is never reused.
 func (t *RTPTransceiver) Stop() error {
-	if t.Sender != nil {
-		t.Sender.Stop()
+	sender, receiver := t.stop()
+	if sender != nil {
+		sender.Stop()
 	}
-	if t.Receiver != nil {
-		if err := t.Receiver.Stop(); err != nil {
+	if receiver != nil {
+		if err := receiver.Stop(); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// stop marks the RTPTransceiver stopped and returns its sender and receiver
+// for the caller to stop, nil if it was already stopped
+func (t *RTPTransceiver) stop() (*RTPSender, *RTPReceiver) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped {
+		return nil, nil
+	}
+	t.stopped = true
+	t.direction = RTPTransceiverDirectionInactive
+	t.currentDirection = RTPTransceiverDirectionInactive
+	return t.sender, t.receiver
+}
@@ -0,0 +1,129 @@
+package webrtc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pions/rtp"
+	"github.com/pions/sdp/v2"
+)
+
+// rtxCodecName is the name of the RTX payload format
+// https://tools.ietf.org/html/rfc4588#section-8.6
+const rtxCodecName = "rtx"
+
+// rtxOSNSize is the size of the original sequence number heading a RTX payload
+const rtxOSNSize = 2
+
+// The dynamic payload types the RTX payload types are allocated from
+const (
+	dynamicPayloadTypeMin = 96
+	dynamicPayloadTypeMax = 127
+)
+
+// rtxPayloadTypesFromMedia returns the RTX payload types of a media section,
+// mapped to the payload type they are associated with through their apt
+func rtxPayloadTypesFromMedia(media *sdp.MediaDescription) map[uint8]uint8 {
+	rtx := map[uint8]bool{}
+	for _, a := range media.Attributes {
+		if a.Key != "rtpmap" {
+			continue
+		}
+		fields := strings.Fields(a.Value)
+		if len(fields) != 2 || !strings.HasPrefix(strings.ToLower(fields[1]), rtxCodecName+"/") {
+			continue
+		}
+		if pt, err := strconv.ParseUint(fields[0], 10, 8); err == nil {
+			rtx[uint8(pt)] = true
+		}
+	}
+
+	payloadTypes := map[uint8]uint8{}
+	for _, a := range media.Attributes {
+		if a.Key != "fmtp" {
+			continue
+		}
+		fields := strings.Fields(a.Value)
+		if len(fields) != 2 || !strings.HasPrefix(fields[1], "apt=") {
+			continue
+		}
+		pt, err := strconv.ParseUint(fields[0], 10, 8)
+		if err != nil || !rtx[uint8(pt)] {
+			continue
+		}
+		apt, err := strconv.ParseUint(strings.TrimPrefix(fields[1], "apt="), 10, 8)
+		if err != nil {
+			continue
+		}
+		payloadTypes[uint8(pt)] = uint8(apt)
+	}
+	return payloadTypes
+}
+
+// rtxPayloadTypeFor returns the RTX payload type associated with a payload type
+func rtxPayloadTypeFor(rtxPayloadTypes map[uint8]uint8, payloadType uint8) (uint8, bool) {
+	for rtx, apt := range rtxPayloadTypes {
+		if apt == payloadType {
+			return rtx, true
+		}
+	}
+	return 0, false
+}
+
+// allocateRTXPayloadTypes gives the codecs a RTX payload type each among the
+// dynamic payload types the MediaEngine doesn't use
+func allocateRTXPayloadTypes(m *MediaEngine, codecs []*RTPCodec) map[uint8]uint8 {
+	used := map[uint8]bool{}
+	for _, codec := range m.codecs {
+		used[codec.PayloadType] = true
+	}
+
+	payloadTypes := map[uint8]uint8{}
+	next := dynamicPayloadTypeMin
+	for _, codec := range codecs {
+		for next <= dynamicPayloadTypeMax && used[uint8(next)] {
+			next++
+		}
+		if next > dynamicPayloadTypeMax {
+			break
+		}
+		payloadTypes[uint8(next)] = codec.PayloadType
+		next++
+	}
+	return payloadTypes
+}
+
+// rtxFmtp returns the format parameters of a RTX payload type
+func rtxFmtp(apt uint8) string {
+	return fmt.Sprintf("apt=%d", apt)
+}
+
+// marshalRTX wraps a packet into a RTX packet as defined in
+// https://tools.ietf.org/html/rfc4588#section-4
+func marshalRTX(packet *rtp.Packet, ssrc uint32, payloadType uint8, sequenceNumber uint16) *rtp.Packet {
+	rtx := &rtp.Packet{Header: packet.Header}
+	rtx.SSRC = ssrc
+	rtx.PayloadType = payloadType
+	rtx.SequenceNumber = sequenceNumber
+
+	rtx.Payload = make([]byte, rtxOSNSize+len(packet.Payload))
+	binary.BigEndian.PutUint16(rtx.Payload, packet.SequenceNumber)
+	copy(rtx.Payload[rtxOSNSize:], packet.Payload)
+	return rtx
+}
+
+// unmarshalRTX restores the original packet of a RTX packet
+func unmarshalRTX(rtx *rtp.Packet, ssrc uint32, payloadType uint8) (*rtp.Packet, error) {
+	if len(rtx.Payload) < rtxOSNSize {
+		return nil, fmt.Errorf("RTX payload is too short")
+	}
+
+	packet := &rtp.Packet{Header: rtx.Header}
+	packet.SSRC = ssrc
+	packet.PayloadType = payloadType
+	packet.SequenceNumber = binary.BigEndian.Uint16(rtx.Payload)
+	packet.Payload = rtx.Payload[rtxOSNSize:]
+	return packet, nil
+}
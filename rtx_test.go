@@ -0,0 +1,58 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/rtp"
+	"github.com/pions/sdp/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTXPayloadTypesFromMedia(t *testing.T) {
+	media := (&sdp.MediaDescription{}).
+		WithCodec(96, VP8, 90000, 0, "").
+		WithCodec(97, rtxCodecName, 90000, 0, rtxFmtp(96)).
+		WithCodec(100, H264, 90000, 0, "packetization-mode=1").
+		WithCodec(101, "RTX", 90000, 0, rtxFmtp(100))
+
+	payloadTypes := rtxPayloadTypesFromMedia(media)
+	assert.Equal(t, map[uint8]uint8{97: 96, 101: 100}, payloadTypes)
+
+	rtx, ok := rtxPayloadTypeFor(payloadTypes, 100)
+	assert.True(t, ok)
+	assert.Equal(t, uint8(101), rtx)
+	_, ok = rtxPayloadTypeFor(payloadTypes, 98)
+	assert.False(t, ok)
+}
+
+func TestAllocateRTXPayloadTypes(t *testing.T) {
+	m := MediaEngine{}
+	m.RegisterDefaultCodecs()
+
+	payloadTypes := allocateRTXPayloadTypes(&m, m.getCodecsByKind(RTPCodecTypeVideo))
+	assert.Equal(t, map[uint8]uint8{
+		97:  DefaultPayloadTypeVP8,
+		99:  DefaultPayloadTypeH264,
+		101: DefaultPayloadTypeVP9,
+	}, payloadTypes)
+}
+
+func TestRTX(t *testing.T) {
+	packet := &rtp.Packet{
+		Header:  rtp.Header{SSRC: 1, PayloadType: 96, SequenceNumber: 0x1234, Timestamp: 10, Marker: true},
+		Payload: []byte{0xAA},
+	}
+
+	rtx := marshalRTX(packet, 2, 97, 7)
+	assert.Equal(t, uint32(2), rtx.SSRC)
+	assert.Equal(t, uint8(97), rtx.PayloadType)
+	assert.Equal(t, uint16(7), rtx.SequenceNumber)
+	assert.Equal(t, []byte{0x12, 0x34, 0xAA}, rtx.Payload)
+
+	restored, err := unmarshalRTX(rtx, 1, 96)
+	assert.NoError(t, err)
+	assert.Equal(t, packet, restored)
+
+	_, err = unmarshalRTX(&rtp.Packet{Payload: []byte{0x00}}, 1, 96)
+	assert.Error(t, err)
+}
@@ -0,0 +1,45 @@
+package webrtc
+
+import "time"
+
+// sampleClock derives the RTP timestamps of the samples from their capture
+// time. The samples without one advance from the last timestamp by the
+// Samples of the previous sample, as the packetizer counts them.
+type sampleClock struct {
+	clockRate uint32
+
+	started  bool
+	first    time.Time
+	firstRTP uint32
+
+	// offset is added to the timestamps of the packetizer, so they carry on
+	// from the last timestamped sample
+	offset uint32
+}
+
+// timestamp returns the RTP timestamp of a sample, packetized is the one
+// the packetizer gave it
+func (c *sampleClock) timestamp(packetized uint32, captured time.Time) uint32 {
+	if captured.IsZero() || c.clockRate == 0 {
+		return packetized + c.offset
+	}
+	if !c.started {
+		c.started = true
+		c.first = captured
+		c.firstRTP = packetized + c.offset
+		return c.firstRTP
+	}
+
+	timestamp := c.firstRTP + uint32(durationTicks(captured.Sub(c.first), c.clockRate))
+	c.offset = timestamp - packetized
+	return timestamp
+}
+
+// durationTicks converts a duration to ticks of an RTP clock. It is split
+// in seconds so the product can't overflow, and rounded as the frame
+// intervals are seldom a whole number of nanoseconds.
+func durationTicks(d time.Duration, clockRate uint32) int64 {
+	seconds := d / time.Second
+	rest := d % time.Second
+	return int64(seconds)*int64(clockRate) + (int64(rest)*int64(clockRate)+int64(time.Second/2))/int64(time.Second)
+}
@@ -0,0 +1,33 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampleClock(t *testing.T) {
+	c := sampleClock{clockRate: 90000}
+	start := time.Unix(1000, 0)
+
+	// Without a capture time the timestamps of the packetizer are kept
+	assert.Equal(t, uint32(100), c.timestamp(100, time.Time{}))
+
+	// The first timestamped sample anchors the clock
+	assert.Equal(t, uint32(200), c.timestamp(200, start))
+	assert.Equal(t, uint32(200+3000), c.timestamp(201, start.Add(time.Second/30)))
+	assert.Equal(t, uint32(200+90000*2), c.timestamp(202, start.Add(2*time.Second)))
+
+	// The samples without one carry on from the last timestamped sample
+	assert.Equal(t, uint32(200+90000*2+1), c.timestamp(203, time.Time{}))
+
+	// The timestamps wrap around
+	c = sampleClock{clockRate: 90000}
+	assert.Equal(t, uint32(0xFFFFFFFF), c.timestamp(0xFFFFFFFF, start))
+	assert.Equal(t, uint32(89999), c.timestamp(0, start.Add(time.Second)))
+
+	// A long elapsed time doesn't overflow
+	ticks := uint64(90000) * 3600 * 24 * 30
+	assert.Equal(t, uint32(0xFFFFFFFF+ticks), c.timestamp(1, start.Add(time.Hour*24*30)))
+}
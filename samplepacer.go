@@ -0,0 +1,62 @@
+package webrtc
+
+import (
+	"io"
+	"time"
+
+	"github.com/pions/webrtc/pkg/media"
+)
+
+// samplePacer writes the samples of a media file as they are due, see
+// Track.SendSamples
+type samplePacer struct {
+	reader media.SampleReader
+	write  func(media.Sample) error
+
+	// keyFrameRequested returns whether the remote requested a keyframe
+	// since the last call, it is nil for audio
+	keyFrameRequested func() bool
+
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+func (p *samplePacer) run() error {
+	var start time.Time
+	var first, skipped time.Duration
+	for {
+		sample, pts, err := p.reader.NextSample()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		// The samples up to the next keyframe are skipped, it is due in
+		// place of the first one
+		if p.keyFrameRequested != nil && p.keyFrameRequested() && !sample.IsKeyFrame {
+			next := pts
+			for !sample.IsKeyFrame {
+				if sample, pts, err = p.reader.NextSample(); err == io.EOF {
+					return nil
+				} else if err != nil {
+					return err
+				}
+			}
+			skipped += pts - next
+		}
+
+		if start.IsZero() {
+			start, first = p.now(), pts
+		}
+		due := start.Add(pts - first - skipped)
+		if wait := due.Sub(p.now()); wait > 0 {
+			p.sleep(wait)
+		}
+
+		sample.Timestamp = due
+		if err := p.write(sample); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package webrtc
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pions/rtcp"
+	"github.com/pions/webrtc/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+type fileSample struct {
+	pts      time.Duration
+	keyFrame bool
+}
+
+type testSampleReader struct {
+	samples []fileSample
+	err     error
+}
+
+func (r *testSampleReader) NextSample() (media.Sample, time.Duration, error) {
+	if len(r.samples) == 0 {
+		if r.err != nil {
+			return media.Sample{}, 0, r.err
+		}
+		return media.Sample{}, 0, io.EOF
+	}
+	s := r.samples[0]
+	r.samples = r.samples[1:]
+	return media.Sample{Data: []byte{byte(s.pts / time.Millisecond)}, IsKeyFrame: s.keyFrame}, s.pts, nil
+}
+
+func TestSamplePacer(t *testing.T) {
+	start := time.Unix(1000, 0)
+	now := start
+
+	var written []media.Sample
+	requests := map[int]bool{2: true}
+	p := &samplePacer{
+		reader: &testSampleReader{samples: []fileSample{
+			{0, true},
+			{40 * time.Millisecond, false},
+			{80 * time.Millisecond, false},
+			{120 * time.Millisecond, false},
+			{160 * time.Millisecond, true},
+			{200 * time.Millisecond, false},
+		}},
+		write: func(s media.Sample) error {
+			written = append(written, s)
+			// Writing takes some time
+			now = now.Add(time.Millisecond)
+			return nil
+		},
+		keyFrameRequested: func() bool {
+			return requests[len(written)]
+		},
+		now:   func() time.Time { return now },
+		sleep: func(d time.Duration) { now = now.Add(d) },
+	}
+	assert.NoError(t, p.run())
+
+	// The keyframe is due in place of the sample following the request
+	if !assert.Len(t, written, 4) {
+		return
+	}
+	for i, expected := range []struct {
+		pts time.Duration
+		due time.Duration
+	}{
+		{0, 0},
+		{40 * time.Millisecond, 40 * time.Millisecond},
+		{160 * time.Millisecond, 80 * time.Millisecond},
+		{200 * time.Millisecond, 120 * time.Millisecond},
+	} {
+		assert.Equal(t, []byte{byte(expected.pts / time.Millisecond)}, written[i].Data)
+		assert.Equal(t, start.Add(expected.due), written[i].Timestamp)
+	}
+}
+
+func TestSamplePacer_Errors(t *testing.T) {
+	readErr := errors.New("read")
+	p := &samplePacer{
+		reader: &testSampleReader{samples: []fileSample{{0, false}}, err: readErr},
+		write:  func(media.Sample) error { return nil },
+		now:    time.Now,
+		sleep:  time.Sleep,
+	}
+	assert.Equal(t, readErr, p.run())
+
+	p.reader = &testSampleReader{samples: []fileSample{{0, false}}}
+	p.write = func(media.Sample) error { return ErrRTPSenderStopped }
+	assert.Equal(t, ErrRTPSenderStopped, p.run())
+
+	// The file ends before the requested keyframe
+	written := 0
+	p.reader = &testSampleReader{samples: []fileSample{{0, true}, {40 * time.Millisecond, false}}}
+	p.write = func(media.Sample) error { written++; return nil }
+	p.keyFrameRequested = func() bool { return written == 1 }
+	assert.NoError(t, p.run())
+	assert.Equal(t, 1, written)
+}
+
+func TestRequestsKeyFrame(t *testing.T) {
+	assert.True(t, requestsKeyFrame(&rtcp.PictureLossIndication{MediaSSRC: 1}, 1))
+	assert.False(t, requestsKeyFrame(&rtcp.PictureLossIndication{MediaSSRC: 2}, 1))
+	assert.True(t, requestsKeyFrame(&FullIntraRequest{FIR: []FIREntry{{SSRC: 2}, {SSRC: 1}}}, 1))
+	assert.False(t, requestsKeyFrame(&FullIntraRequest{FIR: []FIREntry{{SSRC: 2}}}, 1))
+	assert.False(t, requestsKeyFrame(&rtcp.ReceiverReport{}, 1))
+}
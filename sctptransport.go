@@ -2,7 +2,6 @@ package webrtc
 
 import (
 	"errors"
-	"fmt"
 	"math"
 	"sync"
 
@@ -36,6 +35,14 @@ type SCTPTransport struct {
 	association          *sctp.Association
 	onDataChannelHandler func(*DataChannel)
 
+	// negotiatedIDs are the IDs of the negotiated data channels. The remote
+	// may open their streams first, these are held unread in
+	// negotiatedStreams until the DataChannel opens, or handed to the
+	// DataChannel waiting for them in negotiatedDataChannels.
+	negotiatedIDs          map[uint16]struct{}
+	negotiatedStreams      map[uint16]*sctp.Stream
+	negotiatedDataChannels map[uint16]*DataChannel
+
 	api *API
 }
 
@@ -48,6 +55,10 @@ func (api *API) NewSCTPTransport(dtls *DTLSTransport) *SCTPTransport {
 		State:         SCTPTransportStateConnecting,
 		port:          5000, // TODO
 		api:           api,
+
+		negotiatedIDs:          make(map[uint16]struct{}),
+		negotiatedStreams:      make(map[uint16]*sctp.Stream),
+		negotiatedDataChannels: make(map[uint16]*DataChannel),
 	}
 
 	res.updateMessageSize()
@@ -76,30 +87,46 @@ func (r *SCTPTransport) GetCapabilities() SCTPCapabilities {
 // a connection over SCTP.
 func (r *SCTPTransport) Start(remoteCaps SCTPCapabilities) error {
 	r.lock.Lock()
-	defer r.lock.Unlock()
 
 	// TODO: port
 	_ = r.MaxMessageSize // TODO
 
 	if err := r.ensureDTLS(); err != nil {
+		r.lock.Unlock()
 		return err
 	}
+	conn := r.dtlsTransport.conn
+	r.lock.Unlock()
 
-	sctpAssociation, err := sctp.Client(r.dtlsTransport.conn)
+	// The handshake runs unlocked, Stop doesn't wait for it. The INIT
+	// isn't retransmitted, a handshake that stalls only ends once the
+	// DTLSTransport is closed after Stop.
+	sctpAssociation, err := sctp.Client(conn)
 	if err != nil {
 		return err
 	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.State == SCTPTransportStateClosed {
+		if err := sctpAssociation.Close(); err != nil {
+			r.api.log.Warnf("Failed to close SCTP association: %v", err)
+		}
+		return ErrSCTPTransportStopped
+	}
 	r.association = sctpAssociation
 
-	go r.acceptDataChannels()
+	go r.acceptDataChannels(sctpAssociation)
 
 	return nil
 }
 
-// Stop stops the SCTPTransport
+// Stop stops the SCTPTransport, a pending Start fails once the
+// DTLSTransport is closed
 func (r *SCTPTransport) Stop() error {
 	r.lock.Lock()
 	defer r.lock.Unlock()
+	r.State = SCTPTransportStateClosed
 	if r.association == nil {
 		return nil
 	}
@@ -109,7 +136,6 @@ func (r *SCTPTransport) Stop() error {
 	}
 
 	r.association = nil
-	r.State = SCTPTransportStateClosed
 
 	return nil
 }
@@ -123,17 +149,29 @@ func (r *SCTPTransport) ensureDTLS() error {
 	return nil
 }
 
-func (r *SCTPTransport) acceptDataChannels() {
-	r.lock.RLock()
-	a := r.association
-	r.lock.RUnlock()
+// acceptDataChannels accepts the data channels of the association it is
+// given, Stop may reset the one of the SCTPTransport before it runs
+func (r *SCTPTransport) acceptDataChannels(a *sctp.Association) {
 	for {
-		dc, err := datachannel.Accept(a)
+		stream, err := a.AcceptStream()
 		if err != nil {
-			fmt.Println("Failed to accept data channel:", err)
+			r.api.log.Warnf("Failed to accept data channel: %v", err)
 			// TODO: Kill DataChannel/PeerConnection?
 			return
 		}
+		stream.SetDefaultPayloadType(sctp.PayloadTypeWebRTCBinary)
+
+		if r.acceptNegotiated(stream) {
+			continue
+		}
+
+		// The stream of a negotiated data channel the application didn't
+		// create yet doesn't start with a DCEP open
+		dc, err := datachannel.Server(stream)
+		if err != nil {
+			r.api.log.Warnf("Failed to accept data channel: %v", err)
+			continue
+		}
 
 		var ordered = true
 		var maxRetransmits *uint16
@@ -170,12 +208,77 @@ func (r *SCTPTransport) acceptDataChannels() {
 			ReadyState:        DataChannelStateOpen,
 			api:               r.api,
 		}
+		stream.SetReliabilityParams(rtcDC.reliabilityParams())
 
 		<-r.onDataChannel(rtcDC)
 		rtcDC.handleOpen(dc)
 	}
 }
 
+// registerNegotiated records the ID of a negotiated data channel, the
+// stream the remote opens with it is kept for the DataChannel
+func (r *SCTPTransport) registerNegotiated(id uint16) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.negotiatedIDs[id] = struct{}{}
+}
+
+// acceptNegotiated takes the accepted stream of a negotiated data channel,
+// it returns false for the streams announced over DCEP
+func (r *SCTPTransport) acceptNegotiated(stream *sctp.Stream) bool {
+	id := stream.StreamIdentifier()
+
+	r.lock.Lock()
+	if _, ok := r.negotiatedIDs[id]; !ok {
+		r.lock.Unlock()
+		return false
+	}
+	d, ok := r.negotiatedDataChannels[id]
+	if !ok {
+		r.negotiatedStreams[id] = stream
+		r.lock.Unlock()
+		return true
+	}
+	delete(r.negotiatedDataChannels, id)
+	r.lock.Unlock()
+
+	d.openNegotiated(stream)
+	return true
+}
+
+// negotiatedStream returns the SCTP stream of the negotiated DataChannel,
+// or nil if the remote opened it and it is yet to be accepted: it is
+// handed to the DataChannel then
+func (r *SCTPTransport) negotiatedStream(d *DataChannel) *sctp.Stream {
+	id := *d.ID
+
+	r.lock.Lock()
+	r.negotiatedIDs[id] = struct{}{}
+	if stream, ok := r.negotiatedStreams[id]; ok {
+		delete(r.negotiatedStreams, id)
+		r.lock.Unlock()
+		return stream
+	}
+	r.negotiatedDataChannels[id] = d
+	association := r.association
+	r.lock.Unlock()
+
+	// The stream is opened unlocked, acceptDataChannels must go on
+	// accepting the streams for the association to create them. OpenStream
+	// only fails if the stream already exists, the DataChannel then stays
+	// waiting for it.
+	stream, err := association.OpenStream(id, sctp.PayloadTypeWebRTCBinary)
+	if err != nil {
+		return nil
+	}
+
+	r.lock.Lock()
+	delete(r.negotiatedDataChannels, id)
+	r.lock.Unlock()
+
+	return stream
+}
+
 // OnDataChannel sets an event handler which is invoked when a data
 // channel message arrives from a remote peer.
 func (r *SCTPTransport) OnDataChannel(f func(*DataChannel)) {
@@ -0,0 +1,164 @@
+package webrtc
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pions/sdp/v2"
+)
+
+// SDES exchanges the SRTP master keys in the a=crypto lines of the media
+// sections https://tools.ietf.org/html/rfc4568, only the crypto suite of
+// the SRTP sessions is supported
+const (
+	sdesAttributeKey = "crypto"
+	sdesCryptoSuite  = "AES_CM_128_HMAC_SHA1_80"
+	sdesKeyMethod    = "inline:"
+
+	sdesMasterKeyLength  = 16
+	sdesMasterSaltLength = 14
+)
+
+// sdesCrypto is an a=crypto line, such as
+// 1 AES_CM_128_HMAC_SHA1_80 inline:<base64 of the master key and salt>
+type sdesCrypto struct {
+	tag int
+
+	// keySalt is the master key followed by the master salt
+	keySalt []byte
+}
+
+// newSDESKeySalt draws a new master key and salt from r
+func newSDESKeySalt(r io.Reader) ([]byte, error) {
+	keySalt := make([]byte, sdesMasterKeyLength+sdesMasterSaltLength)
+	if _, err := io.ReadFull(r, keySalt); err != nil {
+		return nil, err
+	}
+	return keySalt, nil
+}
+
+// parseSDESCrypto parses the value of an a=crypto line. The lifetime and
+// MKI of the key parameters are ignored, the session parameters too.
+func parseSDESCrypto(value string) (sdesCrypto, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 3 {
+		return sdesCrypto{}, fmt.Errorf("invalid crypto attribute %q", value)
+	}
+
+	tag, err := strconv.ParseUint(fields[0], 10, 31)
+	if err != nil {
+		return sdesCrypto{}, fmt.Errorf("invalid crypto tag %q", fields[0])
+	}
+	if fields[1] != sdesCryptoSuite {
+		return sdesCrypto{}, fmt.Errorf("unsupported crypto suite %s", fields[1])
+	}
+	if !strings.HasPrefix(fields[2], sdesKeyMethod) {
+		return sdesCrypto{}, fmt.Errorf("unsupported key method %q", fields[2])
+	}
+
+	// Several keys can be separated by ;, the first one is used
+	keyParams := strings.Split(strings.Split(fields[2][len(sdesKeyMethod):], ";")[0], "|")
+	keySalt, err := base64.StdEncoding.DecodeString(keyParams[0])
+	if err != nil {
+		return sdesCrypto{}, fmt.Errorf("invalid crypto key: %v", err)
+	}
+	if len(keySalt) != sdesMasterKeyLength+sdesMasterSaltLength {
+		return sdesCrypto{}, fmt.Errorf("invalid crypto key length %d", len(keySalt))
+	}
+
+	return sdesCrypto{tag: int(tag), keySalt: keySalt}, nil
+}
+
+func (c sdesCrypto) String() string {
+	return fmt.Sprintf("%d %s %s%s", c.tag, sdesCryptoSuite, sdesKeyMethod, base64.StdEncoding.EncodeToString(c.keySalt))
+}
+
+func (c sdesCrypto) masterKey() []byte {
+	return c.keySalt[:sdesMasterKeyLength]
+}
+
+func (c sdesCrypto) masterSalt() []byte {
+	return c.keySalt[sdesMasterKeyLength:]
+}
+
+// sdesCryptoFromMedia returns the first supported a=crypto line of a media
+// section
+func sdesCryptoFromMedia(media *sdp.MediaDescription) (sdesCrypto, bool) {
+	for _, a := range media.Attributes {
+		if a.Key != sdesAttributeKey {
+			continue
+		}
+		if crypto, err := parseSDESCrypto(a.Value); err == nil {
+			return crypto, true
+		}
+	}
+	return sdesCrypto{}, false
+}
+
+// hasFingerprint returns whether a description carries a DTLS fingerprint,
+// at the session level or in its first media section
+func hasFingerprint(desc *sdp.SessionDescription) bool {
+	if _, ok := desc.Attribute("fingerprint"); ok {
+		return true
+	}
+	if len(desc.MediaDescriptions) == 0 {
+		return false
+	}
+	_, ok := desc.MediaDescriptions[0].Attribute("fingerprint")
+	return ok
+}
+
+// remoteSDESCrypto returns the a=crypto line carrying the keys of the
+// remote. SDES is negotiated when it is enabled and the remote description
+// has no fingerprint to run the DTLS handshake with.
+func (pc *PeerConnection) remoteSDESCrypto(desc *sdp.SessionDescription) (sdesCrypto, bool) {
+	if !pc.api.settingEngine.sdes || hasFingerprint(desc) {
+		return sdesCrypto{}, false
+	}
+	for _, m := range desc.MediaDescriptions {
+		if m.MediaName.Media == "application" || m.MediaName.Port.Value == 0 {
+			continue
+		}
+		if crypto, ok := sdesCryptoFromMedia(m); ok {
+			return crypto, true
+		}
+	}
+	return sdesCrypto{}, false
+}
+
+// ensureSDESKeySalt draws the master key and salt the PeerConnection
+// announces, once
+func (pc *PeerConnection) ensureSDESKeySalt() error {
+	if pc.sdesKeySalt != nil {
+		return nil
+	}
+	keySalt, err := newSDESKeySalt(pc.api.settingEngine.randomSource())
+	if err != nil {
+		return err
+	}
+	pc.sdesKeySalt = keySalt
+	return nil
+}
+
+// localSDESCrypto returns the a=crypto line of a local media section. An
+// offer announces the keys with SDES enabled, an answer only once SDES was
+// negotiated, with the tag of the line of the remote media section.
+func (pc *PeerConnection) localSDESCrypto(remoteMedia *sdp.MediaDescription) (sdesCrypto, bool) {
+	if pc.sdesKeySalt == nil {
+		return sdesCrypto{}, false
+	}
+	if remoteMedia == nil {
+		return sdesCrypto{tag: 1, keySalt: pc.sdesKeySalt}, true
+	}
+	if _, ok := pc.remoteSDESCrypto(pc.RemoteDescription().parsed); !ok {
+		return sdesCrypto{}, false
+	}
+	remote, ok := sdesCryptoFromMedia(remoteMedia)
+	if !ok {
+		return sdesCrypto{}, false
+	}
+	return sdesCrypto{tag: remote.tag, keySalt: pc.sdesKeySalt}, true
+}
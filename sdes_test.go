@@ -0,0 +1,88 @@
+package webrtc
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/pions/sdp/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSDESCrypto(t *testing.T) {
+	keySalt, err := newSDESKeySalt(bytes.NewReader(bytes.Repeat([]byte{0x01}, 30)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crypto := sdesCrypto{tag: 1, keySalt: keySalt}
+	assert.Equal(t, "1 AES_CM_128_HMAC_SHA1_80 inline:AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEB", crypto.String())
+	assert.Len(t, crypto.masterKey(), 16)
+	assert.Len(t, crypto.masterSalt(), 14)
+
+	parsed, err := parseSDESCrypto(crypto.String())
+	assert.NoError(t, err)
+	assert.Equal(t, crypto, parsed)
+
+	// The lifetime, MKI and session parameters are ignored
+	parsed, err = parseSDESCrypto("2 AES_CM_128_HMAC_SHA1_80 inline:AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEB|2^20|1:4 UNENCRYPTED_SRTCP")
+	assert.NoError(t, err)
+	assert.Equal(t, sdesCrypto{tag: 2, keySalt: keySalt}, parsed)
+
+	for _, value := range []string{
+		"1 AES_CM_128_HMAC_SHA1_80",
+		"x AES_CM_128_HMAC_SHA1_80 inline:AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEB",
+		"1 AES_CM_128_HMAC_SHA1_32 inline:AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEB",
+		"1 AES_CM_128_HMAC_SHA1_80 uri:AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEB",
+		"1 AES_CM_128_HMAC_SHA1_80 inline:AQEB",
+		"1 AES_CM_128_HMAC_SHA1_80 inline:!!",
+	} {
+		_, err := parseSDESCrypto(value)
+		assert.Error(t, err, value)
+	}
+
+	// The first supported line of a media section is used
+	m := (&sdp.MediaDescription{}).
+		WithValueAttribute(sdesAttributeKey, "1 AES_CM_128_HMAC_SHA1_32 inline:AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEB").
+		WithValueAttribute(sdesAttributeKey, "2 AES_CM_128_HMAC_SHA1_80 inline:AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEB")
+	found, ok := sdesCryptoFromMedia(m)
+	assert.True(t, ok)
+	assert.Equal(t, 2, found.tag)
+}
+
+var fingerprintLine = regexp.MustCompile(`a=fingerprint:[^\r\n]*\r\n`)
+
+func TestPeerConnection_SDES_Disabled(t *testing.T) {
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The offer has no a=crypto lines without the opt-in
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ok := sdesCryptoFromMedia(offer.parsed.MediaDescriptions[0])
+	assert.False(t, ok)
+
+	// The remote keys are never used either
+	keySalt, err := newSDESKeySalt(bytes.NewReader(bytes.Repeat([]byte{0x01}, 30)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range offer.parsed.MediaDescriptions {
+		m.WithValueAttribute(sdesAttributeKey, sdesCrypto{tag: 1, keySalt: keySalt}.String())
+	}
+	raw, err := offer.parsed.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	offer.SDP = fingerprintLine.ReplaceAllString(string(raw), "")
+	assert.Error(t, pcAnswer.SetRemoteDescription(offer))
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
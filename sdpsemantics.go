@@ -0,0 +1,211 @@
+package webrtc
+
+import (
+	"strconv"
+
+	"github.com/pions/sdp/v2"
+)
+
+// SDPSemantics determines how the RTPTransceivers are mapped to the media
+// sections of the offers and answers, and how the media sections of the
+// remote descriptions are read
+type SDPSemantics int
+
+const (
+	// SDPSemanticsUnifiedPlan uses a media section per RTPTransceiver,
+	// each with its own mid
+	// https://tools.ietf.org/html/draft-ietf-rtcweb-jsep-26#section-5.2.1
+	SDPSemanticsUnifiedPlan SDPSemantics = iota + 1
+
+	// SDPSemanticsPlanB uses a single media section per kind, carrying
+	// the SSRCs of all the tracks of the kind
+	// https://tools.ietf.org/html/draft-uberti-rtcweb-plan-00
+	SDPSemanticsPlanB
+)
+
+// This is done this way because of a linter.
+const (
+	sdpSemanticsUnifiedPlanStr = "unified-plan"
+	sdpSemanticsPlanBStr       = "plan-b"
+)
+
+func newSDPSemantics(raw string) SDPSemantics {
+	switch raw {
+	case sdpSemanticsUnifiedPlanStr:
+		return SDPSemanticsUnifiedPlan
+	case sdpSemanticsPlanBStr:
+		return SDPSemanticsPlanB
+	default:
+		return SDPSemantics(Unknown)
+	}
+}
+
+func (s SDPSemantics) String() string {
+	switch s {
+	case SDPSemanticsUnifiedPlan:
+		return sdpSemanticsUnifiedPlanStr
+	case SDPSemanticsPlanB:
+		return sdpSemanticsPlanBStr
+	default:
+		return ErrUnknownType.Error()
+	}
+}
+
+// isSending returns whether the RTPTransceiver has a Track to send
+func (t *RTPTransceiver) isSending() bool {
+	sender := t.Sender()
+	if sender == nil || sender.Track == nil || t.Stopped() {
+		return false
+	}
+	direction := t.Direction()
+	return direction == RTPTransceiverDirectionSendrecv || direction == RTPTransceiverDirectionSendonly
+}
+
+// generateMid returns the mid of a new sending RTPTransceiver of the kind.
+// With plan-b all the tracks of a kind share the media section named after
+// the kind, with unified-plan the first one does and the next ones are
+// numbered, audio1, audio2...
+func (pc *PeerConnection) generateMid(kind RTPCodecType) string {
+	if pc.configuration.SDPSemantics == SDPSemanticsPlanB {
+		return kind.String()
+	}
+
+	used := map[string]bool{}
+	for _, t := range pc.rtpTransceivers {
+		used[t.Mid()] = true
+	}
+	mid := kind.String()
+	for i := 1; used[mid]; i++ {
+		mid = kind.String() + strconv.Itoa(i)
+	}
+	return mid
+}
+
+// offeredMids returns the mids of the media sections of the kind of the
+// RTPTransceivers. There is a single one with plan-b, one per RTPTransceiver
+// with unified-plan.
+func (pc *PeerConnection) offeredMids(kind RTPCodecType) []string {
+	if pc.configuration.SDPSemantics == SDPSemanticsPlanB {
+		return []string{kind.String()}
+	}
+
+	var mids []string
+	seen := map[string]bool{}
+	for _, t := range pc.transceivers() {
+		if t.kind() != kind || t.Mid() == "" || seen[t.Mid()] {
+			continue
+		}
+		seen[t.Mid()] = true
+		mids = append(mids, t.Mid())
+	}
+	return mids
+}
+
+// offeredSection is a media section of an offer, the kind is zero for the
+// application section of the data channels
+type offeredSection struct {
+	kind RTPCodecType
+	mid  string
+}
+
+// offeredSections returns the media sections of an offer in order. The
+// sections of the last local description keep their position, as m-lines
+// are never removed, and the ones of the new RTPTransceivers follow. A
+// first offer has the audio sections, the video ones and the data one,
+// with a section to receive each kind without RTPTransceiver.
+func (pc *PeerConnection) offeredSections(dataChannelsOnly bool) []offeredSection {
+	var sections []offeredSection
+	seen := map[string]bool{}
+	kinds := map[RTPCodecType]bool{}
+	if desc := pc.LocalDescription(); desc != nil && desc.parsed != nil {
+		for _, media := range desc.parsed.MediaDescriptions {
+			var kind RTPCodecType
+			switch media.MediaName.Media {
+			case RTPCodecTypeAudio.String():
+				kind = RTPCodecTypeAudio
+			case RTPCodecTypeVideo.String():
+				kind = RTPCodecTypeVideo
+			case "application":
+			default:
+				continue
+			}
+			mid, _ := media.Attribute(sdp.AttrKeyMID)
+			seen[mid], kinds[kind] = true, true
+			sections = append(sections, offeredSection{kind: kind, mid: mid})
+		}
+	}
+
+	if !dataChannelsOnly {
+		for _, kind := range []RTPCodecType{RTPCodecTypeAudio, RTPCodecTypeVideo} {
+			mids := pc.offeredMids(kind)
+			if len(mids) == 0 && !kinds[kind] {
+				mids = []string{kind.String()}
+			}
+			for _, mid := range mids {
+				if !seen[mid] {
+					seen[mid] = true
+					sections = append(sections, offeredSection{kind: kind, mid: mid})
+				}
+			}
+		}
+	}
+
+	if !kinds[0] {
+		sections = append(sections, offeredSection{mid: "data"})
+	}
+	return sections
+}
+
+// mediaSectionTransceivers returns the RTPTransceivers of the kind sent on
+// the media section with the mid
+func (pc *PeerConnection) mediaSectionTransceivers(kind RTPCodecType, mid string) []*RTPTransceiver {
+	var transceivers []*RTPTransceiver
+	for _, t := range pc.rtpTransceivers {
+		if t.kind() != kind {
+			continue
+		}
+		if pc.configuration.SDPSemantics != SDPSemanticsPlanB && t.Mid() != mid {
+			continue
+		}
+		transceivers = append(transceivers, t)
+	}
+	return transceivers
+}
+
+// matchRemoteMids gives the sending RTPTransceivers the mids of the media
+// sections of a unified-plan offer, in order. A media section of the offer
+// is matched with the first RTPTransceiver of its kind whose mid is not in
+// the offer.
+func (pc *PeerConnection) matchRemoteMids(desc *sdp.SessionDescription) {
+	if pc.configuration.SDPSemantics == SDPSemanticsPlanB {
+		return
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	offered := map[string]bool{}
+	for _, media := range desc.MediaDescriptions {
+		if mid, ok := media.Attribute(sdp.AttrKeyMID); ok {
+			offered[mid] = true
+		}
+	}
+	taken := map[string]bool{}
+	for _, t := range pc.rtpTransceivers {
+		taken[t.Mid()] = true
+	}
+
+	for _, media := range desc.MediaDescriptions {
+		mid, ok := media.Attribute(sdp.AttrKeyMID)
+		if !ok || taken[mid] {
+			continue
+		}
+		for _, t := range pc.rtpTransceivers {
+			if t.isSending() && t.kind().String() == media.MediaName.Media && !offered[t.Mid()] {
+				t.setMid(mid)
+				taken[mid] = true
+				break
+			}
+		}
+	}
+}
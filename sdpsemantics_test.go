@@ -0,0 +1,104 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSDPSemantics(t *testing.T) {
+	testCases := []struct {
+		semanticsString   string
+		expectedSemantics SDPSemantics
+	}{
+		{unknownStr, SDPSemantics(Unknown)},
+		{"unified-plan", SDPSemanticsUnifiedPlan},
+		{"plan-b", SDPSemanticsPlanB},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedSemantics,
+			newSDPSemantics(testCase.semanticsString),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
+
+func TestSDPSemantics_String(t *testing.T) {
+	testCases := []struct {
+		semantics      SDPSemantics
+		expectedString string
+	}{
+		{SDPSemantics(Unknown), unknownStr},
+		{SDPSemanticsUnifiedPlan, "unified-plan"},
+		{SDPSemanticsPlanB, "plan-b"},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedString,
+			testCase.semantics.String(),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
+
+func TestPeerConnection_SDPSemantics_Offer(t *testing.T) {
+	testCases := []struct {
+		semantics    SDPSemantics
+		expectedMids []string
+	}{
+		{SDPSemantics(Unknown), []string{"audio", "video", "video1", "data"}},
+		{SDPSemanticsUnifiedPlan, []string{"audio", "video", "video1", "data"}},
+		{SDPSemanticsPlanB, []string{"audio", "video", "data"}},
+	}
+
+	for _, testCase := range testCases {
+		api := NewAPI()
+		api.mediaEngine.RegisterDefaultCodecs()
+		pc, err := api.NewPeerConnection(Configuration{SDPSemantics: testCase.semantics})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var ssrcs []uint32
+		for _, id := range []string{"video1", "video2"} {
+			track, err := pc.NewTrack(DefaultPayloadTypeVP8, id, "pion")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = pc.AddTrack(track); err != nil {
+				t.Fatal(err)
+			}
+			ssrcs = append(ssrcs, track.SSRC)
+		}
+
+		offer, err := pc.CreateOffer(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var mids []string
+		var videoSSRCs [][]uint32
+		for _, media := range offer.parsed.MediaDescriptions {
+			m := newMediaDescription(media)
+			mids = append(mids, m.Mid)
+			if m.Kind == RTPCodecTypeVideo {
+				videoSSRCs = append(videoSSRCs, m.SSRCs)
+			}
+		}
+		assert.Equal(t, testCase.expectedMids, mids, "%s", testCase.semantics)
+
+		group, _ := offer.parsed.Attribute("group")
+		if testCase.semantics == SDPSemanticsPlanB {
+			assert.Equal(t, [][]uint32{ssrcs}, videoSSRCs)
+			assert.Equal(t, "BUNDLE audio video data", group)
+		} else {
+			assert.Equal(t, [][]uint32{{ssrcs[0]}, {ssrcs[1]}}, videoSSRCs)
+			assert.Equal(t, "BUNDLE audio video video1 data", group)
+		}
+
+		assert.NoError(t, pc.Close())
+	}
+}
@@ -0,0 +1,70 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pions/rtcp"
+)
+
+// senderReports builds the Sender Reports of a sent stream. The NTP time of
+// a report is mapped to the RTP clock through the media last written to the
+// Track: its RTP timestamp and the time it was captured.
+type senderReports struct {
+	lock sync.Mutex
+
+	hasReference bool
+	timestamp    uint32
+	captured     time.Time
+
+	// lastReport is the time the previous report was sent at, zero until
+	// the first one, bytesPrior the bytes sent until then
+	lastReport     time.Time
+	reportInterval time.Duration
+	bytesPrior     uint64
+}
+
+// setReference records the RTP timestamp of media captured at the given
+// time, the sample written or the packet of a raw RTP Track
+func (s *senderReports) setReference(timestamp uint32, captured time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.hasReference = true
+	s.timestamp = timestamp
+	s.captured = captured
+}
+
+// dueReport returns the Sender Report of the stream with the given counters
+// once the interval elapsed since the previous one, the first report is due
+// with the first packet. The interval is the given one when set, it is
+// otherwise derived from the bitrate sent as for the Receiver Reports.
+func (s *senderReports) dueReport(now time.Time, interval *time.Duration, clockRate uint32, packets, octets, bytes uint64) (*rtcp.SenderReport, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !s.hasReference || clockRate == 0 {
+		return nil, false
+	}
+	if s.lastReport.IsZero() {
+		s.reportInterval = initialRTCPReportInterval
+	} else {
+		if interval != nil {
+			s.reportInterval = *interval
+		}
+		elapsed := now.Sub(s.lastReport)
+		if elapsed < s.reportInterval {
+			return nil, false
+		}
+		s.reportInterval = reducedReportInterval(bytes-s.bytesPrior, elapsed)
+	}
+	s.lastReport = now
+	s.bytesPrior = bytes
+
+	// The counters wrap around in the 32-bit fields
+	return &rtcp.SenderReport{
+		NTPTime:     toNTPTime(now),
+		RTPTime:     s.timestamp + uint32(durationTicks(now.Sub(s.captured), clockRate)),
+		PacketCount: uint32(packets),
+		OctetCount:  uint32(octets),
+	}, true
+}
@@ -0,0 +1,60 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSenderReports(t *testing.T) {
+	var s senderReports
+	now := time.Unix(1000, 0)
+
+	// No report before the first packet
+	_, ok := s.dueReport(now, nil, 90000, 0, 0, 0)
+	assert.False(t, ok)
+
+	// The RTP time is extrapolated from the last media written
+	s.setReference(1000, now.Add(-100*time.Millisecond))
+	sr, ok := s.dueReport(now, nil, 90000, 1, 100, 112)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, toNTPTime(now), sr.NTPTime)
+	assert.Equal(t, uint32(1000+9000), sr.RTPTime)
+	assert.Equal(t, uint32(1), sr.PacketCount)
+	assert.Equal(t, uint32(100), sr.OctetCount)
+
+	// The second report comes after the initial interval, the next ones
+	// after the interval derived from the bitrate: 360 kbps here
+	_, ok = s.dueReport(now.Add(initialRTCPReportInterval/2), nil, 90000, 2, 200, 224)
+	assert.False(t, ok)
+	now = now.Add(initialRTCPReportInterval)
+	_, ok = s.dueReport(now, nil, 90000, 3, 45000, 45112)
+	assert.True(t, ok)
+	assert.Equal(t, time.Second, s.reportInterval)
+
+	now = now.Add(500 * time.Millisecond)
+	_, ok = s.dueReport(now, nil, 90000, 4, 45000, 45112)
+	assert.False(t, ok)
+
+	// The interval of the SettingEngine prevails
+	interval := 200 * time.Millisecond
+	_, ok = s.dueReport(now, &interval, 90000, 4, 45000, 45112)
+	assert.True(t, ok)
+
+	// The timestamps wrap around, nothing was sent during the last interval
+	s.setReference(0xFFFFFFFF, now)
+	sr, ok = s.dueReport(now.Add(maxRTCPReportInterval), nil, 48000, 5, 45000, 45112)
+	if assert.True(t, ok) {
+		assert.Equal(t, uint32(5*48000-1), sr.RTPTime)
+	}
+}
+
+func TestReducedReportInterval(t *testing.T) {
+	assert.Equal(t, maxRTCPReportInterval, reducedReportInterval(0, time.Second))
+	assert.Equal(t, maxRTCPReportInterval, reducedReportInterval(1000, time.Second))
+	assert.Equal(t, time.Second, reducedReportInterval(45000, time.Second))
+	assert.Equal(t, minRTCPReportInterval, reducedReportInterval(100000000, time.Second))
+}
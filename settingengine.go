@@ -1,9 +1,15 @@
 package webrtc
 
 import (
+	"crypto/rand"
+	"crypto/tls"
+	"io"
+	"net"
+	"strings"
 	"time"
 
 	"github.com/pions/webrtc/pkg/ice"
+	"github.com/pions/webrtc/pkg/logging"
 )
 
 // SettingEngine allows influencing behavior in ways that are not
@@ -20,9 +26,108 @@ type SettingEngine struct {
 	timeout struct {
 		ICEConnection *time.Duration
 		ICEKeepalive  *time.Duration
+		ICEFailed     *time.Duration
+		RTPRead       time.Duration
+		Receiving     time.Duration
 	}
+	disableSSRCLatching bool
+	disableRTCP         bool
+	passthrough         bool
+	deliverPadding      bool
+	nack                bool
+	retransmitDeadline  time.Duration
+	keyFrameInterval    time.Duration
+	nackReorder         struct {
+		Window   time.Duration
+		Adaptive bool
+	}
+	jitterBufferLatency time.Duration
+	statsLoopInterval   time.Duration
+	rtcpReport          struct {
+		SenderInterval   *time.Duration
+		ReceiverInterval *time.Duration
+	}
+	receiverReports      bool
+	insecureRandomSource io.Reader
+	dscp                 uint8
+	dtls                 struct {
+		EllipticCurves []tls.CurveID
+	}
+	networkTypes   []NetworkType
+	hostCandidates struct {
+		Max    int
+		Policy HostCandidatePolicy
+	}
+	multicastDNSMode     MulticastDNSMode
+	sdpFilter            func(sdpType SDPType, sdp string) string
+	simulcastReceiveRIDs []string
+	bandwidthEstimation  struct {
+		MinBitrate int
+		MaxBitrate int
+	}
+	receiveBuffer struct {
+		Audio receiveBufferSize
+		Video receiveBufferSize
+	}
+	receiveDropPolicy  ReceiveDropPolicy
+	maxIncomingStreams int
+	audioRedundancy    int
+	preferREMB         bool
+	sdes               bool
+	iceConn            net.PacketConn
+	iceUDPMux          *ICEUDPMux
+	interfaceFilter    func(string) bool
+	ipFilter           func(net.IP) bool
+	turnTLSConfig      *tls.Config
+	nat1To1IPs         struct {
+		IPs           []string
+		CandidateType ice.CandidateType
+	}
+	payloadTransform struct {
+		Send    PayloadTransform
+		Receive PayloadTransform
+	}
+	logger             logging.LoggerFactory
+	readStreamRetry    readStreamRetry
+	disconnectedBuffer time.Duration
+	iceRestartRetry    iceRestartRetry
+	trickle            bool
+
+	receiveBandwidthEstimation bool
+}
+
+// iceRestartRetry is the number of times a failed ICE connection is
+// restarted, and the backoff before the first restart
+type iceRestartRetry struct {
+	Attempts int
+	Backoff  time.Duration
+}
+
+// readStreamRetry is the number of times the opening of a ReadStream is
+// retried, and the backoff before the first retry
+type readStreamRetry struct {
+	Attempts int
+	Backoff  time.Duration
+}
+
+// receiveBufferSize is the number of packets buffered for a stream
+type receiveBufferSize struct {
+	SRTP  int
+	SRTCP int
 }
 
+// defaultReceiveBufferSize is the number of packets buffered for each
+// incoming stream unless configured otherwise
+const defaultReceiveBufferSize = 15
+
+// defaultReceivingTimeout is how long RTPReceiver.IsReceiving reports media
+// after the last packet unless configured otherwise
+const defaultReceivingTimeout = 2 * time.Second
+
+// minRTCPReportInterval is the shortest interval RTCP reports may be sent at,
+// it keeps a misconfigured PeerConnection from flooding the remote with reports.
+const minRTCPReportInterval = 100 * time.Millisecond
+
 // DetachDataChannels enables detaching data channels. When enabled
 // data channels have to be detached in the OnOpen callback using the
 // DataChannel.Detach method.
@@ -30,16 +135,320 @@ func (e *SettingEngine) DetachDataChannels() {
 	e.detach.DataChannels = true
 }
 
+// DisableSSRCLatching prevents RTPReceivers from binding to the first
+// undeclared SSRC when the remote SDP doesn't carry one for a media section.
+// Receive then fails with ErrSSRCLatchingDisabled.
+func (e *SettingEngine) DisableSSRCLatching() {
+	e.disableSSRCLatching = true
+}
+
+// SetInsecureRandomSource replaces crypto/rand as the source of randomness
+// used for SSRCs, ICE credentials and certificates. This is only meant to make
+// tests reproducible, a predictable reader makes the PeerConnection INSECURE
+// and must never be used in production.
+func (e *SettingEngine) SetInsecureRandomSource(r io.Reader) {
+	e.insecureRandomSource = r
+}
+
+// randomSource returns the reader all randomness should be drawn from
+func (e *SettingEngine) randomSource() io.Reader {
+	if e.insecureRandomSource != nil {
+		return e.insecureRandomSource
+	}
+	return rand.Reader
+}
+
+// DisableRTCP suppresses all RTCP for bandwidth-constrained links. No RTCP
+// feedback is negotiated, the RTPSenders and RTPReceivers don't read RTCP,
+// their Track.RTCPPackets channel is closed right away, and sending RTCP
+// fails with ErrRTCPDisabled.
+//
+// This is a deliberate degradation: the remote gets no reports and no
+// keyframe requests or NACKs. Browsers may lower their bitrate or time the
+// stream out, and lost video is only repaired by the next periodic keyframe.
+func (e *SettingEngine) DisableRTCP() {
+	e.disableRTCP = true
+}
+
+// EnableNACK turns on the retransmission of lost packets for the media whose
+// codecs negotiate generic NACK, video with the default codecs. RTPReceivers
+// NACK the packets missing from their stream, RTPSenders keep their last
+// packets and retransmit the NACKed ones, over RTX when the remote negotiated
+// it and on the media stream otherwise. RTPReceivers put the recovered packets
+// back in their Track, late. NACKStats of both counts the retransmissions.
+//
+// The option applies to every transceiver, the transceivers of remote tracks
+// only exist once their first packet arrived.
+func (e *SettingEngine) EnableNACK() {
+	e.nack = true
+}
+
+// SetRetransmitDeadline sets how long after sending a packet a RTPSender
+// still retransmits it when NACKed, see EnableNACK. A packet the receiver can
+// no longer play out only takes bandwidth from the fresh ones, a latency
+// sensitive stream such as screen sharing may use 200ms. The NACKed packets
+// past the deadline are counted in NACKStats.RetransmitsAbandoned. The default
+// of 0 retransmits every packet still in the history.
+func (e *SettingEngine) SetRetransmitDeadline(deadline time.Duration) {
+	e.retransmitDeadline = deadline
+}
+
+// SetKeyFrameRequestInterval sets the minimum interval between the keyframe
+// requests a RTPReceiver sends, for the forwarding units whose viewers all
+// ask the publisher for a keyframe as they join. The first request of a
+// RTPReceiver is sent immediately so the video of a new viewer appears fast,
+// the ones following it within the interval are suppressed as the keyframe
+// already requested serves them. KeyFrameRequestStats counts both.
+// ErrInvalidKeyFrameRequestInterval is returned for a negative interval, the
+// default of 0 sends every request.
+func (e *SettingEngine) SetKeyFrameRequestInterval(interval time.Duration) error {
+	if interval < 0 {
+		return ErrInvalidKeyFrameRequestInterval
+	}
+	e.keyFrameInterval = interval
+	return nil
+}
+
+// SetNACKReorderWindow sets how long a RTPReceiver waits for a missing
+// packet before NACKing it, see EnableNACK. Networks that reorder packets
+// deliver them late rather than lose them, NACKing them right away wastes
+// the bandwidth of their retransmission. An adaptive window widens to half
+// again the longest reordering observed, and narrows back when the
+// reordering stops. The NACKs sent for reordered packets are counted in
+// NACKStats.SpuriousNACKs. ErrInvalidReorderWindow is returned for a window
+// that is negative or longer than 100ms, the default of 0 NACKs immediately.
+func (e *SettingEngine) SetNACKReorderWindow(window time.Duration, adaptive bool) error {
+	if window < 0 || window > nackMaxReorderWindow {
+		return ErrInvalidReorderWindow
+	}
+
+	e.nackReorder.Window = window
+	e.nackReorder.Adaptive = adaptive
+	return nil
+}
+
+// EnableJitterBuffer makes the RTPReceivers put the packets of their Track
+// in sequence number order. The packets following a gap are held for up to
+// latency for the missing ones, NACKed when EnableNACK is set, to arrive;
+// the gap is then skipped. The packets arriving after their turn are dropped
+// and counted in RTPReceiverStats.PacketsLate. ErrInvalidJitterBufferLatency
+// is returned for a latency that isn't positive or is longer than 2s. The
+// option doesn't apply with EnablePassthrough.
+func (e *SettingEngine) EnableJitterBuffer(latency time.Duration) error {
+	if latency <= 0 || latency > jitterBufferMaxLatency {
+		return ErrInvalidJitterBufferLatency
+	}
+
+	e.jitterBufferLatency = latency
+	return nil
+}
+
+// EnablePassthrough makes the RTPReceivers hand their packets out undecoded
+// through RTPReceiver.ReadPassthroughRTP and ReadPassthroughRTCP, for the
+// forwarding units that only relay the decrypted packets. The packets are
+// decrypted straight into the buffers of the caller. Only the first packet is
+// parsed, to fire OnTrack, the Track.Packets and Track.RTCPPackets channels
+// are closed after it and the RTPReceivers send no NACKs.
+//
+// The packets of each stream must be read until io.EOF: the SRTP session
+// hands the packets over one at a time, a stream that is left unread stalls
+// every other stream of the PeerConnection.
+func (e *SettingEngine) EnablePassthrough() {
+	e.passthrough = true
+}
+
+// DeliverPaddingPackets puts the padding-only RTP packets in Track.Packets
+// like the media ones. They are filtered out by default: senders probe the
+// bandwidth with them, and they carry nothing a depacketizer could decode.
+// They are counted in RTPReceiverStats.PacketsPadding either way.
+func (e *SettingEngine) DeliverPaddingPackets() {
+	e.deliverPadding = true
+}
+
 // SetConnectionTimeout sets the amount of silence needed on a given candidate pair
-// before the ICE agent considers the pair timed out.
+// before the ICE agent considers the pair timed out. The DTLS handshake starts
+// on the first valid pair, before one is nominated, and moves on to the next
+// valid pair when that one times out.
 func (e *SettingEngine) SetConnectionTimeout(connectionTimeout, keepAlive time.Duration) {
 	e.timeout.ICEConnection = &connectionTimeout
 	e.timeout.ICEKeepalive = &keepAlive
 }
 
+// SetICEFailedTimeout sets how long the ICE connection stays disconnected,
+// once the selected pair timed out, before it fails. It defaults to 30
+// seconds, the connection never fails if it is 0. The candidates are still
+// checked once failed, the connection can come back or be restarted with
+// OfferOptions.ICERestart, or automatically with SetICERestartOnFailure.
+func (e *SettingEngine) SetICEFailedTimeout(failedTimeout time.Duration) {
+	e.timeout.ICEFailed = &failedTimeout
+}
+
+// SetReadTimeout sets how long RTPReceiver.ReadRTP waits for a packet before
+// failing with ErrReadTimeout, for servers that want a uniform policy for
+// dead streams. The deadline set with RTPReceiver.SetReadDeadline and the
+// one of the context given to ReadRTPContext take precedence. The default
+// of 0 blocks until a packet arrives.
+func (e *SettingEngine) SetReadTimeout(timeout time.Duration) {
+	e.timeout.RTPRead = timeout
+}
+
+// SetReceivingTimeout sets how long after the last packet RTPReceiver.IsReceiving
+// still reports media as flowing, 2 seconds by default. A longer window
+// tolerates the pauses of sources that stop sending on silence, such as
+// audio with DTX. A timeout of 0 restores the default.
+func (e *SettingEngine) SetReceivingTimeout(timeout time.Duration) {
+	e.timeout.Receiving = timeout
+}
+
+// receivingTimeout returns the staleness window of RTPReceiver.IsReceiving
+func (e *SettingEngine) receivingTimeout() time.Duration {
+	if e.timeout.Receiving <= 0 {
+		return defaultReceivingTimeout
+	}
+	return e.timeout.Receiving
+}
+
+// DSCP code points commonly used for media
+// https://tools.ietf.org/html/rfc8837#section-5
+const (
+	// DSCPExpeditedForwarding is recommended for interactive audio
+	DSCPExpeditedForwarding uint8 = 46
+
+	// DSCPAF41 is recommended for interactive video
+	DSCPAF41 uint8 = 34
+)
+
+// SetDSCP sets the DSCP code point of the packets sent by the PeerConnection,
+// using IP_TOS and IPV6_TCLASS on its UDP sockets. All kinds of media share
+// the bundled transport, so the code point is the same for audio, video and
+// data. Marking packets may require privileges and is not supported on
+// Windows, the packets are then sent unmarked and a warning is logged.
+func (e *SettingEngine) SetDSCP(dscp uint8) error {
+	if dscp > 63 {
+		return ErrInvalidDSCP
+	}
+
+	e.dscp = dscp
+	return nil
+}
+
+// SetDTLSCipherSuites validates the cipher suites of the DTLS handshake, with
+// the IDs of crypto/tls such as tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256.
+//
+// pions/dtls has no configuration for its cipher suites: it always offers all
+// the suites it implements and, as the server, selects the first one the
+// remote client offers. The suites can't be restricted, so
+// ErrDTLSCipherSuiteUnsupported is returned for any list that leaves out one
+// of them or names one it doesn't implement. nil and the full list are
+// accepted. The negotiated suite is reported in TransportStats.DTLSCipher.
+func (e *SettingEngine) SetDTLSCipherSuites(cipherSuites []uint16) error {
+	if cipherSuites == nil {
+		return nil
+	}
+
+	for _, id := range cipherSuites {
+		if !containsUint16(dtlsCipherSuites, id) {
+			return ErrDTLSCipherSuiteUnsupported
+		}
+	}
+	for _, id := range dtlsCipherSuites {
+		if !containsUint16(cipherSuites, id) {
+			return ErrDTLSCipherSuiteUnsupported
+		}
+	}
+	return nil
+}
+
+// SetDTLSEllipticCurves restricts the key exchange of the DTLS handshake to the
+// given curves, tls.X25519 and tls.CurveP256 are implemented.
+// ErrDTLSEllipticCurveUnsupported is returned for any other, nil permits both.
+// Unlike the cipher suites the restriction is enforced, though the curves
+// can't be left out of the offer of pions/dtls: the handshake fails when the
+// curve selected isn't permitted. X25519 is preferred as the client, a P-256
+// only policy requires remote servers that select it.
+func (e *SettingEngine) SetDTLSEllipticCurves(curves []tls.CurveID) error {
+	for _, id := range curves {
+		if !containsCurve(dtlsEllipticCurves, id) {
+			return ErrDTLSEllipticCurveUnsupported
+		}
+	}
+
+	e.dtls.EllipticCurves = curves
+	return nil
+}
+
+// SetNetworkTypes restricts the candidates gathered to the ones of the given
+// network types. Only NetworkTypeUDP6 is used on IPv6-only hosts and only
+// NetworkTypeUDP4 on IPv4-only ones, the sockets of the other family are
+// never bound. Both are gathered by default, which is dual-stack. Host and
+// server reflexive candidates honor the same network types.
+func (e *SettingEngine) SetNetworkTypes(candidateTypes []NetworkType) {
+	e.networkTypes = candidateTypes
+}
+
+// SetMaxHostCandidates limits the number of host candidates gathered, to keep
+// the SDP small on hosts with many interfaces such as VPNs and virtual
+// adapters. The addresses are kept in the order of the policy, the dropped
+// ones are logged. ErrInvalidMaxHostCandidates is returned for a negative
+// limit or an unknown policy. A limit of 0, the default, gathers every
+// interface whatever the policy.
+func (e *SettingEngine) SetMaxHostCandidates(max int, policy HostCandidatePolicy) error {
+	if max < 0 {
+		return ErrInvalidMaxHostCandidates
+	}
+	if _, err := policy.toICE(); max > 0 && err != nil {
+		return ErrInvalidMaxHostCandidates
+	}
+
+	e.hostCandidates.Max = max
+	e.hostCandidates.Policy = policy
+	return nil
+}
+
+// SetICEMulticastDNSMode sets how the ICE agents use multicast DNS. By
+// default they resolve the .local names Chrome hides the addresses of its
+// host candidates behind, so that the peers of a LAN connect directly.
+// MulticastDNSModeQueryAndGather hides the addresses of the local host
+// candidates the same way, for privacy, and answers the queries of the
+// remotes for their names. The mDNS group is joined on the default multicast
+// interface.
+func (e *SettingEngine) SetICEMulticastDNSMode(mode MulticastDNSMode) {
+	e.multicastDNSMode = mode
+}
+
+// SetSDPFilter sets a filter the SDP of the descriptions created by
+// CreateOffer and CreateAnswer goes through before being returned, to insert
+// bandwidth limits or custom attributes for example. The filter is an escape
+// hatch for advanced uses: it must return a valid description matching the
+// state of the PeerConnection, a malformed one breaks the negotiation.
+// CreateOffer and CreateAnswer fail if the filtered SDP can't be parsed.
+func (e *SettingEngine) SetSDPFilter(filter func(sdpType SDPType, sdp string) string) {
+	e.sdpFilter = filter
+}
+
+// SetSimulcastReceiveRIDs restricts the simulcast layers received to the ones
+// with the given RIDs, to save the bandwidth of the unused layers. An answer
+// to a simulcast offer requests the offered layers that are permitted with a
+// recv simulcast description, and a Track is received for each of them. The
+// RID header extension (RIDURI) has to be registered in the MediaEngine for
+// simulcast to be negotiated. Every offered layer is requested by default,
+// an empty list requests none so the remote sends a single stream.
+// ErrInvalidRID is returned if a RID is malformed.
+func (e *SettingEngine) SetSimulcastReceiveRIDs(rids []string) error {
+	for _, rid := range rids {
+		if !validRID(rid) {
+			return ErrInvalidRID
+		}
+	}
+
+	e.simulcastReceiveRIDs = append([]string{}, rids...)
+	return nil
+}
+
 // SetEphemeralUDPPortRange limits the pool of ephemeral ports that
 // ICE UDP connections can allocate from. This setting currently only
-// affects host candidates, not server reflexive candidates.
+// affects host candidates and the server reflexive candidates of
+// SetNAT1To1IPs, not the ones of the STUN servers.
 func (e *SettingEngine) SetEphemeralUDPPortRange(portMin, portMax uint16) error {
 	if portMax < portMin {
 		return ice.ErrPort
@@ -49,3 +458,398 @@ func (e *SettingEngine) SetEphemeralUDPPortRange(portMin, portMax uint16) error
 	e.ephemeralUDP.PortMax = portMax
 	return nil
 }
+
+// SetSenderReportInterval sets the interval at which RTCP Sender Reports are
+// sent for the outgoing streams, instead of the one derived from the bandwidth.
+// A RTPSender sends its first report with the first packet, each report maps
+// the time it is sent at to the RTP clock of the media last written to the
+// Track: the capture time of a sample, the time a raw RTP packet is written.
+func (e *SettingEngine) SetSenderReportInterval(interval time.Duration) error {
+	if interval < minRTCPReportInterval {
+		return ErrRTCPReportInterval
+	}
+
+	e.rtcpReport.SenderInterval = &interval
+	return nil
+}
+
+// EnableReceiverReports makes every RTPReceiver send RTCP Receiver Reports
+// about its stream, with the packets lost, the highest sequence number and the
+// interarrival jitter as counted by its RTP read loop, and the fields of the
+// last Sender Report the remote gets the round-trip time from. The interval is
+// derived from the received bitrate, as the reduced minimum of RFC3550: 360
+// seconds divided by the kbps received, at most 5 seconds, unless set with
+// SetReceiverReportInterval. The packets lost are NACKed with EnableNACK.
+func (e *SettingEngine) EnableReceiverReports() {
+	e.receiverReports = true
+}
+
+// EnableReceiveBandwidthEstimation makes every RTPReceiver estimate the
+// bitrate its Track can be received at and tell the remote, an SFU tells the
+// senders how much they can send this way. The estimate follows the variation
+// of the delay of the packets, measured with their abs-send-time extension
+// (AbsSendTimeURI) when negotiated and their RTP timestamp otherwise, and is
+// reported by RTPReceiver.OnBandwidthEstimate within the bounds of
+// SetBandwidthEstimationBounds.
+//
+// The remote is sent the feedback it negotiated: TransportLayerCC feedback
+// every 100ms about the packets of every stream of the transport when the
+// transport-wide sequence number extension (TransportCCURI) is negotiated,
+// a REMB message with the estimate every second or as soon as it decreases
+// otherwise, when the codec negotiated the goog-remb feedback. The feedback
+// is sent after a reception report of the stream, which the remote routes
+// it to the stream with.
+func (e *SettingEngine) EnableReceiveBandwidthEstimation() {
+	e.receiveBandwidthEstimation = true
+}
+
+// SetReceiverReportInterval sets the interval at which RTCP Receiver Reports
+// are sent for the incoming streams, instead of the one derived from the bandwidth.
+func (e *SettingEngine) SetReceiverReportInterval(interval time.Duration) error {
+	if interval < minRTCPReportInterval {
+		return ErrRTCPReportInterval
+	}
+
+	e.rtcpReport.ReceiverInterval = &interval
+	return nil
+}
+
+// SetStatsInterval sets the interval at which the handler of
+// PeerConnection.OnStats is fired, every second by default.
+func (e *SettingEngine) SetStatsInterval(interval time.Duration) error {
+	if interval <= 0 {
+		return ErrStatsInterval
+	}
+
+	e.statsLoopInterval = interval
+	return nil
+}
+
+// statsInterval returns the interval of the OnStats sampling loop
+func (e *SettingEngine) statsInterval() time.Duration {
+	if e.statsLoopInterval == 0 {
+		return defaultStatsInterval
+	}
+	return e.statsLoopInterval
+}
+
+// SetBandwidthEstimationBounds sets the minimum and maximum in bits per
+// second of the send bandwidth estimate reported by RTPSender.OnBandwidthEstimate,
+// and of the receive one reported by RTPReceiver.OnBandwidthEstimate.
+// The estimates default to the 30kbps to 2.5Mbps range.
+func (e *SettingEngine) SetBandwidthEstimationBounds(minBitrate, maxBitrate int) error {
+	if minBitrate <= 0 || maxBitrate < minBitrate {
+		return ErrBandwidthEstimationBounds
+	}
+
+	e.bandwidthEstimation.MinBitrate = minBitrate
+	e.bandwidthEstimation.MaxBitrate = maxBitrate
+	return nil
+}
+
+// SetLoggerFactory sets the factory of the loggers the PeerConnections of
+// the API log through, including their ICE agents, DTLS transports and send
+// and receive paths. The loggers are created with the pc and ice scopes, the
+// messages of a PeerConnection are prefixed with its LogTag to tell apart the
+// logs of a connection among many. An API per PeerConnection, each with its
+// own factory, traces a single connection without the output of the others.
+// It defaults to logging.DefaultLoggerFactory, whose levels are set with the
+// PIONS_LOG_* environment variables, a factory of logging.NopLogger discards
+// everything.
+func (e *SettingEngine) SetLoggerFactory(factory logging.LoggerFactory) {
+	e.logger = factory
+}
+
+// loggerFactory returns the factory of the loggers
+func (e *SettingEngine) loggerFactory() logging.LoggerFactory {
+	if e.logger == nil {
+		return logging.DefaultLoggerFactory{}
+	}
+	return e.logger
+}
+
+// PreferREMB makes the RTPSenders follow the REMB feedback of the remote
+// rather than the transport-wide congestion control feedback when both are
+// negotiated. Some clients send both, following both counts the congestion
+// twice, so only one is used: transport-cc unless this is set. The packets
+// still carry the transport-wide sequence numbers the remote expects.
+func (e *SettingEngine) PreferREMB() {
+	e.preferREMB = true
+}
+
+// bandwidthEstimationBounds returns the bounds of the send bandwidth estimate
+func (e *SettingEngine) bandwidthEstimationBounds() (int, int) {
+	if e.bandwidthEstimation.MaxBitrate == 0 {
+		return defaultMinBitrate, defaultMaxBitrate
+	}
+	return e.bandwidthEstimation.MinBitrate, e.bandwidthEstimation.MaxBitrate
+}
+
+// SetReceiveBufferSize sets the number of RTP and RTCP packets buffered for
+// each incoming stream of the given kind, waiting to be read from the Track.
+// Packets arriving while the buffer is full are dropped, as set with
+// SetReceiveDropPolicy. Both default to 15.
+//
+// Each buffered packet keeps its own copy of the decrypted packet, so the
+// buffer of a stream costs about one MTU per packet, 1.5KB for most
+// networks. A video stream at a few Mbps bursts 100 packets when a key
+// frame is sent, while an audio stream rarely needs more than the default.
+// The copies come from a pool the packets can be handed back to with
+// ReleaseRTPPacket once read.
+func (e *SettingEngine) SetReceiveBufferSize(kind RTPCodecType, srtpPackets, srtcpPackets int) error {
+	if srtpPackets <= 0 || srtcpPackets <= 0 {
+		return ErrReceiveBufferSize
+	}
+
+	size := receiveBufferSize{SRTP: srtpPackets, SRTCP: srtcpPackets}
+	switch kind {
+	case RTPCodecTypeAudio:
+		e.receiveBuffer.Audio = size
+	case RTPCodecTypeVideo:
+		e.receiveBuffer.Video = size
+	default:
+		return ErrUnknownType
+	}
+	return nil
+}
+
+// SetReceiveDropPolicy sets which packet is dropped when a packet of an
+// incoming stream arrives while its receive buffer is full, for the RTP and
+// RTCP packets of all the streams. ReceiveDropPolicyNewest, the default,
+// drops the packet arriving. ReceiveDropPolicyOldest drops the oldest packet
+// buffered instead, so a reader falling behind catches up on the latest
+// media rather than draining stale packets. The dropped RTP packets are
+// counted in the stats of the RTPReceiver either way. ErrUnknownType is
+// returned for an unknown policy.
+func (e *SettingEngine) SetReceiveDropPolicy(policy ReceiveDropPolicy) error {
+	switch policy {
+	case ReceiveDropPolicyNewest, ReceiveDropPolicyOldest:
+		e.receiveDropPolicy = policy
+		return nil
+	default:
+		return ErrUnknownType
+	}
+}
+
+// receiveBufferSize returns the number of RTP and RTCP packets buffered for
+// each incoming stream of the given kind
+func (e *SettingEngine) receiveBufferSize(kind RTPCodecType) (int, int) {
+	size := e.receiveBuffer.Video
+	if kind == RTPCodecTypeAudio {
+		size = e.receiveBuffer.Audio
+	}
+
+	if size.SRTP == 0 {
+		return defaultReceiveBufferSize, defaultReceiveBufferSize
+	}
+	return size.SRTP, size.SRTCP
+}
+
+// SetMaxIncomingStreams caps the SRTP streams the remote can send on a
+// DTLSTransport at once. Each new SSRC opens a read stream, and a goroutine
+// reads it until a RTPReceiver claims it, a malicious peer could otherwise
+// exhaust a publicly reachable server with random SSRCs. Once the cap is
+// reached the packets of new SSRCs are dropped and
+// DTLSTransport.OnLimitExceeded fires, the streams of the SSRCs the remote
+// declared in its description are always received. A place is freed when a
+// RTPReceiver is stopped, or when no packet of a SSRC no RTPReceiver reads
+// arrived for 10 seconds. The stream of that SSRC stays open until the
+// DTLSTransport is closed. The SSRCs of RTCP packets are encrypted, the SRTCP
+// streams aren't limited. ErrInvalidMaxIncomingStreams is returned for a
+// negative max, the default of 0 doesn't limit the streams.
+func (e *SettingEngine) SetMaxIncomingStreams(max int) error {
+	if max < 0 {
+		return ErrInvalidMaxIncomingStreams
+	}
+	e.maxIncomingStreams = max
+	return nil
+}
+
+// SetAudioRedundancy sets the number of previous packets each audio packet
+// carries when RED is negotiated for its codec, see NewRTPRedCodec. The
+// receiver recovers a lost packet from any of the distance packets that
+// follow it, at the cost of sending each payload distance more times. The
+// redundant encodings are bounded by the MTU and by the timestamp offset RED
+// can carry, 340ms at 48kHz. ErrInvalidAudioRedundancy is returned for a negative distance, the
+// default of 0 sends no redundancy.
+func (e *SettingEngine) SetAudioRedundancy(distance int) error {
+	if distance < 0 {
+		return ErrInvalidAudioRedundancy
+	}
+	e.audioRedundancy = distance
+	return nil
+}
+
+// EnableSDES allows the SRTP master keys to be exchanged in the a=crypto
+// lines of the descriptions (SDES, RFC 4568) rather than by the DTLS
+// handshake, for the telephony gateways that don't support DTLS-SRTP.
+// Offers carry a=crypto lines next to the fingerprint, and the answerer
+// chooses. A remote description without a fingerprint but with a=crypto
+// lines negotiates SDES: no DTLS handshake is run, the SRTP sessions use
+// the keys of the descriptions, and the data channels are not available.
+//
+// SDES is LESS SECURE than DTLS-SRTP: the keys are sent in the clear within
+// the signaling, anyone able to read or modify it can decrypt or hijack the
+// media. It must only be enabled when the signaling is secured end to end.
+func (e *SettingEngine) EnableSDES() {
+	e.sdes = true
+}
+
+// SetPayloadTransforms sets the PayloadTransforms applied to the payloads of
+// the packets of every Track, send before SRTP protects the outgoing packets
+// and receive once SRTP decrypted the incoming ones. Either can be nil. They
+// are set here rather than on each RTPSender and RTPReceiver so the first
+// packets received, delivered before OnTrack fires, are transformed as well,
+// the PayloadTransformInfo tells which one a packet belongs to.
+// The packets of a sample Track fit in a MTU of 1400 bytes before the
+// transform, it must not grow them past the MTU of the network. The Raw of
+// the received packets keeps the payload as received, the packets read with
+// ReadPassthroughRTP aren't transformed.
+func (e *SettingEngine) SetPayloadTransforms(send, receive PayloadTransform) {
+	e.payloadTransform.Send = send
+	e.payloadTransform.Receive = receive
+}
+
+// SetICEConn sets the conn ICE sends and receives on instead of binding
+// sockets, such as one of a userspace network stack or of a proxy. The
+// single host candidate is the UDP address of its LocalAddr, which must be
+// the address the remote can reach, the interfaces aren't gathered and the
+// STUN servers aren't queried. The conn is closed with the PeerConnection.
+//
+// Only the first ICEGatherer of the API gathers on the conn, the following
+// ones fail with ErrICEConnInUse: the API must be used for a single
+// PeerConnection, whose data channels aren't unbundled.
+func (e *SettingEngine) SetICEConn(conn net.PacketConn) {
+	e.iceConn = conn
+}
+
+// SetICEUDPMux sets the socket the ICE agents of the PeerConnections share
+// instead of binding sockets, so every PeerConnection of the API is reached
+// on the single address of the mux. That address is the single host
+// candidate of each agent, the interfaces aren't gathered and the STUN
+// servers aren't queried. The mux is left open once the PeerConnections
+// are closed, it can't be used with SetICEConn.
+func (e *SettingEngine) SetICEUDPMux(mux *ICEUDPMux) {
+	e.iceUDPMux = mux
+}
+
+// SetInterfaceFilter restricts the host candidates to the interfaces the
+// filter returns true for, given their name, such as to leave out the
+// bridges of the containers. Every interface is gathered by default.
+func (e *SettingEngine) SetInterfaceFilter(filter func(string) bool) {
+	e.interfaceFilter = filter
+}
+
+// SetIPFilter restricts the host candidates to the addresses the filter
+// returns true for. Every address of the interfaces is gathered by default.
+func (e *SettingEngine) SetIPFilter(filter func(net.IP) bool) {
+	e.ipFilter = filter
+}
+
+// SetTURNTLSConfig sets the config of the TLS connections the relay
+// candidates of the turns: servers are allocated over, such as RootCAs with
+// the certificate of a self-signed server, or InsecureSkipVerify for the
+// test servers. The certificates are verified against the system roots and
+// the host of the URL by default.
+func (e *SettingEngine) SetTURNTLSConfig(config *tls.Config) {
+	e.turnTLSConfig = config
+}
+
+// SetNAT1To1IPs sets the external IPs of a 1:1 NAT, such as the public IP
+// of a cloud instance or of the host of a container, advertised to the
+// remotes instead of the local ones. Each is either the sole external IP of
+// its family, or an "external/local" mapping of a local IP. With
+// ICECandidateTypeHost the IPs replace the addresses of the host
+// candidates, which can't then be hidden behind mDNS names. With
+// ICECandidateTypeSrflx a server reflexive candidate is gathered with the
+// sole external IP of each family instead, and the host candidates keep
+// their addresses.
+// ErrInvalidNAT1To1IPs is returned if an IP is malformed or if the
+// candidate type is another one, mixing a sole IP of a family with mapped
+// ones fails the gathering.
+func (e *SettingEngine) SetNAT1To1IPs(ips []string, candidateType ICECandidateType) error {
+	for _, ip := range ips {
+		parts := strings.Split(ip, "/")
+		if len(parts) > 2 {
+			return ErrInvalidNAT1To1IPs
+		}
+		for _, part := range parts {
+			if net.ParseIP(part) == nil {
+				return ErrInvalidNAT1To1IPs
+			}
+		}
+	}
+
+	switch candidateType {
+	case ICECandidateTypeHost:
+		e.nat1To1IPs.CandidateType = ice.CandidateTypeHost
+	case ICECandidateTypeSrflx:
+		e.nat1To1IPs.CandidateType = ice.CandidateTypeServerReflexive
+	default:
+		return ErrInvalidNAT1To1IPs
+	}
+	e.nat1To1IPs.IPs = ips
+	return nil
+}
+
+// SetReadStreamRetry sets how many times a RTPReceiver retries opening the
+// SRTP and SRTCP ReadStreams of its Track, so the failures of a session that
+// is briefly unavailable don't end the Track. The backoff is doubled after
+// each attempt. The failures that can't heal are never retried: those of a
+// zero SSRC, of a DTLS transport that is closed or failed, or of a stopped
+// RTPReceiver.
+// ErrInvalidReadStreamRetry is returned for negative attempts or backoff, the
+// default of 0 attempts fails on the first error.
+func (e *SettingEngine) SetReadStreamRetry(attempts int, backoff time.Duration) error {
+	if attempts < 0 || backoff < 0 {
+		return ErrInvalidReadStreamRetry
+	}
+	e.readStreamRetry = readStreamRetry{Attempts: attempts, Backoff: backoff}
+	return nil
+}
+
+// SetDisconnectedBuffer sets how long the RTPSenders hold back the packets
+// written while the ICE connection is disconnected. Sending them on the
+// dead path only queues them up to burst out once it recovers. The packets
+// written within the buffer duration are sent once the ICE connection
+// reconnects, the older ones are dropped, as are the oldest past 1024
+// packets. Only the dropped packets are counted in
+// RTPSenderStats.PacketsDroppedDisconnected.
+// ErrInvalidDisconnectedBuffer is returned for a negative buffer, the
+// default of 0 drops every packet written while disconnected.
+func (e *SettingEngine) SetDisconnectedBuffer(buffer time.Duration) error {
+	if buffer < 0 {
+		return ErrInvalidDisconnectedBuffer
+	}
+	e.disconnectedBuffer = buffer
+	return nil
+}
+
+// SetICERestartOnFailure makes the PeerConnection restart the ICE connection
+// once it failed, the remote having lost consent for the timeouts of
+// SetConnectionTimeout and SetICEFailedTimeout, up to attempts times. The
+// next offer created restarts ICE as with OfferOptions.ICERestart, and OnNegotiationNeeded is fired for the
+// application to exchange it. The restarts are immediate with a 0 backoff,
+// otherwise the first waits for backoff, which is doubled after each
+// attempt. The attempts are counted again once the connection is connected.
+// ErrInvalidICERestartRetry is returned for negative attempts or backoff,
+// the default of 0 attempts never restarts.
+func (e *SettingEngine) SetICERestartOnFailure(attempts int, backoff time.Duration) error {
+	if attempts < 0 || backoff < 0 {
+		return ErrInvalidICERestartRetry
+	}
+	e.iceRestartRetry = iceRestartRetry{Attempts: attempts, Backoff: backoff}
+	return nil
+}
+
+// EnableTrickle defers the gathering of the local candidates from the
+// creation of the PeerConnection to SetLocalDescription, so the offer and
+// the answer are created without waiting for the STUN servers. The
+// candidates are passed to OnICECandidate as they are gathered, to be
+// signaled to the remote with AddICECandidate. The descriptions carry the
+// candidates gathered when they were created, and the trickle ICE option.
+// The candidates of unbundled data channels are still gathered before
+// the offer is created.
+func (e *SettingEngine) EnableTrickle() {
+	e.trickle = true
+}
@@ -1,8 +1,17 @@
 package webrtc
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/pions/webrtc/pkg/ice"
 )
 
 func TestSetEphemeralUDPPortRange(t *testing.T) {
@@ -59,3 +68,562 @@ func TestDetachDataChannels(t *testing.T) {
 		t.Fatalf("Failed to enable detached data channels.")
 	}
 }
+
+func TestSetRTCPReportInterval(t *testing.T) {
+	s := SettingEngine{}
+
+	if s.rtcpReport.SenderInterval != nil ||
+		s.rtcpReport.ReceiverInterval != nil {
+		t.Fatalf("SettingEngine defaults aren't as expected.")
+	}
+
+	if err := s.SetSenderReportInterval(time.Millisecond); err != ErrRTCPReportInterval {
+		t.Fatalf("Setting engine should fail too short sender report interval.")
+	}
+	if err := s.SetReceiverReportInterval(time.Millisecond); err != ErrRTCPReportInterval {
+		t.Fatalf("Setting engine should fail too short receiver report interval.")
+	}
+
+	if err := s.SetSenderReportInterval(2 * time.Second); err != nil {
+		t.Fatalf("Setting engine failed valid sender report interval: %s", err)
+	}
+	if err := s.SetReceiverReportInterval(500 * time.Millisecond); err != nil {
+		t.Fatalf("Setting engine failed valid receiver report interval: %s", err)
+	}
+
+	if s.rtcpReport.SenderInterval == nil ||
+		*s.rtcpReport.SenderInterval != 2*time.Second ||
+		s.rtcpReport.ReceiverInterval == nil ||
+		*s.rtcpReport.ReceiverInterval != 500*time.Millisecond {
+		t.Fatalf("RTCP report intervals do not reflect requested values.")
+	}
+
+	if s.receiverReports {
+		t.Fatalf("Receiver Reports should be disabled by default.")
+	}
+	s.EnableReceiverReports()
+	if !s.receiverReports {
+		t.Fatalf("Failed to enable Receiver Reports.")
+	}
+}
+
+func TestSetInsecureRandomSource(t *testing.T) {
+	s := SettingEngine{}
+	if s.randomSource() != rand.Reader {
+		t.Fatalf("SettingEngine should default to crypto/rand")
+	}
+
+	api := NewAPI(WithSettingEngine(s))
+	api.mediaEngine.RegisterDefaultCodecs()
+	pc, err := api.NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatalf("Failed to create PeerConnection: %v", err)
+	}
+
+	api.settingEngine.SetInsecureRandomSource(bytes.NewReader([]byte{0x01, 0x02, 0x03, 0x04}))
+	track, err := pc.NewSampleTrack(DefaultPayloadTypeOpus, "audio", "pions")
+	if err != nil {
+		t.Fatalf("Failed to create track: %v", err)
+	}
+	if track.SSRC != 0x04030201 {
+		t.Fatalf("Track SSRC %#x wasn't drawn from the random source", track.SSRC)
+	}
+
+	if _, err = pc.NewSampleTrack(DefaultPayloadTypeOpus, "audio", "pions"); err == nil {
+		t.Fatalf("NewSampleTrack should fail when the random source is exhausted")
+	}
+
+	if err = pc.Close(); err != nil {
+		t.Fatalf("Failed to close PeerConnection: %v", err)
+	}
+
+	api.settingEngine.SetInsecureRandomSource(bytes.NewReader(nil))
+	if _, err = api.NewPeerConnection(Configuration{}); err == nil {
+		t.Fatalf("NewPeerConnection should fail when the random source is exhausted")
+	}
+}
+
+func TestSetBandwidthEstimationBounds(t *testing.T) {
+	s := SettingEngine{}
+
+	if min, max := s.bandwidthEstimationBounds(); min != defaultMinBitrate || max != defaultMaxBitrate {
+		t.Fatalf("SettingEngine defaults aren't as expected.")
+	}
+
+	if err := s.SetBandwidthEstimationBounds(0, 1000); err != ErrBandwidthEstimationBounds {
+		t.Fatalf("Setting engine should fail a non positive minimum bitrate.")
+	}
+	if err := s.SetBandwidthEstimationBounds(2000, 1000); err != ErrBandwidthEstimationBounds {
+		t.Fatalf("Setting engine should fail a minimum bitrate above the maximum.")
+	}
+
+	if err := s.SetBandwidthEstimationBounds(100000, 1000000); err != nil {
+		t.Fatalf("Setting engine failed valid bandwidth estimation bounds: %s", err)
+	}
+	if min, max := s.bandwidthEstimationBounds(); min != 100000 || max != 1000000 {
+		t.Fatalf("Bandwidth estimation bounds do not reflect requested values.")
+	}
+}
+
+func TestSetDSCP(t *testing.T) {
+	s := SettingEngine{}
+
+	if s.dscp != 0 {
+		t.Fatalf("SettingEngine defaults aren't as expected.")
+	}
+
+	if err := s.SetDSCP(64); err != ErrInvalidDSCP {
+		t.Fatalf("Setting engine should fail a DSCP above 63.")
+	}
+	if err := s.SetDSCP(DSCPExpeditedForwarding); err != nil {
+		t.Fatalf("Setting engine failed valid DSCP: %s", err)
+	}
+	if s.dscp != DSCPExpeditedForwarding {
+		t.Fatalf("DSCP does not reflect requested value.")
+	}
+}
+
+func TestSetTURNTLSConfig(t *testing.T) {
+	s := SettingEngine{}
+
+	if s.turnTLSConfig != nil {
+		t.Fatalf("SettingEngine defaults aren't as expected.")
+	}
+
+	config := &tls.Config{ServerName: "turn.example.com"}
+	s.SetTURNTLSConfig(config)
+	if s.turnTLSConfig != config {
+		t.Fatalf("TURN TLS config does not reflect requested value.")
+	}
+}
+
+func TestSetICERestartOnFailure(t *testing.T) {
+	s := SettingEngine{}
+
+	if s.iceRestartRetry.Attempts != 0 || s.iceRestartRetry.Backoff != 0 {
+		t.Fatalf("SettingEngine defaults aren't as expected.")
+	}
+
+	if err := s.SetICERestartOnFailure(-1, 0); err != ErrInvalidICERestartRetry {
+		t.Fatalf("Setting engine should fail negative attempts.")
+	}
+	if err := s.SetICERestartOnFailure(1, -time.Second); err != ErrInvalidICERestartRetry {
+		t.Fatalf("Setting engine should fail a negative backoff.")
+	}
+	if err := s.SetICERestartOnFailure(3, time.Second); err != nil {
+		t.Fatalf("Setting engine failed valid ICE restart retry: %s", err)
+	}
+	if s.iceRestartRetry.Attempts != 3 || s.iceRestartRetry.Backoff != time.Second {
+		t.Fatalf("ICE restart retry does not reflect requested value.")
+	}
+}
+
+func TestSetNACKReorderWindow(t *testing.T) {
+	s := SettingEngine{}
+
+	if s.nackReorder.Window != 0 || s.nackReorder.Adaptive {
+		t.Fatalf("SettingEngine defaults aren't as expected.")
+	}
+
+	if err := s.SetNACKReorderWindow(-time.Millisecond, false); err != ErrInvalidReorderWindow {
+		t.Fatalf("Setting engine should fail a negative reorder window.")
+	}
+	if err := s.SetNACKReorderWindow(time.Second, false); err != ErrInvalidReorderWindow {
+		t.Fatalf("Setting engine should fail a reorder window above the maximum.")
+	}
+	if err := s.SetNACKReorderWindow(20*time.Millisecond, true); err != nil {
+		t.Fatalf("Setting engine failed valid reorder window: %s", err)
+	}
+	if s.nackReorder.Window != 20*time.Millisecond || !s.nackReorder.Adaptive {
+		t.Fatalf("NACK reorder window does not reflect requested values.")
+	}
+}
+
+func TestEnableJitterBuffer(t *testing.T) {
+	s := SettingEngine{}
+
+	if s.jitterBufferLatency != 0 {
+		t.Fatalf("SettingEngine defaults aren't as expected.")
+	}
+
+	for _, latency := range []time.Duration{0, -time.Millisecond, 3 * time.Second} {
+		if err := s.EnableJitterBuffer(latency); err != ErrInvalidJitterBufferLatency {
+			t.Fatalf("Setting engine should fail the jitter buffer latency %v.", latency)
+		}
+	}
+	if err := s.EnableJitterBuffer(50 * time.Millisecond); err != nil {
+		t.Fatalf("Setting engine failed valid jitter buffer latency: %s", err)
+	}
+	if s.jitterBufferLatency != 50*time.Millisecond {
+		t.Fatalf("Jitter buffer latency does not reflect requested value.")
+	}
+}
+
+func TestSetICEMulticastDNSMode(t *testing.T) {
+	s := SettingEngine{}
+
+	if s.multicastDNSMode != MulticastDNSMode(Unknown) {
+		t.Fatalf("SettingEngine defaults aren't as expected.")
+	}
+
+	s.SetICEMulticastDNSMode(MulticastDNSModeQueryAndGather)
+	if s.multicastDNSMode != MulticastDNSModeQueryAndGather {
+		t.Fatalf("Multicast DNS mode does not reflect requested value.")
+	}
+}
+
+func TestSetSimulcastReceiveRIDs(t *testing.T) {
+	s := SettingEngine{}
+
+	if s.simulcastReceiveRIDs != nil {
+		t.Fatalf("SettingEngine defaults aren't as expected.")
+	}
+
+	for _, rid := range []string{"", "a b", "l;m", "abcdefghijklmnopq"} {
+		if err := s.SetSimulcastReceiveRIDs([]string{"l", rid}); err != ErrInvalidRID {
+			t.Fatalf("Setting engine should fail the RID %q.", rid)
+		}
+	}
+	if err := s.SetSimulcastReceiveRIDs([]string{"l", "m_1"}); err != nil {
+		t.Fatalf("Setting engine failed valid RIDs: %s", err)
+	}
+	if len(s.simulcastReceiveRIDs) != 2 {
+		t.Fatalf("Simulcast receive RIDs do not reflect requested values.")
+	}
+}
+
+func TestSetReceiveBufferSize(t *testing.T) {
+	s := SettingEngine{}
+
+	if rtpSize, rtcpSize := s.receiveBufferSize(RTPCodecTypeVideo); rtpSize != defaultReceiveBufferSize || rtcpSize != defaultReceiveBufferSize {
+		t.Fatalf("SettingEngine defaults aren't as expected.")
+	}
+
+	if err := s.SetReceiveBufferSize(RTPCodecTypeVideo, 0, 15); err != ErrReceiveBufferSize {
+		t.Fatalf("Setting engine should fail an empty receive buffer.")
+	}
+	if err := s.SetReceiveBufferSize(RTPCodecType(0), 15, 15); err != ErrUnknownType {
+		t.Fatalf("Setting engine should fail an unknown kind.")
+	}
+
+	if err := s.SetReceiveBufferSize(RTPCodecTypeVideo, 512, 32); err != nil {
+		t.Fatalf("Setting engine failed valid receive buffer size: %s", err)
+	}
+
+	if rtpSize, rtcpSize := s.receiveBufferSize(RTPCodecTypeVideo); rtpSize != 512 || rtcpSize != 32 {
+		t.Fatalf("Video receive buffer size does not reflect requested values.")
+	}
+	if rtpSize, rtcpSize := s.receiveBufferSize(RTPCodecTypeAudio); rtpSize != defaultReceiveBufferSize || rtcpSize != defaultReceiveBufferSize {
+		t.Fatalf("Audio receive buffer size should be left unchanged.")
+	}
+
+	api := NewAPI(WithSettingEngine(s))
+	receiver := api.NewRTPReceiver(RTPCodecTypeVideo, nil)
+	if cap(receiver.rtpOut) != 512 || cap(receiver.rtcpOut) != 32 {
+		t.Fatalf("RTPReceiver buffers do not reflect the SettingEngine.")
+	}
+}
+
+func TestSetReceiveDropPolicy(t *testing.T) {
+	s := SettingEngine{}
+
+	if s.receiveDropPolicy != ReceiveDropPolicy(Unknown) {
+		t.Fatalf("SettingEngine defaults aren't as expected.")
+	}
+
+	if err := s.SetReceiveDropPolicy(ReceiveDropPolicy(Unknown)); err != ErrUnknownType {
+		t.Fatalf("Setting engine should fail an unknown policy.")
+	}
+
+	if err := s.SetReceiveDropPolicy(ReceiveDropPolicyOldest); err != nil {
+		t.Fatalf("Setting engine failed valid drop policy: %s", err)
+	}
+	if s.receiveDropPolicy != ReceiveDropPolicyOldest {
+		t.Fatalf("Drop policy does not reflect requested value.")
+	}
+}
+
+func TestSetDTLSCipherSuites(t *testing.T) {
+	s := SettingEngine{}
+
+	if s.dtls.EllipticCurves != nil {
+		t.Fatalf("SettingEngine defaults aren't as expected.")
+	}
+
+	if err := s.SetDTLSCipherSuites([]uint16{tls.TLS_AES_128_GCM_SHA256}); err != ErrDTLSCipherSuiteUnsupported {
+		t.Fatalf("Setting engine should fail an unsupported cipher suite.")
+	}
+	if err := s.SetDTLSCipherSuites([]uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}); err != ErrDTLSCipherSuiteUnsupported {
+		t.Fatalf("Setting engine should fail cipher suites it can't restrict the handshake to.")
+	}
+	if err := s.SetDTLSCipherSuites(dtlsCipherSuites); err != nil {
+		t.Fatalf("Setting engine failed all the cipher suites: %s", err)
+	}
+	if err := s.SetDTLSCipherSuites(nil); err != nil {
+		t.Fatalf("Setting engine failed nil cipher suites: %s", err)
+	}
+
+	if err := s.SetDTLSEllipticCurves([]tls.CurveID{tls.CurveP384}); err != ErrDTLSEllipticCurveUnsupported {
+		t.Fatalf("Setting engine should fail an unsupported curve.")
+	}
+	if err := s.SetDTLSEllipticCurves([]tls.CurveID{tls.CurveP256}); err != nil {
+		t.Fatalf("Setting engine failed valid curves: %s", err)
+	}
+	if len(s.dtls.EllipticCurves) != 1 {
+		t.Fatalf("Curves do not reflect requested value.")
+	}
+}
+
+func TestSetMaxHostCandidates(t *testing.T) {
+	s := SettingEngine{}
+
+	if s.hostCandidates.Max != 0 {
+		t.Fatalf("SettingEngine defaults aren't as expected.")
+	}
+
+	if err := s.SetMaxHostCandidates(-1, HostCandidatePolicyInterfaceOrder); err != ErrInvalidMaxHostCandidates {
+		t.Fatalf("Setting engine should fail a negative limit.")
+	}
+	if err := s.SetMaxHostCandidates(2, HostCandidatePolicy(Unknown)); err != ErrInvalidMaxHostCandidates {
+		t.Fatalf("Setting engine should fail an unknown policy.")
+	}
+	if err := s.SetMaxHostCandidates(2, HostCandidatePolicyDefaultRouteFirst); err != nil {
+		t.Fatalf("Setting engine failed valid limit: %s", err)
+	}
+	if s.hostCandidates.Max != 2 || s.hostCandidates.Policy != HostCandidatePolicyDefaultRouteFirst {
+		t.Fatalf("Host candidate limit does not reflect requested value.")
+	}
+	if err := s.SetMaxHostCandidates(0, HostCandidatePolicy(Unknown)); err != nil {
+		t.Fatalf("Setting engine failed an unlimited number of host candidates: %s", err)
+	}
+	if s.hostCandidates.Max != 0 {
+		t.Fatalf("Host candidate limit does not reflect requested value.")
+	}
+}
+
+func TestSetMaxIncomingStreams(t *testing.T) {
+	s := SettingEngine{}
+
+	if s.maxIncomingStreams != 0 {
+		t.Fatalf("SettingEngine defaults aren't as expected.")
+	}
+
+	if err := s.SetMaxIncomingStreams(-1); err != ErrInvalidMaxIncomingStreams {
+		t.Fatalf("Setting engine should fail a negative maximum of incoming streams.")
+	}
+	if err := s.SetMaxIncomingStreams(16); err != nil {
+		t.Fatalf("Setting engine failed valid maximum of incoming streams: %s", err)
+	}
+	if s.maxIncomingStreams != 16 {
+		t.Fatalf("Maximum of incoming streams does not reflect requested value.")
+	}
+}
+
+func TestSetAudioRedundancy(t *testing.T) {
+	s := SettingEngine{}
+
+	if err := s.SetAudioRedundancy(-1); err != ErrInvalidAudioRedundancy {
+		t.Fatalf("Setting engine should fail a negative audio redundancy.")
+	}
+	if err := s.SetAudioRedundancy(2); err != nil {
+		t.Fatalf("Setting engine failed valid audio redundancy: %s", err)
+	}
+	if s.audioRedundancy != 2 {
+		t.Fatalf("Audio redundancy does not reflect requested value.")
+	}
+}
+
+func TestSetICEConn(t *testing.T) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IP{127, 0, 0, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := SettingEngine{}
+	s.SetICEConn(conn)
+	api := NewAPI(WithSettingEngine(s))
+	pc, err := api.NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The offer has the single candidate of the conn
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := strconv.Itoa(conn.LocalAddr().(*net.UDPAddr).Port)
+	for _, m := range offer.parsed.MediaDescriptions {
+		var candidates []string
+		for _, a := range m.Attributes {
+			if a.Key == "candidate" {
+				candidates = append(candidates, a.Value)
+			}
+		}
+		// RTCP is multiplexed, the RTP component has the only candidate
+		if len(candidates) != 1 || !strings.Contains(candidates[0], " 127.0.0.1 "+port+" typ host") {
+			t.Fatalf("Unexpected candidates %v", candidates)
+		}
+	}
+
+	// The conn serves a single PeerConnection
+	if _, err = api.NewPeerConnection(Configuration{}); err != ErrICEConnInUse {
+		t.Fatalf("A second PeerConnection should fail with ErrICEConnInUse, got %v", err)
+	}
+
+	if err = pc.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetICEUDPMux(t *testing.T) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IP{127, 0, 0, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := NewICEUDPMux(conn)
+
+	s := SettingEngine{}
+	s.SetICEUDPMux(mux)
+	api := NewAPI(WithSettingEngine(s))
+
+	// Every PeerConnection has the single candidate of the mux
+	port := strconv.Itoa(mux.LocalAddr().(*net.UDPAddr).Port)
+	var pcs []*PeerConnection
+	for i := 0; i < 2; i++ {
+		pc, err := api.NewPeerConnection(Configuration{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pcs = append(pcs, pc)
+
+		offer, err := pc.CreateOffer(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range offer.parsed.MediaDescriptions {
+			var candidates []string
+			for _, a := range m.Attributes {
+				if a.Key == "candidate" {
+					candidates = append(candidates, a.Value)
+				}
+			}
+			if len(candidates) != 1 || !strings.Contains(candidates[0], " 127.0.0.1 "+port+" typ host") {
+				t.Fatalf("Unexpected candidates %v", candidates)
+			}
+		}
+	}
+
+	for _, pc := range pcs {
+		if err = pc.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The mux outlives the PeerConnections
+	pc, err := api.NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = mux.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetNAT1To1IPs(t *testing.T) {
+	s := SettingEngine{}
+
+	if s.nat1To1IPs.IPs != nil || s.nat1To1IPs.CandidateType != 0 {
+		t.Fatalf("SettingEngine defaults aren't as expected.")
+	}
+
+	for _, ips := range [][]string{{"not an IP"}, {"203.0.113.1/"}, {"203.0.113.1/192.0.2.2/10.0.0.1"}} {
+		if err := s.SetNAT1To1IPs(ips, ICECandidateTypeHost); err != ErrInvalidNAT1To1IPs {
+			t.Fatalf("Setting engine should fail the NAT 1:1 IPs %v.", ips)
+		}
+	}
+	if err := s.SetNAT1To1IPs([]string{"203.0.113.1"}, ICECandidateTypeRelay); err != ErrInvalidNAT1To1IPs {
+		t.Fatalf("Setting engine should fail relay NAT 1:1 IPs.")
+	}
+
+	ips := []string{"203.0.113.1/192.0.2.2", "2001:db8::1"}
+	if err := s.SetNAT1To1IPs(ips, ICECandidateTypeSrflx); err != nil {
+		t.Fatalf("Setting engine failed valid NAT 1:1 IPs: %s", err)
+	}
+	if !reflect.DeepEqual(s.nat1To1IPs.IPs, ips) || s.nat1To1IPs.CandidateType != ice.CandidateTypeServerReflexive {
+		t.Fatalf("NAT 1:1 IPs do not reflect requested value.")
+	}
+}
+
+func TestSetInterfaceFilter(t *testing.T) {
+	s := SettingEngine{}
+
+	if s.interfaceFilter != nil || s.ipFilter != nil {
+		t.Fatalf("SettingEngine defaults aren't as expected.")
+	}
+
+	// Filtering out every interface leaves no host candidate
+	s.SetInterfaceFilter(func(string) bool { return false })
+	s.SetIPFilter(func(net.IP) bool { return true })
+	pc, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(offer.SDP, "a=candidate") {
+		t.Fatalf("Unexpected candidates in %s", offer.SDP)
+	}
+	if err = pc.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetKeyFrameRequestInterval(t *testing.T) {
+	s := SettingEngine{}
+
+	if err := s.SetKeyFrameRequestInterval(-time.Second); err != ErrInvalidKeyFrameRequestInterval {
+		t.Fatalf("Setting engine should fail a negative keyframe request interval.")
+	}
+	if err := s.SetKeyFrameRequestInterval(time.Second); err != nil {
+		t.Fatalf("Setting engine failed valid keyframe request interval: %s", err)
+	}
+	if s.keyFrameInterval != time.Second {
+		t.Fatalf("Keyframe request interval does not reflect requested value.")
+	}
+}
+
+func TestSetReadStreamRetry(t *testing.T) {
+	s := SettingEngine{}
+
+	if err := s.SetReadStreamRetry(-1, time.Millisecond); err != ErrInvalidReadStreamRetry {
+		t.Fatalf("Setting engine should fail negative read stream retries.")
+	}
+	if err := s.SetReadStreamRetry(1, -time.Millisecond); err != ErrInvalidReadStreamRetry {
+		t.Fatalf("Setting engine should fail a negative read stream backoff.")
+	}
+	if err := s.SetReadStreamRetry(3, time.Millisecond); err != nil {
+		t.Fatalf("Setting engine failed valid read stream retry: %s", err)
+	}
+	if s.readStreamRetry != (readStreamRetry{Attempts: 3, Backoff: time.Millisecond}) {
+		t.Fatalf("Read stream retry does not reflect requested value.")
+	}
+}
+
+func TestSetDisconnectedBuffer(t *testing.T) {
+	s := SettingEngine{}
+
+	if err := s.SetDisconnectedBuffer(-time.Second); err != ErrInvalidDisconnectedBuffer {
+		t.Fatalf("Setting engine should fail a negative disconnected buffer.")
+	}
+	if err := s.SetDisconnectedBuffer(time.Second); err != nil {
+		t.Fatalf("Setting engine failed valid disconnected buffer: %s", err)
+	}
+	if s.disconnectedBuffer != time.Second {
+		t.Fatalf("Disconnected buffer does not reflect requested value.")
+	}
+}
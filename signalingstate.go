@@ -104,6 +104,30 @@ func (t SignalingState) String() string {
 	}
 }
 
+// nextSignalingState returns the signaling state a description of the type
+// moves to from cur, an InvalidModificationError naming the transition if
+// it is set out of order, such as a local answer while stable
+func nextSignalingState(cur SignalingState, op stateChangeOp, sdpType SDPType) (SignalingState, error) {
+	next := SignalingState(Unknown)
+	switch sdpType {
+	case SDPTypeOffer:
+		next = SignalingStateHaveRemoteOffer
+		if op == stateChangeOpSetLocal {
+			next = SignalingStateHaveLocalOffer
+		}
+	case SDPTypePranswer:
+		next = SignalingStateHaveRemotePranswer
+		if op == stateChangeOpSetLocal {
+			next = SignalingStateHaveLocalPranswer
+		}
+	case SDPTypeAnswer:
+		next = SignalingStateStable
+	case SDPTypeRollback:
+		next = SignalingStateStable
+	}
+	return checkNextSignalingState(cur, next, op, sdpType)
+}
+
 func checkNextSignalingState(cur, next SignalingState, op stateChangeOp, sdpType SDPType) (SignalingState, error) {
 	// Special case for rollbacks
 	if sdpType == SDPTypeRollback && cur == SignalingStateStable {
@@ -111,6 +135,10 @@ func checkNextSignalingState(cur, next SignalingState, op stateChangeOp, sdpType
 			Err: errors.New("Can't rollback from stable state"),
 		}
 	}
+	if sdpType == SDPTypeRollback &&
+		(cur == SignalingStateHaveLocalPranswer || cur == SignalingStateHaveRemotePranswer) {
+		return cur, &rtcerr.InvalidStateError{Err: ErrRollbackPranswer}
+	}
 
 	// 4.3.1 valid state transitions
 	switch cur {
@@ -128,6 +156,10 @@ func checkNextSignalingState(cur, next SignalingState, op stateChangeOp, sdpType
 			}
 		}
 	case SignalingStateHaveLocalOffer:
+		// have-local-offer->SetLocal(rollback)->stable
+		if op == stateChangeOpSetLocal && sdpType == SDPTypeRollback && next == SignalingStateStable {
+			return next, nil
+		}
 		if op == stateChangeOpSetRemote {
 			switch sdpType {
 			// have-local-offer->SetRemote(answer)->stable
@@ -127,6 +127,38 @@ func TestSignalingState_Transitions(t *testing.T) {
 			SDPTypeAnswer,
 			nil,
 		},
+		{
+			"have-local-offer->SetLocal(rollback)->stable",
+			SignalingStateHaveLocalOffer,
+			SignalingStateStable,
+			stateChangeOpSetLocal,
+			SDPTypeRollback,
+			nil,
+		},
+		{
+			"(invalid) have-local-pranswer->SetLocal(rollback)->stable",
+			SignalingStateHaveLocalPranswer,
+			SignalingStateStable,
+			stateChangeOpSetLocal,
+			SDPTypeRollback,
+			&rtcerr.InvalidStateError{},
+		},
+		{
+			"(invalid) have-remote-offer->SetRemote(rollback)->stable",
+			SignalingStateHaveRemoteOffer,
+			SignalingStateStable,
+			stateChangeOpSetRemote,
+			SDPTypeRollback,
+			&rtcerr.InvalidModificationError{},
+		},
+		{
+			"(invalid) have-remote-offer->SetLocal(rollback)->stable",
+			SignalingStateHaveRemoteOffer,
+			SignalingStateStable,
+			stateChangeOpSetLocal,
+			SDPTypeRollback,
+			&rtcerr.InvalidModificationError{},
+		},
 		{
 			"(invalid) stable->SetRemote(pranswer)->have-remote-pranswer",
 			SignalingStateStable,
@@ -159,3 +191,50 @@ func TestSignalingState_Transitions(t *testing.T) {
 		}
 	}
 }
+
+func TestNextSignalingState(t *testing.T) {
+	testCases := []struct {
+		current  SignalingState
+		op       stateChangeOp
+		sdpType  SDPType
+		expected SignalingState
+	}{
+		{SignalingStateStable, stateChangeOpSetLocal, SDPTypeOffer, SignalingStateHaveLocalOffer},
+		{SignalingStateStable, stateChangeOpSetRemote, SDPTypeOffer, SignalingStateHaveRemoteOffer},
+		{SignalingStateHaveLocalOffer, stateChangeOpSetRemote, SDPTypePranswer, SignalingStateHaveRemotePranswer},
+		{SignalingStateHaveRemoteOffer, stateChangeOpSetLocal, SDPTypePranswer, SignalingStateHaveLocalPranswer},
+		{SignalingStateHaveRemotePranswer, stateChangeOpSetRemote, SDPTypeAnswer, SignalingStateStable},
+		{SignalingStateHaveLocalOffer, stateChangeOpSetLocal, SDPTypeRollback, SignalingStateStable},
+	}
+	for i, tc := range testCases {
+		next, err := nextSignalingState(tc.current, tc.op, tc.sdpType)
+		assert.NoError(t, err, "testCase: %d", i)
+		assert.Equal(t, tc.expected, next, "testCase: %d", i)
+	}
+
+	// A pending pranswer can't be rolled back
+	for _, current := range []SignalingState{SignalingStateHaveLocalPranswer, SignalingStateHaveRemotePranswer} {
+		_, err := nextSignalingState(current, stateChangeOpSetLocal, SDPTypeRollback)
+		assert.Equal(t, &rtcerr.InvalidStateError{Err: ErrRollbackPranswer}, err)
+	}
+
+	// The calls out of order name the transition they attempted
+	_, err := nextSignalingState(SignalingStateStable, stateChangeOpSetLocal, SDPTypeAnswer)
+	assert.IsType(t, &rtcerr.InvalidModificationError{}, err)
+	assert.EqualError(t, err, "InvalidModificationError: invalid proposed signaling state transition stable->SetLocal(answer)->stable")
+
+	for _, tc := range []struct {
+		current SignalingState
+		op      stateChangeOp
+		sdpType SDPType
+	}{
+		{SignalingStateHaveLocalOffer, stateChangeOpSetLocal, SDPTypeOffer},
+		{SignalingStateHaveLocalOffer, stateChangeOpSetRemote, SDPTypeOffer},
+		{SignalingStateHaveRemoteOffer, stateChangeOpSetRemote, SDPTypeAnswer},
+		{SignalingStateClosed, stateChangeOpSetRemote, SDPTypeOffer},
+		{SignalingStateStable, stateChangeOpSetRemote, SDPType(Unknown)},
+	} {
+		_, err := nextSignalingState(tc.current, tc.op, tc.sdpType)
+		assert.Error(t, err, "%s->%s(%s)", tc.current, tc.op, tc.sdpType)
+	}
+}
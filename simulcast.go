@@ -0,0 +1,208 @@
+package webrtc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pions/sdp/v2"
+)
+
+// Attributes negotiating simulcast
+// https://tools.ietf.org/html/draft-ietf-mmusic-sdp-simulcast-14
+const (
+	sdpAttributeRID       = "rid"
+	sdpAttributeSimulcast = "simulcast"
+
+	simulcastDirectionSend = "send"
+	simulcastDirectionRecv = "recv"
+
+	// maxRIDLength is the longest RID the RID header extension can carry
+	// https://tools.ietf.org/html/draft-ietf-avtext-rid-09#section-3
+	maxRIDLength = 16
+)
+
+// validRID checks the syntax of a RID
+// https://tools.ietf.org/html/draft-ietf-mmusic-rid-15#section-10
+func validRID(rid string) bool {
+	if rid == "" || len(rid) > maxRIDLength {
+		return false
+	}
+	for _, c := range rid {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// RIDRestrictions are the restrictions of a simulcast layer carried by its
+// a=rid line, zero when the line doesn't restrict it
+// https://tools.ietf.org/html/draft-ietf-mmusic-rid-15#section-5
+type RIDRestrictions struct {
+	MaxWidth  uint32  `json:"maxWidth,omitempty"`
+	MaxHeight uint32  `json:"maxHeight,omitempty"`
+	MaxFPS    float64 `json:"maxFps,omitempty"`
+}
+
+// parseRIDRestrictions parses the restrictions of an a=rid line, the unknown
+// and malformed ones are ignored
+func parseRIDRestrictions(value string) RIDRestrictions {
+	var r RIDRestrictions
+	for _, restriction := range strings.Split(value, ";") {
+		kv := strings.SplitN(restriction, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "max-width":
+			if v, err := strconv.ParseUint(kv[1], 10, 32); err == nil {
+				r.MaxWidth = uint32(v)
+			}
+		case "max-height":
+			if v, err := strconv.ParseUint(kv[1], 10, 32); err == nil {
+				r.MaxHeight = uint32(v)
+			}
+		case "max-fps":
+			if v, err := strconv.ParseFloat(kv[1], 64); err == nil && v > 0 {
+				r.MaxFPS = v
+			}
+		}
+	}
+	return r
+}
+
+// String returns the restrictions as written in an a=rid line
+func (r RIDRestrictions) String() string {
+	var restrictions []string
+	if r.MaxWidth != 0 {
+		restrictions = append(restrictions, fmt.Sprintf("max-width=%d", r.MaxWidth))
+	}
+	if r.MaxHeight != 0 {
+		restrictions = append(restrictions, fmt.Sprintf("max-height=%d", r.MaxHeight))
+	}
+	if r.MaxFPS != 0 {
+		restrictions = append(restrictions, "max-fps="+strconv.FormatFloat(r.MaxFPS, 'f', -1, 64))
+	}
+	return strings.Join(restrictions, ";")
+}
+
+// ridsFromMedia returns the RIDs of the a=rid attributes of a media section
+// with the given direction, along their restrictions
+func ridsFromMedia(media *sdp.MediaDescription, direction string) map[string]RIDRestrictions {
+	rids := map[string]RIDRestrictions{}
+	for _, a := range media.Attributes {
+		if a.Key != sdpAttributeRID {
+			continue
+		}
+		fields := strings.Fields(a.Value)
+		if len(fields) < 2 || fields[1] != direction || !validRID(fields[0]) {
+			continue
+		}
+		var restrictions RIDRestrictions
+		if len(fields) >= 3 {
+			restrictions = parseRIDRestrictions(fields[2])
+		}
+		rids[fields[0]] = restrictions
+	}
+	return rids
+}
+
+// simulcastRIDsFromMedia returns the RIDs of the a=simulcast attribute of a
+// media section for the given direction, in order. The alternatives of a
+// layer are all listed and the paused ones are included.
+func simulcastRIDsFromMedia(media *sdp.MediaDescription, direction string) []string {
+	value, ok := media.Attribute(sdpAttributeSimulcast)
+	if !ok {
+		return nil
+	}
+
+	declared := ridsFromMedia(media, direction)
+	fields := strings.Fields(value)
+	var rids []string
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i] != direction {
+			continue
+		}
+		for _, layer := range strings.Split(fields[i+1], ";") {
+			for _, rid := range strings.Split(layer, ",") {
+				rid = strings.TrimPrefix(rid, "~")
+				if _, ok := declared[rid]; ok {
+					rids = append(rids, rid)
+				}
+			}
+		}
+	}
+	return rids
+}
+
+// receivedRIDs returns the layers of remote simulcast media section that are
+// received: the ones permitted by the SettingEngine, in the order of the
+// remote. Nothing is received without the RID header extension, the layers
+// can't be told apart.
+func (pc *PeerConnection) receivedRIDs(remoteMedia *sdp.MediaDescription, ridExtensionNegotiated bool) []string {
+	if !ridExtensionNegotiated || !remoteSends(remoteMedia) {
+		return nil
+	}
+
+	var rids []string
+	for _, rid := range simulcastRIDsFromMedia(remoteMedia, simulcastDirectionSend) {
+		if permitted := pc.api.settingEngine.simulcastReceiveRIDs; permitted == nil || containsString(permitted, rid) {
+			rids = append(rids, rid)
+		}
+	}
+	return rids
+}
+
+// receivedLayers returns the received layers of a remote simulcast media
+// section, with the restrictions the remote announced for them
+func (pc *PeerConnection) receivedLayers(remoteMedia *sdp.MediaDescription, ridExtensionNegotiated bool) map[string]RIDRestrictions {
+	rids := pc.receivedRIDs(remoteMedia, ridExtensionNegotiated)
+	if len(rids) == 0 {
+		return nil
+	}
+
+	restrictions := ridsFromMedia(remoteMedia, simulcastDirectionSend)
+	layers := make(map[string]RIDRestrictions, len(rids))
+	for _, rid := range rids {
+		layers[rid] = restrictions[rid]
+	}
+	return layers
+}
+
+// ridLine returns the value of the a=rid line of a layer
+func ridLine(rid, direction string, restrictions RIDRestrictions) string {
+	line := rid + " " + direction
+	if r := restrictions.String(); r != "" {
+		line += " " + r
+	}
+	return line
+}
+
+// addSimulcast describes the layer a media section sends, with its
+// restrictions, and requests the layers it receives
+func addSimulcast(media *sdp.MediaDescription, sendRID string, sendRestrictions RIDRestrictions, recvRIDs []string) {
+	var simulcast []string
+	if sendRID != "" {
+		media.WithValueAttribute(sdpAttributeRID, ridLine(sendRID, simulcastDirectionSend, sendRestrictions))
+		simulcast = append(simulcast, simulcastDirectionSend+" "+sendRID)
+	}
+	for _, rid := range recvRIDs {
+		media.WithValueAttribute(sdpAttributeRID, ridLine(rid, simulcastDirectionRecv, RIDRestrictions{}))
+	}
+	if len(recvRIDs) != 0 {
+		simulcast = append(simulcast, simulcastDirectionRecv+" "+strings.Join(recvRIDs, ";"))
+	}
+	media.WithValueAttribute(sdpAttributeSimulcast, strings.Join(simulcast, " "))
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package webrtc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pions/sdp/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimulcastRIDsFromMedia(t *testing.T) {
+	media := &sdp.MediaDescription{}
+	media.WithValueAttribute(sdpAttributeRID, "h send max-width=1280")
+	media.WithValueAttribute(sdpAttributeRID, "m send")
+	media.WithValueAttribute(sdpAttributeRID, "l send")
+	media.WithValueAttribute(sdpAttributeRID, "r recv")
+	media.WithValueAttribute(sdpAttributeSimulcast, "send h;~m,l;x recv r")
+
+	// The paused layers and alternatives are listed, the undeclared RIDs aren't
+	assert.Equal(t, []string{"h", "m", "l"}, simulcastRIDsFromMedia(media, simulcastDirectionSend))
+	assert.Equal(t, []string{"r"}, simulcastRIDsFromMedia(media, simulcastDirectionRecv))
+	assert.Nil(t, simulcastRIDsFromMedia(&sdp.MediaDescription{}, simulcastDirectionSend))
+}
+
+func TestRIDRestrictions(t *testing.T) {
+	restrictions := parseRIDRestrictions("pt=96,97;max-width=1280;max-height=720;max-fps=29.97;max-br=x")
+	assert.Equal(t, RIDRestrictions{MaxWidth: 1280, MaxHeight: 720, MaxFPS: 29.97}, restrictions)
+	assert.Equal(t, "max-width=1280;max-height=720;max-fps=29.97", restrictions.String())
+
+	// The malformed restrictions are ignored
+	assert.Equal(t, RIDRestrictions{MaxHeight: 360}, parseRIDRestrictions("max-width=-1;max-height=360;max-fps=0;max-width"))
+	assert.Equal(t, "", RIDRestrictions{}.String())
+
+	media := &sdp.MediaDescription{}
+	media.WithValueAttribute(sdpAttributeRID, "h send max-width=1280;max-fps=30")
+	media.WithValueAttribute(sdpAttributeRID, "l send")
+	assert.Equal(t, map[string]RIDRestrictions{
+		"h": {MaxWidth: 1280, MaxFPS: 30},
+		"l": {},
+	}, ridsFromMedia(media, simulcastDirectionSend))
+}
+
+func TestRTPSender_SetRID(t *testing.T) {
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	if _, err := api.mediaEngine.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: RIDURI}, RTPCodecTypeVideo); err != nil {
+		t.Fatal(err)
+	}
+	pc, err := api.NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	track, err := pc.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := pc.AddTrack(track)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, ErrInvalidRID, sender.SetRID("h!", RIDRestrictions{}))
+	assert.NoError(t, sender.SetRID("h", RIDRestrictions{MaxWidth: 1280, MaxHeight: 720, MaxFPS: 30}))
+
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, offer.SDP, "a=rid:h send max-width=1280;max-height=720;max-fps=30\r\n")
+	assert.Contains(t, offer.SDP, "a=simulcast:send h\r\n")
+
+	// No layer is announced once unset
+	assert.NoError(t, sender.SetRID("", RIDRestrictions{}))
+	offer, err = pc.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotContains(t, offer.SDP, "a=rid:")
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestPeerConnection_SimulcastReceiveSubset(t *testing.T) {
+	newAPI := func(s SettingEngine) *API {
+		api := NewAPI(WithSettingEngine(s))
+		api.mediaEngine.RegisterDefaultCodecs()
+		for _, uri := range []string{MIDURI, RIDURI} {
+			if _, err := api.mediaEngine.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: uri}, RTPCodecTypeVideo); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return api
+	}
+
+	// The filter adds the simulcast description to the video media section
+	offerSettings := SettingEngine{}
+	offerSettings.SetSDPFilter(func(sdpType SDPType, sdp string) string {
+		return strings.Replace(sdp, "a=mid:video\r\n",
+			"a=mid:video\r\na=rid:h send\r\na=rid:m send\r\na=rid:l send\r\na=simulcast:send h;m;l\r\n", 1)
+	})
+	pcOffer, err := newAPI(offerSettings).NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(track); err != nil {
+		t.Fatal(err)
+	}
+
+	offer, err := pcOffer.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		rids   []string
+		answer []string
+	}{
+		{nil, []string{"a=rid:h recv", "a=rid:m recv", "a=rid:l recv", "a=simulcast:recv h;m;l"}},
+		{[]string{"l", "m"}, []string{"a=rid:m recv", "a=rid:l recv", "a=simulcast:recv m;l"}},
+		{[]string{}, nil},
+	} {
+		answerSettings := SettingEngine{}
+		if test.rids != nil {
+			assert.NoError(t, answerSettings.SetSimulcastReceiveRIDs(test.rids))
+		}
+		pcAnswer, err := newAPI(answerSettings).NewPeerConnection(Configuration{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err = pcAnswer.SetRemoteDescription(offer); err != nil {
+			t.Fatal(err)
+		}
+		answer, err := pcAnswer.CreateAnswer(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, line := range test.answer {
+			assert.Contains(t, answer.SDP, line+"\r\n")
+		}
+		if test.answer == nil {
+			assert.False(t, strings.Contains(answer.SDP, "a=simulcast"), "no layer should be requested")
+		}
+		if test.rids != nil {
+			assert.NotContains(t, answer.SDP, "a=rid:h recv")
+		}
+
+		assert.NoError(t, pcAnswer.Close())
+	}
+
+	assert.NoError(t, pcOffer.Close())
+}
@@ -0,0 +1,217 @@
+package webrtc
+
+import (
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/sha1" // #nosec
+	"encoding/binary"
+	"hash"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/pions/rtp"
+	"github.com/pkg/errors"
+)
+
+// The AES_CM_128_HMAC_SHA1_80 parameters of
+// https://tools.ietf.org/html/rfc3711#section-8.2, the only protection profile
+// pions/srtp supports
+const (
+	srtpMasterKeyLength  = 16
+	srtpMasterSaltLength = 14
+	srtpAuthKeyLength    = 20
+	srtpAuthTagLength    = 10
+	srtcpIndexLength     = 4
+
+	// The key derivation labels of the authentication keys
+	srtpAuthKeyLabel  = 0x01
+	srtcpAuthKeyLabel = 0x04
+
+	// srtpMaxDisorder is the distance to the wrap around of the sequence
+	// numbers within which the rollover counter is guessed, as pions/srtp
+	// does
+	srtpMaxDisorder = 100
+)
+
+// srtpDeriveAuthKey derives the session authentication key of the label from
+// the master key and salt with the AES-CM key derivation of
+// https://tools.ietf.org/html/rfc3711#section-4.3, with a key derivation
+// rate of 0
+func srtpDeriveAuthKey(masterKey, masterSalt []byte, label byte) ([]byte, error) {
+	if len(masterKey) != srtpMasterKeyLength || len(masterSalt) != srtpMasterSaltLength {
+		return nil, errors.New("invalid SRTP master key or salt length")
+	}
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// x = label || index DIV kdr, XORed with the master salt, is the IV of
+	// the key stream
+	iv := make([]byte, aes.BlockSize)
+	copy(iv, masterSalt)
+	iv[7] ^= label
+
+	key := make([]byte, 0, 2*aes.BlockSize)
+	for counter := uint16(0); len(key) < srtpAuthKeyLength; counter++ {
+		binary.BigEndian.PutUint16(iv[14:], counter)
+		out := make([]byte, aes.BlockSize)
+		block.Encrypt(out, iv)
+		key = append(key, out...)
+	}
+	return key[:srtpAuthKeyLength], nil
+}
+
+// srtpRolloverState is the rollover counter of the packets of a SSRC
+type srtpRolloverState struct {
+	processed          bool
+	rolloverCounter    uint32
+	lastSequenceNumber uint16
+}
+
+// next returns the state after a packet with the sequence number, following
+// the estimation of pions/srtp so both agree on the counter
+func (s srtpRolloverState) next(sequenceNumber uint16) srtpRolloverState {
+	switch {
+	case !s.processed:
+		s.processed = true
+	case sequenceNumber == 0:
+		if s.lastSequenceNumber > srtpMaxDisorder {
+			s.rolloverCounter++
+		}
+	case s.lastSequenceNumber < srtpMaxDisorder && sequenceNumber > 0xFFFF-srtpMaxDisorder:
+		s.rolloverCounter--
+	case sequenceNumber < srtpMaxDisorder && s.lastSequenceNumber > 0xFFFF-srtpMaxDisorder:
+		s.rolloverCounter++
+	}
+	s.lastSequenceNumber = sequenceNumber
+	return s
+}
+
+// srtpAuthConn sits between a pions/srtp session and the mux endpoint it
+// reads the packets of the remote from. It verifies their authentication tag
+// and skips those which fail, so a forged or corrupted packet is counted
+// instead of reaching the session.
+type srtpAuthConn struct {
+	// failures is first to be aligned for the atomic operations
+	failures uint64
+
+	net.Conn
+
+	rtcp bool
+
+	// mac, rolloverCounter and tag are reused for every packet, only the
+	// read goroutine of the session accesses them as well as
+	// rolloverStates, the counters of the authenticated SRTP packets
+	mac             hash.Hash
+	rolloverCounter [4]byte
+	tag             []byte
+	rolloverStates  map[uint32]srtpRolloverState
+
+	// receiveTimes records the receive time of the authenticated SRTP
+	// packets, nil for SRTCP
+	receiveTimes *receiveTimes
+}
+
+// timestampReader is implemented by the conns reading the packets with their
+// receive time, such as the mux endpoints of the ICE transport
+type timestampReader interface {
+	ReadWithTimestamp(p []byte) (int, time.Time, error)
+}
+
+func newSRTPAuthConn(conn net.Conn, remoteMasterKey, remoteMasterSalt []byte, rtcp bool) (*srtpAuthConn, error) {
+	label := byte(srtpAuthKeyLabel)
+	if rtcp {
+		label = srtcpAuthKeyLabel
+	}
+	authKey, err := srtpDeriveAuthKey(remoteMasterKey, remoteMasterSalt, label)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha1.New, authKey)
+	return &srtpAuthConn{
+		Conn:           conn,
+		rtcp:           rtcp,
+		mac:            mac,
+		tag:            make([]byte, 0, mac.Size()),
+		rolloverStates: map[uint32]srtpRolloverState{},
+	}, nil
+}
+
+func (c *srtpAuthConn) Read(p []byte) (int, error) {
+	for {
+		n, received, err := c.read(p)
+		if err != nil {
+			return n, err
+		}
+
+		if c.authenticate(p[:n]) {
+			if !c.rtcp {
+				// The header was checked by the authentication
+				c.receiveTimes.record(binary.BigEndian.Uint32(p[8:12]), binary.BigEndian.Uint16(p[2:4]), received)
+			}
+			return n, nil
+		}
+		atomic.AddUint64(&c.failures, 1)
+	}
+}
+
+// read reads the next packet of the remote with its receive time, the time
+// it is read unless the conn knows better
+func (c *srtpAuthConn) read(p []byte) (int, time.Time, error) {
+	if r, ok := c.Conn.(timestampReader); ok {
+		return r.ReadWithTimestamp(p)
+	}
+	n, err := c.Conn.Read(p)
+	return n, time.Now(), err
+}
+
+// authenticate returns whether the authentication tag of the packet is valid
+func (c *srtpAuthConn) authenticate(packet []byte) bool {
+	if c.rtcp {
+		// The SRTCP index is part of the authenticated portion
+		if len(packet) < rtcpHeaderLength+4+srtcpIndexLength+srtpAuthTagLength {
+			return false
+		}
+		tagOffset := len(packet) - srtpAuthTagLength
+		return c.verify(packet[:tagOffset], nil, packet[tagOffset:])
+	}
+
+	var header rtp.Header
+	if len(packet) < srtpAuthTagLength || header.Unmarshal(packet) != nil || header.PayloadOffset > len(packet)-srtpAuthTagLength {
+		return false
+	}
+
+	// The rollover counter is appended to the authenticated portion, it is
+	// only moved forward by the authenticated packets
+	state := c.rolloverStates[header.SSRC].next(header.SequenceNumber)
+	tagOffset := len(packet) - srtpAuthTagLength
+	binary.BigEndian.PutUint32(c.rolloverCounter[:], state.rolloverCounter)
+	if !c.verify(packet[:tagOffset], c.rolloverCounter[:], packet[tagOffset:]) {
+		return false
+	}
+	c.rolloverStates[header.SSRC] = state
+	return true
+}
+
+// verify returns whether tag authenticates the portion of the packet
+// followed by the rollover counter, nil for SRTCP. The HMAC is reset rather
+// than created for each packet, the path doesn't allocate.
+func (c *srtpAuthConn) verify(authenticated, rolloverCounter, tag []byte) bool {
+	c.mac.Reset()
+	if _, err := c.mac.Write(authenticated); err != nil {
+		return false
+	}
+	if _, err := c.mac.Write(rolloverCounter); err != nil {
+		return false
+	}
+	c.tag = c.mac.Sum(c.tag[:0])
+	return hmac.Equal(c.tag[:srtpAuthTagLength], tag)
+}
+
+// authFailures returns the number of packets skipped since their
+// authentication failed
+func (c *srtpAuthConn) authFailures() uint64 {
+	return atomic.LoadUint64(&c.failures)
+}
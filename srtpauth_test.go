@@ -0,0 +1,174 @@
+package webrtc
+
+import (
+	"net"
+	"testing"
+
+	"github.com/pions/rtcp"
+	"github.com/pions/rtp"
+	"github.com/pions/srtp"
+	"github.com/stretchr/testify/assert"
+)
+
+// readAuthenticated writes the packets to an srtpAuthConn and returns those
+// it delivered
+func readAuthenticated(t *testing.T, c *srtpAuthConn, remote net.Conn, packets [][]byte) [][]byte {
+	go func() {
+		for _, p := range packets {
+			if _, err := remote.Write(p); err != nil {
+				t.Error(err)
+			}
+		}
+		if err := remote.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	var delivered [][]byte
+	for {
+		b := make([]byte, 1500)
+		n, err := c.Read(b)
+		if err != nil {
+			return delivered
+		}
+		delivered = append(delivered, b[:n])
+	}
+}
+
+func TestSRTPAuthConn_RTP(t *testing.T) {
+	key, salt := make([]byte, srtpMasterKeyLength), make([]byte, srtpMasterSaltLength)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	context, err := srtp.CreateContext(key, salt, srtp.ProtectionProfileAes128CmHmacSha1_80)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The sequence numbers wrap around, the rollover counter is followed
+	var packets [][]byte
+	for _, sequenceNumber := range []uint16{65534, 65535, 0, 1} {
+		raw, err := (&rtp.Packet{
+			Header:  rtp.Header{Version: 2, SSRC: 5000, SequenceNumber: sequenceNumber},
+			Payload: []byte{0x01, 0x02, 0x03},
+		}).Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		encrypted, err := context.EncryptRTP(nil, raw, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		packets = append(packets, encrypted)
+	}
+
+	// A corrupted packet and a truncated one are skipped
+	corrupted := append([]byte{}, packets[1]...)
+	corrupted[len(corrupted)-1] ^= 0xFF
+	packets = append(packets[:1], append([][]byte{corrupted, packets[1][:8]}, packets[1:]...)...)
+
+	local, remote := net.Pipe()
+	c, err := newSRTPAuthConn(local, key, salt, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delivered := readAuthenticated(t, c, remote, packets)
+	assert.Equal(t, append(packets[:1], packets[3:]...), delivered)
+	assert.Equal(t, uint64(2), c.authFailures())
+}
+
+func TestSRTPAuthConn_RTCP(t *testing.T) {
+	key, salt := make([]byte, srtpMasterKeyLength), make([]byte, srtpMasterSaltLength)
+	context, err := srtp.CreateContext(key, salt, srtp.ProtectionProfileAes128CmHmacSha1_80)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := (&rtcp.PictureLossIndication{SenderSSRC: 1, MediaSSRC: 2}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := context.EncryptRTCP(nil, raw, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupted := append([]byte{}, encrypted...)
+	corrupted[9] ^= 0xFF
+
+	local, remote := net.Pipe()
+	c, err := newSRTPAuthConn(local, key, salt, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delivered := readAuthenticated(t, c, remote, [][]byte{corrupted, encrypted})
+	assert.Equal(t, [][]byte{encrypted}, delivered)
+	assert.Equal(t, uint64(1), c.authFailures())
+
+	_, err = newSRTPAuthConn(local, key[:4], salt, true)
+	assert.Error(t, err)
+}
+
+// packetConn is a net.Conn reading the packets it is given in a loop,
+// without allocating
+type packetConn struct {
+	net.Conn
+	packets [][]byte
+	next    int
+}
+
+func (c *packetConn) Read(p []byte) (int, error) {
+	n := copy(p, c.packets[c.next])
+	c.next = (c.next + 1) % len(c.packets)
+	return n, nil
+}
+
+// BenchmarkSRTPAuthConn_Read measures the authentication of the SRTP packets
+// of many streams, interleaved as they reach the session of a forwarding
+// unit. It doesn't allocate.
+func BenchmarkSRTPAuthConn_Read(b *testing.B) {
+	key, salt := make([]byte, srtpMasterKeyLength), make([]byte, srtpMasterSaltLength)
+	context, err := srtp.CreateContext(key, salt, srtp.ProtectionProfileAes128CmHmacSha1_80)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	// The sequence numbers start again without wrapping around, the
+	// rollover counter stays the same
+	const streams, packetsPerStream = 64, 16
+	conn := &packetConn{}
+	for sequenceNumber := uint16(1000); sequenceNumber < 1000+packetsPerStream; sequenceNumber++ {
+		for ssrc := uint32(0); ssrc < streams; ssrc++ {
+			raw, err := (&rtp.Packet{
+				Header:  rtp.Header{Version: 2, SSRC: ssrc, SequenceNumber: sequenceNumber},
+				Payload: make([]byte, 1200),
+			}).Marshal()
+			if err != nil {
+				b.Fatal(err)
+			}
+			encrypted, err := context.EncryptRTP(nil, raw, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			conn.packets = append(conn.packets, encrypted)
+		}
+	}
+
+	c, err := newSRTPAuthConn(conn, key, salt, false)
+	if err != nil {
+		b.Fatal(err)
+	}
+	buf := make([]byte, receiveMTU)
+	b.SetBytes(int64(len(conn.packets[0])))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.Read(buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+
+	if failures := c.authFailures(); failures != 0 {
+		b.Fatalf("%d packets failed the authentication", failures)
+	}
+}
@@ -0,0 +1,42 @@
+package webrtc
+
+import (
+	"github.com/pions/dtls"
+	"github.com/pions/srtp"
+)
+
+// SRTPProtectionProfile indicates the protection profile DTLS negotiated
+// to protect RTP and RTCP.
+// https://tools.ietf.org/html/rfc5764#section-4.1.2
+type SRTPProtectionProfile uint16
+
+const (
+	// SRTPProtectionProfileAes128CmHmacSha1_80 indicates AES-128 in counter
+	// mode with a 80-bit HMAC-SHA1 authentication tag.
+	SRTPProtectionProfileAes128CmHmacSha1_80 SRTPProtectionProfile = 0x0001
+)
+
+// This is done this way because of a linter.
+const (
+	srtpProtectionProfileAes128CmHmacSha1_80Str = "SRTP_AES128_CM_HMAC_SHA1_80"
+)
+
+func (p SRTPProtectionProfile) String() string {
+	switch p {
+	case SRTPProtectionProfileAes128CmHmacSha1_80:
+		return srtpProtectionProfileAes128CmHmacSha1_80Str
+	default:
+		return unknownStr
+	}
+}
+
+// newSRTPProtectionProfile maps the profile negotiated by DTLS to the
+// profiles of webrtc and srtp, false if srtp doesn't support it
+func newSRTPProtectionProfile(p dtls.SRTPProtectionProfile) (SRTPProtectionProfile, srtp.ProtectionProfile, bool) {
+	switch p {
+	case dtls.SRTP_AES128_CM_HMAC_SHA1_80:
+		return SRTPProtectionProfileAes128CmHmacSha1_80, srtp.ProtectionProfileAes128CmHmacSha1_80, true
+	default:
+		return SRTPProtectionProfile(Unknown), 0, false
+	}
+}
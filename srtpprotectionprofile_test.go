@@ -0,0 +1,37 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/dtls"
+	"github.com/pions/srtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSRTPProtectionProfile_String(t *testing.T) {
+	testCases := []struct {
+		profile        SRTPProtectionProfile
+		expectedString string
+	}{
+		{SRTPProtectionProfile(Unknown), unknownStr},
+		{SRTPProtectionProfileAes128CmHmacSha1_80, "SRTP_AES128_CM_HMAC_SHA1_80"},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedString,
+			testCase.profile.String(),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
+
+func TestNewSRTPProtectionProfile(t *testing.T) {
+	profile, srtpProfile, ok := newSRTPProtectionProfile(dtls.SRTP_AES128_CM_HMAC_SHA1_80)
+	assert.True(t, ok)
+	assert.Equal(t, SRTPProtectionProfileAes128CmHmacSha1_80, profile)
+	assert.Equal(t, srtp.ProtectionProfileAes128CmHmacSha1_80, srtpProfile)
+
+	_, _, ok = newSRTPProtectionProfile(0)
+	assert.False(t, ok)
+}
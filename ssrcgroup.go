@@ -0,0 +1,82 @@
+package webrtc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pions/sdp/v2"
+)
+
+// Semantics of the a=ssrc-group attributes
+const (
+	// ssrcGroupFID associates a media SSRC with its RTX SSRC
+	// https://tools.ietf.org/html/rfc4588#section-8.1
+	ssrcGroupFID = "FID"
+
+	// ssrcGroupSIM lists the SSRCs of the layers of a simulcast stream
+	ssrcGroupSIM = "SIM"
+)
+
+// ssrcGroup is a a=ssrc-group attribute as defined in
+// https://tools.ietf.org/html/rfc5576#section-4.2
+type ssrcGroup struct {
+	semantics string
+	ssrcs     []uint32
+}
+
+func (g ssrcGroup) String() string {
+	fields := []string{g.semantics}
+	for _, ssrc := range g.ssrcs {
+		fields = append(fields, strconv.FormatUint(uint64(ssrc), 10))
+	}
+	return strings.Join(fields, " ")
+}
+
+func parseSSRCGroup(value string) (ssrcGroup, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 2 {
+		return ssrcGroup{}, fmt.Errorf("invalid ssrc-group %q", value)
+	}
+
+	g := ssrcGroup{semantics: fields[0]}
+	for _, field := range fields[1:] {
+		ssrc, err := strconv.ParseUint(field, 10, 32)
+		if err != nil {
+			return ssrcGroup{}, fmt.Errorf("invalid ssrc-group SSRC %q", field)
+		}
+		g.ssrcs = append(g.ssrcs, uint32(ssrc))
+	}
+	return g, nil
+}
+
+// ssrcGroupsFromMedia returns the a=ssrc-group attributes of a media section
+func ssrcGroupsFromMedia(media *sdp.MediaDescription) []ssrcGroup {
+	var groups []ssrcGroup
+	for _, a := range media.Attributes {
+		if a.Key != sdp.AttrKeySSRCGroup {
+			continue
+		}
+
+		g, err := parseSSRCGroup(a.Value)
+		if err != nil {
+			pcLog.Warnf("Failed to parse ssrc-group: %v", err)
+			continue
+		}
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// rtxSSRCsFromMedia maps the media SSRCs of the FID groups of a media section
+// to their RTX SSRC
+func rtxSSRCsFromMedia(media *sdp.MediaDescription) map[uint32]uint32 {
+	rtx := map[uint32]uint32{}
+	for _, g := range ssrcGroupsFromMedia(media) {
+		if g.semantics != ssrcGroupFID || len(g.ssrcs) != 2 {
+			continue
+		}
+		rtx[g.ssrcs[0]] = g.ssrcs[1]
+	}
+	return rtx
+}
@@ -0,0 +1,36 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/sdp/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSSRCGroup(t *testing.T) {
+	g, err := parseSSRCGroup("FID 1111 2222")
+	assert.NoError(t, err)
+	assert.Equal(t, ssrcGroup{semantics: ssrcGroupFID, ssrcs: []uint32{1111, 2222}}, g)
+	assert.Equal(t, "FID 1111 2222", g.String())
+
+	g, err = parseSSRCGroup("SIM 1 2 3")
+	assert.NoError(t, err)
+	assert.Equal(t, ssrcGroup{semantics: ssrcGroupSIM, ssrcs: []uint32{1, 2, 3}}, g)
+
+	for _, value := range []string{"", "FID", "FID 1111 foo", "FID 1111 4294967296"} {
+		_, err = parseSSRCGroup(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestRTXSSRCsFromMedia(t *testing.T) {
+	media := (&sdp.MediaDescription{}).
+		WithValueAttribute(sdp.AttrKeySSRCGroup, "FID 1111 2222").
+		WithValueAttribute(sdp.AttrKeySSRCGroup, "SIM 1111 3333 5555").
+		WithValueAttribute(sdp.AttrKeySSRCGroup, "FID 3333 4444").
+		WithValueAttribute(sdp.AttrKeySSRCGroup, "FID 5555").
+		WithValueAttribute(sdp.AttrKeySSRC, "1111 cname:pion")
+
+	assert.Len(t, ssrcGroupsFromMedia(media), 4)
+	assert.Equal(t, map[uint32]uint32{1111: 2222, 3333: 4444}, rtxSSRCsFromMedia(media))
+}
@@ -0,0 +1,152 @@
+package webrtc
+
+import "time"
+
+// defaultStatsInterval is the interval the OnStats handler is fired at unless
+// configured otherwise
+const defaultStatsInterval = time.Second
+
+// StatsReport is a snapshot of the counters of a PeerConnection, delivered
+// periodically to the OnStats handler
+type StatsReport struct {
+	// Timestamp is the time the snapshot was taken at
+	Timestamp time.Time
+
+	Transport TransportStats
+
+	// Senders and Receivers have the counters of each started stream, in the
+	// order of the transceivers
+	Senders   []RTPSenderStats
+	Receivers []RTPReceiverStats
+}
+
+// RTPSenderStats contains the counters of a sent Track
+type RTPSenderStats struct {
+	TrackID string
+	SSRC    uint32
+	Paused  bool
+	NACK    NACKStats
+
+	// KeyFramesSent is the number of samples sent with IsKeyFrame set
+	KeyFramesSent uint64
+
+	// PacketsDroppedDisconnected is the number of packets dropped as they
+	// were written while the ICE connection was disconnected, see
+	// SettingEngine.SetDisconnectedBuffer
+	PacketsDroppedDisconnected uint64
+}
+
+// RTPReceiverStats contains the counters of a received Track
+type RTPReceiverStats struct {
+	TrackID string
+	SSRC    uint32
+
+	// PacketsDelivered is the number of packets put in Track.Packets
+	PacketsDelivered uint64
+
+	// PacketsDropped is the number of packets dropped because Track.Packets
+	// was full, the Track isn't read fast enough
+	PacketsDropped uint64
+
+	// PacketsDelayed and PacketsRateLimited are the number of packets held
+	// back and dropped to deliver the Track at its drain rate, see
+	// RTPReceiver.SetDrainRate
+	PacketsDelayed     uint64
+	PacketsRateLimited uint64
+
+	// PacketsPadding is the number of padding-only packets received, such as
+	// the ones senders probe the bandwidth with. They are not put in
+	// Track.Packets unless SettingEngine.DeliverPaddingPackets is set.
+	PacketsPadding uint64
+
+	// PacketsLate is the number of packets dropped by the jitter buffer as
+	// they arrived after their turn, see SettingEngine.EnableJitterBuffer
+	PacketsLate uint64
+
+	NACK NACKStats
+
+	KeyFrameRequests KeyFrameRequestStats
+}
+
+// KeyFrameRequestStats counts the keyframe requests of a RTPReceiver, see
+// SettingEngine.SetKeyFrameRequestInterval
+type KeyFrameRequestStats struct {
+	// Sent is the number of PLI and FIR requests sent
+	Sent uint64
+
+	// Suppressed is the number of requests not sent as they followed the
+	// previous one within the interval
+	Suppressed uint64
+}
+
+// OnStats sets an event handler which is fired with a StatsReport at the
+// interval set with SettingEngine.SetStatsInterval, every second by default.
+// The sampling loop starts with the first handler and stops on Close, each
+// report is sampled right before the handler is fired. A handler slower than
+// the interval delays the next reports instead of piling them up.
+func (pc *PeerConnection) OnStats(f func(StatsReport)) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.onStatsHandler = f
+
+	if f == nil || pc.statsLoopClose != nil || pc.closed() {
+		return
+	}
+	pc.statsLoopClose = make(chan struct{})
+	go pc.statsLoop(pc.api.settingEngine.statsInterval(), pc.statsLoopClose)
+}
+
+func (pc *PeerConnection) statsLoop(interval time.Duration, closed <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+		}
+
+		pc.mu.RLock()
+		hdlr := pc.onStatsHandler
+		pc.mu.RUnlock()
+		if hdlr != nil {
+			hdlr(pc.statsReport())
+		}
+	}
+}
+
+// stopStatsLoop stops the sampling loop of OnStats, if started
+func (pc *PeerConnection) stopStatsLoop() {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if pc.statsLoopClose != nil {
+		close(pc.statsLoopClose)
+	}
+	pc.onStatsHandler = nil
+}
+
+// statsReport samples the counters of the PeerConnection
+func (pc *PeerConnection) statsReport() StatsReport {
+	report := StatsReport{
+		Timestamp: time.Now(),
+		Transport: pc.dtlsTransport.GetStats(),
+	}
+
+	pc.mu.RLock()
+	transceivers := append([]*RTPTransceiver{}, pc.rtpTransceivers...)
+	pc.mu.RUnlock()
+
+	for _, t := range transceivers {
+		if sender := t.Sender(); sender != nil && sender.Track != nil {
+			report.Senders = append(report.Senders, sender.stats())
+		}
+		if receiver := t.Receiver(); receiver != nil {
+			if stats, ok := receiver.stats(); ok {
+				report.Receivers = append(report.Receivers, stats)
+			}
+		}
+	}
+	return report
+}
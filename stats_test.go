@@ -0,0 +1,101 @@
+package webrtc
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pions/transport/test"
+	"github.com/pions/webrtc/pkg/media"
+)
+
+func TestPeerConnection_OnStats(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	if err := s.SetStatsInterval(0); err != ErrStatsInterval {
+		t.Fatalf("Setting engine should fail a stats interval of 0.")
+	}
+	if err := s.SetStatsInterval(50 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	api := NewAPI(WithSettingEngine(s))
+	api.mediaEngine.RegisterDefaultCodecs()
+
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vp8Track, err := pcOffer.NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pcOffer.AddTrack(vp8Track); err != nil {
+		t.Fatal(err)
+	}
+
+	var sent int32
+	pcOffer.OnStats(func(report StatsReport) {
+		if len(report.Senders) == 1 && report.Senders[0].SSRC == vp8Track.SSRC && report.Senders[0].KeyFramesSent != 0 && report.Transport.PacketsSent != 0 {
+			atomic.StoreInt32(&sent, 1)
+		}
+	})
+
+	// The Track isn't read, the packets beyond its buffer are dropped
+	pcAnswer.OnTrack(func(*Track) {})
+	awaitDropped := make(chan struct{})
+	var answerReports int32
+	pcAnswer.OnStats(func(report StatsReport) {
+		atomic.AddInt32(&answerReports, 1)
+		if len(report.Receivers) == 1 && report.Receivers[0].PacketsDelivered != 0 && report.Receivers[0].PacketsDropped != 0 {
+			select {
+			case <-awaitDropped:
+			default:
+				close(awaitDropped)
+			}
+		}
+	})
+
+	awaitRTPSendDone := make(chan bool)
+	go func() {
+		defer close(awaitRTPSendDone)
+		for {
+			time.Sleep(time.Millisecond * 10)
+			vp8Track.Samples <- media.Sample{Data: []byte{0x00}, Samples: 1, IsKeyFrame: true}
+
+			select {
+			case <-awaitDropped:
+				return
+			default:
+			}
+		}
+	}()
+
+	if err = signalPair(pcOffer, pcAnswer); err != nil {
+		t.Fatal(err)
+	}
+
+	<-awaitRTPSendDone
+	if atomic.LoadInt32(&sent) == 0 {
+		t.Fatal("The sender was not in the stats of the offer")
+	}
+
+	if err = pcOffer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// No report is delivered once closed
+	reports := atomic.LoadInt32(&answerReports)
+	time.Sleep(200 * time.Millisecond)
+	if atomic.LoadInt32(&answerReports) != reports {
+		t.Fatal("Stats were delivered after Close")
+	}
+}
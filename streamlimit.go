@@ -0,0 +1,120 @@
+package webrtc
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"time"
+)
+
+// limitExceededInterval is the minimum interval between two invocations of
+// the OnLimitExceeded handler
+const limitExceededInterval = time.Second
+
+// unclaimedStreamTimeout is how long the stream of a SSRC no RTPReceiver
+// reads keeps its place without packets
+const unclaimedStreamTimeout = 10 * time.Second
+
+// srtpStreamLimitConn sits between the SRTP session and the packets of the
+// remote. The session opens a read stream for every new SSRC, and the
+// PeerConnection accepts and reads each of them: once max SSRCs have a
+// stream the packets of the other ones are dropped before they open a new
+// one. The SSRC of an RTP packet is sent in the clear, unlike the ones an
+// RTCP packet refers to, only the SRTP streams are limited.
+//
+// The unclaimed SSRCs lose their place once their packets stopped for
+// unclaimedStreamTimeout. Their read stream stays open: pions/srtp can't
+// close a stream while its reader waits for a packet.
+type srtpStreamLimitConn struct {
+	net.Conn
+
+	max int
+
+	// reserved are the SSRCs RTPReceivers read, unclaimed the last packet
+	// time of the other ones admitted
+	mu        sync.Mutex
+	reserved  map[uint32]bool
+	unclaimed map[uint32]time.Time
+	lastDrop  time.Time
+	onDrop    func(ssrc uint32)
+}
+
+func newSRTPStreamLimitConn(conn net.Conn, max int, onDrop func(ssrc uint32)) *srtpStreamLimitConn {
+	return &srtpStreamLimitConn{
+		Conn:      conn,
+		max:       max,
+		reserved:  map[uint32]bool{},
+		unclaimed: map[uint32]time.Time{},
+		onDrop:    onDrop,
+	}
+}
+
+func (c *srtpStreamLimitConn) Read(p []byte) (int, error) {
+	for {
+		n, err := c.Conn.Read(p)
+		if err != nil || n < 12 {
+			return n, err
+		}
+
+		if c.admit(binary.BigEndian.Uint32(p[8:12]), time.Now()) {
+			return n, nil
+		}
+	}
+}
+
+// admit returns whether the packets of the SSRC can reach the session, the
+// handler is invoked when they can't
+func (c *srtpStreamLimitConn) admit(ssrc uint32, now time.Time) bool {
+	c.mu.Lock()
+	if c.reserved[ssrc] {
+		c.mu.Unlock()
+		return true
+	}
+	_, admitted := c.unclaimed[ssrc]
+	if !admitted && c.max != 0 && len(c.reserved)+len(c.unclaimed) >= c.max {
+		c.expire(now)
+	}
+	if admitted || c.max == 0 || len(c.reserved)+len(c.unclaimed) < c.max {
+		c.unclaimed[ssrc] = now
+		c.mu.Unlock()
+		return true
+	}
+
+	notify := now.Sub(c.lastDrop) >= limitExceededInterval
+	if notify {
+		c.lastDrop = now
+	}
+	c.mu.Unlock()
+
+	if notify {
+		c.onDrop(ssrc)
+	}
+	return false
+}
+
+// expire frees the places of the unclaimed SSRCs without packets for
+// unclaimedStreamTimeout
+func (c *srtpStreamLimitConn) expire(now time.Time) {
+	for ssrc, last := range c.unclaimed {
+		if now.Sub(last) >= unclaimedStreamTimeout {
+			delete(c.unclaimed, ssrc)
+		}
+	}
+}
+
+// reserve admits the stream of a SSRC a RTPReceiver reads, regardless of
+// the limit, the SSRCs the remote announced are bounded by their description
+func (c *srtpStreamLimitConn) reserve(ssrc uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.unclaimed, ssrc)
+	c.reserved[ssrc] = true
+}
+
+// release frees the place of the stream of a SSRC once closed
+func (c *srtpStreamLimitConn) release(ssrc uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.reserved, ssrc)
+	delete(c.unclaimed, ssrc)
+}
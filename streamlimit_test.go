@@ -0,0 +1,67 @@
+package webrtc
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSRTPStreamLimitConn(t *testing.T) {
+	var dropped []uint32
+	local, remote := net.Pipe()
+	c := newSRTPStreamLimitConn(local, 2, func(ssrc uint32) {
+		dropped = append(dropped, ssrc)
+	})
+
+	// The packets of a third SSRC are dropped, the handler is throttled
+	now := time.Now()
+	assert.True(t, c.admit(1, now))
+	assert.True(t, c.admit(2, now))
+	assert.True(t, c.admit(1, now))
+	assert.False(t, c.admit(3, now))
+	assert.False(t, c.admit(4, now))
+	assert.Equal(t, []uint32{3}, dropped)
+	assert.False(t, c.admit(4, now.Add(limitExceededInterval)))
+	assert.Equal(t, []uint32{3, 4}, dropped)
+
+	// The declared SSRCs exceed the limit, a released SSRC frees a place
+	c.reserve(5)
+	assert.True(t, c.admit(5, now))
+	c.release(1)
+	c.release(5)
+	assert.True(t, c.admit(3, now))
+
+	// An unclaimed SSRC without packets loses its place, a reserved one
+	// keeps it
+	idle := newSRTPStreamLimitConn(nil, 2, func(uint32) {})
+	idle.reserve(1)
+	assert.True(t, idle.admit(2, now))
+	assert.True(t, idle.admit(2, now.Add(unclaimedStreamTimeout/2)))
+	assert.False(t, idle.admit(3, now.Add(unclaimedStreamTimeout)))
+	assert.True(t, idle.admit(3, now.Add(unclaimedStreamTimeout*3/2)))
+	assert.False(t, idle.admit(2, now.Add(unclaimedStreamTimeout*3/2)))
+	assert.True(t, idle.admit(1, now.Add(time.Hour)))
+	assert.True(t, idle.admit(2, now.Add(time.Hour)))
+	assert.False(t, idle.admit(3, now.Add(time.Hour)))
+
+	// Read skips the dropped packets
+	go func() {
+		for _, ssrc := range []uint32{6, 2} {
+			packet := make([]byte, 12)
+			binary.BigEndian.PutUint32(packet[8:], ssrc)
+			if _, err := remote.Write(packet); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	b := make([]byte, 1500)
+	n, err := c.Read(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, uint32(2), binary.BigEndian.Uint32(b[8:n]))
+	assert.NoError(t, remote.Close())
+}
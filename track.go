@@ -1,11 +1,18 @@
 package webrtc
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/binary"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/pions/rtcp"
 	"github.com/pions/rtp"
+	"github.com/pions/srtp"
+	"github.com/pions/webrtc/pkg/logging"
 	"github.com/pions/webrtc/pkg/media"
 	"github.com/pkg/errors"
 )
@@ -17,6 +24,33 @@ type Track struct {
 	rawInput    chan *rtp.Packet
 	rtcpInput   chan rtcp.Packet
 
+	// rtxSSRC is the SSRC the packets of the Track are retransmitted with,
+	// 0 when retransmissions are not sent on a separate stream
+	rtxSSRC uint32
+
+	// headerExtensions maps the URI of the negotiated header extensions to their ID
+	headerExtensionsLock sync.RWMutex
+	headerExtensions     map[string]uint8
+
+	// frameAssembler assembles the packets read by ReadFrame
+	frameAssembler *frameAssembler
+
+	// receiver is the RTPReceiver of a received Track, nil for a sent one
+	receiver *RTPReceiver
+
+	// sender is the RTPSender of a sent Track, nil until the Track is added
+	// to a PeerConnection
+	sender *RTPSender
+
+	// rawRTCP is accessed atomically, it is set once ReadRTCPRaw is called
+	rawRTCP int32
+
+	// writeDeadline bounds the writes of WriteRTP and WriteSample
+	writeDeadline deadline
+
+	userDataMu sync.RWMutex
+	userData   interface{}
+
 	ID          string
 	PayloadType uint8
 	Kind        RTPCodecType
@@ -24,6 +58,14 @@ type Track struct {
 	SSRC        uint32
 	Codec       *RTPCodec
 
+	// RID is the simulcast layer a received Track carries, empty
+	// without simulcast
+	RID string
+
+	// RIDRestrictions are the restrictions the remote announced for the
+	// layer in its a=rid line
+	RIDRestrictions RIDRestrictions
+
 	Packets     <-chan *rtp.Packet
 	RTCPPackets <-chan rtcp.Packet
 
@@ -54,12 +96,16 @@ func NewRawRTPTrack(payloadType uint8, ssrc uint32, id, label string, codec *RTP
 
 // NewSampleTrack initializes a new *Track configured to accept media.Sample
 func NewSampleTrack(payloadType uint8, id, label string, codec *RTPCodec) (*Track, error) {
+	return newSampleTrack(payloadType, id, label, codec, rand.Reader)
+}
+
+func newSampleTrack(payloadType uint8, id, label string, codec *RTPCodec, random io.Reader) (*Track, error) {
 	if codec == nil {
 		return nil, errors.New("codec supplied to NewSampleTrack() must not be nil")
 	}
 
 	buf := make([]byte, 4)
-	if _, err := rand.Read(buf); err != nil {
+	if _, err := io.ReadFull(random, buf); err != nil {
 		return nil, errors.New("failed to generate random value")
 	}
 
@@ -74,3 +120,393 @@ func NewSampleTrack(payloadType uint8, id, label string, codec *RTPCodec) (*Trac
 		Codec:       codec,
 	}, nil
 }
+
+// SetUserData attaches a value of the application to the Track, to carry
+// its context such as the room or participant it belongs to. The value is
+// never used by pions-webrtc. It can be set and read concurrently.
+func (t *Track) SetUserData(data interface{}) {
+	t.userDataMu.Lock()
+	defer t.userDataMu.Unlock()
+	t.userData = data
+}
+
+// UserData returns the value set with SetUserData, nil if none was set
+func (t *Track) UserData() interface{} {
+	t.userDataMu.RLock()
+	defer t.userDataMu.RUnlock()
+	return t.userData
+}
+
+func (t *Track) headerExtensionID(uri string) (uint8, bool) {
+	t.headerExtensionsLock.RLock()
+	defer t.headerExtensionsLock.RUnlock()
+	id, ok := t.headerExtensions[uri]
+	return id, ok
+}
+
+// setHeaderExtensions sets the negotiated header extensions, the Track may
+// be written meanwhile
+func (t *Track) setHeaderExtensions(headerExtensions map[string]uint8) {
+	t.headerExtensionsLock.Lock()
+	defer t.headerExtensionsLock.Unlock()
+	t.headerExtensions = headerExtensions
+}
+
+// HeaderExtensions returns the negotiated header extensions of the Track,
+// mapping their URI to their ID. It is nil until the Track is sent or
+// received.
+func (t *Track) HeaderExtensions() map[string]uint8 {
+	t.headerExtensionsLock.RLock()
+	defer t.headerExtensionsLock.RUnlock()
+	if t.headerExtensions == nil {
+		return nil
+	}
+	headerExtensions := make(map[string]uint8, len(t.headerExtensions))
+	for uri, id := range t.headerExtensions {
+		headerExtensions[uri] = id
+	}
+	return headerExtensions
+}
+
+// WriteRTP sends a packet on a raw RTP Track, keeping the header extensions
+// it carries. headerExtensions is the mapping of the session the packet comes
+// from, such as the HeaderExtensions of the received Track being forwarded:
+// the IDs of the extensions are rewritten to the ones negotiated for this
+// Track, and extensions not negotiated for it are stripped. A nil mapping
+// sends the extensions as is. The packet isn't modified, it is copied, so it
+// may be reused or released with ReleaseRTPPacket once WriteRTP returns.
+//
+// Unlike the RawRTP channel, WriteRTP can be used while the RTPSender of the
+// Track is stopped, it returns ErrRTPSenderStopped once it is. It fails with
+// ErrWriteTimeout if the write deadline elapses while the RTPSender is too
+// slow to take the packet.
+func (t *Track) WriteRTP(packet *rtp.Packet, headerExtensions map[string]uint8) error {
+	if !t.isRawRTP {
+		return ErrNotRawRTPTrack
+	} else if t.sender == nil {
+		return ErrTrackNotSending
+	}
+
+	// The packet is sent once the RTPSender takes it, its bytes are copied
+	// as the caller may reuse them, such as by releasing a received packet
+	p := *packet
+	buf := make([]byte, len(packet.ExtensionPayload)+len(packet.Payload))
+	n := copy(buf, packet.ExtensionPayload)
+	copy(buf[n:], packet.Payload)
+	if packet.ExtensionPayload != nil {
+		p.ExtensionPayload = buf[:n:n]
+	}
+	p.Payload = buf[n:]
+	p.CSRC = append([]uint32(nil), packet.CSRC...)
+	p.Raw = nil
+	if headerExtensions != nil {
+		t.headerExtensionsLock.RLock()
+		err := remapHeaderExtensions(&p.Header, headerExtensions, t.headerExtensions)
+		t.headerExtensionsLock.RUnlock()
+		if err != nil {
+			return err
+		}
+	}
+	return t.sender.writeInput(func() error {
+		for {
+			if t.writeDeadline.exceeded() {
+				return ErrWriteTimeout
+			}
+			elapsed, changed, stop := t.writeDeadline.wait()
+			select {
+			case t.rawInput <- &p:
+				stop()
+				return nil
+			case <-t.sender.inputStopped:
+				stop()
+				return ErrRTPSenderStopped
+			case <-elapsed:
+				return ErrWriteTimeout
+			case <-changed:
+				stop()
+			}
+		}
+	})
+}
+
+// WriteSample sends a sample on a sample Track, like the Samples channel.
+// Unlike the channel, WriteSample can be used while the RTPSender of the
+// Track is stopped, it returns ErrRTPSenderStopped once it is, and it is
+// bound by the write deadline as WriteRTP is.
+func (t *Track) WriteSample(sample media.Sample) error {
+	if t.isRawRTP {
+		return ErrNotSampleTrack
+	} else if t.sender == nil {
+		return ErrTrackNotSending
+	}
+	return t.sender.writeInput(func() error {
+		for {
+			if t.writeDeadline.exceeded() {
+				return ErrWriteTimeout
+			}
+			elapsed, changed, stop := t.writeDeadline.wait()
+			select {
+			case t.sampleInput <- sample:
+				stop()
+				return nil
+			case <-t.sender.inputStopped:
+				stop()
+				return ErrRTPSenderStopped
+			case <-elapsed:
+				return ErrWriteTimeout
+			case <-changed:
+				stop()
+			}
+		}
+	})
+}
+
+// SendSamples sends the samples of a media file on a sample Track, such as
+// the ones of an ivfreader or oggreader, paced by their presentation time:
+// a sample is written once the time since the first one reaches its own.
+// Its capture time is the time it is due, so the RTP timestamps follow the
+// pacing.
+//
+// When the remote requests a keyframe of a video Track with a PLI or a FIR,
+// the samples up to the next one flagged IsKeyFrame are skipped, the
+// keyframe is sent in place of the next sample. The reader must flag the
+// keyframes, the rest of the file is skipped otherwise.
+//
+// SendSamples returns nil at the end of the file, the error of the reader
+// or ErrRTPSenderStopped once the RTPSender of the Track is stopped.
+func (t *Track) SendSamples(reader media.SampleReader) error {
+	if t.isRawRTP {
+		return ErrNotSampleTrack
+	} else if t.sender == nil {
+		return ErrTrackNotSending
+	}
+
+	p := &samplePacer{
+		reader: reader,
+		write:  t.WriteSample,
+		now:    time.Now,
+		sleep:  time.Sleep,
+	}
+	if t.Kind == RTPCodecTypeVideo {
+		p.keyFrameRequested = t.sender.takeKeyFrameRequest
+	}
+	return p.run()
+}
+
+// ReadRTP returns the next RTP packet of a received Track, parsed, like
+// RTPReceiver.ReadRTP. It competes with the other readers of Packets, and
+// returns io.EOF once the Track is done. ErrTrackNotReceived is returned for
+// a Track that is sent.
+func (t *Track) ReadRTP() (*rtp.Packet, error) {
+	return t.ReadRTPContext(context.Background())
+}
+
+// ReadRTPContext is ReadRTP bound to a context, like
+// RTPReceiver.ReadRTPContext.
+func (t *Track) ReadRTPContext(ctx context.Context) (*rtp.Packet, error) {
+	if t.receiver == nil {
+		return nil, ErrTrackNotReceived
+	}
+	return t.receiver.ReadRTPContext(ctx)
+}
+
+// SetReadDeadline sets the read deadline of the RTPReceiver of a received
+// Track, see RTPReceiver.SetReadDeadline. ErrTrackNotReceived is returned
+// for a Track that is sent.
+func (t *Track) SetReadDeadline(deadline time.Time) error {
+	if t.receiver == nil {
+		return ErrTrackNotReceived
+	}
+	return t.receiver.SetReadDeadline(deadline)
+}
+
+// SetWriteDeadline sets the deadline of WriteRTP and WriteSample, as the one
+// of a net.Conn: the writes pending and the following ones fail with
+// ErrWriteTimeout once it elapses, until it is set again. The zero time
+// removes it. The writes to the RawRTP and Samples channels aren't bound by
+// it.
+func (t *Track) SetWriteDeadline(deadline time.Time) error {
+	t.writeDeadline.set(deadline)
+	return nil
+}
+
+// VideoOrientation returns the CVO information carried by the given header, if
+// the extension was negotiated for the Track and is present in the packet
+func (t *Track) VideoOrientation(header *rtp.Header) (VideoOrientation, bool) {
+	id, ok := t.headerExtensionID(VideoOrientationURI)
+	if !ok {
+		return VideoOrientation{}, false
+	}
+
+	payload, ok := getHeaderExtension(header, id)
+	if !ok {
+		return VideoOrientation{}, false
+	}
+
+	o, err := unmarshalVideoOrientation(payload)
+	if err != nil {
+		return VideoOrientation{}, false
+	}
+	return o, true
+}
+
+// AbsCaptureTime returns the absolute capture time carried by the given header,
+// if the extension was negotiated for the Track and is present in the packet
+func (t *Track) AbsCaptureTime(header *rtp.Header) (AbsCaptureTime, bool) {
+	id, ok := t.headerExtensionID(AbsCaptureTimeURI)
+	if !ok {
+		return AbsCaptureTime{}, false
+	}
+
+	payload, ok := getHeaderExtension(header, id)
+	if !ok {
+		return AbsCaptureTime{}, false
+	}
+
+	a, err := unmarshalAbsCaptureTime(payload)
+	if err != nil {
+		return AbsCaptureTime{}, false
+	}
+	return a, true
+}
+
+// SetAbsCaptureTime writes the absolute capture time into the header of a
+// packet sent on a raw RTP Track. ErrHeaderExtensionNotNegotiated is returned
+// if the abs-capture-time extension wasn't negotiated.
+func (t *Track) SetAbsCaptureTime(header *rtp.Header, a AbsCaptureTime) error {
+	id, ok := t.headerExtensionID(AbsCaptureTimeURI)
+	if !ok {
+		return ErrHeaderExtensionNotNegotiated
+	}
+	return setHeaderExtension(header, id, a.marshal())
+}
+
+// ReadFrame returns the next complete frame of a received VP8 or H264 Track,
+// assembled from the packets read from Track.Packets. H264 access units are
+// returned as an Annex B byte stream. Frames missing a packet are dropped,
+// H264 carries no frame start so its assembly resumes after the next marker
+// bit following a loss. ReadFrame must not be used concurrently with other readers of
+// Track.Packets, it returns io.EOF once the Track is done.
+func (t *Track) ReadFrame() ([]byte, error) {
+	if t.frameAssembler == nil {
+		depacketizer, err := newFrameDepacketizer(t.Codec)
+		if err != nil {
+			return nil, err
+		}
+		t.frameAssembler = &frameAssembler{depacketizer: depacketizer}
+	}
+
+	for {
+		packet, ok := <-t.Packets
+		if !ok {
+			return nil, io.EOF
+		}
+
+		if frame, complete := t.frameAssembler.push(packet); complete {
+			return frame, nil
+		}
+	}
+}
+
+// IsFrameBoundary returns whether a packet of the Track ends a frame. A
+// video frame is split across packets, the marker bit flags the last one
+// https://tools.ietf.org/html/rfc3550#section-5.1. Every audio packet
+// carries entire frames, its marker bit flags the start of a talkspurt.
+// The header of the packets read from Track.Packets or RTPReceiver.ReadRTP
+// carries the marker bit and the CSRCs as received.
+func (t *Track) IsFrameBoundary(packet *rtp.Packet) bool {
+	if t.Kind == RTPCodecTypeAudio {
+		return true
+	}
+	return packet.Marker
+}
+
+// ReadRTCPRaw returns the next RTCP packet of RTCPPackets. From its first
+// call on, the packets the library fails to parse, such as vendor-specific
+// feedback or a malformed packet of a known type, are put in RTCPPackets as a
+// *rtcp.RawPacket holding the header and the raw bytes. The whole compound
+// packet is discarded otherwise, while the packets of an unknown type are
+// always kept as a RawPacket. ReadRTCPRaw must not be used concurrently with
+// other readers of RTCPPackets, it returns io.EOF once the Track is done.
+func (t *Track) ReadRTCPRaw() (rtcp.Packet, error) {
+	atomic.StoreInt32(&t.rawRTCP, 1)
+
+	packet, ok := <-t.RTCPPackets
+	if !ok {
+		return nil, io.EOF
+	}
+	return packet, nil
+}
+
+// unmarshalRTCP unmarshals a compound RTCP packet about the Track, keeping
+// the packets that fail to parse once ReadRTCPRaw was called
+func (t *Track) unmarshalRTCP(rawPacket []byte) ([]rtcp.Packet, error) {
+	if atomic.LoadInt32(&t.rawRTCP) == 1 {
+		return unmarshalCompoundRTCPRaw(rawPacket), nil
+	}
+	return unmarshalCompoundRTCP(rawPacket)
+}
+
+// srtcpReader reads the RTCP packets of the Track from its SRTCP stream, the
+// packets failing to unmarshal are discarded
+func (t *Track) srtcpReader(readStream *srtp.ReadStreamSRTCP, log logging.LeveledLogger) RTCPReader {
+	readBuf := make([]byte, receiveMTU)
+	return RTCPReaderFunc(func() ([]rtcp.Packet, error) {
+		for {
+			rtcpLen, err := readStream.Read(readBuf)
+			if err != nil {
+				return nil, err
+			}
+
+			packets, err := t.unmarshalRTCP(append([]byte{}, readBuf[:rtcpLen]...))
+			if err != nil {
+				log.Warnf("Failed to unmarshal RTCP packet, discarding: %v \n", err)
+				continue
+			}
+			return packets, nil
+		}
+	})
+}
+
+// CaptureTime returns the wall-clock time of the sender at which the media
+// of a received packet was captured, mapping its RTP timestamp through the
+// NTP time of the last Sender Report about the Track. It returns false until
+// a Sender Report was received, and for a sent Track.
+func (t *Track) CaptureTime(packet *rtp.Packet) (time.Time, bool) {
+	if t.receiver == nil || t.Codec == nil {
+		return time.Time{}, false
+	}
+	return t.receiver.captureTime(packet.Timestamp, t.Codec.ClockRate)
+}
+
+// ReceiveTime returns the time a received packet arrived: the timestamp the
+// kernel gave it where the sockets support SO_TIMESTAMP, the time the ICE
+// transport read it otherwise. Unlike the time it is read from the Track, it
+// leaves out the scheduling and buffering of the stack, for one-way delay and
+// jitter measurements. A packet carried by a RED or RTX packet arrived with
+// it. It returns false for a sent Track and once the packet is no longer
+// among the last 1024 of its SSRC.
+func (t *Track) ReceiveTime(packet *rtp.Packet) (time.Time, bool) {
+	if t.receiver == nil {
+		return time.Time{}, false
+	}
+	return t.receiver.transport.receiveTimes.lookup(packet.SSRC, packet.SequenceNumber)
+}
+
+// Close stops delivering to a received Track. Packets and RTCPPackets are
+// closed once they are drained, ReadRTP and ReadFrame then return io.EOF.
+//
+// A RTPReceiver receives a single Track, it keeps reading and discarding the
+// packets of a closed Track so the other streams of the transport aren't held
+// up, and releases the streams only once stopped. Stopping the RTPReceiver
+// closes its Track as well. Close can be called several times and has no
+// effect on a sent Track.
+func (t *Track) Close() error {
+	if t.receiver == nil {
+		return nil
+	}
+
+	t.receiver.closeRTPOut()
+	t.receiver.closeRTCPOut()
+	return nil
+}
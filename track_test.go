@@ -0,0 +1,306 @@
+package webrtc
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pions/rtcp"
+	"github.com/pions/rtp"
+	"github.com/pions/transport/test"
+	"github.com/pions/webrtc/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrack_UserData(t *testing.T) {
+	track, err := NewSampleTrack(DefaultPayloadTypeOpus, "audio", "pion", NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, track.UserData())
+
+	type participant struct{ room, id string }
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			track.SetUserData(participant{"room", "alice"})
+			_ = track.UserData()
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, participant{"room", "alice"}, track.UserData())
+}
+
+func TestTrack_WriteRTP(t *testing.T) {
+	sampleTrack, err := NewSampleTrack(DefaultPayloadTypeOpus, "audio", "pion", NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ErrNotRawRTPTrack, sampleTrack.WriteRTP(&rtp.Packet{}, nil))
+
+	track, err := NewRawRTPTrack(DefaultPayloadTypeVP8, 5000, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ErrTrackNotSending, track.WriteRTP(&rtp.Packet{}, nil))
+
+	NewAPI().NewRTPSender(track, nil)
+	track.headerExtensions = map[string]uint8{MIDURI: 2}
+	assert.Equal(t, map[string]uint8{MIDURI: 2}, track.HeaderExtensions())
+
+	packet := &rtp.Packet{Header: rtp.Header{SSRC: 5000}, Payload: []byte{0x00}}
+	assert.NoError(t, setHeaderExtension(&packet.Header, 1, []byte("0")))
+	assert.NoError(t, setHeaderExtension(&packet.Header, 3, []byte{0x01}))
+	source := packet.Header.ExtensionPayload
+
+	assert.NoError(t, track.WriteRTP(packet, map[string]uint8{MIDURI: 1, RIDURI: 3}))
+	sent := <-track.rawInput
+	payload, ok := getHeaderExtension(&sent.Header, 2)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("0"), payload)
+	_, ok = getHeaderExtension(&sent.Header, 3)
+	assert.False(t, ok)
+
+	// The packet written is left untouched
+	assert.Equal(t, source, packet.Header.ExtensionPayload)
+
+	// Writing to a stopped sender fails rather than panicking, twice too
+	track.sender.Stop()
+	assert.Equal(t, ErrRTPSenderStopped, track.WriteRTP(packet, nil))
+	track.sender.Stop()
+	assert.Equal(t, ErrRTPSenderStopped, track.WriteRTP(packet, nil))
+}
+
+func TestTrack_WriteSample(t *testing.T) {
+	rawTrack, err := NewRawRTPTrack(DefaultPayloadTypeVP8, 5000, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ErrNotSampleTrack, rawTrack.WriteSample(media.Sample{}))
+
+	track, err := NewSampleTrack(DefaultPayloadTypeOpus, "audio", "pion", NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ErrTrackNotSending, track.WriteSample(media.Sample{}))
+
+	sender := NewAPI().NewRTPSender(track, nil)
+	assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x01}, Samples: 960}))
+	assert.Equal(t, media.Sample{Data: []byte{0x01}, Samples: 960}, <-track.sampleInput)
+
+	sender.Stop()
+	assert.Equal(t, ErrRTPSenderStopped, track.WriteSample(media.Sample{}))
+}
+
+func TestTrack_SendSamples(t *testing.T) {
+	rawTrack, err := NewRawRTPTrack(DefaultPayloadTypeVP8, 5000, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ErrNotSampleTrack, rawTrack.SendSamples(&testSampleReader{}))
+
+	track, err := NewSampleTrack(DefaultPayloadTypeVP8, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ErrTrackNotSending, track.SendSamples(&testSampleReader{}))
+
+	// A keyframe requested before the first sample skips to the next one
+	sender := NewAPI().NewRTPSender(track, nil)
+	atomic.StoreInt32(&sender.keyFrameRequested, 1)
+	assert.NoError(t, track.SendSamples(&testSampleReader{samples: []fileSample{{0, false}, {time.Millisecond, true}}}))
+	sample := <-track.sampleInput
+	assert.Equal(t, []byte{1}, sample.Data)
+	assert.True(t, sample.IsKeyFrame)
+	assert.False(t, sample.Timestamp.IsZero())
+
+	sender.Stop()
+	assert.Equal(t, ErrRTPSenderStopped, track.SendSamples(&testSampleReader{samples: []fileSample{{0, true}}}))
+}
+
+func TestTrack_ReadRTP(t *testing.T) {
+	track, err := NewSampleTrack(DefaultPayloadTypeOpus, "audio", "pion", NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = track.ReadRTP()
+	assert.Equal(t, ErrTrackNotReceived, err)
+
+	receiver := NewAPI().NewRTPReceiver(RTPCodecTypeAudio, nil)
+	received := &Track{receiver: receiver, Packets: receiver.rtpOut}
+	packet := &rtp.Packet{Header: rtp.Header{SequenceNumber: 5}}
+	receiver.rtpOut <- packet
+
+	read, err := received.ReadRTP()
+	assert.NoError(t, err)
+	assert.Equal(t, packet, read)
+
+	close(receiver.rtpOut)
+	_, err = received.ReadRTP()
+	assert.Equal(t, io.EOF, err)
+
+	assert.Equal(t, ErrTrackNotReceived, track.SetReadDeadline(time.Now()))
+	assert.NoError(t, received.SetReadDeadline(time.Now()))
+	_, err = received.ReadRTPContext(context.Background())
+	assert.Equal(t, ErrReadTimeout, err)
+}
+
+func TestTrack_SetWriteDeadline(t *testing.T) {
+	track, err := NewRawRTPTrack(DefaultPayloadTypeVP8, 5000, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := NewAPI().NewRTPSender(track, nil)
+
+	// Nothing takes the packets of a sender that isn't sending, the writes
+	// block once its input is full
+	for i := 0; i < cap(track.rawInput); i++ {
+		assert.NoError(t, track.WriteRTP(&rtp.Packet{}, nil))
+	}
+	assert.NoError(t, track.SetWriteDeadline(time.Now().Add(10*time.Millisecond)))
+	err = track.WriteRTP(&rtp.Packet{}, nil)
+	assert.Equal(t, ErrWriteTimeout, err)
+	netErr, ok := err.(net.Error)
+	assert.True(t, ok && netErr.Timeout())
+
+	// Setting the deadline again rearms the pending writes
+	assert.NoError(t, track.SetWriteDeadline(time.Now().Add(50*time.Millisecond)))
+	written := make(chan error)
+	go func() {
+		written <- track.WriteRTP(&rtp.Packet{}, nil)
+	}()
+	time.Sleep(5 * time.Millisecond)
+	assert.NoError(t, track.SetWriteDeadline(time.Time{}))
+	time.Sleep(100 * time.Millisecond)
+	<-track.rawInput
+	assert.NoError(t, <-written)
+
+	sender.Stop()
+}
+
+func TestTrack_CloseWhileWriteBlocked(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	pc, err := NewPeerConnection(Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	track, err := pc.NewRawRTPTrack(DefaultPayloadTypeVP8, 5000, "video", "pion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pc.AddTrack(track); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing takes the packets before the PeerConnection connects, the
+	// writes block once the input of the sender is full
+	written := make(chan error)
+	go func() {
+		for i := 0; i < 100; i++ {
+			if writeErr := track.WriteRTP(&rtp.Packet{}, nil); writeErr != nil {
+				written <- writeErr
+				return
+			}
+		}
+		written <- nil
+	}()
+	for len(track.rawInput) < cap(track.rawInput) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Closing releases the blocked write
+	assert.NoError(t, pc.Close())
+	assert.Equal(t, ErrRTPSenderStopped, <-written)
+}
+
+func TestTrack_ReadRTCPRaw(t *testing.T) {
+	rtcpPackets := make(chan rtcp.Packet, 1)
+	track := &Track{RTCPPackets: rtcpPackets}
+
+	fir := []byte{0x84, 0xce, 0x00, 0x01, 0x90, 0x2f, 0x9e, 0x2e}
+	_, err := track.unmarshalRTCP(fir)
+	assert.Error(t, err)
+
+	pli := &rtcp.PictureLossIndication{MediaSSRC: 1}
+	rtcpPackets <- pli
+	packet, err := track.ReadRTCPRaw()
+	assert.NoError(t, err)
+	assert.Equal(t, pli, packet)
+
+	// The packets that fail to parse are kept from then on
+	packets, err := track.unmarshalRTCP(fir)
+	assert.NoError(t, err)
+	rawFIR := rtcp.RawPacket(fir)
+	assert.Equal(t, []rtcp.Packet{&rawFIR}, packets)
+
+	close(rtcpPackets)
+	_, err = track.ReadRTCPRaw()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestTrack_IsFrameBoundary(t *testing.T) {
+	video, err := NewRawRTPTrack(DefaultPayloadTypeVP8, 5000, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, video.IsFrameBoundary(&rtp.Packet{Header: rtp.Header{Marker: true}}))
+	assert.False(t, video.IsFrameBoundary(&rtp.Packet{}))
+
+	// The marker bit of audio flags a talkspurt, not the end of a frame
+	audio, err := NewRawRTPTrack(DefaultPayloadTypeOpus, 5001, "audio", "pion", NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, audio.IsFrameBoundary(&rtp.Packet{Header: rtp.Header{Marker: true}}))
+	assert.True(t, audio.IsFrameBoundary(&rtp.Packet{}))
+}
+
+func TestTrack_CaptureTime(t *testing.T) {
+	api := NewAPI()
+	receiver := api.NewRTPReceiver(RTPCodecTypeVideo, nil)
+	track := &Track{SSRC: 5, Codec: NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000), receiver: receiver}
+	receiver.Track = track
+
+	packet := &rtp.Packet{Header: rtp.Header{Timestamp: 1000}}
+	_, ok := track.CaptureTime(packet)
+	assert.False(t, ok, "no Sender Report was received")
+
+	// The reports about other streams are ignored
+	reported := time.Unix(1500000000, 0)
+	receiver.readSenderReport(&rtcp.SenderReport{SSRC: 6, NTPTime: toNTPTime(reported), RTPTime: 1000})
+	_, ok = track.CaptureTime(packet)
+	assert.False(t, ok)
+
+	receiver.readSenderReport(&rtcp.SenderReport{SSRC: 5, NTPTime: toNTPTime(reported), RTPTime: 1000})
+	for _, c := range []struct {
+		timestamp uint32
+		offset    time.Duration
+	}{
+		{1000, 0},
+		{1000 + 90000, time.Second},
+		{1000 - 900, -10 * time.Millisecond},
+	} {
+		captured, ok := track.CaptureTime(&rtp.Packet{Header: rtp.Header{Timestamp: c.timestamp}})
+		assert.True(t, ok)
+		assert.WithinDuration(t, reported.Add(c.offset), captured, time.Microsecond, c.timestamp)
+	}
+
+	// The timestamp wrapped around since the report
+	receiver.readSenderReport(&rtcp.SenderReport{SSRC: 5, NTPTime: toNTPTime(reported), RTPTime: 0xFFFFFFFF - 8099})
+	captured, ok := track.CaptureTime(&rtp.Packet{Header: rtp.Header{Timestamp: 900}})
+	assert.True(t, ok)
+	assert.WithinDuration(t, reported.Add(100*time.Millisecond), captured, time.Microsecond)
+
+	sent := &Track{Codec: NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000)}
+	_, ok = sent.CaptureTime(packet)
+	assert.False(t, ok)
+}
@@ -0,0 +1,64 @@
+package webrtc
+
+import (
+	"github.com/pions/rtp"
+	"github.com/pkg/errors"
+)
+
+const (
+	// The transmission offset is a 24-bit signed integer
+	transmissionOffsetMin = -(1 << 23)
+	transmissionOffsetMax = 1<<23 - 1
+)
+
+func marshalTransmissionOffset(offset int32) []byte {
+	return []byte{byte(offset >> 16), byte(offset >> 8), byte(offset)}
+}
+
+func unmarshalTransmissionOffset(payload []byte) (int32, error) {
+	if len(payload) != 3 {
+		return 0, errors.Errorf("invalid transmission offset extension size %d", len(payload))
+	}
+
+	// Shifted to the top of the int32 and back to extend the sign
+	offset := int32(uint32(payload[0])<<24|uint32(payload[1])<<16|uint32(payload[2])<<8) >> 8
+	return offset, nil
+}
+
+// TransmissionOffset returns the transmission time offset carried by the
+// given header, if the toffset extension was negotiated for the Track and is
+// present in the packet. The offset is in the units of the RTP timestamp: the
+// packet was sent that long after the time of its RTP timestamp.
+func (t *Track) TransmissionOffset(header *rtp.Header) (int32, bool) {
+	id, ok := t.headerExtensionID(TransmissionOffsetURI)
+	if !ok {
+		return 0, false
+	}
+
+	payload, ok := getHeaderExtension(header, id)
+	if !ok {
+		return 0, false
+	}
+
+	offset, err := unmarshalTransmissionOffset(payload)
+	if err != nil {
+		return 0, false
+	}
+	return offset, true
+}
+
+// SetTransmissionOffset writes the transmission time offset into the header
+// of a packet sent on a raw RTP Track, in the units of the RTP timestamp.
+// ErrHeaderExtensionNotNegotiated is returned if the toffset extension wasn't
+// negotiated, ErrInvalidTransmissionOffset if the offset doesn't fit in the
+// 24 bits of the extension.
+func (t *Track) SetTransmissionOffset(header *rtp.Header, offset int32) error {
+	if offset < transmissionOffsetMin || offset > transmissionOffsetMax {
+		return ErrInvalidTransmissionOffset
+	}
+	id, ok := t.headerExtensionID(TransmissionOffsetURI)
+	if !ok {
+		return ErrHeaderExtensionNotNegotiated
+	}
+	return setHeaderExtension(header, id, marshalTransmissionOffset(offset))
+}
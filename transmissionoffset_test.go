@@ -0,0 +1,40 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pions/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransmissionOffset_Marshal(t *testing.T) {
+	for _, offset := range []int32{0, 1, -1, 90000, -90000, transmissionOffsetMin, transmissionOffsetMax} {
+		payload := marshalTransmissionOffset(offset)
+		assert.Len(t, payload, 3)
+
+		parsed, err := unmarshalTransmissionOffset(payload)
+		assert.NoError(t, err)
+		assert.Equal(t, offset, parsed)
+	}
+	assert.Equal(t, []byte{0xFF, 0xFF, 0xFF}, marshalTransmissionOffset(-1))
+
+	_, err := unmarshalTransmissionOffset([]byte{0x00})
+	assert.Error(t, err)
+}
+
+func TestTrack_TransmissionOffset(t *testing.T) {
+	track := &Track{}
+	header := &rtp.Header{}
+
+	assert.Equal(t, ErrHeaderExtensionNotNegotiated, track.SetTransmissionOffset(header, 10))
+	_, ok := track.TransmissionOffset(header)
+	assert.False(t, ok)
+
+	track.headerExtensions = map[string]uint8{TransmissionOffsetURI: 2}
+	assert.Equal(t, ErrInvalidTransmissionOffset, track.SetTransmissionOffset(header, transmissionOffsetMax+1))
+	assert.NoError(t, track.SetTransmissionOffset(header, -450))
+
+	offset, ok := track.TransmissionOffset(header)
+	assert.True(t, ok)
+	assert.Equal(t, int32(-450), offset)
+}
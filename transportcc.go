@@ -0,0 +1,456 @@
+package webrtc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pions/rtcp"
+	"github.com/pions/rtp"
+	"github.com/pkg/errors"
+)
+
+// TransportLayerCC is the transport-wide congestion control feedback message
+// defined in https://tools.ietf.org/html/draft-holmer-rmcat-transport-wide-cc-extensions-01#section-3.1,
+// it reports the arrival time of the packets carrying the transport-wide
+// sequence number extension (TransportCCURI) of every stream of the transport.
+type TransportLayerCC struct {
+	// SSRC of sender
+	SenderSSRC uint32
+
+	// SSRC of the media source
+	MediaSSRC uint32
+
+	// BaseSequenceNumber is the transport-wide sequence number of the
+	// first packet of Packets
+	BaseSequenceNumber uint16
+
+	// ReferenceTime is the arrival time the deltas start from, in multiples
+	// of 64ms. It is a 24 bits value.
+	ReferenceTime uint32
+
+	// FeedbackPacketCount is incremented for every feedback message sent
+	FeedbackPacketCount uint8
+
+	// Packets are the statuses of the packets from BaseSequenceNumber on
+	Packets []TransportCCPacketStatus
+}
+
+// TransportCCPacketStatus is the status of a packet in a TransportLayerCC
+type TransportCCPacketStatus struct {
+	Received bool
+
+	// Delta is the arrival time of a received packet relative to the
+	// previous received one, or to the reference time for the first. It is
+	// carried with a 250µs resolution.
+	Delta time.Duration
+}
+
+const (
+	formatTransportCC = 15
+
+	transportCCHeaderLength    = 16
+	transportCCDeltaResolution = 250 * time.Microsecond
+
+	// The packet status symbols
+	transportCCNotReceived = 0
+	transportCCSmallDelta  = 1
+	transportCCLargeDelta  = 2
+
+	// A two-bit status vector chunk holds 7 symbols
+	transportCCSymbolsPerChunk = 7
+)
+
+var _ rtcp.Packet = (*TransportLayerCC)(nil)
+
+// symbol returns the status symbol of a packet and its delta in 250µs units
+func (s TransportCCPacketStatus) symbol() (uint16, int64) {
+	if !s.Received {
+		return transportCCNotReceived, 0
+	}
+	delta := int64(s.Delta / transportCCDeltaResolution)
+	if delta >= 0 && delta <= 0xFF {
+		return transportCCSmallDelta, delta
+	}
+	return transportCCLargeDelta, delta
+}
+
+// Header returns the Header associated with this packet.
+func (p *TransportLayerCC) Header() rtcp.Header {
+	length, padding := p.len()
+	return rtcp.Header{
+		Padding: padding != 0,
+		Count:   formatTransportCC,
+		Type:    rtcp.TypeTransportSpecificFeedback,
+		Length:  uint16(length/4 - 1),
+	}
+}
+
+// len returns the length of the packet and of its padding
+func (p TransportLayerCC) len() (int, int) {
+	length := rtcpHeaderLength + transportCCHeaderLength
+	length += 2 * ((len(p.Packets) + transportCCSymbolsPerChunk - 1) / transportCCSymbolsPerChunk)
+	for _, s := range p.Packets {
+		symbol, _ := s.symbol()
+		length += int(symbol)
+	}
+
+	padding := (4 - length%4) % 4
+	return length + padding, padding
+}
+
+// Marshal encodes the TransportLayerCC in binary, the statuses are written
+// in two-bit status vector chunks
+func (p TransportLayerCC) Marshal() ([]byte, error) {
+	if len(p.Packets) > 0xFFFF {
+		return nil, errors.New("rtcp: too many packet statuses")
+	}
+
+	length, padding := p.len()
+	rawPacket := make([]byte, length)
+	hData, err := p.Header().Marshal()
+	if err != nil {
+		return nil, err
+	}
+	copy(rawPacket, hData)
+
+	packetBody := rawPacket[rtcpHeaderLength:]
+	binary.BigEndian.PutUint32(packetBody, p.SenderSSRC)
+	binary.BigEndian.PutUint32(packetBody[4:], p.MediaSSRC)
+	binary.BigEndian.PutUint16(packetBody[8:], p.BaseSequenceNumber)
+	binary.BigEndian.PutUint16(packetBody[10:], uint16(len(p.Packets)))
+	binary.BigEndian.PutUint32(packetBody[12:], p.ReferenceTime<<8|uint32(p.FeedbackPacketCount))
+
+	offset := transportCCHeaderLength
+	for i := 0; i < len(p.Packets); i += transportCCSymbolsPerChunk {
+		chunk := uint16(0xC000)
+		for j := 0; j < transportCCSymbolsPerChunk && i+j < len(p.Packets); j++ {
+			symbol, _ := p.Packets[i+j].symbol()
+			chunk |= symbol << uint(2*(transportCCSymbolsPerChunk-1-j))
+		}
+		binary.BigEndian.PutUint16(packetBody[offset:], chunk)
+		offset += 2
+	}
+
+	for _, s := range p.Packets {
+		switch symbol, delta := s.symbol(); symbol {
+		case transportCCSmallDelta:
+			packetBody[offset] = uint8(delta)
+			offset++
+		case transportCCLargeDelta:
+			if delta < -0x8000 || delta > 0x7FFF {
+				return nil, errors.New("rtcp: packet delta out of range")
+			}
+			binary.BigEndian.PutUint16(packetBody[offset:], uint16(int16(delta)))
+			offset += 2
+		}
+	}
+
+	if padding != 0 {
+		rawPacket[len(rawPacket)-1] = uint8(padding)
+	}
+	return rawPacket, nil
+}
+
+// Unmarshal decodes the TransportLayerCC from binary, the run length and
+// status vector chunks are supported
+func (p *TransportLayerCC) Unmarshal(rawPacket []byte) error {
+	if len(rawPacket) < rtcpHeaderLength+transportCCHeaderLength {
+		return errors.New("rtcp: packet too short")
+	}
+
+	var h rtcp.Header
+	if err := h.Unmarshal(rawPacket); err != nil {
+		return err
+	}
+
+	if h.Type != rtcp.TypeTransportSpecificFeedback || h.Count != formatTransportCC {
+		return errors.New("rtcp: wrong packet type")
+	}
+
+	end := (int(h.Length) + 1) * 4
+	if end > len(rawPacket) || end < rtcpHeaderLength+transportCCHeaderLength {
+		return errors.New("rtcp: invalid packet length")
+	}
+	packetBody := rawPacket[rtcpHeaderLength:end]
+
+	p.SenderSSRC = binary.BigEndian.Uint32(packetBody)
+	p.MediaSSRC = binary.BigEndian.Uint32(packetBody[4:])
+	p.BaseSequenceNumber = binary.BigEndian.Uint16(packetBody[8:])
+	statusCount := int(binary.BigEndian.Uint16(packetBody[10:]))
+	p.ReferenceTime = binary.BigEndian.Uint32(packetBody[12:]) >> 8
+	p.FeedbackPacketCount = packetBody[15]
+
+	symbols := make([]uint16, 0, statusCount)
+	offset := transportCCHeaderLength
+	for len(symbols) < statusCount {
+		if offset+2 > len(packetBody) {
+			return errors.New("rtcp: packet status chunks exceed the packet")
+		}
+		chunk := binary.BigEndian.Uint16(packetBody[offset:])
+		offset += 2
+
+		switch {
+		case chunk&0x8000 == 0:
+			// Run length chunk
+			symbol := chunk >> 13 & 0x3
+			for i := 0; i < int(chunk&0x1FFF) && len(symbols) < statusCount; i++ {
+				symbols = append(symbols, symbol)
+			}
+		case chunk&0x4000 == 0:
+			// One-bit status vector chunk
+			for i := 13; i >= 0 && len(symbols) < statusCount; i-- {
+				symbols = append(symbols, chunk>>uint(i)&0x1)
+			}
+		default:
+			// Two-bit status vector chunk
+			for i := transportCCSymbolsPerChunk - 1; i >= 0 && len(symbols) < statusCount; i-- {
+				symbols = append(symbols, chunk>>uint(2*i)&0x3)
+			}
+		}
+	}
+
+	p.Packets = make([]TransportCCPacketStatus, 0, statusCount)
+	for _, symbol := range symbols {
+		var delta int64
+		switch symbol {
+		case transportCCNotReceived:
+			p.Packets = append(p.Packets, TransportCCPacketStatus{})
+			continue
+		case transportCCSmallDelta:
+			if offset+1 > len(packetBody) {
+				return errors.New("rtcp: packet deltas exceed the packet")
+			}
+			delta = int64(packetBody[offset])
+			offset++
+		case transportCCLargeDelta:
+			if offset+2 > len(packetBody) {
+				return errors.New("rtcp: packet deltas exceed the packet")
+			}
+			delta = int64(int16(binary.BigEndian.Uint16(packetBody[offset:])))
+			offset += 2
+		default:
+			return errors.New("rtcp: reserved packet status symbol")
+		}
+		p.Packets = append(p.Packets, TransportCCPacketStatus{
+			Received: true,
+			Delta:    time.Duration(delta) * transportCCDeltaResolution,
+		})
+	}
+	return nil
+}
+
+// DestinationSSRC returns an array of SSRC values that this packet refers to.
+func (p *TransportLayerCC) DestinationSSRC() []uint32 {
+	return []uint32{p.MediaSSRC}
+}
+
+func (p *TransportLayerCC) String() string {
+	received := 0
+	for _, s := range p.Packets {
+		if s.Received {
+			received++
+		}
+	}
+	return fmt.Sprintf("TransportLayerCC %x %x base %d received %d/%d",
+		p.SenderSSRC, p.MediaSSRC, p.BaseSequenceNumber, received, len(p.Packets))
+}
+
+// transportCCHistorySize is the number of sent packets whose send time is
+// kept, it divides 65536 so the sequence numbers wrap around with the history
+const transportCCHistorySize = 4096
+
+// transportCCPacket is a packet sent with a transport-wide sequence number
+type transportCCPacket struct {
+	sequenceNumber uint16
+	sent           time.Time
+	size           int
+}
+
+// transportCCHistory numbers the packets sent on a transport and keeps their
+// send time, to correlate the TransportLayerCC feedback of the remote
+type transportCCHistory struct {
+	mu      sync.Mutex
+	next    uint16
+	packets [transportCCHistorySize]transportCCPacket
+}
+
+// add numbers a packet of the given size sent now
+func (h *transportCCHistory) add(size int, now time.Time) uint16 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sequenceNumber := h.next
+	h.next++
+	h.packets[sequenceNumber%transportCCHistorySize] = transportCCPacket{sequenceNumber: sequenceNumber, sent: now, size: size}
+	return sequenceNumber
+}
+
+// get returns the sent packet with the given sequence number, if still kept
+func (h *transportCCHistory) get(sequenceNumber uint16) (transportCCPacket, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	p := h.packets[sequenceNumber%transportCCHistorySize]
+	if p.sent.IsZero() || p.sequenceNumber != sequenceNumber {
+		return transportCCPacket{}, false
+	}
+	return p, true
+}
+
+// rtpPacketSize returns the size of a marshaled RTP packet
+func rtpPacketSize(header *rtp.Header, payload []byte) int {
+	size := 12 + 4*len(header.CSRC) + len(payload)
+	if header.Extension {
+		size += 4 + len(header.ExtensionPayload)
+	}
+	return size
+}
+
+// transportCCArrival is a packet reported received by a TransportLayerCC
+type transportCCArrival struct {
+	transportCCPacket
+
+	// arrival is the time the packet arrived on the clock of the remote
+	arrival time.Duration
+}
+
+// arrivals correlates the packets reported received by a feedback message
+// with their send time, in the order of the sequence numbers. The packets no
+// longer in the history are skipped.
+func (h *transportCCHistory) arrivals(feedback *TransportLayerCC) []transportCCArrival {
+	var arrivals []transportCCArrival
+	arrival := time.Duration(feedback.ReferenceTime) * 64 * time.Millisecond
+	for i, s := range feedback.Packets {
+		if !s.Received {
+			continue
+		}
+		arrival += s.Delta
+
+		if p, ok := h.get(feedback.BaseSequenceNumber + uint16(i)); ok {
+			arrivals = append(arrivals, transportCCArrival{transportCCPacket: p, arrival: arrival})
+		}
+	}
+	return arrivals
+}
+
+const (
+	// transportCCFeedbackInterval is the interval of the TransportLayerCC
+	// feedback sent about the packets received
+	transportCCFeedbackInterval = 100 * time.Millisecond
+
+	// transportCCMaxStatuses is the number of packets a feedback message
+	// reports at most, the older ones are reported lost
+	transportCCMaxStatuses = 1024
+
+	// transportCCMaxDelta is the largest delta carried by a status
+	transportCCMaxDelta = 0x7FFF * transportCCDeltaResolution
+)
+
+// transportCCRecorder keeps the arrival time of the packets received on a
+// transport with a transport-wide sequence number, until they are reported
+// in a TransportLayerCC feedback message. It is shared by the RTPReceivers
+// of the transport.
+type transportCCRecorder struct {
+	mu sync.Mutex
+
+	// The sequence numbers are extended past their 16 bits, next is the
+	// first one not reported yet and highest the highest one received
+	started  bool
+	next     int64
+	highest  int64
+	arrivals map[int64]time.Time
+
+	// start is the time the reference times are counted from
+	start         time.Time
+	lastFeedback  time.Time
+	feedbackCount uint8
+}
+
+// record keeps the arrival time of a packet, unless it was already reported
+func (r *transportCCRecorder) record(sequenceNumber uint16, arrival time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.started {
+		r.started = true
+		r.next = 1<<16 | int64(sequenceNumber)
+		r.highest = r.next
+		r.arrivals = map[int64]time.Time{}
+		r.start = arrival
+		r.lastFeedback = arrival
+	}
+
+	extended := r.highest + int64(int16(sequenceNumber-uint16(r.highest)))
+	if extended < r.next {
+		return
+	}
+	if extended > r.highest {
+		r.highest = extended
+	}
+	r.arrivals[extended] = arrival
+}
+
+// dueFeedback returns the feedback message reporting the packets received
+// since the previous one, once transportCCFeedbackInterval elapsed. The
+// MediaSSRC and SenderSSRC are left to the caller.
+func (r *transportCCRecorder) dueFeedback(now time.Time) (*TransportLayerCC, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.arrivals) == 0 || now.Sub(r.lastFeedback) < transportCCFeedbackInterval {
+		return nil, false
+	}
+	if r.highest-r.next >= transportCCMaxStatuses {
+		for sequenceNumber := r.next; sequenceNumber <= r.highest-transportCCMaxStatuses; sequenceNumber++ {
+			delete(r.arrivals, sequenceNumber)
+		}
+		r.next = r.highest - transportCCMaxStatuses + 1
+	}
+
+	// The reference time is the arrival of the first packet received,
+	// in multiples of 64ms
+	var first time.Time
+	for sequenceNumber := r.next; sequenceNumber <= r.highest && first.IsZero(); sequenceNumber++ {
+		first = r.arrivals[sequenceNumber]
+	}
+	if first.IsZero() {
+		return nil, false
+	}
+	reference := first.Sub(r.start) / (64 * time.Millisecond)
+	if reference < 0 {
+		reference = 0
+	}
+
+	feedback := &TransportLayerCC{
+		BaseSequenceNumber:  uint16(r.next),
+		ReferenceTime:       uint32(reference) & 0xFFFFFF,
+		FeedbackPacketCount: r.feedbackCount,
+	}
+	previous := r.start.Add(reference * 64 * time.Millisecond)
+	for sequenceNumber := r.next; sequenceNumber <= r.highest; sequenceNumber++ {
+		arrival, ok := r.arrivals[sequenceNumber]
+		if !ok {
+			feedback.Packets = append(feedback.Packets, TransportCCPacketStatus{})
+			continue
+		}
+
+		// The deltas add up at their resolution
+		delta := arrival.Sub(previous) / transportCCDeltaResolution * transportCCDeltaResolution
+		switch {
+		case delta > transportCCMaxDelta:
+			delta = transportCCMaxDelta
+		case delta < -transportCCMaxDelta:
+			delta = -transportCCMaxDelta
+		}
+		feedback.Packets = append(feedback.Packets, TransportCCPacketStatus{Received: true, Delta: delta})
+		previous = previous.Add(delta)
+		delete(r.arrivals, sequenceNumber)
+	}
+
+	r.next = r.highest + 1
+	r.lastFeedback = now
+	r.feedbackCount++
+	return feedback, true
+}
@@ -0,0 +1,159 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransportLayerCC_Unmarshal(t *testing.T) {
+	// A run length chunk of two small deltas and a one-bit status vector
+	// chunk, padded
+	raw := []byte{
+		0xaf, 0xcd, 0x00, 0x06,
+		0x00, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x02,
+		0x00, 0x05, 0x00, 0x04,
+		0x00, 0x00, 0x10, 0x01,
+		0x20, 0x02, 0x90, 0x00,
+		0x04, 0x08, 0xff, 0x01,
+	}
+
+	packet, err := unmarshalRTCP(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &TransportLayerCC{
+		SenderSSRC:          1,
+		MediaSSRC:           2,
+		BaseSequenceNumber:  5,
+		ReferenceTime:       16,
+		FeedbackPacketCount: 1,
+		Packets: []TransportCCPacketStatus{
+			{Received: true, Delta: time.Millisecond},
+			{Received: true, Delta: 2 * time.Millisecond},
+			{},
+			{Received: true, Delta: 63750 * time.Microsecond},
+		},
+	}, packet)
+	assert.Equal(t, []uint32{2}, packet.DestinationSSRC())
+
+	// The deltas must be in the packet
+	raw[3] = 0x05
+	_, err = unmarshalRTCP(raw[:24])
+	assert.Error(t, err)
+}
+
+func TestTransportLayerCC_Marshal(t *testing.T) {
+	packet := &TransportLayerCC{
+		SenderSSRC:          1,
+		MediaSSRC:           2,
+		BaseSequenceNumber:  65534,
+		ReferenceTime:       0xFFFFFF,
+		FeedbackPacketCount: 255,
+		Packets: []TransportCCPacketStatus{
+			{Received: true, Delta: 100 * time.Millisecond},
+			{Received: true, Delta: -time.Millisecond},
+			{},
+			{Received: true},
+			{Received: true, Delta: 250 * time.Microsecond},
+			{},
+			{Received: true, Delta: 8 * time.Second},
+			{Received: true, Delta: 5 * time.Millisecond},
+		},
+	}
+
+	raw, err := packet.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, len(raw)%4)
+
+	decoded := &TransportLayerCC{}
+	if err = decoded.Unmarshal(raw); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, packet, decoded)
+
+	// The deltas are carried in 16 bits at most
+	packet.Packets[0].Delta = 10 * time.Second
+	_, err = packet.Marshal()
+	assert.Error(t, err)
+}
+
+func TestTransportCCHistory(t *testing.T) {
+	h := &transportCCHistory{}
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, uint16(i), h.add(1000+i, now.Add(time.Duration(i)*time.Millisecond)))
+	}
+
+	arrivals := h.arrivals(&TransportLayerCC{
+		BaseSequenceNumber: 0,
+		ReferenceTime:      1,
+		Packets: []TransportCCPacketStatus{
+			{Received: true, Delta: time.Millisecond},
+			{},
+			{Received: true, Delta: 2 * time.Millisecond},
+			// Never sent
+			{Received: true},
+		},
+	})
+	if len(arrivals) != 2 {
+		t.Fatalf("expected 2 arrivals, got %d", len(arrivals))
+	}
+	assert.Equal(t, 1000, arrivals[0].size)
+	assert.Equal(t, 65*time.Millisecond, arrivals[0].arrival)
+	assert.Equal(t, now.Add(2*time.Millisecond), arrivals[1].sent)
+	assert.Equal(t, 67*time.Millisecond, arrivals[1].arrival)
+}
+
+func TestTransportCCRecorder(t *testing.T) {
+	r := &transportCCRecorder{}
+	start := time.Now()
+
+	// The sequence numbers wrap around, 0 is lost and 1 reordered
+	r.record(65534, start)
+	r.record(65535, start.Add(time.Millisecond))
+	r.record(2, start.Add(70*time.Millisecond))
+	r.record(1, start.Add(69*time.Millisecond))
+
+	_, ok := r.dueFeedback(start.Add(50 * time.Millisecond))
+	assert.False(t, ok)
+
+	feedback, ok := r.dueFeedback(start.Add(100 * time.Millisecond))
+	if !ok {
+		t.Fatal("feedback should be due")
+	}
+	assert.Equal(t, &TransportLayerCC{
+		BaseSequenceNumber: 65534,
+		Packets: []TransportCCPacketStatus{
+			{Received: true},
+			{Received: true, Delta: time.Millisecond},
+			{},
+			{Received: true, Delta: 68 * time.Millisecond},
+			{Received: true, Delta: time.Millisecond},
+		},
+	}, feedback)
+	_, err := feedback.Marshal()
+	assert.NoError(t, err)
+
+	// The packets reported aren't reported again, the reference time
+	// follows the first packet of the feedback
+	r.record(2, start.Add(150*time.Millisecond))
+	r.record(3, start.Add(150*time.Millisecond))
+	feedback, ok = r.dueFeedback(start.Add(200 * time.Millisecond))
+	if !ok {
+		t.Fatal("feedback should be due")
+	}
+	assert.Equal(t, &TransportLayerCC{
+		BaseSequenceNumber:  3,
+		ReferenceTime:       2,
+		FeedbackPacketCount: 1,
+		Packets:             []TransportCCPacketStatus{{Received: true, Delta: 22 * time.Millisecond}},
+	}, feedback)
+
+	_, ok = r.dueFeedback(start.Add(time.Second))
+	assert.False(t, ok, "no packet is left to report")
+}
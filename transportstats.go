@@ -0,0 +1,28 @@
+package webrtc
+
+// TransportStats contains the cumulative traffic of a transport, as counted
+// on its sockets. It includes STUN, DTLS, SCTP and all the media.
+// https://www.w3.org/TR/webrtc-stats/#transportstats-dict*
+type TransportStats struct {
+	BytesSent       uint64 `json:"bytesSent"`
+	BytesReceived   uint64 `json:"bytesReceived"`
+	PacketsSent     uint64 `json:"packetsSent"`
+	PacketsReceived uint64 `json:"packetsReceived"`
+
+	// DTLSCipher is the IANA name of the DTLS cipher suite, empty until
+	// negotiated
+	DTLSCipher string `json:"dtlsCipher,omitempty"`
+
+	// SRTPCipher is the SRTP protection profile, empty until negotiated
+	SRTPCipher string `json:"srtpCipher,omitempty"`
+
+	// SRTPAuthFailures and SRTCPAuthFailures are the packets of the remote
+	// dropped since their authentication tag was invalid, the valid packets
+	// keep being delivered
+	SRTPAuthFailures  uint64 `json:"srtpAuthFailures"`
+	SRTCPAuthFailures uint64 `json:"srtcpAuthFailures"`
+
+	// ICERole is the role the ICE agent plays once the role conflicts are
+	// resolved, it can differ from the role the ICETransport was started with
+	ICERole ICERole `json:"iceRole,omitempty"`
+}
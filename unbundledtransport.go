@@ -0,0 +1,101 @@
+package webrtc
+
+import (
+	"strings"
+
+	"github.com/pions/sdp/v2"
+)
+
+// unbundledTransport is the ICE and DTLS transport the data channels use
+// when the application section isn't bundled with the media. It gathers its
+// own candidates and runs its own ICE session and DTLS handshake.
+type unbundledTransport struct {
+	iceGatherer   *ICEGatherer
+	iceTransport  *ICETransport
+	dtlsTransport *DTLSTransport
+
+	// mid is the media section of the data channels once the remote agreed
+	// not to bundle it, empty until then
+	mid string
+}
+
+func (pc *PeerConnection) createUnbundledTransport() (*unbundledTransport, error) {
+	gatherer, err := pc.createICEGatherer()
+	if err != nil {
+		return nil, err
+	}
+	// The candidates aren't trickled, the offer carries them
+	gatherer.trickle = false
+	if err = gatherer.Gather(); err != nil {
+		return nil, err
+	}
+
+	iceTransport := pc.api.NewICETransport(gatherer)
+	dtlsTransport, err := pc.api.NewDTLSTransport(iceTransport, pc.configuration.Certificates)
+	if err != nil {
+		return nil, err
+	}
+	return &unbundledTransport{iceGatherer: gatherer, iceTransport: iceTransport, dtlsTransport: dtlsTransport}, nil
+}
+
+// unbundledData returns the transport of the data channels if they don't
+// use the media transport
+func (pc *PeerConnection) unbundledData() *unbundledTransport {
+	if pc.dataTransport == nil || pc.dataTransport.mid == "" {
+		return nil
+	}
+	return pc.dataTransport
+}
+
+// start connects the transport with the parameters the remote announced in
+// the application section
+func (t *unbundledTransport) start(iceParams ICEParameters, iceRole ICERole, dtlsParams DTLSParameters) error {
+	if err := t.iceTransport.Start(t.iceGatherer, iceParams, &iceRole); err != nil {
+		return err
+	}
+	return t.dtlsTransport.Start(dtlsParams)
+}
+
+func (t *unbundledTransport) stop() error {
+	var closeErrs []error
+	if err := t.dtlsTransport.Stop(); err != nil {
+		closeErrs = append(closeErrs, err)
+	}
+	if err := t.iceTransport.Stop(); err != nil {
+		closeErrs = append(closeErrs, err)
+	}
+	return flattenErrs(closeErrs)
+}
+
+// unbundledDataMid returns the mid of the application section when the
+// description bundles media sections but leaves it out of the BUNDLE group.
+// A description without a BUNDLE group is handled as bundled.
+func unbundledDataMid(desc *sdp.SessionDescription) (string, bool) {
+	group, ok := desc.Attribute(sdp.AttrKeyGroup)
+	if !ok {
+		return "", false
+	}
+	fields := strings.Fields(group)
+	if len(fields) < 2 || fields[0] != "BUNDLE" {
+		return "", false
+	}
+	bundled := map[string]bool{}
+	for _, mid := range fields[1:] {
+		bundled[mid] = true
+	}
+
+	dataMid, bundledMedia := "", false
+	for _, m := range desc.MediaDescriptions {
+		mid, _ := m.Attribute(sdp.AttrKeyMID)
+		switch {
+		case m.MediaName.Media == "application":
+			dataMid = mid
+		case bundled[mid]:
+			bundledMedia = true
+		}
+	}
+	if dataMid == "" || !bundledMedia || bundled[dataMid] {
+		return "", false
+	}
+	return dataMid, true
+}
@@ -0,0 +1,107 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pions/sdp/v2"
+	"github.com/pions/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnbundledDataMid(t *testing.T) {
+	for _, test := range []struct {
+		group     string
+		mid       string
+		unbundled bool
+	}{
+		{"BUNDLE audio video", "data", true},
+		{"BUNDLE audio video data", "", false},
+		// Nothing to bundle the data channels with
+		{"BUNDLE", "", false},
+		{"", "", false},
+	} {
+		desc := &sdp.SessionDescription{}
+		if test.group != "" {
+			desc.WithValueAttribute(sdp.AttrKeyGroup, test.group)
+		}
+		for _, m := range []struct{ media, mid string }{{"audio", "audio"}, {"video", "video"}, {"application", "data"}} {
+			desc.WithMedia((&sdp.MediaDescription{MediaName: sdp.MediaName{Media: m.media}}).WithValueAttribute(sdp.AttrKeyMID, m.mid))
+		}
+
+		mid, unbundled := unbundledDataMid(desc)
+		assert.Equal(t, test.mid, mid, test.group)
+		assert.Equal(t, test.unbundled, unbundled, test.group)
+	}
+}
+
+func TestPeerConnection_UnbundleDataChannel(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	api := NewAPI()
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan bool)
+	dc, err := pcOffer.CreateDataChannel("data", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dc.OnOpen(func() {
+		if e := dc.SendText("Ping"); e != nil {
+			t.Error(e)
+		}
+	})
+	dc.OnMessage(func(msg DataChannelMessage) {
+		// The messages went through the transport of the data channels
+		if dataTransport := pcOffer.unbundledData(); dataTransport == nil || dataTransport.dtlsTransport.State() != DTLSTransportStateConnected {
+			t.Error("the data channels should use their own transport")
+		}
+		done <- true
+	})
+	pcAnswer.OnDataChannel(func(d *DataChannel) {
+		d.OnMessage(func(msg DataChannelMessage) {
+			if e := d.SendText("Pong"); e != nil {
+				t.Error(e)
+			}
+		})
+	})
+
+	offer, err := pcOffer.CreateOffer(&OfferOptions{UnbundleDataChannel: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	group, _ := offer.parsed.Attribute(sdp.AttrKeyGroup)
+	assert.Equal(t, "BUNDLE audio video", group)
+
+	// The data channels have their own ICE credentials
+	audioUfrag, _ := offer.parsed.MediaDescriptions[0].Attribute("ice-ufrag")
+	dataUfrag, _ := offer.parsed.MediaDescriptions[2].Attribute("ice-ufrag")
+	assert.NotEqual(t, audioUfrag, dataUfrag)
+
+	if err = pcOffer.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcAnswer.SetRemoteDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	answer, err := pcAnswer.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	group, _ = answer.parsed.Attribute(sdp.AttrKeyGroup)
+	assert.Equal(t, "BUNDLE audio video", group)
+
+	if err = pcAnswer.SetLocalDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+	if err = pcOffer.SetRemoteDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+
+	closePair(t, pcOffer, pcAnswer, done)
+}
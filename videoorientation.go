@@ -0,0 +1,82 @@
+package webrtc
+
+import (
+	"github.com/pkg/errors"
+)
+
+// VideoRotation indicates the rotation the receiver has to apply to the
+// video, clockwise, to display it upright.
+type VideoRotation int
+
+const (
+	// VideoRotation0 indicates that the video is upright.
+	VideoRotation0 VideoRotation = iota
+
+	// VideoRotation90 indicates that the video must be rotated by 90 degrees.
+	VideoRotation90
+
+	// VideoRotation180 indicates that the video must be rotated by 180 degrees.
+	VideoRotation180
+
+	// VideoRotation270 indicates that the video must be rotated by 270 degrees.
+	VideoRotation270
+)
+
+func (r VideoRotation) String() string {
+	switch r {
+	case VideoRotation0:
+		return "0"
+	case VideoRotation90:
+		return "90"
+	case VideoRotation180:
+		return "180"
+	case VideoRotation270:
+		return "270"
+	default:
+		return ErrUnknownType.Error()
+	}
+}
+
+// VideoOrientation represents the coordination of video orientation (CVO)
+// information carried by the urn:3gpp:video-orientation header extension.
+type VideoOrientation struct {
+	// BackCamera indicates the video was captured by a back-facing camera
+	BackCamera bool
+
+	// Flip indicates the video must be flipped horizontally
+	Flip bool
+
+	// Rotation indicates how the video must be rotated
+	Rotation VideoRotation
+}
+
+// The CVO byte is laid out as 0 0 0 0 C F R1 R0
+// http://www.3gpp.org/ftp/Specs/html-info/26114.htm
+const (
+	videoOrientationCameraBit = 0x08
+	videoOrientationFlipBit   = 0x04
+	videoOrientationRotation  = 0x03
+)
+
+func (o VideoOrientation) marshal() []byte {
+	b := byte(o.Rotation) & videoOrientationRotation
+	if o.BackCamera {
+		b |= videoOrientationCameraBit
+	}
+	if o.Flip {
+		b |= videoOrientationFlipBit
+	}
+	return []byte{b}
+}
+
+func unmarshalVideoOrientation(payload []byte) (VideoOrientation, error) {
+	if len(payload) != 1 {
+		return VideoOrientation{}, errors.Errorf("invalid video orientation extension size %d", len(payload))
+	}
+
+	return VideoOrientation{
+		BackCamera: payload[0]&videoOrientationCameraBit != 0,
+		Flip:       payload[0]&videoOrientationFlipBit != 0,
+		Rotation:   VideoRotation(payload[0] & videoOrientationRotation),
+	}, nil
+}
@@ -0,0 +1,51 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVideoOrientation_Marshal(t *testing.T) {
+	testCases := []struct {
+		orientation VideoOrientation
+		expected    byte
+	}{
+		{VideoOrientation{}, 0x00},
+		{VideoOrientation{Rotation: VideoRotation90}, 0x01},
+		{VideoOrientation{Rotation: VideoRotation270, Flip: true}, 0x07},
+		{VideoOrientation{Rotation: VideoRotation180, BackCamera: true}, 0x0A},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t, []byte{testCase.expected}, testCase.orientation.marshal(), "testCase: %d %v", i, testCase)
+
+		o, err := unmarshalVideoOrientation([]byte{testCase.expected})
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.orientation, o, "testCase: %d %v", i, testCase)
+	}
+
+	_, err := unmarshalVideoOrientation([]byte{})
+	assert.Error(t, err)
+}
+
+func TestVideoRotation_String(t *testing.T) {
+	testCases := []struct {
+		rotation       VideoRotation
+		expectedString string
+	}{
+		{VideoRotation(4), unknownStr},
+		{VideoRotation0, "0"},
+		{VideoRotation90, "90"},
+		{VideoRotation180, "180"},
+		{VideoRotation270, "270"},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedString,
+			testCase.rotation.String(),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}